@@ -2,11 +2,17 @@ package app_test
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/livebud/bud/internal/cli/testcli"
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/package/socket"
 )
 
 func TestWelcome(t *testing.T) {
@@ -30,3 +36,96 @@ func TestWelcome(t *testing.T) {
 	is.NoErr(td.Exists("bud/app"))
 	is.NoErr(app.Close())
 }
+
+func TestDotenv(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		import "os"
+		type Controller struct {}
+		func (c *Controller) Index() string {
+			return os.Getenv("A") + " " + os.Getenv("B") + " " + os.Getenv("C") + " " + os.Getenv("D")
+		}
+	`
+	td.Files[".env"] = "A=env\nB=env\nC=env\nD=env\n"
+	td.Files[".env.development"] = "B=development\nC=development\n"
+	td.Files[".env.production"] = "B=production\n"
+	td.Files[".env.local"] = "C=local\n"
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["D"] = "process"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.Equal(res.Body().String(), `"env development local process"`)
+	is.NoErr(app.Close())
+}
+
+// get the path from the server listening on the unix socket, retrying until
+// the server is up
+func get(socketPath, path string) (*http.Response, []byte, error) {
+	transport, err := socket.Transport(socketPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &http.Client{Transport: transport, Timeout: 10 * time.Second}
+	var res *http.Response
+	for i := 0; i < 50; i++ {
+		res, err = client.Get("http://app" + path)
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+func TestProfilesOnlyOnInternal(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	publicPath := filepath.Join(dir, "public.sock")
+	internalPath := filepath.Join(dir, "internal.sock")
+	cmd := exec.CommandContext(ctx, filepath.Join(dir, "bud", "app"), "--listen="+publicPath, "--internal="+internalPath)
+	cmd.Dir = dir
+	is.NoErr(cmd.Start())
+	defer cmd.Wait()
+	defer cancel()
+	// The internal listener serves the profiles
+	res, body, err := get(internalPath, "/debug/pprof/")
+	is.NoErr(err)
+	is.Equal(res.StatusCode, 200)
+	is.In(string(body), "goroutine")
+	res, _, err = get(internalPath, "/debug/pprof/cmdline")
+	is.NoErr(err)
+	is.Equal(res.StatusCode, 200)
+	// The public listener doesn't
+	res, body, err = get(publicPath, "/debug/pprof/")
+	is.NoErr(err)
+	is.Equal(res.StatusCode, 404)
+	is.NotIn(string(body), "goroutine")
+	res, _, err = get(publicPath, "/debug/pprof/heap")
+	is.NoErr(err)
+	is.Equal(res.StatusCode, 404)
+}
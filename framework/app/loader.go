@@ -38,12 +38,28 @@ type loader struct {
 func (l *loader) Load() (state *State, err error) {
 	defer l.Recover2(&err, "app: unable to load state")
 	state = new(State)
-	l.imports.AddStd("os", "context", "errors")
+	l.imports.AddStd("os", "context", "encoding/json", "errors", "fmt", "net/http", "runtime/debug", "syscall", "time")
 	l.imports.AddNamed("commander", "github.com/livebud/bud/package/commander")
 	l.imports.AddNamed("budhttp", "github.com/livebud/bud/package/budhttp")
+	// Let BUD_LISTEN select the gRPC transport, e.g. grpc://127.0.0.1:35730
+	l.imports.AddNamed("_", "github.com/livebud/bud/package/budhttp/budgrpc")
 	l.imports.AddNamed("console", "github.com/livebud/bud/package/log/console")
 	l.imports.AddNamed("log", "github.com/livebud/bud/package/log")
 	l.imports.AddNamed("filter", "github.com/livebud/bud/package/log/filter")
+	l.imports.AddNamed("format", "github.com/livebud/bud/package/log/format")
+	l.imports.AddNamed("sample", "github.com/livebud/bud/package/log/sample")
+	l.imports.AddNamed("sink", "github.com/livebud/bud/package/log/sink")
+	l.imports.AddNamed("crash", "github.com/livebud/bud/package/crash")
+	l.imports.AddNamed("bud", "github.com/livebud/bud/package/bud")
+	l.imports.AddNamed("dotenv", "github.com/livebud/bud/package/dotenv")
+	l.imports.AddNamed("graceful", "github.com/livebud/bud/package/graceful")
+	l.imports.AddNamed("lifecycle", "github.com/livebud/bud/package/lifecycle")
+	l.imports.AddNamed("reload", "github.com/livebud/bud/package/reload")
+	l.imports.AddNamed("secretenv", "github.com/livebud/bud/package/secretenv")
+	l.imports.AddNamed("metrics", "github.com/livebud/bud/package/metrics")
+	l.imports.AddNamed("pprof", "net/http/pprof")
+	l.imports.AddNamed("socket", "github.com/livebud/bud/package/socket")
+	l.imports.AddNamed("webrt", "github.com/livebud/bud/framework/web/webrt")
 	l.imports.Add(l.module.Import("bud/internal/web"))
 	state.Provider = l.loadProvider()
 	state.Flag = l.flag
@@ -64,9 +80,13 @@ func (l *loader) loadProvider() *di.Provider {
 			{Import: "github.com/livebud/bud/package/gomod", Type: "*Module"},
 			{Import: "github.com/livebud/bud/package/budhttp", Type: "Client"},
 			{Import: "context", Type: "Context"},
+			{Import: "github.com/livebud/bud/package/crash", Type: "*Trail"},
+			{Import: "github.com/livebud/bud/package/crash", Type: "*Dumper"},
+			{Import: "github.com/livebud/bud/package/reload", Type: "*Reloader"},
 		},
 		Results: []di.Dependency{
 			di.ToType(l.module.Import("bud/internal/web"), "*Server"),
+			&di.Lifecycle{},
 			&di.Error{},
 		},
 		Aliases: di.Aliases{
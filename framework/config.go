@@ -0,0 +1,76 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+)
+
+// Config is the "bud" section of package.json, e.g.
+//
+//	"bud": {
+//	  "cdn": "https://cdn.example.com",
+//	  "public": {
+//	    "embed": true,
+//	    "cache": [
+//	      { "path": "/fonts/**", "control": "public, max-age=31536000, immutable" }
+//	    ]
+//	  }
+//	}
+type Config struct {
+	// CDN that serves the assets in production, like https://cdn.example.com.
+	// Upload the assets with bud assets push.
+	CDN    string       `json:"cdn"`
+	Public PublicConfig `json:"public"`
+}
+
+// PublicConfig configures the files in public/
+type PublicConfig struct {
+	// Embed public/ into production builds. When false, the app reads public/
+	// from disk at runtime instead. Defaults to true.
+	Embed *bool `json:"embed"`
+	// Cache-Control headers by path. The first policy that matches wins.
+	Cache []*CachePolicy `json:"cache"`
+}
+
+// Embedded is true unless the files in public/ are read from disk
+func (c *PublicConfig) Embedded() bool {
+	return c.Embed == nil || *c.Embed
+}
+
+// CachePolicy sets the Cache-Control header for the paths that match
+type CachePolicy struct {
+	Path    string `json:"path"`
+	Control string `json:"control"`
+}
+
+// LoadConfig from package.json. Apps without a package.json have an empty
+// config.
+func LoadConfig(fsys fs.FS) (*Config, error) {
+	config := new(Config)
+	data, err := fs.ReadFile(fsys, "package.json")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return config, nil
+		}
+		return nil, fmt.Errorf("framework: unable to read package.json. %w", err)
+	}
+	var pkg struct {
+		Bud *Config `json:"bud"`
+	}
+	pkg.Bud = config
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("framework: unable to parse package.json. %w", err)
+	}
+	if config.CDN != "" {
+		u, err := url.Parse(config.CDN)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.RawQuery != "" {
+			return nil, fmt.Errorf("framework: invalid cdn %q in package.json. Expected a URL like https://cdn.example.com", config.CDN)
+		}
+		config.CDN = strings.TrimSuffix(config.CDN, "/")
+	}
+	return config, nil
+}
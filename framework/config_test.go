@@ -0,0 +1,56 @@
+package framework_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	config, err := framework.LoadConfig(fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{
+			"name": "app",
+			"bud": {
+				"cdn": "https://cdn.example.com/assets/",
+				"public": {
+					"cache": [{ "path": "/fonts/**", "control": "public, max-age=86400" }]
+				}
+			}
+		}`)},
+	})
+	is.NoErr(err)
+	is.Equal(config.CDN, "https://cdn.example.com/assets")
+	is.Equal(len(config.Public.Cache), 1)
+	is.Equal(config.Public.Cache[0].Path, "/fonts/**")
+	is.Equal(config.Public.Cache[0].Control, "public, max-age=86400")
+	is.True(config.Public.Embedded())
+	config, err = framework.LoadConfig(fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"bud": {"public": {"embed": false}}}`)},
+	})
+	is.NoErr(err)
+	is.True(!config.Public.Embedded())
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	is := is.New(t)
+	config, err := framework.LoadConfig(fstest.MapFS{})
+	is.NoErr(err)
+	is.Equal(config.CDN, "")
+	config, err = framework.LoadConfig(fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"name": "app"}`)},
+	})
+	is.NoErr(err)
+	is.Equal(config.CDN, "")
+}
+
+func TestLoadConfigInvalidCDN(t *testing.T) {
+	is := is.New(t)
+	_, err := framework.LoadConfig(fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"bud": {"cdn": "cdn.example.com"}}`)},
+	})
+	is.True(err != nil)
+	is.Equal(err.Error(), `framework: invalid cdn "cdn.example.com" in package.json. Expected a URL like https://cdn.example.com`)
+}
@@ -319,6 +319,58 @@ func TestIndex500(t *testing.T) {
 	is.NoErr(app.Close())
 }
 
+func TestShowErrorKinds(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		import "github.com/livebud/bud/package/errors"
+		type Controller struct {}
+		type Post struct {}
+		func (c *Controller) Show(id int) (*Post, error) {
+			if id == 1 {
+				return nil, errors.Conflict.New("post is locked")
+			}
+			return nil, errors.NotFound.Errorf("post %d not found", id)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/10")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 404 Not Found
+		Content-Type: application/json
+
+		{"error":"post 10 not found"}
+	`))
+	res, err = app.GetJSON("/1")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 409 Conflict
+		Content-Type: application/json
+
+		{"error":"post is locked"}
+	`))
+	// Browsers get an error page
+	req, err := app.GetRequest("/10")
+	is.NoErr(err)
+	req.Header.Set("Accept", "text/html")
+	res, err = app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.DiffHeaders(`
+		HTTP/1.1 404 Not Found
+		Content-Type: text/html; charset=utf-8
+	`))
+	is.In(res.Body().String(), "<p>post 10 not found</p>")
+	is.NoErr(app.Close())
+}
+
 func TestIndexList500(t *testing.T) {
 	t.SkipNow()
 	is := is.New(t)
@@ -3072,3 +3124,154 @@ func TestUpdateBodyAndSignatureAndRoute(t *testing.T) {
 	`))
 	is.In(res.Body().String(), `/10`)
 }
+
+func TestCreateInvalid422(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/users/controller.go"] = `
+		package users
+		type Controller struct {}
+		type User struct {
+			Name  string ` + "`json:\"name\" validate:\"required\"`" + `
+			Email string ` + "`json:\"email\" validate:\"email\"`" + `
+		}
+		func (c *Controller) Create(user *User) (*User, error) {
+			return user, nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.PostJSON("/users", bytes.NewBufferString(`{"email":"alice"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 422 Unprocessable Entity
+		Content-Type: application/json
+
+		{"error":"name is required, email must be a valid email address","fields":{"email":"must be a valid email address","name":"is required"}}
+	`))
+	res, err = app.PostJSON("/users", bytes.NewBufferString(`{"name":"Alice","email":"alice@livebud.com"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"name":"Alice","email":"alice@livebud.com"}
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestActionInvalid422(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/users/controller.go"] = `
+		package users
+		import "github.com/livebud/bud/package/validate"
+		type Controller struct {}
+		func (c *Controller) Create(email string) error {
+			if email == "alice@livebud.com" {
+				return validate.Field("email", "is already taken")
+			}
+			return nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.PostJSON("/users", bytes.NewBufferString(`{"email":"alice@livebud.com"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 422 Unprocessable Entity
+		Content-Type: application/json
+
+		{"error":"email is already taken","fields":{"email":"is already taken"}}
+	`))
+	res, err = app.PostJSON("/users", bytes.NewBufferString(`{"email":"bob@livebud.com"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 204 No Content
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestPermissions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/posts/controller.go"] = `
+		package posts
+		type Controller struct {}
+		func (c *Controller) Index() string { return "posts" }
+		// Delete a post
+		//authz:require posts:delete
+		func (c *Controller) Delete(id int) error { return nil }
+	`
+	td.Files["controller/admin/controller.go"] = `
+		package admin
+		// Controller for admins
+		//authz:require admin
+		type Controller struct {}
+		func (c *Controller) Index() string { return "admin" }
+	`
+	td.Files["policy/policy.go"] = `
+		package policy
+		import (
+			"context"
+			"github.com/livebud/bud/package/authz"
+		)
+		var roles = authz.Roles{"editor": {"posts:*"}}
+		type Policy struct {}
+		func (p *Policy) Allow(ctx context.Context, permission string) (bool, error) {
+			return roles.Allows(permission, "editor"), nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/posts")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	res, err = app.DeleteJSON("/posts/1", nil)
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 204 No Content
+	`))
+	res, err = app.GetJSON("/admin")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 403 Forbidden
+		Content-Type: application/json
+
+		{"error":"authz: forbidden. missing the \"admin\" permission"}
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestPermissionsWithoutPolicy(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		type Controller struct {}
+		//authz:require admin
+		func (c *Controller) Index() string { return "admin" }
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "controller: /index requires permissions, but policy/ doesn't exist")
+}
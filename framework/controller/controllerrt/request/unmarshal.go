@@ -8,17 +8,19 @@ import (
 	"net/url"
 
 	"github.com/ajg/form"
+	"github.com/livebud/bud/package/errors"
 )
 
-// Unmarshal the request data into v
+// Unmarshal the request data into v. Errors are Invalid, since they're caused
+// by malformed requests.
 func Unmarshal(r *http.Request, v interface{}) error {
 	err := unmarshalBody(r, v)
 	if err != nil {
-		return err
+		return errors.Invalid.Wrap(err)
 	}
 	err = unmarshalURL(r.URL, v)
 	if err != nil {
-		return err
+		return errors.Invalid.Wrap(err)
 	}
 	return nil
 }
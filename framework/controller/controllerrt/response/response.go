@@ -3,10 +3,16 @@ package response
 import (
 	"encoding/json"
 	"fmt"
+	"html"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
 	"path"
+	"strings"
 
 	"github.com/livebud/bud/framework/controller/controllerrt/request"
+	"github.com/livebud/bud/package/errors"
 )
 
 // Format returns different responses depending on the Accepts request header
@@ -30,6 +36,14 @@ func (f *Format) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Cleanup calls cleanup after the handler serves the request
+func Cleanup(handler http.Handler, cleanup func()) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer cleanup()
+		handler.ServeHTTP(w, r)
+	})
+}
+
 // Response struct
 type Response struct {
 	status  int
@@ -87,6 +101,11 @@ func JSON(props interface{}) http.Handler {
 	return response.JSON(props)
 }
 
+// linker is implemented by paginated results
+type linker interface {
+	Link(u *url.URL) string
+}
+
 // JSON responds with a JSON response.
 func (res *Response) JSON(props interface{}) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -97,6 +116,12 @@ func (res *Response) JSON(props interface{}) http.Handler {
 		}
 		// Override any existing content types
 		header.Set("Content-Type", "application/json")
+		// Link to the other pages of paginated results
+		if linker, ok := props.(linker); ok {
+			if link := linker.Link(r.URL); link != "" {
+				header.Set("Link", link)
+			}
+		}
 		// Marshal the JSON response
 		result, err := json.Marshal(props)
 		if err != nil {
@@ -143,6 +168,97 @@ func (res *Response) HTML(body string) http.Handler {
 	})
 }
 
+// Render serves the handler with the response's status and headers. It's used
+// to re-render a view with a different status, such as a form with errors.
+func (res *Response) Render(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Attach all preset headers
+		header := w.Header()
+		for key, value := range res.headers {
+			header.Set(key, value)
+		}
+		handler.ServeHTTP(&statusWriter{w, res.status}, r)
+	})
+}
+
+// statusWriter overrides the status written by the wrapped handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		status = w.status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Error responds with the status code of the error's kind. Browsers get an
+// error page, while everyone else gets JSON like {"error":"..."}. Internal
+// error messages are left off of the error page.
+func Error(err error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kind := errors.KindOf(err)
+		status := kind.Status()
+		// Only browsers ask for HTML explicitly
+		if strings.Contains(r.Header.Get("Accept"), "text/html") {
+			message := ""
+			if kind != errors.Internal {
+				message = err.Error()
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			w.Write([]byte(errorPage(status, message)))
+			return
+		}
+		body, _ := json.Marshal(map[string]string{"error": err.Error()})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	})
+}
+
+func errorPage(status int, message string) string {
+	title := fmt.Sprintf("%d %s", status, http.StatusText(status))
+	body := "<h1>" + title + "</h1>"
+	if message != "" {
+		body += "\n\t\t<p>" + html.EscapeString(message) + "</p>"
+	}
+	return `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8"/>
+		<title>` + title + `</title>
+	</head>
+	<body>
+		` + body + `
+	</body>
+</html>
+`
+}
+
+// hotAddr is where bud run is listening for live reloads. Bud run may pick
+// another address when 127.0.0.1:35729 is taken, so it's passed in through
+// $BUD_LISTEN.
+var hotAddr = budAddr(os.Getenv("BUD_LISTEN"))
+
+// budAddr returns the address that browsers reach bud on. Servers listening on
+// every interface are reached on 127.0.0.1.
+func budAddr(address string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		address = u.Host
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "127.0.0.1:35729"
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
 // TODO: make hot reload configurable
 func wrapHTML(body string) string {
 	return `
@@ -154,9 +270,9 @@ func wrapHTML(body string) string {
 		<body>
 			` + body + `
 			<script>
-				// TODO: host should be dynamic
-				const sse = new EventSource("http://127.0.0.1:35729/bud/hot")
-				sse.addEventListener("message", () => { location.reload() })
+				const sse = new EventSource("http://` + hotAddr + `/bud/events?topics=app:ready,build:error")
+				sse.addEventListener("app:ready", () => { location.reload() })
+				sse.addEventListener("build:error", () => { location.reload() })
 			</script>
 		</body>
 		</html>`
@@ -14,6 +14,7 @@ import (
 
 	"github.com/livebud/bud/internal/bail"
 	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/package/authz"
 	"github.com/livebud/bud/package/di"
 	"github.com/livebud/bud/package/gomod"
 	"github.com/livebud/bud/package/parser"
@@ -47,6 +48,7 @@ type loader struct {
 	providers *providerSet
 	module    *gomod.Module
 	parser    *parser.Parser
+	policy    string // Import name of the app's policy package, once loaded
 }
 
 // load fn
@@ -133,8 +135,14 @@ func (l *loader) loadControllerRoute(controllerPath string) string {
 
 func (l *loader) loadActions(controller *Controller, stct *parser.Struct) (actions []*Action) {
 	var usesResponse bool
+	// Permissions on the controller apply to every action
+	permissions := loadPermissions(stct.Directives())
 	for _, method := range stct.PublicMethods() {
 		action := l.loadAction(controller, method)
+		action.Permissions = append(append([]string{}, permissions...), loadPermissions(method.Directives())...)
+		if len(action.Permissions) > 0 {
+			action.Policy = l.loadPolicy(action)
+		}
 		if !action.HandlerFunc {
 			usesResponse = true
 		}
@@ -165,6 +173,7 @@ func (l *loader) loadAction(controller *Controller, method *parser.Function) *Ac
 	action.Key = l.loadActionKey(controller.Path, action.Name)
 	action.View = l.loadView(controller.Path, action.Key, action.Route)
 	action.Method = l.loadActionMethod(action.Name)
+	action.FormView = l.loadFormView(controller, action.Name)
 	params := method.Params()
 	results := method.Results()
 	action.HandlerFunc = l.isHandlerFunc(params, results)
@@ -180,6 +189,41 @@ func (l *loader) loadAction(controller *Controller, method *parser.Function) *Ac
 	return action
 }
 
+// loadPermissions from //authz:require directives
+func loadPermissions(directives []string) (permissions []string) {
+	for _, directive := range directives {
+		if perms, ok := authz.Parse(directive); ok {
+			permissions = append(permissions, perms...)
+		}
+	}
+	return permissions
+}
+
+// loadPolicy loads the app's policy package, which resolves the permissions
+// that actions require
+func (l *loader) loadPolicy(action *Action) string {
+	if l.policy != "" {
+		return l.policy
+	}
+	if _, err := fs.Stat(l.fsys, "policy"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			l.Bail(fmt.Errorf("controller: %s requires permissions, but policy/ doesn't exist. Add a Policy struct with an Allow(ctx context.Context, permission string) (bool, error) method", action.Key))
+		}
+		l.Bail(err)
+	}
+	pkg, err := l.parser.Parse("policy")
+	if err != nil {
+		l.Bail(err)
+	}
+	stct := pkg.Struct("Policy")
+	if stct == nil || stct.Method("Allow") == nil {
+		l.Bail(fmt.Errorf("controller: expected policy.Policy to have an Allow(ctx context.Context, permission string) (bool, error) method"))
+	}
+	l.imports.AddNamed("authz", "github.com/livebud/bud/package/authz")
+	l.policy = l.imports.AddNamed("policy", l.module.Import("policy"))
+	return l.policy
+}
+
 func (l *loader) loadActionKey(controllerPath, actionName string) string {
 	return path.Join(controllerPath, text.Lower(text.Snake(actionName)))
 }
@@ -212,6 +256,25 @@ func (l *loader) isHandlerFunc(params []*parser.Param, results []*parser.Result)
 	return true
 }
 
+// loadFormView finds the view with the form that submits to the action. When
+// the input is invalid, the form is rendered again with the errors.
+func (l *loader) loadFormView(controller *Controller, actionName string) *View {
+	var formAction string
+	switch actionName {
+	case "Create":
+		formAction = "New"
+	case "Update":
+		formAction = "Edit"
+	default:
+		return nil
+	}
+	return l.loadView(
+		controller.Path,
+		l.loadActionKey(controller.Path, formAction),
+		l.loadActionRoute(controller.Route, formAction),
+	)
+}
+
 // Route to the action
 func (l *loader) loadActionRoute(controllerRoute, actionName string) string {
 	switch actionName {
@@ -277,6 +340,7 @@ func (l *loader) loadActionParams(params []*parser.Param) (inputs []*ActionParam
 	}
 	if len(inputs) > 0 {
 		l.imports.Add("github.com/livebud/bud/framework/controller/controllerrt/request")
+		l.imports.Add("github.com/livebud/bud/package/validate")
 	}
 	return inputs
 }
@@ -522,10 +586,13 @@ func (l *loader) loadProvider(controller *Controller, method *parser.Function) *
 				Import: importPath,
 				Type:   recv.Type().String(),
 			},
+			&di.Cleanup{},
 			&di.Error{},
 		},
 		Params: []*di.Param{
 			{Import: "context", Type: "Context", Hoist: true},
+			{Import: "github.com/livebud/bud/package/log", Type: "Interface", Hoist: true},
+			{Import: "github.com/livebud/bud/package/reload", Type: "*Reloader", Hoist: true},
 			{Import: "net/http", Type: "*Request"},
 			{Import: "net/http", Type: "ResponseWriter"},
 		},
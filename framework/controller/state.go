@@ -46,6 +46,7 @@ type Action struct {
 	Camel       string
 	Short       string
 	View        *View
+	FormView    *View  // Form to re-render when the input is invalid
 	Key         string // Key is an extension-less path
 	Route       string // Route to this action
 	Redirect    string
@@ -58,6 +59,17 @@ type Action struct {
 	RespondJSON bool
 	RespondHTML bool
 	PropsKey    string
+	Permissions []string // Permissions the policy must allow
+	Policy      string   // Import name of the app's policy package
+}
+
+// PermissionArgs are the permissions as arguments to authz.Check
+func (a *Action) PermissionArgs() string {
+	args := make([]string, len(a.Permissions))
+	for i, permission := range a.Permissions {
+		args[i] = strconv.Quote(permission)
+	}
+	return strings.Join(args, ", ")
 }
 
 // View struct
@@ -0,0 +1,46 @@
+package db
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed db.gotext
+var template string
+
+var generator = gotemplate.MustParse("framework/db/db.gotext", template)
+
+// Generate the db package from state
+func Generate(state *State) ([]byte, error) {
+	return generator.Generate(state)
+}
+
+// New db generator
+func New(module *gomod.Module, parser *parser.Parser) *Generator {
+	return &Generator{module, parser}
+}
+
+// Generator for the db package. Each struct in model/ with a primary key gets
+// a table with typed queries.
+type Generator struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := Load(fsys, g.module, g.parser)
+	if err != nil {
+		return fmt.Errorf("framework/db: unable to load. %w", err)
+	}
+	code, err := Generate(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
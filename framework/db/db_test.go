@@ -0,0 +1,661 @@
+package db_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestNoModels(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/package/db"))
+}
+
+func TestNoPrimaryKey(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/visit.go"] = `
+		package model
+		type Visit struct {
+			Path string
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/package/db"))
+}
+
+func TestControllerQueries(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID    int    ` + "`json:\"id\"`" + `
+			Name  string ` + "`json:\"name\"`" + `
+			Email string ` + "`json:\"email\" db:\"email_address\"`" + `
+		}
+	`
+	td.Files["controller/users/controller.go"] = `
+		package users
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context) ([]*model.User, error) {
+			return c.DB.User.FindMany(ctx)
+		}
+		func (c *Controller) Show(ctx context.Context, id int) (*model.User, error) {
+			return c.DB.User.Find(ctx, id)
+		}
+		func (c *Controller) Create(ctx context.Context, name, email string) (*model.User, error) {
+			user := &model.User{Name: name, Email: email}
+			if err := c.DB.User.Insert(ctx, user); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	_, err = conn.Exec(`CREATE TABLE users (id integer primary key, name text not null, email_address text not null)`)
+	is.NoErr(err)
+	is.NoErr(conn.Close())
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/package/db/db.go"))
+	res, err := app.PostJSON("/users", bytes.NewBufferString(`{"name":"Alice","email":"alice@livebud.com"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"name":"Alice","email":"alice@livebud.com"}
+	`))
+	res, err = app.GetJSON("/users")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		[{"id":1,"name":"Alice","email":"alice@livebud.com"}]
+	`))
+	res, err = app.GetJSON("/users/1")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"name":"Alice","email":"alice@livebud.com"}
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestFailedActionRollsBack(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID   int    ` + "`json:\"id\"`" + `
+			Name string ` + "`json:\"name\"`" + `
+		}
+	`
+	td.Files["controller/users/controller.go"] = `
+		package users
+		import (
+			"context"
+			"errors"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Create(ctx context.Context, name string) (*model.User, error) {
+			user := &model.User{Name: name}
+			if err := c.DB.User.Insert(ctx, user); err != nil {
+				return nil, err
+			}
+			return nil, errors.New("unable to send welcome email")
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE users (id integer primary key, name text not null)`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.PostJSON("/users", bytes.NewBufferString(`{"name":"Alice"}`))
+	is.NoErr(err)
+	is.Equal(res.Status(), 500)
+	is.NoErr(app.Close())
+	// The insert was rolled back along with the failed request
+	var count int
+	is.NoErr(conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count))
+	is.Equal(count, 0)
+}
+
+func TestQueries(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID    int    ` + "`json:\"id\"`" + `
+			Name  string ` + "`json:\"name\"`" + `
+			Email string ` + "`json:\"email\"`" + `
+		}
+	`
+	td.Files["query/users.sql"] = `
+		-- name: FindUserByEmail :one
+		SELECT * FROM users WHERE email = @email;
+
+		-- name: CountUsers :one
+		SELECT count(*) AS total FROM users;
+	`
+	td.Files["controller/users/controller.go"] = `
+		package users
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context) (*db.CountUsersRow, error) {
+			return c.DB.CountUsers(ctx)
+		}
+		func (c *Controller) Show(ctx context.Context, email string) (*model.User, error) {
+			return c.DB.FindUserByEmail(ctx, email)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE users (id integer primary key, name text not null, email text not null)`)
+	is.NoErr(err)
+	_, err = conn.Exec(`INSERT INTO users (name, email) VALUES ('Alice', 'alice@livebud.com')`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/package/db/query.go"))
+	res, err := app.GetJSON("/users/alice@livebud.com")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"name":"Alice","email":"alice@livebud.com"}
+	`))
+	res, err = app.GetJSON("/users")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"total":1}
+	`))
+	// Changing the query regenerates the db package
+	is.NoErr(os.WriteFile(filepath.Join(dir, "query", "users.sql"), []byte(`
+		-- name: FindUserByEmail :one
+		SELECT * FROM users WHERE email = @email;
+
+		-- name: CountUsers :one
+		SELECT count(*) AS total FROM users WHERE name != 'Alice';
+	`), 0644))
+	readyCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	is.NoErr(app.Ready(readyCtx))
+	cancel()
+	res, err = app.GetJSON("/users")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"total":0}
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestQueryMissingType(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID   int
+			Name string
+		}
+	`
+	td.Files["query/users.sql"] = `
+		-- name: LongestName :one
+		SELECT max(length(name)) AS longest FROM users;
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), `unable to infer the type of column "longest" in LongestName. Add a "-- column: longest <type>" hint to query/users.sql`)
+}
+
+func TestQueryModelImports(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		import "time"
+		type User struct {
+			ID        int
+			Email     string
+			CreatedAt time.Time
+		}
+	`
+	// Queries that return the model don't need to import time
+	td.Files["query/users.sql"] = `
+		-- name: FindUserByEmail :one
+		SELECT * FROM users WHERE email = @email;
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.NoErr(td.Exists("bud/package/db/query.go"))
+}
+
+func TestHealth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID   int    ` + "`json:\"id\"`" + `
+			Name string ` + "`json:\"name\"`" + `
+		}
+	`
+	td.Files["controller/users/controller.go"] = `
+		package users
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context) ([]*model.User, error) {
+			return c.DB.User.FindMany(ctx)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = "sqlite://" + filepath.Join(dir, "app.db")
+	cli.Env["DATABASE_MAX_OPEN_CONNS"] = "7"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.Get("/bud/health")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.In(res.Body().String(), `"status":"ok"`)
+	// The pool statistics are only on the internal listener
+	is.NotIn(res.Body().String(), `"max_open_connections"`)
+	is.NoErr(app.Close())
+}
+
+func TestConventions(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/post.go"] = `
+		package model
+		import "time"
+		type Post struct {
+			ID        int        ` + "`json:\"id\"`" + `
+			Title     string     ` + "`json:\"title\"`" + `
+			CreatedAt time.Time  ` + "`json:\"-\"`" + `
+			UpdatedAt time.Time  ` + "`json:\"-\"`" + `
+			DeletedAt *time.Time ` + "`json:\"-\"`" + `
+			Version   int        ` + "`json:\"version\"`" + `
+		}
+	`
+	td.Files["controller/posts/controller.go"] = `
+		package posts
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context) ([]*model.Post, error) {
+			return c.DB.Post.FindMany(ctx)
+		}
+		func (c *Controller) Create(ctx context.Context, title string) (*model.Post, error) {
+			post := &model.Post{Title: title}
+			if err := c.DB.Post.Insert(ctx, post); err != nil {
+				return nil, err
+			}
+			return post, nil
+		}
+		func (c *Controller) Update(ctx context.Context, id int, title string, version int) (*model.Post, error) {
+			post, err := c.DB.Post.Find(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			post.Title = title
+			post.Version = version
+			if err := c.DB.Post.Update(ctx, post); err != nil {
+				return nil, err
+			}
+			return post, nil
+		}
+		func (c *Controller) Delete(ctx context.Context, id int) error {
+			return c.DB.Post.Delete(ctx, id)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE posts (id integer primary key, title text not null, created_at datetime not null, updated_at datetime not null, deleted_at datetime, version integer not null)`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.PostJSON("/posts", bytes.NewBufferString(`{"title":"a"}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"title":"a","version":1}
+	`))
+	res, err = app.PatchJSON("/posts/1", bytes.NewBufferString(`{"title":"b","version":1}`))
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"title":"b","version":2}
+	`))
+	// Updating a stale version conflicts
+	res, err = app.PatchJSON("/posts/1", bytes.NewBufferString(`{"title":"c","version":1}`))
+	is.NoErr(err)
+	is.Equal(res.Status(), 500)
+	is.In(res.Body().String(), "row was changed by another update")
+	res, err = app.DeleteJSON("/posts/1", nil)
+	is.NoErr(err)
+	is.Equal(res.Status(), 204)
+	res, err = app.GetJSON("/posts")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		null
+	`))
+	is.NoErr(app.Close())
+	// The row is soft deleted with its timestamps set
+	var createdAt, updatedAt, deletedAt string
+	is.NoErr(conn.QueryRow(`SELECT created_at, updated_at, deleted_at FROM posts WHERE id = 1`).Scan(&createdAt, &updatedAt, &deletedAt))
+	is.True(createdAt != "")
+	is.True(updatedAt != "")
+	is.True(deletedAt != "")
+}
+
+func TestTenant(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/post.go"] = `
+		package model
+		type Post struct {
+			ID       int    ` + "`json:\"id\"`" + `
+			TenantID string ` + "`json:\"tenant_id\"`" + `
+			Title    string ` + "`json:\"title\"`" + `
+		}
+	`
+	td.Files["query/posts.sql"] = `
+		-- name: FindPostsByTitle :many
+		SELECT * FROM posts WHERE tenant_id = @tenant_id AND title = @title;
+	`
+	td.Files["controller/posts/controller.go"] = `
+		package posts
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context, title string) ([]*model.Post, error) {
+			if title != "" {
+				return c.DB.FindPostsByTitle(ctx, title)
+			}
+			return c.DB.Post.FindMany(ctx)
+		}
+		func (c *Controller) Show(ctx context.Context, id int) (*model.Post, error) {
+			return c.DB.Post.Find(ctx, id)
+		}
+		func (c *Controller) Create(ctx context.Context, title string) (*model.Post, error) {
+			post := &model.Post{Title: title}
+			if err := c.DB.Post.Insert(ctx, post); err != nil {
+				return nil, err
+			}
+			return post, nil
+		}
+		func (c *Controller) Delete(ctx context.Context, id int) error {
+			return c.DB.Post.Delete(ctx, id)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE posts (id integer primary key, tenant_id text not null, title text not null)`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	cli.Env["TENANT_FROM"] = "header"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	request := func(method, path, tenant string) *testcli.Response {
+		req, err := http.NewRequest(method, "http://host"+path, nil)
+		is.NoErr(err)
+		req.Header.Set("Accept", "application/json")
+		if tenant != "" {
+			req.Header.Set("X-Tenant", tenant)
+		}
+		res, err := app.Do(req)
+		is.NoErr(err)
+		return res
+	}
+	res := request(http.MethodPost, "/posts?title=a", "acme")
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":1,"tenant_id":"acme","title":"a"}
+	`))
+	res = request(http.MethodPost, "/posts?title=b", "other")
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		{"id":2,"tenant_id":"other","title":"b"}
+	`))
+	// Tenants only see their own rows
+	res = request(http.MethodGet, "/posts", "acme")
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		[{"id":1,"tenant_id":"acme","title":"a"}]
+	`))
+	res = request(http.MethodGet, "/posts?title=a", "other")
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		null
+	`))
+	res = request(http.MethodGet, "/posts/1", "other")
+	is.Equal(res.Status(), 500)
+	is.In(res.Body().String(), "no rows in result set")
+	res = request(http.MethodDelete, "/posts/1", "other")
+	is.Equal(res.Status(), 500)
+	// Requests without a tenant can't query the table
+	res = request(http.MethodGet, "/posts", "")
+	is.Equal(res.Status(), 500)
+	is.In(res.Body().String(), "request doesn't have a tenant")
+	is.NoErr(app.Close())
+	var count int
+	is.NoErr(conn.QueryRow(`SELECT count(*) FROM posts`).Scan(&count))
+	is.Equal(count, 2)
+}
+
+func TestConventionType(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/post.go"] = `
+		package model
+		type Post struct {
+			ID        int
+			CreatedAt string
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "expected Post.CreatedAt to be a time.Time")
+}
+
+func TestPagination(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/post.go"] = `
+		package model
+		type Post struct {
+			ID    int    ` + "`json:\"id\"`" + `
+			Title string ` + "`json:\"title\"`" + `
+		}
+	`
+	td.Files["controller/posts/controller.go"] = `
+		package posts
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"github.com/livebud/bud/framework/db/dbrt"
+		)
+		type Controller struct {
+			DB *db.DB
+		}
+		func (c *Controller) Index(ctx context.Context, page, limit int, after string) (*db.PostPage, error) {
+			return c.DB.Post.FindPage(ctx, dbrt.Page{Number: page, Limit: limit, After: after})
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE posts (id integer primary key, title text not null)`)
+	is.NoErr(err)
+	_, err = conn.Exec(`INSERT INTO posts (title) VALUES ('a'), ('b'), ('c')`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/posts?limit=2")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+		Link: </posts?limit=2&page=2>; rel="next", </posts?limit=2&page=1>; rel="first", </posts?limit=2&page=2>; rel="last"
+
+		{"data":[{"id":1,"title":"a"},{"id":2,"title":"b"}],"pagination":{"page":1,"limit":2,"total":3,"next_page":2}}
+	`))
+	res, err = app.GetJSON("/posts?limit=2&page=2")
+	is.NoErr(err)
+	is.In(res.Body().String(), `{"data":[{"id":3,"title":"c"}],"pagination":{"page":2,"limit":2,"total":3}}`)
+	// Cursor pagination
+	cursor, err := dbrt.EncodeCursor(1)
+	is.NoErr(err)
+	res, err = app.GetJSON("/posts?limit=1&after=" + cursor)
+	is.NoErr(err)
+	is.In(res.Body().String(), `{"data":[{"id":2,"title":"b"}]`)
+	is.In(res.Header("Link"), `rel="next"`)
+	is.NoErr(app.Close())
+}
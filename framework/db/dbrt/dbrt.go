@@ -0,0 +1,160 @@
+package dbrt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+
+	// Drivers that are supported out of the box
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Dialect is the SQL dialect spoken by the database
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+// system names the database in traces
+func (d Dialect) system() string {
+	switch d {
+	case Postgres:
+		return "postgresql"
+	case SQLite:
+		return "sqlite"
+	default:
+		return string(d)
+	}
+}
+
+// Rebind replaces the ? placeholders in query with the dialect's placeholders.
+// Question marks within quoted strings are left alone.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+	out := new(strings.Builder)
+	quoted := false
+	n := 0
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			out.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// Queryer is implemented by both *sql.DB and *sql.Tx
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var _ Queryer = (*sql.DB)(nil)
+var _ Queryer = (*sql.Tx)(nil)
+
+// Scanner is implemented by both *sql.Row and *sql.Rows
+type Scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// Open a database from a URL. Postgres URLs start with postgres:// and SQLite
+// URLs start with sqlite://, followed by the path to the database file.
+func Open(databaseURL string) (*DB, error) {
+	dialect, dsn, err := parseURL(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open(string(dialect), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("dbrt: unable to open %s database. %w", dialect, err)
+	}
+	return &DB{db, dialect, clock.Load()}, nil
+}
+
+func parseURL(databaseURL string) (dialect Dialect, dsn string, err error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return "", "", fmt.Errorf("dbrt: unable to parse database url. %w", err)
+	}
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return Postgres, databaseURL, nil
+	case "sqlite", "sqlite3":
+		// Everything after the scheme is passed through to the driver
+		dsn := strings.TrimPrefix(databaseURL, u.Scheme+"://")
+		if dsn == "" {
+			return "", "", fmt.Errorf("dbrt: missing path in sqlite url %q", databaseURL)
+		}
+		return SQLite, dsn, nil
+	default:
+		return "", "", fmt.Errorf("dbrt: unsupported database url scheme %q", u.Scheme)
+	}
+}
+
+// DB wraps *sql.DB with the dialect that's used to build queries and the clock
+// that's used for timestamps
+type DB struct {
+	*sql.DB
+	dialect Dialect
+	clock   clock.Clock
+}
+
+// New wraps an existing connection
+func New(db *sql.DB, dialect Dialect) *DB {
+	return &DB{db, dialect, clock.Load()}
+}
+
+// UseClock sets the clock that fills in timestamp columns. Tests can freeze
+// timestamps with clock.Freeze.
+func (db *DB) UseClock(clock clock.Clock) {
+	db.clock = clock
+}
+
+// Now returns the current time on the database's clock for timestamp columns
+func (db *DB) Now() time.Time {
+	return Timestamp(db.clock.Now())
+}
+
+// Dialect of the database
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// IsZero returns true if v is the zero value for its type. It's used to decide
+// whether the database should fill in the primary key on insert.
+func IsZero(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	return reflect.ValueOf(v).IsZero()
+}
+
+// Now returns the current time for timestamp columns
+func Now() time.Time {
+	return Timestamp(time.Now())
+}
+
+// Timestamp converts t for a timestamp column. It's in UTC and truncated to
+// microseconds, the precision that Postgres stores, so the model matches
+// what's read back from the database.
+func Timestamp(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,56 @@
+package dbrt_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestRebind(t *testing.T) {
+	is := is.New(t)
+	query := `SELECT * FROM "users" WHERE "name" = ? AND "bio" != '?' AND "id" = ?`
+	is.Equal(dbrt.Postgres.Rebind(query), `SELECT * FROM "users" WHERE "name" = $1 AND "bio" != '?' AND "id" = $2`)
+	is.Equal(dbrt.SQLite.Rebind(query), query)
+}
+
+func TestOpenSQLite(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	is.Equal(db.Dialect(), dbrt.SQLite)
+	_, err = db.Exec(`CREATE TABLE users (id integer primary key, name text)`)
+	is.NoErr(err)
+	var id int
+	err = db.QueryRow(db.Dialect().Rebind(`INSERT INTO users (name) VALUES (?) RETURNING id`), "Alice").Scan(&id)
+	is.NoErr(err)
+	is.Equal(id, 1)
+}
+
+func TestOpenPostgres(t *testing.T) {
+	is := is.New(t)
+	// Opening doesn't connect, so this works without a running database
+	db, err := dbrt.Open("postgres://localhost:5432/app?sslmode=disable")
+	is.NoErr(err)
+	defer db.Close()
+	is.Equal(db.Dialect(), dbrt.Postgres)
+}
+
+func TestOpenUnsupported(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("mysql://localhost:3306/app")
+	is.True(err != nil)
+	is.Equal(db, nil)
+	is.In(err.Error(), `unsupported database url scheme "mysql"`)
+}
+
+func TestIsZero(t *testing.T) {
+	is := is.New(t)
+	is.True(dbrt.IsZero(0))
+	is.True(dbrt.IsZero(""))
+	is.True(dbrt.IsZero(nil))
+	is.True(!dbrt.IsZero(1))
+	is.True(!dbrt.IsZero("a"))
+}
@@ -0,0 +1,100 @@
+package dbrt
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/livebud/bud/package/bud"
+)
+
+// Health of the database and its connection pool
+type Health struct {
+	Status string     `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	Pool   *PoolStats `json:"pool"`
+}
+
+// PoolStats are the connection pool statistics
+type PoolStats struct {
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	WaitCount          int64  `json:"wait_count"`
+	WaitDuration       string `json:"wait_duration"`
+	MaxIdleClosed      int64  `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64  `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64  `json:"max_lifetime_closed"`
+}
+
+// PoolStats returns the current connection pool statistics
+func (db *DB) PoolStats() *PoolStats {
+	stats := db.DB.Stats()
+	return &PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration.String(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// Health pings the database and reports the pool statistics
+func (db *DB) Health(ctx context.Context) *Health {
+	health := &Health{Status: "ok"}
+	if err := db.DB.PingContext(ctx); err != nil {
+		health.Status = "unavailable"
+		health.Error = err.Error()
+	}
+	health.Pool = db.PoolStats()
+	return health
+}
+
+// HealthHandler responds with the database's status for load balancers and
+// uptime checks. It responds with 503 when the database can't be reached. The
+// error and pool statistics are left to HealthDetailsHandler, since they can
+// reveal the database's host and user.
+func (db *DB) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := db.check(r.Context())
+		writeJSON(w, health.Status, map[string]interface{}{
+			"status": health.Status,
+		})
+	})
+}
+
+// HealthDetailsHandler responds with the database's health, including the
+// ping error and pool statistics, and the app's version. Serve it on the
+// internal listener alongside /metrics.
+func (db *DB) HealthDetailsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := db.check(r.Context())
+		writeJSON(w, health.Status, map[string]interface{}{
+			"status":   health.Status,
+			"database": health,
+			"version":  bud.Version(),
+		})
+	})
+}
+
+// check the database's health, giving up after 5 seconds
+func (db *DB) check(ctx context.Context) *Health {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return db.Health(ctx)
+}
+
+func writeJSON(w http.ResponseWriter, status string, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,71 @@
+package dbrt_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/bud"
+)
+
+func TestHealth(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	rec := httptest.NewRecorder()
+	db.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "application/json")
+	is.Equal(strings.TrimSpace(rec.Body.String()), `{"status":"ok"}`)
+}
+
+func TestHealthDetails(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	db.Configure(&dbrt.Pool{MaxOpenConns: 4})
+	rec := httptest.NewRecorder()
+	db.HealthDetailsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "application/json")
+	var body struct {
+		Status   string
+		Database *dbrt.Health
+		Version  *bud.Info
+	}
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &body))
+	is.Equal(body.Status, "ok")
+	is.Equal(body.Database.Pool.MaxOpenConnections, 4)
+	is.Equal(body.Database.Pool.OpenConnections, 1)
+	is.Equal(body.Version.Go, runtime.Version())
+}
+
+func TestHealthUnavailable(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "missing", "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	rec := httptest.NewRecorder()
+	db.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 503)
+	// The error stays off of the public endpoint
+	is.Equal(strings.TrimSpace(rec.Body.String()), `{"status":"unavailable"}`)
+	rec = httptest.NewRecorder()
+	db.HealthDetailsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 503)
+	var body struct {
+		Status   string
+		Database *dbrt.Health
+	}
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &body))
+	is.Equal(body.Status, "unavailable")
+	is.True(body.Database.Error != "")
+}
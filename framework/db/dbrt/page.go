@@ -0,0 +1,118 @@
+package dbrt
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultLimit is the number of rows per page when the limit isn't set
+	DefaultLimit = 20
+	// MaxLimit is the most rows a page can have
+	MaxLimit = 100
+)
+
+// Page selects a page of rows. Pages are selected by number unless After is
+// set, in which case the page starts after the row with that cursor. Cursors
+// stay stable while rows are inserted, so prefer them for feeds.
+type Page struct {
+	Number int    // Page number, starting at 1
+	Limit  int    // Rows per page
+	After  string // Cursor of the last row on the previous page
+}
+
+// Size returns the number of rows on the page, within [1, MaxLimit]
+func (p Page) Size() int {
+	switch {
+	case p.Limit <= 0:
+		return DefaultLimit
+	case p.Limit > MaxLimit:
+		return MaxLimit
+	default:
+		return p.Limit
+	}
+}
+
+// Offset returns the number of rows before the page
+func (p Page) Offset() int {
+	if p.Number <= 1 {
+		return 0
+	}
+	return (p.Number - 1) * p.Size()
+}
+
+// PageInfo describes a page of results
+type PageInfo struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	NextPage   int    `json:"next_page,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// LastPage returns the number of the last page
+func (p *PageInfo) LastPage() int {
+	if p.Total == 0 {
+		return 1
+	}
+	return int((p.Total + int64(p.Limit) - 1) / int64(p.Limit))
+}
+
+// Link returns the Link header for the page, relative to u. The header has
+// next, prev, first and last links for numbered pages and a next link for
+// cursor pages.
+func (p *PageInfo) Link(u *url.URL) string {
+	var links []string
+	link := func(rel string, set map[string]string) {
+		query := u.Query()
+		query.Del("page")
+		query.Del("after")
+		for key, value := range set {
+			query.Set(key, value)
+		}
+		next := *u
+		next.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", next.String(), rel))
+	}
+	// Cursor pagination only moves forward
+	if p.Page == 0 {
+		if p.NextCursor != "" {
+			link("next", map[string]string{"after": p.NextCursor})
+		}
+		return strings.Join(links, ", ")
+	}
+	if p.NextPage != 0 {
+		link("next", map[string]string{"page": strconv.Itoa(p.NextPage)})
+	}
+	if p.Page > 1 {
+		link("prev", map[string]string{"page": strconv.Itoa(p.Page - 1)})
+	}
+	link("first", map[string]string{"page": "1"})
+	link("last", map[string]string{"page": strconv.Itoa(p.LastPage())})
+	return strings.Join(links, ", ")
+}
+
+// EncodeCursor encodes a row's key into an opaque cursor
+func EncodeCursor(key interface{}) (string, error) {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("dbrt: unable to encode cursor. %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor decodes a cursor into the key it was encoded from
+func DecodeCursor(cursor string, key interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("dbrt: invalid cursor %q. %w", cursor, err)
+	}
+	if err := json.Unmarshal(data, key); err != nil {
+		return fmt.Errorf("dbrt: invalid cursor %q. %w", cursor, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package dbrt_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestPageSize(t *testing.T) {
+	is := is.New(t)
+	is.Equal(dbrt.Page{}.Size(), dbrt.DefaultLimit)
+	is.Equal(dbrt.Page{Limit: 1000}.Size(), dbrt.MaxLimit)
+	is.Equal(dbrt.Page{Number: 3, Limit: 10}.Offset(), 20)
+	is.Equal(dbrt.Page{Number: 0, Limit: 10}.Offset(), 0)
+}
+
+func TestPageLink(t *testing.T) {
+	is := is.New(t)
+	u, err := url.Parse("/posts?limit=10&page=2&sort=title")
+	is.NoErr(err)
+	info := &dbrt.PageInfo{Page: 2, Limit: 10, Total: 35, NextPage: 3}
+	is.Equal(info.LastPage(), 4)
+	is.Equal(info.Link(u), `</posts?limit=10&page=3&sort=title>; rel="next", </posts?limit=10&page=1&sort=title>; rel="prev", </posts?limit=10&page=1&sort=title>; rel="first", </posts?limit=10&page=4&sort=title>; rel="last"`)
+	// Cursor pages only link forward
+	info = &dbrt.PageInfo{Limit: 10, Total: 35, NextCursor: "MTA"}
+	is.Equal(info.Link(u), `</posts?after=MTA&limit=10&sort=title>; rel="next"`)
+	info = &dbrt.PageInfo{Limit: 10, Total: 35}
+	is.Equal(info.Link(u), "")
+}
+
+func TestCursor(t *testing.T) {
+	is := is.New(t)
+	cursor, err := dbrt.EncodeCursor(42)
+	is.NoErr(err)
+	var key int
+	is.NoErr(dbrt.DecodeCursor(cursor, &key))
+	is.Equal(key, 42)
+	err = dbrt.DecodeCursor("not a cursor", &key)
+	is.True(err != nil)
+}
@@ -0,0 +1,79 @@
+package dbrt
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Pool configures the connection pool. Zero values keep database/sql's
+// defaults.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// LoadPool reads the pool configuration from the environment:
+//
+//	DATABASE_MAX_OPEN_CONNS=20
+//	DATABASE_MAX_IDLE_CONNS=5
+//	DATABASE_CONN_MAX_LIFETIME=30m
+//	DATABASE_CONN_MAX_IDLE_TIME=5m
+func LoadPool(getenv func(key string) string) (pool *Pool, err error) {
+	pool = new(Pool)
+	if pool.MaxOpenConns, err = envInt(getenv, "DATABASE_MAX_OPEN_CONNS"); err != nil {
+		return nil, err
+	}
+	if pool.MaxIdleConns, err = envInt(getenv, "DATABASE_MAX_IDLE_CONNS"); err != nil {
+		return nil, err
+	}
+	if pool.ConnMaxLifetime, err = envDuration(getenv, "DATABASE_CONN_MAX_LIFETIME"); err != nil {
+		return nil, err
+	}
+	if pool.ConnMaxIdleTime, err = envDuration(getenv, "DATABASE_CONN_MAX_IDLE_TIME"); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func envInt(getenv func(string) string, key string) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("dbrt: expected %s to be a positive number, got %q", key, value)
+	}
+	return n, nil
+}
+
+func envDuration(getenv func(string) string, key string) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("dbrt: expected %s to be a duration like 5m, got %q", key, value)
+	}
+	return d, nil
+}
+
+// Configure the connection pool
+func (db *DB) Configure(pool *Pool) {
+	if pool.MaxOpenConns > 0 {
+		db.DB.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.DB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.DB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.DB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
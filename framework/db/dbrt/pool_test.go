@@ -0,0 +1,39 @@
+package dbrt_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestLoadPool(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{
+		"DATABASE_MAX_OPEN_CONNS":    "20",
+		"DATABASE_CONN_MAX_LIFETIME": "30m",
+	}
+	pool, err := dbrt.LoadPool(func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(pool.MaxOpenConns, 20)
+	is.Equal(pool.MaxIdleConns, 0)
+	is.Equal(pool.ConnMaxLifetime, 30*time.Minute)
+	is.Equal(pool.ConnMaxIdleTime, time.Duration(0))
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	db.Configure(pool)
+	is.Equal(db.PoolStats().MaxOpenConnections, 20)
+}
+
+func TestLoadPoolInvalid(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{
+		"DATABASE_CONN_MAX_IDLE_TIME": "5",
+	}
+	_, err := dbrt.LoadPool(func(key string) string { return env[key] })
+	is.True(err != nil)
+	is.Equal(err.Error(), `dbrt: expected DATABASE_CONN_MAX_IDLE_TIME to be a duration like 5m, got "5"`)
+}
@@ -0,0 +1,240 @@
+package dbrt
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/livebud/bud/package/tracing"
+)
+
+type txKey struct{}
+
+// pendingTx is the transaction carried by the context. Requests begin their
+// transaction on the first query, so those that don't query skip it.
+type pendingTx struct {
+	db  *sql.DB
+	ctx context.Context
+	mu  sync.Mutex
+	tx  *sql.Tx
+	err error
+}
+
+// begin the transaction if it hasn't begun yet
+func (p *pendingTx) begin() (*sql.Tx, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.tx == nil && p.err == nil {
+		p.tx, p.err = p.db.BeginTx(p.ctx, nil)
+	}
+	return p.tx, p.err
+}
+
+// started returns the transaction or nil if it hasn't begun
+func (p *pendingTx) started() (*sql.Tx, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.tx, p.err
+}
+
+// WithTx returns a context that carries the transaction. Queries made through
+// *DB with this context run within the transaction.
+func WithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, &pendingTx{tx: tx})
+}
+
+// TxFrom returns the transaction in the context or nil if there isn't one.
+// Transactions opened by the middleware are nil until the first query.
+func TxFrom(ctx context.Context) *sql.Tx {
+	pending, ok := ctx.Value(txKey{}).(*pendingTx)
+	if !ok {
+		return nil
+	}
+	tx, _ := pending.started()
+	return tx
+}
+
+// queryer returns the transaction in the context, falling back to the database
+func (db *DB) queryer(ctx context.Context) (Queryer, error) {
+	pending, ok := ctx.Value(txKey{}).(*pendingTx)
+	if !ok {
+		return db.DB, nil
+	}
+	tx, err := pending.begin()
+	if err != nil {
+		return nil, fmt.Errorf("dbrt: unable to begin transaction. %w", err)
+	}
+	return tx, nil
+}
+
+// ExecContext runs within the context's transaction if there is one
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := db.trace(ctx, query)
+	defer span.End()
+	queryer, err := db.queryer(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	result, err := queryer.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return result, err
+}
+
+// QueryContext runs within the context's transaction if there is one
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := db.trace(ctx, query)
+	defer span.End()
+	queryer, err := db.queryer(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	rows, err := queryer.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+// QueryRowContext runs within the context's transaction if there is one
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := db.trace(ctx, query)
+	defer span.End()
+	queryer, err := db.queryer(ctx)
+	if err != nil {
+		span.RecordError(err)
+		// Rows can't carry our error, so fail the query with a canceled context.
+		// The middleware responds with the error once the handler returns.
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+		return db.DB.QueryRowContext(canceled, query, args...)
+	}
+	row := queryer.QueryRowContext(ctx, query, args...)
+	span.RecordError(row.Err())
+	return row
+}
+
+// trace starts a span for the query when the request is traced. The span is
+// named after the statement, like SELECT.
+func (db *DB) trace(ctx context.Context, query string) (context.Context, *tracing.Span) {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	ctx, span := tracing.StartClient(ctx, strings.ToUpper(operation))
+	span.SetAttribute("db.system", db.dialect.system())
+	span.SetAttribute("db.statement", query)
+	return ctx, span
+}
+
+// Transact calls fn with a context that carries a new transaction. The
+// transaction is committed if fn succeeds and rolled back if fn returns an
+// error or panics. If ctx already carries a transaction, fn joins it.
+func (db *DB) Transact(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if pending, ok := ctx.Value(txKey{}).(*pendingTx); ok {
+		if _, err := pending.begin(); err != nil {
+			return fmt.Errorf("dbrt: unable to begin transaction. %w", err)
+		}
+		return fn(ctx)
+	}
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("dbrt: unable to begin transaction. %w", err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := fn(WithTx(ctx, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("dbrt: unable to commit transaction. %w", err)
+	}
+	return nil
+}
+
+// Middleware gives each POST, PUT, PATCH and DELETE request a transaction that
+// begins with the request's first query. The transaction is committed if the
+// handler responds with a status below 400 and rolled back otherwise, or if
+// the handler panics. Responses are buffered until the transaction finishes,
+// so clients never see a success that failed to commit.
+func (db *DB) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutation(r.Method) || r.Context().Value(txKey{}) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		pending := &pendingTx{db: db.DB, ctx: r.Context()}
+		bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+		defer func() {
+			if r := recover(); r != nil {
+				if tx, _ := pending.started(); tx != nil {
+					tx.Rollback()
+				}
+				panic(r)
+			}
+		}()
+		next.ServeHTTP(bw, r.WithContext(context.WithValue(r.Context(), txKey{}, pending)))
+		tx, err := pending.started()
+		switch {
+		case err != nil:
+			fail(w, fmt.Errorf("dbrt: unable to begin transaction. %w", err))
+		case tx == nil:
+			// The handler didn't query the database
+			bw.flush()
+		case bw.status >= 400:
+			tx.Rollback()
+			bw.flush()
+		default:
+			if err := tx.Commit(); err != nil {
+				fail(w, fmt.Errorf("dbrt: unable to commit transaction. %w", err))
+				return
+			}
+			bw.flush()
+		}
+	})
+}
+
+// fail responds with the error in place of the handler's response. The
+// handler's headers are dropped, since they may carry a redirect or a session
+// cookie for changes that were rolled back.
+func fail(w http.ResponseWriter, err error) {
+	header := w.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// bufferedWriter holds onto the response until the transaction finishes
+type bufferedWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *bufferedWriter) Write(p []byte) (int, error) {
+	return w.body.Write(p)
+}
+
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.body.Bytes())
+}
@@ -0,0 +1,187 @@
+package dbrt_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func openUsers(t testing.TB) *dbrt.DB {
+	t.Helper()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE users (id integer primary key, name text)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func countUsers(t testing.TB, db *dbrt.DB) (n int) {
+	t.Helper()
+	if err := db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// insertUser responds with the given status after inserting a user
+func insertUser(db *dbrt.DB, status int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.ExecContext(r.Context(), `INSERT INTO users (name) VALUES ('alice')`); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("inserted"))
+	})
+}
+
+func TestMiddlewareCommit(t *testing.T) {
+	is := is.New(t)
+	db := openUsers(t)
+	rec := httptest.NewRecorder()
+	db.Middleware(insertUser(db, http.StatusCreated)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+	is.Equal(rec.Code, http.StatusCreated)
+	is.Equal(rec.Body.String(), "inserted")
+	is.Equal(countUsers(t, db), 1)
+}
+
+func TestMiddlewareRollbackOnError(t *testing.T) {
+	is := is.New(t)
+	db := openUsers(t)
+	for _, status := range []int{http.StatusUnprocessableEntity, http.StatusInternalServerError} {
+		rec := httptest.NewRecorder()
+		db.Middleware(insertUser(db, status)).ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/users/1", nil))
+		is.Equal(rec.Code, status)
+		is.Equal(rec.Body.String(), "inserted")
+	}
+	is.Equal(countUsers(t, db), 0)
+}
+
+func TestMiddlewareRollbackOnPanic(t *testing.T) {
+	is := is.New(t)
+	db := openUsers(t)
+	handler := db.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.ExecContext(r.Context(), `INSERT INTO users (name) VALUES ('alice')`); err != nil {
+			t.Fatal(err)
+		}
+		panic("oops")
+	}))
+	func() {
+		defer func() { is.Equal(recover(), "oops") }()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	}()
+	is.Equal(countUsers(t, db), 0)
+}
+
+func TestMiddlewareSkipsReads(t *testing.T) {
+	is := is.New(t)
+	db := openUsers(t)
+	handler := db.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.True(dbrt.TxFrom(r.Context()) == nil)
+		w.Write([]byte("hello"))
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "hello")
+}
+
+func TestMiddlewareSkipsUnusedTransactions(t *testing.T) {
+	is := is.New(t)
+	db := openUsers(t)
+	// Hold onto the only connection, so beginning a transaction would block
+	db.SetMaxOpenConns(1)
+	conn, err := db.Conn(context.Background())
+	is.NoErr(err)
+	defer conn.Close()
+	handler := db.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("queued"))
+	}))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jobs", nil).WithContext(ctx))
+	is.Equal(rec.Code, http.StatusAccepted)
+	is.Equal(rec.Body.String(), "queued")
+}
+
+func TestMiddlewareCommitFailure(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db") + "?_foreign_keys=on")
+	is.NoErr(err)
+	defer db.Close()
+	_, err = db.Exec(`CREATE TABLE users (id integer primary key, name text)`)
+	is.NoErr(err)
+	// Deferred foreign keys are checked when the transaction commits
+	_, err = db.Exec(`CREATE TABLE posts (id integer primary key, user_id integer REFERENCES users (id) DEFERRABLE INITIALLY DEFERRED)`)
+	is.NoErr(err)
+	handler := db.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := db.ExecContext(r.Context(), `INSERT INTO posts (user_id) VALUES (10)`); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "flash", Value: "created"})
+		http.Redirect(w, r, "/posts/1", http.StatusSeeOther)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/posts", nil))
+	is.Equal(rec.Code, http.StatusInternalServerError)
+	is.In(rec.Body.String(), "dbrt: unable to commit transaction")
+	// The redirect and cookie belonged to the rolled back write
+	is.Equal(rec.Header().Get("Location"), "")
+	is.Equal(rec.Header().Get("Set-Cookie"), "")
+}
+
+func TestTransact(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := openUsers(t)
+	err := db.Transact(ctx, func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, `INSERT INTO users (name) VALUES ('alice')`)
+		return err
+	})
+	is.NoErr(err)
+	is.Equal(countUsers(t, db), 1)
+	err = db.Transact(ctx, func(ctx context.Context) error {
+		if _, err := db.ExecContext(ctx, `INSERT INTO users (name) VALUES ('bob')`); err != nil {
+			return err
+		}
+		return errors.New("oops")
+	})
+	is.True(err != nil)
+	is.Equal(err.Error(), "oops")
+	is.Equal(countUsers(t, db), 1)
+}
+
+func TestTransactNested(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := openUsers(t)
+	err := db.Transact(ctx, func(ctx context.Context) error {
+		outer := dbrt.TxFrom(ctx)
+		is.True(outer != nil)
+		if err := db.Transact(ctx, func(ctx context.Context) error {
+			is.Equal(dbrt.TxFrom(ctx), outer)
+			_, err := db.ExecContext(ctx, `INSERT INTO users (name) VALUES ('alice')`)
+			return err
+		}); err != nil {
+			return err
+		}
+		return errors.New("oops")
+	})
+	is.True(err != nil)
+	// The inner transaction joined the outer one, so both rolled back
+	is.Equal(countUsers(t, db), 0)
+}
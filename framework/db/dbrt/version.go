@@ -0,0 +1,34 @@
+package dbrt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/livebud/bud/package/errors"
+)
+
+// ErrConflict is returned when a row was changed by someone else between
+// reading and updating it. Reload the row and try again.
+var ErrConflict = errors.Conflict.New("dbrt: row was changed by another update")
+
+// CheckVersion checks the result of an update that's guarded by a version
+// column. When no rows were updated, existsSQL is used to tell a missing row
+// (sql.ErrNoRows) apart from a stale version (ErrConflict). The args are the
+// primary key and tenant, if any.
+func CheckVersion(ctx context.Context, db Queryer, result sql.Result, existsSQL string, args ...interface{}) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if n > 0 {
+		return nil
+	}
+	var exists int
+	if err := db.QueryRowContext(ctx, existsSQL, args...).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
+		return fmt.Errorf("dbrt: unable to check for a version conflict. %w", err)
+	}
+	return ErrConflict
+}
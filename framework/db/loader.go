@@ -0,0 +1,332 @@
+package db
+
+import (
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/gotext"
+	"github.com/matthewmueller/text"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+	if err := hasModel(fsys); err != nil {
+		return nil, err
+	}
+	loader := &loader{
+		imports: imports.New(),
+		module:  module,
+		parser:  parser,
+	}
+	return loader.Load()
+}
+
+// hasModel returns fs.ErrNotExist if there are no Go files in model/
+func hasModel(fsys fs.FS) error {
+	des, err := fs.ReadDir(fsys, "model")
+	if err != nil {
+		return err
+	}
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			return nil
+		}
+	}
+	return fs.ErrNotExist
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	module  *gomod.Module
+	parser  *parser.Parser
+}
+
+// reserved variable names within the generated methods
+var reserved = map[string]bool{
+	"t":       true,
+	"ctx":     true,
+	"err":     true,
+	"row":     true,
+	"rows":    true,
+	"result":  true,
+	"model":   true,
+	"models":  true,
+	"dbrt":    true,
+	"fmt":     true,
+	"sql":     true,
+	"context": true,
+	"os":      true,
+	"db":      true,
+	"results": true,
+	"now":     true,
+	"page":    true,
+	"info":    true,
+	"after":   true,
+	"cursor":  true,
+	"url":     true,
+	"time":    true,
+	"clock":   true,
+	"tenant":  true,
+	"ok":      true,
+	// Holds the request's tenant in tenant scoped tables
+	"tenantID": true,
+}
+
+// Load the db state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "db: unable to load")
+	state = new(State)
+	l.imports.AddStd("context", "database/sql", "fmt", "net/url", "os", "time")
+	l.imports.AddNamed("dbrt", "github.com/livebud/bud/framework/db/dbrt")
+	l.imports.AddNamed("clock", "github.com/livebud/bud/package/clock")
+	l.imports.AddNamed("model", l.module.Import("model"))
+	state.Tables = l.loadTables()
+	if len(state.Tables) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	for _, table := range state.Tables {
+		// Only the primary key's type is referenced in the generated code
+		table.Key.Type = l.loadType(table.Key.field.Type())
+		if table.Tenant != nil {
+			l.imports.AddNamed("tenant", "github.com/livebud/bud/package/tenant")
+		}
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// loadTables loads a table for each public model with a primary key
+func (l *loader) loadTables() (tables []*Table) {
+	pkg, err := l.parser.Parse("model")
+	if err != nil {
+		l.Bail(err)
+	}
+	for _, stct := range pkg.Structs() {
+		if stct.Private() {
+			continue
+		}
+		table := l.loadTable(stct)
+		if table == nil {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// loadTable returns nil for structs that don't have a primary key
+func (l *loader) loadTable(stct *parser.Struct) *Table {
+	table := new(Table)
+	table.Pascal = gotext.Pascal(stct.Name())
+	table.Singular = strings.ToLower(text.Space(stct.Name()))
+	table.Name = strings.ToLower(text.Snake(text.Plural(stct.Name())))
+	table.Model = "model." + stct.Name()
+	table.Variable = l.loadVariable(gotext.Camel(stct.Name()))
+	for _, field := range stct.PublicFields() {
+		tags, err := field.Tags()
+		if err != nil {
+			l.Bail(err)
+		}
+		var tag *parser.Tag
+		for _, t := range tags {
+			if t.Key == "db" {
+				tag = t
+			}
+		}
+		// Skip ignored fields
+		if tag != nil && tag.Value == "-" {
+			continue
+		}
+		column := l.loadColumn(field, tag)
+		table.Columns = append(table.Columns, column)
+		if tag != nil && hasOption(tag, "pk") {
+			table.Key = column
+		} else if table.Key == nil && field.Name() == "ID" {
+			table.Key = column
+		}
+	}
+	if table.Key == nil {
+		return nil
+	}
+	l.loadConventions(stct, table)
+	l.loadQueries(table)
+	return table
+}
+
+// loadConventions finds the columns that are managed by the generated code
+func (l *loader) loadConventions(stct *parser.Struct, table *Table) {
+	for _, column := range table.Columns {
+		if column == table.Key {
+			continue
+		}
+		dt := column.field.Type()
+		_, isPointer := dt.(*parser.StarType)
+		switch column.Field {
+		case "CreatedAt", "UpdatedAt":
+			if isPointer || !l.isImportType(dt, "time", "Time") {
+				l.Bail(fmt.Errorf("expected %s.%s to be a time.Time", stct.Name(), column.Field))
+			}
+			if column.Field == "CreatedAt" {
+				table.CreatedAt = column
+			} else {
+				table.UpdatedAt = column
+			}
+		case "DeletedAt":
+			if !(isPointer && l.isImportType(dt, "time", "Time")) && !l.isImportType(dt, "database/sql", "NullTime") {
+				l.Bail(fmt.Errorf("expected %s.DeletedAt to be a *time.Time or sql.NullTime", stct.Name()))
+			}
+			table.DeletedAt = column
+		case "Version":
+			switch dt.String() {
+			case "int", "int32", "int64":
+				table.Version = column
+			default:
+				l.Bail(fmt.Errorf("expected %s.Version to be an int, int32 or int64", stct.Name()))
+			}
+		case "TenantID":
+			if dt.String() != "string" {
+				l.Bail(fmt.Errorf("expected %s.TenantID to be a string", stct.Name()))
+			}
+			table.Tenant = column
+		}
+	}
+}
+
+func (l *loader) isImportType(dt parser.Type, importPath, name string) bool {
+	ok, err := parser.IsImportType(dt, importPath, name)
+	if err != nil {
+		l.Bail(err)
+	}
+	return ok
+}
+
+func (l *loader) loadColumn(field *parser.Field, tag *parser.Tag) *Column {
+	column := new(Column)
+	column.Field = field.Name()
+	column.Name = strings.ToLower(text.Snake(field.Name()))
+	if tag != nil && tag.Value != "" {
+		column.Name = tag.Value
+	}
+	column.Param = l.loadVariable(gotext.Camel(field.Name()))
+	column.field = field
+	return column
+}
+
+func (l *loader) loadVariable(name string) string {
+	if reserved[name] {
+		return name + "Model"
+	}
+	return name
+}
+
+// loadType qualifies the field's type. Types are resolved from their import
+// path because the parser doesn't support looking up named types like
+// "type Status string".
+func (l *loader) loadType(dt parser.Type) string {
+	importPath, err := parser.ImportPath(parser.Innermost(dt))
+	if err != nil {
+		l.Bail(err)
+	}
+	if importPath == "" {
+		return dt.String()
+	}
+	name := l.imports.Add(importPath)
+	// Types declared alongside the model are unqualified
+	if _, ok := parser.Innermost(dt).(*parser.IdentType); ok {
+		return parser.Qualify(dt, name).String()
+	}
+	return parser.Requalify(dt, name).String()
+}
+
+// loadQueries builds the queries up front, so they don't need to be built on
+// each call
+func (l *loader) loadQueries(table *Table) {
+	name := quote(table.Name)
+	key := quote(table.Key.Name)
+	columns := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = quote(column.Name)
+	}
+	fields := table.Fields()
+	names := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = quote(field.Name)
+	}
+	var sets []string
+	for _, field := range table.UpdateFields() {
+		sets = append(sets, quote(field.Name)+" = ?")
+	}
+	// Rows belong to the request's tenant and soft deleted rows are hidden from
+	// every query except Purge
+	var scopes []string
+	if table.DeletedAt != nil {
+		scopes = append(scopes, quote(table.DeletedAt.Name)+" IS NULL")
+	}
+	ofTenant := ""
+	if table.Tenant != nil {
+		ofTenant = " AND " + quote(table.Tenant.Name) + " = ?"
+		scopes = append(scopes, quote(table.Tenant.Name)+" = ?")
+	}
+	where := "WHERE " + key + " = ?"
+	for _, scope := range scopes {
+		where += " AND " + scope
+	}
+	scoped := ""
+	if len(scopes) > 0 {
+		scoped = " WHERE " + strings.Join(scopes, " AND ")
+	}
+	selectColumns := strings.Join(columns, ", ")
+	table.SelectSQL = "SELECT " + selectColumns + " FROM " + name + scoped + " ORDER BY " + key
+	table.FindSQL = "SELECT " + selectColumns + " FROM " + name + " " + where
+	table.CountSQL = "SELECT COUNT(*) FROM " + name + scoped
+	table.PageSQL = "SELECT " + selectColumns + " FROM " + name + scoped + " ORDER BY " + key + " LIMIT ? OFFSET ?"
+	table.PageAfterSQL = "SELECT " + selectColumns + " FROM " + name + " WHERE " + key + " > ?"
+	for _, scope := range scopes {
+		table.PageAfterSQL += " AND " + scope
+	}
+	table.PageAfterSQL += " ORDER BY " + key + " LIMIT ?"
+	table.InsertSQL = "INSERT INTO " + name + " (" + selectColumns + ") VALUES (" + placeholders(len(columns)) + ")"
+	if len(fields) == 0 {
+		table.InsertAutoSQL = "INSERT INTO " + name + " DEFAULT VALUES RETURNING " + key
+	} else {
+		table.InsertAutoSQL = "INSERT INTO " + name + " (" + strings.Join(names, ", ") + ") VALUES (" + placeholders(len(fields)) + ") RETURNING " + key
+	}
+	if table.Version != nil {
+		version := quote(table.Version.Name)
+		sets = append(sets, version+" = "+version+" + 1")
+		table.UpdateSQL = "UPDATE " + name + " SET " + strings.Join(sets, ", ") + " " + where + " AND " + version + " = ?"
+		table.ExistsSQL = "SELECT 1 FROM " + name + " " + where
+	} else if len(sets) > 0 {
+		table.UpdateSQL = "UPDATE " + name + " SET " + strings.Join(sets, ", ") + " " + where
+	}
+	if table.DeletedAt != nil {
+		table.DeleteSQL = "UPDATE " + name + " SET " + quote(table.DeletedAt.Name) + " = ? " + where
+		table.PurgeSQL = "DELETE FROM " + name + " WHERE " + key + " = ?" + ofTenant
+	} else {
+		table.DeleteSQL = "DELETE FROM " + name + " WHERE " + key + " = ?" + ofTenant
+	}
+}
+
+func hasOption(tag *parser.Tag, option string) bool {
+	for _, o := range tag.Options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// quote an identifier. Double quotes work for both Postgres and SQLite.
+func quote(ident string) string {
+	return `"` + ident + `"`
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
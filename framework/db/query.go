@@ -0,0 +1,46 @@
+package db
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed query.gotext
+var queryTemplate string
+
+var queryGenerator = gotemplate.MustParse("framework/db/query.gotext", queryTemplate)
+
+// GenerateQuery generates the query methods from state
+func GenerateQuery(state *QueryState) ([]byte, error) {
+	return queryGenerator.Generate(state)
+}
+
+// NewQuery generator
+func NewQuery(module *gomod.Module, parser *parser.Parser) *QueryGenerator {
+	return &QueryGenerator{module, parser}
+}
+
+// QueryGenerator adds a method to the db package for each named query in
+// query/*.sql. Parameter and result types are inferred from the models.
+type QueryGenerator struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (g *QueryGenerator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := LoadQuery(fsys, g.module, g.parser)
+	if err != nil {
+		return fmt.Errorf("framework/db: unable to load queries. %w", err)
+	}
+	code, err := GenerateQuery(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
@@ -0,0 +1,357 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/framework/db/sqlquery"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/gotext"
+)
+
+// LoadQuery loads the queries in query/*.sql
+func LoadQuery(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*QueryState, error) {
+	paths, err := fs.Glob(fsys, "query/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		// Without queries, query.go is empty as long as the db package exists.
+		// Otherwise the missing file would break importing the package.
+		if _, err := fs.Stat(fsys, "bud/package/db/db.go"); err != nil {
+			return nil, err
+		}
+		return new(QueryState), nil
+	}
+	// Don't wrap fs.ErrNotExist, otherwise the queries would be silently skipped
+	if err := hasModel(fsys); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("db: queries are typed using models, but there are no models in model/")
+		}
+		return nil, err
+	}
+	loader := &queryLoader{
+		loader: &loader{
+			imports: imports.New(),
+			module:  module,
+			parser:  parser,
+		},
+		fsys:  fsys,
+		names: map[string]string{},
+	}
+	return loader.Load(paths)
+}
+
+type queryLoader struct {
+	*loader
+	fsys   fs.FS
+	tables []*Table
+	names  map[string]string // names taken on *DB and what took them
+}
+
+// Load the query state
+func (l *queryLoader) Load(paths []string) (state *QueryState, err error) {
+	defer l.Recover2(&err, "db")
+	state = new(QueryState)
+	l.imports.AddStd("context", "fmt")
+	l.tables = l.loadTables()
+	if len(l.tables) == 0 {
+		return nil, fmt.Errorf("db: queries are typed using models, but no model in model/ has a primary key")
+	}
+	// Methods and fields on *DB that a query would shadow
+	for _, table := range l.tables {
+		l.names[table.Pascal] = "the " + table.Pascal + " table"
+	}
+	dbType := reflect.TypeOf(&dbrt.DB{})
+	for i := 0; i < dbType.NumMethod(); i++ {
+		l.names[dbType.Method(i).Name] = "a method on *dbrt.DB"
+	}
+	l.names["DB"] = "the embedded *dbrt.DB"
+	for _, path := range paths {
+		data, err := fs.ReadFile(l.fsys, path)
+		if err != nil {
+			l.Bail(err)
+		}
+		queries, err := sqlquery.Parse(path, data)
+		if err != nil {
+			l.Bail(err)
+		}
+		for _, query := range queries {
+			state.Queries = append(state.Queries, l.loadQuery(query))
+		}
+	}
+	if len(state.Queries) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// scope is a table referenced within a query
+type scope struct {
+	ref   *sqlquery.Table
+	table *Table // nil when there's no model for the table
+}
+
+// result is a resolved result column. Only hinted columns have a typ up front.
+// Model columns load their type when a row struct needs it, so results that
+// scan into the model don't import packages they don't use.
+type result struct {
+	name   string
+	typ    string
+	table  *Table
+	column *Column
+}
+
+func (l *queryLoader) loadQuery(q *sqlquery.Query) *Query {
+	if !token.IsExported(q.Name) {
+		l.Bail(fmt.Errorf("query name %q in %s must start with an uppercase letter", q.Name, q.Path))
+	}
+	if other, ok := l.names[q.Name]; ok {
+		l.Bail(fmt.Errorf("query %s in %s conflicts with %s", q.Name, q.Path, other))
+	}
+	l.names[q.Name] = q.Path
+	query := new(Query)
+	query.Name = q.Name
+	query.Kind = string(q.Kind)
+	query.Path = q.Path
+	query.Const = gotext.Camel(q.Name) + "SQL"
+	scopes := make([]*scope, len(q.Tables))
+	for i, ref := range q.Tables {
+		scopes[i] = &scope{ref, l.findTable(ref.Name)}
+	}
+	// Load the parameters
+	names := map[string]string{}
+	for _, p := range q.Params {
+		// Like the table methods, queries are scoped to the request's tenant
+		if p.Name == "tenant_id" {
+			query.Tenant = true
+			names[p.Name] = "tenantID"
+			l.imports.AddNamed("tenant", "github.com/livebud/bud/package/tenant")
+			continue
+		}
+		param := new(Param)
+		param.Name = l.loadVariable(gotext.Camel(p.Name))
+		param.Type = l.loadParamType(q, scopes, p)
+		names[p.Name] = param.Name
+		query.Params = append(query.Params, param)
+	}
+	for _, arg := range q.Args {
+		query.Args = append(query.Args, names[arg])
+	}
+	// Load the results, expanding stars into their columns
+	sql := q.SQL
+	var results []*result
+	for i := len(q.Columns) - 1; i >= 0; i-- {
+		column := q.Columns[i]
+		if !column.Star {
+			results = append([]*result{l.loadResult(q, scopes, column)}, results...)
+			continue
+		}
+		expanded, columns := l.expandStar(q, scopes, column)
+		results = append(expanded, results...)
+		sql = sql[:column.Start] + strings.Join(columns, ", ") + sql[column.End:]
+	}
+	query.SQL = literal(sql)
+	if len(results) > 0 {
+		l.loadResults(query, results)
+	}
+	return query
+}
+
+// findTable finds the model's table by name
+func (l *queryLoader) findTable(name string) *Table {
+	for _, table := range l.tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+// findColumn finds the column that a reference refers to
+func (l *queryLoader) findColumn(scopes []*scope, ref *sqlquery.Ref) (*Table, *Column) {
+	for _, scope := range scopes {
+		if scope.table == nil {
+			continue
+		}
+		if ref.Table != "" && ref.Table != scope.ref.Alias && ref.Table != scope.ref.Name {
+			continue
+		}
+		for _, column := range scope.table.Columns {
+			if column.Name == ref.Name {
+				return scope.table, column
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (l *queryLoader) loadParamType(q *sqlquery.Query, scopes []*scope, p *sqlquery.Param) string {
+	if p.Type != "" {
+		return l.loadHint(q, p.Type)
+	}
+	if p.Column != nil {
+		if _, column := l.findColumn(scopes, p.Column); column != nil {
+			return l.loadType(column.field.Type())
+		}
+	}
+	// Fallback to a column with the same name as the parameter
+	if _, column := l.findColumn(scopes, &sqlquery.Ref{Name: p.Name}); column != nil {
+		return l.loadType(column.field.Type())
+	}
+	l.Bail(fmt.Errorf("unable to infer the type of @%s in %s. Add a \"-- param: %s <type>\" hint to %s", p.Name, q.Name, p.Name, q.Path))
+	return ""
+}
+
+func (l *queryLoader) loadResult(q *sqlquery.Query, scopes []*scope, c *sqlquery.Column) *result {
+	result := &result{name: c.Name}
+	if c.Ref != nil {
+		result.table, result.column = l.findColumn(scopes, c.Ref)
+	}
+	switch {
+	case c.Type != "":
+		result.typ = l.loadHint(q, c.Type)
+		// Hinted columns don't map onto the model
+		result.table, result.column = nil, nil
+	case result.column == nil:
+		l.Bail(fmt.Errorf("unable to infer the type of column %q in %s. Add a \"-- column: %s <type>\" hint to %s", c.Name, q.Name, c.Name, q.Path))
+	}
+	return result
+}
+
+// expandStar expands * and table.* into the model's columns, so the columns
+// are always scanned in the right order
+func (l *queryLoader) expandStar(q *sqlquery.Query, scopes []*scope, c *sqlquery.Column) (results []*result, columns []string) {
+	for _, scope := range scopes {
+		if c.Ref != nil && c.Ref.Table != scope.ref.Alias && c.Ref.Table != scope.ref.Name {
+			continue
+		}
+		if scope.table == nil {
+			l.Bail(fmt.Errorf("unable to expand * in %s because there's no model for the %q table", q.Name, scope.ref.Name))
+		}
+		qualifier := ""
+		if c.Ref != nil || len(scopes) > 1 {
+			qualifier = scope.ref.Name
+			if scope.ref.Alias != "" {
+				qualifier = scope.ref.Alias
+			}
+			qualifier = quote(qualifier) + "."
+		}
+		for _, column := range scope.table.Columns {
+			columns = append(columns, qualifier+quote(column.Name))
+			results = append(results, &result{
+				name:   column.Name,
+				table:  scope.table,
+				column: column,
+			})
+		}
+	}
+	if len(results) == 0 {
+		l.Bail(fmt.Errorf("unable to expand %q in %s", q.SQL[c.Start:c.End], q.Name))
+	}
+	return results, columns
+}
+
+// loadResults returns the model when the results are exactly the model's
+// columns. Otherwise a row struct is generated.
+func (l *queryLoader) loadResults(query *Query, results []*result) {
+	if table := modelOf(results); table != nil {
+		l.imports.AddNamed("model", l.module.Import("model"))
+		query.Result = table.Model
+		for _, result := range results {
+			query.Scans = append(query.Scans, result.column.Field)
+		}
+		return
+	}
+	row := &Row{Name: query.Name + "Row"}
+	if other, ok := l.names[row.Name]; ok {
+		l.Bail(fmt.Errorf("generated type %s in %s conflicts with %s", row.Name, query.Path, other))
+	}
+	l.names[row.Name] = query.Path
+	seen := map[string]bool{}
+	for _, result := range results {
+		typ := result.typ
+		if typ == "" {
+			typ = l.loadType(result.column.field.Type())
+		}
+		field := &RowField{
+			Name:   gotext.Pascal(result.name),
+			Type:   typ,
+			Column: result.name,
+		}
+		if seen[field.Name] {
+			l.Bail(fmt.Errorf("duplicate column %q in %s. Use AS to rename one of them", result.name, query.Name))
+		}
+		seen[field.Name] = true
+		row.Fields = append(row.Fields, field)
+		query.Scans = append(query.Scans, field.Name)
+	}
+	query.Result = row.Name
+	query.Row = row
+}
+
+// modelOf returns the table if each of its columns is returned exactly once
+func modelOf(results []*result) *Table {
+	table := results[0].table
+	if table == nil || len(results) != len(table.Columns) {
+		return nil
+	}
+	seen := map[*Column]bool{}
+	for _, result := range results {
+		if result.table != table || seen[result.column] {
+			return nil
+		}
+		seen[result.column] = true
+	}
+	return table
+}
+
+// stdHints are the packages that can be referenced in type hints
+var stdHints = map[string]string{
+	"big":   "math/big",
+	"json":  "encoding/json",
+	"netip": "net/netip",
+	"sql":   "database/sql",
+	"time":  "time",
+}
+
+var qualifierRe = regexp.MustCompile(`\b([A-Za-z_][A-Za-z0-9_]*)\.`)
+
+// loadHint loads the type hint, importing the packages it references
+func (l *queryLoader) loadHint(q *sqlquery.Query, hint string) string {
+	var err error
+	hint = qualifierRe.ReplaceAllStringFunc(hint, func(match string) string {
+		name := strings.TrimSuffix(match, ".")
+		if name == "model" {
+			return l.imports.AddNamed("model", l.module.Import("model")) + "."
+		}
+		importPath, ok := stdHints[name]
+		if !ok {
+			err = fmt.Errorf("unknown package %q in the type hint %q in %s", name, hint, q.Path)
+			return match
+		}
+		return l.imports.Add(importPath) + "."
+	})
+	if err != nil {
+		l.Bail(err)
+	}
+	return hint
+}
+
+// literal returns the SQL as a Go string literal
+func literal(sql string) string {
+	if strings.Contains(sql, "`") {
+		return strconv.Quote(sql)
+	}
+	return "`" + sql + "`"
+}
@@ -0,0 +1,464 @@
+package sqlquery
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	identToken tokenType = iota
+	quotedToken
+	stringToken
+	numberToken
+	punctToken
+	placeholderToken
+)
+
+type token struct {
+	Type  tokenType
+	Text  string // Unquoted text of the token
+	Upper string // Uppercased text for keyword matching
+	Start int
+	End   int
+	Depth int // Parenthesis depth
+}
+
+// keyword returns true if the token is an unquoted identifier matching one of
+// the keywords
+func (t *token) keyword(keywords ...string) bool {
+	if t.Type != identToken {
+		return false
+	}
+	for _, keyword := range keywords {
+		if t.Upper == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *token) punct(text string) bool {
+	return t.Type == punctToken && t.Text == text
+}
+
+// name returns true if the token can be used as a name
+func (t *token) name() bool {
+	return t.Type == quotedToken || (t.Type == identToken && !reserved[t.Upper])
+}
+
+// reserved keywords that end a table reference or column expression
+var reserved = map[string]bool{}
+
+func init() {
+	for _, keyword := range strings.Fields(`
+		ALL AND AS BETWEEN BY CROSS DEFAULT DISTINCT EXCEPT FETCH FOR FROM FULL
+		GROUP HAVING ILIKE IN INNER INTERSECT INTO IS JOIN LEFT LIKE LIMIT NATURAL
+		NOT NULL OFFSET ON OR ORDER OUTER RETURNING RIGHT SELECT SET UNION USING
+		VALUES WHERE WINDOW WITH
+	`) {
+		reserved[keyword] = true
+	}
+}
+
+var operators = []string{"<=", ">=", "<>", "!=", "::", "||"}
+
+// tokenize the SQL, skipping whitespace and comments
+func tokenize(sql string) (tokens []*token) {
+	depth := 0
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				return tokens
+			}
+			i += end
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				return tokens
+			}
+			i += end + 4
+		case ch == '\'':
+			end := skipQuoted(sql, i, ch)
+			tokens = append(tokens, &token{Type: stringToken, Text: sql[i:end], Start: i, End: end, Depth: depth})
+			i = end
+		case ch == '"':
+			end := skipQuoted(sql, i, ch)
+			text := strings.ReplaceAll(strings.Trim(sql[i:end], `"`), `""`, `"`)
+			tokens = append(tokens, &token{Type: quotedToken, Text: text, Upper: strings.ToUpper(text), Start: i, End: end, Depth: depth})
+			i = end
+		case isIdentStart(ch):
+			end := i + 1
+			for end < len(sql) && (isIdent(sql[end]) || sql[end] == '$') {
+				end++
+			}
+			text := sql[i:end]
+			tokens = append(tokens, &token{Type: identToken, Text: text, Upper: strings.ToUpper(text), Start: i, End: end, Depth: depth})
+			i = end
+		case ch >= '0' && ch <= '9':
+			end := i + 1
+			for end < len(sql) && (isIdent(sql[end]) || sql[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, &token{Type: numberToken, Text: sql[i:end], Start: i, End: end, Depth: depth})
+			i = end
+		case ch == '?':
+			tokens = append(tokens, &token{Type: placeholderToken, Text: "?", Start: i, End: i + 1, Depth: depth})
+			i++
+		case ch == '(':
+			tokens = append(tokens, &token{Type: punctToken, Text: "(", Start: i, End: i + 1, Depth: depth})
+			depth++
+			i++
+		case ch == ')':
+			if depth > 0 {
+				depth--
+			}
+			tokens = append(tokens, &token{Type: punctToken, Text: ")", Start: i, End: i + 1, Depth: depth})
+			i++
+		default:
+			text := sql[i : i+1]
+			for _, op := range operators {
+				if strings.HasPrefix(sql[i:], op) {
+					text = op
+					break
+				}
+			}
+			tokens = append(tokens, &token{Type: punctToken, Text: text, Start: i, End: i + len(text), Depth: depth})
+			i += len(text)
+		}
+	}
+	return tokens
+}
+
+type parser struct {
+	query  *Query
+	tokens []*token
+	params map[string]*Param
+}
+
+func (p *parser) parse() error {
+	// Find the main statement, skipping past any common table expressions
+	start := -1
+	for i, t := range p.tokens {
+		if t.Depth == 0 && t.keyword("SELECT", "INSERT", "UPDATE", "DELETE") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return fmt.Errorf("expected a SELECT, INSERT, UPDATE or DELETE statement")
+	}
+	p.parseTables(start)
+	if err := p.parseColumns(start); err != nil {
+		return err
+	}
+	p.parseComparisons()
+	return nil
+}
+
+// parseTables finds the tables in FROM, JOIN, INTO and UPDATE clauses
+func (p *parser) parseTables(start int) {
+	for i := start; i < len(p.tokens); i++ {
+		t := p.tokens[i]
+		if t.Depth != 0 {
+			continue
+		}
+		switch {
+		case t.keyword("FROM"):
+			i = p.parseTable(i + 1)
+			for i < len(p.tokens) && p.tokens[i].punct(",") {
+				i = p.parseTable(i + 1)
+			}
+			i--
+		case t.keyword("JOIN"):
+			i = p.parseTable(i+1) - 1
+		case t.keyword("INTO"):
+			i = p.parseInsert(i+1) - 1
+		case t.keyword("UPDATE") && i == start:
+			i = p.parseTable(i+1) - 1
+		}
+	}
+}
+
+// parseTable parses a table reference with an optional alias, returning the
+// offset after the reference
+func (p *parser) parseTable(i int) int {
+	if i >= len(p.tokens) || !p.tokens[i].name() {
+		return i
+	}
+	table := &Table{Name: p.tokens[i].Text}
+	i++
+	// Schema qualified table
+	if i+1 < len(p.tokens) && p.tokens[i].punct(".") && p.tokens[i+1].name() {
+		table.Name = p.tokens[i+1].Text
+		i += 2
+	}
+	if i < len(p.tokens) && p.tokens[i].keyword("AS") {
+		i++
+	}
+	if i < len(p.tokens) && p.tokens[i].name() {
+		table.Alias = p.tokens[i].Text
+		i++
+	}
+	p.query.Tables = append(p.query.Tables, table)
+	return i
+}
+
+// parseInsert parses the table and columns of an INSERT statement, mapping
+// values to the columns they're inserted into
+func (p *parser) parseInsert(i int) int {
+	tableIndex := len(p.query.Tables)
+	i = p.parseTable(i)
+	if tableIndex == len(p.query.Tables) {
+		return i
+	}
+	table := p.query.Tables[tableIndex].Name
+	var columns []string
+	if i < len(p.tokens) && p.tokens[i].punct("(") {
+		for i++; i < len(p.tokens) && !p.tokens[i].punct(")"); i++ {
+			if p.tokens[i].name() {
+				columns = append(columns, p.tokens[i].Text)
+			}
+		}
+		i++
+	}
+	if i >= len(p.tokens) || !p.tokens[i].keyword("VALUES") {
+		return i
+	}
+	i++
+	for i < len(p.tokens) && p.tokens[i].punct("(") {
+		depth := p.tokens[i].Depth + 1
+		item, n := 0, 0
+		for i++; i < len(p.tokens) && !(p.tokens[i].punct(")") && p.tokens[i].Depth == depth-1); i++ {
+			t := p.tokens[i]
+			if t.Depth == depth && t.punct(",") {
+				item++
+				n = 0
+				continue
+			}
+			n++
+			// Only map values that are a lone placeholder
+			if t.Type == placeholderToken && n == 1 && item < len(columns) {
+				next := p.tokens[i+1]
+				if next.punct(",") || next.punct(")") {
+					p.link(t, &Ref{Table: table, Name: columns[item]})
+				}
+			}
+		}
+		i++
+		if i < len(p.tokens) && p.tokens[i].punct(",") {
+			i++
+		}
+	}
+	return i
+}
+
+// parseColumns parses the result columns of a SELECT or RETURNING clause
+func (p *parser) parseColumns(start int) error {
+	i, end := len(p.tokens), len(p.tokens)
+	if p.tokens[start].keyword("SELECT") {
+		i = start + 1
+		if i < len(p.tokens) && p.tokens[i].keyword("DISTINCT", "ALL") {
+			i++
+			// DISTINCT ON (...)
+			if i < len(p.tokens) && p.tokens[i].keyword("ON") {
+				for i++; i < len(p.tokens) && !(p.tokens[i].punct(")") && p.tokens[i].Depth == 0); i++ {
+				}
+				i++
+			}
+		}
+		for j := i; j < len(p.tokens); j++ {
+			t := p.tokens[j]
+			if t.Depth == 0 && t.keyword("FROM", "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "OFFSET", "UNION", "EXCEPT", "INTERSECT", "WINDOW", "FETCH", "FOR", "INTO") {
+				end = j
+				break
+			}
+		}
+	} else {
+		for j := start; j < len(p.tokens); j++ {
+			if p.tokens[j].Depth == 0 && p.tokens[j].keyword("RETURNING") {
+				i = j + 1
+				break
+			}
+		}
+	}
+	var item []*token
+	for ; i <= end; i++ {
+		if i < end && !(p.tokens[i].Depth == 0 && p.tokens[i].punct(",")) {
+			item = append(item, p.tokens[i])
+			continue
+		}
+		if len(item) == 0 {
+			continue
+		}
+		column, err := p.parseColumn(item)
+		if err != nil {
+			return err
+		}
+		p.query.Columns = append(p.query.Columns, column)
+		item = nil
+	}
+	return nil
+}
+
+func (p *parser) parseColumn(item []*token) (*Column, error) {
+	n := len(item)
+	column := &Column{Start: item[0].Start, End: item[n-1].End}
+	switch {
+	case n == 1 && item[0].punct("*"):
+		column.Star = true
+		return column, nil
+	case n == 3 && item[0].name() && item[1].punct(".") && item[2].punct("*"):
+		column.Star = true
+		column.Ref = &Ref{Table: item[0].Text}
+		return column, nil
+	}
+	expr := item
+	// Explicit and implicit aliases
+	if n >= 3 && item[n-2].keyword("AS") && item[n-1].name() {
+		column.Name = item[n-1].Text
+		expr = item[:n-2]
+	} else if n >= 2 && item[n-1].name() && isOperand(item[n-2]) {
+		column.Name = item[n-1].Text
+		expr = item[:n-1]
+	}
+	column.Ref = toRef(expr)
+	// COUNT(...) is always an integer
+	if len(expr) >= 2 && expr[0].keyword("COUNT") && expr[1].punct("(") {
+		column.Type = "int64"
+	}
+	if column.Name == "" {
+		if column.Ref == nil {
+			return nil, fmt.Errorf("column %q needs an alias", p.query.SQL[column.Start:column.End])
+		}
+		column.Name = column.Ref.Name
+	}
+	return column, nil
+}
+
+// isOperand returns true if the token ends an expression, so an identifier
+// following it is an implicit alias
+func isOperand(t *token) bool {
+	switch t.Type {
+	case identToken:
+		return !reserved[t.Upper]
+	case quotedToken, stringToken, numberToken:
+		return true
+	case punctToken:
+		return t.Text == ")"
+	default:
+		return false
+	}
+}
+
+// toRef returns the column reference or nil if the expression isn't a column
+func toRef(expr []*token) *Ref {
+	switch {
+	case len(expr) == 1 && expr[0].name():
+		return &Ref{Name: expr[0].Text}
+	case len(expr) == 3 && expr[0].name() && expr[1].punct(".") && expr[2].name():
+		return &Ref{Table: expr[0].Text, Name: expr[2].Text}
+	case len(expr) == 5 && expr[0].name() && expr[1].punct(".") && expr[2].name() && expr[3].punct(".") && expr[4].name():
+		return &Ref{Table: expr[2].Text, Name: expr[4].Text}
+	default:
+		return nil
+	}
+}
+
+var comparisons = map[string]bool{
+	"=": true, "!=": true, "<>": true, "<": true, ">": true, "<=": true, ">=": true,
+	"LIKE": true, "ILIKE": true,
+}
+
+func isComparison(t *token) bool {
+	if t.Type == punctToken {
+		return comparisons[t.Text]
+	}
+	return t.Type == identToken && comparisons[t.Upper]
+}
+
+// parseComparisons links parameters to the columns they're compared with
+func (p *parser) parseComparisons() {
+	for i, t := range p.tokens {
+		if t.Type != placeholderToken {
+			continue
+		}
+		// column = ?
+		if i >= 2 && isComparison(p.tokens[i-1]) {
+			if ref := p.refBefore(i - 1); ref != nil {
+				p.link(t, ref)
+				continue
+			}
+		}
+		// ? = column
+		if i+2 < len(p.tokens) && isComparison(p.tokens[i+1]) {
+			if ref := p.refAfter(i + 2); ref != nil {
+				p.link(t, ref)
+				continue
+			}
+		}
+		// LIMIT ? and OFFSET ?
+		if i >= 1 && p.tokens[i-1].keyword("LIMIT", "OFFSET") {
+			if param := p.param(t); param != nil && param.Type == "" && param.Column == nil {
+				param.Type = "int"
+			}
+		}
+	}
+}
+
+// refBefore returns the column reference ending before i
+func (p *parser) refBefore(i int) *Ref {
+	for n := 5; n >= 1; n -= 2 {
+		if i-n < 0 {
+			continue
+		}
+		if ref := toRef(p.tokens[i-n : i]); ref != nil {
+			// Make sure we have the whole reference
+			if i-n-1 >= 0 && p.tokens[i-n-1].punct(".") {
+				continue
+			}
+			return ref
+		}
+	}
+	return nil
+}
+
+// refAfter returns the column reference starting at i
+func (p *parser) refAfter(i int) *Ref {
+	for n := 5; n >= 1; n -= 2 {
+		if i+n > len(p.tokens) {
+			continue
+		}
+		if ref := toRef(p.tokens[i : i+n]); ref != nil {
+			return ref
+		}
+	}
+	return nil
+}
+
+// param returns the parameter for the placeholder token
+func (p *parser) param(t *token) *Param {
+	n := 0
+	for _, other := range p.tokens {
+		if other == t {
+			return p.params[p.query.Args[n]]
+		}
+		if other.Type == placeholderToken {
+			n++
+		}
+	}
+	return nil
+}
+
+// link the placeholder's parameter to the column, keeping the first link
+func (p *parser) link(t *token, ref *Ref) {
+	if param := p.param(t); param != nil && param.Column == nil {
+		param.Column = ref
+	}
+}
@@ -0,0 +1,267 @@
+// Package sqlquery parses annotated SQL files into queries. Each query starts
+// with a name annotation and uses @name parameters:
+//
+//	-- name: FindUserByEmail :one
+//	SELECT * FROM users WHERE email = @email
+//
+// Parsing is intentionally shallow. It finds the tables, result columns and
+// parameter comparisons needed to type the query, leaving the rest of the
+// statement to the database.
+package sqlquery
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Kind of query, which determines the shape of the generated function
+type Kind string
+
+const (
+	One      Kind = "one"      // Returns a single row
+	Many     Kind = "many"     // Returns a slice of rows
+	Exec     Kind = "exec"     // Returns only an error
+	ExecRows Kind = "execrows" // Returns the number of affected rows
+)
+
+// Query is a single named query within a SQL file
+type Query struct {
+	Name    string
+	Kind    Kind
+	Path    string
+	Line    int
+	SQL     string    // SQL with @name parameters replaced by ?
+	Args    []string  // Parameter name for each ? in the SQL
+	Params  []*Param  // Unique parameters in the order they first appear
+	Tables  []*Table  // Tables the query reads from or writes to
+	Columns []*Column // Columns returned by the query
+}
+
+// Param is a named parameter like @email
+type Param struct {
+	Name   string
+	Type   string // Go type from a "-- param: name type" hint
+	Column *Ref   // Column the parameter is compared with or inserted into
+}
+
+// Table referenced by the query
+type Table struct {
+	Name  string
+	Alias string
+}
+
+// Ref is a reference to a column, optionally qualified by a table or alias
+type Ref struct {
+	Table string
+	Name  string
+}
+
+// Column returned by the query
+type Column struct {
+	Name  string // Name of the result column
+	Type  string // Go type from a "-- column: name type" hint
+	Ref   *Ref   // Column this result refers to, nil for expressions
+	Star  bool   // Column is * or table.*
+	Start int    // Start offset of the column within the SQL
+	End   int    // End offset of the column within the SQL
+}
+
+var nameRe = regexp.MustCompile(`^--\s*name:\s*([A-Za-z_][A-Za-z0-9_]*)\s+:([a-z]+)\s*$`)
+var hintRe = regexp.MustCompile(`^--\s*(param|column):\s*([A-Za-z_][A-Za-z0-9_]*)\s+(\S.*?)\s*$`)
+
+// Parse the queries in a SQL file
+func Parse(path string, data []byte) (queries []*Query, err error) {
+	var query *Query
+	var hints map[string]map[string]string
+	var body []string
+	flush := func() error {
+		if query == nil {
+			return nil
+		}
+		if err := query.parse(dedent(body), hints); err != nil {
+			return fmt.Errorf("sqlquery: unable to parse %s in %s:%d. %w", query.Name, path, query.Line, err)
+		}
+		queries = append(queries, query)
+		return nil
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if match := nameRe.FindStringSubmatch(trimmed); match != nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			kind := Kind(match[2])
+			switch kind {
+			case One, Many, Exec, ExecRows:
+			default:
+				return nil, fmt.Errorf("sqlquery: unknown query kind %q in %s:%d. Expected :one, :many, :exec or :execrows", ":"+match[2], path, i+1)
+			}
+			query = &Query{Name: match[1], Kind: kind, Path: path, Line: i + 1}
+			hints = map[string]map[string]string{"param": {}, "column": {}}
+			body = nil
+			continue
+		}
+		if match := hintRe.FindStringSubmatch(trimmed); match != nil && query != nil {
+			hints[match[1]][match[2]] = match[3]
+			continue
+		}
+		if query == nil {
+			if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+				continue
+			}
+			return nil, fmt.Errorf("sqlquery: missing a \"-- name: Name :kind\" annotation before %s:%d", path, i+1)
+		}
+		// Skip comments above the query
+		if len(body) == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		body = append(body, line)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+func (q *Query) parse(body string, hints map[string]map[string]string) error {
+	body = strings.TrimSpace(body)
+	body = strings.TrimSpace(strings.TrimSuffix(body, ";"))
+	if body == "" {
+		return fmt.Errorf("empty query")
+	}
+	sql, args, err := replaceParams(body)
+	if err != nil {
+		return err
+	}
+	q.SQL = sql
+	q.Args = args
+	params := map[string]*Param{}
+	for _, arg := range args {
+		if params[arg] != nil {
+			continue
+		}
+		params[arg] = &Param{Name: arg, Type: hints["param"][arg]}
+		q.Params = append(q.Params, params[arg])
+	}
+	for name := range hints["param"] {
+		if params[name] == nil {
+			return fmt.Errorf("hint for unknown param %q", name)
+		}
+	}
+	tokens := tokenize(sql)
+	p := &parser{q, tokens, params}
+	if err := p.parse(); err != nil {
+		return err
+	}
+	columns := map[string]bool{}
+	for _, column := range q.Columns {
+		if hint, ok := hints["column"][column.Name]; ok && !column.Star {
+			column.Type = hint
+		}
+		columns[column.Name] = true
+	}
+	for name := range hints["column"] {
+		if !columns[name] {
+			return fmt.Errorf("hint for unknown column %q", name)
+		}
+	}
+	switch q.Kind {
+	case One, Many:
+		if len(q.Columns) == 0 {
+			return fmt.Errorf(":%s queries must return columns", q.Kind)
+		}
+	}
+	return nil
+}
+
+// replaceParams replaces @name parameters with ? placeholders, skipping over
+// strings, quoted identifiers and comments
+func replaceParams(sql string) (string, []string, error) {
+	var out strings.Builder
+	var args []string
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+		switch {
+		case ch == '\'' || ch == '"':
+			end := skipQuoted(sql, i, ch)
+			out.WriteString(sql[i:end])
+			i = end - 1
+		case ch == '-' && i+1 < len(sql) && sql[i+1] == '-':
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			out.WriteString(sql[i : i+end])
+			i += end - 1
+		case ch == '/' && i+1 < len(sql) && sql[i+1] == '*':
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 2
+			} else {
+				end += 2
+			}
+			out.WriteString(sql[i : i+2+end])
+			i += 2 + end - 1
+		case ch == '?':
+			return "", nil, fmt.Errorf("use @name parameters instead of ?")
+		case ch == '@' && i+1 < len(sql) && isIdentStart(sql[i+1]) && (i == 0 || sql[i-1] != '@'):
+			j := i + 1
+			for j < len(sql) && isIdent(sql[j]) {
+				j++
+			}
+			args = append(args, sql[i+1:j])
+			out.WriteByte('?')
+			i = j - 1
+		default:
+			out.WriteByte(ch)
+		}
+	}
+	return out.String(), args, nil
+}
+
+// dedent removes the indentation shared by each line
+func dedent(lines []string) string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range lines {
+		if len(line) < indent {
+			lines[i] = ""
+		} else if indent > 0 {
+			lines[i] = line[indent:]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// skipQuoted returns the offset after the quoted string starting at i.
+// Doubled quotes are escapes.
+func skipQuoted(sql string, i int, quote byte) int {
+	for j := i + 1; j < len(sql); j++ {
+		if sql[j] != quote {
+			continue
+		}
+		if j+1 < len(sql) && sql[j+1] == quote {
+			j++
+			continue
+		}
+		return j + 1
+	}
+	return len(sql)
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isIdent(ch byte) bool {
+	return isIdentStart(ch) || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,147 @@
+package sqlquery_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/sqlquery"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestSelect(t *testing.T) {
+	is := is.New(t)
+	queries, err := sqlquery.Parse("query/users.sql", []byte(`
+		-- Users by their email
+		-- name: FindUserByEmail :one
+		SELECT * FROM users WHERE email = @email;
+
+		-- name: ListPosts :many
+		SELECT p.id, p.title AS headline, u.name author, count(*) AS total
+		FROM posts AS p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.title LIKE @title AND @user_id = u.id
+		GROUP BY p.id, p.title, u.name
+		LIMIT @limit
+	`))
+	is.NoErr(err)
+	is.Equal(len(queries), 2)
+
+	q := queries[0]
+	is.Equal(q.Name, "FindUserByEmail")
+	is.Equal(q.Kind, sqlquery.One)
+	is.Equal(q.Line, 3)
+	is.Equal(q.SQL, "SELECT * FROM users WHERE email = ?")
+	is.Equal(q.Args, []string{"email"})
+	is.Equal(len(q.Tables), 1)
+	is.Equal(q.Tables[0].Name, "users")
+	is.Equal(len(q.Columns), 1)
+	is.True(q.Columns[0].Star)
+	is.Equal(q.SQL[q.Columns[0].Start:q.Columns[0].End], "*")
+	is.Equal(q.Params[0].Column.Name, "email")
+
+	q = queries[1]
+	is.Equal(q.Kind, sqlquery.Many)
+	is.True(strings.HasPrefix(q.SQL, "SELECT p.id, p.title AS headline, u.name author, count(*) AS total\nFROM posts AS p\nJOIN users u"))
+	is.Equal(q.Args, []string{"title", "user_id", "limit"})
+	is.Equal(len(q.Tables), 2)
+	is.Equal(q.Tables[0].Name, "posts")
+	is.Equal(q.Tables[0].Alias, "p")
+	is.Equal(q.Tables[1].Name, "users")
+	is.Equal(q.Tables[1].Alias, "u")
+	is.Equal(len(q.Columns), 4)
+	is.Equal(q.Columns[0].Name, "id")
+	is.Equal(*q.Columns[0].Ref, sqlquery.Ref{Table: "p", Name: "id"})
+	is.Equal(q.Columns[1].Name, "headline")
+	is.Equal(*q.Columns[1].Ref, sqlquery.Ref{Table: "p", Name: "title"})
+	is.Equal(q.Columns[2].Name, "author")
+	is.Equal(*q.Columns[2].Ref, sqlquery.Ref{Table: "u", Name: "name"})
+	is.Equal(q.Columns[3].Name, "total")
+	is.Equal(q.Columns[3].Ref, nil)
+	is.Equal(q.Columns[3].Type, "int64")
+	is.Equal(*q.Params[0].Column, sqlquery.Ref{Table: "p", Name: "title"})
+	is.Equal(*q.Params[1].Column, sqlquery.Ref{Table: "u", Name: "id"})
+	is.Equal(q.Params[2].Type, "int")
+}
+
+func TestInsertReturning(t *testing.T) {
+	is := is.New(t)
+	queries, err := sqlquery.Parse("query/users.sql", []byte(`
+		-- name: CreateUser :one
+		INSERT INTO users (name, email) VALUES (@name, lower(@email))
+		RETURNING id, created_at
+	`))
+	is.NoErr(err)
+	is.Equal(len(queries), 1)
+	q := queries[0]
+	is.Equal(q.Args, []string{"name", "email"})
+	is.Equal(*q.Params[0].Column, sqlquery.Ref{Table: "users", Name: "name"})
+	// Only lone placeholders are mapped
+	is.Equal(q.Params[1].Column, nil)
+	is.Equal(len(q.Columns), 2)
+	is.Equal(q.Columns[0].Name, "id")
+	is.Equal(q.Columns[1].Name, "created_at")
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	is := is.New(t)
+	queries, err := sqlquery.Parse("query/users.sql", []byte(`
+		-- name: RenameUser :execrows
+		UPDATE users SET name = @name WHERE id = @id AND name != @name;
+		-- name: DeleteUser :exec
+		DELETE FROM users WHERE id = @id;
+	`))
+	is.NoErr(err)
+	is.Equal(len(queries), 2)
+	is.Equal(queries[0].Kind, sqlquery.ExecRows)
+	is.Equal(queries[0].Args, []string{"name", "id", "name"})
+	is.Equal(len(queries[0].Params), 2)
+	is.Equal(queries[0].Tables[0].Name, "users")
+	is.Equal(len(queries[0].Columns), 0)
+	is.Equal(queries[1].Kind, sqlquery.Exec)
+	is.Equal(queries[1].Tables[0].Name, "users")
+}
+
+func TestHints(t *testing.T) {
+	is := is.New(t)
+	queries, err := sqlquery.Parse("query/users.sql", []byte(`
+		-- name: CountUsers :one
+		-- param: since time.Time
+		-- column: total int64
+		SELECT count(*) AS total FROM users WHERE created_at > @since
+	`))
+	is.NoErr(err)
+	q := queries[0]
+	is.Equal(q.Params[0].Type, "time.Time")
+	is.Equal(q.Columns[0].Type, "int64")
+}
+
+func TestSkipsStringsAndComments(t *testing.T) {
+	is := is.New(t)
+	queries, err := sqlquery.Parse("query/users.sql", []byte(`
+		-- name: FindAdmins :many
+		SELECT id FROM users -- filter by @role
+		WHERE email LIKE '%@admin.com' AND "role@" = @role
+	`))
+	is.NoErr(err)
+	is.Equal(queries[0].Args, []string{"role"})
+	is.Equal(*queries[0].Params[0].Column, sqlquery.Ref{Name: "role@"})
+}
+
+func TestErrors(t *testing.T) {
+	is := is.New(t)
+	_, err := sqlquery.Parse("query/users.sql", []byte(`SELECT 1`))
+	is.True(err != nil)
+	is.In(err.Error(), `missing a "-- name: Name :kind" annotation before query/users.sql:1`)
+	_, err = sqlquery.Parse("query/users.sql", []byte("-- name: Find :first\nSELECT 1"))
+	is.True(err != nil)
+	is.In(err.Error(), `unknown query kind ":first"`)
+	_, err = sqlquery.Parse("query/users.sql", []byte("-- name: Find :one\nSELECT * FROM users WHERE id = ?"))
+	is.True(err != nil)
+	is.In(err.Error(), "use @name parameters instead of ?")
+	_, err = sqlquery.Parse("query/users.sql", []byte("-- name: Count :one\nSELECT count(*) FROM users"))
+	is.True(err != nil)
+	is.In(err.Error(), `column "count(*)" needs an alias`)
+	_, err = sqlquery.Parse("query/users.sql", []byte("-- name: Delete :one\nDELETE FROM users"))
+	is.True(err != nil)
+	is.In(err.Error(), ":one queries must return columns")
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/package/parser"
+)
+
+type State struct {
+	Imports []*imports.Import
+	Tables  []*Table
+}
+
+// Table is generated from a model struct
+type Table struct {
+	Name     string // Name of the table (e.g. users)
+	Pascal   string // Pascal name of the model (e.g. User)
+	Singular string // Singular name used in errors (e.g. user)
+	Variable string // Variable name of the model (e.g. user)
+	Model    string // Qualified model type (e.g. model.User)
+	Key      *Column
+	Columns  []*Column
+
+	// Columns with conventions, nil when the model doesn't have them
+	CreatedAt *Column // Set on insert
+	UpdatedAt *Column // Set on insert and update
+	DeletedAt *Column // Set on delete instead of deleting the row
+	Version   *Column // Incremented on update to detect conflicting writes
+	Tenant    *Column // Scopes every query to the request's tenant
+
+	// Queries that are built at generation time
+	SelectSQL     string
+	FindSQL       string
+	InsertSQL     string
+	InsertAutoSQL string
+	UpdateSQL     string
+	DeleteSQL     string
+	ExistsSQL     string // Used to tell missing rows apart from version conflicts
+	PurgeSQL      string // Permanently deletes soft deleted rows
+	CountSQL      string
+	PageSQL       string // Selects a numbered page
+	PageAfterSQL  string // Selects the page after a cursor
+}
+
+// Column is generated from a model field
+type Column struct {
+	Name  string // Name of the column (e.g. first_name)
+	Field string // Name of the field (e.g. FirstName)
+	Type  string // Qualified type of the field, only set for primary keys
+	Param string // Parameter name when used as an argument (e.g. firstName)
+
+	field *parser.Field
+}
+
+// Fields returns the fields of the model, except the primary key
+func (t *Table) Fields() (columns []*Column) {
+	for _, column := range t.Columns {
+		if column == t.Key {
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// UpdateFields returns the fields that are set on update. The creation time,
+// deletion time, version and tenant are managed by the generated code.
+func (t *Table) UpdateFields() (columns []*Column) {
+	for _, column := range t.Fields() {
+		switch column {
+		case t.CreatedAt, t.DeletedAt, t.Version, t.Tenant:
+			continue
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// QueryState is used to generate typed functions from the SQL files in query/
+type QueryState struct {
+	Imports []*imports.Import
+	Queries []*Query
+}
+
+// Query is generated from a named query in a SQL file
+type Query struct {
+	Name   string   // Name of the method (e.g. FindUserByEmail)
+	Kind   string   // Kind of query: one, many, exec or execrows
+	Path   string   // Path to the SQL file (e.g. query/users.sql)
+	Const  string   // Name of the SQL constant (e.g. findUserByEmailSQL)
+	SQL    string   // SQL as a Go string literal
+	Params []*Param // Parameters of the method
+	Args   []string // Argument for each placeholder in the SQL
+	Result string   // Type of each result row (e.g. model.User)
+	Scans  []string // Fields of the result that are scanned into
+	Row    *Row     // Generated row type, nil when the result is a model
+	Tenant bool     // @tenant_id is filled in with the request's tenant
+}
+
+// Param is a typed parameter of a query method
+type Param struct {
+	Name string // Name of the parameter (e.g. email)
+	Type string // Qualified type of the parameter (e.g. string)
+}
+
+// Row is a generated struct for results that don't match a model
+type Row struct {
+	Name   string
+	Fields []*RowField
+}
+
+// RowField is a field within a generated row
+type RowField struct {
+	Name   string // Name of the field (e.g. CreatedAt)
+	Type   string // Qualified type of the field (e.g. time.Time)
+	Column string // Name of the column (e.g. created_at)
+}
@@ -0,0 +1,46 @@
+package event
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed event.gotext
+var template string
+
+var generator = gotemplate.MustParse("framework/event/event.gotext", template)
+
+// Generate the events package from state
+func Generate(state *State) ([]byte, error) {
+	return generator.Generate(state)
+}
+
+// New events generator
+func New(module *gomod.Module, parser *parser.Parser) *Generator {
+	return &Generator{module, parser}
+}
+
+// Generator for the events package, which has a typed method to publish each
+// event and registers the subscribers in event/
+type Generator struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := Load(fsys, g.module, g.parser)
+	if err != nil {
+		return fmt.Errorf("framework/event: unable to load. %w", err)
+	}
+	code, err := Generate(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
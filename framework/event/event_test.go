@@ -0,0 +1,102 @@
+package event_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestNoEvents(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/package/events"))
+}
+
+func TestInvalidHandle(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["event/welcome.go"] = `
+		package event
+		type SendWelcome struct {}
+		func (s *SendWelcome) Handle(email string) error {
+			return nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "expected SendWelcome.Handle in event/welcome.go to have the signature func(context.Context, T) error")
+}
+
+func TestPublish(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["event/user.go"] = `
+		package event
+		import (
+			"context"
+			"os"
+			"path/filepath"
+		)
+		type UserCreated struct {
+			Email string
+		}
+		type SendWelcome struct {}
+		func (s *SendWelcome) Handle(ctx context.Context, user *UserCreated) error {
+			return os.WriteFile(filepath.Join(os.Getenv("OUTBOX"), user.Email), []byte("welcome!"), 0644)
+		}
+	`
+	td.Files["controller/controller.go"] = `
+		package controller
+		import (
+			"context"
+			"app.com/bud/package/events"
+			"app.com/event"
+		)
+		type Controller struct {
+			Events *events.Publisher
+		}
+		func (c *Controller) Create(ctx context.Context, email string) error {
+			return c.Events.UserCreated(ctx, &event.UserCreated{Email: email})
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	outbox := t.TempDir()
+	cli := testcli.New(dir)
+	cli.Env["OUTBOX"] = outbox
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/package/events/events.go"))
+	req, err := http.NewRequest(http.MethodPost, "http://host/?email=a@b.co", nil)
+	is.NoErr(err)
+	req.Header.Set("Accept", "application/json")
+	res, err := app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.DiffHeaders(`
+		HTTP/1.1 204 No Content
+	`))
+	// In-process subscribers run before the event is published
+	data, err := os.ReadFile(filepath.Join(outbox, "a@b.co"))
+	is.NoErr(err)
+	is.Equal(string(data), "welcome!")
+	is.NoErr(app.Close())
+}
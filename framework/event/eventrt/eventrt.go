@@ -0,0 +1,221 @@
+// Package eventrt delivers events to the subscribers in event/. Events are
+// delivered in-process by default. Use a broker like NATS or Redis streams to
+// fan events out across processes.
+package eventrt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/redis"
+	"golang.org/x/sync/errgroup"
+)
+
+// Handler handles an event that's encoded as JSON
+type Handler func(ctx context.Context, data []byte) error
+
+// Broker delivers events across processes. Brokers must be safe for
+// concurrent use.
+type Broker interface {
+	// Publish the event to the topic
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe the group to the topic until the context is canceled. Each
+	// event is handled once per group, even when the group is subscribed in
+	// many processes.
+	Subscribe(ctx context.Context, topic, group string, handler Handler) error
+}
+
+// Load the bus from the environment
+func Load(log log.Interface) (*Bus, error) {
+	broker, err := LoadBroker(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	outbox, err := LoadOutbox(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	bus := New(log, broker)
+	bus.Outbox = outbox
+	return bus, nil
+}
+
+// LoadBroker loads the broker from the environment:
+//
+//	EVENT_BROKER=memory
+//
+// EVENT_BROKER may be memory, redis or nats. The memory broker delivers
+// events within the process, so it returns a nil broker. The Redis broker
+// connects using REDIS_URL and the NATS broker connects using NATS_URL.
+func LoadBroker(getenv func(key string) string) (Broker, error) {
+	switch broker := getenv("EVENT_BROKER"); broker {
+	case "", "memory":
+		return nil, nil
+	case "redis":
+		config, err := redis.LoadConfig(getenv)
+		if err != nil {
+			return nil, err
+		}
+		client, err := redis.Dial(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisBroker(client), nil
+	case "nats":
+		natsURL := getenv("NATS_URL")
+		if natsURL == "" {
+			return nil, fmt.Errorf("eventrt: the nats broker requires the NATS_URL environment variable")
+		}
+		return NewNATSBroker(natsURL)
+	default:
+		return nil, fmt.Errorf("eventrt: expected EVENT_BROKER to be memory, redis or nats, got %q", broker)
+	}
+}
+
+// New bus. Events are delivered in-process when the broker is nil.
+func New(log log.Interface, broker Broker) *Bus {
+	return &Bus{log: log.Named("event"), broker: broker}
+}
+
+// Bus publishes events to their subscribers
+type Bus struct {
+	// Outbox keeps published events in the database until they're relayed.
+	// Events are relayed right away when the outbox is nil.
+	Outbox        *Outbox
+	log           log.Interface
+	broker        Broker
+	subscriptions []*subscription
+}
+
+type subscription struct {
+	topic   string
+	group   string
+	handler Handler
+}
+
+// Subscribe the group to the topic. Subscribe before publishing or running
+// the bus.
+func (b *Bus) Subscribe(topic, group string, handler Handler) {
+	b.subscriptions = append(b.subscriptions, &subscription{topic, group, handler})
+}
+
+// Publish the event to the topic. The event is encoded as JSON. In-process
+// subscribers handle the event before Publish returns. Subscribers that fail
+// are logged, so they don't fail the publisher. With an outbox, the event is
+// written to the outbox instead and relayed by Run. The generated events
+// package has a typed method for each event, so prefer those.
+func (b *Bus) Publish(ctx context.Context, topic string, event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("eventrt: unable to encode %s. %w", topic, err)
+	}
+	if b.Outbox != nil {
+		return b.Outbox.Add(ctx, topic, data)
+	}
+	return b.deliver(ctx, topic, data)
+}
+
+// deliver the event to the broker or the in-process subscribers
+func (b *Bus) deliver(ctx context.Context, topic string, data []byte) error {
+	if b.broker != nil {
+		if err := b.broker.Publish(ctx, topic, data); err != nil {
+			return fmt.Errorf("eventrt: unable to publish %s. %w", topic, err)
+		}
+		return nil
+	}
+	for _, sub := range b.subscriptions {
+		if sub.topic == topic {
+			b.handle(ctx, sub, data)
+		}
+	}
+	return nil
+}
+
+// Run the subscribers and the outbox relay until the context is canceled.
+// Without a broker or an outbox, events are delivered when they're published,
+// so Run returns right away.
+func (b *Bus) Run(ctx context.Context) error {
+	if b.broker == nil && b.Outbox == nil {
+		return nil
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	if b.Outbox != nil {
+		eg.Go(func() error { return b.relay(ctx) })
+	}
+	if b.broker == nil {
+		return eg.Wait()
+	}
+	for _, sub := range b.subscriptions {
+		sub := sub
+		eg.Go(func() error {
+			return b.broker.Subscribe(ctx, sub.topic, sub.group, func(ctx context.Context, data []byte) error {
+				b.handle(ctx, sub, data)
+				return nil
+			})
+		})
+	}
+	return eg.Wait()
+}
+
+// relay the events in the outbox until the context is canceled. Events are
+// removed once they're delivered, so they're delivered at least once.
+func (b *Bus) relay(ctx context.Context) error {
+	for {
+		relayed, err := b.relayBatch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			b.log.Error("eventrt: unable to relay events", "error", err)
+		}
+		// Keep going while the outbox is full
+		if err == nil && relayed == b.Outbox.BatchSize {
+			continue
+		}
+		timer := time.NewTimer(b.Outbox.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *Bus) relayBatch(ctx context.Context) (int, error) {
+	events, err := b.Outbox.Claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i, event := range events {
+		// Stop at the first failure to keep the events in order. The rest are
+		// claimed again once their lease runs out.
+		if err := b.deliver(ctx, event.Topic, event.Payload); err != nil {
+			return i, err
+		}
+		if err := b.Outbox.Remove(ctx, event); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// handle the event, logging failures
+func (b *Bus) handle(ctx context.Context, sub *subscription, data []byte) {
+	if err := b.call(ctx, sub, data); err != nil {
+		b.log.Error("eventrt: subscriber failed", "event", sub.topic, "subscriber", sub.group, "error", err)
+	}
+}
+
+func (b *Bus) call(ctx context.Context, sub *subscription, data []byte) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("eventrt: %s panicked. %v", sub.group, e)
+		}
+	}()
+	return sub.handler(ctx, data)
+}
@@ -0,0 +1,303 @@
+package eventrt_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/framework/event/eventrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+)
+
+type UserCreated struct {
+	Email string
+}
+
+func TestPublishInProcess(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	bus := eventrt.New(log.Discard, nil)
+	var handled []string
+	bus.Subscribe("UserCreated", "SendWelcome", func(ctx context.Context, data []byte) error {
+		handled = append(handled, "SendWelcome "+string(data))
+		return nil
+	})
+	bus.Subscribe("UserCreated", "Fails", func(ctx context.Context, data []byte) error {
+		return errors.New("oops")
+	})
+	bus.Subscribe("UserCreated", "Panics", func(ctx context.Context, data []byte) error {
+		panic("oops")
+	})
+	bus.Subscribe("UserCreated", "Audit", func(ctx context.Context, data []byte) error {
+		handled = append(handled, "Audit "+string(data))
+		return nil
+	})
+	bus.Subscribe("UserDeleted", "Cleanup", func(ctx context.Context, data []byte) error {
+		handled = append(handled, "Cleanup")
+		return nil
+	})
+	// Failing subscribers don't stop the others or fail the publisher
+	is.NoErr(bus.Publish(ctx, "UserCreated", &UserCreated{Email: "alice@example.com"}))
+	is.Equal(handled, []string{
+		`SendWelcome {"Email":"alice@example.com"}`,
+		`Audit {"Email":"alice@example.com"}`,
+	})
+	// Run returns right away without a broker
+	is.NoErr(bus.Run(ctx))
+}
+
+func TestLoadBroker(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	broker, err := eventrt.LoadBroker(getenv)
+	is.NoErr(err)
+	is.Equal(broker, nil)
+	env["EVENT_BROKER"] = "kafka"
+	_, err = eventrt.LoadBroker(getenv)
+	is.Equal(err.Error(), `eventrt: expected EVENT_BROKER to be memory, redis or nats, got "kafka"`)
+	env["EVENT_BROKER"] = "nats"
+	_, err = eventrt.LoadBroker(getenv)
+	is.Equal(err.Error(), "eventrt: the nats broker requires the NATS_URL environment variable")
+	env["NATS_URL"] = "http://localhost:4222"
+	_, err = eventrt.LoadBroker(getenv)
+	is.Equal(err.Error(), `eventrt: expected NATS_URL to start with nats:// or tls://, got "http"`)
+	env["NATS_URL"] = "nats://token@localhost"
+	broker, err = eventrt.LoadBroker(getenv)
+	is.NoErr(err)
+	_, ok := broker.(*eventrt.NATSBroker)
+	is.True(ok)
+	env["EVENT_BROKER"] = "redis"
+	_, err = eventrt.LoadBroker(getenv)
+	is.Equal(err.Error(), "redis: missing the REDIS_URL environment variable")
+}
+
+// natsServer routes messages like a NATS server, delivering each message to
+// one subscriber per queue group
+type natsServer struct {
+	mu   sync.Mutex
+	subs map[string][]*natsSub
+}
+
+type natsSub struct {
+	conn  net.Conn
+	group string
+	sid   string
+}
+
+func (s *natsServer) serve(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	io.WriteString(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "PING":
+			io.WriteString(conn, "PONG\r\n")
+		case "SUB":
+			s.mu.Lock()
+			s.subs[fields[1]] = append(s.subs[fields[1]], &natsSub{conn, fields[2], fields[3]})
+			s.mu.Unlock()
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return
+			}
+			s.mu.Lock()
+			seen := map[string]bool{}
+			for _, sub := range s.subs[fields[1]] {
+				if seen[sub.group] {
+					continue
+				}
+				seen[sub.group] = true
+				fmt.Fprintf(sub.conn, "MSG %s %s %d\r\n%s", fields[1], sub.sid, size, data)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func TestNATS(t *testing.T) {
+	is := is.New(t)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer listener.Close()
+	server := &natsServer{subs: map[string][]*natsSub{}}
+	go server.serve(listener)
+	broker, err := eventrt.NewNATSBroker("nats://" + listener.Addr().String())
+	is.NoErr(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan string, 10)
+	// Two processes subscribe the same group
+	for i := 0; i < 2; i++ {
+		bus := eventrt.New(log.Discard, broker)
+		bus.Subscribe("UserCreated", "SendWelcome", func(ctx context.Context, data []byte) error {
+			received <- string(data)
+			return nil
+		})
+		go bus.Run(ctx)
+	}
+	// Wait for the subscriptions
+	for i := 0; i < 50; i++ {
+		server.mu.Lock()
+		subs := len(server.subs["events.UserCreated"])
+		server.mu.Unlock()
+		if subs == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	publisher := eventrt.New(log.Discard, broker)
+	is.NoErr(publisher.Publish(ctx, "UserCreated", &UserCreated{Email: "alice@example.com"}))
+	select {
+	case data := <-received:
+		is.Equal(data, `{"Email":"alice@example.com"}`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the event")
+	}
+	// Each group handles the event once
+	select {
+	case data := <-received:
+		t.Fatalf("unexpected event %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func openOutbox(t testing.TB) (*dbrt.DB, *eventrt.Outbox) {
+	t.Helper()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`create table bud_outbox (
+		id integer primary key,
+		topic text not null,
+		payload text not null,
+		created_at timestamp not null,
+		locked_until timestamp
+	)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, eventrt.NewOutbox(db)
+}
+
+func TestOutbox(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db, outbox := openOutbox(t)
+	outbox.PollInterval = 10 * time.Millisecond
+	bus := eventrt.New(log.Discard, nil)
+	bus.Outbox = outbox
+	received := make(chan string, 10)
+	bus.Subscribe("UserCreated", "SendWelcome", func(ctx context.Context, data []byte) error {
+		received <- string(data)
+		return nil
+	})
+	// Events are written in the transaction, so they're dropped on rollback
+	err := db.Transact(ctx, func(ctx context.Context) error {
+		if err := bus.Publish(ctx, "UserCreated", &UserCreated{Email: "rolled@back.com"}); err != nil {
+			return err
+		}
+		return errors.New("rollback")
+	})
+	is.Equal(err.Error(), "rollback")
+	is.NoErr(db.Transact(ctx, func(ctx context.Context) error {
+		return bus.Publish(ctx, "UserCreated", &UserCreated{Email: "alice@example.com"})
+	}))
+	is.NoErr(bus.Publish(ctx, "UserCreated", &UserCreated{Email: "bob@example.com"}))
+	// Nothing is delivered until the relay runs
+	is.Equal(len(received), 0)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- bus.Run(ctx) }()
+	for _, expect := range []string{`{"Email":"alice@example.com"}`, `{"Email":"bob@example.com"}`} {
+		select {
+		case data := <-received:
+			is.Equal(data, expect)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the event")
+		}
+	}
+	cancel()
+	is.NoErr(<-result)
+	// Relayed events are removed
+	var count int
+	is.NoErr(db.QueryRow(`select count(*) from bud_outbox`).Scan(&count))
+	is.Equal(count, 0)
+}
+
+func TestOutboxLease(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	_, outbox := openOutbox(t)
+	is.NoErr(outbox.Add(ctx, "UserCreated", []byte(`{}`)))
+	is.NoErr(outbox.Add(ctx, "UserDeleted", []byte(`{}`)))
+	events, err := outbox.Claim(ctx)
+	is.NoErr(err)
+	is.Equal(len(events), 2)
+	is.Equal(events[0].Topic, "UserCreated")
+	is.Equal(events[1].Topic, "UserDeleted")
+	// Claimed events aren't claimed again
+	events, err = outbox.Claim(ctx)
+	is.NoErr(err)
+	is.Equal(len(events), 0)
+	// Until their lease runs out, like when the relay crashed
+	_, outbox2 := openOutbox(t)
+	is.NoErr(outbox2.Add(ctx, "UserCreated", []byte(`{}`)))
+	outbox2.Lease = -time.Second
+	events, err = outbox2.Claim(ctx)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	events, err = outbox2.Claim(ctx)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.NoErr(outbox2.Remove(ctx, events[0]))
+	events, err = outbox2.Claim(ctx)
+	is.NoErr(err)
+	is.Equal(len(events), 0)
+}
+
+func TestLoadOutbox(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	outbox, err := eventrt.LoadOutbox(getenv)
+	is.NoErr(err)
+	is.True(outbox == nil)
+	env["EVENT_OUTBOX"] = "on"
+	_, err = eventrt.LoadOutbox(getenv)
+	is.Equal(err.Error(), "eventrt: the outbox requires the DATABASE_URL environment variable")
+	env["EVENT_OUTBOX"] = "yes"
+	_, err = eventrt.LoadOutbox(getenv)
+	is.Equal(err.Error(), `eventrt: expected EVENT_OUTBOX to be on or off, got "yes"`)
+}
@@ -0,0 +1,132 @@
+package eventrt
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+)
+
+// LoadOutbox loads the outbox from the environment:
+//
+//	EVENT_OUTBOX=on
+//
+// The outbox is off by default. It connects using DATABASE_URL.
+func LoadOutbox(getenv func(key string) string) (*Outbox, error) {
+	switch value := getenv("EVENT_OUTBOX"); value {
+	case "", "off":
+		return nil, nil
+	case "on":
+		databaseURL := getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("eventrt: the outbox requires the DATABASE_URL environment variable")
+		}
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewOutbox(db), nil
+	default:
+		return nil, fmt.Errorf("eventrt: expected EVENT_OUTBOX to be on or off, got %q", value)
+	}
+}
+
+// NewOutbox keeps published events in the bud_outbox table until they're
+// relayed to their subscribers. The table needs to be created with a
+// migration. For Postgres:
+//
+//	create table bud_outbox (
+//		id bigserial primary key,
+//		topic text not null,
+//		payload text not null,
+//		created_at timestamp not null,
+//		locked_until timestamp
+//	);
+//
+// Use "id integer primary key" for SQLite.
+//
+// Events that are published within a transaction, like during a request that
+// changes the database, are written in that transaction. They're only relayed
+// if the transaction commits and they're not lost if the process crashes.
+func NewOutbox(db *dbrt.DB) *Outbox {
+	return &Outbox{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Lease:        time.Minute,
+		db:           db,
+	}
+}
+
+// Outbox keeps events in the database until they're relayed
+type Outbox struct {
+	PollInterval time.Duration // How often to check for events
+	BatchSize    int           // Events to relay at once
+	// Lease is how long a relay has to publish the events it claimed before
+	// another relay claims them, in case it crashed
+	Lease time.Duration
+	db    *dbrt.DB
+}
+
+// OutboxEvent is an event in the outbox
+type OutboxEvent struct {
+	ID      int64
+	Topic   string
+	Payload []byte
+}
+
+// Add the event to the outbox
+func (o *Outbox) Add(ctx context.Context, topic string, data []byte) error {
+	query := o.db.Dialect().Rebind(`insert into "bud_outbox" ("topic", "payload", "created_at") values (?, ?, ?)`)
+	if _, err := o.db.ExecContext(ctx, query, topic, string(data), dbrt.Now()); err != nil {
+		return fmt.Errorf("eventrt: unable to add %s to the outbox. %w", topic, err)
+	}
+	return nil
+}
+
+// Claim the next batch of events, oldest first. Claimed events aren't claimed
+// again until the lease runs out.
+func (o *Outbox) Claim(ctx context.Context) ([]*OutboxEvent, error) {
+	lock := ""
+	if o.db.Dialect() == dbrt.Postgres {
+		lock = " for update skip locked"
+	}
+	query := o.db.Dialect().Rebind(`update "bud_outbox" set "locked_until" = ? ` +
+		`where "id" in (select "id" from "bud_outbox" where "locked_until" is null or "locked_until" <= ? ` +
+		`order by "id" limit ?` + lock + `) ` +
+		`returning "id", "topic", "payload"`)
+	now := dbrt.Now()
+	rows, err := o.db.QueryContext(ctx, query, now.Add(o.Lease), now, o.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("eventrt: unable to claim events from the outbox. %w", err)
+	}
+	defer rows.Close()
+	var events []*OutboxEvent
+	for rows.Next() {
+		event := new(OutboxEvent)
+		var payload string
+		if err := rows.Scan(&event.ID, &event.Topic, &payload); err != nil {
+			return nil, err
+		}
+		event.Payload = []byte(payload)
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Returning doesn't keep the order
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+// Remove the event once it's been relayed
+func (o *Outbox) Remove(ctx context.Context, event *OutboxEvent) error {
+	query := o.db.Dialect().Rebind(`delete from "bud_outbox" where "id" = ?`)
+	if _, err := o.db.ExecContext(ctx, query, event.ID); err != nil {
+		return fmt.Errorf("eventrt: unable to remove event %d from the outbox. %w", event.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package eventrt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// NewRedisBroker delivers events with Redis streams. Each topic is a stream
+// and each group is a consumer group on that stream.
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	hostname, _ := os.Hostname()
+	return &RedisBroker{
+		MaxLen:   10000,
+		client:   client,
+		prefix:   "events:",
+		consumer: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// RedisBroker delivers events with Redis streams
+type RedisBroker struct {
+	// MaxLen roughly caps the number of events kept in each stream
+	MaxLen   int64
+	client   *redis.Client
+	prefix   string
+	consumer string
+}
+
+var _ Broker = (*RedisBroker)(nil)
+
+func (b *RedisBroker) Publish(ctx context.Context, topic string, data []byte) error {
+	return b.client.XAdd(ctx, &goredis.XAddArgs{
+		Stream: b.prefix + topic,
+		MaxLen: b.MaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"data": data},
+	}).Err()
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, topic, group string, handler Handler) error {
+	stream := b.prefix + topic
+	// Start the group with the events published from now on
+	err := b.client.XGroupCreateMkStream(ctx, stream, group, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("eventrt: unable to create the %s group on %s. %w", group, stream, err)
+	}
+	for {
+		messages, err := b.read(ctx, stream, group)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("eventrt: unable to read from %s. %w", stream, err)
+		}
+		for _, message := range messages {
+			data, _ := message.Values["data"].(string)
+			handler(ctx, []byte(data))
+			if err := b.client.XAck(ctx, stream, group, message.ID).Err(); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("eventrt: unable to acknowledge %s on %s. %w", message.ID, stream, err)
+			}
+		}
+	}
+}
+
+// read the next events. Events that another consumer read but didn't
+// acknowledge within a minute, like when its process crashed, are claimed
+// first.
+func (b *RedisBroker) read(ctx context.Context, stream, group string) ([]goredis.XMessage, error) {
+	claimed, _, err := b.client.XAutoClaim(ctx, &goredis.XAutoClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: b.consumer,
+		MinIdle:  time.Minute,
+		Start:    "0-0",
+		Count:    10,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(claimed) > 0 {
+		return claimed, nil
+	}
+	streams, err := b.client.XReadGroup(ctx, &goredis.XReadGroupArgs{
+		Group:    group,
+		Consumer: b.consumer,
+		Streams:  []string{stream, ">"},
+		Count:    10,
+		Block:    time.Second,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var messages []goredis.XMessage
+	for _, stream := range streams {
+		messages = append(messages, stream.Messages...)
+	}
+	return messages, nil
+}
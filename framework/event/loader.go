@@ -0,0 +1,155 @@
+package event
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/gotext"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+	des, err := fs.ReadDir(fsys, "event")
+	if err != nil {
+		return nil, err
+	}
+	hasGo := false
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			hasGo = true
+			break
+		}
+	}
+	if !hasGo {
+		return nil, fs.ErrNotExist
+	}
+	loader := &loader{
+		imports: imports.New(),
+		module:  module,
+		parser:  parser,
+		events:  map[string]*Event{},
+	}
+	return loader.Load()
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	module  *gomod.Module
+	parser  *parser.Parser
+	events  map[string]*Event
+}
+
+// Load the event state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "event: unable to load")
+	state = new(State)
+	pkg, err := l.parser.Parse("event")
+	if err != nil {
+		l.Bail(err)
+	}
+	l.imports.AddStd("context")
+	l.imports.AddNamed("eventrt", "github.com/livebud/bud/framework/event/eventrt")
+	eventName := l.imports.AddNamed("event", l.module.Import("event"))
+	for _, stct := range pkg.Structs() {
+		if stct.Private() {
+			continue
+		}
+		handle := stct.Method("Handle")
+		if handle == nil {
+			// Structs without a Handle method are events
+			state.Events = l.addEvent(state.Events, stct.Name(), eventName+"."+stct.Name())
+			continue
+		}
+		subscriber := l.loadSubscriber(eventName, stct, handle)
+		state.Events = l.addEvent(state.Events, subscriber.Event, subscriber.Elem)
+		state.Subscribers = append(state.Subscribers, subscriber)
+	}
+	if len(state.Events) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	if len(state.Subscribers) > 0 {
+		l.imports.AddStd("encoding/json", "fmt")
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// addEvent adds the event once. Events are published by name, so two event
+// types can't share a name.
+func (l *loader) addEvent(events []*Event, name, elem string) []*Event {
+	if event, ok := l.events[name]; ok {
+		if event.Elem != elem {
+			l.Bail(fmt.Errorf("%s and %s have the same name. Rename one of the events", event.Elem, elem))
+		}
+		return events
+	}
+	event := &Event{Name: name, Elem: elem}
+	l.events[name] = event
+	return append(events, event)
+}
+
+// loadSubscriber checks that the Handle method has the
+// func(context.Context, T) error signature
+func (l *loader) loadSubscriber(eventName string, stct *parser.Struct, handle *parser.Function) *Subscriber {
+	subscriber := &Subscriber{
+		Name:     stct.Name(),
+		Path:     stct.File().Path(),
+		Type:     "*" + eventName + "." + stct.Name(),
+		Variable: gotext.Camel(stct.Name()) + "Subscriber",
+	}
+	params := handle.Params()
+	results := handle.Results()
+	if len(params) != 2 || len(results) != 1 || !results[0].IsError() ||
+		!l.isType(params[0].Type(), "context", "Context") {
+		l.Bail(fmt.Errorf("expected %s.Handle in %s to have the signature func(context.Context, T) error", subscriber.Name, subscriber.Path))
+	}
+	payload := params[1].Type()
+	subscriber.Payload = l.loadType(payload)
+	subscriber.Elem = subscriber.Payload
+	elem := payload
+	if star, ok := payload.(*parser.StarType); ok {
+		subscriber.Pointer = true
+		subscriber.Elem = l.loadType(star.Inner())
+		elem = star.Inner()
+	}
+	// The event's name is the name of its type
+	switch t := elem.(type) {
+	case *parser.IdentType:
+		subscriber.Event = t.Name()
+	case *parser.SelectorType:
+		subscriber.Event = t.Name()
+	default:
+		l.Bail(fmt.Errorf("expected %s.Handle in %s to handle a named type, got %s", subscriber.Name, subscriber.Path, payload))
+	}
+	return subscriber
+}
+
+func (l *loader) isType(t parser.Type, importPath, name string) bool {
+	ok, err := parser.IsImportType(t, importPath, name)
+	if err != nil {
+		l.Bail(err)
+	}
+	return ok
+}
+
+// loadType qualifies the event's type
+func (l *loader) loadType(dt parser.Type) string {
+	importPath, err := parser.ImportPath(parser.Innermost(dt))
+	if err != nil {
+		l.Bail(err)
+	}
+	if importPath == "" {
+		return dt.String()
+	}
+	name := l.imports.Add(importPath)
+	// Types declared alongside the subscriber are unqualified
+	if _, ok := parser.Innermost(dt).(*parser.IdentType); ok {
+		return parser.Qualify(dt, name).String()
+	}
+	return parser.Requalify(dt, name).String()
+}
@@ -0,0 +1,28 @@
+package event
+
+import "github.com/livebud/bud/internal/imports"
+
+type State struct {
+	Imports     []*imports.Import
+	Events      []*Event
+	Subscribers []*Subscriber
+}
+
+// Event is a struct in event/ or a payload that a subscriber handles
+type Event struct {
+	Name string // Name of the event's type, which is also its topic (e.g. UserCreated)
+	Elem string // Qualified event type without the pointer (e.g. event.UserCreated)
+}
+
+// Subscriber is a struct in event/ with a Handle(ctx context.Context, event T)
+// error method
+type Subscriber struct {
+	Name     string // Name of the struct (e.g. SendWelcome)
+	Path     string // Path to the file containing the struct (e.g. event/welcome.go)
+	Type     string // Qualified subscriber type (e.g. *event.SendWelcome)
+	Variable string // Variable holding the subscriber (e.g. sendWelcomeSubscriber)
+	Event    string // Name of the event it handles (e.g. UserCreated)
+	Payload  string // Qualified event type (e.g. *event.UserCreated)
+	Elem     string // Event type without the pointer (e.g. event.UserCreated)
+	Pointer  bool   // Whether the event is a pointer
+}
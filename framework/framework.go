@@ -1,6 +1,10 @@
 package framework
 
-import "io"
+import (
+	"io"
+
+	"github.com/livebud/bud/internal/trace"
+)
 
 // Flag is used by many of the framework generators
 type Flag struct {
@@ -8,9 +12,16 @@ type Flag struct {
 	Minify bool
 	Hot    bool
 
+	// Address of the live-reload server, e.g. "127.0.0.1:35729". Set by bud run
+	// once it's listening.
+	HotAddr string
+
 	// Comes from *bud.Input
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
 	Env    []string
+
+	// Records where the time goes when profiling. Nil otherwise.
+	Trace *trace.Recorder
 }
@@ -0,0 +1,180 @@
+package job_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/internal/versions"
+)
+
+func TestNoJobs(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/package/jobs"))
+}
+
+func TestInvalidRun(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["job/welcome.go"] = `
+		package job
+		type SendWelcome struct {}
+		func (s *SendWelcome) Run(email string) error {
+			return nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "expected SendWelcome.Run in job/welcome.go to have the signature func(context.Context, T) error")
+}
+
+func TestEnqueue(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["job/welcome.go"] = `
+		package job
+		import (
+			"context"
+			"os"
+			"path/filepath"
+		)
+		type Welcome struct {
+			Email string
+		}
+		type SendWelcome struct {}
+		func (s *SendWelcome) Run(ctx context.Context, welcome *Welcome) error {
+			return os.WriteFile(filepath.Join(os.Getenv("OUTBOX"), welcome.Email), []byte("welcome!"), 0644)
+		}
+	`
+	td.Files["controller/controller.go"] = `
+		package controller
+		import (
+			"context"
+			"app.com/bud/package/jobs"
+			"app.com/job"
+		)
+		type Controller struct {
+			Jobs *jobs.Client
+		}
+		func (c *Controller) Create(ctx context.Context, email string) error {
+			return c.Jobs.SendWelcome(ctx, &job.Welcome{Email: email})
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	outbox := t.TempDir()
+	cli := testcli.New(dir)
+	cli.Env["OUTBOX"] = outbox
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/package/jobs/jobs.go"))
+	req, err := http.NewRequest(http.MethodPost, "http://host/?email=a@b.co", nil)
+	is.NoErr(err)
+	req.Header.Set("Accept", "application/json")
+	res, err := app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.DiffHeaders(`
+		HTTP/1.1 204 No Content
+	`))
+	// Wait for the worker to send the email
+	var data []byte
+	for i := 0; i < 50; i++ {
+		data, err = os.ReadFile(filepath.Join(outbox, "a@b.co"))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	is.NoErr(err)
+	is.Equal(string(data), "welcome!")
+	is.NoErr(app.Close())
+}
+
+func TestDashboard(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["job/broken.go"] = `
+		package job
+		import (
+			"context"
+			"errors"
+		)
+		type Broken struct {}
+		func (b *Broken) Run(ctx context.Context, n int) error {
+			return errors.New("broken")
+		}
+	`
+	td.Files["controller/controller.go"] = `
+		package controller
+		import (
+			"context"
+			"time"
+			"app.com/bud/package/jobs"
+			"github.com/livebud/bud/framework/job/jobrt"
+		)
+		type Controller struct {
+			Jobs *jobs.Client
+		}
+		func (c *Controller) Create(ctx context.Context) error {
+			return c.Jobs.Broken(ctx, 1, jobrt.Delay(time.Hour))
+		}
+	`
+	td.Files["view/admin/jobs.svelte"] = `
+		<script>
+			export let queued = []
+		</script>
+		{#each queued as job}
+			<p>{job.name} {job.payload}</p>
+		{/each}
+	`
+	td.NodeModules["svelte"] = versions.Svelte
+	td.NodeModules["livebud"] = "*"
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["JOB_DASHBOARD_PASSWORD"] = "secret"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	req, err := http.NewRequest(http.MethodPost, "http://host/", nil)
+	is.NoErr(err)
+	req.Header.Set("Accept", "application/json")
+	res, err := app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.DiffHeaders(`
+		HTTP/1.1 204 No Content
+	`))
+	// The dashboard requires the password
+	res, err = app.Get("/bud/jobs")
+	is.NoErr(err)
+	is.Equal(res.Status(), 401)
+	req, err = http.NewRequest(http.MethodGet, "http://host/bud/jobs", nil)
+	is.NoErr(err)
+	req.SetBasicAuth("admin", "secret")
+	res, err = app.Do(req)
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.In(res.Body().String(), "<p>Broken 1</p>")
+	is.NoErr(app.Close())
+}
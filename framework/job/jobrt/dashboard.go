@@ -0,0 +1,193 @@
+package jobrt
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/framework/view/ssr"
+)
+
+// Renderer renders views. The view server is a renderer.
+type Renderer interface {
+	RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error)
+}
+
+// LoadDashboard loads the dashboard from the environment:
+//
+//	JOB_DASHBOARD_PASSWORD=secret
+//
+// The dashboard is off until the password is set.
+func LoadDashboard(client *Client, worker *Worker, renderer Renderer, route string) *Dashboard {
+	dashboard := NewDashboard(client, worker, renderer, route)
+	dashboard.Password = os.Getenv("JOB_DASHBOARD_PASSWORD")
+	return dashboard
+}
+
+// NewDashboard shows the jobs in the queue at /bud/jobs, rendering them with
+// the view at route. Failed and queued jobs can be retried right away or
+// deleted. The client's queue needs to be an Inspector.
+func NewDashboard(client *Client, worker *Worker, renderer Renderer, route string) *Dashboard {
+	return &Dashboard{
+		Limit:    100,
+		client:   client,
+		worker:   worker,
+		renderer: renderer,
+		route:    route,
+	}
+}
+
+// Dashboard shows the jobs in the queue
+type Dashboard struct {
+	// Password protects the dashboard with basic auth. Any username works.
+	// The dashboard is off without a password.
+	Password string
+	// Limit is the number of jobs listed in each state
+	Limit    int
+	client   *Client
+	worker   *Worker
+	renderer Renderer
+	route    string
+}
+
+// DashboardProps are passed to the dashboard's view
+type DashboardProps struct {
+	Queued    []*DashboardJob `json:"queued"`
+	Running   []*DashboardJob `json:"running"`
+	Failed    []*DashboardJob `json:"failed"`
+	Completed []*DashboardJob `json:"completed"`
+}
+
+// DashboardJob is a job in the dashboard
+type DashboardJob struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Payload  string    `json:"payload"`
+	Attempts int       `json:"attempts"`
+	RunAt    time.Time `json:"run_at"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// Middleware serves the dashboard at /bud/jobs:
+//
+//	GET    /bud/jobs           lists the jobs
+//	POST   /bud/jobs/:id/retry runs the job right away
+//	DELETE /bud/jobs/:id       deletes the job
+//
+// Forms can delete with a hidden _method=delete field.
+func (d *Dashboard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.Password == "" || (r.URL.Path != "/bud/jobs" && !strings.HasPrefix(r.URL.Path, "/bud/jobs/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !d.authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="jobs", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		// Browsers send basic auth along with cross-site forms, so only accept
+		// changes from the same site
+		if r.Method != http.MethodGet && !sameOrigin(r) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		inspector, ok := d.client.Queue.(Inspector)
+		if !ok {
+			http.Error(w, fmt.Sprintf("jobrt: the %T queue can't be inspected", d.client.Queue), http.StatusNotImplemented)
+			return
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/bud/jobs"), "/")
+		switch {
+		case path == "" && r.Method == http.MethodGet:
+			d.list(w, r, inspector)
+		case strings.HasSuffix(path, "/retry") && r.Method == http.MethodPost:
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/retry")
+			d.change(w, r, inspector.Requeue(r.Context(), id))
+		case path != "" && r.Method == http.MethodDelete:
+			d.change(w, r, inspector.Delete(r.Context(), strings.TrimPrefix(path, "/")))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+func (d *Dashboard) authorized(r *http.Request) bool {
+	_, password, ok := r.BasicAuth()
+	return ok && subtle.ConstantTimeCompare([]byte(password), []byte(d.Password)) == 1
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+func (d *Dashboard) list(w http.ResponseWriter, r *http.Request, inspector Inspector) {
+	props := new(DashboardProps)
+	for _, list := range []struct {
+		state State
+		jobs  *[]*DashboardJob
+	}{
+		{Queued, &props.Queued},
+		{Running, &props.Running},
+		{Failed, &props.Failed},
+	} {
+		jobs, err := inspector.Jobs(r.Context(), list.state, d.Limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		*list.jobs = dashboardJobs(jobs)
+	}
+	props.Completed = dashboardJobs(d.worker.Completed())
+	if len(props.Completed) > d.Limit {
+		props.Completed = props.Completed[:d.Limit]
+	}
+	responses, err := d.renderer.RenderBatch(&ssr.Request{Route: d.route, Props: props})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("jobrt: unable to render %s. %s", d.route, err), http.StatusInternalServerError)
+		return
+	}
+	if len(responses) != 1 {
+		http.Error(w, fmt.Sprintf("jobrt: unable to render %s", d.route), http.StatusInternalServerError)
+		return
+	}
+	responses[0].Write(w)
+}
+
+func dashboardJobs(jobs []*Job) []*DashboardJob {
+	list := make([]*DashboardJob, len(jobs))
+	for i, job := range jobs {
+		list[i] = &DashboardJob{
+			ID:       job.ID,
+			Name:     job.Name,
+			Payload:  string(job.Payload),
+			Attempts: job.Attempts,
+			RunAt:    job.RunAt,
+			Error:    job.Error,
+		}
+	}
+	return list
+}
+
+// change redirects back to the dashboard after a change
+func (d *Dashboard) change(w http.ResponseWriter, r *http.Request, err error) {
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/bud/jobs", http.StatusSeeOther)
+}
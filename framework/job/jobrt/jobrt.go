@@ -0,0 +1,219 @@
+// Package jobrt runs the jobs in job/ in the background. Jobs are pushed onto
+// a queue, then popped off and run by workers, which retry failed jobs with
+// exponential backoff.
+package jobrt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/metrics"
+	"github.com/livebud/bud/package/redis"
+)
+
+// Job is a queued call to a job's Run method
+type Job struct {
+	ID      string
+	Name    string // Name of the job (e.g. SendWelcome)
+	Payload []byte // Payload encoded as JSON
+	// Attempts is the number of times the job has been popped off the queue
+	Attempts int
+	// RunAt is when the job is ready to run
+	RunAt time.Time
+	// Error from the last attempt
+	Error string
+}
+
+// Queue stores jobs until they're run. Queues must be safe for concurrent use.
+type Queue interface {
+	// Push the job onto the queue, filling in its ID
+	Push(ctx context.Context, job *Job) error
+	// Pop claims the next job that's ready to run and increments its attempts.
+	// It returns nil without an error when no jobs are ready. Claimed jobs
+	// that aren't finished within the lease are popped again, in case the
+	// worker crashed.
+	Pop(ctx context.Context, lease time.Duration) (*Job, error)
+	// Done removes a job that succeeded
+	Done(ctx context.Context, job *Job) error
+	// Retry the job at job.RunAt
+	Retry(ctx context.Context, job *Job) error
+	// Fail keeps a job that's out of attempts without running it again
+	Fail(ctx context.Context, job *Job) error
+}
+
+// State of a job in the queue
+type State string
+
+const (
+	Queued  State = "queued"  // Waiting to run
+	Running State = "running" // Claimed by a worker
+	Failed  State = "failed"  // Out of attempts
+)
+
+// ErrNotFound is returned when a job isn't in the queue
+var ErrNotFound = errors.New("jobrt: job not found")
+
+// Inspector lists and manages the jobs in a queue. The dashboard requires the
+// queue to be an inspector.
+type Inspector interface {
+	// Jobs lists up to limit jobs in the state. Queued and running jobs are
+	// listed in the order they run, failed jobs with the latest failure first.
+	Jobs(ctx context.Context, state State, limit int) ([]*Job, error)
+	// Requeue the job to run right away with its attempts reset
+	Requeue(ctx context.Context, id string) error
+	// Delete the job
+	Delete(ctx context.Context, id string) error
+}
+
+// Counter counts the jobs in a queue. The job_queue_depth metric requires the
+// queue to be a counter.
+type Counter interface {
+	// Count the jobs in the state
+	Count(ctx context.Context, state State) (int, error)
+}
+
+// Load the client from the environment. Jobs are scheduled with the clock and
+// the Redis queue gives jobs IDs from ids.
+func Load(clock clock.Clock, ids idgen.IDGenerator) (*Client, error) {
+	queue, err := LoadQueue(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	switch queue := queue.(type) {
+	case *MemoryQueue:
+		queue.Clock = clock
+	case *RedisQueue:
+		queue.Clock = clock
+		queue.IDs = ids
+	case *SQLQueue:
+		queue.db.UseClock(clock)
+	}
+	client := New(queue)
+	client.Clock = clock
+	// Report the depth of the queue at /metrics
+	if counter, ok := queue.(Counter); ok {
+		metrics.Register(&depth{counter})
+	}
+	return client, nil
+}
+
+// LoadQueue loads the queue from the environment:
+//
+//	JOB_QUEUE=memory
+//
+// JOB_QUEUE may be memory, redis or sql. The Redis queue connects using
+// REDIS_URL and the SQL queue connects using DATABASE_URL.
+func LoadQueue(getenv func(key string) string) (Queue, error) {
+	switch queue := getenv("JOB_QUEUE"); queue {
+	case "", "memory":
+		return NewMemoryQueue(), nil
+	case "redis":
+		config, err := redis.LoadConfig(getenv)
+		if err != nil {
+			return nil, err
+		}
+		client, err := redis.Dial(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisQueue(client), nil
+	case "sql":
+		databaseURL := getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("jobrt: the sql queue requires the DATABASE_URL environment variable")
+		}
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLQueue(db), nil
+	default:
+		return nil, fmt.Errorf("jobrt: expected JOB_QUEUE to be memory, redis or sql, got %q", queue)
+	}
+}
+
+// New client that pushes jobs onto the queue
+func New(queue Queue) *Client {
+	return &Client{queue, clock.Load()}
+}
+
+// Client enqueues jobs
+type Client struct {
+	Queue Queue
+	// Clock schedules jobs. Defaults to the system clock.
+	Clock clock.Clock
+}
+
+// Option configures an enqueued job
+type Option func(job *Job)
+
+// Delay running the job
+func Delay(d time.Duration) Option {
+	return func(job *Job) {
+		job.RunAt = job.RunAt.Add(d)
+	}
+}
+
+// At runs the job at a time
+func At(t time.Time) Option {
+	return func(job *Job) {
+		job.RunAt = t.UTC().Truncate(time.Microsecond)
+	}
+}
+
+// Enqueue the named job with a payload that's encoded as JSON. The generated
+// jobs package has a typed method for each job, so prefer those.
+func (c *Client) Enqueue(ctx context.Context, name string, payload interface{}, options ...Option) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("jobrt: unable to encode the payload of %s. %w", name, err)
+	}
+	job := &Job{
+		Name:    name,
+		Payload: data,
+		RunAt:   dbrt.Timestamp(c.Clock.Now()),
+	}
+	for _, option := range options {
+		option(job)
+	}
+	if err := c.Queue.Push(ctx, job); err != nil {
+		return fmt.Errorf("jobrt: unable to enqueue %s. %w", name, err)
+	}
+	return nil
+}
+
+// depth reports the number of jobs in each state when metrics are scraped
+type depth struct {
+	counter Counter
+}
+
+func (d *depth) Describe() metrics.Desc {
+	return metrics.Desc{
+		Name: "job_queue_depth",
+		Help: "Jobs in the queue by state.",
+		Type: metrics.GaugeType,
+	}
+}
+
+func (d *depth) Collect(ctx context.Context) (samples []metrics.Sample) {
+	for _, state := range []State{Queued, Running, Failed} {
+		count, err := d.counter.Count(ctx, state)
+		if err != nil {
+			// Leave out the states that can't be counted right now
+			continue
+		}
+		samples = append(samples, metrics.Sample{
+			Name:   "job_queue_depth",
+			Labels: []metrics.Label{{Name: "state", Value: string(state)}},
+			Value:  float64(count),
+		})
+	}
+	return samples
+}
@@ -0,0 +1,434 @@
+package jobrt_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/framework/job/jobrt"
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/log"
+)
+
+func TestLoadQueue(t *testing.T) {
+	is := is.New(t)
+	getenv := func(env map[string]string) func(string) string {
+		return func(key string) string { return env[key] }
+	}
+	queue, err := jobrt.LoadQueue(getenv(nil))
+	is.NoErr(err)
+	_, ok := queue.(*jobrt.MemoryQueue)
+	is.True(ok)
+	queue, err = jobrt.LoadQueue(getenv(map[string]string{
+		"JOB_QUEUE":    "sql",
+		"DATABASE_URL": "sqlite://" + filepath.Join(t.TempDir(), "app.db"),
+	}))
+	is.NoErr(err)
+	_, ok = queue.(*jobrt.SQLQueue)
+	is.True(ok)
+	_, err = jobrt.LoadQueue(getenv(map[string]string{"JOB_QUEUE": "sql"}))
+	is.True(err != nil)
+	_, err = jobrt.LoadQueue(getenv(map[string]string{"JOB_QUEUE": "kafka"}))
+	is.Equal(err.Error(), `jobrt: expected JOB_QUEUE to be memory, redis or sql, got "kafka"`)
+}
+
+// testQueue runs through the lifecycle of a job
+func testQueue(t *testing.T, queue jobrt.Queue) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := jobrt.New(queue)
+	is.NoErr(client.Enqueue(ctx, "Later", map[string]int{"n": 1}, jobrt.Delay(time.Hour)))
+	is.NoErr(client.Enqueue(ctx, "Welcome", map[string]string{"email": "a@b.co"}))
+	// Jobs in the future aren't ready
+	job, err := queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.True(job != nil)
+	is.Equal(job.Name, "Welcome")
+	is.Equal(string(job.Payload), `{"email":"a@b.co"}`)
+	is.Equal(job.Attempts, 1)
+	job2, err := queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job2, nil)
+	// Retried jobs are popped again once they're ready
+	job.RunAt = dbrt.Now()
+	job.Error = "oops"
+	is.NoErr(queue.Retry(ctx, job))
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.True(job != nil)
+	is.Equal(job.Name, "Welcome")
+	is.Equal(job.Attempts, 2)
+	is.Equal(job.Error, "oops")
+	is.NoErr(queue.Done(ctx, job))
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job, nil)
+}
+
+// testInspector lists, counts, requeues and deletes jobs
+func testInspector(t *testing.T, queue interface {
+	jobrt.Queue
+	jobrt.Inspector
+	jobrt.Counter
+}) {
+	is := is.New(t)
+	ctx := context.Background()
+	client := jobrt.New(queue)
+	is.NoErr(client.Enqueue(ctx, "Later", nil, jobrt.Delay(time.Hour)))
+	is.NoErr(client.Enqueue(ctx, "Broken", nil))
+	is.NoErr(client.Enqueue(ctx, "Running", nil, jobrt.Delay(time.Millisecond)))
+	job, err := queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job.Name, "Broken")
+	job.Error = "broken"
+	is.NoErr(queue.Fail(ctx, job))
+	time.Sleep(2 * time.Millisecond)
+	running, err := queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(running.Name, "Running")
+	queued, err := queue.Jobs(ctx, jobrt.Queued, 10)
+	is.NoErr(err)
+	is.Equal(len(queued), 1)
+	is.Equal(queued[0].Name, "Later")
+	jobs, err := queue.Jobs(ctx, jobrt.Running, 10)
+	is.NoErr(err)
+	is.Equal(len(jobs), 1)
+	is.Equal(jobs[0].Name, "Running")
+	failed, err := queue.Jobs(ctx, jobrt.Failed, 10)
+	is.NoErr(err)
+	is.Equal(len(failed), 1)
+	is.Equal(failed[0].Name, "Broken")
+	is.Equal(failed[0].Error, "broken")
+	is.Equal(failed[0].Attempts, 1)
+	for _, state := range []jobrt.State{jobrt.Queued, jobrt.Running, jobrt.Failed} {
+		count, err := queue.Count(ctx, state)
+		is.NoErr(err)
+		is.Equal(count, 1)
+	}
+	// Requeued jobs run right away with their attempts reset
+	is.NoErr(queue.Requeue(ctx, failed[0].ID))
+	failed, err = queue.Jobs(ctx, jobrt.Failed, 10)
+	is.NoErr(err)
+	is.Equal(len(failed), 0)
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job.Name, "Broken")
+	is.Equal(job.Attempts, 1)
+	// Deleted jobs are gone
+	is.NoErr(queue.Delete(ctx, queued[0].ID))
+	queued, err = queue.Jobs(ctx, jobrt.Queued, 10)
+	is.NoErr(err)
+	is.Equal(len(queued), 0)
+	is.True(errors.Is(queue.Delete(ctx, "404"), jobrt.ErrNotFound))
+	is.True(errors.Is(queue.Requeue(ctx, "404"), jobrt.ErrNotFound))
+}
+
+func TestMemoryQueue(t *testing.T) {
+	testQueue(t, jobrt.NewMemoryQueue())
+	testInspector(t, jobrt.NewMemoryQueue())
+}
+
+func TestFrozenClock(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	queue := jobrt.NewMemoryQueue()
+	queue.Clock = frozen
+	client := jobrt.New(queue)
+	client.Clock = frozen
+	is.NoErr(client.Enqueue(ctx, "Later", nil, jobrt.Delay(time.Hour)))
+	job, err := queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job, nil)
+	// Delayed jobs run once the clock catches up
+	frozen.Advance(time.Hour)
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job.Name, "Later")
+	is.Equal(job.RunAt, time.Date(2022, 1, 1, 1, 0, 0, 0, time.UTC))
+}
+
+func openSQLQueue(t *testing.T) (*dbrt.DB, *jobrt.SQLQueue) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`create table bud_jobs (
+		id integer primary key,
+		name text not null,
+		payload text not null,
+		attempts integer not null default 0,
+		run_at timestamp not null,
+		locked_until timestamp,
+		failed_at timestamp,
+		last_error text
+	)`)
+	is.NoErr(err)
+	return db, jobrt.NewSQLQueue(db)
+}
+
+func TestSQLQueue(t *testing.T) {
+	is := is.New(t)
+	db, queue := openSQLQueue(t)
+	testQueue(t, queue)
+	// Jobs that outlive their lease are popped again
+	ctx := context.Background()
+	is.NoErr(jobrt.New(queue).Enqueue(ctx, "Crash", nil))
+	job, err := queue.Pop(ctx, -time.Second)
+	is.NoErr(err)
+	is.Equal(job.Name, "Crash")
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job.Name, "Crash")
+	is.Equal(job.Attempts, 2)
+	job.Error = "crashed"
+	is.NoErr(queue.Fail(ctx, job))
+	job, err = queue.Pop(ctx, time.Minute)
+	is.NoErr(err)
+	is.Equal(job, nil)
+	var lastError string
+	is.NoErr(db.QueryRow(`select last_error from bud_jobs where failed_at is not null`).Scan(&lastError))
+	is.Equal(lastError, "crashed")
+}
+
+func TestSQLInspector(t *testing.T) {
+	_, queue := openSQLQueue(t)
+	testInspector(t, queue)
+}
+
+func TestWorker(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	queue := jobrt.NewMemoryQueue()
+	client := jobrt.New(queue)
+	worker, err := jobrt.NewWorker(log.Discard, client)
+	is.NoErr(err)
+	worker.MaxAttempts = 3
+	worker.Backoff = func(int) time.Duration { return 0 }
+	calls := 0
+	worker.Handle("Flaky", func(ctx context.Context, payload []byte) error {
+		calls++
+		if calls < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+	worker.Handle("Broken", func(ctx context.Context, payload []byte) error {
+		panic("broken")
+	})
+	is.NoErr(client.Enqueue(ctx, "Flaky", nil))
+	for i := 0; i < 3; i++ {
+		ran, err := worker.Work(ctx)
+		is.NoErr(err)
+		is.True(ran)
+	}
+	is.Equal(calls, 3)
+	is.Equal(queue.Len(), 0)
+	is.Equal(len(queue.Failed()), 0)
+	// Panics are retried, then fail
+	is.NoErr(client.Enqueue(ctx, "Broken", nil))
+	for i := 0; i < 3; i++ {
+		ran, err := worker.Work(ctx)
+		is.NoErr(err)
+		is.True(ran)
+	}
+	ran, err := worker.Work(ctx)
+	is.NoErr(err)
+	is.True(!ran)
+	failed := queue.Failed()
+	is.Equal(len(failed), 1)
+	is.Equal(failed[0].Attempts, 3)
+	is.Equal(failed[0].Error, "jobrt: Broken panicked. broken")
+	// Unknown jobs fail right away
+	is.NoErr(client.Enqueue(ctx, "Missing", nil))
+	ran, err = worker.Work(ctx)
+	is.NoErr(err)
+	is.True(ran)
+	failed = queue.Failed()
+	is.Equal(len(failed), 2)
+	is.Equal(failed[1].Error, `jobrt: unknown job "Missing"`)
+}
+
+func TestWorkerRun(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	client := jobrt.New(jobrt.NewMemoryQueue())
+	worker, err := jobrt.NewWorker(log.Discard, client)
+	is.NoErr(err)
+	worker.PollInterval = 10 * time.Millisecond
+	done := make(chan string, 1)
+	worker.Handle("Ping", func(ctx context.Context, payload []byte) error {
+		done <- string(payload)
+		return nil
+	})
+	result := make(chan error, 1)
+	go func() { result <- worker.Run(ctx) }()
+	is.NoErr(client.Enqueue(ctx, "Ping", "pong"))
+	select {
+	case payload := <-done:
+		is.Equal(payload, `"pong"`)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the job")
+	}
+	cancel()
+	is.NoErr(<-result)
+}
+
+func TestWorkerDrain(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	queue := jobrt.NewMemoryQueue()
+	client := jobrt.New(queue)
+	worker, err := jobrt.NewWorker(log.Discard, client)
+	is.NoErr(err)
+	worker.Concurrency = 2
+	worker.PollInterval = 10 * time.Millisecond
+	worker.ShutdownTimeout = 100 * time.Millisecond
+	worker.Backoff = func(int) time.Duration { return time.Hour }
+	started := make(chan string, 2)
+	worker.Handle("Quick", func(ctx context.Context, payload []byte) error {
+		started <- "Quick"
+		time.Sleep(20 * time.Millisecond)
+		return ctx.Err()
+	})
+	worker.Handle("Slow", func(ctx context.Context, payload []byte) error {
+		started <- "Slow"
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	is.NoErr(client.Enqueue(ctx, "Quick", nil))
+	is.NoErr(client.Enqueue(ctx, "Slow", nil))
+	result := make(chan error, 1)
+	go func() { result <- worker.Run(ctx) }()
+	for i := 0; i < 2; i++ {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the jobs to start")
+		}
+	}
+	// Running jobs keep running after the worker is canceled
+	cancel()
+	select {
+	case err := <-result:
+		is.NoErr(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the worker to drain")
+	}
+	// The quick job finished and the slow job was canceled and retried
+	is.Equal(queue.Len(), 1)
+	is.Equal(len(queue.Failed()), 0)
+}
+
+func TestWorkerConfig(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("JOB_CONCURRENCY", "0")
+	t.Setenv("JOB_MAX_ATTEMPTS", "2")
+	t.Setenv("JOB_SHUTDOWN_TIMEOUT", "1m")
+	worker, err := jobrt.NewWorker(log.Discard, jobrt.New(jobrt.NewMemoryQueue()))
+	is.NoErr(err)
+	is.Equal(worker.Concurrency, 0)
+	is.Equal(worker.MaxAttempts, 2)
+	is.Equal(worker.ShutdownTimeout, time.Minute)
+	// The worker is off
+	is.NoErr(worker.Run(context.Background()))
+	t.Setenv("JOB_CONCURRENCY", "lots")
+	_, err = jobrt.NewWorker(log.Discard, jobrt.New(jobrt.NewMemoryQueue()))
+	is.Equal(err.Error(), `jobrt: expected JOB_CONCURRENCY to be a positive number, got "lots"`)
+	t.Setenv("JOB_CONCURRENCY", "1")
+	t.Setenv("JOB_SHUTDOWN_TIMEOUT", "30")
+	_, err = jobrt.NewWorker(log.Discard, jobrt.New(jobrt.NewMemoryQueue()))
+	is.Equal(err.Error(), `jobrt: expected JOB_SHUTDOWN_TIMEOUT to be a duration like 30s, got "30"`)
+}
+
+type renderer struct {
+	requests []*ssr.Request
+}
+
+func (r *renderer) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	r.requests = append(r.requests, requests...)
+	props, err := json.Marshal(requests[0].Props)
+	if err != nil {
+		return nil, err
+	}
+	return []*ssr.Response{{Status: 200, Body: string(props)}}, nil
+}
+
+func TestDashboard(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	queue := jobrt.NewMemoryQueue()
+	client := jobrt.New(queue)
+	worker, err := jobrt.NewWorker(log.Discard, client)
+	is.NoErr(err)
+	worker.MaxAttempts = 1
+	worker.Handle("Welcome", func(ctx context.Context, payload []byte) error { return nil })
+	worker.Handle("Broken", func(ctx context.Context, payload []byte) error { return errors.New("broken") })
+	is.NoErr(client.Enqueue(ctx, "Welcome", "a@b.co"))
+	is.NoErr(client.Enqueue(ctx, "Broken", nil))
+	is.NoErr(client.Enqueue(ctx, "Later", nil, jobrt.Delay(time.Hour)))
+	for i := 0; i < 2; i++ {
+		_, err := worker.Work(ctx)
+		is.NoErr(err)
+	}
+	renderer := &renderer{}
+	dashboard := jobrt.NewDashboard(client, worker, renderer, "/admin/jobs")
+	handler := dashboard.Middleware(http.NotFoundHandler())
+	serve := func(method, path, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Origin", "http://example.com")
+		if password != "" {
+			req.SetBasicAuth("admin", password)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	// Off without a password
+	is.Equal(serve("GET", "/bud/jobs", "").Code, 404)
+	dashboard.Password = "secret"
+	rec := serve("GET", "/bud/jobs", "")
+	is.Equal(rec.Code, 401)
+	is.In(rec.Header().Get("WWW-Authenticate"), "Basic")
+	is.Equal(serve("GET", "/bud/jobs", "wrong").Code, 401)
+	rec = serve("GET", "/bud/jobs", "secret")
+	is.Equal(rec.Code, 200)
+	is.Equal(renderer.requests[0].Route, "/admin/jobs")
+	props := new(jobrt.DashboardProps)
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), props))
+	is.Equal(len(props.Queued), 1)
+	is.Equal(props.Queued[0].Name, "Later")
+	is.Equal(len(props.Running), 0)
+	is.Equal(len(props.Failed), 1)
+	is.Equal(props.Failed[0].Error, "broken")
+	is.Equal(len(props.Completed), 1)
+	is.Equal(props.Completed[0].Name, "Welcome")
+	is.Equal(props.Completed[0].Payload, `"a@b.co"`)
+	// Retry the failed job
+	rec = serve("POST", "/bud/jobs/"+props.Failed[0].ID+"/retry", "secret")
+	is.Equal(rec.Code, 303)
+	is.Equal(rec.Header().Get("Location"), "/bud/jobs")
+	failed, err := queue.Jobs(ctx, jobrt.Failed, 10)
+	is.NoErr(err)
+	is.Equal(len(failed), 0)
+	// Delete the queued job
+	is.Equal(serve("DELETE", "/bud/jobs/"+props.Queued[0].ID, "secret").Code, 303)
+	is.Equal(serve("DELETE", "/bud/jobs/"+props.Queued[0].ID, "secret").Code, 404)
+	// Changes from other sites are forbidden
+	req := httptest.NewRequest("DELETE", "/bud/jobs/1", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.SetBasicAuth("admin", "secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 403)
+	// Other routes pass through
+	is.Equal(serve("GET", "/bud/jobsite", "secret").Code, 404)
+}
@@ -0,0 +1,199 @@
+package jobrt
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+)
+
+// NewMemoryQueue keeps jobs in memory. Jobs are lost on restart and aren't
+// shared between processes, so it's only suitable for development and
+// testing.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		Clock:   clock.Load(),
+		running: map[string]*Job{},
+	}
+}
+
+// MemoryQueue keeps jobs in memory
+type MemoryQueue struct {
+	// Clock decides when jobs are ready to run. Freeze it in tests to control
+	// when delayed jobs run.
+	Clock clock.Clock
+
+	mu      sync.Mutex
+	nextID  int
+	ready   []*Job // Sorted by RunAt
+	running map[string]*Job
+	failed  []*Job
+}
+
+var _ Queue = (*MemoryQueue)(nil)
+var _ Inspector = (*MemoryQueue)(nil)
+var _ Counter = (*MemoryQueue)(nil)
+
+func (q *MemoryQueue) Push(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	job.ID = strconv.Itoa(q.nextID)
+	q.insert(job)
+	return nil
+}
+
+// insert the job, keeping the jobs sorted by when they run
+func (q *MemoryQueue) insert(job *Job) {
+	clone := *job
+	i := sort.Search(len(q.ready), func(i int) bool {
+		return q.ready[i].RunAt.After(job.RunAt)
+	})
+	q.ready = append(q.ready, nil)
+	copy(q.ready[i+1:], q.ready[i:])
+	q.ready[i] = &clone
+}
+
+func (q *MemoryQueue) Pop(ctx context.Context, lease time.Duration) (*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.ready) == 0 || q.ready[0].RunAt.After(q.Clock.Now()) {
+		return nil, nil
+	}
+	job := q.ready[0]
+	q.ready = q.ready[1:]
+	job.Attempts++
+	q.running[job.ID] = job
+	clone := *job
+	return &clone, nil
+}
+
+func (q *MemoryQueue) Done(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, job.ID)
+	return nil
+}
+
+func (q *MemoryQueue) Retry(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, job.ID)
+	q.insert(job)
+	return nil
+}
+
+func (q *MemoryQueue) Fail(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.running, job.ID)
+	clone := *job
+	q.failed = append(q.failed, &clone)
+	return nil
+}
+
+// Len returns the number of jobs that are waiting or running
+func (q *MemoryQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.ready) + len(q.running)
+}
+
+// Failed returns the jobs that ran out of attempts
+func (q *MemoryQueue) Failed() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	failed := make([]*Job, len(q.failed))
+	copy(failed, q.failed)
+	return failed
+}
+
+func (q *MemoryQueue) Jobs(ctx context.Context, state State, limit int) ([]*Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobs []*Job
+	switch state {
+	case Queued:
+		jobs = q.ready
+	case Running:
+		for _, job := range q.running {
+			jobs = append(jobs, job)
+		}
+		sort.Slice(jobs, func(i, j int) bool {
+			return jobs[i].RunAt.Before(jobs[j].RunAt)
+		})
+	case Failed:
+		for i := len(q.failed) - 1; i >= 0; i-- {
+			jobs = append(jobs, q.failed[i])
+		}
+	}
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	clones := make([]*Job, len(jobs))
+	for i, job := range jobs {
+		clone := *job
+		clones[i] = &clone
+	}
+	return clones, nil
+}
+
+func (q *MemoryQueue) Count(ctx context.Context, state State) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch state {
+	case Queued:
+		return len(q.ready), nil
+	case Running:
+		return len(q.running), nil
+	case Failed:
+		return len(q.failed), nil
+	}
+	return 0, nil
+}
+
+func (q *MemoryQueue) Requeue(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := q.remove(id)
+	if job == nil {
+		return ErrNotFound
+	}
+	job.Attempts = 0
+	job.RunAt = q.Clock.Now().UTC()
+	q.insert(job)
+	return nil
+}
+
+func (q *MemoryQueue) Delete(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remove(id) == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// remove the job from the queue, returning nil if it's not there
+func (q *MemoryQueue) remove(id string) *Job {
+	if job, ok := q.running[id]; ok {
+		delete(q.running, id)
+		return job
+	}
+	for i, job := range q.ready {
+		if job.ID == id {
+			q.ready = append(q.ready[:i], q.ready[i+1:]...)
+			return job
+		}
+	}
+	for i, job := range q.failed {
+		if job.ID == id {
+			q.failed = append(q.failed[:i], q.failed[i+1:]...)
+			return job
+		}
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package jobrt
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// NewRedisQueue keeps jobs in Redis. Each job is a hash, while sorted sets
+// track when jobs are ready to run and when their lease runs out.
+func NewRedisQueue(client *redis.Client) *RedisQueue {
+	return &RedisQueue{
+		Clock:  clock.Load(),
+		IDs:    idgen.Load(),
+		client: client,
+		prefix: "jobs:",
+	}
+}
+
+// RedisQueue keeps jobs in Redis
+type RedisQueue struct {
+	// Clock decides when jobs are ready to run
+	Clock clock.Clock
+	// IDs generates job IDs
+	IDs idgen.IDGenerator
+
+	client *redis.Client
+	prefix string
+}
+
+var _ Queue = (*RedisQueue)(nil)
+var _ Inspector = (*RedisQueue)(nil)
+var _ Counter = (*RedisQueue)(nil)
+
+func (q *RedisQueue) key(name string) string {
+	return q.prefix + name
+}
+
+func (q *RedisQueue) Push(ctx context.Context, job *Job) error {
+	job.ID = q.IDs.NewID()
+	pipe := q.client.TxPipeline()
+	pipe.HSet(ctx, q.key("job:"+job.ID), map[string]interface{}{
+		"name":     job.Name,
+		"payload":  job.Payload,
+		"attempts": job.Attempts,
+		"run_at":   job.RunAt.UnixMicro(),
+	})
+	pipe.ZAdd(ctx, q.key("ready"), goredis.Z{Score: float64(job.RunAt.UnixMicro()), Member: job.ID})
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// popScript moves expired leases back to the ready set, then claims the next
+// job that's ready
+var popScript = goredis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('ZADD', KEYS[1], ARGV[1], id)
+end
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, 1)
+if #ids == 0 then
+	return false
+end
+local id = ids[1]
+redis.call('ZREM', KEYS[1], id)
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+local key = ARGV[3] .. id
+redis.call('HINCRBY', key, 'attempts', 1)
+return {id, redis.call('HGET', key, 'name'), redis.call('HGET', key, 'payload'), redis.call('HGET', key, 'attempts'), redis.call('HGET', key, 'run_at'), redis.call('HGET', key, 'error') or ''}
+`)
+
+func (q *RedisQueue) Pop(ctx context.Context, lease time.Duration) (*Job, error) {
+	now := dbrt.Timestamp(q.Clock.Now())
+	keys := []string{q.key("ready"), q.key("running")}
+	result, err := popScript.Run(ctx, q.client, keys, now.UnixMicro(), now.Add(lease).UnixMicro(), q.key("job:")).StringSlice()
+	if err != nil {
+		if err == goredis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("jobrt: unable to pop a job. %w", err)
+	}
+	if len(result) != 6 {
+		return nil, fmt.Errorf("jobrt: unable to pop a job. unexpected result %q", result)
+	}
+	attempts, err := strconv.Atoi(result[3])
+	if err != nil {
+		return nil, fmt.Errorf("jobrt: unable to pop a job. %w", err)
+	}
+	runAt, err := strconv.ParseInt(result[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("jobrt: unable to pop a job. %w", err)
+	}
+	return &Job{
+		ID:       result[0],
+		Name:     result[1],
+		Payload:  []byte(result[2]),
+		Attempts: attempts,
+		RunAt:    time.UnixMicro(runAt).UTC(),
+		Error:    result[5],
+	}, nil
+}
+
+func (q *RedisQueue) Done(ctx context.Context, job *Job) error {
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("running"), job.ID)
+	pipe.Del(ctx, q.key("job:"+job.ID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("jobrt: unable to remove job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Retry(ctx context.Context, job *Job) error {
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("running"), job.ID)
+	pipe.HSet(ctx, q.key("job:"+job.ID), "run_at", job.RunAt.UnixMicro(), "error", job.Error)
+	pipe.ZAdd(ctx, q.key("ready"), goredis.Z{Score: float64(job.RunAt.UnixMicro()), Member: job.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("jobrt: unable to retry job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Fail(ctx context.Context, job *Job) error {
+	now := dbrt.Timestamp(q.Clock.Now())
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("running"), job.ID)
+	pipe.HSet(ctx, q.key("job:"+job.ID), "error", job.Error)
+	pipe.ZAdd(ctx, q.key("failed"), goredis.Z{Score: float64(now.UnixMicro()), Member: job.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("jobrt: unable to fail job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Jobs(ctx context.Context, state State, limit int) ([]*Job, error) {
+	var ids []string
+	var err error
+	switch state {
+	case Queued:
+		ids, err = q.client.ZRange(ctx, q.key("ready"), 0, int64(limit)-1).Result()
+	case Running:
+		ids, err = q.client.ZRange(ctx, q.key("running"), 0, int64(limit)-1).Result()
+	case Failed:
+		ids, err = q.client.ZRevRange(ctx, q.key("failed"), 0, int64(limit)-1).Result()
+	default:
+		return nil, fmt.Errorf("jobrt: unknown job state %q", state)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jobrt: unable to list %s jobs. %w", state, err)
+	}
+	pipe := q.client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, q.key("job:"+id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("jobrt: unable to list %s jobs. %w", state, err)
+	}
+	jobs := make([]*Job, 0, len(ids))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		// Skip jobs that finished while listing
+		if len(fields) == 0 {
+			continue
+		}
+		attempts, _ := strconv.Atoi(fields["attempts"])
+		runAt, _ := strconv.ParseInt(fields["run_at"], 10, 64)
+		jobs = append(jobs, &Job{
+			ID:       ids[i],
+			Name:     fields["name"],
+			Payload:  []byte(fields["payload"]),
+			Attempts: attempts,
+			RunAt:    time.UnixMicro(runAt).UTC(),
+			Error:    fields["error"],
+		})
+	}
+	return jobs, nil
+}
+
+func (q *RedisQueue) Count(ctx context.Context, state State) (int, error) {
+	var key string
+	switch state {
+	case Queued:
+		key = q.key("ready")
+	case Running:
+		key = q.key("running")
+	case Failed:
+		key = q.key("failed")
+	default:
+		return 0, fmt.Errorf("jobrt: unknown job state %q", state)
+	}
+	count, err := q.client.ZCard(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("jobrt: unable to count %s jobs. %w", state, err)
+	}
+	return int(count), nil
+}
+
+func (q *RedisQueue) Requeue(ctx context.Context, id string) error {
+	exists, err := q.client.Exists(ctx, q.key("job:"+id)).Result()
+	if err != nil {
+		return fmt.Errorf("jobrt: unable to requeue job %s. %w", id, err)
+	} else if exists == 0 {
+		return ErrNotFound
+	}
+	now := dbrt.Timestamp(q.Clock.Now())
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("running"), id)
+	pipe.ZRem(ctx, q.key("failed"), id)
+	pipe.HSet(ctx, q.key("job:"+id), "attempts", 0, "run_at", now.UnixMicro())
+	pipe.ZAdd(ctx, q.key("ready"), goredis.Z{Score: float64(now.UnixMicro()), Member: id})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("jobrt: unable to requeue job %s. %w", id, err)
+	}
+	return nil
+}
+
+func (q *RedisQueue) Delete(ctx context.Context, id string) error {
+	pipe := q.client.TxPipeline()
+	pipe.ZRem(ctx, q.key("ready"), id)
+	pipe.ZRem(ctx, q.key("running"), id)
+	pipe.ZRem(ctx, q.key("failed"), id)
+	deleted := pipe.Del(ctx, q.key("job:"+id))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("jobrt: unable to delete job %s. %w", id, err)
+	}
+	if deleted.Val() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
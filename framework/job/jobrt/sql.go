@@ -0,0 +1,197 @@
+package jobrt
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+)
+
+// NewSQLQueue keeps jobs in the bud_jobs table, which needs to be created
+// with a migration. For Postgres:
+//
+//	create table bud_jobs (
+//		id bigserial primary key,
+//		name text not null,
+//		payload text not null,
+//		attempts integer not null default 0,
+//		run_at timestamp not null,
+//		locked_until timestamp,
+//		failed_at timestamp,
+//		last_error text
+//	);
+//	create index bud_jobs_run_at on bud_jobs (run_at);
+//
+// Use "id integer primary key" for SQLite. Postgres workers claim jobs with
+// "for update skip locked", so they don't wait on each other.
+//
+// Jobs that are pushed within a transaction, like during a request that
+// changes the database, are only run if the transaction commits.
+func NewSQLQueue(db *dbrt.DB) *SQLQueue {
+	return &SQLQueue{db}
+}
+
+// SQLQueue keeps jobs in a Postgres or SQLite database
+type SQLQueue struct {
+	db *dbrt.DB
+}
+
+var _ Queue = (*SQLQueue)(nil)
+var _ Inspector = (*SQLQueue)(nil)
+var _ Counter = (*SQLQueue)(nil)
+
+func (q *SQLQueue) Push(ctx context.Context, job *Job) error {
+	query := q.db.Dialect().Rebind(`insert into "bud_jobs" ("name", "payload", "attempts", "run_at") values (?, ?, ?, ?) returning "id"`)
+	var id int64
+	if err := q.db.QueryRowContext(ctx, query, job.Name, string(job.Payload), job.Attempts, job.RunAt.UTC()).Scan(&id); err != nil {
+		return err
+	}
+	job.ID = strconv.FormatInt(id, 10)
+	return nil
+}
+
+func (q *SQLQueue) Pop(ctx context.Context, lease time.Duration) (*Job, error) {
+	lock := ""
+	if q.db.Dialect() == dbrt.Postgres {
+		lock = " for update skip locked"
+	}
+	query := q.db.Dialect().Rebind(`update "bud_jobs" set "locked_until" = ?, "attempts" = "attempts" + 1 ` +
+		`where "id" = (select "id" from "bud_jobs" where "failed_at" is null and "run_at" <= ? and ("locked_until" is null or "locked_until" <= ?) ` +
+		`order by "run_at", "id" limit 1` + lock + `) ` +
+		`returning "id", "name", "payload", "attempts", "run_at", "last_error"`)
+	now := q.db.Now()
+	job := new(Job)
+	var id int64
+	var payload string
+	var lastError sql.NullString
+	err := q.db.QueryRowContext(ctx, query, now.Add(lease), now, now).Scan(&id, &job.Name, &payload, &job.Attempts, &job.RunAt, &lastError)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("jobrt: unable to pop a job. %w", err)
+	}
+	job.ID = strconv.FormatInt(id, 10)
+	job.Payload = []byte(payload)
+	job.Error = lastError.String
+	return job, nil
+}
+
+func (q *SQLQueue) Done(ctx context.Context, job *Job) error {
+	query := q.db.Dialect().Rebind(`delete from "bud_jobs" where "id" = ?`)
+	if _, err := q.db.ExecContext(ctx, query, job.ID); err != nil {
+		return fmt.Errorf("jobrt: unable to remove job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *SQLQueue) Retry(ctx context.Context, job *Job) error {
+	query := q.db.Dialect().Rebind(`update "bud_jobs" set "run_at" = ?, "locked_until" = null, "last_error" = ? where "id" = ?`)
+	if _, err := q.db.ExecContext(ctx, query, job.RunAt.UTC(), job.Error, job.ID); err != nil {
+		return fmt.Errorf("jobrt: unable to retry job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+func (q *SQLQueue) Fail(ctx context.Context, job *Job) error {
+	query := q.db.Dialect().Rebind(`update "bud_jobs" set "failed_at" = ?, "locked_until" = null, "last_error" = ? where "id" = ?`)
+	if _, err := q.db.ExecContext(ctx, query, q.db.Now(), job.Error, job.ID); err != nil {
+		return fmt.Errorf("jobrt: unable to fail job %s. %w", job.ID, err)
+	}
+	return nil
+}
+
+// filter returns the condition for jobs in the state and their order
+func (q *SQLQueue) filter(state State) (where, order string, args []interface{}, err error) {
+	switch state {
+	case Queued:
+		where = `"failed_at" is null and ("locked_until" is null or "locked_until" <= ?)`
+		order = `"run_at", "id"`
+		args = append(args, q.db.Now())
+	case Running:
+		where = `"failed_at" is null and "locked_until" > ?`
+		order = `"run_at", "id"`
+		args = append(args, q.db.Now())
+	case Failed:
+		where = `"failed_at" is not null`
+		order = `"failed_at" desc, "id" desc`
+	default:
+		return "", "", nil, fmt.Errorf("jobrt: unknown job state %q", state)
+	}
+	return where, order, args, nil
+}
+
+func (q *SQLQueue) Jobs(ctx context.Context, state State, limit int) ([]*Job, error) {
+	where, order, args, err := q.filter(state)
+	if err != nil {
+		return nil, err
+	}
+	query := q.db.Dialect().Rebind(`select "id", "name", "payload", "attempts", "run_at", "last_error" from "bud_jobs" ` +
+		`where ` + where + ` order by ` + order + ` limit ?`)
+	rows, err := q.db.QueryContext(ctx, query, append(args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("jobrt: unable to list %s jobs. %w", state, err)
+	}
+	defer rows.Close()
+	var jobs []*Job
+	for rows.Next() {
+		job := new(Job)
+		var id int64
+		var payload string
+		var lastError sql.NullString
+		if err := rows.Scan(&id, &job.Name, &payload, &job.Attempts, &job.RunAt, &lastError); err != nil {
+			return nil, fmt.Errorf("jobrt: unable to list %s jobs. %w", state, err)
+		}
+		job.ID = strconv.FormatInt(id, 10)
+		job.Payload = []byte(payload)
+		job.Error = lastError.String
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (q *SQLQueue) Count(ctx context.Context, state State) (int, error) {
+	where, _, args, err := q.filter(state)
+	if err != nil {
+		return 0, err
+	}
+	var count int
+	query := q.db.Dialect().Rebind(`select count(*) from "bud_jobs" where ` + where)
+	if err := q.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("jobrt: unable to count %s jobs. %w", state, err)
+	}
+	return count, nil
+}
+
+func (q *SQLQueue) Requeue(ctx context.Context, id string) error {
+	query := q.db.Dialect().Rebind(`update "bud_jobs" set "run_at" = ?, "attempts" = 0, "locked_until" = null, "failed_at" = null where "id" = ?`)
+	result, err := q.db.ExecContext(ctx, query, q.db.Now(), id)
+	if err != nil {
+		return fmt.Errorf("jobrt: unable to requeue job %s. %w", id, err)
+	}
+	return expectRow(result)
+}
+
+func (q *SQLQueue) Delete(ctx context.Context, id string) error {
+	query := q.db.Dialect().Rebind(`delete from "bud_jobs" where "id" = ?`)
+	result, err := q.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("jobrt: unable to delete job %s. %w", id, err)
+	}
+	return expectRow(result)
+}
+
+// expectRow returns ErrNotFound when the query didn't change a row
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package jobrt
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/graceful"
+	"github.com/livebud/bud/package/log"
+)
+
+// Handler runs a job's payload
+type Handler func(ctx context.Context, payload []byte) error
+
+// NewWorker creates a worker that runs jobs from the client's queue. It's
+// configured from the environment:
+//
+//	JOB_CONCURRENCY=10
+//	JOB_MAX_ATTEMPTS=5
+//	JOB_SHUTDOWN_TIMEOUT=30s
+//
+// Setting JOB_CONCURRENCY to 0 turns the worker off, which is useful when jobs
+// are run by a different process. JOB_SHUTDOWN_TIMEOUT is how long running
+// jobs have to finish when the worker shuts down.
+func NewWorker(log log.Interface, client *Client) (*Worker, error) {
+	return newWorker(log, client, os.Getenv)
+}
+
+func newWorker(log log.Interface, client *Client, getenv func(string) string) (*Worker, error) {
+	concurrency, err := envInt(getenv, "JOB_CONCURRENCY", 10)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := envInt(getenv, "JOB_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	shutdownTimeout, err := envDuration(getenv, "JOB_SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &Worker{
+		Concurrency:     concurrency,
+		MaxAttempts:     maxAttempts,
+		Timeout:         10 * time.Minute,
+		ShutdownTimeout: shutdownTimeout,
+		PollInterval:    time.Second,
+		Backoff:         Backoff,
+		log:             log.Named("job"),
+		queue:           client.Queue,
+		clock:           client.Clock,
+		handlers:        map[string]Handler{},
+	}, nil
+}
+
+func envInt(getenv func(string) string, key string, fallback int) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("jobrt: expected %s to be a positive number, got %q", key, value)
+	}
+	return n, nil
+}
+
+func envDuration(getenv func(string) string, key string, fallback time.Duration) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("jobrt: expected %s to be a duration like 30s, got %q", key, value)
+	}
+	return d, nil
+}
+
+// Worker pops jobs off the queue and runs them
+type Worker struct {
+	// Concurrency is the number of jobs that run at once
+	Concurrency int
+	// MaxAttempts is the number of times a job runs before it fails
+	MaxAttempts int
+	// Timeout cancels jobs that run too long
+	Timeout time.Duration
+	// ShutdownTimeout is how long running jobs have to finish after the worker
+	// is canceled. Jobs that don't finish in time are canceled and retried.
+	ShutdownTimeout time.Duration
+	// PollInterval is how long to wait before checking an empty queue again
+	PollInterval time.Duration
+	// Backoff returns how long to wait before retrying a job
+	Backoff func(attempts int) time.Duration
+
+	log      log.Interface
+	queue    Queue
+	clock    clock.Clock
+	handlers map[string]Handler
+
+	mu        sync.Mutex
+	completed []*Job // Recently completed jobs, oldest first
+}
+
+// keepCompleted is the number of completed jobs the worker remembers
+const keepCompleted = 100
+
+// Backoff exponentially with jitter, starting around 2s and capped at an hour
+func Backoff(attempts int) time.Duration {
+	if attempts > 12 {
+		attempts = 12
+	}
+	d := time.Second << attempts
+	d = d/2 + time.Duration(rand.Int63n(int64(d/2)))
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// Handle the named job
+func (w *Worker) Handle(name string, handler Handler) {
+	w.handlers[name] = handler
+}
+
+// Run jobs until the context is canceled. Once canceled, the worker stops
+// taking jobs and drains the jobs that are running, giving them until the
+// shutdown timeout to finish.
+func (w *Worker) Run(ctx context.Context) error {
+	if w.Concurrency <= 0 {
+		return nil
+	}
+	jobCtx, cancel := graceful.Context(ctx, w.ShutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(w.Concurrency)
+	for i := 0; i < w.Concurrency; i++ {
+		go func() {
+			defer wg.Done()
+			w.loop(ctx, jobCtx)
+		}()
+	}
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+	w.log.Info("jobrt: draining running jobs", "timeout", w.ShutdownTimeout.String())
+	select {
+	case <-drained:
+	case <-jobCtx.Done():
+		w.log.Warn("jobrt: canceled running jobs after the shutdown timeout")
+		<-drained
+	}
+	return nil
+}
+
+// loop takes jobs until ctx is canceled and runs them with jobCtx
+func (w *Worker) loop(ctx, jobCtx context.Context) {
+	for {
+		ran, err := w.work(ctx, jobCtx)
+		if err != nil {
+			w.log.Error(err.Error())
+		}
+		if ran && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// Work runs the next job that's ready. It reports whether there was a job to
+// run.
+func (w *Worker) Work(ctx context.Context) (bool, error) {
+	return w.work(ctx, ctx)
+}
+
+func (w *Worker) work(ctx, jobCtx context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	// Jobs that outlive their lease get popped again, so leave room to finish
+	// up after the timeout
+	job, err := w.queue.Pop(ctx, w.Timeout+time.Minute)
+	if err != nil {
+		return false, err
+	} else if job == nil {
+		return false, nil
+	}
+	// Finish the bookkeeping even if the worker is shutting down
+	bookkeeping := context.Background()
+	if err := w.run(jobCtx, job); err != nil {
+		job.Error = err.Error()
+		if job.Attempts >= w.MaxAttempts {
+			w.log.Error("jobrt: giving up on job", "job", job.Name, "id", job.ID, "attempts", job.Attempts, "error", job.Error)
+			return true, w.queue.Fail(bookkeeping, job)
+		}
+		job.RunAt = dbrt.Timestamp(w.clock.Now()).Add(w.Backoff(job.Attempts))
+		w.log.Warn("jobrt: retrying job", "job", job.Name, "id", job.ID, "at", job.RunAt.Format(time.RFC3339), "error", job.Error)
+		return true, w.queue.Retry(bookkeeping, job)
+	}
+	if err := w.queue.Done(bookkeeping, job); err != nil {
+		return true, err
+	}
+	w.complete(job)
+	return true, nil
+}
+
+func (w *Worker) complete(job *Job) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.completed = append(w.completed, job)
+	if len(w.completed) > keepCompleted {
+		w.completed = w.completed[len(w.completed)-keepCompleted:]
+	}
+}
+
+// Completed returns the jobs that this worker completed recently, latest
+// first. Completed jobs are removed from the queue, so they're only
+// remembered by the worker that ran them.
+func (w *Worker) Completed() []*Job {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	jobs := make([]*Job, len(w.completed))
+	for i, job := range w.completed {
+		jobs[len(jobs)-1-i] = job
+	}
+	return jobs
+}
+
+func (w *Worker) run(ctx context.Context, job *Job) (err error) {
+	handler, ok := w.handlers[job.Name]
+	if !ok {
+		// Don't retry jobs that don't exist
+		job.Attempts = w.MaxAttempts
+		return fmt.Errorf("jobrt: unknown job %q", job.Name)
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("jobrt: %s panicked. %v", job.Name, e)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+	return handler(ctx, job.Payload)
+}
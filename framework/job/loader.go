@@ -0,0 +1,155 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/entrypoint"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/gotext"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+	des, err := fs.ReadDir(fsys, "job")
+	if err != nil {
+		return nil, err
+	}
+	hasGo := false
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			hasGo = true
+			break
+		}
+	}
+	if !hasGo {
+		return nil, fs.ErrNotExist
+	}
+	loader := &loader{
+		imports: imports.New(),
+		fsys:    fsys,
+		module:  module,
+		parser:  parser,
+	}
+	return loader.Load()
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	fsys    fs.FS
+	module  *gomod.Module
+	parser  *parser.Parser
+}
+
+// Load the job state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "job: unable to load")
+	state = new(State)
+	pkg, err := l.parser.Parse("job")
+	if err != nil {
+		l.Bail(err)
+	}
+	l.imports.AddStd("context", "encoding/json", "fmt")
+	l.imports.AddNamed("jobrt", "github.com/livebud/bud/framework/job/jobrt")
+	l.imports.AddNamed("log", "github.com/livebud/bud/package/log")
+	jobName := l.imports.AddNamed("job", l.module.Import("job"))
+	for _, stct := range pkg.Structs() {
+		if stct.Private() {
+			continue
+		}
+		run := stct.Method("Run")
+		if run == nil {
+			continue
+		}
+		state.Jobs = append(state.Jobs, l.loadJob(jobName, stct, run))
+	}
+	if len(state.Jobs) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	// Show the jobs at /bud/jobs when there's a view for the dashboard
+	hasDashboard, err := HasDashboard(l.fsys)
+	if err != nil {
+		l.Bail(err)
+	}
+	if hasDashboard {
+		state.Dashboard = DashboardRoute
+		l.imports.AddNamed("view", l.module.Import("bud/internal/web/view"))
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// loadJob checks that the Run method has the
+// func(context.Context, T) error signature
+func (l *loader) loadJob(jobName string, stct *parser.Struct, run *parser.Function) *Job {
+	job := &Job{
+		Name:     stct.Name(),
+		Path:     stct.File().Path(),
+		Type:     "*" + jobName + "." + stct.Name(),
+		Variable: gotext.Camel(stct.Name()) + "Job",
+	}
+	params := run.Params()
+	results := run.Results()
+	if len(params) != 2 || len(results) != 1 || !results[0].IsError() ||
+		!l.isType(params[0].Type(), "context", "Context") {
+		l.Bail(fmt.Errorf("expected %s.Run in %s to have the signature func(context.Context, T) error", job.Name, job.Path))
+	}
+	payload := params[1].Type()
+	job.Payload = l.loadType(payload)
+	job.Elem = job.Payload
+	if star, ok := payload.(*parser.StarType); ok {
+		job.Pointer = true
+		job.Elem = l.loadType(star.Inner())
+	}
+	return job
+}
+
+func (l *loader) isType(t parser.Type, importPath, name string) bool {
+	ok, err := parser.IsImportType(t, importPath, name)
+	if err != nil {
+		l.Bail(err)
+	}
+	return ok
+}
+
+// loadType qualifies the payload's type
+func (l *loader) loadType(dt parser.Type) string {
+	importPath, err := parser.ImportPath(parser.Innermost(dt))
+	if err != nil {
+		l.Bail(err)
+	}
+	if importPath == "" {
+		return dt.String()
+	}
+	name := l.imports.Add(importPath)
+	// Types declared alongside the job are unqualified
+	if _, ok := parser.Innermost(dt).(*parser.IdentType); ok {
+		return parser.Qualify(dt, name).String()
+	}
+	return parser.Requalify(dt, name).String()
+}
+
+// DashboardRoute is the route of the view that renders the job dashboard
+const DashboardRoute = "/admin/jobs"
+
+// HasDashboard checks if the app has a view for the job dashboard
+func HasDashboard(fsys fs.FS) (bool, error) {
+	views, err := entrypoint.List(fsys, "view")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, view := range views {
+		if view.Route == DashboardRoute {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,21 @@
+package job
+
+import "github.com/livebud/bud/internal/imports"
+
+type State struct {
+	Imports   []*imports.Import
+	Jobs      []*Job
+	Dashboard string // Route of the dashboard's view, if any
+}
+
+// Job is a struct in job/ with a Run(ctx context.Context, payload T) error
+// method
+type Job struct {
+	Name     string // Name of the struct (e.g. SendWelcome)
+	Path     string // Path to the file containing the struct (e.g. job/welcome.go)
+	Type     string // Qualified job type (e.g. *job.SendWelcome)
+	Variable string // Variable holding the job (e.g. sendWelcomeJob)
+	Payload  string // Qualified payload type (e.g. *job.Welcome)
+	Elem     string // Payload type without the pointer (e.g. job.Welcome)
+	Pointer  bool   // Whether the payload is a pointer
+}
@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/entrypoint"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/text"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser, flag *framework.Flag) (*State, error) {
+	des, err := fs.ReadDir(fsys, "mail")
+	if err != nil {
+		return nil, err
+	}
+	hasGo := false
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			hasGo = true
+			break
+		}
+	}
+	if !hasGo {
+		return nil, fs.ErrNotExist
+	}
+	loader := &loader{
+		imports: imports.New(),
+		fsys:    fsys,
+		module:  module,
+		parser:  parser,
+		flag:    flag,
+	}
+	return loader.Load()
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	fsys    fs.FS
+	module  *gomod.Module
+	parser  *parser.Parser
+	flag    *framework.Flag
+}
+
+// Load the mail state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "mail: unable to load")
+	state = new(State)
+	pkg, err := l.parser.Parse("mail")
+	if err != nil {
+		l.Bail(err)
+	}
+	routes := l.loadRoutes()
+	l.imports.AddStd("context")
+	l.imports.AddNamed("mailrt", "github.com/livebud/bud/framework/mail/mailrt")
+	l.imports.AddNamed("log", "github.com/livebud/bud/package/log")
+	l.imports.AddNamed("view", l.module.Import("bud/internal/web/view"))
+	mailName := l.imports.AddNamed("mail", l.module.Import("mail"))
+	for _, stct := range pkg.Structs() {
+		if stct.Private() || stct.Method("Envelope") == nil {
+			continue
+		}
+		mail := &Mail{
+			Name:  stct.Name(),
+			Path:  stct.File().Path(),
+			Type:  "*" + mailName + "." + stct.Name(),
+			Elem:  mailName + "." + stct.Name(),
+			Route: "/mail/" + text.Lower(text.Snake(stct.Name())),
+		}
+		if !routes[mail.Route] {
+			l.Bail(fmt.Errorf("expected a view for %s in %s. Add view%s.svelte to render it", mail.Name, mail.Path, mail.Route))
+		}
+		state.Mails = append(state.Mails, mail)
+	}
+	if len(state.Mails) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	// Preview the emails during development
+	if !l.flag.Embed {
+		state.Preview = true
+		l.imports.AddStd("net/http")
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// loadRoutes loads the routes of the views that can render emails
+func (l *loader) loadRoutes() map[string]bool {
+	routes := map[string]bool{}
+	views, err := entrypoint.List(l.fsys, "view")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return routes
+		}
+		l.Bail(err)
+	}
+	for _, view := range views {
+		routes[view.Route] = true
+	}
+	return routes
+}
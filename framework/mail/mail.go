@@ -0,0 +1,48 @@
+package mail
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed mail.gotext
+var template string
+
+var generator = gotemplate.MustParse("framework/mail/mail.gotext", template)
+
+// Generate the mailer package from state
+func Generate(state *State) ([]byte, error) {
+	return generator.Generate(state)
+}
+
+// New mailer generator
+func New(module *gomod.Module, parser *parser.Parser, flag *framework.Flag) *Generator {
+	return &Generator{module, parser, flag}
+}
+
+// Generator for the mailer package, which has a typed method to send each
+// email in mail/
+type Generator struct {
+	module *gomod.Module
+	parser *parser.Parser
+	flag   *framework.Flag
+}
+
+func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := Load(fsys, g.module, g.parser, g.flag)
+	if err != nil {
+		return fmt.Errorf("framework/mail: unable to load. %w", err)
+	}
+	code, err := Generate(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
@@ -0,0 +1,139 @@
+package mail_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/internal/versions"
+)
+
+func TestNoMail(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/package/mailer"))
+}
+
+const welcomeMail = `
+	package mail
+	import "github.com/livebud/bud/framework/mail/mailrt"
+	type Welcome struct {
+		Email string ` + "`json:\"-\"`" + `
+		Name  string ` + "`json:\"name\"`" + `
+	}
+	func (w *Welcome) Envelope() *mailrt.Envelope {
+		return &mailrt.Envelope{To: []string{w.Email}, Subject: "Welcome, " + w.Name}
+	}
+	func (w *Welcome) Preview() {
+		w.Email = "alice@example.com"
+		w.Name = "Alice"
+	}
+`
+
+func TestMissingView(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["mail/welcome.go"] = welcomeMail
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "expected a view for Welcome in mail/welcome.go. Add view/mail/welcome.svelte to render it")
+}
+
+func TestSend(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["mail/welcome.go"] = welcomeMail
+	td.Files["view/mail/welcome.svelte"] = `
+		<script>
+			export let name = ""
+		</script>
+		<h1>Welcome, {name}!</h1>
+	`
+	td.Files["controller/controller.go"] = `
+		package controller
+		import (
+			"context"
+			"app.com/bud/package/mailer"
+			"app.com/mail"
+		)
+		type Controller struct {
+			Mailer *mailer.Mailer
+		}
+		func (c *Controller) Create(ctx context.Context, email string) (string, error) {
+			sent := c.Mailer.Capture()
+			if err := c.Mailer.Welcome(ctx, &mail.Welcome{Email: email, Name: "Bob"}); err != nil {
+				return "", err
+			}
+			return sent.Messages()[0].Text, nil
+		}
+	`
+	td.NodeModules["svelte"] = versions.Svelte
+	td.NodeModules["livebud"] = "*"
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["MAIL_FROM"] = "bud@example.com"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/package/mailer/mailer.go"))
+	req, err := http.NewRequest(http.MethodPost, "http://host/?email=bob@example.com", nil)
+	is.NoErr(err)
+	req.Header.Set("Accept", "application/json")
+	res, err := app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.DiffHeaders(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+	`))
+	is.Equal(res.Body().String(), `"Welcome, Bob!"`)
+	// Preview the email
+	res, err = app.Get("/bud/mail/welcome")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.In(res.Body().String(), "<h1>Welcome, Alice!</h1>")
+	is.NotIn(res.Body().String(), "<script")
+	res, err = app.Get("/bud/mail")
+	is.NoErr(err)
+	is.In(res.Body().String(), `<a href="/bud/mail/welcome">Welcome</a>`)
+	is.NoErr(app.Close())
+}
+
+func TestNoPreviewInProduction(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["mail/welcome.go"] = welcomeMail
+	td.Files["view/mail/welcome.svelte"] = `<h1>Welcome!</h1>`
+	td.NodeModules["svelte"] = versions.Svelte
+	td.NodeModules["livebud"] = "*"
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.Exists("bud/package/mailer/mailer.go"))
+	app, err := cli.Start(ctx, "run", "--embed")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.Get("/bud/mail")
+	is.NoErr(err)
+	is.Equal(res.Status(), 404)
+	is.NoErr(app.Close())
+}
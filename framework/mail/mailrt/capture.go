@@ -0,0 +1,65 @@
+package mailrt
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Capture keeps the messages in memory instead of sending them
+type Capture struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+var _ Transport = (*Capture)(nil)
+
+// Send captures the message
+func (c *Capture) Send(ctx context.Context, message *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+// Messages that were captured, oldest first
+func (c *Capture) Messages() []*Message {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	messages := make([]*Message, len(c.messages))
+	copy(messages, c.messages)
+	return messages
+}
+
+// Reset forgets the captured messages
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+// NewLog creates a transport that logs messages instead of sending them. It's
+// the default during development.
+func NewLog(log log.Interface) *Log {
+	return &Log{log}
+}
+
+// Log transport logs messages instead of sending them
+type Log struct {
+	log log.Interface
+}
+
+var _ Transport = (*Log)(nil)
+
+// Send logs the message
+func (l *Log) Send(ctx context.Context, message *Message) error {
+	l.log.Info("mailrt: not sending email. Set MAIL_TRANSPORT to send it",
+		"from", message.From,
+		"to", strings.Join(message.Recipients(), ", "),
+		"subject", message.Subject,
+	)
+	l.log.Debug("mailrt: email text", "text", message.Text)
+	return nil
+}
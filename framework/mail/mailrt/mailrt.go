@@ -0,0 +1,217 @@
+// Package mailrt sends the emails in mail/. Emails are rendered by the views in
+// view/mail/, then sent through a transport like SMTP, SES or Postmark.
+package mailrt
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/package/log"
+)
+
+// Envelope describes who an email goes to
+type Envelope struct {
+	From    string // Defaults to MAIL_FROM
+	To      []string
+	Cc      []string
+	Bcc     []string
+	ReplyTo string
+	Subject string
+	Headers map[string]string
+}
+
+// Mail is a struct in mail/. Its fields are passed to its view as props.
+type Mail interface {
+	Envelope() *Envelope
+}
+
+// Previewer fills a mail with example data for the preview
+type Previewer interface {
+	Preview()
+}
+
+// Message is a rendered email
+type Message struct {
+	Envelope
+	HTML string
+	Text string
+}
+
+// Recipients returns everyone the message is sent to, including Bcc
+func (m *Message) Recipients() (recipients []string) {
+	recipients = append(recipients, m.To...)
+	recipients = append(recipients, m.Cc...)
+	recipients = append(recipients, m.Bcc...)
+	return recipients
+}
+
+// Transport sends messages. Transports must be safe for concurrent use.
+type Transport interface {
+	Send(ctx context.Context, message *Message) error
+}
+
+// Renderer renders views. The view server is a renderer.
+type Renderer interface {
+	RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error)
+}
+
+// Load the client from the environment
+func Load(log log.Interface, renderer Renderer) (*Client, error) {
+	transport, err := LoadTransport(log, os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	client := New(transport, renderer)
+	client.From = os.Getenv("MAIL_FROM")
+	return client, nil
+}
+
+// LoadTransport loads the transport from the environment:
+//
+//	MAIL_TRANSPORT=log
+//
+// MAIL_TRANSPORT may be log, smtp, ses or postmark. The log transport logs
+// emails instead of sending them. The SMTP transport connects using SMTP_URL,
+// the SES transport uses AWS_REGION, AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY and the Postmark transport uses POSTMARK_SERVER_TOKEN.
+func LoadTransport(log log.Interface, getenv func(key string) string) (Transport, error) {
+	switch transport := getenv("MAIL_TRANSPORT"); transport {
+	case "", "log":
+		return NewLog(log.Named("mail")), nil
+	case "smtp":
+		return LoadSMTP(getenv)
+	case "ses":
+		return LoadSES(getenv)
+	case "postmark":
+		return LoadPostmark(getenv)
+	default:
+		return nil, fmt.Errorf("mailrt: expected MAIL_TRANSPORT to be log, smtp, ses or postmark, got %q", transport)
+	}
+}
+
+// New mail client
+func New(transport Transport, renderer Renderer) *Client {
+	return &Client{transport: transport, renderer: renderer}
+}
+
+// Client renders and sends emails
+type Client struct {
+	From      string // Default sender
+	mu        sync.RWMutex
+	transport Transport
+	renderer  Renderer
+}
+
+// Render the mail with the view at route
+func (c *Client) Render(route string, mail Mail) (*Message, error) {
+	envelope := mail.Envelope()
+	if envelope == nil {
+		return nil, fmt.Errorf("mailrt: %s returned an empty envelope", route)
+	}
+	message := &Message{Envelope: *envelope}
+	// Skip empty addresses, like a user without an email
+	message.To = compact(message.To)
+	message.Cc = compact(message.Cc)
+	message.Bcc = compact(message.Bcc)
+	if message.From == "" {
+		message.From = c.From
+	}
+	responses, err := c.renderer.RenderBatch(&ssr.Request{Route: route, Props: mail})
+	if err != nil {
+		return nil, fmt.Errorf("mailrt: unable to render %s. %w", route, err)
+	}
+	if len(responses) != 1 || responses[0].Status >= 400 {
+		return nil, fmt.Errorf("mailrt: unable to render %s", route)
+	}
+	message.HTML = stripScripts(responses[0].Body)
+	message.Text = toText(message.HTML)
+	return message, nil
+}
+
+// Send the mail with the view at route
+func (c *Client) Send(ctx context.Context, route string, mail Mail) error {
+	message, err := c.Render(route, mail)
+	if err != nil {
+		return err
+	}
+	if message.From == "" {
+		return fmt.Errorf("mailrt: %s needs a sender. Set MAIL_FROM or the envelope's From", route)
+	}
+	if len(message.Recipients()) == 0 {
+		return fmt.Errorf("mailrt: %s needs at least one recipient", route)
+	}
+	c.mu.RLock()
+	transport := c.transport
+	c.mu.RUnlock()
+	if err := transport.Send(ctx, message); err != nil {
+		return fmt.Errorf("mailrt: unable to send %s. %w", route, err)
+	}
+	return nil
+}
+
+// Capture the emails that are sent from now on instead of sending them. Use
+// this in tests to check what was sent.
+func (c *Client) Capture() *Capture {
+	capture := new(Capture)
+	c.mu.Lock()
+	c.transport = capture
+	c.mu.Unlock()
+	return capture
+}
+
+func compact(addresses []string) (compacted []string) {
+	for _, address := range addresses {
+		if strings.TrimSpace(address) != "" {
+			compacted = append(compacted, address)
+		}
+	}
+	return compacted
+}
+
+// Emails are read in mail clients, so they can't run scripts. Remove the
+// scripts that hydrate the view in the browser.
+var scriptTags = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script>`)
+
+func stripScripts(html string) string {
+	return scriptTags.ReplaceAllString(html, "")
+}
+
+var (
+	hiddenTags = regexp.MustCompile(`(?is)<(head|style|title)\b[^>]*>.*?</(head|style|title)>`)
+	blockTags  = regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|ul|ol|blockquote)>`)
+	breakTags  = regexp.MustCompile(`(?i)<br\s*/?>|</(li|tr)>`)
+	linkTags   = regexp.MustCompile(`(?is)<a\b[^>]*\bhref="([^"]*)"[^>]*>(.*?)</a>`)
+	allTags    = regexp.MustCompile(`(?s)<[^>]*>`)
+	spaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	newlines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// toText converts the HTML into the plain text part of the email for mail
+// clients that don't show HTML
+func toText(body string) string {
+	body = hiddenTags.ReplaceAllString(body, "")
+	body = linkTags.ReplaceAllStringFunc(body, func(link string) string {
+		match := linkTags.FindStringSubmatch(link)
+		text := strings.TrimSpace(allTags.ReplaceAllString(match[2], ""))
+		if text == "" || text == match[1] {
+			return match[1]
+		}
+		return text + " (" + match[1] + ")"
+	})
+	body = blockTags.ReplaceAllString(body, "$0\n\n")
+	body = breakTags.ReplaceAllString(body, "$0\n")
+	body = allTags.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+	lines := strings.Split(spaces.ReplaceAllString(body, " "), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	body = newlines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(body)
+}
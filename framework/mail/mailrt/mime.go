@@ -0,0 +1,108 @@
+package mailrt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MIME encodes the message with its text and HTML parts. Bcc recipients are
+// left out of the headers.
+func (m *Message) MIME() ([]byte, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, fmt.Errorf("mailrt: invalid sender %q. %w", m.From, err)
+	}
+	id, err := messageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+	body := new(bytes.Buffer)
+	parts := multipart.NewWriter(body)
+	if err := writePart(parts, "text/plain", m.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(parts, "text/html", m.HTML); err != nil {
+		return nil, err
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	out := new(bytes.Buffer)
+	header := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(out, "%s: %s\r\n", key, value)
+		}
+	}
+	header("From", from.String())
+	header("To", strings.Join(m.To, ", "))
+	header("Cc", strings.Join(m.Cc, ", "))
+	header("Reply-To", m.ReplyTo)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", id)
+	// Sort the custom headers to keep the output stable
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header(textproto.CanonicalMIMEHeaderKey(key), mime.QEncoding.Encode("utf-8", m.Headers[key]))
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	out.WriteString("\r\n")
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
+
+func writePart(parts *multipart.Writer, contentType, content string) error {
+	part, err := parts.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	writer := quotedprintable.NewWriter(part)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func messageID(address string) (string, error) {
+	domain := "localhost"
+	if at := strings.LastIndex(address, "@"); at >= 0 {
+		domain = address[at+1:]
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">", nil
+}
+
+// addresses parses the email addresses out of a list like
+// "Alice <alice@example.com>"
+func addresses(list ...string) ([]string, error) {
+	addrs := make([]string, len(list))
+	for i, item := range list {
+		addr, err := mail.ParseAddress(item)
+		if err != nil {
+			return nil, fmt.Errorf("mailrt: invalid address %q. %w", item, err)
+		}
+		addrs[i] = addr.Address
+	}
+	return addrs, nil
+}
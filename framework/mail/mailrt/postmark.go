@@ -0,0 +1,102 @@
+package mailrt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// LoadPostmark loads the Postmark transport from the environment:
+//
+//	POSTMARK_SERVER_TOKEN=...
+func LoadPostmark(getenv func(key string) string) (*Postmark, error) {
+	token := getenv("POSTMARK_SERVER_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("mailrt: the postmark transport requires the POSTMARK_SERVER_TOKEN environment variable")
+	}
+	return &Postmark{Token: token}, nil
+}
+
+// Postmark sends messages with Postmark's API
+type Postmark struct {
+	Token    string
+	Endpoint string // Defaults to https://api.postmarkapp.com
+	Client   *http.Client
+}
+
+var _ Transport = (*Postmark)(nil)
+
+type postmarkHeader struct {
+	Name  string
+	Value string
+}
+
+type postmarkEmail struct {
+	From     string
+	To       string
+	Cc       string           `json:",omitempty"`
+	Bcc      string           `json:",omitempty"`
+	ReplyTo  string           `json:",omitempty"`
+	Subject  string           `json:",omitempty"`
+	HtmlBody string           `json:",omitempty"`
+	TextBody string           `json:",omitempty"`
+	Headers  []postmarkHeader `json:",omitempty"`
+}
+
+// Send the message
+func (p *Postmark) Send(ctx context.Context, message *Message) error {
+	email := &postmarkEmail{
+		From:     message.From,
+		To:       strings.Join(message.To, ", "),
+		Cc:       strings.Join(message.Cc, ", "),
+		Bcc:      strings.Join(message.Bcc, ", "),
+		ReplyTo:  message.ReplyTo,
+		Subject:  message.Subject,
+		HtmlBody: message.HTML,
+		TextBody: message.Text,
+	}
+	for name, value := range message.Headers {
+		email.Headers = append(email.Headers, postmarkHeader{name, value})
+	}
+	sort.Slice(email.Headers, func(i, j int) bool {
+		return email.Headers[i].Name < email.Headers[j].Name
+	})
+	body, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+	endpoint := p.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.postmarkapp.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/email", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Postmark-Server-Token", p.Token)
+	return doRequest(p.Client, req, "postmark")
+}
+
+// doRequest sends the request to an email API, returning the API's error
+func doRequest(client *http.Client, req *http.Request, name string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	return fmt.Errorf("mailrt: %s responded with %d. %s", name, res.StatusCode, strings.TrimSpace(string(body)))
+}
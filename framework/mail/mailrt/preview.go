@@ -0,0 +1,115 @@
+package mailrt
+
+import (
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Preview is a mail that can be previewed
+type Preview struct {
+	Name  string      // Name of the mail (e.g. Welcome)
+	Route string      // Route of the mail's view (e.g. /mail/welcome)
+	New   func() Mail // New mail to fill with example data
+}
+
+// Preview serves previews of the emails during development. The emails are
+// listed at /bud/mail and rendered at /bud/mail/:name. Add .txt to see the
+// plain text version. Mails that have a Preview() method are filled in with
+// its example data.
+func (c *Client) Preview(next http.Handler, previews ...*Preview) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || (r.URL.Path != "/bud/mail" && !strings.HasPrefix(r.URL.Path, "/bud/mail/")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.URL.Path == "/bud/mail" || r.URL.Path == "/bud/mail/" {
+			c.serveIndex(w, previews)
+			return
+		}
+		name := strings.TrimPrefix(r.URL.Path, "/bud/mail/")
+		text := path.Ext(name) == ".txt"
+		name = strings.TrimSuffix(name, ".txt")
+		for _, preview := range previews {
+			if previewName(preview) != name {
+				continue
+			}
+			message, err := c.Render(preview.Route, newPreview(preview))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if text {
+				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+				w.Write([]byte(message.Text))
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(message.HTML))
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func previewName(preview *Preview) string {
+	return strings.TrimPrefix(preview.Route, "/mail/")
+}
+
+func newPreview(preview *Preview) Mail {
+	mail := preview.New()
+	if previewer, ok := mail.(Previewer); ok {
+		previewer.Preview()
+	}
+	return mail
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Mail Previews</title>
+<style>body{font-family:system-ui,sans-serif;margin:2rem}td{padding:.25rem 1rem .25rem 0}</style>
+</head>
+<body>
+<h1>Mail Previews</h1>
+<table>
+{{- range $.Mails }}
+<tr>
+<td><a href="/bud/mail/{{ .Path }}">{{ .Name }}</a> (<a href="/bud/mail/{{ .Path }}.txt">text</a>)</td>
+{{- if .Error }}
+<td>{{ .Error }}</td>
+{{- else }}
+<td>{{ .Envelope.Subject }}</td>
+<td>{{ range $i, $to := .Envelope.To }}{{ if $i }}, {{ end }}{{ $to }}{{ end }}</td>
+{{- end }}
+</tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
+
+type indexMail struct {
+	Name     string
+	Path     string
+	Envelope *Envelope
+	Error    string
+}
+
+func (c *Client) serveIndex(w http.ResponseWriter, previews []*Preview) {
+	mails := make([]*indexMail, len(previews))
+	for i, preview := range previews {
+		mail := &indexMail{Name: preview.Name, Path: previewName(preview)}
+		mail.Envelope = newPreview(preview).Envelope()
+		if mail.Envelope == nil {
+			mail.Error = "empty envelope"
+		}
+		mails[i] = mail
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := indexTemplate.Execute(w, map[string]interface{}{"Mails": mails}); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
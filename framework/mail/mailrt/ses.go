@@ -0,0 +1,159 @@
+package mailrt
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoadSES loads the Amazon SES transport from the environment:
+//
+//	AWS_REGION=us-east-1
+//	AWS_ACCESS_KEY_ID=...
+//	AWS_SECRET_ACCESS_KEY=...
+//
+// AWS_SESSION_TOKEN is sent too when it's set.
+func LoadSES(getenv func(key string) string) (*SES, error) {
+	transport := &SES{
+		Region:       getenv("AWS_REGION"),
+		AccessKeyID:  getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: getenv("AWS_SESSION_TOKEN"),
+	}
+	if transport.Region == "" || transport.AccessKeyID == "" || transport.SecretKey == "" {
+		return nil, fmt.Errorf("mailrt: the ses transport requires the AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+	}
+	return transport, nil
+}
+
+// SES sends messages with Amazon SES's v2 API
+type SES struct {
+	Region       string
+	AccessKeyID  string
+	SecretKey    string
+	SessionToken string
+	Endpoint     string // Defaults to https://email.{region}.amazonaws.com
+	Client       *http.Client
+	Now          func() time.Time
+}
+
+var _ Transport = (*SES)(nil)
+
+type sesEmail struct {
+	FromEmailAddress string
+	Destination      sesDestination
+	Content          sesContent
+}
+
+type sesDestination struct {
+	ToAddresses  []string `json:",omitempty"`
+	CcAddresses  []string `json:",omitempty"`
+	BccAddresses []string `json:",omitempty"`
+}
+
+type sesContent struct {
+	Raw struct {
+		Data []byte // Encoded as base64
+	}
+}
+
+// Send the message. The message is sent as raw MIME, so custom headers are
+// kept.
+func (s *SES) Send(ctx context.Context, message *Message) error {
+	data, err := message.MIME()
+	if err != nil {
+		return err
+	}
+	email := &sesEmail{
+		FromEmailAddress: message.From,
+		Destination: sesDestination{
+			ToAddresses:  message.To,
+			CcAddresses:  message.Cc,
+			BccAddresses: message.Bcc,
+		},
+	}
+	email.Content.Raw.Data = data
+	body, err := json.Marshal(email)
+	if err != nil {
+		return err
+	}
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://email." + s.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	s.sign(req, body, now().UTC())
+	return doRequest(s.Client, req, "ses")
+}
+
+// sign the request with AWS Signature Version 4
+func (s *SES) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	// The headers are already in sorted order
+	signed := []string{"content-type", "host", "x-amz-date"}
+	if s.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	canonicalHeaders := new(strings.Builder)
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+	scope := date + "/" + s.Region + "/ses/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "ses")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,18 @@
+package mail
+
+import "github.com/livebud/bud/internal/imports"
+
+type State struct {
+	Imports []*imports.Import
+	Mails   []*Mail
+	Preview bool // Serve previews of the emails during development
+}
+
+// Mail is a struct in mail/ with an Envelope() method
+type Mail struct {
+	Name  string // Name of the struct (e.g. PasswordReset)
+	Path  string // Path to the file containing the struct (e.g. mail/reset.go)
+	Type  string // Qualified mail type (e.g. *mail.PasswordReset)
+	Elem  string // Mail type without the pointer (e.g. mail.PasswordReset)
+	Route string // Route of the view that renders the mail (e.g. /mail/password_reset)
+}
@@ -0,0 +1,84 @@
+package public
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/glob"
+)
+
+const (
+	// immutable is for files whose name changes whenever their contents change
+	immutable = "public, max-age=31536000, immutable"
+	// revalidate briefly caches other files, since they keep their name
+	revalidate = "public, max-age=300, must-revalidate"
+	// noCache always checks for changes during development
+	noCache = "no-cache"
+)
+
+// cachePolicy sets the Cache-Control header for the routes that match
+type cachePolicy struct {
+	control string
+	matcher glob.Matcher
+}
+
+// loadCachePolicies from the "bud.public.cache" section of package.json
+func loadCachePolicies(config *framework.Config) ([]*cachePolicy, error) {
+	policies := make([]*cachePolicy, len(config.Public.Cache))
+	for i, policy := range config.Public.Cache {
+		if !strings.HasPrefix(policy.Path, "/") {
+			return nil, fmt.Errorf("public: cache path %q in package.json must start with a slash", policy.Path)
+		}
+		matcher, err := glob.Compile(policy.Path)
+		if err != nil {
+			return nil, fmt.Errorf("public: invalid cache path %q in package.json. %w", policy.Path, err)
+		}
+		policies[i] = &cachePolicy{policy.Control, matcher}
+	}
+	return policies, nil
+}
+
+// CacheControl returns the Cache-Control headers that routes have in
+// production, like the view bundles that bud assets push uploads to a CDN
+func CacheControl(fsys fs.FS) (func(route string) string, error) {
+	config, err := framework.LoadConfig(fsys)
+	if err != nil {
+		return nil, err
+	}
+	policies, err := loadCachePolicies(config)
+	if err != nil {
+		return nil, err
+	}
+	return func(route string) string {
+		return cacheControl(policies, route)
+	}, nil
+}
+
+// cacheControl returns the Cache-Control header for a route. Without a
+// matching policy, hashed files are immutable and other files revalidate.
+func cacheControl(policies []*cachePolicy, route string) string {
+	for _, policy := range policies {
+		if policy.matcher.Match(route) {
+			return policy.control
+		}
+	}
+	if isHashed(route) {
+		return immutable
+	}
+	return revalidate
+}
+
+// reHash matches content hashes in filenames like app.3f9a2c1b.js and
+// chunk-HBKQ2D7C.js
+var reHash = regexp.MustCompile(`[.-]([0-9A-Za-z]{8,})\.[^.]+$`)
+
+// isHashed is true if the file's name includes a hash of its contents. Hashes
+// contain digits, which tells them apart from words like "bootstrap".
+func isHashed(route string) bool {
+	match := reHash.FindStringSubmatch(path.Base(route))
+	return match != nil && strings.ContainsAny(match[1], "0123456789")
+}
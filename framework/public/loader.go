@@ -1,6 +1,9 @@
 package public
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
 	"io/fs"
 	"path"
 	"strings"
@@ -9,6 +12,7 @@ import (
 
 	"github.com/livebud/bud/framework"
 	"github.com/livebud/bud/package/finder"
+	"github.com/livebud/bud/package/imageset"
 
 	"github.com/livebud/bud/internal/bail"
 	"github.com/livebud/bud/internal/imports"
@@ -48,36 +52,236 @@ func (l *loader) Load() (state *State, err error) {
 		return nil, fs.ErrNotExist
 	}
 	state = new(State)
+	config, err := framework.LoadConfig(l.fsys)
+	if err != nil {
+		return nil, err
+	}
+	// Production builds may read public/ from disk rather than embed it
+	state.Disk = l.flag.Embed && !config.Public.Embedded()
+	state.OnDemand = !l.flag.Embed
 	// Load the files from paths
 	state.Files = l.loadFiles(paths)
+	// Set the Cache-Control headers
+	policies, err := loadCachePolicies(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range state.Files {
+		file.CacheControl = noCache
+		if l.flag.Embed {
+			file.CacheControl = cacheControl(policies, file.Route)
+		}
+	}
+	// Precompress the embedded files
+	if l.flag.Embed && !state.Disk {
+		state.Encoded = l.compressFiles(state.Files, paths)
+		state.Resized = l.resizeImages(state.Files, paths)
+		state.Converted = l.convertImages(state.Files, state.Resized, paths)
+	}
 	// Default imports
 	l.imports.AddNamed("virtual", "github.com/livebud/bud/package/virtual")
 	l.imports.AddNamed("publicrt", "github.com/livebud/bud/framework/public/publicrt")
 	l.imports.AddNamed("router", "github.com/livebud/bud/package/router")
 	l.imports.AddNamed("http", "net/http")
 	l.imports.AddNamed("fs", "io/fs")
+	l.imports.AddNamed("os", "os")
+	if l.flag.Embed && !state.Disk {
+		l.imports.AddNamed("time", "time")
+	}
 	// Add the imports
 	state.Imports = l.imports.List()
 	return state, nil
 }
 
 func (l *loader) loadFiles(paths []string) (files []*File) {
+	exists := make(map[string]bool, len(paths))
 	for _, path := range paths {
-		files = append(files, l.loadFile(path))
+		exists[path] = true
+	}
+	for _, path := range paths {
+		file := l.loadFile(path)
+		file.Variants = variantRoutes(path, exists)
+		files = append(files, file)
 	}
 	return files
 }
 
+// variantRoutes are the routes to the resized variants of an image. Variants
+// that already exist in public/ have their own routes.
+func variantRoutes(path string, exists map[string]bool) (routes []string) {
+	if _, _, ok := imageset.Parse(path); ok || !imageset.Supported(path) {
+		return nil
+	}
+	for _, variant := range imageset.Variants(path) {
+		if !exists[variant] {
+			routes = append(routes, strings.TrimPrefix(variant, "public"))
+		}
+	}
+	return routes
+}
+
 func (l *loader) loadFile(path string) *File {
 	file := new(File)
 	file.Path = path
 	file.Route = strings.TrimPrefix(path, "public")
 	if l.flag.Embed {
+		stat, err := fs.Stat(l.fsys, path)
+		if err != nil {
+			l.Bail(err)
+		}
 		data, err := fs.ReadFile(l.fsys, path)
 		if err != nil {
 			l.Bail(err)
 		}
 		file.Data = data
+		file.Mode = stat.Mode().Perm()
+		file.ModTime = stat.ModTime()
+		// Stream the files from string constants, so they're not copied into
+		// memory when the app starts
+		file.Stream = true
 	}
 	return file
 }
+
+// minCompressSize is the smallest file worth compressing
+const minCompressSize = 1 << 10
+
+// compressible extensions. Most other formats like images and fonts like woff2
+// are already compressed.
+var compressible = map[string]bool{
+	".css":  true,
+	".csv":  true,
+	".htm":  true,
+	".html": true,
+	".ico":  true,
+	".js":   true,
+	".json": true,
+	".map":  true,
+	".mjs":  true,
+	".otf":  true,
+	".svg":  true,
+	".ttf":  true,
+	".txt":  true,
+	".wasm": true,
+	".xml":  true,
+}
+
+// compressFiles gzips the compressible files that don't already have a gzipped
+// variant in public/
+func (l *loader) compressFiles(files []*File, paths []string) (encoded []*File) {
+	exists := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exists[path] = true
+	}
+	for _, file := range files {
+		if len(file.Data) < minCompressSize || !compressible[path.Ext(file.Path)] || exists[file.Path+".gz"] {
+			continue
+		}
+		buf := new(bytes.Buffer)
+		// Leave the header empty, so builds are reproducible
+		writer, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+		if err != nil {
+			l.Bail(err)
+		}
+		if _, err := writer.Write(file.Data); err != nil {
+			l.Bail(err)
+		}
+		if err := writer.Close(); err != nil {
+			l.Bail(err)
+		}
+		// Not worth it unless it's at least 10% smaller
+		if buf.Len() > len(file.Data)*9/10 {
+			continue
+		}
+		gzipped := &File{
+			Path:         file.Path + ".gz",
+			Data:         buf.Bytes(),
+			Mode:         file.Mode,
+			ModTime:      file.ModTime,
+			Stream:       true,
+			CacheControl: file.CacheControl,
+			Const:        fmt.Sprintf("gzipped%d", len(encoded)),
+		}
+		file.Gzipped = gzipped
+		encoded = append(encoded, gzipped)
+	}
+	return encoded
+}
+
+// resizeImages resizes the images that are wider than the variant, skipping
+// variants that already exist in public/
+func (l *loader) resizeImages(files []*File, paths []string) (resized []*File) {
+	exists := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exists[path] = true
+	}
+	for _, file := range files {
+		if len(file.Variants) == 0 {
+			continue
+		}
+		width, err := imageset.Width(bytes.NewReader(file.Data))
+		if err != nil {
+			l.Bail(fmt.Errorf("public: unable to resize %q. %w", file.Path, err))
+		}
+		for _, variant := range imageset.Widths {
+			name := imageset.Variant(file.Path, variant)
+			// Narrower images are served as is
+			if variant >= width || exists[name] {
+				continue
+			}
+			data, err := imageset.Resize(file.Data, variant)
+			if err != nil {
+				l.Bail(fmt.Errorf("public: unable to resize %q. %w", file.Path, err))
+			}
+			resized = append(resized, &File{
+				Path:         name,
+				Data:         data,
+				Mode:         file.Mode,
+				ModTime:      file.ModTime,
+				Stream:       true,
+				CacheControl: file.CacheControl,
+			})
+		}
+	}
+	return resized
+}
+
+// convertImages converts the images and their resized variants to the formats
+// that can be encoded, skipping images that were already converted in public/
+func (l *loader) convertImages(files, resized []*File, paths []string) (converted []*File) {
+	exists := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exists[path] = true
+	}
+	var images []*File
+	for _, file := range files {
+		if imageset.Supported(file.Path) {
+			images = append(images, file)
+		}
+	}
+	images = append(images, resized...)
+	for _, format := range imageset.Formats {
+		if !imageset.CanEncode(format) {
+			continue
+		}
+		for _, image := range images {
+			name := imageset.Converted(image.Path, format)
+			if exists[name] {
+				continue
+			}
+			data, err := imageset.Convert(image.Data, 0, format)
+			if err != nil {
+				l.Bail(fmt.Errorf("public: unable to convert %q to %s. %w", image.Path, format, err))
+			}
+			converted = append(converted, &File{
+				Path:         name,
+				Data:         data,
+				Mode:         image.Mode,
+				ModTime:      image.ModTime,
+				Stream:       true,
+				CacheControl: image.CacheControl,
+			})
+		}
+	}
+	return converted
+}
@@ -1,10 +1,18 @@
 package public_test
 
 import (
+	"bytes"
 	"context"
+	"go/format"
+	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/framework/public"
 	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/imports"
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/internal/testdir"
 )
@@ -174,3 +182,222 @@ func TestEmbedFavicon(t *testing.T) {
 	is.Equal(res.Body().Bytes(), favicon)
 	is.NoErr(app.Close())
 }
+
+func TestGenerateEmbed(t *testing.T) {
+	is := is.New(t)
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	code, err := public.Generate(&public.State{
+		Imports: []*imports.Import{
+			{Name: "virtual", Path: "github.com/livebud/bud/package/virtual"},
+			{Name: "time", Path: "time"},
+		},
+		Files: []*public.File{
+			{Path: "public/run.sh", Route: "/run.sh", Data: []byte("#!/bin/sh"), Mode: 0755, ModTime: modTime},
+			{Path: "public/video.mp4", Route: "/video.mp4", Data: []byte("mp4"), Mode: 0644, ModTime: modTime, Stream: true},
+		},
+	})
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	// Ignore the alignment of the fields
+	generated := strings.Join(strings.Fields(string(code)), " ")
+	is.In(generated, `Mode: 0755,`)
+	is.In(generated, `ModTime: time.Unix(1640995200, 0),`)
+	is.In(generated, `Data: []byte("\x23\x21\x2f\x62\x69\x6e\x2f\x73\x68"),`)
+	is.In(generated, `Size: 3,`)
+	is.In(generated, `Stream: virtual.StreamString("\x6d\x70\x34"),`)
+}
+
+func TestGenerateEncoded(t *testing.T) {
+	is := is.New(t)
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	code, err := public.Generate(&public.State{
+		Imports: []*imports.Import{
+			{Name: "virtual", Path: "github.com/livebud/bud/package/virtual"},
+			{Name: "time", Path: "time"},
+		},
+		Files: []*public.File{
+			{Path: "public/app.js", Route: "/app.js", Data: []byte("app"), Mode: 0644, ModTime: modTime},
+		},
+		Encoded: []*public.File{
+			{Path: "public/app.js.gz", Data: []byte("gz"), Mode: 0644, ModTime: modTime},
+		},
+	})
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	generated := strings.Join(strings.Fields(string(code)), " ")
+	is.In(generated, `"public/app.js": &virtual.File{`)
+	is.In(generated, `"public/app.js.gz": &virtual.File{`)
+	is.In(generated, `Data: []byte("\x67\x7a"),`)
+}
+
+func TestGenerateVariantRoutes(t *testing.T) {
+	is := is.New(t)
+	code, err := public.Generate(&public.State{
+		Imports: []*imports.Import{
+			{Name: "virtual", Path: "github.com/livebud/bud/package/virtual"},
+		},
+		Files: []*public.File{
+			{Path: "public/cat.jpg", Route: "/cat.jpg", Variants: []string{"/cat.320w.jpg", "/cat.640w.jpg"}},
+		},
+	})
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	is.In(string(code), "r.Get(`/cat.jpg`, h.handler)")
+	is.In(string(code), "r.Add(http.MethodHead, `/cat.jpg`, h.handler)")
+	is.In(string(code), "r.Get(`/cat.320w.jpg`, h.handler)")
+	is.In(string(code), "r.Add(http.MethodHead, `/cat.320w.jpg`, h.handler)")
+	is.In(string(code), "r.Get(`/cat.640w.jpg`, h.handler)")
+}
+
+func TestGenerateConverted(t *testing.T) {
+	is := is.New(t)
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	code, err := public.Generate(&public.State{
+		Imports: []*imports.Import{
+			{Name: "virtual", Path: "github.com/livebud/bud/package/virtual"},
+			{Name: "time", Path: "time"},
+		},
+		Files: []*public.File{
+			{Path: "public/cat.jpg", Route: "/cat.jpg", Data: []byte("jpg"), Mode: 0644, ModTime: modTime, Variants: []string{"/cat.320w.jpg"}},
+		},
+		Resized: []*public.File{
+			{Path: "public/cat.320w.jpg", Data: []byte("320"), Mode: 0644, ModTime: modTime},
+		},
+		Converted: []*public.File{
+			{Path: "public/cat.jpg.webp", Data: []byte("webp"), Mode: 0644, ModTime: modTime},
+			{Path: "public/cat.320w.jpg.webp", Data: []byte("webp"), Mode: 0644, ModTime: modTime},
+		},
+	})
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	generated := strings.Join(strings.Fields(string(code)), " ")
+	is.In(generated, `"public/cat.jpg.webp": &virtual.File{`)
+	is.In(generated, `"public/cat.320w.jpg.webp": &virtual.File{`)
+	// Converted images are served in place of the image, so they have no routes
+	is.True(!strings.Contains(generated, "r.Get(`/cat.jpg.webp`"))
+}
+
+func TestCacheControl(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{
+			"bud": {
+				"public": {
+					"cache": [
+						{ "path": "/fonts/**", "control": "public, max-age=86400" }
+					]
+				}
+			}
+		}`)},
+		"public/app.3f9a2c1b.js":            &fstest.MapFile{Data: []byte("app")},
+		"public/chunk-HBKQ2D7C.js":          &fstest.MapFile{Data: []byte("chunk")},
+		"public/bootstrap.css":              &fstest.MapFile{Data: []byte("css")},
+		"public/fonts/inter.a1b2c3d4.woff2": &fstest.MapFile{Data: []byte("font")},
+	}
+	state, err := public.Load(fsys, &framework.Flag{Embed: true})
+	is.NoErr(err)
+	controls := map[string]string{}
+	for _, file := range state.Files {
+		controls[file.Route] = file.CacheControl
+	}
+	is.Equal(controls["/app.3f9a2c1b.js"], "public, max-age=31536000, immutable")
+	is.Equal(controls["/chunk-HBKQ2D7C.js"], "public, max-age=31536000, immutable")
+	is.Equal(controls["/bootstrap.css"], "public, max-age=300, must-revalidate")
+	is.Equal(controls["/fonts/inter.a1b2c3d4.woff2"], "public, max-age=86400")
+	// Development always revalidates
+	state, err = public.Load(fsys, &framework.Flag{})
+	is.NoErr(err)
+	for _, file := range state.Files {
+		is.Equal(file.CacheControl, "no-cache")
+	}
+}
+
+func TestInvalidCachePath(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"package.json":   &fstest.MapFile{Data: []byte(`{"bud":{"public":{"cache":[{"path":"fonts/**","control":"no-store"}]}}}`)},
+		"public/app.css": &fstest.MapFile{Data: []byte("css")},
+	}
+	_, err := public.Load(fsys, &framework.Flag{Embed: true})
+	is.True(err != nil)
+	is.In(err.Error(), `public: cache path "fonts/**" in package.json must start with a slash`)
+}
+
+func TestGenerateCacheControl(t *testing.T) {
+	is := is.New(t)
+	code, err := public.Generate(&public.State{
+		Imports: []*imports.Import{
+			{Name: "virtual", Path: "github.com/livebud/bud/package/virtual"},
+		},
+		Files: []*public.File{
+			{Path: "public/cat.jpg", Route: "/cat.jpg", Variants: []string{"/cat.320w.jpg"}, CacheControl: "no-cache"},
+		},
+	})
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	is.In(string(code), "r.Get(`/cat.jpg`, publicrt.CacheControl(\"no-cache\", h.handler))")
+	is.In(string(code), "r.Get(`/cat.320w.jpg`, publicrt.CacheControl(\"no-cache\", h.handler))")
+}
+
+func TestLoadLazy(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"public/app.js":  &fstest.MapFile{Data: bytes.Repeat([]byte("console.log('hi');\n"), 100)},
+		"public/tiny.js": &fstest.MapFile{Data: []byte("1")},
+	}
+	state, err := public.Load(fsys, &framework.Flag{Embed: true})
+	is.NoErr(err)
+	is.Equal(len(state.Encoded), 1)
+	is.Equal(state.Encoded[0].Const, "gzipped0")
+	code, err := public.Generate(state)
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	generated := strings.Join(strings.Fields(string(code)), " ")
+	// The gzipped contents are embedded once
+	is.In(generated, `gzipped0 = "\x1f\x8b`)
+	is.Equal(strings.Count(generated, `"\x1f\x8b`), 1)
+	is.In(generated, `Stream: virtual.GunzipString(gzipped0),`)
+	is.In(generated, `Stream: virtual.StreamString(gzipped0),`)
+	is.In(generated, `Size: 1900,`)
+	// Other files are streamed from strings
+	is.In(generated, `Stream: virtual.StreamString("\x31"),`)
+	is.True(!strings.Contains(generated, `[]byte(`))
+	is.In(generated, `os.Getenv("BUD_PUBLIC_DIR")`)
+}
+
+func TestLoadDisk(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		"package.json":  &fstest.MapFile{Data: []byte(`{"bud":{"public":{"embed":false}}}`)},
+		"public/app.js": &fstest.MapFile{Data: bytes.Repeat([]byte("console.log('hi');\n"), 100)},
+	}
+	state, err := public.Load(fsys, &framework.Flag{Embed: true})
+	is.NoErr(err)
+	is.True(state.Disk)
+	is.Equal(len(state.Encoded), 0)
+	// Routes keep their production Cache-Control headers
+	is.Equal(state.Files[0].CacheControl, "public, max-age=300, must-revalidate")
+	code, err := public.Generate(state)
+	is.NoErr(err)
+	_, err = format.Source(code)
+	is.NoErr(err)
+	is.In(string(code), `return publicrt.DirFS(os.Getenv("BUD_PUBLIC_DIR"))`)
+	is.True(!strings.Contains(string(code), "virtual.Map{"))
+	// Production doesn't convert images on demand
+	is.True(!state.OnDemand)
+	is.True(!strings.Contains(string(code), "OnDemand"))
+	// Development doesn't read from disk in the app
+	state, err = public.Load(fsys, &framework.Flag{})
+	is.NoErr(err)
+	is.True(!state.Disk)
+	is.True(state.OnDemand)
+	code, err = public.Generate(state)
+	is.NoErr(err)
+	is.In(string(code), "handler.OnDemand = true")
+}
@@ -1,43 +1,370 @@
 package publicrt
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"os"
 	"path"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/livebud/bud/package/imageset"
 )
 
 type FS = fs.FS
 
+// StreamFS is an optional interface for filesystems that can stream files
+// without buffering them into memory first (e.g. the budhttp client)
+type StreamFS interface {
+	FS
+	Stream(name string) (fs.File, error)
+}
+
+// DirFS reads public/ from dir on disk, which defaults to public in the
+// working directory. Paths keep their public/ prefix, like the embedded files.
+func DirFS(dir string) FS {
+	if dir == "" {
+		dir = "public"
+	}
+	return &dirFS{os.DirFS(dir)}
+}
+
+type dirFS struct {
+	fsys fs.FS
+}
+
+func (d *dirFS) Open(name string) (fs.File, error) {
+	if name == "public" {
+		return d.fsys.Open(".")
+	}
+	rel := strings.TrimPrefix(name, "public/")
+	if rel == name || !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return d.fsys.Open(rel)
+}
+
 func NewHandler(fsys FS) *Handler {
-	return &Handler{http.FS(fsys)}
+	handler := &Handler{fsys: http.FS(fsys), images: newImageCache()}
+	if sfs, ok := fsys.(StreamFS); ok {
+		handler.stream = sfs.Stream
+	}
+	return handler
 }
 
 type Handler struct {
-	fsys http.FileSystem
+	fsys   http.FileSystem
+	stream func(name string) (fs.File, error)
+	images *imageCache
+
+	// OnDemand resizes and converts images that weren't generated ahead of
+	// time. It's for development, since production builds generate the images
+	// at build time and converting them on request is slow and unbounded.
+	// Without it, missing variants are served as the original image.
+	OnDemand bool
 }
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	file, err := h.fsys.Open(path.Join("public", r.URL.Path))
+	name := path.Join("public", r.URL.Path)
+	// Responses depend on the encodings the client accepts
+	w.Header().Add("Vary", "Accept-Encoding")
+	// Serve the precompressed variant, if there is one
+	for _, encoding := range encodings {
+		if !accepts(r.Header.Get("Accept-Encoding"), encoding.name) {
+			continue
+		}
+		file, err := h.open(name + encoding.ext)
+		if err != nil {
+			continue
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil || stat.IsDir() {
+			continue
+		}
+		w.Header().Set("Content-Encoding", encoding.name)
+		setETag(w, stat, encoding.name)
+		// Named after the original file, so the Content-Type matches
+		serveContent(w, r, r.URL.Path, stat.ModTime(), file)
+		return
+	}
+	// Serve images in the smallest format the client accepts
+	if imageset.Supported(name) {
+		w.Header().Add("Vary", "Accept")
+		for _, format := range imageset.Accepted(r.Header.Get("Accept")) {
+			if h.serveConverted(w, r, name, format) {
+				return
+			}
+		}
+	}
+	file, err := h.open(name)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		// Images that weren't resized at build time are resized on demand
+		if original, width, ok := imageset.Parse(name); ok {
+			h.serveImage(w, r, original, width)
+			return
+		}
+		serveError(w, err.Error(), 500)
 		return
 	}
 	defer file.Close()
 	stat, err := file.Stat()
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		serveError(w, err.Error(), 500)
 		return
 	}
 	if stat.IsDir() {
-		http.Error(w, fmt.Sprintf("%q is a directory", r.URL.Path), 500)
+		serveError(w, fmt.Sprintf("%q is a directory", r.URL.Path), 500)
 		return
 	}
+	setETag(w, stat, "")
+	serveContent(w, r, r.URL.Path, stat.ModTime(), file)
+}
+
+// serveFormat serves the image that was already converted to format
+func (h Handler) serveFormat(w http.ResponseWriter, r *http.Request, name, format string) bool {
+	file, err := h.open(name)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	w.Header().Set("Content-Type", imageset.ContentType(format))
+	setETag(w, stat, format)
 	serveContent(w, r, r.URL.Path, stat.ModTime(), file)
+	return true
+}
+
+// Open a file, preferring to stream it if the filesystem supports it
+func (h Handler) open(name string) (seekableFile, error) {
+	if h.stream == nil {
+		return h.fsys.Open(name)
+	}
+	file, err := h.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	sf, ok := file.(seekableFile)
+	if !ok {
+		file.Close()
+		return nil, fmt.Errorf("public: streamed file %q is not seekable", name)
+	}
+	return sf, nil
+}
+
+type seekableFile interface {
+	fs.File
+	io.Seeker
+}
+
+// CacheControl sets the Cache-Control header of the files served by handler
+func CacheControl(value string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", value)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// serveError without caching it
+func serveError(w http.ResponseWriter, message string, status int) {
+	w.Header().Del("Cache-Control")
+	http.Error(w, message, status)
 }
 
+// serveContent supports conditional and Range requests, including multiple
+// ranges, which are served as multipart/byteranges
 func serveContent(w http.ResponseWriter, req *http.Request, name string, modtime time.Time, content io.ReadSeeker) {
+	// Ranges of precompressed variants are ranges of the compressed bytes
+	w.Header().Set("Accept-Ranges", "bytes")
 	http.ServeContent(w, req, name, modtime, content)
 }
+
+// setETag sets a strong ETag from the file's size and modification time.
+// Precompressed variants get their own ETag, so If-Range never resumes a
+// download with bytes from another encoding.
+func setETag(w http.ResponseWriter, stat fs.FileInfo, encoding string) {
+	if stat.ModTime().IsZero() {
+		return
+	}
+	etag := strconv.FormatInt(stat.ModTime().UnixNano(), 16) + "-" + strconv.FormatInt(stat.Size(), 16)
+	if encoding != "" {
+		etag += "-" + encoding
+	}
+	w.Header().Set("ETag", `"`+etag+`"`)
+}
+
+// serveImage resizes the original image to width, or serves the original when
+// images aren't resized on demand
+func (h Handler) serveImage(w http.ResponseWriter, r *http.Request, original string, width int) {
+	file, err := h.open(original)
+	if err != nil {
+		serveError(w, err.Error(), 500)
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		serveError(w, err.Error(), 500)
+		return
+	}
+	if stat.IsDir() {
+		serveError(w, fmt.Sprintf("%q is a directory", original), 500)
+		return
+	}
+	if !h.OnDemand {
+		setETag(w, stat, "")
+		serveContent(w, r, original, stat.ModTime(), file)
+		return
+	}
+	data, err := h.images.Convert(file, original, stat.ModTime(), width, "")
+	if err != nil {
+		serveError(w, err.Error(), 500)
+		return
+	}
+	serveContent(w, r, r.URL.Path, stat.ModTime(), bytes.NewReader(data))
+}
+
+// serveConverted serves the image converted to format. Images that weren't
+// converted at build time are converted on demand in development, if there's
+// an encoder for the format. It returns false when the image can't be converted, so the next
+// format can be tried.
+func (h Handler) serveConverted(w http.ResponseWriter, r *http.Request, name, format string) bool {
+	if h.serveFormat(w, r, imageset.Converted(name, format), format) {
+		return true
+	}
+	// Convert the image itself or the original it's a variant of
+	source, width := name, 0
+	file, err := h.open(source)
+	if err != nil {
+		original, variant, ok := imageset.Parse(name)
+		if !ok {
+			return false
+		}
+		source, width = original, variant
+		if file, err = h.open(source); err != nil {
+			return false
+		}
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	// Variants at or above the original's width are the original
+	if width > 0 {
+		if original, err := imageset.Width(file); err == nil && width >= original {
+			if h.serveFormat(w, r, imageset.Converted(source, format), format) {
+				return true
+			}
+			width = 0
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return false
+		}
+	}
+	if !h.OnDemand || !imageset.CanEncode(format) {
+		return false
+	}
+	data, err := h.images.Convert(file, source, stat.ModTime(), width, format)
+	if err != nil {
+		return false
+	}
+	w.Header().Set("Content-Type", imageset.ContentType(format))
+	serveContent(w, r, r.URL.Path, stat.ModTime(), bytes.NewReader(data))
+	return true
+}
+
+func newImageCache() *imageCache {
+	return &imageCache{images: map[imageKey]resizedImage{}}
+}
+
+// imageCache holds the images resized and converted on demand, until the
+// original changes
+type imageCache struct {
+	mu     sync.Mutex
+	images map[imageKey]resizedImage
+}
+
+type imageKey struct {
+	path   string
+	width  int
+	format string
+}
+
+type resizedImage struct {
+	modTime time.Time
+	data    []byte
+}
+
+func (c *imageCache) Convert(file io.Reader, path string, modTime time.Time, width int, format string) ([]byte, error) {
+	key := imageKey{path, width, format}
+	if c != nil {
+		c.mu.Lock()
+		image, ok := c.images[key]
+		c.mu.Unlock()
+		if ok && image.modTime.Equal(modTime) {
+			return image.data, nil
+		}
+	}
+	original, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	data, err := imageset.Convert(original, width, format)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil {
+		c.mu.Lock()
+		c.images[key] = resizedImage{modTime, data}
+		c.mu.Unlock()
+	}
+	return data, nil
+}
+
+// encodings of precompressed files, in order of preference
+var encodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// accepts is true if the Accept-Encoding header allows the encoding. Naming
+// the encoding takes precedence over the "*" wildcard.
+func accepts(header, encoding string) bool {
+	wildcard := false
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		switch {
+		case strings.EqualFold(name, encoding):
+			return !zeroQuality(params)
+		case name == "*":
+			wildcard = !zeroQuality(params)
+		}
+	}
+	return wildcard
+}
+
+// zeroQuality is true for encodings marked as unacceptable with "q=0"
+func zeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.TrimSpace(key) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q == 0
+	}
+	return false
+}
@@ -0,0 +1,375 @@
+package publicrt_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/livebud/bud/framework/public/publicrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/virtual"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/app.js":      &fstest.MapFile{Data: []byte("app")},
+		"public/app.js.br":   &fstest.MapFile{Data: []byte("br")},
+		"public/app.js.gz":   &fstest.MapFile{Data: []byte("gz")},
+		"public/lib.js":      &fstest.MapFile{Data: []byte("lib")},
+		"public/lib.js.gz":   &fstest.MapFile{Data: []byte("gz")},
+		"public/favicon.ico": &fstest.MapFile{Data: []byte("ico")},
+	})
+	tests := []struct {
+		path     string
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"/app.js", "", "", "app"},
+		{"/app.js", "gzip, deflate, br", "br", "br"},
+		{"/app.js", "gzip", "gzip", "gz"},
+		{"/app.js", "br;q=0, gzip", "gzip", "gz"},
+		{"/app.js", "*", "br", "br"},
+		{"/app.js", "*, br;q=0, gzip;q=0", "", "app"},
+		{"/lib.js", "br, gzip", "gzip", "gz"},
+		{"/lib.js", "br", "", "lib"},
+		{"/favicon.ico", "br, gzip", "", "ico"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		res := rec.Result()
+		is.Equal(res.StatusCode, 200)
+		is.Equal(res.Header.Get("Vary"), "Accept-Encoding")
+		is.Equal(res.Header.Get("Content-Encoding"), test.encoding)
+		is.Equal(rec.Body.String(), test.body)
+	}
+}
+
+func TestEncodedContentType(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/style.css":    &fstest.MapFile{Data: []byte("body{}")},
+		"public/style.css.gz": &fstest.MapFile{Data: []byte("gz")},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/style.css", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Header().Get("Content-Encoding"), "gzip")
+	is.Equal(rec.Header().Get("Content-Type"), "text/css; charset=utf-8")
+}
+
+func TestResizeOnDemand(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 800, 600))))
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/cat.png": &fstest.MapFile{Data: buf.Bytes()},
+	})
+	handler.OnDemand = true
+	// Resized to the variant's width
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cat.320w.png", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "image/png")
+	config, err := png.DecodeConfig(rec.Body)
+	is.NoErr(err)
+	is.Equal(config.Width, 320)
+	is.Equal(config.Height, 240)
+	// Wider variants are the original
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cat.1280w.png", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.Bytes(), buf.Bytes())
+	// Other widths aren't variants
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cat.321w.png", nil))
+	is.Equal(rec.Code, 500)
+}
+
+func TestConvertOnDemand(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 800, 600))))
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/cat.png":      &fstest.MapFile{Data: buf.Bytes()},
+		"public/cat.png.avif": &fstest.MapFile{Data: []byte("avif")},
+	})
+	handler.OnDemand = true
+	accept := "image/avif,image/webp,*/*"
+	// Converted images are served to browsers that accept them
+	req := httptest.NewRequest(http.MethodGet, "/cat.320w.png", nil)
+	req.Header.Set("Accept", "image/webp,*/*")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "image/webp")
+	is.Equal(rec.Header().Values("Vary"), []string{"Accept-Encoding", "Accept"})
+	config, format, err := image.DecodeConfig(rec.Body)
+	is.NoErr(err)
+	is.Equal(format, "webp")
+	is.Equal(config.Width, 320)
+	// Images converted at build time are preferred, including for variants
+	// that are the original
+	for _, path := range []string{"/cat.png", "/cat.1280w.png"} {
+		req = httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		is.Equal(rec.Code, 200)
+		is.Equal(rec.Header().Get("Content-Type"), "image/avif")
+		is.Equal(rec.Body.String(), "avif")
+	}
+	// Other browsers get the original format
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/cat.320w.png", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "image/png")
+}
+
+func TestWithoutOnDemand(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 800, 600))))
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/cat.png":           &fstest.MapFile{Data: buf.Bytes()},
+		"public/cat.320w.png.webp": &fstest.MapFile{Data: []byte("webp")},
+	})
+	// Missing variants are served as the original
+	req := httptest.NewRequest(http.MethodGet, "/cat.640w.png", nil)
+	req.Header.Set("Accept", "image/avif,image/webp,*/*")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "image/png")
+	is.Equal(rec.Body.Bytes(), buf.Bytes())
+	// Images converted ahead of time are still served
+	req = httptest.NewRequest(http.MethodGet, "/cat.320w.png", nil)
+	req.Header.Set("Accept", "image/avif,image/webp,*/*")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "image/webp")
+	is.Equal(rec.Body.String(), "webp")
+}
+
+func TestCacheControl(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.CacheControl("public, max-age=300", publicrt.NewHandler(fstest.MapFS{
+		"public/app.css": &fstest.MapFile{Data: []byte("body{}")},
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/app.css", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Cache-Control"), "public, max-age=300")
+	// Errors aren't cached
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/missing.css", nil))
+	is.Equal(rec.Code, 500)
+	is.Equal(rec.Header().Get("Cache-Control"), "")
+}
+
+const alphabet = "abcdefghijklmnopqrstuvwxyz"
+
+func TestRange(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/video.mp4": &fstest.MapFile{Data: []byte(alphabet), ModTime: time.Unix(1700000000, 0)},
+	})
+	tests := []struct {
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"bytes=0-4", 206, "bytes 0-4/26", "abcde"},
+		{"bytes=20-", 206, "bytes 20-25/26", "uvwxyz"},
+		{"bytes=-3", 206, "bytes 23-25/26", "xyz"},
+		{"bytes=25-100", 206, "bytes 25-25/26", "z"},
+		{"bytes=30-", 416, "bytes */26", ""},
+		{"bytes=5-2", 416, "", ""},
+		{"bytes=abc", 416, "", ""},
+		// Ranges that add up to more than the file are ignored
+		{"bytes=0-20,5-25", 200, "", alphabet},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+		req.Header.Set("Range", test.rangeHeader)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		is.Equal(rec.Code, test.status)
+		is.Equal(rec.Header().Get("Accept-Ranges"), "bytes")
+		is.Equal(rec.Header().Get("Content-Range"), test.contentRange)
+		if test.status != 416 {
+			is.Equal(rec.Body.String(), test.body)
+		}
+	}
+}
+
+func TestMultiRange(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/archive.zip": &fstest.MapFile{Data: []byte(alphabet), ModTime: time.Unix(1700000000, 0)},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/archive.zip", nil)
+	req.Header.Set("Range", "bytes=0-1, 10-12, -2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 206)
+	mediaType, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	is.NoErr(err)
+	is.Equal(mediaType, "multipart/byteranges")
+	reader := multipart.NewReader(rec.Body, params["boundary"])
+	expect := []struct {
+		contentRange string
+		body         string
+	}{
+		{"bytes 0-1/26", "ab"},
+		{"bytes 10-12/26", "klm"},
+		{"bytes 24-25/26", "yz"},
+	}
+	for _, part := range expect {
+		p, err := reader.NextPart()
+		is.NoErr(err)
+		is.Equal(p.Header.Get("Content-Range"), part.contentRange)
+		is.Equal(p.Header.Get("Content-Type"), "application/zip")
+		body, err := io.ReadAll(p)
+		is.NoErr(err)
+		is.Equal(string(body), part.body)
+	}
+	_, err = reader.NextPart()
+	is.Equal(err, io.EOF)
+}
+
+func TestIfRange(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/video.mp4": &fstest.MapFile{Data: []byte(alphabet), ModTime: time.Unix(1700000000, 0)},
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/video.mp4", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Length"), "26")
+	is.Equal(rec.Header().Get("Accept-Ranges"), "bytes")
+	etag := rec.Header().Get("ETag")
+	is.True(etag != "")
+	// Resume the download while the file is unchanged
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", etag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 206)
+	is.Equal(rec.Body.String(), alphabet[10:])
+	// Restart the download once the file changed
+	req = httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=10-")
+	req.Header.Set("If-Range", `"stale"`)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), alphabet)
+}
+
+func TestRangeEncoded(t *testing.T) {
+	is := is.New(t)
+	modTime := time.Unix(1700000000, 0)
+	handler := publicrt.NewHandler(fstest.MapFS{
+		"public/data.json":    &fstest.MapFile{Data: []byte(alphabet), ModTime: modTime},
+		"public/data.json.gz": &fstest.MapFile{Data: []byte("0123456789"), ModTime: modTime},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/data.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	gzipETag := rec.Header().Get("ETag")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/data.json", nil))
+	is.Equal(rec.Code, 200)
+	identityETag := rec.Header().Get("ETag")
+	is.True(gzipETag != identityETag)
+	// Ranges of precompressed variants are ranges of the compressed bytes
+	req = httptest.NewRequest(http.MethodGet, "/data.json", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=5-")
+	req.Header.Set("If-Range", gzipETag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 206)
+	is.Equal(rec.Header().Get("Content-Encoding"), "gzip")
+	is.Equal(rec.Header().Get("Accept-Ranges"), "bytes")
+	is.Equal(rec.Header().Get("Content-Range"), "bytes 5-9/10")
+	is.Equal(rec.Body.String(), "56789")
+	// Resuming with the ETag of another encoding restarts the download
+	req = httptest.NewRequest(http.MethodGet, "/data.json", nil)
+	req.Header.Set("Range", "bytes=5-")
+	req.Header.Set("If-Range", gzipETag)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), alphabet)
+}
+
+func TestRangeStream(t *testing.T) {
+	is := is.New(t)
+	handler := publicrt.NewHandler(virtual.Map{
+		"public/video.mp4": &virtual.File{
+			Path:    "public/video.mp4",
+			ModTime: time.Unix(1700000000, 0),
+			Size:    26,
+			Stream:  virtual.StreamString(alphabet),
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=3-5,-2")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 206)
+	_, params, err := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	is.NoErr(err)
+	reader := multipart.NewReader(rec.Body, params["boundary"])
+	for _, expect := range []string{"def", "yz"} {
+		part, err := reader.NextPart()
+		is.NoErr(err)
+		body, err := io.ReadAll(part)
+		is.NoErr(err)
+		is.Equal(string(body), expect)
+	}
+}
+
+func TestDirFS(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "css"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "css", "app.css"), []byte("body{}"), 0644))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "..", "secret.txt"), []byte("secret"), 0644))
+	fsys := publicrt.DirFS(dir)
+	handler := publicrt.NewHandler(fsys)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/css/app.css", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), "body{}")
+	is.Equal(rec.Header().Get("Content-Type"), "text/css; charset=utf-8")
+	// Only files in public/ can be opened
+	_, err := fsys.Open("css/app.css")
+	is.True(err != nil)
+	_, err = fsys.Open("public/../secret.txt")
+	is.True(err != nil)
+}
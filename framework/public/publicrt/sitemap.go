@@ -0,0 +1,82 @@
+package publicrt
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ExcludeDirective leaves a controller or action out of the sitemap, e.g.
+//
+//	//sitemap:exclude
+//	type Controller struct {}
+const ExcludeDirective = "sitemap:exclude"
+
+// NewSitemap lists the pages at routes, which were last modified at lastmod
+func NewSitemap(lastmod time.Time, routes ...string) *Sitemap {
+	return &Sitemap{lastmod, routes}
+}
+
+// Sitemap serves sitemap.xml and robots.txt for the app's static pages.
+// Sitemaps need absolute URLs, so they're built from the request's host.
+type Sitemap struct {
+	lastmod time.Time
+	routes  []string
+}
+
+var _ http.Handler = (*Sitemap)(nil)
+
+type urlset struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod"`
+}
+
+// ServeHTTP serves sitemap.xml
+func (s *Sitemap) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	base := baseURL(r)
+	lastmod := s.lastmod.UTC().Format(time.RFC3339)
+	set := urlset{URLs: make([]sitemapURL, len(s.routes))}
+	for i, route := range s.routes {
+		set.URLs[i] = sitemapURL{base + route, lastmod}
+	}
+	data, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("publicrt: unable to encode the sitemap. %s", err), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Last-Modified", s.lastmod.UTC().Format(http.TimeFormat))
+	io.WriteString(w, xml.Header)
+	w.Write(data)
+	io.WriteString(w, "\n")
+}
+
+// Robots serves robots.txt, which points crawlers to the sitemap
+func (s *Sitemap) Robots() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		robots := new(strings.Builder)
+		robots.WriteString("User-agent: *\n")
+		robots.WriteString("Disallow: /bud/\n")
+		robots.WriteString("\n")
+		robots.WriteString("Sitemap: " + baseURL(r) + "/sitemap.xml\n")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, robots.String())
+	})
+}
+
+// baseURL of the app, as seen by the client
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,48 @@
+package publicrt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/public/publicrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestSitemap(t *testing.T) {
+	is := is.New(t)
+	lastmod := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sitemap := publicrt.NewSitemap(lastmod, "/", "/about")
+	req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+	req.Host = "example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	sitemap.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "application/xml; charset=utf-8")
+	is.Equal(rec.Body.String(), `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2022-01-01T00:00:00Z</lastmod>
+  </url>
+  <url>
+    <loc>https://example.com/about</loc>
+    <lastmod>2022-01-01T00:00:00Z</lastmod>
+  </url>
+</urlset>
+`)
+}
+
+func TestRobots(t *testing.T) {
+	is := is.New(t)
+	sitemap := publicrt.NewSitemap(time.Now(), "/")
+	req := httptest.NewRequest(http.MethodGet, "/robots.txt", nil)
+	req.Host = "localhost:3000"
+	rec := httptest.NewRecorder()
+	sitemap.Robots().ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	is.Equal(rec.Body.String(), "User-agent: *\nDisallow: /bud/\n\nSitemap: http://localhost:3000/sitemap.xml\n")
+}
@@ -1,6 +1,9 @@
 package public
 
 import (
+	"io/fs"
+	"time"
+
 	"github.com/livebud/bud/internal/embed"
 	"github.com/livebud/bud/internal/imports"
 )
@@ -8,10 +11,38 @@ import (
 type State struct {
 	Imports []*imports.Import
 	Files   []*File
+	// Precompressed variants of the files, like public/app.js.gz. They're
+	// embedded without routes and served to clients that accept them.
+	Encoded []*File
+	// Resized variants of the images, like public/cat.640w.jpg. Variants that
+	// aren't embedded are resized on demand in development.
+	Resized []*File
+	// Images converted to smaller formats, like public/cat.640w.jpg.webp.
+	// They're embedded without routes and served to clients that accept them.
+	Converted []*File
+	// Disk is true when production builds read public/ from disk instead of
+	// embedding it
+	Disk bool
+	// OnDemand resizes and converts images as they're requested. It's only
+	// true in development, since production builds do it ahead of time.
+	OnDemand bool
 }
 
 type File struct {
-	Path  string
-	Route string
-	Data  embed.Data
+	Path    string
+	Route   string
+	Data    embed.Data
+	Mode    fs.FileMode
+	ModTime time.Time
+	Stream  bool // Streamed from a string instead of copied into memory
+	// Routes to the resized variants of images, like /cat.640w.jpg
+	Variants []string
+	// Cache-Control header for the file and its variants
+	CacheControl string
+	// Gzipped variant of the file. The file is decompressed from it on demand
+	// rather than embedded twice.
+	Gzipped *File
+	// Const holds the contents of gzipped variants, which are shared with the
+	// original file
+	Const string
 }
@@ -0,0 +1,160 @@
+package schedule
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"github.com/livebud/bud/framework/schedule/schedulert"
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"github.com/matthewmueller/gotext"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+	des, err := fs.ReadDir(fsys, "schedule")
+	if err != nil {
+		return nil, err
+	}
+	hasGo := false
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			hasGo = true
+			break
+		}
+	}
+	if !hasGo {
+		return nil, fs.ErrNotExist
+	}
+	loader := &loader{
+		imports: imports.New(),
+		module:  module,
+		parser:  parser,
+		deps:    map[string]string{},
+	}
+	return loader.Load()
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	module  *gomod.Module
+	parser  *parser.Parser
+	deps    map[string]string // type -> variable
+}
+
+// Load the schedule state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "schedule: unable to load")
+	state = new(State)
+	pkg, err := l.parser.Parse("schedule")
+	if err != nil {
+		l.Bail(err)
+	}
+	l.imports.AddStd("context")
+	l.imports.AddNamed("log", "github.com/livebud/bud/package/log")
+	l.imports.AddNamed("schedulert", "github.com/livebud/bud/framework/schedule/schedulert")
+	l.imports.AddNamed("schedule", l.module.Import("schedule"))
+	// Tasks that depend on the log share the scheduler's log
+	l.deps["log.Interface"] = "log"
+	for _, fn := range pkg.PublicFunctions() {
+		if fn.Receiver() != nil {
+			continue
+		}
+		state.Tasks = append(state.Tasks, l.loadTask(state, fn))
+	}
+	if len(state.Tasks) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// loadTask checks that the function has the func(context.Context, ...) error
+// signature and a schedule:cron directive
+func (l *loader) loadTask(state *State, fn *parser.Function) *Task {
+	task := &Task{
+		Name:   fn.Name(),
+		Path:   fn.File().Path(),
+		Missed: "Skip",
+	}
+	params := fn.Params()
+	results := fn.Results()
+	if len(params) == 0 || len(results) != 1 || !results[0].IsError() ||
+		!l.isType(params[0].Type(), "context", "Context") {
+		l.Bail(fmt.Errorf("expected %s in %s to have the signature func(context.Context, ...) error", task.Name, task.Path))
+	}
+	for _, directive := range fn.Directives() {
+		fields := strings.SplitN(directive, " ", 2)
+		value := ""
+		if len(fields) == 2 {
+			value = strings.TrimSpace(fields[1])
+		}
+		switch fields[0] {
+		case "schedule:cron":
+			if _, err := schedulert.Parse(value); err != nil {
+				l.Bail(fmt.Errorf("invalid schedule for %s in %s. %w", task.Name, task.Path, err))
+			}
+			task.Cron = value
+		case "schedule:missed":
+			missed, err := schedulert.ParseMissed(value)
+			if err != nil {
+				l.Bail(fmt.Errorf("invalid missed policy for %s in %s. %w", task.Name, task.Path, err))
+			}
+			task.Missed = gotext.Pascal(string(missed))
+		}
+	}
+	if task.Cron == "" {
+		l.Bail(fmt.Errorf("expected %s in %s to have a schedule, like //schedule:cron 0 * * * *", task.Name, task.Path))
+	}
+	// The rest of the parameters are injected
+	for _, param := range params[1:] {
+		task.Args = append(task.Args, l.loadDep(state, param.Type()))
+	}
+	return task
+}
+
+// loadDep returns the variable for the dependency, sharing variables between
+// tasks with the same dependency
+func (l *loader) loadDep(state *State, dt parser.Type) string {
+	typ := l.loadType(dt)
+	if variable, ok := l.deps[typ]; ok {
+		return variable
+	}
+	variable := "dep" + strconv.Itoa(len(state.Deps)+1)
+	l.deps[typ] = variable
+	state.Deps = append(state.Deps, &Dep{
+		Variable: variable,
+		Type:     typ,
+	})
+	return variable
+}
+
+func (l *loader) isType(t parser.Type, importPath, name string) bool {
+	ok, err := parser.IsImportType(t, importPath, name)
+	if err != nil {
+		l.Bail(err)
+	}
+	return ok
+}
+
+// loadType qualifies the dependency's type
+func (l *loader) loadType(dt parser.Type) string {
+	importPath, err := parser.ImportPath(parser.Innermost(dt))
+	if err != nil {
+		l.Bail(err)
+	}
+	if importPath == "" {
+		return dt.String()
+	}
+	name := l.imports.Add(importPath)
+	// Types declared alongside the task are unqualified
+	if _, ok := parser.Innermost(dt).(*parser.IdentType); ok {
+		return parser.Qualify(dt, name).String()
+	}
+	return parser.Requalify(dt, name).String()
+}
@@ -0,0 +1,46 @@
+package schedule
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed schedule.gotext
+var template string
+
+var generator = gotemplate.MustParse("framework/schedule/schedule.gotext", template)
+
+// Generate the scheduler from state
+func Generate(state *State) ([]byte, error) {
+	return generator.Generate(state)
+}
+
+// New scheduler generator
+func New(module *gomod.Module, parser *parser.Parser) *Generator {
+	return &Generator{module, parser}
+}
+
+// Generator for the scheduler, which runs each function in schedule/ on its
+// cron schedule
+type Generator struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := Load(fsys, g.module, g.parser)
+	if err != nil {
+		return fmt.Errorf("framework/schedule: unable to load. %w", err)
+	}
+	code, err := Generate(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
@@ -0,0 +1,107 @@
+package schedule_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestNoSchedule(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.NoErr(td.NotExists("bud/internal/scheduler"))
+}
+
+func TestMissingCron(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["schedule/cleanup.go"] = `
+		package schedule
+		import "context"
+		func Cleanup(ctx context.Context) error {
+			return nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), "expected Cleanup in schedule/cleanup.go to have a schedule, like //schedule:cron 0 * * * *")
+}
+
+func TestInvalidCron(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["schedule/cleanup.go"] = `
+		package schedule
+		import "context"
+		//schedule:cron 61 * * * *
+		func Cleanup(ctx context.Context) error {
+			return nil
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "build")
+	is.True(err != nil)
+	is.In(err.Error(), `invalid schedule for Cleanup in schedule/cleanup.go. schedulert: invalid minute in "61 * * * *"`)
+}
+
+func TestSchedule(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["schedule/tick.go"] = `
+		package schedule
+		import (
+			"context"
+			"os"
+			"path/filepath"
+			"github.com/livebud/bud/package/log"
+		)
+		// Tick every second
+		//schedule:cron @every 1s
+		//schedule:missed once
+		func Tick(ctx context.Context, log log.Interface) error {
+			log.Debug("tick")
+			return os.WriteFile(filepath.Join(os.Getenv("OUTBOX"), "tick"), []byte("tock"), 0644)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	outbox := t.TempDir()
+	cli := testcli.New(dir)
+	cli.Env["OUTBOX"] = outbox
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	is.NoErr(td.Exists("bud/internal/scheduler/scheduler.go"))
+	var data []byte
+	for i := 0; i < 50; i++ {
+		data, err = os.ReadFile(filepath.Join(outbox, "tick"))
+		if err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	is.NoErr(err)
+	is.Equal(string(data), "tock")
+	is.NoErr(app.Close())
+}
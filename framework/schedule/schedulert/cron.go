@@ -0,0 +1,203 @@
+package schedulert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns when a task runs next
+type Schedule interface {
+	// Next returns the first time after t that the task runs
+	Next(t time.Time) time.Time
+}
+
+// Parse a cron expression with minute, hour, day of month, month and day of
+// week fields:
+//
+//	*/15 * * * *   every 15 minutes
+//	0 9 * * mon-fri 9am on weekdays
+//
+// Fields support lists (1,15), ranges (1-5), steps (*/2) and names (jan,
+// mon). Parse also supports the @yearly, @monthly, @weekly, @daily and
+// @hourly shorthands, as well as "@every 90s".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("schedulert: invalid duration in %q. %w", spec, err)
+		}
+		if d < time.Second {
+			return nil, fmt.Errorf("schedulert: expected %q to be at least a second", spec)
+		}
+		return every(d), nil
+	}
+	if shorthand, ok := shorthands[spec]; ok {
+		spec = shorthand
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedulert: expected 5 fields in %q, got %d", spec, len(fields))
+	}
+	cron := new(cron)
+	var err error
+	if cron.minute, err = parseField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("schedulert: invalid minute in %q. %w", spec, err)
+	}
+	if cron.hour, err = parseField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("schedulert: invalid hour in %q. %w", spec, err)
+	}
+	if cron.dom, err = parseField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("schedulert: invalid day of month in %q. %w", spec, err)
+	}
+	if cron.month, err = parseField(fields[3], 1, 12, months); err != nil {
+		return nil, fmt.Errorf("schedulert: invalid month in %q. %w", spec, err)
+	}
+	// Allow 7 for Sunday
+	if cron.dow, err = parseField(fields[4], 0, 7, weekdays); err != nil {
+		return nil, fmt.Errorf("schedulert: invalid day of week in %q. %w", spec, err)
+	}
+	if cron.dow&(1<<7) != 0 {
+		cron.dow |= 1
+	}
+	// When both days are restricted, cron runs on either of them
+	cron.either = !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*")
+	if cron.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("schedulert: %q never runs", spec)
+	}
+	return cron, nil
+}
+
+// MustParse parses the cron expression, panicking if it's invalid
+func MustParse(spec string) Schedule {
+	schedule, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+var shorthands = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var months = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// parseField parses a field into a bitset of the values it matches
+func parseField(field string, min, max int, names map[string]int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			stepped = true
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part[i+1:])
+			}
+			part = part[:i]
+		}
+		start, end := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			if start, err = parseValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(bounds[1], min, max, names); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			if start, err = parseValue(part, min, max, names); err != nil {
+				return 0, err
+			}
+			// A step without a range runs from the value to the max (e.g. 5/15)
+			if !stepped {
+				end = start
+			}
+		}
+		for i := start; i <= end; i += step {
+			bits |= 1 << i
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, min, max int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToLower(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+	if n < min || n > max {
+		return 0, fmt.Errorf("expected %d to be between %d and %d", n, min, max)
+	}
+	return n, nil
+}
+
+type cron struct {
+	minute, hour, dom, month, dow uint64
+	either                        bool
+}
+
+// Next time the cron expression matches in t's location
+func (c *cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	// Give up on expressions that never match, like February 30th
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.either {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// every runs at a fixed interval
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e)).Truncate(time.Second)
+}
@@ -0,0 +1,154 @@
+// Package schedulert runs the tasks in schedule/ on their cron schedules.
+package schedulert
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/graceful"
+	"github.com/livebud/bud/package/log"
+)
+
+// Missed decides what to do with runs that were missed because the previous
+// run was still going
+type Missed string
+
+const (
+	// Skip missed runs and wait for the next one. This is the default.
+	Skip Missed = "skip"
+	// Once runs the task once right away to make up for the missed runs
+	Once Missed = "once"
+)
+
+// ParseMissed parses the missed-run policy
+func ParseMissed(policy string) (Missed, error) {
+	switch Missed(policy) {
+	case "", Skip:
+		return Skip, nil
+	case Once:
+		return Once, nil
+	default:
+		return "", fmt.Errorf("schedulert: expected the missed policy to be skip or once, got %q", policy)
+	}
+}
+
+// Task runs on a schedule
+type Task struct {
+	Name     string
+	Schedule Schedule
+	Missed   Missed
+	Run      func(ctx context.Context) error
+}
+
+// Next returns when the task runs after the run that was scheduled at
+// scheduled and finished at finished. Runs never overlap, so runs that were
+// due while the task was running are handled by the missed-run policy.
+func (t *Task) Next(scheduled, finished time.Time) time.Time {
+	next := t.Schedule.Next(scheduled)
+	if next.IsZero() || next.After(finished) {
+		return next
+	}
+	if t.Missed == Once {
+		return finished
+	}
+	return t.Schedule.Next(finished)
+}
+
+// New scheduler. It's configured from the environment:
+//
+//	SCHEDULE=off
+//	SCHEDULE_SHUTDOWN_TIMEOUT=30s
+//
+// Turn the schedule off in processes that shouldn't run it, like when the
+// schedule runs in a separate "bud work" process. SCHEDULE_SHUTDOWN_TIMEOUT is
+// how long running tasks have to finish when the scheduler shuts down.
+func New(log log.Interface) (*Scheduler, error) {
+	return newScheduler(log, os.Getenv)
+}
+
+func newScheduler(log log.Interface, getenv func(key string) string) (*Scheduler, error) {
+	scheduler := &Scheduler{Now: time.Now, ShutdownTimeout: 30 * time.Second, log: log.Named("schedule")}
+	switch value := getenv("SCHEDULE"); value {
+	case "", "on":
+	case "off":
+		scheduler.off = true
+	default:
+		return nil, fmt.Errorf("schedulert: expected SCHEDULE to be on or off, got %q", value)
+	}
+	if value := getenv("SCHEDULE_SHUTDOWN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("schedulert: expected SCHEDULE_SHUTDOWN_TIMEOUT to be a duration like 30s, got %q", value)
+		}
+		scheduler.ShutdownTimeout = timeout
+	}
+	return scheduler, nil
+}
+
+// Scheduler runs tasks on their schedules
+type Scheduler struct {
+	Now func() time.Time
+	// ShutdownTimeout is how long running tasks have to finish after the
+	// scheduler is canceled
+	ShutdownTimeout time.Duration
+	log             log.Interface
+	off             bool
+	tasks           []*Task
+}
+
+// Add a task to the schedule
+func (s *Scheduler) Add(task *Task) {
+	s.tasks = append(s.tasks, task)
+}
+
+// Run the tasks until the context is canceled. Tasks that are running finish
+// first, unless they outlast the shutdown timeout.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if s.off {
+		return nil
+	}
+	taskCtx, cancel := graceful.Context(ctx, s.ShutdownTimeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	wg.Add(len(s.tasks))
+	for _, task := range s.tasks {
+		go func(task *Task) {
+			defer wg.Done()
+			s.loop(ctx, taskCtx, task)
+		}(task)
+	}
+	wg.Wait()
+	return nil
+}
+
+// loop waits for the task's next run until ctx is canceled and runs the task
+// with taskCtx
+func (s *Scheduler) loop(ctx, taskCtx context.Context, task *Task) {
+	next := task.Schedule.Next(s.Now())
+	for !next.IsZero() {
+		timer := time.NewTimer(next.Sub(s.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.run(taskCtx, task); err != nil {
+			s.log.Error("schedulert: task failed", "task", task.Name, "error", err)
+		}
+		next = task.Next(next, s.Now())
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, task *Task) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			err = fmt.Errorf("schedulert: %s panicked. %v", task.Name, e)
+		}
+	}()
+	s.log.Debug("schedulert: running task", "task", task.Name)
+	return task.Run(ctx)
+}
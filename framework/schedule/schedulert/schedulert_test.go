@@ -0,0 +1,178 @@
+package schedulert_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/schedule/schedulert"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	is := is.New(t)
+	tests := []struct {
+		spec  string
+		after string
+		next  string
+	}{
+		{"* * * * *", "2023-05-10 10:15:30", "2023-05-10 10:16:00"},
+		{"*/15 * * * *", "2023-05-10 10:15:00", "2023-05-10 10:30:00"},
+		{"*/15 * * * *", "2023-05-10 23:59:00", "2023-05-11 00:00:00"},
+		{"0 9 * * mon-fri", "2023-05-12 09:00:00", "2023-05-15 09:00:00"},
+		{"30 2 1 * *", "2023-05-10 00:00:00", "2023-06-01 02:30:00"},
+		{"0 0 1 jan *", "2023-05-10 00:00:00", "2024-01-01 00:00:00"},
+		{"0 0 29 2 *", "2023-03-01 00:00:00", "2024-02-29 00:00:00"},
+		{"0 12 * * 7", "2023-05-10 00:00:00", "2023-05-14 12:00:00"},
+		{"5/20 * * * *", "2023-05-10 10:26:00", "2023-05-10 10:45:00"},
+		{"0 8,17 * * *", "2023-05-10 09:00:00", "2023-05-10 17:00:00"},
+		// Either day matches when both are restricted
+		{"0 0 13 * fri", "2023-05-10 00:00:00", "2023-05-12 00:00:00"},
+		{"@hourly", "2023-05-10 10:15:00", "2023-05-10 11:00:00"},
+		{"@daily", "2023-05-10 10:15:00", "2023-05-11 00:00:00"},
+		{"@weekly", "2023-05-10 10:15:00", "2023-05-14 00:00:00"},
+		{"@every 90s", "2023-05-10 10:15:00", "2023-05-10 10:16:30"},
+	}
+	for _, test := range tests {
+		schedule, err := schedulert.Parse(test.spec)
+		is.NoErr(err)
+		is.Equal(schedule.Next(date(test.after)), date(test.next), test.spec)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	is := is.New(t)
+	tests := map[string]string{
+		"* * * *":      `schedulert: expected 5 fields in "* * * *", got 4`,
+		"60 * * * *":   `schedulert: invalid minute in "60 * * * *". expected 60 to be between 0 and 59`,
+		"* * * foo *":  `schedulert: invalid month in "* * * foo *". invalid value "foo"`,
+		"*/0 * * * *":  `schedulert: invalid minute in "*/0 * * * *". invalid step "0"`,
+		"5-1 * * * *":  `schedulert: invalid minute in "5-1 * * * *". invalid range "5-1"`,
+		"0 0 30 2 *":   `schedulert: "0 0 30 2 *" never runs`,
+		"@every 100ms": `schedulert: expected "@every 100ms" to be at least a second`,
+	}
+	for spec, expect := range tests {
+		_, err := schedulert.Parse(spec)
+		is.True(err != nil)
+		is.Equal(err.Error(), expect)
+	}
+}
+
+func TestMissed(t *testing.T) {
+	is := is.New(t)
+	task := &schedulert.Task{
+		Name:     "Report",
+		Schedule: schedulert.MustParse("*/5 * * * *"),
+	}
+	// Finished before the next run
+	is.Equal(task.Next(date("2023-05-10 10:00:00"), date("2023-05-10 10:01:00")), date("2023-05-10 10:05:00"))
+	// Skip the runs that were missed
+	is.Equal(task.Next(date("2023-05-10 10:00:00"), date("2023-05-10 10:12:00")), date("2023-05-10 10:15:00"))
+	// Run once to make up for them
+	task.Missed = schedulert.Once
+	is.Equal(task.Next(date("2023-05-10 10:00:00"), date("2023-05-10 10:12:00")), date("2023-05-10 10:12:00"))
+	missed, err := schedulert.ParseMissed("")
+	is.NoErr(err)
+	is.Equal(missed, schedulert.Skip)
+	_, err = schedulert.ParseMissed("all")
+	is.Equal(err.Error(), `schedulert: expected the missed policy to be skip or once, got "all"`)
+}
+
+func TestRun(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler, err := schedulert.New(log.Discard)
+	is.NoErr(err)
+	var runs, running, overlaps int32
+	scheduler.Add(&schedulert.Task{
+		Name:     "Tick",
+		Schedule: schedulert.MustParse("@every 1s"),
+		Run: func(ctx context.Context) error {
+			if atomic.AddInt32(&running, 1) > 1 {
+				atomic.AddInt32(&overlaps, 1)
+			}
+			defer atomic.AddInt32(&running, -1)
+			if atomic.AddInt32(&runs, 1) == 1 {
+				panic("oops")
+			}
+			return errors.New("failed")
+		},
+	})
+	result := make(chan error, 1)
+	go func() { result <- scheduler.Run(ctx) }()
+	time.Sleep(2500 * time.Millisecond)
+	cancel()
+	is.NoErr(<-result)
+	// Panics and errors don't stop the schedule
+	is.True(atomic.LoadInt32(&runs) >= 2)
+	is.Equal(atomic.LoadInt32(&overlaps), int32(0))
+}
+
+func TestDrain(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	scheduler, err := schedulert.New(log.Discard)
+	is.NoErr(err)
+	scheduler.ShutdownTimeout = 100 * time.Millisecond
+	started := make(chan struct{}, 1)
+	finished := make(chan error, 1)
+	scheduler.Add(&schedulert.Task{
+		Name:     "Slow",
+		Schedule: schedulert.MustParse("@every 1s"),
+		Run: func(ctx context.Context) error {
+			started <- struct{}{}
+			select {
+			case <-time.After(20 * time.Millisecond):
+				finished <- ctx.Err()
+			case <-ctx.Done():
+				finished <- ctx.Err()
+			}
+			return nil
+		},
+	})
+	result := make(chan error, 1)
+	go func() { result <- scheduler.Run(ctx) }()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the task to start")
+	}
+	// The running task finishes after the scheduler is canceled
+	cancel()
+	is.NoErr(<-result)
+	is.NoErr(<-finished)
+}
+
+func TestOff(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("SCHEDULE", "off")
+	scheduler, err := schedulert.New(log.Discard)
+	is.NoErr(err)
+	scheduler.Add(&schedulert.Task{
+		Name:     "Never",
+		Schedule: schedulert.MustParse("@every 1s"),
+		Run: func(ctx context.Context) error {
+			return errors.New("shouldn't run")
+		},
+	})
+	// Returns right away
+	is.NoErr(scheduler.Run(context.Background()))
+	t.Setenv("SCHEDULE", "sometimes")
+	_, err = schedulert.New(log.Discard)
+	is.Equal(err.Error(), `schedulert: expected SCHEDULE to be on or off, got "sometimes"`)
+	t.Setenv("SCHEDULE", "on")
+	t.Setenv("SCHEDULE_SHUTDOWN_TIMEOUT", "soon")
+	_, err = schedulert.New(log.Discard)
+	is.Equal(err.Error(), `schedulert: expected SCHEDULE_SHUTDOWN_TIMEOUT to be a duration like 30s, got "soon"`)
+}
@@ -0,0 +1,24 @@
+package schedule
+
+import "github.com/livebud/bud/internal/imports"
+
+type State struct {
+	Imports []*imports.Import
+	Deps    []*Dep
+	Tasks   []*Task
+}
+
+// Dep is a dependency that's injected into the tasks
+type Dep struct {
+	Variable string // Variable holding the dependency (e.g. dep1)
+	Type     string // Qualified type of the dependency (e.g. *db.DB)
+}
+
+// Task is a function in schedule/ with a schedule:cron directive
+type Task struct {
+	Name   string   // Name of the function (e.g. Cleanup)
+	Path   string   // Path to the file containing the function (e.g. schedule/cleanup.go)
+	Cron   string   // Cron expression (e.g. */5 * * * *)
+	Missed string   // Missed-run policy (e.g. Skip)
+	Args   []string // Variables passed after the context
+}
@@ -0,0 +1,101 @@
+package seed
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/livebud/bud/internal/bail"
+	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+	des, err := fs.ReadDir(fsys, "seed")
+	if err != nil {
+		return nil, err
+	}
+	hasGo := false
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			hasGo = true
+			break
+		}
+	}
+	if !hasGo {
+		return nil, fs.ErrNotExist
+	}
+	loader := &loader{
+		imports: imports.New(),
+		module:  module,
+		parser:  parser,
+	}
+	return loader.Load()
+}
+
+type loader struct {
+	bail.Struct
+	imports *imports.Set
+	module  *gomod.Module
+	parser  *parser.Parser
+}
+
+// Load the seed state
+func (l *loader) Load() (state *State, err error) {
+	defer l.Recover2(&err, "seed: unable to load")
+	state = new(State)
+	pkg, err := l.parser.Parse("seed")
+	if err != nil {
+		l.Bail(err)
+	}
+	for _, fn := range pkg.PublicFunctions() {
+		if fn.Receiver() != nil {
+			continue
+		}
+		state.Seeds = append(state.Seeds, l.loadSeed(fn))
+	}
+	if len(state.Seeds) == 0 {
+		return nil, fs.ErrNotExist
+	}
+	l.imports.AddStd("context", "fmt", "os")
+	l.imports.AddNamed("clock", "github.com/livebud/bud/package/clock")
+	l.imports.AddNamed("log", "github.com/livebud/bud/package/log")
+	l.imports.AddNamed("console", "github.com/livebud/bud/package/log/console")
+	l.imports.AddNamed("filter", "github.com/livebud/bud/package/log/filter")
+	l.imports.AddNamed("db", l.module.Import("bud/package/db"))
+	l.imports.AddNamed("seed", l.module.Import("seed"))
+	state.Imports = l.imports.List()
+	return state, nil
+}
+
+// loadSeed checks that the function has the func(context.Context, *db.DB) error
+// signature
+func (l *loader) loadSeed(fn *parser.Function) *Seed {
+	seed := &Seed{
+		Name: fn.Name(),
+		Path: fn.File().Path(),
+	}
+	params := fn.Params()
+	results := fn.Results()
+	if len(params) != 2 || len(results) != 1 || !results[0].IsError() ||
+		!l.isType(params[0].Type(), "context", "Context") ||
+		!l.isType(params[1].Type(), l.module.Import("bud/package/db"), "DB") ||
+		!isPointer(params[1].Type()) {
+		l.Bail(fmt.Errorf("expected %s in %s to have the signature func(context.Context, *db.DB) error", seed.Name, seed.Path))
+	}
+	return seed
+}
+
+func (l *loader) isType(t parser.Type, importPath, name string) bool {
+	ok, err := parser.IsImportType(t, importPath, name)
+	if err != nil {
+		l.Bail(err)
+	}
+	return ok
+}
+
+func isPointer(t parser.Type) bool {
+	_, ok := t.(*parser.StarType)
+	return ok
+}
@@ -0,0 +1,46 @@
+package seed
+
+import (
+	_ "embed"
+	"fmt"
+
+	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+//go:embed seed.gotext
+var template string
+
+var generator = gotemplate.MustParse("framework/seed/seed.gotext", template)
+
+// Generate the seed command from state
+func Generate(state *State) ([]byte, error) {
+	return generator.Generate(state)
+}
+
+// New seed generator
+func New(module *gomod.Module, parser *parser.Parser) *Generator {
+	return &Generator{module, parser}
+}
+
+// Generator for the seed command, which runs each public function in seed/
+// against the database
+type Generator struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	state, err := Load(fsys, g.module, g.parser)
+	if err != nil {
+		return fmt.Errorf("framework/seed: unable to load. %w", err)
+	}
+	code, err := Generate(state)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	return nil
+}
@@ -0,0 +1,14 @@
+package seed
+
+import "github.com/livebud/bud/internal/imports"
+
+type State struct {
+	Imports []*imports.Import
+	Seeds   []*Seed
+}
+
+// Seed is a function in seed/ that seeds the database
+type Seed struct {
+	Name string // Name of the function (e.g. Users)
+	Path string // Path to the file containing the function (e.g. seed/users.go)
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/livebud/bud/internal/entrypoint"
 	"github.com/livebud/bud/internal/esmeta"
 	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/internal/trace"
 	"github.com/livebud/bud/package/gomod"
 )
 
@@ -26,60 +27,14 @@ var template string
 // generator
 var generator = gotemplate.MustParse("dom.gotext", template)
 
-// Serve node_modules
-// TODO: migrate to it's own package
-func NodeModules(module *gomod.Module) budfs.FileGenerator {
-	plugins := []esbuild.Plugin{
-		domExternalizePlugin(),
-	}
-	return budfs.GenerateFile(func(fsys budfs.FS, file *budfs.File) error {
-		// If the name starts with node_modules, trim it to allow esbuild to do
-		// the resolving. e.g. node_modules/timeago.js => timeago.js
-		entryPoint := trimEntrypoint(file.Target())
-		result := esbuild.Build(esbuild.BuildOptions{
-			EntryPoints:   []string{entryPoint},
-			AbsWorkingDir: module.Directory(),
-			Format:        esbuild.FormatESModule,
-			Platform:      esbuild.PlatformBrowser,
-			// Add "import" condition to support svelte/internal
-			// https://esbuild.github.io/api/#how-conditions-work
-			Conditions: []string{"browser", "default", "import"},
-			Metafile:   true,
-			Bundle:     true,
-			Plugins:    plugins,
-		})
-		if len(result.Errors) > 0 {
-			msgs := esbuild.FormatMessages(result.Errors, esbuild.FormatMessagesOptions{
-				Color:         true,
-				Kind:          esbuild.ErrorMessage,
-				TerminalWidth: 80,
-			})
-			return fmt.Errorf(strings.Join(msgs, "\n"))
-		}
-		content := result.OutputFiles[0].Contents
-		// Replace require statements and updates the path on imports
-		code := replaceDependencyPaths(content)
-		file.Data = code
-		// Link the dependencies
-		metafile, err := esmeta.Parse(result.Metafile)
-		if err != nil {
-			return err
-		}
-		// Watch the dependencies for changes
-		if err := fsys.Watch(metafile.Dependencies()...); err != nil {
-			return err
-		}
-		return nil
-	})
-}
-
 func New(module *gomod.Module, transformer transformrt.Transformer) *Compiler {
-	return &Compiler{module, transformer}
+	return &Compiler{module, transformer, "127.0.0.1:35729"}
 }
 
 type Compiler struct {
 	module      *gomod.Module
 	transformer transformrt.Transformer
+	Hot         string // Address of the live-reload server
 }
 
 // Compile into a list of  views for embedding
@@ -100,6 +55,7 @@ func (c *Compiler) Compile(ctx context.Context, fsys fs.FS) ([]esbuild.OutputFil
 	}
 	// If the name starts with node_modules, trim it to allow esbuild to do
 	// the resolving. e.g. node_modules/livebud => livebud
+	end := trace.From(ctx).Start("esbuild", "bud/view")
 	result := esbuild.Build(esbuild.BuildOptions{
 		EntryPointsAdvanced: entries,
 		Outdir:              "/",
@@ -117,10 +73,11 @@ func (c *Compiler) Compile(ctx context.Context, fsys fs.FS) ([]esbuild.OutputFil
 		MinifySyntax:      true,
 		MinifyWhitespace:  true,
 		Plugins: append([]esbuild.Plugin{
-			domPlugin(fsys, c.module),
+			domPlugin(fsys, c.module, c.Hot),
 		}, c.transformer.Plugins()...),
 		Write: false,
 	})
+	end()
 	if len(result.Errors) > 0 {
 		msgs := esbuild.FormatMessages(result.Errors, esbuild.FormatMessagesOptions{
 			Color:         true,
@@ -167,6 +124,7 @@ func (c *Compiler) GenerateFile(fsys budfs.FS, file *budfs.File) error {
 		}
 	}
 	// Run esbuild
+	end := trace.From(fsys.Context()).Start("esbuild", file.Target())
 	result := esbuild.Build(esbuild.BuildOptions{
 		EntryPoints:   []string{entryPoint},
 		AbsWorkingDir: c.module.Directory(),
@@ -178,10 +136,11 @@ func (c *Compiler) GenerateFile(fsys budfs.FS, file *budfs.File) error {
 		Metafile:   true,
 		Bundle:     true,
 		Plugins: append([]esbuild.Plugin{
-			domPlugin(fsys, c.module),
+			domPlugin(fsys, c.module, c.Hot),
 			domExternalizePlugin(),
 		}, c.transformer.Plugins()...),
 	})
+	end()
 	if len(result.Errors) > 0 {
 		msgs := esbuild.FormatMessages(result.Errors, esbuild.FormatMessagesOptions{
 			Color:         true,
@@ -241,7 +200,7 @@ func trimEntrypoint(path string) string {
 }
 
 // Build the bud/view/$page.{jsx,svelte} client-side entrypoint
-func domPlugin(fsys fs.FS, module *gomod.Module) esbuild.Plugin {
+func domPlugin(fsys fs.FS, module *gomod.Module, hot string) esbuild.Plugin {
 	return esbuild.Plugin{
 		Name: "dom",
 		Setup: func(epb esbuild.PluginBuild) {
@@ -255,6 +214,7 @@ func domPlugin(fsys fs.FS, module *gomod.Module) esbuild.Plugin {
 				if err != nil {
 					return result, err
 				}
+				view.Hot = hot
 				code, err := generator.Generate(view)
 				if err != nil {
 					return result, err
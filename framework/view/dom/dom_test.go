@@ -54,7 +54,7 @@ func TestServeFile(t *testing.T) {
 	is.True(strings.Contains(string(code), `text("index")`))
 	is.True(strings.Contains(string(code), `"/bud/view/index.svelte": view_default`))
 	is.True(strings.Contains(string(code), `page: "/bud/view/index.svelte",`))
-	is.True(strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/hot/view/index.svelte", components)`))
+	is.True(strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/events", "view/index.svelte", components)`))
 
 	// Unwrapped version with node_modules rewritten
 	code, err = fs.ReadFile(bfs, "bud/view/index.svelte")
@@ -65,7 +65,7 @@ func TestServeFile(t *testing.T) {
 	// Unwrapped version doesn't contain wrapping
 	is.True(!strings.Contains(string(code), `"/bud/view/index.svelte": view_default`))
 	is.True(!strings.Contains(string(code), `page: "/bud/view/index.svelte",`))
-	is.True(!strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/hot/view/index.svelte", components)`))
+	is.True(!strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/events", "view/index.svelte", components)`))
 
 	// Read the wrapped version of about/index.svelte with node_modules rewritten
 	code, err = fs.ReadFile(bfs, "bud/view/about/_index.svelte.js")
@@ -75,7 +75,7 @@ func TestServeFile(t *testing.T) {
 	is.True(strings.Contains(string(code), `text("about")`))
 	is.True(strings.Contains(string(code), `"/bud/view/about/index.svelte": about_default`))
 	is.True(strings.Contains(string(code), `page: "/bud/view/about/index.svelte",`))
-	is.True(strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/hot/view/about/index.svelte", components)`))
+	is.True(strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/events", "view/about/index.svelte", components)`))
 
 	// Unwrapped version with node_modules rewritten
 	code, err = fs.ReadFile(bfs, "bud/view/about/index.svelte")
@@ -86,7 +86,35 @@ func TestServeFile(t *testing.T) {
 	// Unwrapped version doesn't contain wrapping
 	is.True(!strings.Contains(string(code), `"/bud/view/about/index.svelte": about_default`))
 	is.True(!strings.Contains(string(code), `page: "/bud/view/about/index.svelte",`))
-	is.True(!strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/hot/view/about/index.svelte", components)`))
+	is.True(!strings.Contains(string(code), `hot: new Hot("http://127.0.0.1:35729/bud/events", "view/about/index.svelte", components)`))
+}
+
+// Pages connect to the address that bud run picked, host included
+func TestHotAddr(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ctx := context.Background()
+	dir := t.TempDir()
+	vm, err := v8.Load()
+	is.NoErr(err)
+	svelteCompiler, err := svelte.Load(vm)
+	is.NoErr(err)
+	transformer := transformrt.MustLoad(
+		svelte.NewTransformable(svelteCompiler),
+	)
+	td := testdir.New(dir)
+	td.Files["view/index.svelte"] = `<h1>index</h1>`
+	td.NodeModules["svelte"] = versions.Svelte
+	is.NoErr(td.Write(ctx))
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	bfs := budfs.New(module, log)
+	compiler := dom.New(module, transformer.DOM)
+	compiler.Hot = "192.168.1.5:35730"
+	bfs.FileServer("bud/view", compiler)
+	code, err := fs.ReadFile(bfs, "bud/view/_index.svelte.js")
+	is.NoErr(err)
+	is.True(strings.Contains(string(code), `hot: new Hot("http://192.168.1.5:35730/bud/events", "view/index.svelte", components)`))
 }
 
 func TestNodeModules(t *testing.T) {
@@ -254,3 +282,67 @@ func TestUpdateFile(t *testing.T) {
 	is.True(errors.Is(err, fs.ErrNotExist))
 	is.Equal(code, nil)
 }
+
+func TestPrebundle(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ctx := context.Background()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                           "module app.com\n",
+		"package.json":                     `{"dependencies":{"uid":"*"}}`,
+		"node_modules/uid/package.json":    `{"name":"uid","main":"index.js"}`,
+		"node_modules/uid/index.js":        `import { pad } from "pad"; export const uid = () => pad("uid")`,
+		"node_modules/pad/package.json":    `{"name":"pad","main":"index.js"}`,
+		"node_modules/pad/index.js":        `export const pad = (s) => " " + s`,
+		"node_modules/unused/package.json": `{"name":"unused","main":"index.js"}`,
+		"node_modules/unused/index.js":     `export const unused = true`,
+	}
+	for path, data := range files {
+		is.NoErr(os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0755))
+		is.NoErr(os.WriteFile(filepath.Join(dir, path), []byte(data), 0644))
+	}
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	nodeModules := dom.NodeModules(module)
+	// Bundles the dependency and the node_modules it imports
+	bundled, err := nodeModules.Prebundle(ctx)
+	is.NoErr(err)
+	is.Equal(bundled, 2)
+	caches, err := filepath.Glob(filepath.Join(dir, "bud", ".cache", "node_modules", "*"))
+	is.NoErr(err)
+	is.Equal(len(caches), 1)
+	code, err := os.ReadFile(filepath.Join(caches[0], "uid.js"))
+	is.NoErr(err)
+	is.True(strings.Contains(string(code), `from "/bud/node_modules/pad"`))
+	_, err = os.Stat(filepath.Join(caches[0], "pad.js"))
+	is.NoErr(err)
+	// Already bundled
+	bundled, err = nodeModules.Prebundle(ctx)
+	is.NoErr(err)
+	is.Equal(bundled, 0)
+	// Requests are served from the cache
+	is.NoErr(os.WriteFile(filepath.Join(caches[0], "pad.js"), []byte("/* cached */"), 0644))
+	bfs := budfs.New(module, log)
+	bfs.FileServer("bud/node_modules", nodeModules)
+	code, err = fs.ReadFile(bfs, "bud/node_modules/pad")
+	is.NoErr(err)
+	is.Equal(string(code), "/* cached */")
+	// Bundles without a cache are cached once requested
+	code, err = fs.ReadFile(bfs, "bud/node_modules/unused")
+	is.NoErr(err)
+	is.True(strings.Contains(string(code), `unused = true`))
+	_, err = os.Stat(filepath.Join(caches[0], "unused.js"))
+	is.NoErr(err)
+	// Changing package.json rebundles and removes the outdated bundles
+	is.NoErr(os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies":{"pad":"*"}}`), 0644))
+	bfs.Change("package.json")
+	code, err = fs.ReadFile(bfs, "bud/node_modules/pad")
+	is.NoErr(err)
+	is.True(strings.Contains(string(code), `" " + s`))
+	bundled, err = nodeModules.Prebundle(ctx)
+	is.NoErr(err)
+	is.Equal(bundled, 0)
+	_, err = os.Stat(caches[0])
+	is.True(errors.Is(err, fs.ErrNotExist))
+}
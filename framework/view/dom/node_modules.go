@@ -0,0 +1,264 @@
+package dom
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cespare/xxhash"
+	esbuild "github.com/evanw/esbuild/pkg/api"
+	"github.com/livebud/bud/internal/esmeta"
+	"github.com/livebud/bud/internal/trace"
+	"github.com/livebud/bud/internal/versions"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+)
+
+// lockFiles change whenever the installed node_modules change
+var lockFiles = []string{
+	"package.json",
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+}
+
+// NodeModules serves node_modules as ESM bundles to the browser.
+// TODO: migrate to it's own package
+func NodeModules(module *gomod.Module) *NodeModuleCompiler {
+	return &NodeModuleCompiler{
+		module:  module,
+		plugins: []esbuild.Plugin{domExternalizePlugin()},
+	}
+}
+
+// NodeModuleCompiler bundles node_modules. Bundles are cached in
+// bud/.cache/node_modules and reused until package.json or a lockfile changes.
+type NodeModuleCompiler struct {
+	module  *gomod.Module
+	plugins []esbuild.Plugin
+}
+
+var _ budfs.FileGenerator = (*NodeModuleCompiler)(nil)
+
+func (c *NodeModuleCompiler) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	// Changing dependencies invalidates the bundles
+	if err := fsys.Watch(lockFiles...); err != nil {
+		return err
+	}
+	key, err := c.key()
+	if err != nil {
+		return err
+	}
+	// If the name starts with node_modules, trim it to allow esbuild to do
+	// the resolving. e.g. node_modules/timeago.js => timeago.js
+	entryPoint := trimEntrypoint(file.Target())
+	cachePath := c.cachePath(key, entryPoint)
+	if code, err := os.ReadFile(cachePath); err == nil {
+		file.Data = code
+		return nil
+	}
+	code, deps, err := c.bundle(fsys.Context(), entryPoint)
+	if err != nil {
+		return err
+	}
+	file.Data = code
+	// Linked packages live outside of node_modules, so watch their files for
+	// changes instead of caching them
+	if !isInstalled(deps) {
+		return fsys.Watch(deps...)
+	}
+	// Bundles are only an optimization, so failing to cache isn't an error
+	writeCache(cachePath, code)
+	return nil
+}
+
+// Prebundle the dependencies in package.json along with the node_modules they
+// import, so pages don't wait on esbuild the first time they load. Bundles
+// that are already cached are skipped. Returns the number of new bundles.
+func (c *NodeModuleCompiler) Prebundle(ctx context.Context) (int, error) {
+	key, err := c.key()
+	if err != nil {
+		return 0, err
+	}
+	if err := c.prune(key); err != nil {
+		return 0, err
+	}
+	queue, err := c.dependencies()
+	if err != nil {
+		return 0, err
+	}
+	seen := map[string]bool{}
+	bundled := 0
+	for len(queue) > 0 {
+		entryPoint := queue[0]
+		queue = queue[1:]
+		if seen[entryPoint] {
+			continue
+		}
+		seen[entryPoint] = true
+		if err := ctx.Err(); err != nil {
+			return bundled, err
+		}
+		cachePath := c.cachePath(key, entryPoint)
+		code, err := os.ReadFile(cachePath)
+		if err != nil {
+			var deps []string
+			code, deps, err = c.bundle(ctx, entryPoint)
+			if err != nil {
+				// Skip dependencies that can't run in the browser. Requesting them
+				// will report the error.
+				continue
+			}
+			if isInstalled(deps) {
+				writeCache(cachePath, code)
+				bundled++
+			}
+		}
+		// Bundles import other node_modules, so prebundle those too
+		for _, match := range reBundleImport.FindAllSubmatch(code, -1) {
+			queue = append(queue, string(match[1]))
+		}
+	}
+	return bundled, nil
+}
+
+var reBundleImport = regexp.MustCompile(`"/bud/node_modules/([^"]+)"`)
+
+// bundle a node module, returning the code and the files it was built from
+func (c *NodeModuleCompiler) bundle(ctx context.Context, entryPoint string) ([]byte, []string, error) {
+	end := trace.From(ctx).Start("esbuild", "bud/node_modules/"+entryPoint)
+	result := esbuild.Build(esbuild.BuildOptions{
+		EntryPoints:   []string{entryPoint},
+		AbsWorkingDir: c.module.Directory(),
+		Format:        esbuild.FormatESModule,
+		Platform:      esbuild.PlatformBrowser,
+		// Add "import" condition to support svelte/internal
+		// https://esbuild.github.io/api/#how-conditions-work
+		Conditions: []string{"browser", "default", "import"},
+		Metafile:   true,
+		Bundle:     true,
+		Plugins:    c.plugins,
+	})
+	end()
+	if len(result.Errors) > 0 {
+		msgs := esbuild.FormatMessages(result.Errors, esbuild.FormatMessagesOptions{
+			Color:         true,
+			Kind:          esbuild.ErrorMessage,
+			TerminalWidth: 80,
+		})
+		return nil, nil, fmt.Errorf(strings.Join(msgs, "\n"))
+	}
+	content := result.OutputFiles[0].Contents
+	// Replace require statements and updates the path on imports
+	code := replaceDependencyPaths(content)
+	metafile, err := esmeta.Parse(result.Metafile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return code, metafile.Dependencies(), nil
+}
+
+// key changes whenever the installed node_modules or bud's bundling changes
+func (c *NodeModuleCompiler) key() (string, error) {
+	h := xxhash.New()
+	fmt.Fprintf(h, "%s\n", versions.Bud)
+	for _, name := range lockFiles {
+		data, err := os.ReadFile(c.module.Directory(name))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", fmt.Errorf("dom: unable to read %q. %w", name, err)
+		}
+		fmt.Fprintf(h, "%s:%d\n", name, len(data))
+		h.Write(data)
+	}
+	return strconv.FormatUint(h.Sum64(), 36), nil
+}
+
+func (c *NodeModuleCompiler) cachePath(key, entryPoint string) string {
+	return c.module.Directory("bud", ".cache", "node_modules", key, filepath.FromSlash(entryPoint)+".js")
+}
+
+// prune bundles cached for dependencies that are no longer installed
+func (c *NodeModuleCompiler) prune(key string) error {
+	dir := c.module.Directory("bud", ".cache", "node_modules")
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("dom: unable to prune cached node_modules. %w", err)
+	}
+	for _, de := range des {
+		if de.Name() == key {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, de.Name())); err != nil {
+			return fmt.Errorf("dom: unable to prune cached node_modules. %w", err)
+		}
+	}
+	return nil
+}
+
+// dependencies listed in package.json
+func (c *NodeModuleCompiler) dependencies() ([]string, error) {
+	data, err := os.ReadFile(c.module.Directory("package.json"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("dom: unable to read package.json. %w", err)
+	}
+	var pkg struct {
+		Dependencies map[string]string `json:"dependencies"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("dom: unable to parse package.json. %w", err)
+	}
+	names := make([]string, 0, len(pkg.Dependencies))
+	for name := range pkg.Dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// isInstalled is true when every file in the bundle comes from node_modules
+func isInstalled(deps []string) bool {
+	for _, dep := range deps {
+		if !strings.HasPrefix(dep, "node_modules/") {
+			return false
+		}
+	}
+	return true
+}
+
+// writeCache writes through a temporary file, so concurrent readers never
+// see a partial bundle
+func writeCache(path string, code []byte) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(code); err != nil {
+		tmp.Close()
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp.Name(), path)
+}
@@ -2,6 +2,7 @@ package view
 
 import (
 	"context"
+	"encoding/json"
 	"io/fs"
 	"path"
 
@@ -56,28 +57,50 @@ func (l *loader) Load(ctx context.Context) (state *State, err error) {
 		return nil, fs.ErrNotExist
 	}
 	if l.flag.Embed {
-		// Add SSR
-		ssrCompiler := ssr.New(l.module, l.transform.SSR)
-		ssrCode, err := ssrCompiler.Compile(ctx, l.fsys)
+		// Load the client bundles from the CDN
+		config, err := framework.LoadConfig(l.fsys)
 		if err != nil {
 			return nil, err
 		}
-		state.Embeds = append(state.Embeds, &embed.File{
-			Path: "bud/view/_ssr.js",
-			Data: ssrCode,
-		})
 		// Add DOM
 		domCompiler := dom.New(l.module, l.transform.DOM)
 		files, err := domCompiler.Compile(ctx, l.fsys)
 		if err != nil {
 			return nil, err
 		}
+		manifest := ssr.Manifest{}
 		for _, file := range files {
+			filePath := path.Join("bud/view", file.Path)
 			state.Embeds = append(state.Embeds, &embed.File{
-				Path: path.Join("bud/view", file.Path),
+				Path: filePath,
 				Data: file.Contents,
 			})
+			manifest[filePath] = &ssr.Asset{
+				Src:       config.CDN + "/" + filePath,
+				Integrity: ssr.Integrity(file.Contents),
+			}
+		}
+		// Add the manifest for custom templates
+		manifestData, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		state.Embeds = append(state.Embeds, &embed.File{
+			Path: "bud/view/_manifest.json",
+			Data: manifestData,
+		})
+		// Add SSR with integrity hashes of the client bundles
+		ssrCompiler := ssr.New(l.module, l.transform.SSR)
+		ssrCompiler.CDN = config.CDN
+		ssrCompiler.Manifest = manifest
+		ssrCode, err := ssrCompiler.Compile(ctx, l.fsys)
+		if err != nil {
+			return nil, err
 		}
+		state.Embeds = append(state.Embeds, &embed.File{
+			Path: "bud/view/_ssr.js",
+			Data: ssrCode,
+		})
 	}
 	// fmt.Println(l.Flag.Embed, l.Transform.SSR, views)
 	if l.flag.Embed {
@@ -0,0 +1,44 @@
+package ssr
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Request to render a route with props. Used to render multiple fragments
+// (e.g. a layout and its islands) within a single evaluation.
+type Request struct {
+	Route string      `json:"route,omitempty"`
+	Props interface{} `json:"props,omitempty"`
+	// Context that components read with getContext(key), like the request's
+	// feature flags
+	Context map[string]interface{} `json:"context,omitempty"`
+}
+
+// BatchExpr creates an expression that renders each request against the
+// compiled _ssr.js script, returning a JSON array of responses in order.
+func BatchExpr(script string, requests []*Request) (string, error) {
+	renders := make([]string, len(requests))
+	for i, req := range requests {
+		props, err := json.Marshal(req.Props)
+		if err != nil {
+			return "", fmt.Errorf("ssr: unable to marshal props for %q. %w", req.Route, err)
+		}
+		context, err := json.Marshal(req.Context)
+		if err != nil {
+			return "", fmt.Errorf("ssr: unable to marshal context for %q. %w", req.Route, err)
+		}
+		renders[i] = fmt.Sprintf(`bud.render(%q, %s, %s)`, req.Route, props, context)
+	}
+	return fmt.Sprintf(`%s; "[" + [%s].join(",") + "]"`, script, strings.Join(renders, ", ")), nil
+}
+
+// UnmarshalBatch unmarshals the result of evaluating a batch expression
+func UnmarshalBatch(result []byte) ([]*Response, error) {
+	var responses []*Response
+	if err := json.Unmarshal(result, &responses); err != nil {
+		return nil, fmt.Errorf("ssr: unable to unmarshal batch. %w", err)
+	}
+	return responses, nil
+}
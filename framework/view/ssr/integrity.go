@@ -0,0 +1,32 @@
+package ssr
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+)
+
+// Integrity returns the Subresource Integrity hash of a client bundle, so
+// browsers refuse bundles that were tampered with, e.g. on a CDN.
+func Integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Asset is a client bundle in the manifest
+type Asset struct {
+	Src       string `json:"src"`
+	Integrity string `json:"integrity"`
+}
+
+// Manifest of the client bundles by path, like bud/view/_index.svelte.js.
+// Custom templates can read bud/view/_manifest.json to load the bundles
+// themselves.
+type Manifest map[string]*Asset
+
+// Integrity of the bundle at path
+func (m Manifest) Integrity(path string) string {
+	if asset, ok := m[path]; ok {
+		return asset.Integrity
+	}
+	return ""
+}
@@ -7,6 +7,7 @@ type View = {
   layout: any
   error?: any
   client: string
+  integrity?: string
 }
 
 export function createView(view: View) {
@@ -22,7 +23,7 @@ export function createView(view: View) {
     let inject = ""
     const hydrate = JSON.stringify(props)
     inject += `<script id="bud_props" type="text/template" defer>${hydrate}</script>`
-    inject += `<script type="module" src="${view.client}" defer></script>`
+    inject += `<script type="module" src="${view.client}"${integrity(view)} defer></script>`
     html = html.replace("</head>", inject + `</head>`)
     return {
       status: 200,
@@ -34,6 +35,13 @@ export function createView(view: View) {
   }
 }
 
+// integrity attributes for the client script. Cross-origin scripts need CORS
+// for the browser to check their hash.
+function integrity(view: View): string {
+  if (!view.integrity) return ""
+  return ` integrity="${view.integrity}" crossorigin="anonymous"`
+}
+
 function defaultLayout(props) {
   return React.createElement(
     "html",
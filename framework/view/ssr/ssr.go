@@ -18,6 +18,7 @@ import (
 	"github.com/livebud/bud/internal/entrypoint"
 	"github.com/livebud/bud/internal/esmeta"
 	"github.com/livebud/bud/internal/gotemplate"
+	"github.com/livebud/bud/internal/trace"
 	"github.com/livebud/bud/package/budfs"
 	"github.com/livebud/bud/package/gomod"
 )
@@ -39,16 +40,25 @@ func (res *Response) Write(w http.ResponseWriter) {
 }
 
 func New(module *gomod.Module, transformer transformrt.Transformer) *Compiler {
-	return &Compiler{module, transformer}
+	return &Compiler{module: module, transformer: transformer}
 }
 
 type Compiler struct {
 	module      *gomod.Module
 	transformer transformrt.Transformer
+
+	// CDN that serves the client bundles, like https://cdn.example.com. The
+	// app serves them when it's empty.
+	CDN string
+
+	// Manifest of the client bundles. Adds integrity attributes to the
+	// client scripts when set.
+	Manifest Manifest
 }
 
 func (c *Compiler) Compile(ctx context.Context, fsys budfs.FS) ([]byte, error) {
 	dir := c.module.Directory()
+	end := trace.From(ctx).Start("esbuild", "bud/view/_ssr.js")
 	result := esbuild.Build(esbuild.BuildOptions{
 		EntryPointsAdvanced: []esbuild.EntryPoint{
 			{
@@ -68,13 +78,14 @@ func (c *Compiler) Compile(ctx context.Context, fsys budfs.FS) ([]byte, error) {
 		Plugins: append([]esbuild.Plugin{
 			ssrPlugin(fsys, dir),
 			ssrRuntimePlugin(fsys, dir),
-			jsxPlugin(fsys, dir),
+			jsxPlugin(fsys, dir, c.entry),
 			jsxRuntimePlugin(fsys, dir),
 			jsxTransformPlugin(fsys, dir),
-			sveltePlugin(fsys, dir),
+			sveltePlugin(fsys, dir, c.entry),
 			svelteRuntimePlugin(fsys, dir),
 		}, c.transformer.Plugins()...),
 	})
+	end()
 	if len(result.Errors) > 0 {
 		msgs := esbuild.FormatMessages(result.Errors, esbuild.FormatMessagesOptions{
 			Color:         true,
@@ -167,13 +178,24 @@ func ssrRuntimePlugin(osfs fs.FS, dir string) esbuild.Plugin {
 	}
 }
 
+// entry is the state of a view's entry file
+type entry struct {
+	*entrypoint.View
+	CDN       string
+	Integrity string
+}
+
+func (c *Compiler) entry(view *entrypoint.View) *entry {
+	return &entry{view, c.CDN, c.Manifest.Integrity(view.Client)}
+}
+
 //go:embed jsx.gotext
 var jsxTemplate string
 
 var jsxGenerator = gotemplate.MustParse("jsx.gotext", jsxTemplate)
 
 // Generate the jsx entry file: bud/view/$page.jsx
-func jsxPlugin(osfs fs.FS, dir string) esbuild.Plugin {
+func jsxPlugin(osfs fs.FS, dir string, entry func(*entrypoint.View) *entry) esbuild.Plugin {
 	return esbuild.Plugin{
 		Name: "jsx",
 		Setup: func(epb esbuild.PluginBuild) {
@@ -187,7 +209,7 @@ func jsxPlugin(osfs fs.FS, dir string) esbuild.Plugin {
 				if err != nil {
 					return result, err
 				}
-				code, err := jsxGenerator.Generate(view)
+				code, err := jsxGenerator.Generate(entry(view))
 				if err != nil {
 					return result, err
 				}
@@ -251,7 +273,7 @@ var svelteTemplate string
 var svelteGenerator = gotemplate.MustParse("svelte.gotext", svelteTemplate)
 
 // Generate the svelte entry file: bud/view/$page.svelte
-func sveltePlugin(osfs fs.FS, dir string) esbuild.Plugin {
+func sveltePlugin(osfs fs.FS, dir string, entry func(*entrypoint.View) *entry) esbuild.Plugin {
 	return esbuild.Plugin{
 		Name: "svelte",
 		Setup: func(epb esbuild.PluginBuild) {
@@ -265,7 +287,7 @@ func sveltePlugin(osfs fs.FS, dir string) esbuild.Plugin {
 				if err != nil {
 					return result, err
 				}
-				code, err := svelteGenerator.Generate(view)
+				code, err := svelteGenerator.Generate(entry(view))
 				if err != nil {
 					return result, err
 				}
@@ -426,3 +426,90 @@ func TestUpdateFile(t *testing.T) {
 	is.NoErr(err)
 	is.True(!strings.Contains(string(code), `views["/:id"] = `), "cached version shouldn't contain /:id")
 }
+
+func TestIntegrity(t *testing.T) {
+	is := is.New(t)
+	is.Equal(ssr.Integrity([]byte("")), "sha384-OLBgp1GsljhM2TJ+sbHjaiH9txEUvgdDTAzHv2P24donTt6/529l+9Ua0vFImLlb")
+	is.Equal(ssr.Integrity([]byte("alert('hi')")), ssr.Integrity([]byte("alert('hi')")))
+	is.True(ssr.Integrity([]byte("alert('hi')")) != ssr.Integrity([]byte("alert('bye')")))
+}
+
+func TestSvelteIntegrity(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["view/index.svelte"] = `<h1>hi world</h1>`
+	td.NodeModules["svelte"] = versions.Svelte
+	is.NoErr(td.Write(ctx))
+	vm, err := v8.Load()
+	is.NoErr(err)
+	svelteCompiler, err := svelte.Load(vm)
+	is.NoErr(err)
+	transformer := transformrt.MustLoad(svelte.NewTransformable(svelteCompiler))
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	bfs := budfs.New(module, log)
+	compiler := ssr.New(module, transformer.SSR)
+	compiler.Manifest = ssr.Manifest{
+		"bud/view/_index.svelte.js": &ssr.Asset{
+			Src:       "/bud/view/_index.svelte.js",
+			Integrity: "sha384-abc",
+		},
+	}
+	bfs.FileGenerator("bud/view/_ssr.js", compiler)
+	code, err := fs.ReadFile(bfs, "bud/view/_ssr.js")
+	is.NoErr(err)
+	result, err := vm.Eval("render.js", string(code)+`; bud.render("/", {})`)
+	is.NoErr(err)
+	var res ssr.Response
+	err = json.Unmarshal([]byte(result), &res)
+	is.NoErr(err)
+	is.Equal(res.Status, 200)
+	is.True(strings.Contains(res.Body, `<script type="module" src="/bud/view/_index.svelte.js" integrity="sha384-abc" crossorigin="anonymous" defer></script>`))
+}
+
+func TestSvelteContext(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["view/index.svelte"] = `
+		<script>
+			import { getContext } from "svelte"
+			const features = getContext("features") || {}
+		</script>
+		{#if features.new_checkout}<h1>new checkout</h1>{:else}<h1>old checkout</h1>{/if}
+	`
+	td.NodeModules["svelte"] = versions.Svelte
+	is.NoErr(td.Write(ctx))
+	vm, err := v8.Load()
+	is.NoErr(err)
+	svelteCompiler, err := svelte.Load(vm)
+	is.NoErr(err)
+	transformer := transformrt.MustLoad(svelte.NewTransformable(svelteCompiler))
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	bfs := budfs.New(module, log)
+	bfs.FileGenerator("bud/view/_ssr.js", ssr.New(module, transformer.SSR))
+	code, err := fs.ReadFile(bfs, "bud/view/_ssr.js")
+	is.NoErr(err)
+	expr, err := ssr.BatchExpr(string(code), []*ssr.Request{
+		{Route: "/", Context: map[string]interface{}{"features": map[string]bool{"new_checkout": true}}},
+		{Route: "/"},
+	})
+	is.NoErr(err)
+	result, err := vm.Eval("render.js", expr)
+	is.NoErr(err)
+	responses, err := ssr.UnmarshalBatch([]byte(result))
+	is.NoErr(err)
+	is.Equal(len(responses), 2)
+	// The context is rendered and passed to the browser for hydration
+	is.In(responses[0].Body, `<h1>new checkout</h1>`)
+	is.In(responses[0].Body, `<script id="bud_context" type="text/template" defer>{"features":{"new_checkout":true}}</script>`)
+	// Without a context
+	is.In(responses[1].Body, `<h1>old checkout</h1>`)
+	is.True(!strings.Contains(responses[1].Body, `bud_context`))
+}
@@ -287,18 +287,21 @@ var import_jsesc = __toESM(require_jsesc());
 function createView(view) {
   view.layout = view.layout || defaultLayout;
   return function({ props, context }) {
-    const page = view.page.render(props);
+    const contextMap = new Map(Object.entries(context || {}));
+    const page = view.page.render(props, { context: contextMap });
     let css = page.css.code;
     let html = page.html;
     let head = page.head;
     const hydrate = (0, import_jsesc.default)(props, { isScriptContext: true, json: true });
+    const hydrateContext = contextScript(context);
     const layout = view.layout.render(props, {
+      context: contextMap,
       head: function() {
         return `
           ${head}
           <style>#bud{}${css}</style>
-          <script id="bud_props" type="text/template" defer>${hydrate}<\/script>
-          <script type="module" src="${view.client}" defer><\/script>
+          <script id="bud_props" type="text/template" defer>${hydrate}<\/script>${hydrateContext}
+          <script type="module" src="${view.client}"${integrity(view)} defer><\/script>
         `;
       },
       default: function() {
@@ -315,6 +318,18 @@ function createView(view) {
     };
   };
 }
+function contextScript(context) {
+  if (!context || Object.keys(context).length === 0)
+    return "";
+  const hydrate = (0, import_jsesc.default)(context, { isScriptContext: true, json: true });
+  return `
+          <script id="bud_context" type="text/template" defer>${hydrate}<\/script>`;
+}
+function integrity(view) {
+  if (!view.integrity)
+    return "";
+  return ` integrity="${view.integrity}" crossorigin="anonymous"`;
+}
 var defaultLayout = {
   render(props, slots) {
     return {
@@ -6,6 +6,7 @@ type View = {
   layout: any
   error?: any
   client: string
+  integrity?: string
 }
 
 // TODO:
@@ -16,19 +17,23 @@ type View = {
 export function createView(view: View) {
   view.layout = view.layout || defaultLayout
   return function ({ props, context }) {
-    const page = view.page.render(props)
+    // Components read the context with getContext(key)
+    const contextMap = new Map(Object.entries(context || {}))
+    const page = view.page.render(props, { context: contextMap })
     let css = page.css.code
     let html = page.html
     let head = page.head
     // Render the layout
     const hydrate = jsesc(props, { isScriptContext: true, json: true })
+    const hydrateContext = contextScript(context)
     const layout = view.layout.render(props, {
+      context: contextMap,
       head: function () {
         return `
           ${head}
           <style>#bud{}${css}</style>
-          <script id="bud_props" type="text/template" defer>${hydrate}</script>
-          <script type="module" src="${view.client}" defer></script>
+          <script id="bud_props" type="text/template" defer>${hydrate}</script>${hydrateContext}
+          <script type="module" src="${view.client}"${integrity(view)} defer></script>
         `
       },
       default: function () {
@@ -46,6 +51,22 @@ export function createView(view: View) {
   }
 }
 
+// contextScript passes the context to the browser, so hydrated components
+// see the same context
+function contextScript(context?: Record<string, any>): string {
+  if (!context || Object.keys(context).length === 0) return ""
+  const hydrate = jsesc(context, { isScriptContext: true, json: true })
+  return `
+          <script id="bud_context" type="text/template" defer>${hydrate}</script>`
+}
+
+// integrity attributes for the client script. Cross-origin scripts need CORS
+// for the browser to check their hash.
+function integrity(view: View): string {
+  if (!view.integrity) return ""
+  return ` integrity="${view.integrity}" crossorigin="anonymous"`
+}
+
 const defaultLayout = {
   render(props, slots) {
     return {
@@ -2,6 +2,7 @@ package view_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -34,7 +35,7 @@ func TestHello(t *testing.T) {
 	app, err := cli.Start(ctx, "run")
 	is.NoErr(err)
 	defer app.Close()
-	hot, err := app.Hot("/bud/hot/view/index.svelte")
+	hot, err := app.Hot("/bud/events?topics=frontend:update")
 	is.NoErr(err)
 	defer hot.Close()
 	res, err := app.Get("/")
@@ -57,7 +58,7 @@ func TestHello(t *testing.T) {
 	// Check that we received a hot reload event
 	event, err := hot.Next(ctx)
 	is.NoErr(err)
-	is.In(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=`)
+	is.Equal(event.Type, "frontend:update")
 	// Should change
 	res, err = app.Get("/")
 	is.NoErr(err)
@@ -78,7 +79,7 @@ func TestHello(t *testing.T) {
 	// Check that we received a hot reload event
 	event, err = hot.Next(ctx)
 	is.NoErr(err)
-	is.In(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=`)
+	is.Equal(event.Type, "frontend:update")
 	// Should change
 	res, err = app.Get("/")
 	is.NoErr(err)
@@ -110,7 +111,7 @@ func TestHelloEmbed(t *testing.T) {
 	app, err := cli.Start(ctx, "run", "--embed")
 	is.NoErr(err)
 	defer app.Close()
-	hot, err := app.Hot("/bud/hot/view/index.svelte")
+	hot, err := app.Hot("/bud/events?topics=frontend:update")
 	is.NoErr(err)
 	defer hot.Close()
 	res, err := app.Get("/")
@@ -132,7 +133,7 @@ func TestHelloEmbed(t *testing.T) {
 	// Ensure that we got a hot reload event
 	event, err := hot.Next(ctx)
 	is.NoErr(err)
-	is.In(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=`)
+	is.Equal(event.Type, "frontend:update")
 	// Shouldn't be any change
 	res, err = app.Get("/")
 	is.NoErr(err)
@@ -299,7 +300,7 @@ func TestRenameView(t *testing.T) {
 	app, err := cli.Start(ctx, "run")
 	is.NoErr(err)
 	defer app.Close()
-	hot, err := app.Hot("/bud/hot/view/show.svelte")
+	hot, err := app.Hot("/bud/events?topics=backend:update")
 	is.NoErr(err)
 	defer hot.Close()
 	res, err := app.Get("/10")
@@ -322,7 +323,7 @@ func TestRenameView(t *testing.T) {
 	// Check that we received a hot reload event
 	event, err := hot.Next(ctx)
 	is.NoErr(err)
-	is.In(string(event.Data), `{"reload":true}`)
+	is.Equal(event.Type, "backend:update")
 	// Should change
 	res, err = app.Get("/10")
 	is.NoErr(err)
@@ -351,7 +352,7 @@ func TestAddView(t *testing.T) {
 	app, err := cli.Start(ctx, "run")
 	is.NoErr(err)
 	defer app.Close()
-	hot, err := app.Hot("/bud/hot/view/show.svelte")
+	hot, err := app.Hot("/bud/events?topics=backend:update")
 	is.NoErr(err)
 	defer hot.Close()
 	res, err := app.Get("/10")
@@ -377,7 +378,7 @@ func TestAddView(t *testing.T) {
 	// Check that we received a hot reload event
 	event, err := hot.Next(ctx)
 	is.NoErr(err)
-	is.In(string(event.Data), `{"reload":true}`)
+	is.Equal(event.Type, "backend:update")
 	// Should change
 	res, err = app.Get("/10")
 	is.NoErr(err)
@@ -460,3 +461,53 @@ func TestSvelteImportFromOtherDir(t *testing.T) {
 	is.In(res.Body().String(), "<h1>The Time</h1>")
 	is.NoErr(app.Close())
 }
+
+func TestCDN(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		type Controller struct {}
+		func (c *Controller) Index() string { return "" }
+	`
+	td.Files["view/index.svelte"] = `<h1>hello</h1>`
+	td.NodeModules["svelte"] = versions.Svelte
+	td.NodeModules["livebud"] = "*"
+	is.NoErr(td.Write(ctx))
+	// Serve the client bundles from the CDN
+	pkgPath := filepath.Join(dir, "package.json")
+	data, err := os.ReadFile(pkgPath)
+	is.NoErr(err)
+	pkg := map[string]interface{}{}
+	is.NoErr(json.Unmarshal(data, &pkg))
+	pkg["bud"] = map[string]interface{}{"cdn": "https://cdn.example.com/assets"}
+	data, err = json.Marshal(pkg)
+	is.NoErr(err)
+	is.NoErr(os.WriteFile(pkgPath, data, 0644))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run", "--embed")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.Get("/")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.In(res.Body().String(), `src="https://cdn.example.com/assets/bud/view/_index.svelte.js"`)
+	is.In(res.Body().String(), `integrity="sha384-`)
+	is.In(res.Body().String(), `crossorigin="anonymous"`)
+	// The manifest has the integrity hashes for custom templates
+	res, err = app.Get("/bud/view/_manifest.json")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	manifest := map[string]struct {
+		Src       string `json:"src"`
+		Integrity string `json:"integrity"`
+	}{}
+	is.NoErr(json.Unmarshal(res.Body().Bytes(), &manifest))
+	asset, ok := manifest["bud/view/_index.svelte.js"]
+	is.True(ok)
+	is.Equal(asset.Src, "https://cdn.example.com/assets/bud/view/_index.svelte.js")
+	is.In(res.Body().String(), `"integrity": "sha384-`)
+	is.NoErr(app.Close())
+}
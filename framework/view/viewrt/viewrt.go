@@ -1,26 +1,38 @@
 package viewrt
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/livebud/bud/framework/view/ssr"
 	"github.com/livebud/bud/package/budhttp"
 	"github.com/livebud/bud/package/js"
 	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/slowlog"
+	"github.com/livebud/bud/package/metrics"
+	"github.com/livebud/bud/package/tracing"
 )
 
+// renderDuration times server-side rendering in the VM by view
+var renderDuration = metrics.NewHistogram("view_render_duration_seconds", "Time to render views on the server.", metrics.DefaultBuckets, "route")
+
+func init() {
+	metrics.Register(renderDuration)
+}
+
 type Server interface {
 	Middleware(http.Handler) http.Handler
 	Handler(route string, props interface{}) http.Handler
+	RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error)
 }
 
 func Proxy(client budhttp.Client, log log.Interface) *liveServer {
-	return &liveServer{http.FS(client), log, &renderer{client, client}}
+	return &liveServer{http.FS(client), log.Named("view"), &renderer{client, client, client}}
 }
 
 type liveServer struct {
@@ -65,13 +77,13 @@ func (s *liveServer) Middleware(next http.Handler) http.Handler {
 
 func (s *liveServer) Handler(route string, props interface{}) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s.respond(w, route, props)
+		s.respond(r.Context(), w, route, props)
 	})
 }
 
 // Respond is a convenience function for render
-func (s *liveServer) respond(w http.ResponseWriter, path string, props interface{}) {
-	res, err := s.render(path, props)
+func (s *liveServer) respond(ctx context.Context, w http.ResponseWriter, path string, props interface{}) {
+	res, err := s.renderer.Render(ctx, path, props)
 	if err != nil {
 		s.log.Error("view: render error", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -85,13 +97,14 @@ func (s *liveServer) respond(w http.ResponseWriter, path string, props interface
 	w.Write([]byte(res.Body))
 }
 
-func (s *liveServer) render(route string, props interface{}) (*ssr.Response, error) {
-	return s.renderer.Render(route, props)
+// RenderBatch renders multiple routes at once
+func (s *liveServer) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	return s.renderer.RenderBatch(requests...)
 }
 
 // Static server serves the same files every time. Used during production.
 func Static(fsys fs.FS, log log.Interface, vm js.VM, wrapProps func(path string, props interface{}) interface{}) *staticServer {
-	return &staticServer{http.FS(fsys), log, &renderer{fsys, vm}}
+	return &staticServer{http.FS(fsys), log.Named("view"), &renderer{fsys, vm, nil}}
 }
 
 type staticServer struct {
@@ -107,8 +120,8 @@ var _ Server = (*staticServer)(nil)
 type Map map[string]interface{}
 
 // Respond is a convenience function for render
-func (s *staticServer) respond(w http.ResponseWriter, path string, props interface{}) {
-	res, err := s.render(path, props)
+func (s *staticServer) respond(ctx context.Context, w http.ResponseWriter, path string, props interface{}) {
+	res, err := s.renderer.Render(ctx, path, props)
 	if err != nil {
 		s.log.Error("view: client open error", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -122,8 +135,30 @@ func (s *staticServer) respond(w http.ResponseWriter, path string, props interfa
 	w.Write([]byte(res.Body))
 }
 
-func (s *staticServer) render(path string, props interface{}) (*ssr.Response, error) {
-	return s.renderer.Render(path, props)
+// RenderBatch renders multiple routes at once
+func (s *staticServer) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	return s.renderer.RenderBatch(requests...)
+}
+
+type contextKey struct{}
+
+// WithContext adds a value to the context that views render with. Svelte
+// components read it with getContext(key), both on the server and in the
+// browser, so the value must marshal to JSON.
+func WithContext(ctx context.Context, key string, value interface{}) context.Context {
+	values := map[string]interface{}{key: value}
+	for k, v := range contextFrom(ctx) {
+		if k != key {
+			values[k] = v
+		}
+	}
+	return context.WithValue(ctx, contextKey{}, values)
+}
+
+// contextFrom returns the values that views render with
+func contextFrom(ctx context.Context) map[string]interface{} {
+	values, _ := ctx.Value(contextKey{}).(map[string]interface{})
+	return values
 }
 
 func isClient(path string) bool {
@@ -144,7 +179,7 @@ func (s *staticServer) Middleware(next http.Handler) http.Handler {
 // Handler returns a handler for a specific server-side route
 func (s *staticServer) Handler(route string, props interface{}) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		s.respond(w, route, props)
+		s.respond(r.Context(), w, route, props)
 	})
 }
 
@@ -169,32 +204,89 @@ func (s *staticServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
 }
 
 type renderer struct {
-	fsys fs.FS
-	vm   js.VM
+	fsys    fs.FS
+	vm      js.VM
+	batcher batcher // Optional
+}
+
+// batcher renders multiple requests remotely in a single round-trip
+type batcher interface {
+	RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error)
 }
 
-func (r *renderer) Render(route string, props interface{}) (*ssr.Response, error) {
-	propBytes, err := json.Marshal(props)
+// Render the route within a span of the request's trace. The page is rendered
+// within its layout and frames by a single request to the VM.
+func (r *renderer) Render(ctx context.Context, route string, props interface{}) (res *ssr.Response, err error) {
+	ctx, span := tracing.Start(ctx, "view.render")
+	defer span.End()
+	span.SetAttribute("view.route", route)
+	defer func() { span.RecordError(err) }()
+	var stack []js.Frame
+	defer func(start time.Time) {
+		elapsed := time.Since(start)
+		renderDuration.Observe(elapsed.Seconds(), route)
+		slowlog.Render(ctx, route, elapsed, stack)
+	}(time.Now())
+	responses, stack, err := r.render(ctx, []*ssr.Request{{
+		Route:   route,
+		Props:   props,
+		Context: contextFrom(ctx),
+	}})
 	if err != nil {
 		return nil, err
 	}
+	return responses[0], nil
+}
+
+// RenderBatch renders multiple routes in a single request to the VM
+func (r *renderer) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	responses, _, err := r.render(context.Background(), requests)
+	return responses, err
+}
+
+// render the requests remotely when there's a batcher, otherwise evaluate them
+// in the VM
+func (r *renderer) render(ctx context.Context, requests []*ssr.Request) (responses []*ssr.Response, stack []js.Frame, err error) {
+	if r.batcher != nil {
+		responses, err = r.batcher.RenderBatch(requests...)
+	} else {
+		responses, stack, err = r.evalBatch(ctx, requests)
+	}
+	if err != nil {
+		return nil, stack, err
+	}
+	if len(responses) != len(requests) {
+		return nil, stack, fmt.Errorf("view: expected %d responses but got %d", len(requests), len(responses))
+	}
+	for _, res := range responses {
+		if res.Status < 100 || res.Status > 999 {
+			return nil, stack, fmt.Errorf("view: invalid status code %d", res.Status)
+		}
+	}
+	return responses, stack, nil
+}
+
+func (r *renderer) evalBatch(ctx context.Context, requests []*ssr.Request) (responses []*ssr.Response, stack []js.Frame, err error) {
 	script, err := fs.ReadFile(r.fsys, "bud/view/_ssr.js")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	// Evaluate the server
-	expr := fmt.Sprintf(`%s; bud.render(%q, %s)`, script, route, propBytes)
-	result, err := r.vm.Eval("_ssr.js", expr)
+	expr, err := ssr.BatchExpr(string(script), requests)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	// Unmarshal the response
-	res := new(ssr.Response)
-	if err := json.Unmarshal([]byte(result), res); err != nil {
-		return nil, err
+	var result string
+	if slowlog.Stack(ctx) {
+		result, stack, err = js.EvalStack(ctx, r.vm, "_ssr.js", expr)
+	} else {
+		result, err = js.Eval(ctx, r.vm, "_ssr.js", expr)
 	}
-	if res.Status < 100 || res.Status > 999 {
-		return nil, fmt.Errorf("view: invalid status code %d", res.Status)
+	if err != nil {
+		return nil, stack, err
+	}
+	responses, err = ssr.UnmarshalBatch([]byte(result))
+	if err != nil {
+		return nil, stack, err
 	}
-	return res, nil
+	return responses, stack, nil
 }
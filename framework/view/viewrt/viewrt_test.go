@@ -0,0 +1,57 @@
+package viewrt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/framework/view/viewrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/budhttp/budhttptest"
+	"github.com/livebud/bud/package/log/testlog"
+)
+
+type batchClient struct {
+	*budhttptest.Client
+	batches int
+}
+
+func (c *batchClient) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	c.batches++
+	return c.Client.RenderBatch(requests...)
+}
+
+func TestRenderProxy(t *testing.T) {
+	is := is.New(t)
+	client := &batchClient{Client: budhttptest.New()}
+	client.View("/users/:id", &ssr.Response{
+		Headers: map[string]string{"Content-Type": "text/html"},
+		Body:    "<h1>10</h1>",
+	})
+	server := viewrt.Proxy(client, testlog.New())
+	handler := server.Handler("/users/:id", map[string]int{"id": 10})
+	req := httptest.NewRequest(http.MethodGet, "/users/10", nil)
+	req = req.WithContext(viewrt.WithContext(req.Context(), "theme", "dark"))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "text/html")
+	is.Equal(rec.Body.String(), "<h1>10</h1>")
+	// The page is rendered within its layout in one request to the dev server
+	is.Equal(client.batches, 1)
+	renders := client.Renders()
+	is.Equal(len(renders), 1)
+	is.Equal(renders[0].Route, "/users/:id")
+	is.Equal(renders[0].Context["theme"], "dark")
+}
+
+func TestRenderBatchStatus(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New()
+	client.View("/", &ssr.Response{Status: 1000})
+	server := viewrt.Proxy(client, testlog.New())
+	_, err := server.RenderBatch(&ssr.Request{Route: "/"})
+	is.True(err != nil)
+	is.Equal(err.Error(), "view: invalid status code 1000")
+}
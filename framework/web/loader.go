@@ -1,16 +1,24 @@
 package web
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/framework/job"
+	"github.com/livebud/bud/framework/public/publicrt"
+	"github.com/livebud/bud/internal/orderedset"
 	"github.com/livebud/bud/internal/scan"
 	"github.com/livebud/bud/internal/valid"
 
 	"github.com/livebud/bud/internal/bail"
 	"github.com/livebud/bud/internal/imports"
+	"github.com/livebud/bud/package/authz"
 	"github.com/livebud/bud/package/finder"
 	"github.com/livebud/bud/package/gomod"
 	"github.com/livebud/bud/package/parser"
@@ -19,22 +27,34 @@ import (
 	"github.com/matthewmueller/text"
 )
 
-func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser) (*State, error) {
+func Load(fsys fs.FS, module *gomod.Module, parser *parser.Parser, flag *framework.Flag) (*State, error) {
 	loader := &loader{
 		imports: imports.New(),
 		fsys:    fsys,
 		module:  module,
 		parser:  parser,
+		flag:    flag,
 	}
 	return loader.Load()
 }
 
 type loader struct {
 	bail.Struct
-	imports *imports.Set
-	fsys    fs.FS
-	module  *gomod.Module
-	parser  *parser.Parser
+	imports         *imports.Set
+	fsys            fs.FS
+	module          *gomod.Module
+	parser          *parser.Parser
+	flag            *framework.Flag
+	usesDB          bool
+	usesSession     bool
+	usesJWT         bool
+	usesTenant      bool
+	usesSigned      bool
+	usesIdempotency bool
+	usesStorage     bool
+	usesFeatures    bool
+	usesWebhooks    bool
+	sitemap         []string // Static routes for the sitemap
 }
 
 // Load the command state
@@ -65,6 +85,36 @@ func (l *loader) Load() (state *State, err error) {
 	l.imports.AddNamed("middleware", "github.com/livebud/bud/package/middleware")
 	l.imports.AddNamed("webrt", "github.com/livebud/bud/framework/web/webrt")
 	l.imports.AddNamed("router", "github.com/livebud/bud/package/router")
+	l.imports.AddNamed("reqlog", "github.com/livebud/bud/package/log/reqlog")
+	l.imports.AddNamed("accesslog", "github.com/livebud/bud/package/log/accesslog")
+	l.imports.AddNamed("tracing", "github.com/livebud/bud/package/tracing")
+	l.imports.AddNamed("metrics", "github.com/livebud/bud/package/metrics")
+	l.imports.AddNamed("slowlog", "github.com/livebud/bud/package/log/slowlog")
+	l.imports.AddNamed("routestats", "github.com/livebud/bud/package/routestats")
+	l.imports.AddNamed("crash", "github.com/livebud/bud/package/crash")
+	l.imports.AddNamed("audit", "github.com/livebud/bud/package/audit")
+	// Run the jobs in job/, the tasks in schedule/ and the subscribers in
+	// event/ alongside the web server
+	background, err := vfs.SomeExist(l.fsys,
+		"bud/package/jobs/jobs.go",
+		"bud/internal/scheduler/scheduler.go",
+		"bud/package/events/events.go",
+	)
+	if err != nil {
+		return nil, err
+	}
+	if background["bud/package/jobs/jobs.go"] {
+		state.HasJobs = true
+		l.imports.AddNamed("jobs", l.module.Import("bud/package/jobs"))
+	}
+	if background["bud/internal/scheduler/scheduler.go"] {
+		state.HasScheduler = true
+		l.imports.AddNamed("scheduler", l.module.Import("bud/internal/scheduler"))
+	}
+	if background["bud/package/events/events.go"] {
+		state.HasEvents = true
+		l.imports.AddNamed("events", l.module.Import("bud/package/events"))
+	}
 	// Show the welcome page if we don't have controllers, views or public files
 	if len(exist) == 0 {
 		l.imports.AddNamed("welcome", "github.com/livebud/bud/framework/web/welcome")
@@ -79,6 +129,20 @@ func (l *loader) Load() (state *State, err error) {
 	if exist["bud/internal/web/view/view.go"] {
 		state.HasView = true
 		l.imports.AddNamed("view", l.module.Import("bud/internal/web/view"))
+		// Preview the emails in mail/ during development
+		if !l.flag.Embed {
+			if err := l.loadMailPreview(state); err != nil {
+				return nil, err
+			}
+		}
+		// Show the jobs at /bud/jobs when there's a view for the dashboard
+		if state.HasJobs {
+			hasDashboard, err := job.HasDashboard(l.fsys)
+			if err != nil {
+				return nil, err
+			}
+			state.HasJobDashboard = hasDashboard
+		}
 	}
 	// Load the controllers
 	if exist["bud/internal/web/controller/controller.go"] {
@@ -86,6 +150,67 @@ func (l *loader) Load() (state *State, err error) {
 		if len(state.Actions) > 0 {
 			l.imports.AddNamed("controller", l.module.Import("bud/internal/web/controller"))
 		}
+		state.Sitemap = l.loadSitemap()
+	}
+	// Load the app's middleware
+	state.Middleware = l.loadMiddleware()
+	// Serve the development dashboard at /bud
+	if !l.flag.Embed {
+		state.HasDashboard = true
+		l.imports.AddNamed("budhttp", "github.com/livebud/bud/package/budhttp")
+		// Forward unmatched requests to the frontend dev server in $BUD_PROXY
+		state.HasProxy = true
+		l.imports.AddStd("os")
+	}
+	// Load sessions before each request when controllers use them
+	if l.usesSession {
+		state.HasSession = true
+		l.imports.AddNamed("session", "github.com/livebud/bud/package/session")
+	}
+	// Require bearer tokens for API requests when controllers use them
+	if l.usesJWT {
+		state.HasJWT = true
+		l.imports.AddNamed("jwt", "github.com/livebud/bud/package/jwt")
+	}
+	// Verify signed links when controllers use them
+	if l.usesSigned {
+		state.HasSignedURL = true
+		l.imports.AddNamed("signedurl", "github.com/livebud/bud/package/signedurl")
+	}
+	// Replay responses to retried requests when controllers use idempotency keys
+	if l.usesIdempotency {
+		state.HasIdempotency = true
+		l.imports.AddNamed("idempotency", "github.com/livebud/bud/package/idempotency")
+	}
+	// Serve the links to uploaded files when controllers store them
+	if l.usesStorage {
+		state.HasStorage = true
+		l.imports.AddNamed("storage", "github.com/livebud/bud/package/storage")
+	}
+	// Pass the feature flags to the views when controllers use them
+	if l.usesFeatures {
+		state.HasFeatures = true
+		l.imports.AddNamed("feature", "github.com/livebud/bud/package/feature")
+	}
+	// Deliver webhooks in the background when controllers or jobs send them
+	if l.usesWebhooks || (state.HasJobs && l.jobsHaveField("github.com/livebud/bud/package/webhook", "Webhooks")) {
+		state.HasWebhooks = true
+		l.imports.AddNamed("webhook", "github.com/livebud/bud/package/webhook")
+	}
+	// Verify the webhooks sent to controller/webhook
+	if hasWebhookRoutes(state.Actions) {
+		state.HasWebhookReceiver = true
+		l.imports.AddNamed("webhook", "github.com/livebud/bud/package/webhook")
+	}
+	// Resolve the tenant when controllers use it or the database is scoped by it
+	if l.usesTenant || (l.usesDB && l.modelsHaveTenant()) {
+		state.HasTenant = true
+		l.imports.AddNamed("tenant", "github.com/livebud/bud/package/tenant")
+	}
+	// Wrap mutating requests in a transaction when controllers use the database
+	if l.usesDB {
+		state.HasDB = true
+		l.imports.AddNamed("db", l.module.Import("bud/package/db"))
 	}
 	// state.Command = l.loadRoot("command")
 	// Load the imports
@@ -93,6 +218,109 @@ func (l *loader) Load() (state *State, err error) {
 	return state, nil
 }
 
+// loadMailPreview serves previews of the emails at /bud/mail
+func (l *loader) loadMailPreview(state *State) error {
+	if _, err := fs.Stat(l.fsys, "bud/package/mailer/mailer.go"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	state.HasMailPreview = true
+	l.imports.AddNamed("mailer", l.module.Import("bud/package/mailer"))
+	return nil
+}
+
+// loadMiddleware loads middleware/, which wraps every request that reaches
+// the router
+func (l *loader) loadMiddleware() *imports.Import {
+	if _, err := fs.Stat(l.fsys, "middleware"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		l.Bail(err)
+	}
+	pkg, err := l.parser.Parse("middleware")
+	if err != nil {
+		l.Bail(err)
+	}
+	stct := pkg.Struct("Middleware")
+	if stct == nil {
+		return nil
+	}
+	if stct.Method("Middleware") == nil {
+		l.Bail(fmt.Errorf("web: expected middleware.Middleware to have a Middleware(next http.Handler) http.Handler method"))
+	}
+	if l.importsPath(pkg, l.module.Import("bud/package/db")) {
+		l.usesDB = true
+	}
+	if l.importsSessions(pkg) {
+		l.usesSession = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/jwt") {
+		l.usesJWT = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/tenant") {
+		l.usesTenant = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/signedurl") {
+		l.usesSigned = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/idempotency") {
+		l.usesIdempotency = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/storage") {
+		l.usesStorage = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/feature") {
+		l.usesFeatures = true
+	}
+	if l.hasField(pkg, "github.com/livebud/bud/package/webhook", "Webhooks") {
+		l.usesWebhooks = true
+	}
+	importPath := l.module.Import("middleware")
+	return &imports.Import{
+		Name: l.imports.AddNamed("appmiddleware", importPath),
+		Path: importPath,
+	}
+}
+
+// hasWebhookRoutes checks if any actions are routed to controller/webhook
+func hasWebhookRoutes(actions []*Action) bool {
+	for _, action := range actions {
+		if action.Route == "/webhook" || strings.HasPrefix(action.Route, "/webhook/") {
+			return true
+		}
+	}
+	return false
+}
+
+// jobsHaveField checks if the jobs in job/ depend on the type
+func (l *loader) jobsHaveField(importPath, name string) bool {
+	pkg, err := l.parser.Parse("job")
+	if err != nil {
+		l.Bail(err)
+	}
+	return l.hasField(pkg, importPath, name)
+}
+
+// hasField checks if a struct in the package has a field of the type, like
+// a controller that depends on *webhook.Webhooks
+func (l *loader) hasField(pkg *parser.Package, importPath, name string) bool {
+	for _, stct := range pkg.Structs() {
+		for _, field := range stct.Fields() {
+			ok, err := parser.IsImportType(field.Type(), importPath, name)
+			if err != nil {
+				l.Bail(err)
+			}
+			if ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (l *loader) loadResource(webDir string) (resource *Resource) {
 	resource = new(Resource)
 	importPath := l.module.Import(webDir)
@@ -129,6 +357,33 @@ func (l *loader) loadActions(dir string) (actions []*Action) {
 	if stct == nil {
 		return nil
 	}
+	if l.importsPath(pkg, l.module.Import("bud/package/db")) {
+		l.usesDB = true
+	}
+	if l.importsSessions(pkg) {
+		l.usesSession = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/jwt") {
+		l.usesJWT = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/tenant") {
+		l.usesTenant = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/signedurl") {
+		l.usesSigned = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/idempotency") {
+		l.usesIdempotency = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/storage") {
+		l.usesStorage = true
+	}
+	if l.importsPath(pkg, "github.com/livebud/bud/package/feature") {
+		l.usesFeatures = true
+	}
+	if l.hasField(pkg, "github.com/livebud/bud/package/webhook", "Webhooks") {
+		l.usesWebhooks = true
+	}
 	basePath := toBasePath(dir)
 	for _, method := range stct.PublicMethods() {
 		action := new(Action)
@@ -137,10 +392,109 @@ func (l *loader) loadActions(dir string) (actions []*Action) {
 		action.Route = l.loadActionRoute(l.loadControllerRoute(basePath), actionName)
 		action.CallName = l.loadActionCallName(basePath, actionName)
 		actions = append(actions, action)
+		if inSitemap(action, stct.Directives(), method.Directives()) {
+			l.sitemap = append(l.sitemap, action.Route)
+		}
 	}
 	return actions
 }
 
+// importsSessions checks if the package uses sessions, either directly or
+// through the auth or providers packages
+func (l *loader) importsSessions(pkg *parser.Package) bool {
+	return l.importsPath(pkg, "github.com/livebud/bud/package/session") ||
+		l.importsPath(pkg, "github.com/livebud/bud/package/auth") ||
+		l.importsPath(pkg, "github.com/livebud/bud/package/providers")
+}
+
+// modelsHaveTenant checks if a model has a TenantID field, which scopes the
+// generated database queries to the request's tenant
+func (l *loader) modelsHaveTenant() bool {
+	if _, err := fs.Stat(l.fsys, "model"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false
+		}
+		l.Bail(err)
+	}
+	pkg, err := l.parser.Parse("model")
+	if err != nil {
+		l.Bail(err)
+	}
+	for _, stct := range pkg.Structs() {
+		if stct.Private() {
+			continue
+		}
+		for _, field := range stct.PublicFields() {
+			if field.Name() == "TenantID" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// importsPath checks if the controller package imports a package
+func (l *loader) importsPath(pkg *parser.Package, importPath string) bool {
+	for _, file := range pkg.Files() {
+		imports, err := file.Imports()
+		if err != nil {
+			l.Bail(err)
+		}
+		for _, path := range imports {
+			if path == importPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inSitemap is true for static GET routes that anyone can see, unless the
+// controller or action opts out with //sitemap:exclude
+func inSitemap(action *Action, directives ...[]string) bool {
+	if action.Method != "Get" || strings.ContainsAny(action.Route, ":*") {
+		return false
+	}
+	for _, list := range directives {
+		for _, directive := range list {
+			if directive == publicrt.ExcludeDirective {
+				return false
+			}
+			if _, ok := authz.Parse(directive); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// loadSitemap serves the sitemap and robots.txt, unless they're in public/
+func (l *loader) loadSitemap() *Sitemap {
+	if len(l.sitemap) == 0 {
+		return nil
+	}
+	exist, err := vfs.SomeExist(l.fsys, "public/sitemap.xml", "public/robots.txt")
+	if err != nil {
+		l.Bail(err)
+	}
+	sitemap := &Sitemap{
+		Routes: orderedset.Strings(l.sitemap...),
+		XML:    !exist["public/sitemap.xml"],
+		Robots: !exist["public/robots.txt"],
+	}
+	if !sitemap.XML && !sitemap.Robots {
+		return nil
+	}
+	l.imports.AddNamed("publicrt", "github.com/livebud/bud/framework/public/publicrt")
+	l.imports.AddStd("time")
+	// Pages are last modified when they're built. During development, they're
+	// last modified when the app starts.
+	if l.flag.Embed {
+		sitemap.BuildTime = time.Now().Unix()
+	}
+	return sitemap
+}
+
 func toBasePath(dir string) string {
 	if dir == "." {
 		return "/"
@@ -197,8 +551,9 @@ func (l *loader) loadActionCallName(basePath, actionName string) string {
 	splitPath := strings.Split(text.Title(basePath), " ")
 	for i, controller := range splitPath {
 		// append controller to every controller base name
+		// match the casing of the generated controller fields (e.g. APIController)
 		if len(controller) > 0 {
-			splitPath[i] = controller + "Controller"
+			splitPath[i] = gotext.Pascal(controller) + "Controller"
 		}
 	}
 	baseCaller := strings.Join(splitPath, ".")
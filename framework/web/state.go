@@ -7,8 +7,38 @@ type State struct {
 	Resources []*Resource
 
 	// TODO: remove below
-	Actions     []*Action
-	HasView     bool
+	Actions      []*Action
+	HasView      bool
+	HasDB        bool
+	HasSession   bool
+	HasJWT       bool
+	HasTenant    bool
+	HasSignedURL bool
+	// HasIdempotency is true when controllers use idempotency keys
+	HasIdempotency bool
+	// HasStorage is true when controllers store uploaded files
+	HasStorage bool
+	// HasFeatures is true when controllers use feature flags
+	HasFeatures  bool
+	HasJobs      bool
+	HasScheduler bool
+	HasEvents    bool
+	// HasWebhooks is true when controllers or jobs send webhooks
+	HasWebhooks bool
+	// HasWebhookReceiver is true when there are controllers in
+	// controller/webhook
+	HasWebhookReceiver bool
+	// Preview the emails in mail/ during development
+	HasMailPreview bool
+	// HasDashboard serves the development dashboard at /bud
+	HasDashboard bool
+	// HasProxy forwards unmatched requests to a frontend dev server
+	HasProxy bool
+	// HasJobDashboard is true when the app has a view for the job dashboard
+	HasJobDashboard bool
+	// Sitemap of the static pages, served at /sitemap.xml and /robots.txt
+	Sitemap     *Sitemap
+	Middleware  *imports.Import
 	ShowWelcome bool
 }
 
@@ -19,6 +49,14 @@ type Resource struct {
 	Camel  string
 }
 
+// Sitemap lists the static GET routes for search engines
+type Sitemap struct {
+	Routes    []string
+	BuildTime int64 // Unix time of the build. Zero during development.
+	XML       bool  // False when public/sitemap.xml exists
+	Robots    bool  // False when public/robots.txt exists
+}
+
 // TODO: remove action
 type Action struct {
 	Method   string
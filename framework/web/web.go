@@ -3,6 +3,7 @@ package web
 import (
 	_ "embed"
 
+	"github.com/livebud/bud/framework"
 	"github.com/livebud/bud/internal/gotemplate"
 	"github.com/livebud/bud/package/budfs"
 	"github.com/livebud/bud/package/gomod"
@@ -19,17 +20,18 @@ func Generate(state *State) ([]byte, error) {
 	return generator.Generate(state)
 }
 
-func New(module *gomod.Module, parser *parser.Parser) *Generator {
-	return &Generator{module, parser}
+func New(module *gomod.Module, parser *parser.Parser, flag *framework.Flag) *Generator {
+	return &Generator{module, parser, flag}
 }
 
 type Generator struct {
 	module *gomod.Module
 	parser *parser.Parser
+	flag   *framework.Flag
 }
 
 func (g *Generator) GenerateFile(fsys budfs.FS, file *budfs.File) error {
-	state, err := Load(fsys, g.module, g.parser)
+	state, err := Load(fsys, g.module, g.parser, g.flag)
 	if err != nil {
 		return err
 	}
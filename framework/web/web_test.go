@@ -2,11 +2,15 @@ package web_test
 
 import (
 	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/livebud/bud/internal/cli/testcli"
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/package/jwt"
 )
 
 func TestEmptyBuild(t *testing.T) {
@@ -24,3 +28,179 @@ func TestEmptyBuild(t *testing.T) {
 	// Empty builds generate the web directory
 	is.NoErr(td.Exists("bud/internal/web"))
 }
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		type Controller struct {}
+		func (c *Controller) Index() string { return "hello" }
+	`
+	td.Files["middleware/middleware.go"] = `
+		package middleware
+		import "net/http"
+		type Middleware struct {}
+		func (m *Middleware) Middleware(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Middleware", "true")
+				next.ServeHTTP(w, r)
+			})
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/")
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+		X-Middleware: true
+
+		"hello"
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestJWT(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/api/controller.go"] = `
+		package api
+		import (
+			"context"
+			"github.com/livebud/bud/package/jwt"
+		)
+		type Controller struct {}
+		func (c *Controller) Index(ctx context.Context) string {
+			return jwt.From(ctx).Subject()
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["JWT_SECRET"] = "secret"
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.GetJSON("/api")
+	is.NoErr(err)
+	is.Equal(res.Status(), 401)
+	config, err := jwt.LoadConfig(func(key string) string { return cli.Env[key] })
+	is.NoErr(err)
+	token, err := jwt.New(config).Issue(jwt.Claims{"sub": "42"})
+	is.NoErr(err)
+	req, err := app.GetRequest("/api")
+	is.NoErr(err)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res, err = app.Do(req)
+	is.NoErr(err)
+	is.NoErr(res.Diff(`
+		HTTP/1.1 200 OK
+		Content-Type: application/json
+
+		"42"
+	`))
+	is.NoErr(app.Close())
+}
+
+func TestSitemap(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		type Controller struct {}
+		func (c *Controller) Index() string { return "home" }
+		func (c *Controller) Show(id int) int { return id }
+		//sitemap:exclude
+		func (c *Controller) Edit() string { return "edit" }
+	`
+	td.Files["controller/about/controller.go"] = `
+		package about
+		type Controller struct {}
+		func (c *Controller) Index() string { return "about" }
+		func (c *Controller) Create() string { return "created" }
+	`
+	td.Files["controller/drafts/controller.go"] = `
+		package drafts
+		//sitemap:exclude
+		type Controller struct {}
+		func (c *Controller) Index() string { return "drafts" }
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.Get("/sitemap.xml")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.Equal(res.Header("Content-Type"), "application/xml; charset=utf-8")
+	sitemap := res.Body().String()
+	is.In(sitemap, `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	is.In(sitemap, `/about</loc>`)
+	is.True(!strings.Contains(sitemap, "/edit"))
+	is.True(!strings.Contains(sitemap, "/drafts"))
+	is.True(!strings.Contains(sitemap, ":id"))
+	res, err = app.Get("/robots.txt")
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.In(res.Body().String(), "/sitemap.xml\n")
+	is.NoErr(app.Close())
+}
+
+func TestStorage(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["controller/controller.go"] = `
+		package controller
+		import (
+			"context"
+			"strings"
+			"time"
+			"github.com/livebud/bud/package/storage"
+		)
+		type Controller struct {
+			Storage storage.Storage
+		}
+		func (c *Controller) Create(ctx context.Context, note string) (string, error) {
+			if err := c.Storage.Save(ctx, "notes/hello.txt", strings.NewReader(note)); err != nil {
+				return "", err
+			}
+			return c.Storage.URL(ctx, "notes/hello.txt", time.Hour)
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["MASTER_KEY"] = "secret"
+	cli.Env["STORAGE_DIR"] = filepath.Join(dir, "uploads")
+	app, err := cli.Start(ctx, "run")
+	is.NoErr(err)
+	defer app.Close()
+	res, err := app.PostJSON("/", strings.NewReader(`{"note":"hello world"}`))
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	var link string
+	is.NoErr(json.Unmarshal(res.Body().Bytes(), &link))
+	is.True(strings.HasPrefix(link, "/bud/storage/notes/hello.txt?"))
+	res, err = app.Get(link)
+	is.NoErr(err)
+	is.Equal(res.Status(), 200)
+	is.Equal(res.Body().String(), "hello world")
+	// Links can't be changed
+	res, err = app.Get(strings.Replace(link, "hello.txt", "other.txt", 1))
+	is.NoErr(err)
+	is.Equal(res.Status(), 403)
+	is.NoErr(app.Close())
+}
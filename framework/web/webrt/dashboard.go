@@ -0,0 +1,154 @@
+package webrt
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/livebud/bud/package/budhttp"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/routestats"
+)
+
+// NewDashboard serves the development dashboard at /bud. The routes come from
+// the router and the build stats come from the dev server's event bus.
+func NewDashboard(client budhttp.Client, router *router.Router) *Dashboard {
+	return &Dashboard{client: client, router: router}
+}
+
+// Dashboard lists the app's routes and middleware alongside the latest builds,
+// generator timings, watched files and errors
+type Dashboard struct {
+	client budhttp.Client
+	router *router.Router
+	// Stack of middleware that wraps the app, shown in order
+	Stack middleware.Stack
+	// RouteStats shows the latency and errors of each route. Optional.
+	RouteStats *routestats.Tracker
+}
+
+var _ middleware.Middleware = (*Dashboard)(nil)
+
+// Middleware serves the dashboard, passing other requests through
+func (d *Dashboard) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || (r.URL.Path != "/bud" && r.URL.Path != "/bud/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d.serve(w)
+	})
+}
+
+type dashboardPage struct {
+	Routes     []*dashboardRoute
+	Middleware []string
+	Stats      *budhttp.Stats
+	Error      string // Unable to get the stats
+}
+
+// dashboardRoute is a route with its recent stats, if it's had requests
+type dashboardRoute struct {
+	*router.Route
+	Stats *routestats.Stats
+}
+
+func (d *Dashboard) serve(w http.ResponseWriter) {
+	page := &dashboardPage{
+		Stats: new(budhttp.Stats),
+	}
+	stats := map[string]*routestats.Stats{}
+	if d.RouteStats != nil {
+		for _, s := range d.RouteStats.Stats() {
+			stats[s.Method+" "+s.Route] = s
+		}
+	}
+	for _, route := range d.router.Routes() {
+		page.Routes = append(page.Routes, &dashboardRoute{route, stats[route.Method+" "+route.Path]})
+	}
+	for _, m := range d.Stack {
+		if m == nil {
+			continue
+		}
+		page.Middleware = append(page.Middleware, fmt.Sprintf("%T", m))
+	}
+	if client, ok := d.client.(budhttp.StatsClient); ok {
+		stats, err := client.Stats()
+		if err != nil {
+			page.Error = err.Error()
+		} else {
+			page.Stats = stats
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Funcs(template.FuncMap{
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Millisecond).String()
+	},
+	"clock": func(t time.Time) string {
+		return t.Format("15:04:05")
+	},
+	"percent": func(ratio float64) string {
+		return fmt.Sprintf("%.1f%%", ratio*100)
+	},
+}).Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dashboard</title>
+<style>body{font-family:system-ui,sans-serif;margin:2rem}td{padding:.25rem 1rem .25rem 0;vertical-align:top}pre{margin:0;white-space:pre-wrap}.error{color:#c00}</style>
+</head>
+<body>
+<h1>Dashboard</h1>
+{{- if $.Error }}
+<p class="error">{{ $.Error }}</p>
+{{- end }}
+<h2>Routes</h2>
+<table>
+{{- range $.Routes }}
+<tr><td>{{ .Method }}</td><td>{{ .Path }}</td>
+{{- with .Stats -}}
+<td>{{ .Requests }} requests</td><td>p50 {{ duration .P50 }}</td><td>p95 {{ duration .P95 }}</td><td>p99 {{ duration .P99 }}</td><td>{{ percent .ErrorRatio }} errors</td>
+{{- with .Over }}<td class="error">Over budget: {{ range $i, $reason := . }}{{ if $i }}, {{ end }}{{ $reason }}{{ end }}</td>{{ end -}}
+{{- end -}}
+</tr>
+{{- end }}
+</table>
+<h2>Middleware</h2>
+<ol>
+{{- range $.Middleware }}
+<li>{{ . }}</li>
+{{- end }}
+</ol>
+<h2>Builds</h2>
+<p>Watching {{ $.Stats.Watching }} files</p>
+<table>
+{{- range $.Stats.Builds }}
+<tr><td>{{ clock .Start }}</td><td>{{ if .Duration }}{{ duration .Duration }}{{ else }}building{{ end }}</td><td class="error">{{ .Error }}</td></tr>
+{{- end }}
+</table>
+<h2>Generators</h2>
+<table>
+{{- range $.Stats.Generators }}
+<tr><td>{{ .Path }}</td><td>{{ duration .Duration }}</td></tr>
+{{- end }}
+</table>
+<h2>Errors</h2>
+<table>
+{{- range $.Stats.Errors }}
+<tr><td>{{ clock .Time }}</td><td>{{ .Topic }}</td><td><pre>{{ .Message }}</pre></td></tr>
+{{- else }}
+<tr><td>No errors</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,62 @@
+package webrt_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/web/webrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/budhttp"
+	"github.com/livebud/bud/package/budhttp/budhttptest"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/routestats"
+)
+
+type statsClient struct {
+	*budhttptest.Client
+	stats *budhttp.Stats
+}
+
+func (c *statsClient) Stats() (*budhttp.Stats, error) {
+	return c.stats, nil
+}
+
+func TestDashboard(t *testing.T) {
+	is := is.New(t)
+	rt := router.New()
+	rt.Get("/users/:id", http.NotFoundHandler())
+	rt.Post("/users", http.NotFoundHandler())
+	client := &statsClient{budhttptest.New(), &budhttp.Stats{
+		Builds:     []*budhttp.BuildStat{{Start: time.Now(), Duration: 1500 * time.Millisecond}},
+		Generators: []*budhttp.GeneratorStat{{Path: "bud/internal/app/main.go", Duration: 20 * time.Millisecond}},
+		Watching:   42,
+		Errors:     []*budhttp.ErrorStat{{Time: time.Now(), Topic: "build:error", Message: "undefined: <hello>"}},
+	}}
+	dashboard := webrt.NewDashboard(client, rt)
+	dashboard.RouteStats = routestats.New()
+	dashboard.RouteStats.SetBudget("/users/:id", routestats.Budget{P50: time.Nanosecond, MinRequests: 1})
+	dashboard.Stack = middleware.Stack{middleware.MethodOverride(), dashboard.RouteStats, dashboard, rt}
+	handler := dashboard.Stack.Middleware(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/10", nil))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud", nil))
+	is.Equal(rec.Code, http.StatusOK)
+	body := rec.Body.String()
+	is.True(strings.Contains(body, "<tr><td>POST</td><td>/users</td></tr>"))
+	is.True(strings.Contains(body, "<tr><td>GET</td><td>/users/:id</td><td>1 requests</td>"))
+	is.True(strings.Contains(body, "<td>0.0% errors</td><td class=\"error\">Over budget: p50 500µs &gt; 1ns</td>"))
+	is.True(strings.Contains(body, "<li>*webrt.Dashboard</li>"))
+	is.True(strings.Contains(body, "<li>*router.Router</li>"))
+	is.True(strings.Contains(body, "Watching 42 files"))
+	is.True(strings.Contains(body, "<td>1.5s</td>"))
+	is.True(strings.Contains(body, "<tr><td>bud/internal/app/main.go</td><td>20ms</td></tr>"))
+	is.True(strings.Contains(body, "undefined: &lt;hello&gt;"))
+	// Other requests pass through
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/budget", nil))
+	is.Equal(rec.Code, http.StatusNotFound)
+}
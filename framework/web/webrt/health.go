@@ -0,0 +1,35 @@
+package webrt
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/livebud/bud/package/bud"
+)
+
+// HealthHandler responds with the app's status for load balancers and uptime
+// checks. Apps with a database check it with dbrt's HealthHandler instead.
+func HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, map[string]interface{}{
+			"status": "ok",
+		})
+	})
+}
+
+// HealthDetailsHandler responds with the app's status and version. Serve it on
+// the internal listener alongside /metrics.
+func HealthDetailsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, map[string]interface{}{
+			"status":  "ok",
+			"version": bud.Version(),
+		})
+	})
+}
+
+func writeHealth(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,37 @@
+package webrt_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/framework/web/webrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/bud"
+)
+
+func TestHealth(t *testing.T) {
+	is := is.New(t)
+	rec := httptest.NewRecorder()
+	webrt.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "application/json")
+	is.Equal(strings.TrimSpace(rec.Body.String()), `{"status":"ok"}`)
+}
+
+func TestHealthDetails(t *testing.T) {
+	is := is.New(t)
+	rec := httptest.NewRecorder()
+	webrt.HealthDetailsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/bud/health", nil))
+	is.Equal(rec.Code, 200)
+	var body struct {
+		Status  string
+		Version *bud.Info
+	}
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &body))
+	is.Equal(body.Status, "ok")
+	is.Equal(body.Version.Go, runtime.Version())
+}
@@ -0,0 +1,59 @@
+package webrt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/livebud/bud/package/middleware"
+)
+
+// NewProxy forwards requests that nothing else in the app handled to a
+// frontend dev server like Vite or webpack, so bud can keep serving the
+// controllers and server-side rendering. An empty target disables the proxy.
+func NewProxy(target string) *Proxy {
+	if target == "" {
+		return &Proxy{}
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return &Proxy{err: fmt.Errorf("webrt: invalid proxy url %q", target)}
+	}
+	proxy := httputil.NewSingleHostReverseProxy(u)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		// Dev servers tend to only accept their own host
+		r.Host = u.Host
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		http.Error(w, fmt.Sprintf("webrt: unable to reach the frontend dev server at %s. %s", u.Host, err), http.StatusBadGateway)
+	}
+	return &Proxy{proxy: proxy}
+}
+
+// Proxy forwards asset requests to a frontend dev server
+type Proxy struct {
+	proxy *httputil.ReverseProxy
+	err   error
+}
+
+var _ middleware.Middleware = (*Proxy)(nil)
+
+// Middleware proxies GET and HEAD requests, which includes the websocket
+// upgrades for hot module replacement. Other requests pass through.
+func (p *Proxy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodGet && r.Method != http.MethodHead:
+			next.ServeHTTP(w, r)
+		case p.err != nil:
+			http.Error(w, p.err.Error(), http.StatusBadGateway)
+		case p.proxy == nil:
+			next.ServeHTTP(w, r)
+		default:
+			p.proxy.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package webrt_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/framework/web/webrt"
+	"github.com/livebud/bud/internal/is"
+)
+
+func TestProxy(t *testing.T) {
+	is := is.New(t)
+	frontend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.Host + r.URL.Path))
+	}))
+	defer frontend.Close()
+	handler := webrt.NewProxy(frontend.URL).Middleware(http.NotFoundHandler())
+	// Asset requests go to the frontend dev server
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/src/main.ts", nil))
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "GET "+strings.TrimPrefix(frontend.URL, "http://")+"/src/main.ts")
+	// Other requests fall through
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", nil))
+	is.Equal(rec.Code, http.StatusNotFound)
+}
+
+func TestProxyDisabled(t *testing.T) {
+	is := is.New(t)
+	handler := webrt.NewProxy("").Middleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/src/main.ts", nil))
+	is.Equal(rec.Code, http.StatusNotFound)
+}
+
+func TestProxyUnreachable(t *testing.T) {
+	is := is.New(t)
+	frontend := httptest.NewServer(http.NotFoundHandler())
+	frontend.Close()
+	handler := webrt.NewProxy(frontend.URL).Middleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/src/main.ts", nil))
+	is.Equal(rec.Code, http.StatusBadGateway)
+	body, err := io.ReadAll(rec.Body)
+	is.NoErr(err)
+	is.True(strings.Contains(string(body), "webrt: unable to reach the frontend dev server at "+strings.TrimPrefix(frontend.URL, "http://")))
+}
+
+func TestProxyInvalid(t *testing.T) {
+	is := is.New(t)
+	handler := webrt.NewProxy("localhost:5173").Middleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/src/main.ts", nil))
+	is.Equal(rec.Code, http.StatusBadGateway)
+	is.Equal(strings.TrimSpace(rec.Body.String()), `webrt: invalid proxy url "localhost:5173"`)
+}
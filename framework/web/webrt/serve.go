@@ -7,10 +7,13 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"syscall"
 
 	"github.com/livebud/bud/internal/extrafile"
 	"github.com/livebud/bud/internal/sig"
+	"github.com/livebud/bud/package/crash"
 	"github.com/livebud/bud/package/socket"
+	"golang.org/x/sync/errgroup"
 )
 
 // listen first tries pulling the connection from a passed in file descriptor.
@@ -32,8 +35,50 @@ func Listen(prefix, path string) (socket.Listener, error) {
 	return listener, nil
 }
 
-// Serve the handler at address
-func Serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
+// Task runs in the background alongside the web server, like a job worker
+type Task func(ctx context.Context) error
+
+// Serve the handler at address. Background tasks run until the server stops
+// and the server stops if a task fails.
+func Serve(ctx context.Context, listener net.Listener, handler http.Handler, tasks ...Task) error {
+	if len(tasks) == 0 {
+		return serve(ctx, listener, handler)
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	for _, task := range tasks {
+		eg.Go(recoverTask(ctx, task))
+	}
+	eg.Go(func() error {
+		defer cancel()
+		return serve(ctx, listener, handler)
+	})
+	return eg.Wait()
+}
+
+// Work runs the background tasks without serving requests
+func Work(ctx context.Context, tasks ...Task) error {
+	if len(tasks) == 0 {
+		return errors.New("webrt: nothing to work on. Add jobs to job/, tasks to schedule/ or subscribers to event/")
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, task := range tasks {
+		eg.Go(recoverTask(ctx, task))
+	}
+	return eg.Wait()
+}
+
+// recoverTask turns a panic in the task into an error, so the app can write a
+// crash report and stop the other tasks before it exits
+func recoverTask(ctx context.Context, task Task) func() error {
+	return func() (err error) {
+		defer crash.Recover(&err)
+		return task(ctx)
+	}
+}
+
+func serve(ctx context.Context, listener net.Listener, handler http.Handler) error {
 	// Create the HTTP server
 	server := &http.Server{Addr: listener.Addr().String(), Handler: handler}
 	// Make the server shutdownable
@@ -59,7 +104,7 @@ func shutdown(ctx context.Context, server *http.Server) <-chan error {
 	go func() {
 		<-ctx.Done()
 		// Wait for one more interrupt to force an immediate shutdown
-		forceCtx := sig.Trap(ctx, os.Interrupt)
+		forceCtx := sig.Trap(ctx, os.Interrupt, syscall.SIGTERM)
 		if err := server.Shutdown(forceCtx); err != nil {
 			shutdown <- err
 		}
@@ -2,12 +2,14 @@ package webrt_test
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 	"testing"
 
 	"github.com/livebud/bud/framework/web/webrt"
 	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/crash"
 	"golang.org/x/sync/errgroup"
 )
 
@@ -32,3 +34,64 @@ func TestServe(t *testing.T) {
 	is.True(res == nil)
 	is.True(strings.Contains(err.Error(), `connection refused`)) // should have stopped
 }
+
+func TestServeTasks(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener, err := webrt.Listen("APP", ":0")
+	is.NoErr(err)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(205)
+	})
+	stopped := make(chan struct{})
+	task := func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	}
+	eg := new(errgroup.Group)
+	eg.Go(func() error { return webrt.Serve(ctx, listener, handler, task) })
+	res, err := http.Get("http://" + listener.Addr().String())
+	is.NoErr(err)
+	is.Equal(res.StatusCode, 205)
+	cancel()
+	is.NoErr(eg.Wait())
+	<-stopped
+}
+
+func TestServeTaskFails(t *testing.T) {
+	is := is.New(t)
+	listener, err := webrt.Listen("APP", ":0")
+	is.NoErr(err)
+	task := func(ctx context.Context) error {
+		return errors.New("task failed")
+	}
+	// The server stops when a task fails
+	err = webrt.Serve(context.Background(), listener, http.NotFoundHandler(), task)
+	is.Equal(err.Error(), "task failed")
+}
+
+func TestWorkTaskPanics(t *testing.T) {
+	is := is.New(t)
+	// Panics are returned as errors, so the app can report them
+	err := webrt.Work(context.Background(), func(ctx context.Context) error {
+		panic("oops")
+	})
+	var panicErr *crash.PanicError
+	is.True(errors.As(err, &panicErr))
+	is.Equal(panicErr.Value, "oops")
+	is.True(strings.Contains(string(panicErr.Stack), "serve_test.go"))
+}
+
+func TestWork(t *testing.T) {
+	is := is.New(t)
+	err := webrt.Work(context.Background())
+	is.Equal(err.Error(), "webrt: nothing to work on. Add jobs to job/, tasks to schedule/ or subscribers to event/")
+	ran := false
+	is.NoErr(webrt.Work(context.Background(), func(ctx context.Context) error {
+		ran = true
+		return nil
+	}))
+	is.True(ran)
+}
@@ -0,0 +1,21 @@
+package webrt
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/livebud/bud/framework/view/viewrt"
+	"github.com/livebud/bud/package/middleware"
+)
+
+// ViewContext passes a value from the request's context to the views, where
+// Svelte components read it with getContext(key)
+func ViewContext(key string, value func(ctx context.Context) interface{}) middleware.Middleware {
+	return middleware.Function(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ctx = viewrt.WithContext(ctx, key, value(ctx))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+}
@@ -1,36 +1,79 @@
 package bfs
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strconv"
+
+	"github.com/cespare/xxhash"
+	esbuild "github.com/evanw/esbuild/pkg/api"
 
 	"github.com/livebud/bud/internal/dsync"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/internal/versions"
 
 	"github.com/livebud/bud/framework"
 	"github.com/livebud/bud/framework/app"
 	"github.com/livebud/bud/framework/controller"
+	"github.com/livebud/bud/framework/db"
+	"github.com/livebud/bud/framework/event"
 	"github.com/livebud/bud/framework/generator"
+	"github.com/livebud/bud/framework/job"
+	"github.com/livebud/bud/framework/mail"
 	"github.com/livebud/bud/framework/public"
+	"github.com/livebud/bud/framework/schedule"
+	"github.com/livebud/bud/framework/seed"
 	"github.com/livebud/bud/framework/transform/transformrt"
 	"github.com/livebud/bud/framework/view"
 	"github.com/livebud/bud/framework/view/dom"
 	"github.com/livebud/bud/framework/view/ssr"
 	"github.com/livebud/bud/framework/web"
 	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/budfs/diskcache"
 	"github.com/livebud/bud/package/di"
+	"github.com/livebud/bud/package/dotenv"
 	"github.com/livebud/bud/package/gomod"
 	v8 "github.com/livebud/bud/package/js/v8"
 	"github.com/livebud/bud/package/log"
 	"github.com/livebud/bud/package/parser"
+	"github.com/livebud/bud/package/pluginmod"
 	"github.com/livebud/bud/package/svelte"
+	"github.com/livebud/bud/package/vfs"
 )
 
 func Load(flag *framework.Flag, log log.Interface, module *gomod.Module) (*FS, error) {
-	fsys := budfs.New(module, log)
+	// Plugins contribute public assets alongside the app's own
+	appfs, err := pluginmod.Merge(module, "public")
+	if err != nil {
+		return nil, err
+	}
+	conflicts, err := appfs.Conflicts("public")
+	if err != nil {
+		return nil, err
+	}
+	for _, conflict := range conflicts {
+		// Apps override their plugins' files on purpose
+		if conflict.Layers[0] == module.Import() {
+			log.Debug("bfs: "+conflict.String(), "path", conflict.Path)
+			continue
+		}
+		log.Warn("bfs: "+conflict.String(), "path", conflict.Path)
+	}
+	fsys := budfs.New(appfs, log)
+	fsys.Trace(flag.Trace)
+	// Reuse the files generated in earlier sessions when their inputs haven't
+	// changed
+	fsys.Persist(diskcache.New(module.Directory("bud", ".cache", "budfs"), salt(flag, module)))
 	parser := parser.New(fsys, module)
 	injector := di.New(fsys, log, module, parser)
+	injector.Environment(environment(flag))
+	end := flag.Trace.Start("v8", "load")
 	vm, err := v8.Load()
+	end()
 	if err != nil {
 		return nil, err
 	}
@@ -43,20 +86,63 @@ func Load(flag *framework.Flag, log log.Interface, module *gomod.Module) (*FS, e
 		return nil, err
 	}
 	fsys.FileGenerator("bud/internal/app/main.go", app.New(injector, module, flag))
-	fsys.FileGenerator("bud/internal/web/web.go", web.New(module, parser))
+	fsys.FileGenerator("bud/internal/web/web.go", web.New(module, parser, flag))
 	fsys.FileGenerator("bud/internal/web/controller/controller.go", controller.New(injector, module, parser))
 	fsys.FileGenerator("bud/internal/web/view/view.go", view.New(module, transforms, flag))
 	fsys.FileGenerator("bud/internal/web/public/public.go", public.New(flag, module))
+	fsys.FileGenerator("bud/package/db/db.go", db.New(module, parser))
+	fsys.FileGenerator("bud/package/db/query.go", db.NewQuery(module, parser))
+	fsys.FileGenerator("bud/package/jobs/jobs.go", job.New(module, parser))
+	fsys.FileGenerator("bud/internal/scheduler/scheduler.go", schedule.New(module, parser))
+	fsys.FileGenerator("bud/package/mailer/mailer.go", mail.New(module, parser, flag))
+	fsys.FileGenerator("bud/package/events/events.go", event.New(module, parser))
+	fsys.FileGenerator("bud/internal/seed/main.go", seed.New(module, parser))
 	fsys.FileGenerator("bud/view/_ssr.js", ssr.New(module, transforms.SSR))
-	fsys.FileServer("bud/view", dom.New(module, transforms.DOM))
-	fsys.FileServer("bud/node_modules", dom.NodeModules(module))
+	domCompiler := dom.New(module, transforms.DOM)
+	if flag.HotAddr != "" {
+		domCompiler.Hot = flag.HotAddr
+	}
+	fsys.FileServer("bud/view", domCompiler)
+	nodeModules := dom.NodeModules(module)
+	fsys.FileServer("bud/node_modules", nodeModules)
 	fsys.FileGenerator("bud/command/.generate/main.go", generator.New(fsys, flag, injector, log, module, parser))
-	return &FS{fsys, module}, nil
+	return &FS{fsys, module, domCompiler, nodeModules}, nil
+}
+
+// salt changes whenever the same inputs might generate different files, like
+// after upgrading bud, changing flags or changing dependencies
+func salt(flag *framework.Flag, module *gomod.Module) string {
+	h := xxhash.New()
+	fmt.Fprintf(h, "%s embed=%t minify=%t hot=%t hotaddr=%s env=%s\n", versions.Bud, flag.Embed, flag.Minify, flag.Hot, flag.HotAddr, environment(flag))
+	// Development builds of bud share a version, so the binary tells them apart
+	if executable, err := os.Executable(); err == nil {
+		if info, err := os.Stat(executable); err == nil {
+			fmt.Fprintf(h, "%s %d %d\n", executable, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	for _, name := range []string{"go.mod", "go.sum"} {
+		if data, err := os.ReadFile(module.Directory(name)); err == nil {
+			h.Write(data)
+		}
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// environment the app is generated for, which is the environment that the app
+// loads its .env files for
+func environment(flag *framework.Flag) string {
+	getenv := func(key string) string { return envs.From(flag.Env)[key] }
+	if flag.Embed {
+		return dotenv.Environment(getenv, dotenv.Production)
+	}
+	return dotenv.Environment(getenv, dotenv.Development)
 }
 
 type FS struct {
-	fsys   *budfs.FileSystem
-	module *gomod.Module
+	fsys        *budfs.FileSystem
+	module      *gomod.Module
+	dom         *dom.Compiler
+	nodeModules *dom.NodeModuleCompiler
 }
 
 func (f *FS) Open(name string) (fs.File, error) {
@@ -77,13 +163,17 @@ var syncDirs = [...]string{
 }
 
 func (f *FS) Sync() error {
-	if err := f.fsys.Sync(f.module, "bud/command/.generate"); err != nil {
+	return f.syncTo(f.module)
+}
+
+func (f *FS) syncTo(target vfs.ReadWritable) error {
+	if err := f.fsys.Sync(target, "bud/command/.generate"); err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
 			return err
 		}
 	}
 	for _, to := range syncDirs {
-		if err := f.fsys.Sync(f.module, to, skipHidden); err != nil {
+		if err := f.fsys.Sync(target, to, skipHidden); err != nil {
 			if !errors.Is(err, fs.ErrNotExist) {
 				return err
 			}
@@ -92,6 +182,38 @@ func (f *FS) Sync() error {
 	return nil
 }
 
+// Plan returns the changes that Sync would make to the module, without
+// making them. The generated directories are synced to a snapshot in memory
+// instead.
+func (f *FS) Plan() ([]*vfs.Change, error) {
+	before, err := vfs.Snapshot(f.module, syncDirs[:]...)
+	if err != nil {
+		return nil, err
+	}
+	after := before.Snapshot()
+	if err := f.syncTo(after); err != nil {
+		return nil, err
+	}
+	return vfs.Diff(before, after)
+}
+
+// Timings returns how long each generator took the last time it ran
+func (f *FS) Timings() []*budfs.Timing {
+	return f.fsys.Timings()
+}
+
+// Prebundle the node_modules that views depend on, so they're ready before the
+// browser asks for them
+func (f *FS) Prebundle(ctx context.Context) (int, error) {
+	return f.nodeModules.Prebundle(ctx)
+}
+
+// Views compiles the bundles that browsers load for the views. Paths are
+// relative to bud/view.
+func (f *FS) Views(ctx context.Context) ([]esbuild.OutputFile, error) {
+	return f.dom.Compile(ctx, f.fsys)
+}
+
 func (f *FS) Change(paths ...string) {
 	f.fsys.Change(paths...)
 }
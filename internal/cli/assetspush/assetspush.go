@@ -0,0 +1,166 @@
+package assetspush
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/framework/public"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/internal/s3"
+	"github.com/livebud/bud/internal/versions"
+	"golang.org/x/sync/errgroup"
+)
+
+// concurrency is the number of uploads in flight at once
+const concurrency = 8
+
+// New command for bud assets push
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Embed:  true,
+			Minify: true,
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+// Command uploads the view bundles and public files to S3-compatible storage,
+// so a CDN can serve them. Keys match the app's routes, so
+// s3://bucket/assets/bud/view/_index.svelte.js is served by the CDN in
+// package.json at https://cdn.example.com/assets/bud/view/_index.svelte.js.
+type Command struct {
+	bud  *bud.Command
+	in   *bud.Input
+	Flag *framework.Flag
+	// URL of the bucket, like s3://bucket/prefix or gs://bucket/prefix
+	URL string
+	// Endpoint of an S3-compatible service, like https://<account>.r2.cloudflarestorage.com
+	Endpoint string
+	// Region of the bucket. Defaults to $AWS_REGION.
+	Region string
+	// DryRun prints the assets without uploading them
+	DryRun bool
+}
+
+// Run the assets push command
+func (c *Command) Run(ctx context.Context) error {
+	client, prefix, err := c.client()
+	if err != nil {
+		return err
+	}
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	if err := bud.EnsureVersionAlignment(ctx, module, versions.Bud); err != nil {
+		return err
+	}
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	cacheControl, err := public.CacheControl(module)
+	if err != nil {
+		return err
+	}
+	var objects []*s3.Object
+	add := func(route string, data []byte, control string) {
+		objects = append(objects, &s3.Object{
+			Key:  path.Join(prefix, strings.TrimPrefix(route, "/")),
+			Data: data,
+			Headers: http.Header{
+				"Content-Type":  {contentType(route, data)},
+				"Cache-Control": {control},
+			},
+		})
+	}
+	// Add the view bundles
+	bfs, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer bfs.Close()
+	views, err := bfs.Views(ctx)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for _, view := range views {
+		route := path.Join("/bud/view", view.Path)
+		add(route, view.Contents, cacheControl(route))
+	}
+	// Add the public files along with the resized images
+	state, err := public.Load(module, c.Flag)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if state != nil {
+		for _, file := range append(state.Files, state.Resized...) {
+			add(strings.TrimPrefix(file.Path, "public"), file.Data, file.CacheControl)
+		}
+	}
+	if c.DryRun {
+		for _, object := range objects {
+			fmt.Fprintf(c.in.Stdout, "%s\t%s\n", object.Key, object.Headers.Get("Cache-Control"))
+		}
+		return nil
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, concurrency)
+	for _, object := range objects {
+		object := object
+		sem <- struct{}{}
+		eg.Go(func() error {
+			defer func() { <-sem }()
+			if err := client.Put(ctx, object); err != nil {
+				return err
+			}
+			log.Debug("assets: pushed", "key", object.Key)
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("Pushed %d assets to %s", len(objects), c.URL))
+	return nil
+}
+
+// client for the bucket in the URL, along with the key prefix
+func (c *Command) client() (*s3.Client, string, error) {
+	env := envs.From(c.in.Env)
+	getenv := func(key string) string { return env[key] }
+	client, prefix, err := s3.Load(getenv, c.URL, c.Endpoint, c.Region)
+	if err != nil {
+		return nil, "", err
+	}
+	// Dry runs don't upload anything
+	if !c.DryRun {
+		if err := client.Verify(); err != nil {
+			return nil, "", err
+		}
+	}
+	return client, prefix, nil
+}
+
+// contentType of an asset, based on its extension
+func contentType(route string, data []byte) string {
+	if contentType := mime.TypeByExtension(path.Ext(route)); contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
@@ -0,0 +1,260 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/framework/web"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/exe"
+	"github.com/livebud/bud/internal/extrafile"
+	"github.com/livebud/bud/internal/gobuild"
+	"github.com/livebud/bud/internal/versions"
+	"github.com/livebud/bud/package/bench"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/parser"
+	"github.com/livebud/bud/package/socket"
+)
+
+// New command for bud bench
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+// Command for running bud bench
+type Command struct {
+	bud         *bud.Command
+	in          *bud.Input
+	Flag        *framework.Flag
+	Routes      []string
+	URL         string
+	Concurrency int
+	Requests    int
+	Duration    time.Duration
+	Params      map[string]string
+	Bodies      []string
+	Headers     []string
+}
+
+// Run builds and starts the app, then sends traffic to each route in turn and
+// reports their latencies. Only GET routes are benchmarked unless routes are
+// passed in.
+func (c *Command) Run(ctx context.Context) error {
+	// Find go.mod
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	// Ensure we have version alignment between the CLI and the runtime
+	if err := bud.EnsureVersionAlignment(ctx, module, versions.Bud); err != nil {
+		return err
+	}
+	// Setup the logger
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	bfs, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer bfs.Close()
+	// Load the route table
+	state, err := web.Load(bfs, module, parser.New(bfs, module), c.Flag)
+	if err != nil {
+		return err
+	}
+	targets, err := c.targets(state.Actions)
+	if err != nil {
+		return err
+	}
+	header, err := parseHeaders(c.Headers)
+	if err != nil {
+		return err
+	}
+	baseURL := strings.TrimSuffix(c.URL, "/")
+	if baseURL == "" {
+		// Generate and build the application
+		if err := bfs.Sync(); err != nil {
+			return err
+		}
+		builder := gobuild.New(module)
+		builder.Env = c.in.Env
+		builder.Stderr = c.in.Stderr
+		builder.Stdout = c.in.Stdout
+		if err := builder.Build(ctx, "bud/internal/app/main.go", "bud/app"); err != nil {
+			return err
+		}
+		app, addr, err := c.start(ctx, module.Directory(), log)
+		if err != nil {
+			return err
+		}
+		defer app.Close()
+		baseURL = "http://" + addr
+	}
+	config := &bench.Config{
+		Concurrency: c.Concurrency,
+		Requests:    c.Requests,
+		Duration:    c.Duration,
+		Header:      header,
+	}
+	if c.Duration > 0 {
+		// The duration takes the place of the number of requests
+		config.Requests = 0
+	}
+	results := make([]*bench.Result, 0, len(targets))
+	for _, target := range targets {
+		log.Info("bench: sending traffic", "route", target.Method+" "+target.Route)
+		result, err := bench.Run(ctx, baseURL, target, config)
+		if err != nil {
+			return fmt.Errorf("%w. Pass params with --param name:value", err)
+		}
+		results = append(results, result)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return bench.Report(c.in.Stdout, results...)
+}
+
+// targets selects the routes to benchmark
+func (c *Command) targets(actions []*web.Action) (targets []*bench.Target, err error) {
+	bodies, err := parseBodies(c.Bodies)
+	if err != nil {
+		return nil, err
+	}
+	newTarget := func(action *web.Action) *bench.Target {
+		return &bench.Target{
+			Method: strings.ToUpper(action.Method),
+			Route:  action.Route,
+			Params: c.Params,
+			Body:   bodies[strings.ToUpper(action.Method)+" "+action.Route],
+		}
+	}
+	if len(c.Routes) == 0 {
+		for _, action := range actions {
+			if strings.EqualFold(action.Method, http.MethodGet) {
+				targets = append(targets, newTarget(action))
+			}
+		}
+		if len(targets) == 0 {
+			return nil, fmt.Errorf("bench: no GET routes to benchmark")
+		}
+		return targets, nil
+	}
+	for _, route := range c.Routes {
+		method, path := splitRoute(route)
+		found := false
+		for _, action := range actions {
+			if strings.EqualFold(action.Method, method) && action.Route == path {
+				targets = append(targets, newTarget(action))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("bench: unknown route %q. The routes are:\n%s", method+" "+path, list(actions))
+		}
+	}
+	return targets, nil
+}
+
+// splitRoute splits "POST /posts" into its method and path. Routes without a
+// method are GET routes.
+func splitRoute(route string) (method, path string) {
+	method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+	if !ok {
+		return http.MethodGet, method
+	}
+	return strings.ToUpper(method), strings.TrimSpace(path)
+}
+
+// parseBodies parses bodies like `POST /posts={"title":"hi"}`
+func parseBodies(bodies []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, body := range bodies {
+		route, template, ok := strings.Cut(body, "=")
+		if !ok {
+			return nil, fmt.Errorf("bench: invalid body %q. Bodies look like \"POST /posts={...}\"", body)
+		}
+		method, path := splitRoute(route)
+		m[method+" "+path] = template
+	}
+	return m, nil
+}
+
+// parseHeaders parses headers like "Authorization: Bearer 123"
+func parseHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, h := range headers {
+		key, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("bench: invalid header %q. Headers look like \"Authorization: Bearer 123\"", h)
+		}
+		header.Add(strings.TrimSpace(key), strings.TrimSpace(value))
+	}
+	return header, nil
+}
+
+func list(actions []*web.Action) string {
+	lines := make([]string, len(actions))
+	for i, action := range actions {
+		lines[i] = "  " + strings.ToUpper(action.Method) + " " + action.Route
+	}
+	return strings.Join(lines, "\n")
+}
+
+// start the app on a random port and wait until it's serving requests
+func (c *Command) start(ctx context.Context, dir string, log log.Interface) (*exe.Process, string, error) {
+	listener, err := socket.Listen("127.0.0.1:0")
+	if err != nil {
+		return nil, "", err
+	}
+	defer listener.Close()
+	file, err := listener.File()
+	if err != nil {
+		return nil, "", err
+	}
+	defer file.Close()
+	cmd := &exe.Command{
+		Dir:    dir,
+		Env:    append([]string{}, c.in.Env...),
+		Stdout: c.in.Stdout,
+		Stderr: c.in.Stderr,
+	}
+	extrafile.Inject(&cmd.ExtraFiles, &cmd.Env, "WEB", file)
+	app, err := cmd.Start(ctx, dir+"/bud/app", "--log", c.bud.Log)
+	if err != nil {
+		return nil, "", err
+	}
+	addr := listener.Addr().String()
+	// Wait for the first response, so startup isn't part of the results
+	client := &http.Client{Timeout: time.Second}
+	for start := time.Now(); time.Since(start) < 30*time.Second; time.Sleep(50 * time.Millisecond) {
+		res, err := client.Get("http://" + addr + "/")
+		if err == nil {
+			res.Body.Close()
+			log.Debug("bench: app started", "address", addr)
+			return app, addr, nil
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	app.Close()
+	return nil, "", fmt.Errorf("bench: app didn't start on %s", addr)
+}
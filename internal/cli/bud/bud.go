@@ -6,17 +6,23 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
 	"github.com/livebud/bud/internal/current"
+	"github.com/livebud/bud/internal/envs"
 	"github.com/livebud/bud/internal/pubsub"
 	"golang.org/x/mod/semver"
 
 	"github.com/livebud/bud/package/commander"
+	"github.com/livebud/bud/package/dotenv"
 	"github.com/livebud/bud/package/gomod"
 	"github.com/livebud/bud/package/log"
 	"github.com/livebud/bud/package/log/console"
 	"github.com/livebud/bud/package/log/filter"
+	"github.com/livebud/bud/package/migrate"
+	"github.com/livebud/bud/package/secretenv"
+	"github.com/livebud/bud/package/secrets"
 	"github.com/livebud/bud/package/socket"
 )
 
@@ -104,6 +110,66 @@ func Log(stderr io.Writer, logFilter string) (log.Interface, error) {
 	return log.New(handler), nil
 }
 
+// Env adds the variables in the app's .env files to the environment, so the
+// CLI sees the same environment as the app. Variables in env always win.
+func Env(module *gomod.Module, env []string) ([]string, error) {
+	environment := dotenv.Environment(func(key string) string { return envs.From(env)[key] }, dotenv.Development)
+	return dotenv.Merge(module, environment, env)
+}
+
+// MasterKeys loads the app's master keys from $MASTER_KEY or the keyfile,
+// along with the keyfile's path. Relative keyfiles are in the module rather
+// than the working directory. The path is empty when $MASTER_KEY is set.
+func MasterKeys(module *gomod.Module, env []string) (*secrets.Keys, string, error) {
+	vars := envs.From(env)
+	if vars["MASTER_KEY"] != "" {
+		keys, err := secrets.LoadEnv(func(key string) string { return vars[key] })
+		return keys, "", err
+	}
+	keyfile := vars["MASTER_KEY_FILE"]
+	if keyfile == "" {
+		keyfile = secrets.DefaultKeyFile
+	}
+	if !filepath.IsAbs(keyfile) {
+		keyfile = module.Directory(keyfile)
+	}
+	vars["MASTER_KEY_FILE"] = keyfile
+	keys, err := secrets.LoadEnv(func(key string) string { return vars[key] })
+	return keys, keyfile, err
+}
+
+// DatabaseURL returns $DATABASE_URL from the environment. References to
+// secrets, like vault:kv/app#database_url, are resolved.
+func DatabaseURL(env []string) (string, error) {
+	vars := envs.From(env)
+	databaseURL := vars["DATABASE_URL"]
+	if databaseURL == "" {
+		return "", fmt.Errorf("bud: missing the DATABASE_URL environment variable")
+	}
+	resolver, err := secretenv.LoadEnv(func(key string) string { return vars[key] })
+	if err != nil {
+		return "", err
+	}
+	if !resolver.IsReference(databaseURL) {
+		return databaseURL, nil
+	}
+	return resolver.Resolve(context.Background(), databaseURL)
+}
+
+// Migrator loads the migrations within the application's migrate/ directory
+// and connects to the database in $DATABASE_URL
+func Migrator(module *gomod.Module, log log.Interface, env []string) (*migrate.Migrator, error) {
+	env, err := Env(module, env)
+	if err != nil {
+		return nil, err
+	}
+	databaseURL, err := DatabaseURL(env)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.Open(log, databaseURL, os.DirFS(module.Directory(migrate.Dir)))
+}
+
 // EnsureVersionAlignment ensures that the CLI and runtime versions are aligned.
 // If they're not aligned, the CLI will correct the go.mod file to align them.
 func EnsureVersionAlignment(ctx context.Context, module *gomod.Module, budVersion string) error {
@@ -2,10 +2,12 @@ package build
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/livebud/bud/framework"
 	"github.com/livebud/bud/internal/bfs"
 	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/coverage"
 	"github.com/livebud/bud/internal/gobuild"
 	"github.com/livebud/bud/internal/versions"
 )
@@ -29,6 +31,12 @@ type Command struct {
 	bud  *bud.Command
 	in   *bud.Input
 	Flag *framework.Flag
+	// Cover instruments the app's packages, so the binary writes its coverage
+	// to $GOCOVERDIR
+	Cover bool
+	// DryRun prints the files that generating would change without writing
+	// them or building the app
+	DryRun bool
 }
 
 // Run the build command
@@ -52,10 +60,33 @@ func (c *Command) Run(ctx context.Context) error {
 		return err
 	}
 	defer bfs.Close()
+	if c.DryRun {
+		return c.plan(bfs)
+	}
 	// Generate the application
 	if err := bfs.Sync(); err != nil {
 		return err
 	}
+	var flags []string
+	if c.Cover {
+		packages, err := coverage.Packages(ctx, module, c.in.Env)
+		if err != nil {
+			return err
+		}
+		flags = coverage.BuildFlags(packages)
+	}
 	builder := gobuild.New(module)
-	return builder.Build(ctx, "bud/internal/app/main.go", "bud/app")
+	return builder.Build(ctx, "bud/internal/app/main.go", "bud/app", flags...)
+}
+
+// plan prints the changes that generating the application would make
+func (c *Command) plan(fsys *bfs.FS) error {
+	changes, err := fsys.Plan()
+	if err != nil {
+		return err
+	}
+	for _, change := range changes {
+		fmt.Fprintln(c.in.Stdout, change.String())
+	}
+	return nil
 }
@@ -2,6 +2,7 @@ package build_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/livebud/bud/internal/cli/testcli"
@@ -44,3 +45,25 @@ func TestBuildTwice(t *testing.T) {
 	is.Equal(result.Stderr(), "")
 	is.NoErr(td.Exists("bud/app"))
 }
+
+func TestBuildDryRun(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "build", "--dry-run")
+	is.NoErr(err)
+	is.True(strings.Contains(result.Stdout(), "create bud/internal/app/main.go\n"))
+	is.Equal(result.Stderr(), "")
+	// Nothing was written
+	is.NoErr(td.NotExists("bud/internal/app/main.go"))
+	is.NoErr(td.NotExists("bud/app"))
+	// Once built, there's nothing left to change
+	result, err = cli.Run(ctx, "build")
+	is.NoErr(err)
+	result, err = cli.Run(ctx, "build", "--dry-run")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "")
+}
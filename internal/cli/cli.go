@@ -4,12 +4,26 @@ import (
 	"context"
 	"errors"
 	"runtime"
+	"time"
 
+	"github.com/livebud/bud/internal/cli/assetspush"
+	"github.com/livebud/bud/internal/cli/bench"
 	"github.com/livebud/bud/internal/cli/bud"
 	"github.com/livebud/bud/internal/cli/build"
 	"github.com/livebud/bud/internal/cli/create"
+	"github.com/livebud/bud/internal/cli/credentialsedit"
+	"github.com/livebud/bud/internal/cli/credentialsrotate"
+	"github.com/livebud/bud/internal/cli/credentialsshow"
+	"github.com/livebud/bud/internal/cli/dbseed"
+	"github.com/livebud/bud/internal/cli/digraph"
+	"github.com/livebud/bud/internal/cli/migratedown"
+	"github.com/livebud/bud/internal/cli/migratenew"
+	"github.com/livebud/bud/internal/cli/migratestatus"
+	"github.com/livebud/bud/internal/cli/migrateup"
+	"github.com/livebud/bud/internal/cli/newauth"
 	"github.com/livebud/bud/internal/cli/newcontroller"
 	"github.com/livebud/bud/internal/cli/run"
+	"github.com/livebud/bud/internal/cli/test"
 	"github.com/livebud/bud/internal/cli/toolbs"
 	"github.com/livebud/bud/internal/cli/toolcache"
 	"github.com/livebud/bud/internal/cli/tooldi"
@@ -19,6 +33,7 @@ import (
 	"github.com/livebud/bud/internal/cli/toolfstxtar"
 	"github.com/livebud/bud/internal/cli/toolv8"
 	"github.com/livebud/bud/internal/cli/version"
+	"github.com/livebud/bud/internal/cli/work"
 	"github.com/livebud/bud/internal/versions"
 	"github.com/livebud/bud/package/commander"
 )
@@ -63,6 +78,11 @@ func (c *CLI) Run(ctx context.Context, args ...string) error {
 		cli.Flag("hot", "hot reloading").Bool(&cmd.Flag.Hot).Default(true)
 		cli.Flag("minify", "minify assets").Bool(&cmd.Flag.Minify).Default(false)
 		cli.Flag("listen", "address to listen to").String(&cmd.Listen).Default(":3000")
+		cli.Flag("migrate", "apply pending migrations on boot").Bool(&cmd.Migrate).Default(false)
+		cli.Flag("watch", "watch for changes with auto, native or poll").String(&cmd.Watch).Default("auto")
+		cli.Flag("profile", "write a timeline of each build to bud/profile.json").Bool(&cmd.Profile).Default(false)
+		cli.Flag("qr", "print a QR code to open the app on another device").Bool(&cmd.QR).Default(false)
+		cli.Flag("proxy", "proxy requests the app doesn't handle to a frontend dev server").String(&cmd.Proxy).Optional()
 		cli.Run(cmd.Run)
 	}
 
@@ -71,9 +91,139 @@ func (c *CLI) Run(ctx context.Context, args ...string) error {
 		cli := cli.Command("build", "build your app into a single binary")
 		cli.Flag("embed", "embed assets").Bool(&cmd.Flag.Embed).Default(true)
 		cli.Flag("minify", "minify assets").Bool(&cmd.Flag.Minify).Default(true)
+		cli.Flag("cover", "build with coverage, written to $GOCOVERDIR").Bool(&cmd.Cover).Default(false)
+		cli.Flag("dry-run", "print the generated files that would change without writing them").Bool(&cmd.DryRun).Default(false)
 		cli.Run(cmd.Run)
 	}
 
+	{ // $ bud assets
+		cli := cli.Command("assets", "manage the app's assets")
+
+		{ // $ bud assets push <url>
+			cmd := assetspush.New(cmd, c.in)
+			cli := cli.Command("push", "upload the assets to s3-compatible storage for a cdn")
+			cli.Arg("url").String(&cmd.URL)
+			cli.Flag("endpoint", "endpoint of an s3-compatible service").String(&cmd.Endpoint).Default("")
+			cli.Flag("region", "region of the bucket").String(&cmd.Region).Default("")
+			cli.Flag("dry-run", "print the assets without uploading them").Bool(&cmd.DryRun).Default(false)
+			cli.Run(cmd.Run)
+		}
+	}
+
+	{ // $ bud work
+		cmd := work.New(cmd, c.in)
+		cli := cli.Command("work", "run jobs and scheduled tasks without serving requests")
+		cli.Flag("embed", "embed assets").Bool(&cmd.Flag.Embed).Default(true)
+		cli.Flag("minify", "minify assets").Bool(&cmd.Flag.Minify).Default(false)
+		cli.Run(cmd.Run)
+	}
+
+	{ // $ bud bench [routes...]
+		cmd := bench.New(cmd, c.in)
+		cli := cli.Command("bench", "send concurrent traffic to routes and report their latency")
+		cli.Args("routes").Strings(&cmd.Routes).Optional()
+		cli.Flag("url", "benchmark a running app instead of building one").String(&cmd.URL).Default("")
+		cli.Flag("concurrency", "number of requests in flight at once").Short('c').Int(&cmd.Concurrency).Default(10)
+		cli.Flag("requests", "number of requests to send to each route").Short('n').Int(&cmd.Requests).Default(200)
+		cli.Flag("duration", "send requests to each route for this long instead").Short('d').Custom(func(value string) (err error) {
+			cmd.Duration, err = time.ParseDuration(value)
+			return err
+		}).Default("0s")
+		cli.Flag("param", "fill in a route param, like id:{{.N}}").Short('p').StringMap(&cmd.Params).Optional()
+		cli.Flag("body", "request body for a route, like 'POST /posts={\"title\":\"post {{.N}}\"}'").Short('b').Strings(&cmd.Bodies).Optional()
+		cli.Flag("header", "send this header, like 'Authorization: Bearer 123'").Short('H').Strings(&cmd.Headers).Optional()
+		cli.Flag("embed", "embed assets").Bool(&cmd.Flag.Embed).Default(true)
+		cli.Flag("minify", "minify assets").Bool(&cmd.Flag.Minify).Default(true)
+		cli.Run(cmd.Run)
+	}
+
+	{ // $ bud test [packages...]
+		cmd := test.New(cmd, c.in)
+		cli := cli.Command("test", "test your app")
+		cli.Args("packages").Strings(&cmd.Packages).Optional()
+		cli.Flag("run", "only run tests matching this pattern").String(&cmd.Pattern).Default("")
+		cli.Flag("verbose", "print each test as it runs").Short('v').Bool(&cmd.Verbose).Default(false)
+		cli.Flag("coverprofile", "write the coverage of your code to this file").String(&cmd.CoverProfile).Default("")
+		cli.Flag("browser", "run browser tests in headless chrome").Bool(&cmd.Browser).Default(false)
+		cli.Run(cmd.Run)
+	}
+
+	{ // $ bud db
+		cli := cli.Command("db", "manage the database")
+
+		{ // $ bud db migrate
+			cli := cli.Command("migrate", "manage database migrations")
+
+			{ // $ bud db migrate new <name>
+				cmd := migratenew.New(cmd, c.in)
+				cli := cli.Command("new", "create a new migration")
+				cli.Arg("name").String(&cmd.Name)
+				cli.Run(cmd.Run)
+			}
+
+			{ // $ bud db migrate up
+				cmd := migrateup.New(cmd, c.in)
+				cli := cli.Command("up", "apply pending migrations")
+				cli.Flag("steps", "number of migrations to apply").Short('n').Int(&cmd.Steps).Default(0)
+				cli.Run(cmd.Run)
+			}
+
+			{ // $ bud db migrate down
+				cmd := migratedown.New(cmd, c.in)
+				cli := cli.Command("down", "revert applied migrations")
+				cli.Flag("steps", "number of migrations to revert").Short('n').Int(&cmd.Steps).Default(1)
+				cli.Run(cmd.Run)
+			}
+
+			{ // $ bud db migrate status
+				cmd := migratestatus.New(cmd, c.in)
+				cli := cli.Command("status", "show the status of each migration")
+				cli.Run(cmd.Run)
+			}
+		}
+
+		{ // $ bud db seed
+			cmd := dbseed.New(cmd, c.in)
+			cli := cli.Command("seed", "seed the database with the fixtures and functions in seed/")
+			cli.Flag("reset", "truncate the fixture tables before seeding").Bool(&cmd.Reset).Default(false)
+			cli.Run(cmd.Run)
+		}
+	}
+
+	{ // $ bud credentials
+		cli := cli.Command("credentials", "manage the app's encrypted credentials")
+
+		{ // $ bud credentials edit
+			cmd := credentialsedit.New(cmd, c.in)
+			cli := cli.Command("edit", "edit the credentials in $EDITOR")
+			cli.Run(cmd.Run)
+		}
+
+		{ // $ bud credentials show
+			cmd := credentialsshow.New(cmd, c.in)
+			cli := cli.Command("show", "print the decrypted credentials")
+			cli.Run(cmd.Run)
+		}
+
+		{ // $ bud credentials rotate
+			cmd := credentialsrotate.New(cmd, c.in)
+			cli := cli.Command("rotate", "encrypt the credentials with a new master key")
+			cli.Run(cmd.Run)
+		}
+	}
+
+	{ // $ bud di
+		cli := cli.Command("di", "inspect dependency injection")
+
+		{ // $ bud di graph [dependencies...]
+			cmd := digraph.New(cmd, c.in)
+			cli := cli.Command("graph", "print the dependency graph of the controllers or the given dependencies")
+			cli.Args("dependencies").Strings(&cmd.Dependencies).Optional()
+			cli.Flag("format", "output format, dot or mermaid").String(&cmd.Format).Default("dot")
+			cli.Run(cmd.Run)
+		}
+	}
+
 	{ // $ bud new
 		cli := cli.Command("new", "scaffold code for your app")
 
@@ -85,6 +235,12 @@ func (c *CLI) Run(ctx context.Context, args ...string) error {
 			cli.Run(cmd.Run)
 		}
 
+		{ // $ bud new auth
+			cmd := newauth.New(cmd, c.in)
+			cli := cli.Command("auth", "scaffold signup, login and logout")
+			cli.Run(cmd.Run)
+		}
+
 	}
 
 	{ // $ bud tool
@@ -96,6 +252,11 @@ func (c *CLI) Run(ctx context.Context, args ...string) error {
 			cli.Flag("embed", "embed assets").Bool(&cmd.Flag.Embed).Default(false)
 			cli.Flag("hot", "hot reloading").Bool(&cmd.Flag.Hot).Default(true)
 			cli.Flag("minify", "minify assets").Bool(&cmd.Flag.Minify).Default(false)
+			cli.Flag("listen", "address to listen to").String(&cmd.Listen).Default("127.0.0.1:35729")
+			cli.Flag("tls-cert", "serve over https with this certificate").String(&cmd.TLSCert).Optional()
+			cli.Flag("tls-key", "serve over https with this private key").String(&cmd.TLSKey).Optional()
+			cli.Flag("grpc", "also serve grpc on this address").String(&cmd.GRPC).Optional()
+			cli.Flag("token", "require clients to send this bearer token").String(&cmd.Token).Optional()
 			cli.Run(cmd.Run)
 		}
 
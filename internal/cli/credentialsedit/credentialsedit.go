@@ -0,0 +1,146 @@
+package credentialsedit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/package/credentials"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/secrets"
+)
+
+// New command for bud credentials edit
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+// Command decrypts the credentials into a temporary file, opens it in
+// $VISUAL or $EDITOR, then encrypts the changes. The first edit creates the
+// master key in config/master.key when there isn't one yet.
+type Command struct {
+	bud *bud.Command
+	in  *bud.Input
+}
+
+// Run the credentials edit command
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	env, err := bud.Env(module, c.in.Env)
+	if err != nil {
+		return err
+	}
+	keys, err := c.masterKeys(module, env)
+	if err != nil {
+		return err
+	}
+	// Decrypt the credentials, starting from a template the first time
+	path := module.Directory(credentials.Path)
+	plain := []byte(credentials.Template)
+	if data, err := os.ReadFile(path); err == nil {
+		if plain, err = credentials.Decrypt(keys, data); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("credentials: unable to read %s. %w", credentials.Path, err)
+	}
+	// Only the current user can read the decrypted file
+	tmp, err := os.CreateTemp("", "credentials-*.yml")
+	if err != nil {
+		return fmt.Errorf("credentials: unable to create a temporary file. %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(plain); err != nil {
+		tmp.Close()
+		return fmt.Errorf("credentials: unable to write a temporary file. %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("credentials: unable to write a temporary file. %w", err)
+	}
+	if err := c.edit(ctx, env, tmp.Name()); err != nil {
+		return err
+	}
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return fmt.Errorf("credentials: unable to read the edited file. %w", err)
+	}
+	if _, err := os.Stat(path); err == nil && bytes.Equal(edited, plain) {
+		fmt.Fprintln(c.in.Stdout, "credentials unchanged")
+		return nil
+	}
+	data, err := credentials.Encrypt(keys, edited)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("credentials: unable to create the config directory. %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("credentials: unable to write %s. %w", credentials.Path, err)
+	}
+	fmt.Fprintln(c.in.Stdout, "updated "+credentials.Path)
+	return nil
+}
+
+// masterKeys loads the master keys, creating the keyfile if there's no key
+func (c *Command) masterKeys(module *gomod.Module, env []string) (*secrets.Keys, error) {
+	keys, keyfile, err := bud.MasterKeys(module, env)
+	if err == nil {
+		return keys, nil
+	} else if keyfile == "" || !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	// Don't create a key that would make the existing credentials unreadable
+	if _, err := os.Stat(module.Directory(credentials.Path)); err == nil {
+		return nil, fmt.Errorf("credentials: missing the master key to decrypt %s. Set $MASTER_KEY or restore %s", credentials.Path, keyfile)
+	}
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyfile), 0755); err != nil {
+		return nil, fmt.Errorf("credentials: unable to create the keyfile's directory. %w", err)
+	}
+	if err := os.WriteFile(keyfile, []byte(key+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("credentials: unable to write the keyfile. %w", err)
+	}
+	if rel, err := filepath.Rel(module.Directory(), keyfile); err == nil {
+		keyfile = rel
+	}
+	fmt.Fprintf(c.in.Stdout, "created %s. Keep it out of git and share it securely\n", keyfile)
+	return secrets.New(key)
+}
+
+// edit the file in the user's editor
+func (c *Command) edit(ctx context.Context, env []string, path string) error {
+	vars := envs.From(env)
+	editor := vars["VISUAL"]
+	if editor == "" {
+		editor = vars["EDITOR"]
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	// Run the editor through the shell, so editors can have arguments, like
+	// EDITOR="code --wait"
+	cmd := exec.CommandContext(ctx, "sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Env = env
+	cmd.Stdin = c.in.Stdin
+	cmd.Stdout = c.in.Stdout
+	cmd.Stderr = c.in.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("credentials: unable to edit with %s. %w", strings.Fields(editor)[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package credentialsedit_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+// editor writes the contents into the file it's asked to edit
+func editor(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "contents.yml")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "cp " + path
+}
+
+func TestEditShowRotate(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["EDITOR"] = editor(t, "stripe:\n  secret_key: sk_test\n")
+	result, err := cli.Run(ctx, "credentials", "edit")
+	is.NoErr(err)
+	is.In(result.Stdout(), "created config/master.key")
+	is.In(result.Stdout(), "updated config/credentials.yml.enc")
+	is.NoErr(td.Exists("config/master.key"))
+	is.NoErr(td.Exists("config/credentials.yml.enc"))
+	data, err := os.ReadFile(filepath.Join(dir, "config/credentials.yml.enc"))
+	is.NoErr(err)
+	is.True(!strings.Contains(string(data), "sk_test"))
+	result, err = cli.Run(ctx, "credentials", "show")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "stripe:\n  secret_key: sk_test\n")
+	// Editing without changes leaves the file alone
+	cli.Env["EDITOR"] = "true"
+	result, err = cli.Run(ctx, "credentials", "edit")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "credentials unchanged\n")
+	// Rotating keeps the credentials readable
+	result, err = cli.Run(ctx, "credentials", "rotate")
+	is.NoErr(err)
+	is.In(result.Stdout(), "rotated config/master.key")
+	is.In(result.Stdout(), "re-encrypted config/credentials.yml.enc")
+	result, err = cli.Run(ctx, "credentials", "show")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "stripe:\n  secret_key: sk_test\n")
+	// Another master key can't decrypt the credentials
+	cli.Env["MASTER_KEY"] = "other"
+	_, err = cli.Run(ctx, "credentials", "show")
+	is.True(err != nil)
+	is.In(err.Error(), "unable to decrypt")
+}
+
+func TestEditInvalid(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["MASTER_KEY"] = "secret"
+	cli.Env["EDITOR"] = editor(t, "stripe: [oops\n")
+	_, err := cli.Run(ctx, "credentials", "edit")
+	is.True(err != nil)
+	is.In(err.Error(), "credentials: unable to parse")
+	is.NoErr(td.NotExists("config/credentials.yml.enc"))
+	is.NoErr(td.NotExists("config/master.key"))
+}
@@ -0,0 +1,90 @@
+package credentialsrotate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/package/credentials"
+	"github.com/livebud/bud/package/secrets"
+)
+
+// New command for bud credentials rotate
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+// Command generates a new master key and encrypts the credentials with it.
+// The previous key moves below the new key in the keyfile, so values like
+// cookies that were encrypted with the previous key keep working.
+type Command struct {
+	bud *bud.Command
+	in  *bud.Input
+}
+
+// Run the credentials rotate command
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	env, err := bud.Env(module, c.in.Env)
+	if err != nil {
+		return err
+	}
+	keys, keyfile, err := bud.MasterKeys(module, env)
+	if err != nil {
+		return err
+	}
+	path := module.Directory(credentials.Path)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("credentials: unable to read %s. %w", credentials.Path, err)
+	}
+	var plain []byte
+	if data != nil {
+		if plain, err = credentials.Decrypt(keys, data); err != nil {
+			return err
+		}
+	}
+	key, err := secrets.GenerateKey()
+	if err != nil {
+		return err
+	}
+	newKeys, err := secrets.New(key)
+	if err != nil {
+		return err
+	}
+	// Write the new key before the credentials that depend on it
+	if keyfile == "" {
+		fmt.Fprintf(c.in.Stdout, "Move the current $MASTER_KEY into $MASTER_PREVIOUS_KEYS and set $MASTER_KEY to:\n\n  %s\n\n", key)
+	} else {
+		previous, err := os.ReadFile(keyfile)
+		if err != nil {
+			return fmt.Errorf("credentials: unable to read the keyfile. %w", err)
+		}
+		if err := os.WriteFile(keyfile, append([]byte(key+"\n"), previous...), 0600); err != nil {
+			return fmt.Errorf("credentials: unable to write the keyfile. %w", err)
+		}
+		if rel, err := filepath.Rel(module.Directory(), keyfile); err == nil {
+			keyfile = rel
+		}
+		fmt.Fprintf(c.in.Stdout, "rotated %s\n", keyfile)
+	}
+	if plain == nil {
+		return nil
+	}
+	data, err = credentials.Encrypt(newKeys, plain)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("credentials: unable to write %s. %w", credentials.Path, err)
+	}
+	fmt.Fprintln(c.in.Stdout, "re-encrypted "+credentials.Path)
+	return nil
+}
@@ -0,0 +1,52 @@
+package credentialsshow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/package/credentials"
+)
+
+// New command for bud credentials show
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+// Command prints the decrypted credentials
+type Command struct {
+	bud *bud.Command
+	in  *bud.Input
+}
+
+// Run the credentials show command
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	env, err := bud.Env(module, c.in.Env)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(module.Directory(credentials.Path))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("credentials: %s doesn't exist yet. Create it with bud credentials edit", credentials.Path)
+		}
+		return fmt.Errorf("credentials: unable to read %s. %w", credentials.Path, err)
+	}
+	keys, _, err := bud.MasterKeys(module, env)
+	if err != nil {
+		return err
+	}
+	plain, err := credentials.Decrypt(keys, data)
+	if err != nil {
+		return err
+	}
+	_, err = c.in.Stdout.Write(plain)
+	return err
+}
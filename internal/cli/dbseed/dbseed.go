@@ -0,0 +1,128 @@
+package dbseed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/gobuild"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/fixture"
+)
+
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+type Command struct {
+	bud   *bud.Command
+	in    *bud.Input
+	Flag  *framework.Flag
+	Reset bool
+}
+
+// Run loads the fixtures in seed/, then runs the Go seed functions
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	env, err := bud.Env(module, c.in.Env)
+	if err != nil {
+		return err
+	}
+	databaseURL, err := bud.DatabaseURL(env)
+	if err != nil {
+		return err
+	}
+	des, err := fs.ReadDir(module, "seed")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("bud: nothing to seed. Add fixtures or Go functions to seed/")
+		}
+		return err
+	}
+	// Load the fixtures
+	tables, err := fixture.Load(os.DirFS(module.Directory("seed")))
+	if err != nil {
+		return err
+	}
+	if len(tables) > 0 {
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if c.Reset {
+			err = fixture.Reload(ctx, db, tables...)
+		} else {
+			err = fixture.Insert(ctx, db, tables...)
+		}
+		if err != nil {
+			return err
+		}
+		for _, table := range tables {
+			log.Info("seed: seeded "+table.Name, "rows", len(table.Rows))
+		}
+	}
+	// Run the Go seed functions
+	if !hasGoFile(des) {
+		return nil
+	}
+	bfs, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer bfs.Close()
+	if err := bfs.Sync(); err != nil {
+		return err
+	}
+	if _, err := fs.Stat(module, "bud/internal/seed/main.go"); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	builder := gobuild.New(module)
+	builder.Env = c.in.Env
+	builder.Stderr = c.in.Stderr
+	builder.Stdout = c.in.Stdout
+	if err := builder.Build(ctx, "bud/internal/seed/main.go", "bud/seed"); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, module.Directory("bud", "seed"))
+	cmd.Dir = module.Directory()
+	cmd.Env = env
+	cmd.Stdout = c.in.Stdout
+	cmd.Stderr = c.in.Stderr
+	return cmd.Run()
+}
+
+func hasGoFile(des []fs.DirEntry) bool {
+	for _, de := range des {
+		if !de.IsDir() && valid.GoFile(de.Name()) {
+			return true
+		}
+	}
+	return false
+}
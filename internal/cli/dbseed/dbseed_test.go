@@ -0,0 +1,76 @@
+package dbseed_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestSeed(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["model/user.go"] = `
+		package model
+		type User struct {
+			ID   int
+			Name string
+		}
+	`
+	td.Files["seed/1_users.yml"] = "- id: 1\n  name: Alice\n- id: 2\n  name: Bob\n"
+	td.Files["seed/admin.go"] = `
+		package seed
+		import (
+			"context"
+			"app.com/bud/package/db"
+			"app.com/model"
+		)
+		func Admin(ctx context.Context, db *db.DB) error {
+			return db.User.Insert(ctx, &model.User{Name: "Admin"})
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	databaseURL := "sqlite://" + filepath.Join(dir, "app.db")
+	conn, err := dbrt.Open(databaseURL)
+	is.NoErr(err)
+	defer conn.Close()
+	_, err = conn.Exec(`CREATE TABLE users (id integer primary key, name text not null)`)
+	is.NoErr(err)
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = databaseURL
+	result, err := cli.Run(ctx, "db", "seed")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "")
+	is.True(strings.Contains(result.Stderr(), "seed: seeded users"))
+	is.True(strings.Contains(result.Stderr(), "seed: seeded Admin"))
+	var count int
+	is.NoErr(conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count))
+	is.Equal(count, 3)
+	// Seeding again without resetting conflicts with the existing rows
+	_, err = cli.Run(ctx, "db", "seed")
+	is.True(err != nil)
+	result, err = cli.Run(ctx, "db", "seed", "--reset")
+	is.NoErr(err)
+	is.NoErr(conn.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count))
+	is.Equal(count, 3)
+}
+
+func TestSeedNothing(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = "sqlite://" + filepath.Join(dir, "app.db")
+	_, err := cli.Run(ctx, "db", "seed")
+	is.True(err != nil)
+	is.In(err.Error(), "nothing to seed")
+}
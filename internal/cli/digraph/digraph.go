@@ -0,0 +1,156 @@
+package digraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/package/di"
+	"github.com/livebud/bud/package/dotenv"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+)
+
+// New command for bud di graph
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+// Command prints the dependency graph of the app's controllers, or of the
+// dependencies passed in, like "controller/posts.*Controller"
+type Command struct {
+	bud          *bud.Command
+	in           *bud.Input
+	Flag         *framework.Flag
+	Dependencies []string
+	Format       string
+}
+
+// Run the di graph command
+func (c *Command) Run(ctx context.Context) error {
+	if c.Format != "dot" && c.Format != "mermaid" {
+		return fmt.Errorf("di: unknown format %q. Expected dot or mermaid", c.Format)
+	}
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	// Generate the app, since controllers can depend on generated packages
+	fsys, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer fsys.Close()
+	parser := parser.New(fsys, module)
+	injector := di.New(fsys, log, module, parser)
+	env := envs.From(c.in.Env)
+	injector.Environment(dotenv.Environment(func(key string) string { return env[key] }, dotenv.Development))
+	// Wire the dependencies like the controller generator, so the graph shows
+	// what's created per request
+	fn := &di.Function{
+		Name:   "graph",
+		Target: module.Import("bud", "controller"),
+		Hoist:  true,
+		Params: []*di.Param{
+			{Import: "context", Type: "Context", Hoist: true},
+			{Import: "github.com/livebud/bud/package/log", Type: "Interface", Hoist: true},
+			{Import: "github.com/livebud/bud/package/reload", Type: "*Reloader", Hoist: true},
+			{Import: "net/http", Type: "*Request"},
+			{Import: "net/http", Type: "ResponseWriter"},
+		},
+		Aliases: di.Aliases{},
+	}
+	if len(c.Dependencies) == 0 {
+		fn.Results, err = controllers(fsys, parser, module)
+		if err != nil {
+			return err
+		}
+		if len(fn.Results) == 0 {
+			return fmt.Errorf("di: no controllers to graph. Pass the dependencies to graph, like controller/posts.*Controller")
+		}
+	}
+	for _, dependency := range c.Dependencies {
+		dep, err := toDependency(module, dependency)
+		if err != nil {
+			return err
+		}
+		fn.Results = append(fn.Results, dep)
+	}
+	node, err := injector.Load(fn)
+	if err != nil {
+		return err
+	}
+	if c.Format == "mermaid" {
+		fmt.Fprint(c.in.Stdout, node.Mermaid())
+		return nil
+	}
+	fmt.Fprint(c.in.Stdout, node.Print())
+	return nil
+}
+
+// controllers finds the controllers in the app
+func controllers(fsys fs.FS, parser *parser.Parser, module *gomod.Module) (deps []di.Dependency, err error) {
+	err = fs.WalkDir(fsys, "controller", func(dir string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if !de.IsDir() {
+			return nil
+		}
+		if _, err := parser.Import(dir); err != nil {
+			// Directories without Go files are still walked
+			return nil
+		}
+		pkg, err := parser.Parse(dir)
+		if err != nil {
+			return err
+		}
+		if pkg.Struct("Controller") == nil {
+			return nil
+		}
+		deps = append(deps, di.ToType(module.Import(dir), "*Controller"))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+// toDependency turns "<import or directory>.<type>" into a dependency
+func toDependency(module *gomod.Module, dependency string) (di.Dependency, error) {
+	i := strings.LastIndex(dependency, ".")
+	if i < 0 || i == len(dependency)-1 {
+		return nil, fmt.Errorf("di: expected a dependency like <import>.<type>, got %q", dependency)
+	}
+	importPath := strings.Trim(dependency[:i], `"`)
+	// Directories in the app are relative to the module
+	if _, err := os.Stat(module.Directory(importPath)); err == nil {
+		importPath = module.Import(path.Clean(importPath))
+	}
+	return di.ToType(importPath, dependency[i+1:]), nil
+}
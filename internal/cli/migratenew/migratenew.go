@@ -0,0 +1,40 @@
+package migratenew
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/package/migrate"
+)
+
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+type Command struct {
+	bud  *bud.Command
+	in   *bud.Input
+	Name string
+}
+
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	up, down, err := migrate.Create(module.Directory(migrate.Dir), c.Name, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{up, down} {
+		rel, err := filepath.Rel(module.Directory(), path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.in.Stdout, "created "+rel)
+	}
+	return nil
+}
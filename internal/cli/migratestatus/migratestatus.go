@@ -0,0 +1,48 @@
+package migratestatus
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/livebud/bud/internal/cli/bud"
+)
+
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+type Command struct {
+	bud *bud.Command
+	in  *bud.Input
+}
+
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	migrator, err := bud.Migrator(module, log, c.in.Env)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(c.in.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS")
+	for _, status := range statuses {
+		state := "pending"
+		if !status.Pending() {
+			state = "applied " + status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\n", status.Version, status.Name, state)
+	}
+	return tw.Flush()
+}
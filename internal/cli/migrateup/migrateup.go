@@ -0,0 +1,46 @@
+package migrateup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/package/migrate"
+)
+
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+type Command struct {
+	bud   *bud.Command
+	in    *bud.Input
+	Steps int
+}
+
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	migrator, err := bud.Migrator(module, log, c.in.Env)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	applied, err := migrator.Up(ctx, c.Steps)
+	for _, migration := range applied {
+		fmt.Fprintln(c.in.Stdout, "applied "+migrate.Filename(migration))
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Fprintln(c.in.Stdout, "no pending migrations")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package migrateup_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestMigrateUpDown(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["migrate/20221015120000_create_users.up.sql"] = `CREATE TABLE users (id integer primary key, name text)`
+	td.Files["migrate/20221015120000_create_users.down.sql"] = `DROP TABLE users`
+	td.Files["migrate/20221016120000_create_posts.up.sql"] = `CREATE TABLE posts (id integer primary key, title text)`
+	td.Files["migrate/20221016120000_create_posts.down.sql"] = `DROP TABLE posts`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = "sqlite://" + filepath.Join(dir, "app.db")
+	result, err := cli.Run(ctx, "db", "migrate", "up")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "applied 20221015120000_create_users\napplied 20221016120000_create_posts\n")
+	is.Equal(result.Stderr(), "")
+	result, err = cli.Run(ctx, "db", "migrate", "up")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "no pending migrations\n")
+	result, err = cli.Run(ctx, "db", "migrate", "down")
+	is.NoErr(err)
+	is.Equal(result.Stdout(), "reverted 20221016120000_create_posts\n")
+	result, err = cli.Run(ctx, "db", "migrate", "status")
+	is.NoErr(err)
+	is.In(result.Stdout(), "20221015120000   create_users   applied")
+	is.In(result.Stdout(), "20221016120000   create_posts   pending")
+}
+
+func TestMigrateMissingURL(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	_, err := cli.Run(ctx, "db", "migrate", "up")
+	is.True(err != nil)
+	is.In(err.Error(), "missing the DATABASE_URL environment variable")
+}
+
+func TestMigrateNew(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "db", "migrate", "new", "create_users")
+	is.NoErr(err)
+	is.In(result.Stdout(), "_create_users.up.sql")
+	is.In(result.Stdout(), "_create_users.down.sql")
+	matches, err := filepath.Glob(filepath.Join(dir, "migrate", "*_create_users.*.sql"))
+	is.NoErr(err)
+	is.Equal(len(matches), 2)
+}
@@ -0,0 +1,92 @@
+package newauth
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/internal/scaffold"
+	"github.com/livebud/bud/package/migrate"
+)
+
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{bud: bud, in: in}
+}
+
+type Command struct {
+	bud *bud.Command
+	in  *bud.Input
+}
+
+//go:embed template
+var templates embed.FS
+
+// State passed to the templates
+type State struct {
+	DB       string // Import path of the generated db package
+	Mailer   string // Import path of the mailer package
+	Model    string // Import path of the model package
+	Postgres bool   // Write migrations for Postgres instead of SQLite
+}
+
+func (c *Command) Run(ctx context.Context) error {
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	state := &State{
+		DB:       module.Import("bud/package/db"),
+		Mailer:   module.Import("mailer"),
+		Model:    module.Import("model"),
+		Postgres: !strings.HasPrefix(envs.From(c.in.Env)["DATABASE_URL"], "sqlite"),
+	}
+	version := time.Now().UTC().Format("20060102150405")
+	var scaffolds []scaffold.Scaffolding
+	var paths []string
+	err = fs.WalkDir(templates, "template", func(fpath string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() {
+			return err
+		}
+		code, err := fs.ReadFile(templates, fpath)
+		if err != nil {
+			return err
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(fpath, "template/"), ".gotext")
+		// Migrations are prefixed with their version
+		if dir, name := path.Split(rel); dir == migrate.Dir+"/" {
+			rel = dir + version + "_" + name
+		}
+		rel = filepath.FromSlash(rel)
+		scaffolds = append(scaffolds, scaffold.Template(rel, string(code), state))
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Check every file before writing, so we don't leave a partial scaffold
+	for _, rel := range paths {
+		if _, err := os.Stat(module.Directory(rel)); err == nil {
+			return fmt.Errorf("new auth: %q already exists", rel)
+		}
+	}
+	fsys := scaffold.MapFS{}
+	if err := scaffold.Scaffold(fsys, scaffolds...); err != nil {
+		return err
+	}
+	if err := scaffold.Write(fsys, module.Directory()); err != nil {
+		return err
+	}
+	for _, rel := range paths {
+		fmt.Fprintln(c.in.Stdout, "created "+rel)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package newauth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestNewAuth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	cli.Env["DATABASE_URL"] = "sqlite://app.db"
+	result, err := cli.Run(ctx, "new", "auth")
+	is.NoErr(err)
+	is.Equal(result.Stderr(), "")
+	is.In(result.Stdout(), "created controller/signup/controller.go\n")
+	is.NoErr(td.Exists(
+		"model/user.go",
+		"query/auth.sql",
+		"mailer/mailer.go",
+		"middleware/middleware.go",
+		"controller/signup/controller.go",
+		"controller/login/controller.go",
+		"controller/logout/controller.go",
+		"controller/verify/controller.go",
+		"controller/account/controller.go",
+		"controller/oauth/controller.go",
+		"controller/oauth/callback/controller.go",
+		"model/identity.go",
+		"controller/keys/controller.go",
+		"model/api_key.go",
+		"view/keys/index.svelte",
+		"view/signup/new.svelte",
+		"view/login/new.svelte",
+	))
+	// Migrations are written for the database in $DATABASE_URL
+	for _, line := range strings.Split(result.Stdout(), "\n") {
+		if strings.HasSuffix(line, "_create_users.up.sql") {
+			is.NoErr(td.Exists(strings.TrimPrefix(line, "created ")))
+		}
+	}
+	// Scaffolding twice doesn't overwrite anything
+	_, err = cli.Run(ctx, "new", "auth")
+	is.True(err != nil)
+	is.In(err.Error(), `"controller/account/controller.go" already exists`)
+}
@@ -1,11 +1,19 @@
 package run
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/fs"
 	"net"
+	"net/http"
+	"net/url"
+	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"golang.org/x/sync/errgroup"
@@ -14,16 +22,26 @@ import (
 	"github.com/livebud/bud/framework/web/webrt"
 	"github.com/livebud/bud/internal/bfs"
 	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/devproxy"
+	"github.com/livebud/bud/internal/envs"
 	"github.com/livebud/bud/internal/exe"
 	"github.com/livebud/bud/internal/extrafile"
+	"github.com/livebud/bud/internal/gitignore"
 	"github.com/livebud/bud/internal/gobuild"
+	"github.com/livebud/bud/internal/hashindex"
+	"github.com/livebud/bud/internal/overlay"
 	"github.com/livebud/bud/internal/prompter"
 	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/internal/qrcode"
+	"github.com/livebud/bud/internal/trace"
+	"github.com/livebud/bud/internal/urlx"
 	"github.com/livebud/bud/internal/versions"
+	"github.com/livebud/bud/package/budhttp"
 	"github.com/livebud/bud/package/budhttp/budsvr"
 	"github.com/livebud/bud/package/gomod"
 	v8 "github.com/livebud/bud/package/js/v8"
 	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/migrate"
 	"github.com/livebud/bud/package/socket"
 	"github.com/livebud/bud/package/watcher"
 )
@@ -48,12 +66,26 @@ type Command struct {
 	in  *bud.Input
 
 	// Flags
-	Flag   *framework.Flag
-	Listen string // Web listener address
+	Flag    *framework.Flag
+	Listen  string // Web listener address
+	Migrate bool   // Apply pending migrations on boot
+	Watch   string // How to watch for changes: auto, native or poll
+	Profile bool   // Write a timeline of each build to bud/profile.json
+	QR      bool   // Print a QR code to open the app on another device
+	Proxy   string // Frontend dev server for requests the app doesn't handle
 }
 
 // Run the run command. That's a mouthful.
 func (c *Command) Run(ctx context.Context) (err error) {
+	watch, err := watchFunc(c.Watch)
+	if err != nil {
+		return err
+	}
+	if c.Proxy != "" {
+		if u, err := url.Parse(c.Proxy); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("run: invalid --proxy %q. Expected a URL like http://localhost:5173", c.Proxy)
+		}
+	}
 	// Find go.mod
 	module, err := bud.Module(c.bud.Dir)
 	if err != nil {
@@ -82,20 +114,45 @@ func (c *Command) Run(ctx context.Context) (err error) {
 			return err
 		}
 		defer webln.Close()
-		log.Info("Listening on http://" + webln.Addr().String())
+		if requested, actual := port(c.Listen), port(webln.Addr().String()); requested != "" && requested != actual {
+			log.Info(fmt.Sprintf("Port %s is in use, using %s instead", requested, actual))
+		}
+		log.Info("Listening on " + localURL(webln))
+		if networkURL := networkURL(webln); networkURL != "" {
+			log.Info("On your network at " + networkURL)
+			if c.QR {
+				if code, err := qrcode.Encode(networkURL); err == nil {
+					fmt.Fprint(c.in.Stderr, code.String())
+				}
+			}
+		} else if c.QR {
+			log.Warn("run: other devices can't reach this machine's loopback address. Try listening on --listen=0.0.0.0:" + port(webln.Addr().String()))
+		}
 	}
 	// Setup the default terminal prompter state
-	prompter.Init(webrt.Format(webln))
+	prompter.Init(localURL(webln))
 	// Setup the bud listener
 	budln := c.in.BudLn
 	if budln == nil {
-		budln, err = socket.Listen(":35729")
+		// Another app may already be running, so move up in that case too
+		budln, err = socket.ListenUp(":35729", 10)
 		if err != nil {
 			return err
 		}
 		defer budln.Close()
 		log.Debug("run: bud server is listening", "url", "http://"+budln.Addr().String())
 	}
+	// Pages connect to the bud server for live reloads
+	if addr := hotAddr(budln); addr != "" {
+		c.Flag.HotAddr = addr
+	}
+	// Record where the time goes during each build
+	var recorder *trace.Recorder
+	if c.Profile {
+		recorder = trace.New()
+		c.Flag.Trace = recorder
+		log.Info("Writing build profiles to " + filepath.Join("bud", "profile.json"))
+	}
 	// Load the generator filesystem
 	bfs, err := bfs.Load(c.Flag, log, module)
 	if err != nil {
@@ -110,9 +167,12 @@ func (c *Command) Run(ctx context.Context) (err error) {
 	// Initialize the bud server
 	budServer := &budServer{
 		budln: budln,
+		// Collect stats before the first build starts
+		stats: budsvr.Collect(bus),
 		bus:   bus,
 		fsys:  bfs,
 		log:   log,
+		trace: recorder,
 	}
 	// Setup the starter command
 	starter := &exe.Command{
@@ -125,23 +185,52 @@ func (c *Command) Run(ctx context.Context) (err error) {
 			"BUD_LISTEN="+budln.Addr().String(),
 		),
 	}
-	// Get the file descriptor for the web listener
-	webFile, err := webln.File()
+	if c.Proxy != "" {
+		starter.Env = append(starter.Env, "BUD_PROXY="+c.Proxy)
+	}
+	// The app listens privately behind a proxy that replays the requests cut off
+	// by restarts
+	appln, err := socket.Listen("127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	defer appln.Close()
+	proxy := devproxy.New(log, appln.Addr().String())
+	// Get the file descriptor for the app listener
+	appFile, err := appln.File()
 	if err != nil {
 		return err
 	}
 	// Inject that file into the starter's extrafiles
-	extrafile.Inject(&starter.ExtraFiles, &starter.Env, "WEB", webFile)
+	extrafile.Inject(&starter.ExtraFiles, &starter.Env, "WEB", appFile)
+	if c.Watch == "poll" || (c.Watch != "native" && watcher.Unreliable(module.Directory())) {
+		log.Debug("run: polling for changes", "dir", module.Directory())
+	}
+	// Load the index of file hashes from the last run
+	index, err := hashindex.Load(module.Directory("bud", ".cache", "hashindex"))
+	if err != nil {
+		return err
+	}
+	builder := gobuild.New(module)
+	builder.Trace = recorder
 	// Initialize the app server
 	appServer := &appServer{
 		dir:      module.Directory(),
-		builder:  gobuild.New(module),
+		appln:    appln,
+		proxy:    proxy,
+		hotURL:   hotURL(budln),
+		builder:  builder,
 		prompter: &prompter,
 		bus:      bus,
 		bfs:      bfs,
 		log:      log,
 		module:   module,
+		index:    index,
 		starter:  starter,
+		env:      c.in.Env,
+		migrate:  c.Migrate,
+		watch:    watch,
+		trace:    recorder,
 	}
 	// Start the servers
 	eg, ctx := errgroup.WithContext(ctx)
@@ -149,6 +238,8 @@ func (c *Command) Run(ctx context.Context) (err error) {
 	eg.Go(func() error { return budServer.Run(ctx) })
 	// Start the internal app server
 	eg.Go(func() error { return appServer.Run(ctx) })
+	// Proxy requests from the browser to the app
+	eg.Go(func() error { return webrt.Serve(ctx, webln, proxy) })
 	// Wait until either the hot or web server exits
 	err = eg.Wait()
 	log.Debug("run: command finished", "err", err)
@@ -158,18 +249,22 @@ func (c *Command) Run(ctx context.Context) (err error) {
 // budServer runs the bud development server
 type budServer struct {
 	budln net.Listener
+	stats *budsvr.Collector
 	bus   pubsub.Client
 	fsys  fs.FS
 	log   log.Interface
+	trace *trace.Recorder
 }
 
 // Run the bud server
 func (s *budServer) Run(ctx context.Context) error {
+	end := s.trace.Start("v8", "load")
 	vm, err := v8.Load()
+	end()
 	if err != nil {
 		return err
 	}
-	devServer := budsvr.New(s.fsys, s.bus, s.log, vm)
+	devServer := budsvr.New(s.fsys, s.bus, s.log, vm, budsvr.WithStats(s.stats))
 	err = webrt.Serve(ctx, s.budln, devServer)
 	s.log.Debug("run: bud server closed", "err", err)
 	return err
@@ -178,25 +273,38 @@ func (s *budServer) Run(ctx context.Context) error {
 // appServer runs the generated web application
 type appServer struct {
 	dir      string
+	appln    socket.Listener // Private listener for the app, behind the proxy
+	proxy    *devproxy.Proxy
+	hotURL   string
 	builder  *gobuild.Builder
 	prompter *prompter.Prompter
 	bus      pubsub.Client
 	bfs      *bfs.FS
 	log      log.Interface
 	module   *gomod.Module
+	index    *hashindex.Index
 	starter  *exe.Command
+	env      []string
+	migrate  bool
+	watch    func(ctx context.Context, dir string, fn func(events []watcher.Event) error) error
+	overlay  *http.Server    // Serves the last build error, if any
+	trace    *trace.Recorder // Nil unless profiling
 }
 
 // Run the app server
 func (a *appServer) Run(ctx context.Context) error {
-	// Generate the app
-	if err := a.bfs.Sync(); err != nil {
+	defer a.hideError()
+	// Include the files generated since the last build, like views that
+	// were bundled when the page reloaded
+	defer a.writeProfile()
+	// Generate and build the app
+	if err := a.build(ctx); err != nil {
 		a.bus.Publish("app:error", []byte(err.Error()))
 		a.log.Debug("run: published event", "event", "app:error")
 		return err
 	}
-	// Build the app
-	if err := a.builder.Build(ctx, "bud/internal/app/main.go", "bud/app"); err != nil {
+	// Check for pending migrations
+	if err := a.checkMigrations(ctx); err != nil {
 		a.bus.Publish("app:error", []byte(err.Error()))
 		a.log.Debug("run: published event", "event", "app:error")
 		return err
@@ -208,8 +316,27 @@ func (a *appServer) Run(ctx context.Context) error {
 		a.log.Debug("run: published event", "event", "app:error")
 		return err
 	}
+	// Index the files we're about to watch, so changes can be told apart from
+	// files that were only touched
+	ignore := gitignore.From(a.dir)
+	if err := a.index.Refresh(a.module, func(path string, isDir bool) bool { return path == "bud" || ignore(path) }); err != nil {
+		return err
+	}
+	if err := a.index.Save(); err != nil {
+		a.log.Debug("run: unable to save the hash index", "err", err)
+	}
+	a.publishWatching()
+	// Bundle node_modules in the background while the browser loads
+	go a.prebundle(ctx)
 	// Watch for changes
-	return watcher.Watch(ctx, a.dir, catchError(a.prompter, func(events []watcher.Event) error {
+	return a.watch(ctx, a.dir, catchError(a.prompter, func(events []watcher.Event) error {
+		events, err := a.changed(events)
+		if err != nil {
+			return err
+		} else if len(events) == 0 {
+			a.log.Debug("run: files were touched without changing")
+			return nil
+		}
 		// Trigger reloading
 		a.prompter.Reloading(events)
 		// Inform the bud filesystem of the changes
@@ -219,12 +346,22 @@ func (a *appServer) Run(ctx context.Context) error {
 			changes[i] = event.Path
 		}
 		a.bfs.Change(changes...)
-		// Check if we can incrementally reload
-		if canIncrementallyReload(events) {
+		a.publishChanges(changes)
+		a.publishWatching()
+		// Check if we can incrementally reload. After a failed build, the app
+		// needs to be rebuilt.
+		if a.overlay == nil && canIncrementallyReload(events) {
 			a.log.Debug("run: incrementally reloading")
-			// Publish the frontend:update event
-			a.bus.Publish("frontend:update", nil)
-			a.log.Debug("run: published event", "event", "frontend:update")
+			// The generated frontend files changed, so announce a new build
+			a.publishBuild()
+			if stylesheets := stylesheets(events); len(stylesheets) == len(events) {
+				// Only stylesheets changed, so swap them without reloading the page
+				a.publishStylesheets(stylesheets)
+			} else {
+				// Publish the frontend:update event
+				a.bus.Publish("frontend:update", nil)
+				a.log.Debug("run: published event", "event", "frontend:update")
+			}
 			// Publish the app:ready event
 			a.bus.Publish("app:ready", nil)
 			a.log.Debug("run: published event", "event", "app:ready")
@@ -233,17 +370,20 @@ func (a *appServer) Run(ctx context.Context) error {
 		}
 		now := time.Now()
 		a.log.Debug("run: restarting the process")
+		// Hold new requests and cut off the ones in flight, so the process stops
+		// right away. They're replayed once the new process is ready.
+		a.proxy.Restarting()
+		defer a.proxy.Ready()
 		if err := process.Close(); err != nil {
 			return err
 		}
+		// Stop showing the last build error, so requests wait for the new build
+		a.hideError()
 		a.bus.Publish("backend:update", nil)
 		a.log.Debug("run: published event", "event", "backend:update")
-		// Generate the app
-		if err := a.bfs.Sync(); err != nil {
-			return err
-		}
-		// Build the app
-		if err := a.builder.Build(ctx, "bud/internal/app/main.go", "bud/app"); err != nil {
+		// Generate and build the app
+		if err := a.build(ctx); err != nil {
+			a.showError(err)
 			return err
 		}
 		// Restart the process
@@ -260,6 +400,304 @@ func (a *appServer) Run(ctx context.Context) error {
 	}))
 }
 
+// watchFunc returns the watcher for the --watch flag
+func watchFunc(mode string) (func(ctx context.Context, dir string, fn func(events []watcher.Event) error) error, error) {
+	switch mode {
+	case "", "auto":
+		return watcher.Watch, nil
+	case "native":
+		return watcher.Native, nil
+	case "poll":
+		return watcher.Poll, nil
+	default:
+		return nil, fmt.Errorf("run: unknown --watch %q. Use auto, native or poll", mode)
+	}
+}
+
+// changed filters out updates to files whose contents didn't change
+func (a *appServer) changed(events []watcher.Event) (changes []watcher.Event, err error) {
+	for _, event := range events {
+		changed, err := a.index.Changed(a.module, filepath.ToSlash(event.Path))
+		if err != nil {
+			return nil, err
+		}
+		// Creates and deletes are always changes, but they still update the index
+		if event.Op == watcher.OpUpdate && !changed {
+			continue
+		}
+		changes = append(changes, event)
+	}
+	if err := a.index.Save(); err != nil {
+		a.log.Debug("run: unable to save the hash index", "err", err)
+	}
+	return changes, nil
+}
+
+// build generates and builds the app, publishing build events along the way
+func (a *appServer) build(ctx context.Context) error {
+	a.bus.Publish("build:start", nil)
+	a.log.Debug("run: published event", "event", "build:start")
+	defer a.writeProfile()
+	defer a.trace.Start("build", "build")()
+	// Generate the app
+	endSync := a.trace.Start("build", "generate")
+	err := a.bfs.Sync()
+	endSync()
+	if err != nil {
+		a.bus.Publish("build:error", []byte(err.Error()))
+		a.log.Debug("run: published event", "event", "build:error")
+		return err
+	}
+	a.publishTimings()
+	// Build the app
+	if err := a.builder.Build(ctx, "bud/internal/app/main.go", "bud/app"); err != nil {
+		a.bus.Publish("build:error", []byte(err.Error()))
+		a.log.Debug("run: published event", "event", "build:error")
+		return err
+	}
+	a.publishBuild()
+	return nil
+}
+
+// prebundle node_modules into bud/.cache, so the first page load doesn't wait
+// on esbuild. Bundles are reused until package.json or a lockfile changes.
+func (a *appServer) prebundle(ctx context.Context) {
+	now := time.Now()
+	defer a.trace.Start("build", "prebundle")()
+	bundled, err := a.bfs.Prebundle(trace.With(ctx, a.trace))
+	if err != nil {
+		a.log.Debug("run: unable to prebundle node_modules", "err", err)
+		return
+	}
+	a.log.Debug("run: prebundled node_modules", "bundled", bundled, "in", time.Since(now))
+}
+
+// writeProfile writes the timeline of the builds so far to bud/profile.json
+func (a *appServer) writeProfile() {
+	if a.trace == nil {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if err := a.trace.Write(buf); err != nil {
+		a.log.Error("run: unable to write the profile", "err", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, "bud", "profile.json"), buf.Bytes(), 0644); err != nil {
+		a.log.Error("run: unable to write the profile", "err", err)
+		return
+	}
+	a.log.Debug("run: wrote the profile", "path", filepath.Join("bud", "profile.json"))
+}
+
+// checkMigrations warns about pending migrations or applies them if the
+// migrate flag is set. Nothing happens when the app doesn't have a migrate/
+// directory or $DATABASE_URL isn't set, either in the environment or in the
+// .env files.
+func (a *appServer) checkMigrations(ctx context.Context) error {
+	if _, err := fs.Stat(a.module, migrate.Dir); err != nil {
+		return nil
+	}
+	env, err := bud.Env(a.module, a.env)
+	if err != nil {
+		return err
+	}
+	if envs.From(env)["DATABASE_URL"] == "" {
+		return nil
+	}
+	migrator, err := bud.Migrator(a.module, a.log, a.env)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	if a.migrate {
+		applied, err := migrator.Up(ctx, 0)
+		if err != nil {
+			return err
+		}
+		for _, migration := range applied {
+			a.log.Info("run: applied migration " + migrate.Filename(migration))
+		}
+		return nil
+	}
+	pending, err := migrator.Pending(ctx)
+	if err != nil {
+		// Don't block booting if we can't reach the database
+		a.log.Warn("run: unable to check for pending migrations", "err", err)
+		return nil
+	}
+	if len(pending) > 0 {
+		a.log.Warn(fmt.Sprintf("run: %d pending migrations. Run `bud db migrate up` to apply them", len(pending)))
+	}
+	return nil
+}
+
+// showError serves the build error in the browser until the next build, since
+// there's no app running to serve requests
+func (a *appServer) showError(err error) {
+	a.hideError()
+	file, ferr := a.appln.File()
+	if ferr != nil {
+		a.log.Debug("run: unable to show the build error in the browser", "err", ferr)
+		return
+	}
+	// Serve from a copy of the listener, so closing the overlay doesn't close
+	// the listener that's passed to the app
+	ln, ferr := net.FileListener(file)
+	file.Close()
+	if ferr != nil {
+		a.log.Debug("run: unable to show the build error in the browser", "err", ferr)
+		return
+	}
+	a.overlay = &http.Server{Handler: overlay.Handler(a.dir, err, a.hotURL)}
+	go a.overlay.Serve(ln)
+	a.log.Debug("run: showing the build error in the browser")
+}
+
+// hideError stops serving the last build error
+func (a *appServer) hideError() {
+	if a.overlay == nil {
+		return
+	}
+	if err := a.overlay.Close(); err != nil {
+		a.log.Debug("run: unable to close the build error overlay", "err", err)
+	}
+	a.overlay = nil
+}
+
+// hotURL returns the URL that pages subscribe to for changes. Browsers can't
+// subscribe over unix sockets.
+func hotURL(budln net.Listener) string {
+	addr := hotAddr(budln)
+	if addr == "" {
+		return ""
+	}
+	return "http://" + addr + "/bud/events"
+}
+
+// hotAddr returns the address pages reach the bud server on, e.g.
+// "127.0.0.1:35729". Servers listening on every interface are reached on
+// 127.0.0.1.
+func hotAddr(budln net.Listener) string {
+	if budln.Addr().Network() == "unix" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(budln.Addr().String())
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// port returns the port of the address or an empty string for unix sockets
+func port(address string) string {
+	u, err := urlx.Parse(address)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return u.Port()
+}
+
+// localURL is the clickable URL to open the app with on this machine
+func localURL(ln net.Listener) string {
+	if ln.Addr().Network() == "unix" {
+		return ln.Addr().String()
+	}
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		return webrt.Format(ln)
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// networkURL is the URL to open the app with from other devices on the same
+// network. It's empty when the app only listens on the loopback interface.
+func networkURL(ln net.Listener) string {
+	if ln.Addr().Network() == "unix" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		return ""
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() {
+		return ""
+	} else if !ip.IsUnspecified() {
+		return "http://" + net.JoinHostPort(host, port)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		ipnet, ok := addr.(*net.IPNet)
+		if !ok || ipnet.IP.IsLoopback() || ipnet.IP.To4() == nil {
+			continue
+		}
+		return "http://" + net.JoinHostPort(ipnet.IP.String(), port)
+	}
+	return ""
+}
+
+// publishBuild announces a new build ID. Clients cache responses from the bud
+// server until the next build is announced.
+func (a *appServer) publishBuild() {
+	buildID := strconv.FormatInt(time.Now().UnixNano(), 36)
+	a.bus.Publish("build:finish", []byte(buildID))
+	a.log.Debug("run: published event", "event", "build:finish", "id", buildID)
+}
+
+// publishChanges publishes the changed paths as a JSON array
+func (a *appServer) publishChanges(paths []string) {
+	data, err := json.Marshal(paths)
+	if err != nil {
+		a.log.Error("run: unable to marshal changed paths", "err", err)
+		return
+	}
+	a.bus.Publish("file:change", data)
+	a.log.Debug("run: published event", "event", "file:change")
+}
+
+// publishTimings publishes how long each generator took as a JSON array
+func (a *appServer) publishTimings() {
+	timings := a.bfs.Timings()
+	generators := make([]*budhttp.GeneratorStat, len(timings))
+	for i, timing := range timings {
+		generators[i] = &budhttp.GeneratorStat{Path: timing.Path, Duration: timing.Duration}
+	}
+	data, err := json.Marshal(generators)
+	if err != nil {
+		a.log.Error("run: unable to marshal generator timings", "err", err)
+		return
+	}
+	a.bus.Publish("build:timings", data)
+	a.log.Debug("run: published event", "event", "build:timings")
+}
+
+// publishWatching publishes the number of files being watched
+func (a *appServer) publishWatching() {
+	a.bus.Publish("watch:files", []byte(strconv.Itoa(a.index.Len())))
+	a.log.Debug("run: published event", "event", "watch:files")
+}
+
+// publishStylesheets publishes the routes of the changed stylesheets as a JSON
+// array
+func (a *appServer) publishStylesheets(routes []string) {
+	data, err := json.Marshal(routes)
+	if err != nil {
+		a.log.Error("run: unable to marshal changed stylesheets", "err", err)
+		return
+	}
+	a.bus.Publish("frontend:update:css", data)
+	a.log.Debug("run: published event", "event", "frontend:update:css")
+}
+
 // logWrap wraps the watch function in a handler that logs the error instead of
 // returning the error (and canceling the watcher)
 func catchError(prompter *prompter.Prompter, fn func(events []watcher.Event) error) func(events []watcher.Event) error {
@@ -274,9 +712,26 @@ func catchError(prompter *prompter.Prompter, fn func(events []watcher.Event) err
 // canIncrementallyReload returns true if we can incrementally reload a page
 func canIncrementallyReload(events []watcher.Event) bool {
 	for _, event := range events {
-		if event.Op != watcher.OpUpdate || filepath.Ext(event.Path) == ".go" {
+		if event.Op != watcher.OpUpdate || filepath.Ext(event.Path) == ".go" || isQuery(event.Path) {
 			return false
 		}
 	}
 	return true
 }
+
+// stylesheets returns the routes of the public stylesheets that were updated
+func stylesheets(events []watcher.Event) (routes []string) {
+	for _, event := range events {
+		path := filepath.ToSlash(event.Path)
+		if event.Op != watcher.OpUpdate || !strings.HasPrefix(path, "public/") || filepath.Ext(path) != ".css" {
+			continue
+		}
+		routes = append(routes, strings.TrimPrefix(path, "public"))
+	}
+	return routes
+}
+
+// isQuery returns true for SQL files that generate Go code
+func isQuery(path string) bool {
+	return filepath.Dir(path) == "query" && filepath.Ext(path) == ".sql"
+}
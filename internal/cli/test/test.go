@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/coverage"
+	"github.com/livebud/bud/internal/envs"
+	"github.com/livebud/bud/internal/versions"
+	"github.com/livebud/bud/package/browser"
+	"github.com/livebud/bud/package/dotenv"
+	"github.com/livebud/bud/package/gomod"
+)
+
+// New command for bud test
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+// Command for running bud test
+type Command struct {
+	bud          *bud.Command
+	in           *bud.Input
+	Flag         *framework.Flag
+	Packages     []string
+	Pattern      string
+	Verbose      bool
+	CoverProfile string
+	Browser      bool
+}
+
+// Run generates the app, then tests its packages with "go test". The
+// generated packages in bud/ aren't tested and are left out of the coverage.
+//
+// With a cover profile, apps started by budtest.Start are built with coverage
+// too, and their coverage is merged into the profile alongside the tests'.
+//
+// With the browser flag, a headless browser is launched for the tests to share.
+// Browser tests are skipped without it.
+func (c *Command) Run(ctx context.Context) error {
+	// Find go.mod
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	// Ensure we have version alignment between the CLI and the runtime
+	if err := bud.EnsureVersionAlignment(ctx, module, versions.Bud); err != nil {
+		return err
+	}
+	// Setup the logger
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	// Test in the test environment, which picks the .env.test file and the
+	// bindings in inject/test, unless $BUD_ENV says otherwise
+	env := append([]string{}, c.in.Env...)
+	if envs.From(env)["BUD_ENV"] == "" {
+		env = append(env, "BUD_ENV="+dotenv.Test)
+		c.Flag.Env = append(append([]string{}, c.Flag.Env...), "BUD_ENV="+dotenv.Test)
+	}
+	// Generate the application, since the app's packages import it
+	bfs, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer bfs.Close()
+	if err := bfs.Sync(); err != nil {
+		return err
+	}
+	packages, err := coverage.Packages(ctx, module, c.in.Env, c.Packages...)
+	if err != nil {
+		return err
+	}
+	if len(packages) == 0 {
+		return fmt.Errorf("test: no packages to test")
+	}
+	args := []string{"test", "-mod=mod"}
+	if c.Verbose {
+		args = append(args, "-v")
+	}
+	if c.Pattern != "" {
+		args = append(args, "-run="+c.Pattern)
+	}
+	var coverDir string
+	if c.CoverProfile != "" {
+		coverDir, err = os.MkdirTemp("", "bud-cover-*")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(coverDir)
+		// Cover all of the app's packages, not just the package being tested
+		all, err := coverage.Packages(ctx, module, c.in.Env)
+		if err != nil {
+			return err
+		}
+		appDir := filepath.Join(coverDir, "app")
+		if err := os.Mkdir(appDir, 0755); err != nil {
+			return err
+		}
+		args = append(args,
+			"-covermode="+coverage.Mode,
+			"-coverpkg="+strings.Join(all, ","),
+			"-coverprofile="+filepath.Join(coverDir, "test.out"),
+		)
+		env = append(env, "BUD_COVERDIR="+appDir)
+	}
+	if c.Browser {
+		browser, err := browser.Launch(ctx)
+		if err != nil {
+			return err
+		}
+		defer browser.Close()
+		env = append(env, "BUD_BROWSER="+browser.URL())
+	}
+	cmd := exec.CommandContext(ctx, "go", append(args, packages...)...)
+	cmd.Dir = module.Directory()
+	cmd.Env = append(env, "GOMODCACHE="+module.ModCache())
+	cmd.Stdin = c.in.Stdin
+	cmd.Stdout = c.in.Stdout
+	cmd.Stderr = c.in.Stderr
+	testErr := cmd.Run()
+	// Write the coverage even when tests fail
+	if c.CoverProfile != "" {
+		if err := c.writeProfile(ctx, module, coverDir); err != nil {
+			return err
+		}
+	}
+	return testErr
+}
+
+// writeProfile merges the coverage of the tests and the apps they started
+func (c *Command) writeProfile(ctx context.Context, module *gomod.Module, coverDir string) error {
+	profiles := []string{filepath.Join(coverDir, "test.out")}
+	appProfile := filepath.Join(coverDir, "app.out")
+	ok, err := coverage.Convert(ctx, filepath.Join(coverDir, "app"), appProfile)
+	if err != nil {
+		return err
+	} else if ok {
+		profiles = append(profiles, appProfile)
+	}
+	var readers []io.Reader
+	for _, profile := range profiles {
+		file, err := os.Open(profile)
+		if err != nil {
+			// Tests that don't compile don't write a profile
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		defer file.Close()
+		readers = append(readers, file)
+	}
+	path := c.CoverProfile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(c.bud.Dir, path)
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("test: unable to create the cover profile. %w", err)
+	}
+	defer out.Close()
+	if err := coverage.Merge(out, module, readers...); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -2,15 +2,21 @@ package toolbs
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
 
 	"github.com/livebud/bud/framework"
 	"github.com/livebud/bud/framework/web/webrt"
 	"github.com/livebud/bud/internal/bfs"
 	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/envs"
 	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/package/budhttp/budgrpc"
 	"github.com/livebud/bud/package/budhttp/budsvr"
 	v8 "github.com/livebud/bud/package/js/v8"
 	"github.com/livebud/bud/package/socket"
+	"golang.org/x/sync/errgroup"
 )
 
 func New(bud *bud.Command, in *bud.Input) *Command {
@@ -30,6 +36,13 @@ type Command struct {
 	bud  *bud.Command
 	in   *bud.Input
 	Flag *framework.Flag
+
+	// Flags
+	Listen  string // Bud server address
+	TLSCert string // Path to a PEM-encoded certificate (optional)
+	TLSKey  string // Path to a PEM-encoded private key (optional)
+	GRPC    string // Also serve gRPC on this address (optional)
+	Token   string // Bearer token that clients must send (optional)
 }
 
 func (c *Command) Run(ctx context.Context) error {
@@ -50,13 +63,72 @@ func (c *Command) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	bus := pubsub.New()
-	server := budsvr.New(bfs, bus, log, vm)
-	budln, err := socket.Listen(":35729")
+	var budln net.Listener
+	budln, err = socket.Listen(c.Listen)
 	if err != nil {
 		return err
 	}
 	defer budln.Close()
-	log.Info("Listening on http://127.0.0.1:35729")
-	return webrt.Serve(ctx, budln, server)
+	// Anyone who can reach the bud server can read files and evaluate scripts,
+	// so require a token from clients beyond this machine
+	token := c.Token
+	if token == "" {
+		token = envs.From(c.in.Env)["BUD_TOKEN"]
+	}
+	if token == "" && !isLocal(budln) {
+		return fmt.Errorf("toolbs: unable to listen on %s without a --token or $BUD_TOKEN for apps to send", budln.Addr())
+	}
+	// Serve the same files, views and events over gRPC, so apps can connect
+	// with BUD_LISTEN=grpc://<address>
+	var grpcln net.Listener
+	if c.GRPC != "" {
+		grpcln, err = socket.Listen(c.GRPC)
+		if err != nil {
+			return err
+		}
+		defer grpcln.Close()
+		if !isLocal(grpcln) {
+			return fmt.Errorf("toolbs: unable to serve grpc on %s. Grpc doesn't authenticate clients, so it only listens on loopback or unix sockets", grpcln.Addr())
+		}
+	}
+	bus := pubsub.New()
+	server := budsvr.New(bfs, bus, log, vm, budsvr.WithToken(token))
+	// Serve over TLS so apps on other machines can connect securely
+	if c.TLSCert != "" || c.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(c.TLSCert, c.TLSKey)
+		if err != nil {
+			return fmt.Errorf("toolbs: unable to load tls certificate. %w", err)
+		}
+		budln = tls.NewListener(budln, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+		log.Info("Listening on https://" + budln.Addr().String())
+	} else {
+		if !isLocal(budln) {
+			log.Warn("toolbs: serving without tls sends the token in plain text. Pass --tls-cert and --tls-key to encrypt it")
+		}
+		log.Info("Listening on http://" + budln.Addr().String())
+	}
+	eg, ctx := errgroup.WithContext(ctx)
+	if grpcln != nil {
+		grpcServer := budgrpc.New(bfs, bus, log, vm)
+		log.Info("Listening on grpc://" + grpcln.Addr().String())
+		eg.Go(func() error { return budgrpc.Serve(ctx, grpcln, grpcServer) })
+	}
+	eg.Go(func() error { return webrt.Serve(ctx, budln, server) })
+	return eg.Wait()
+}
+
+// isLocal returns true when only this machine can reach the listener
+func isLocal(ln net.Listener) bool {
+	if ln.Addr().Network() == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
@@ -0,0 +1,76 @@
+package work
+
+import (
+	"context"
+
+	"github.com/livebud/bud/framework"
+	"github.com/livebud/bud/internal/bfs"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/exe"
+	"github.com/livebud/bud/internal/gobuild"
+	"github.com/livebud/bud/internal/versions"
+)
+
+// New command for bud work
+func New(bud *bud.Command, in *bud.Input) *Command {
+	return &Command{
+		bud: bud,
+		in:  in,
+		Flag: &framework.Flag{
+			Env:    in.Env,
+			Stderr: in.Stderr,
+			Stdin:  in.Stdin,
+			Stdout: in.Stdout,
+		},
+	}
+}
+
+// Command for running bud work
+type Command struct {
+	bud  *bud.Command
+	in   *bud.Input
+	Flag *framework.Flag
+}
+
+// Run builds the app, then runs its jobs and scheduled tasks without serving
+// requests
+func (c *Command) Run(ctx context.Context) error {
+	// Find go.mod
+	module, err := bud.Module(c.bud.Dir)
+	if err != nil {
+		return err
+	}
+	// Ensure we have version alignment between the CLI and the runtime
+	if err := bud.EnsureVersionAlignment(ctx, module, versions.Bud); err != nil {
+		return err
+	}
+	// Setup the logger
+	log, err := bud.Log(c.in.Stderr, c.bud.Log)
+	if err != nil {
+		return err
+	}
+	bfs, err := bfs.Load(c.Flag, log, module)
+	if err != nil {
+		return err
+	}
+	defer bfs.Close()
+	// Generate the application
+	if err := bfs.Sync(); err != nil {
+		return err
+	}
+	builder := gobuild.New(module)
+	builder.Env = c.in.Env
+	builder.Stderr = c.in.Stderr
+	builder.Stdout = c.in.Stdout
+	if err := builder.Build(ctx, "bud/internal/app/main.go", "bud/app"); err != nil {
+		return err
+	}
+	// Interrupt the app when the context is canceled, so running work finishes
+	cmd := &exe.Command{
+		Dir:    module.Directory(),
+		Env:    c.in.Env,
+		Stdout: c.in.Stdout,
+		Stderr: c.in.Stderr,
+	}
+	return cmd.Run(ctx, module.Directory("bud", "app"), "work", "--log", c.bud.Log)
+}
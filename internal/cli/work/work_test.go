@@ -0,0 +1,50 @@
+package work_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/cli/testcli"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+)
+
+func TestNothingToWorkOn(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	result, err := cli.Run(ctx, "work")
+	is.True(err != nil)
+	is.In(result.Stderr(), "webrt: nothing to work on. Add jobs to job/, tasks to schedule/ or subscribers to event/")
+	is.NoErr(td.Exists("bud/app"))
+}
+
+func TestWork(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["schedule/tick.go"] = `
+		package schedule
+		import (
+			"context"
+			"errors"
+		)
+		//schedule:cron @every 1s
+		func Tick(ctx context.Context) error {
+			return errors.New("ticked")
+		}
+	`
+	is.NoErr(td.Write(ctx))
+	cli := testcli.New(dir)
+	// Work until the context is canceled
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, _ := cli.Run(ctx, "work")
+	is.In(result.Stderr(), "ticked")
+	is.NoErr(td.Exists("bud/app"))
+}
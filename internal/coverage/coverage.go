@@ -0,0 +1,162 @@
+// Package coverage measures the coverage of the app's own code. Generated
+// packages in bud/ are left out, so coverage reports only reflect the code
+// you wrote.
+package coverage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/livebud/bud/package/gomod"
+)
+
+// Mode counts how many times each block runs. It's safe to use in the app,
+// which runs code concurrently.
+const Mode = "atomic"
+
+// Packages lists the packages matching the patterns, leaving out the generated
+// packages in bud/. Patterns default to "./...". The generated code needs to
+// be synced beforehand, since the app's packages import it.
+func Packages(ctx context.Context, module *gomod.Module, env []string, patterns ...string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, "go", append([]string{"list", "-mod=mod", "-f={{.ImportPath}}"}, patterns...)...)
+	cmd.Dir = module.Directory()
+	cmd.Env = append(env, "GOMODCACHE="+module.ModCache())
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("coverage: unable to list packages. %w\n%s", err, stderr)
+	}
+	var packages []string
+	for _, pkg := range strings.Fields(string(out)) {
+		if !Generated(module, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages, nil
+}
+
+// Generated reports whether the import path or file is generated by bud.
+// Files in main packages that are built from their file are listed by their
+// absolute path.
+func Generated(module *gomod.Module, path string) bool {
+	for _, bud := range []string{module.Import("bud"), module.Directory("bud")} {
+		if path == bud || strings.HasPrefix(path, bud+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// mainPackage is the import path of a main package that's built from its
+// file, like "go build bud/internal/app/main.go"
+const mainPackage = "command-line-arguments"
+
+// BuildFlags instrument the packages when building a binary from its main
+// file. Binaries built with these flags write their coverage to $GOCOVERDIR
+// when they exit.
+func BuildFlags(packages []string) []string {
+	if len(packages) == 0 {
+		return nil
+	}
+	// Binaries only write their coverage when the main package is instrumented.
+	// The main package is generated, so Merge leaves it out.
+	return []string{
+		"-cover",
+		"-covermode=" + Mode,
+		"-coverpkg=" + strings.Join(append([]string{mainPackage}, packages...), ","),
+	}
+}
+
+// Convert the coverage that binaries wrote to dir into a profile in the same
+// format as "go test -coverprofile". It returns false if dir is empty.
+func Convert(ctx context.Context, dir, profile string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("coverage: unable to read %s. %w", dir, err)
+	} else if len(entries) == 0 {
+		return false, nil
+	}
+	stderr := new(bytes.Buffer)
+	cmd := exec.CommandContext(ctx, "go", "tool", "covdata", "textfmt", "-i="+dir, "-o="+profile)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return false, fmt.Errorf("coverage: unable to convert the coverage in %s. %w\n%s", dir, err, stderr)
+	}
+	return true, nil
+}
+
+// Merge the profiles into w, leaving out generated code. Blocks that are in
+// more than one profile, like code that's covered by the tests of two
+// packages, have their counts added up.
+func Merge(w io.Writer, module *gomod.Module, profiles ...io.Reader) error {
+	mode := ""
+	var blocks []string
+	counts := map[string]int{}
+	for _, profile := range profiles {
+		scanner := bufio.NewScanner(profile)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			if strings.HasPrefix(line, "mode: ") {
+				m := strings.TrimPrefix(line, "mode: ")
+				if mode != "" && mode != m {
+					return fmt.Errorf("coverage: unable to merge a %q profile with a %q profile", m, mode)
+				}
+				mode = m
+				continue
+			}
+			// Lines look like "app.com/controller/controller.go:10.2,12.3 1 5"
+			i := strings.LastIndexByte(line, ' ')
+			if i < 0 {
+				return fmt.Errorf("coverage: unable to parse %q", line)
+			}
+			block := line[:i]
+			count, err := strconv.Atoi(line[i+1:])
+			if err != nil {
+				return fmt.Errorf("coverage: unable to parse %q. %w", line, err)
+			}
+			file, _, _ := strings.Cut(block, ":")
+			if Generated(module, file) {
+				continue
+			}
+			previous, ok := counts[block]
+			if !ok {
+				blocks = append(blocks, block)
+			}
+			if mode == "set" {
+				if count > 0 {
+					counts[block] = 1
+				} else {
+					counts[block] = previous
+				}
+				continue
+			}
+			counts[block] = previous + count
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("coverage: unable to read profile. %w", err)
+		}
+	}
+	if mode == "" {
+		mode = Mode
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", mode)
+	for _, block := range blocks {
+		fmt.Fprintf(bw, "%s %d\n", block, counts[block])
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,97 @@
+package coverage_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/coverage"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/gomod"
+)
+
+func writeApp(t testing.TB, files map[string]string) *gomod.Module {
+	t.Helper()
+	is := is.New(t)
+	dir := t.TempDir()
+	for path, data := range files {
+		path = filepath.Join(dir, path)
+		is.NoErr(os.MkdirAll(filepath.Dir(path), 0755))
+		is.NoErr(os.WriteFile(path, []byte(data), 0644))
+	}
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	return module
+}
+
+func TestPackages(t *testing.T) {
+	is := is.New(t)
+	module := writeApp(t, map[string]string{
+		"go.mod":                    "module app.com\n\ngo 1.18\n",
+		"controller/controller.go":  "package controller\n",
+		"controller/posts/posts.go": "package posts\n",
+		"bud/internal/app/main.go":  "package main\nfunc main() {}\n",
+	})
+	packages, err := coverage.Packages(context.Background(), module, os.Environ())
+	is.NoErr(err)
+	is.Equal(strings.Join(packages, " "), "app.com/controller app.com/controller/posts")
+	packages, err = coverage.Packages(context.Background(), module, os.Environ(), "./controller/posts")
+	is.NoErr(err)
+	is.Equal(strings.Join(packages, " "), "app.com/controller/posts")
+}
+
+func TestGenerated(t *testing.T) {
+	is := is.New(t)
+	module := writeApp(t, map[string]string{"go.mod": "module app.com\n"})
+	is.True(coverage.Generated(module, "app.com/bud/internal/web"))
+	is.True(coverage.Generated(module, "app.com/bud/internal/web/web.go"))
+	is.True(coverage.Generated(module, module.Directory("bud", "internal", "app", "main.go")))
+	is.True(!coverage.Generated(module, "app.com/controller/controller.go"))
+	is.True(!coverage.Generated(module, "app.com/budget/budget.go"))
+}
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+	module := writeApp(t, map[string]string{"go.mod": "module app.com\n"})
+	tests := `mode: atomic
+app.com/controller/controller.go:10.40,10.66 1 1
+app.com/controller/controller.go:12.52,12.80 1 0
+app.com/bud/internal/web/web.go:5.1,6.2 2 3
+`
+	app := `mode: atomic
+app.com/controller/controller.go:10.40,10.66 1 2
+app.com/controller/controller.go:12.52,12.80 1 4
+app.com/controller/controller.go:14.51,14.75 1 0
+` + module.Directory("bud", "internal", "app", "main.go") + `:19.2,20.1 1 1
+`
+	out := new(bytes.Buffer)
+	is.NoErr(coverage.Merge(out, module, strings.NewReader(tests), strings.NewReader(app)))
+	is.Equal(out.String(), `mode: atomic
+app.com/controller/controller.go:10.40,10.66 1 3
+app.com/controller/controller.go:12.52,12.80 1 4
+app.com/controller/controller.go:14.51,14.75 1 0
+`)
+}
+
+func TestMergeSet(t *testing.T) {
+	is := is.New(t)
+	module := writeApp(t, map[string]string{"go.mod": "module app.com\n"})
+	a := "mode: set\napp.com/a.go:1.1,2.2 1 1\napp.com/a.go:3.1,4.2 1 0\n"
+	b := "mode: set\napp.com/a.go:1.1,2.2 1 0\napp.com/a.go:3.1,4.2 1 1\n"
+	out := new(bytes.Buffer)
+	is.NoErr(coverage.Merge(out, module, strings.NewReader(a), strings.NewReader(b)))
+	is.Equal(out.String(), "mode: set\napp.com/a.go:1.1,2.2 1 1\napp.com/a.go:3.1,4.2 1 1\n")
+	// Profiles with different modes can't be merged
+	err := coverage.Merge(out, module, strings.NewReader(a), strings.NewReader("mode: atomic\n"))
+	is.True(err != nil)
+}
+
+func TestBuildFlags(t *testing.T) {
+	is := is.New(t)
+	is.Equal(len(coverage.BuildFlags(nil)), 0)
+	flags := coverage.BuildFlags([]string{"app.com/controller", "app.com/job"})
+	is.Equal(strings.Join(flags, " "), "-cover -covermode=atomic -coverpkg=command-line-arguments,app.com/controller,app.com/job")
+}
@@ -0,0 +1,215 @@
+// Package devproxy sits between the browser and the app during development,
+// so restarting the app doesn't show connection errors in the browser.
+// Requests that are cut off by a restart are replayed against the new process.
+package devproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// maxReplayBody is the largest request body that's buffered for replaying
+const maxReplayBody = 1 << 20
+
+// New proxy to the app listening on address
+func New(log log.Interface, address string) *Proxy {
+	target := &url.URL{Scheme: "http", Host: address}
+	p := &Proxy{
+		log:      log,
+		ready:    make(chan struct{}),
+		inflight: map[*context.CancelFunc]struct{}{},
+	}
+	close(p.ready)
+	p.proxy = httputil.NewSingleHostReverseProxy(target)
+	// Stream responses like server-sent events right away
+	p.proxy.FlushInterval = -1
+	p.proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		r.Context().Value(attemptKey{}).(*attempt).err = err
+	}
+	return p
+}
+
+// Proxy requests to the app
+type Proxy struct {
+	log   log.Interface
+	proxy *httputil.ReverseProxy
+
+	mu       sync.Mutex
+	ready    chan struct{} // Closed when the app is ready for requests
+	restarts int
+	inflight map[*context.CancelFunc]struct{}
+}
+
+var _ http.Handler = (*Proxy)(nil)
+
+// Restarting cancels the requests in flight that can be replayed, so the app
+// can shut down right away. They're replayed once Ready is called.
+func (p *Proxy) Restarting() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.ready:
+		p.ready = make(chan struct{})
+	default:
+	}
+	p.restarts++
+	for cancel := range p.inflight {
+		(*cancel)()
+	}
+}
+
+// Ready replays the requests that were cut off by the restart
+func (p *Proxy) Ready() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	select {
+	case <-p.ready:
+	default:
+		close(p.ready)
+	}
+}
+
+// state returns a channel that's closed when the app is ready, along with the
+// number of times the app has restarted
+func (p *Proxy) state() (ready <-chan struct{}, restarts int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ready, p.restarts
+}
+
+func (p *Proxy) track(cancel context.CancelFunc) (untrack func()) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := &cancel
+	p.inflight[key] = struct{}{}
+	return func() {
+		p.mu.Lock()
+		delete(p.inflight, key)
+		p.mu.Unlock()
+		cancel()
+	}
+}
+
+type attemptKey struct{}
+
+// attempt at proxying a request
+type attempt struct {
+	err error
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, replayable, err := buffer(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("devproxy: unable to read the request body. %s", err), http.StatusBadRequest)
+		return
+	}
+	for replays := 0; ; replays++ {
+		ready, _ := p.state()
+		select {
+		case <-ready:
+		case <-r.Context().Done():
+			return
+		}
+		_, restarts := p.state()
+		ctx, cancel := context.WithCancel(r.Context())
+		// Requests that can't be replayed are left to finish
+		untrack := func() { cancel() }
+		if replayable {
+			untrack = p.track(cancel)
+		}
+		attempt := new(attempt)
+		req := r.Clone(context.WithValue(ctx, attemptKey{}, attempt))
+		req.Body = body()
+		rw := &responseWriter{ResponseWriter: w}
+		p.proxy.ServeHTTP(rw, req)
+		untrack()
+		if attempt.err == nil || rw.wrote || r.Context().Err() != nil {
+			return
+		}
+		// Only replay requests that were cut off by a restart
+		if _, now := p.state(); now == restarts || !replayable || replays > 0 {
+			p.log.Debug("devproxy: unable to proxy", "method", r.Method, "path", r.URL.Path, "err", attempt.err)
+			http.Error(w, fmt.Sprintf("devproxy: unable to reach the app. %s", attempt.err), http.StatusBadGateway)
+			return
+		}
+		p.log.Debug("devproxy: replaying after the restart", "method", r.Method, "path", r.URL.Path)
+	}
+}
+
+// buffer the request body so the request can be replayed. Requests that
+// aren't idempotent or have large bodies can't be replayed.
+func buffer(r *http.Request) (body func() io.ReadCloser, replayable bool, err error) {
+	replayable = isIdempotent(r)
+	if r.Body == nil || r.Body == http.NoBody {
+		return func() io.ReadCloser { return http.NoBody }, replayable, nil
+	}
+	if !replayable {
+		once := r.Body
+		return func() io.ReadCloser { return once }, false, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxReplayBody+1))
+	if err != nil {
+		return nil, false, err
+	}
+	// Too large to buffer, so stream the rest through
+	if len(data) > maxReplayBody {
+		rest := io.NopCloser(io.MultiReader(bytes.NewReader(data), r.Body))
+		return func() io.ReadCloser { return rest }, false, nil
+	}
+	return func() io.ReadCloser { return io.NopCloser(bytes.NewReader(data)) }, true, nil
+}
+
+// isIdempotent is true when replaying the request is safe, even if the app
+// already started handling it
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return r.Header.Get("Idempotency-Key") != ""
+	}
+}
+
+// responseWriter tracks whether the response has started, since responses
+// can't be replayed after that
+type responseWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wrote = true
+		flusher.Flush()
+	}
+}
+
+// Hijack supports websockets
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("devproxy: %T doesn't support hijacking", w.ResponseWriter)
+	}
+	w.wrote = true
+	return hijacker.Hijack()
+}
@@ -0,0 +1,103 @@
+package devproxy_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/livebud/bud/internal/devproxy"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log/testlog"
+)
+
+func TestProxy(t *testing.T) {
+	is := is.New(t)
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Method + " " + r.Host + r.URL.Path))
+	}))
+	defer app.Close()
+	proxy := devproxy.New(testlog.New(), strings.TrimPrefix(app.URL, "http://"))
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://example.com/users", nil))
+	is.Equal(rec.Code, http.StatusOK)
+	// The app sees the original host
+	is.Equal(rec.Body.String(), "GET example.com/users")
+}
+
+func TestReplay(t *testing.T) {
+	is := is.New(t)
+	started := make(chan struct{})
+	var requests int32
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		// The first request hangs until the restart cuts it off
+		if atomic.AddInt32(&requests, 1) == 1 {
+			close(started)
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("replayed " + string(body)))
+	}))
+	defer app.Close()
+	proxy := devproxy.New(testlog.New(), strings.TrimPrefix(app.URL, "http://"))
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/users/1", strings.NewReader("name=bud")))
+		close(done)
+	}()
+	<-started
+	proxy.Restarting()
+	// Waits for the new process
+	select {
+	case <-done:
+		t.Fatal("expected the request to wait for the restart")
+	default:
+	}
+	proxy.Ready()
+	<-done
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "replayed name=bud")
+	is.Equal(atomic.LoadInt32(&requests), int32(2))
+}
+
+func TestNoReplayPost(t *testing.T) {
+	is := is.New(t)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-finish
+		w.Write([]byte("created"))
+	}))
+	defer app.Close()
+	proxy := devproxy.New(testlog.New(), strings.TrimPrefix(app.URL, "http://"))
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("name=bud")))
+		close(done)
+	}()
+	<-started
+	// Requests that can't be replayed are left to finish
+	proxy.Restarting()
+	close(finish)
+	<-done
+	proxy.Ready()
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "created")
+}
+
+func TestAppDown(t *testing.T) {
+	is := is.New(t)
+	app := httptest.NewServer(http.NotFoundHandler())
+	app.Close()
+	proxy := devproxy.New(testlog.New(), strings.TrimPrefix(app.URL, "http://"))
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	is.Equal(rec.Code, http.StatusBadGateway)
+	is.True(strings.Contains(rec.Body.String(), "devproxy: unable to reach the app."))
+}
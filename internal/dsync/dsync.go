@@ -1,6 +1,7 @@
 package dsync
 
 import (
+	"bytes"
 	"errors"
 	"io/fs"
 	"path/filepath"
@@ -99,6 +100,7 @@ type Op struct {
 	Type OpType
 	Path string
 	Data []byte
+	Mode fs.FileMode // Permissions of the source file. Defaults to 0644.
 }
 
 func (o Op) String() string {
@@ -181,7 +183,7 @@ func createOps(opt *option, sfs fs.FS, dir string, des []fs.DirEntry) (ops []Op,
 			if err != nil {
 				return nil, err
 			}
-			ops = append(ops, Op{CreateType, rel, data})
+			ops = append(ops, Op{CreateType, rel, data, perm(de)})
 			continue
 		}
 		des, err := fs.ReadDir(sfs, path)
@@ -215,7 +217,7 @@ func deleteOps(opt *option, dir string, des []fs.DirEntry) (ops []Op, err error)
 		if err != nil {
 			return nil, err
 		}
-		ops = append(ops, Op{DeleteType, rel, nil})
+		ops = append(ops, Op{DeleteType, rel, nil, 0})
 		continue
 	}
 	return ops, nil
@@ -261,11 +263,16 @@ func updateOps(opt *option, sfs fs.FS, sdir string, tfs vfs.ReadWritable, tdir s
 			}
 			return nil, err
 		}
+		// Skip files that were regenerated with the same contents, so they keep
+		// their modtime and we don't write more than we need to
+		if prev, err := fs.ReadFile(tfs, tpath); err == nil && bytes.Equal(prev, data) {
+			continue
+		}
 		rel, err := opt.rel(spath)
 		if err != nil {
 			return nil, err
 		}
-		ops = append(ops, Op{UpdateType, rel, data})
+		ops = append(ops, Op{UpdateType, rel, data, perm(de)})
 	}
 	return ops, nil
 }
@@ -278,11 +285,11 @@ func apply(sfs fs.FS, tfs vfs.ReadWritable, ops []Op) error {
 			if err := tfs.MkdirAll(dir, 0755); err != nil {
 				return err
 			}
-			if err := tfs.WriteFile(op.Path, op.Data, 0644); err != nil {
+			if err := tfs.WriteFile(op.Path, op.Data, op.perm()); err != nil {
 				return err
 			}
 		case UpdateType:
-			if err := tfs.WriteFile(op.Path, op.Data, 0644); err != nil {
+			if err := tfs.WriteFile(op.Path, op.Data, op.perm()); err != nil {
 				return err
 			}
 		case DeleteType:
@@ -294,6 +301,23 @@ func apply(sfs fs.FS, tfs vfs.ReadWritable, ops []Op) error {
 	return nil
 }
 
+// perm returns the permissions of the source file, so executables stay
+// executable. Generated files often don't have any.
+func perm(de fs.DirEntry) fs.FileMode {
+	info, err := de.Info()
+	if err != nil {
+		return 0
+	}
+	return info.Mode().Perm()
+}
+
+func (o Op) perm() fs.FileMode {
+	if o.Mode == 0 {
+		return 0644
+	}
+	return o.Mode
+}
+
 // Stamp the path, returning "" if the file doesn't exist.
 // Uses the modtime and size to determine if a file has changed.
 func stamp(fsys fs.FS, path string) (stamp string, err error) {
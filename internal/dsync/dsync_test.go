@@ -367,3 +367,45 @@ func TestDeleteNotExist(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(len(targetFS), 0)
 }
+
+func TestSkipSameContents(t *testing.T) {
+	is := is.New(t)
+	before := time.Date(2021, 8, 4, 14, 56, 0, 0, time.UTC)
+	after := time.Date(2021, 8, 4, 14, 57, 0, 0, time.UTC)
+	vfs.Now = func() time.Time { return after }
+	sourceFS := vfs.Memory{
+		"a.txt": &vfs.File{Data: []byte("a"), ModTime: after},
+		"b.txt": &vfs.File{Data: []byte("b"), ModTime: after},
+	}
+	targetFS := vfs.Memory{
+		"a.txt": &vfs.File{Data: []byte("a"), ModTime: before},
+		"b.txt": &vfs.File{Data: []byte("bb"), ModTime: before},
+	}
+	err := dsync.To(sourceFS, targetFS, ".")
+	is.NoErr(err)
+	// a.txt has the same contents, so it wasn't written
+	stat, err := fs.Stat(targetFS, "a.txt")
+	is.NoErr(err)
+	is.True(stat.ModTime().Equal(before))
+	stat, err = fs.Stat(targetFS, "b.txt")
+	is.NoErr(err)
+	is.True(stat.ModTime().Equal(after))
+}
+
+func TestKeepMode(t *testing.T) {
+	is := is.New(t)
+	sourceFS := vfs.Memory{
+		"run.sh": &vfs.File{Data: []byte("#!/bin/sh"), Mode: 0755},
+		"a.txt":  &vfs.File{Data: []byte("a")},
+	}
+	targetFS := vfs.Memory{}
+	err := dsync.To(sourceFS, targetFS, ".")
+	is.NoErr(err)
+	stat, err := fs.Stat(targetFS, "run.sh")
+	is.NoErr(err)
+	is.Equal(stat.Mode().Perm(), fs.FileMode(0755))
+	// Files without permissions are written with the defaults
+	stat, err = fs.Stat(targetFS, "a.txt")
+	is.NoErr(err)
+	is.Equal(stat.Mode().Perm(), fs.FileMode(0644))
+}
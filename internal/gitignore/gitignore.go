@@ -1,9 +1,12 @@
+// Package gitignore decides which files bud skips while scanning and
+// watching. Rules come from the project's .gitignore followed by its
+// .budignore, so a .budignore can add rules of its own or bring back ignored
+// files with a "!" pattern.
 package gitignore
 
 import (
 	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
 
 	gitignore "github.com/sabhiram/go-gitignore"
@@ -13,6 +16,19 @@ var alwaysIgnore = []string{
 	"node_modules",
 	".git",
 	".DS_Store",
+	// Editor temporary files
+	"*.swp",
+	"*.swx",
+	"*~",
+	".#*",
+	"#*#",
+}
+
+// alwaysWatch brings back the .env files that are usually ignored, like
+// .env.local, since the app reloads when they change
+var alwaysWatch = []string{
+	"!/.env",
+	"!/.env.*",
 }
 
 var defaultIgnores = append([]string{"/bud"}, alwaysIgnore...)
@@ -20,23 +36,23 @@ var defaultIgnores = append([]string{"/bud"}, alwaysIgnore...)
 var defaultIgnore = gitignore.CompileIgnoreLines(defaultIgnores...).MatchesPath
 
 func FromFS(fsys fs.FS) (ignore func(path string) bool) {
-	code, err := fs.ReadFile(fsys, ".gitignore")
-	if err != nil {
+	gitIgnore, gitErr := fs.ReadFile(fsys, ".gitignore")
+	budIgnore, budErr := fs.ReadFile(fsys, ".budignore")
+	if gitErr != nil && budErr != nil {
 		return defaultIgnore
 	}
-	lines := strings.Split(string(code), "\n")
-	lines = append(lines, alwaysIgnore...)
+	lines := append([]string{}, defaultIgnores...)
+	if gitErr == nil {
+		lines = append(strings.Split(string(gitIgnore), "\n"), alwaysWatch...)
+		lines = append(lines, alwaysIgnore...)
+	}
+	if budErr == nil {
+		lines = append(lines, strings.Split(string(budIgnore), "\n")...)
+	}
 	ignorer := gitignore.CompileIgnoreLines(lines...)
 	return ignorer.MatchesPath
 }
 
 func From(dir string) (ignore func(path string) bool) {
-	code, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
-	if err != nil {
-		return defaultIgnore
-	}
-	lines := strings.Split(string(code), "\n")
-	lines = append(lines, alwaysIgnore...)
-	ignorer := gitignore.CompileIgnoreLines(lines...)
-	return ignorer.MatchesPath
+	return FromFS(os.DirFS(dir))
 }
@@ -34,3 +34,55 @@ func TestNodeModules(t *testing.T) {
 	is.True(ignore("node_modules"))
 	is.True(ignore("node_modules/svelte/internal/compiler.js"))
 }
+
+func TestEditorFiles(t *testing.T) {
+	is := is.New(t)
+	ignore := gitignore.FromFS(fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte(``)},
+	})
+	is.True(ignore("view/.index.svelte.swp"))
+	is.True(ignore("view/index.svelte~"))
+	is.True(ignore("controller/.#controller.go"))
+	is.True(!ignore("view/index.svelte"))
+}
+
+func TestBudIgnore(t *testing.T) {
+	is := is.New(t)
+	ignore := gitignore.FromFS(fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte("/bud\n/public/build\n")},
+		".budignore": &fstest.MapFile{Data: []byte("/tmp\n!/public/build\n")},
+	})
+	is.True(ignore("bud/internal/web/web.go"))
+	is.True(ignore("tmp/scratch.go"))
+	is.True(!ignore("public/build"))
+	is.True(!ignore("public/build/app.css"))
+	is.True(ignore("node_modules"))
+}
+
+func TestBudIgnoreOnly(t *testing.T) {
+	is := is.New(t)
+	ignore := gitignore.FromFS(fstest.MapFS{
+		".budignore": &fstest.MapFile{Data: []byte("/tmp\n")},
+	})
+	is.True(ignore("bud/internal/web/web.go"))
+	is.True(ignore("tmp/scratch.go"))
+	is.True(ignore("node_modules"))
+	is.True(!ignore("main.go"))
+}
+
+func TestEnvFiles(t *testing.T) {
+	is := is.New(t)
+	ignore := gitignore.FromFS(fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte(".env*\n")},
+	})
+	is.True(!ignore(".env"))
+	is.True(!ignore(".env.local"))
+	is.True(ignore("config/.env"))
+	// Unless they're ignored by .budignore
+	ignore = gitignore.FromFS(fstest.MapFS{
+		".gitignore": &fstest.MapFile{Data: []byte(".env*\n")},
+		".budignore": &fstest.MapFile{Data: []byte(".env.local\n")},
+	})
+	is.True(!ignore(".env"))
+	is.True(ignore(".env.local"))
+}
@@ -22,9 +22,8 @@ func Expand(str string) ([]string, error) {
 }
 
 func expand(node *ast.Node) (patterns []string, err error) {
-	prefix := ""
+	patterns = []string{""}
 	write := func(value string) {
-		prefix += value
 		for i := range patterns {
 			patterns[i] += value
 		}
@@ -68,21 +67,25 @@ func expand(node *ast.Node) (patterns []string, err error) {
 		case ast.KindSingle:
 			write("?")
 		case ast.KindAnyOf:
+			// Each alternative follows each of the patterns so far
+			var alternatives []string
 			for _, child := range child.Children {
 				results, err := expand(child)
 				if err != nil {
 					return nil, err
 				}
-				for _, result := range results {
-					patterns = append(patterns, prefix+result)
+				alternatives = append(alternatives, results...)
+			}
+			var expanded []string
+			for _, pattern := range patterns {
+				for _, alternative := range alternatives {
+					expanded = append(expanded, pattern+alternative)
 				}
 			}
+			patterns = expanded
 		default:
 			return nil, fmt.Errorf("unknown node kind: %v", child.Kind)
 		}
 	}
-	if len(patterns) == 0 {
-		patterns = append(patterns, prefix)
-	}
 	return patterns, nil
 }
@@ -64,5 +64,6 @@ func TestExpand(t *testing.T) {
 	test("{controller/**.go,view/**}", "controller/**.go", "view/**")
 	test("{{controller,view}/**.go,view/**}", "controller/**.go", "view/**.go", "view/**")
 	test("{controller,controller}", "controller")
-	// TODO support: test("{a,b}/{c,d}", "a/c", "a/d", "b/c", "b/d")
+	test("{a,b}/{c,d}", "a/c", "a/d", "b/c", "b/d")
+	test("{a,b}/*.{go,js}", "a/*.go", "a/*.js", "b/*.go", "b/*.js")
 }
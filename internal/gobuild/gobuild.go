@@ -1,16 +1,24 @@
 package gobuild
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/cespare/xxhash"
 	"github.com/livebud/bud/internal/imhash"
 	"github.com/livebud/bud/internal/symlink"
+	"github.com/livebud/bud/internal/trace"
+	"github.com/livebud/bud/package/bud"
 	"github.com/livebud/bud/package/gomod"
 )
 
@@ -20,6 +28,7 @@ func New(module *gomod.Module) *Builder {
 		os.Stderr,
 		os.Stdin,
 		os.Stdout,
+		nil,
 		module,
 		module.Directory("bud", ".cache"),
 	}
@@ -30,29 +39,63 @@ type Builder struct {
 	Stderr   io.Writer
 	Stdin    io.Reader
 	Stdout   io.Writer
+	Trace    *trace.Recorder // Records each package that's compiled
 	module   *gomod.Module
 	cacheDir string
 }
 
-// Build a Go binary and cache it for later use
+// Build a Go binary and cache it for later use. Binaries are stamped with
+// their build ID, the commit and when they were built. See package/bud.
 func (b *Builder) Build(ctx context.Context, mainPath string, outPath string, flags ...string) error {
-	hash, err := imhash.Hash(b.module, filepath.Dir(mainPath))
+	start := time.Now()
+	buildID, err := imhash.Hash(b.module, filepath.Dir(mainPath))
 	if err != nil {
 		return err
 	}
+	hash := buildID
+	// Binaries built with flags, like coverage builds, are cached separately
+	if len(flags) > 0 {
+		hash += "-" + strconv.FormatUint(xxhash.Sum64String(strings.Join(flags, " ")), 36)
+	}
+	// So are binaries built from different commits, so the stamped commit is
+	// always right
+	commit := b.commit(ctx)
+	if commit != "" {
+		hash += "-" + commit
+	}
 	cachePath := filepath.Join(b.cacheDir, hash)
 	exists, err := b.exists(cachePath)
 	if err != nil {
 		return err
 	} else if exists {
+		b.Trace.Add("compile", "go build (cached)", start, time.Now())
 		return symlink.Link(cachePath, b.module.Directory(outPath))
 	}
+	stamp := bud.Flags(&bud.Info{
+		BuildID:   buildID,
+		Commit:    commit,
+		Generated: time.Now(),
+	})
+	flags = append(flags, "-ldflags="+stamp)
 	if err := b.build(ctx, mainPath, cachePath, flags...); err != nil {
 		return err
 	}
+	b.Trace.Add("compile", "go build", start, time.Now())
 	return symlink.Link(cachePath, b.module.Directory(outPath))
 }
 
+// commit returns the module's current git commit. It's empty when the module
+// isn't in a git repository.
+func (b *Builder) commit(ctx context.Context) string {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
+	cmd.Dir = b.module.Directory()
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
 // Build calls `go build -mod=mod -o main [flags...] main.go`
 func (b *Builder) build(ctx context.Context, mainPath string, outPath string, flags ...string) error {
 	// Compile the args
@@ -61,22 +104,89 @@ func (b *Builder) build(ctx context.Context, mainPath string, outPath string, fl
 		"-mod=mod",
 		"-o=" + outPath,
 	}, flags...)
+	// Ask Go for the timing of each action when tracing
+	var actionGraph string
+	if b.Trace != nil {
+		actionGraph = outPath + ".actions.json"
+		args = append(args, "-debug-actiongraph="+actionGraph)
+		defer os.Remove(actionGraph)
+	}
 	args = append(args, mainPath)
 	cmd := exec.CommandContext(ctx, "go", args...)
 	cmd.Env = append(b.Env,
 		"GOMODCACHE="+b.module.ModCache(),
 	)
+	// Keep the compiler's output for the error
+	output := new(bytes.Buffer)
 	cmd.Stdout = b.Stdout
-	cmd.Stderr = b.Stderr
+	cmd.Stderr = output
+	if b.Stderr != nil {
+		cmd.Stderr = io.MultiWriter(b.Stderr, output)
+	}
 	cmd.Stdin = b.Stdin
 	cmd.Dir = b.module.Directory()
 	err := cmd.Run()
+	if actionGraph != "" {
+		b.traceActions(actionGraph, mainPath)
+	}
 	if err != nil {
-		return err
+		return &Error{output.String(), err}
 	}
 	return nil
 }
 
+// action in the graph written by `go build -debug-actiongraph`
+type action struct {
+	Mode      string
+	Package   string
+	TimeStart time.Time
+	TimeDone  time.Time
+	Cmd       []string // Empty when the action was cached
+}
+
+// traceActions records how long each package took to compile and link.
+// Traces are best-effort, so errors are ignored.
+func (b *Builder) traceActions(path, mainPath string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var actions []*action
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return
+	}
+	for _, action := range actions {
+		if len(action.Cmd) == 0 || action.TimeStart.IsZero() {
+			continue
+		}
+		name := action.Package
+		// Files passed to go build are in a package without a name
+		if name == "command-line-arguments" {
+			name = mainPath
+		}
+		if action.Mode != "build" {
+			name += " (" + action.Mode + ")"
+		}
+		b.Trace.Add("compile", name, action.TimeStart, action.TimeDone)
+	}
+}
+
+// Error is returned when the Go compiler fails. The compiler's output has
+// already been written to Stderr, but it's kept for showing elsewhere, like in
+// the browser.
+type Error struct {
+	Output string
+	err    error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
 // Check if the path exists
 func (b *Builder) exists(path string) (bool, error) {
 	if _, err := os.Stat(path); err != nil {
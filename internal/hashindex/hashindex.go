@@ -0,0 +1,237 @@
+// Package hashindex tracks files by the hash of their contents, so touching a
+// file without changing it doesn't cause a rebuild and a change is never missed
+// because the filesystem's clock is coarse or skewed, like within Docker
+// volumes or over NFS.
+//
+// The index is persisted between runs. Files that still have the size and
+// modtime they were indexed with aren't read again when the index is
+// refreshed.
+package hashindex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash"
+	"github.com/livebud/bud/package/vfs"
+)
+
+// entry is an indexed file
+type entry struct {
+	Hash    uint64
+	Size    int64
+	ModTime int64
+}
+
+// Index of file hashes
+type Index struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]*entry
+	dirty   bool
+}
+
+// Load the index from path. A missing or corrupt index starts out empty.
+func Load(path string) (*Index, error) {
+	index := &Index{
+		path:    path,
+		entries: map[string]*entry{},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return index, nil
+		}
+		return nil, fmt.Errorf("hashindex: unable to load %q. %w", path, err)
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, e, ok := parseLine(scanner.Text())
+		if !ok {
+			// The index is only a cache, so start over
+			index.entries = map[string]*entry{}
+			return index, nil
+		}
+		index.entries[name] = e
+	}
+	return index, nil
+}
+
+// Each line is "<hash> <size> <modtime> <path>"
+func parseLine(line string) (path string, e *entry, ok bool) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 {
+		return "", nil, false
+	}
+	hash, err := strconv.ParseUint(fields[0], 16, 64)
+	if err != nil {
+		return "", nil, false
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return "", nil, false
+	}
+	modTime, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return "", nil, false
+	}
+	return fields[3], &entry{hash, size, modTime}, true
+}
+
+// Refresh the index with the files in fsys, skipping the paths where skip
+// returns true. Files that kept their size and modtime keep their hash without
+// being read. Files that no longer exist are removed from the index.
+//
+// Directories are read and files are hashed concurrently when fsys allows it,
+// so skip may be called from multiple goroutines at once.
+func (x *Index) Refresh(fsys fs.FS, skip func(path string, isDir bool) bool) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	// Guards entries, seen and dirty while walking
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	err := vfs.WalkParallel(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != "." && skip(path, de.IsDir()) {
+			if de.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !de.Type().IsRegular() {
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		seen[path] = true
+		prev, ok := x.entries[path]
+		mu.Unlock()
+		if ok && prev.Size == info.Size() && prev.ModTime == info.ModTime().UnixNano() {
+			return nil
+		}
+		hash, err := hashFile(fsys, path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		mu.Lock()
+		x.entries[path] = &entry{hash, info.Size(), info.ModTime().UnixNano()}
+		x.dirty = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("hashindex: unable to refresh. %w", err)
+	}
+	for path := range x.entries {
+		if !seen[path] {
+			delete(x.entries, path)
+			x.dirty = true
+		}
+	}
+	return nil
+}
+
+// Changed reads the file and returns true if its contents changed since it
+// was last indexed. Files that weren't indexed before have changed. Files
+// that no longer exist have changed if they were indexed. Modtimes are never
+// trusted here, so touched files haven't changed.
+func (x *Index) Changed(fsys fs.FS, path string) (changed bool, err error) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	prev, indexed := x.entries[path]
+	info, err := fs.Stat(fsys, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			delete(x.entries, path)
+			x.dirty = x.dirty || indexed
+			return indexed, nil
+		}
+		return false, err
+	}
+	if !info.Mode().IsRegular() {
+		return true, nil
+	}
+	hash, err := hashFile(fsys, path)
+	if err != nil {
+		return false, err
+	}
+	next := &entry{hash, info.Size(), info.ModTime().UnixNano()}
+	if !indexed || *prev != *next {
+		x.entries[path] = next
+		x.dirty = true
+	}
+	return !indexed || prev.Hash != hash, nil
+}
+
+// Len returns the number of indexed files
+func (x *Index) Len() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return len(x.entries)
+}
+
+// Save the index if it changed since it was loaded
+func (x *Index) Save() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.dirty {
+		return nil
+	}
+	paths := make([]string, 0, len(x.entries))
+	for path := range x.entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	buf := new(bytes.Buffer)
+	for _, path := range paths {
+		e := x.entries[path]
+		fmt.Fprintf(buf, "%x %d %d %s\n", e.Hash, e.Size, e.ModTime, path)
+	}
+	if err := os.MkdirAll(filepath.Dir(x.path), 0755); err != nil {
+		return fmt.Errorf("hashindex: unable to save %q. %w", x.path, err)
+	}
+	// Write to a temporary file first so a crash doesn't leave a partial index
+	tmp := x.path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("hashindex: unable to save %q. %w", x.path, err)
+	}
+	if err := os.Rename(tmp, x.path); err != nil {
+		return fmt.Errorf("hashindex: unable to save %q. %w", x.path, err)
+	}
+	x.dirty = false
+	return nil
+}
+
+func hashFile(fsys fs.FS, path string) (uint64, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	h := xxhash.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return 0, err
+	}
+	return h.Sum64(), nil
+}
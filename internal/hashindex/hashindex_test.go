@@ -0,0 +1,99 @@
+package hashindex_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/hashindex"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/vfs"
+)
+
+func TestChanged(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"view/index.svelte": []byte(`<h1>index</h1>`),
+	})
+	is.NoErr(err)
+	fsys := os.DirFS(dir)
+	index, err := hashindex.Load(filepath.Join(dir, "bud", ".cache", "hashindex"))
+	is.NoErr(err)
+	// Files that weren't indexed have changed
+	changed, err := index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(changed)
+	changed, err = index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(!changed)
+	// Touching a file isn't a change
+	later := time.Now().Add(time.Hour)
+	is.NoErr(os.Chtimes(filepath.Join(dir, "view/index.svelte"), later, later))
+	changed, err = index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(!changed)
+	// Changing the contents is, even if the size and modtime are the same
+	is.NoErr(os.WriteFile(filepath.Join(dir, "view/index.svelte"), []byte(`<h1>about</h1>`), 0644))
+	is.NoErr(os.Chtimes(filepath.Join(dir, "view/index.svelte"), later, later))
+	changed, err = index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(changed)
+	// Deleting an indexed file is a change
+	is.NoErr(os.Remove(filepath.Join(dir, "view/index.svelte")))
+	changed, err = index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(changed)
+	changed, err = index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(!changed)
+}
+
+func TestPersist(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"view/index.svelte": []byte(`<h1>index</h1>`),
+		"view/about.svelte": []byte(`<h1>about</h1>`),
+		"bud/app":           []byte(`binary`),
+	})
+	is.NoErr(err)
+	fsys := os.DirFS(dir)
+	path := filepath.Join(dir, "bud", ".cache", "hashindex")
+	skip := func(path string, isDir bool) bool { return path == "bud" }
+	index, err := hashindex.Load(path)
+	is.NoErr(err)
+	is.NoErr(index.Refresh(fsys, skip))
+	is.NoErr(index.Save())
+	// Reload the index in another run
+	index, err = hashindex.Load(path)
+	is.NoErr(err)
+	changed, err := index.Changed(fsys, "view/index.svelte")
+	is.NoErr(err)
+	is.True(!changed)
+	// Skipped files aren't indexed
+	changed, err = index.Changed(fsys, "bud/app")
+	is.NoErr(err)
+	is.True(changed)
+	// Changes between runs are picked up by the refresh
+	is.NoErr(os.WriteFile(filepath.Join(dir, "view/about.svelte"), []byte(`<h1>changed</h1>`), 0644))
+	is.NoErr(index.Refresh(fsys, skip))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "view/about.svelte"), []byte(`<h1>changed</h1>`), 0644))
+	changed, err = index.Changed(fsys, "view/about.svelte")
+	is.NoErr(err)
+	is.True(!changed)
+}
+
+func TestCorrupt(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hashindex")
+	is.NoErr(os.WriteFile(path, []byte("not an index"), 0644))
+	index, err := hashindex.Load(path)
+	is.NoErr(err)
+	is.NoErr(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	changed, err := index.Changed(os.DirFS(dir), "a.txt")
+	is.NoErr(err)
+	is.True(changed)
+}
@@ -0,0 +1,208 @@
+// Package overlay shows build errors in the browser during development, so a
+// failed build explains itself instead of leaving a blank page.
+package overlay
+
+import (
+	"bufio"
+	"errors"
+	"go/scanner"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/livebud/bud/internal/gobuild"
+)
+
+// Diagnostic is an error at a position within a file
+type Diagnostic struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+	Frame   []*Line // Code around the error
+}
+
+// Line of code within a frame
+type Line struct {
+	Number int
+	Code   string
+	Error  bool // True for the line with the error
+}
+
+// Lines of code shown before and after the error
+const frameSize = 2
+
+// Message returns the error's text along with the compiler's output, without
+// any terminal colors
+func Message(err error) string {
+	message := err.Error()
+	var buildErr *gobuild.Error
+	if errors.As(err, &buildErr) && buildErr.Output != "" {
+		message = strings.TrimSpace(buildErr.Output) + "\n\n" + message
+	}
+	return stripANSI(message)
+}
+
+// Parse the diagnostics out of the error. Positions come from go/parser's
+// error list when there is one, otherwise from the "path:line:col: message"
+// lines that the Go compiler and esbuild print. Code frames are read from dir,
+// which is usually the module's directory.
+func Parse(dir string, err error) (diagnostics []*Diagnostic) {
+	var list scanner.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			diagnostics = append(diagnostics, &Diagnostic{
+				Path:    e.Pos.Filename,
+				Line:    e.Pos.Line,
+				Column:  e.Pos.Column,
+				Message: e.Msg,
+			})
+		}
+	} else {
+		diagnostics = parseText(Message(err))
+	}
+	for _, diagnostic := range diagnostics {
+		diagnostic.Frame = readFrame(dir, diagnostic.Path, diagnostic.Line)
+	}
+	return diagnostics
+}
+
+// position matches "path:line:col: message". esbuild prints the message on an
+// earlier line, marked with [ERROR].
+var position = regexp.MustCompile(`^\s*((?:[A-Za-z]:)?[^\s:]+\.\w+):(\d+):(\d+):\s*(.*)$`)
+
+func parseText(text string) (diagnostics []*Diagnostic) {
+	seen := map[string]bool{}
+	lastError := ""
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.Index(line, "[ERROR]"); i >= 0 {
+			lastError = strings.TrimSpace(line[i+len("[ERROR]"):])
+			continue
+		}
+		match := position.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		lineno, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		message := match[4]
+		if message == "" {
+			message = lastError
+		}
+		path := strings.TrimPrefix(filepath.ToSlash(match[1]), "./")
+		key := path + ":" + match[2] + ":" + match[3] + ":" + message
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		diagnostics = append(diagnostics, &Diagnostic{
+			Path:    path,
+			Line:    lineno,
+			Column:  column,
+			Message: message,
+		})
+	}
+	return diagnostics
+}
+
+// readFrame reads the lines around the error. Files that can't be read don't
+// have a frame.
+func readFrame(dir, path string, lineno int) (frame []*Line) {
+	if lineno <= 0 {
+		return nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, filepath.FromSlash(path))
+	}
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(code), "\n"), "\n")
+	for n := lineno - frameSize; n <= lineno+frameSize; n++ {
+		if n < 1 || n > len(lines) {
+			continue
+		}
+		frame = append(frame, &Line{
+			Number: n,
+			Code:   strings.TrimRight(lines[n-1], "\r"),
+			Error:  n == lineno,
+		})
+	}
+	return frame
+}
+
+var ansiCode = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func stripANSI(s string) string {
+	return ansiCode.ReplaceAllString(s, "")
+}
+
+// Handler serves the error as a page, or as text to clients that don't accept
+// HTML. The page reloads itself when hotURL publishes the next change.
+func Handler(dir string, err error, hotURL string) http.Handler {
+	page := &page{
+		Message:     Message(err),
+		Diagnostics: Parse(dir, err),
+		Hot:         hotURL,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if !strings.Contains(r.Header.Get("Accept"), "text/html") {
+			http.Error(w, page.Message, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		pageTemplate.Execute(w, page)
+	})
+}
+
+type page struct {
+	Message     string
+	Diagnostics []*Diagnostic
+	Hot         string
+}
+
+var pageTemplate = template.Must(template.New("overlay").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Build failed</title>
+<style>
+body{font-family:system-ui,sans-serif;margin:0;padding:2rem;background:#1e1e1e;color:#eee}
+h1{color:#ff6b6b;font-size:1.5rem;margin-top:0}
+h2{font-size:1rem;font-family:monospace;margin-bottom:.25rem}
+p{margin:.25rem 0 .75rem}
+pre{background:#2a2a2a;padding:1rem;overflow:auto;line-height:1.4}
+.line{display:block;color:#999}
+.line.error{color:#fff;background:#5a1e1e}
+.number{display:inline-block;width:4ch;text-align:right;margin-right:1ch;user-select:none}
+</style>
+</head>
+<body>
+<h1>Build failed</h1>
+{{- range $.Diagnostics }}
+<h2>{{ .Path }}:{{ .Line }}:{{ .Column }}</h2>
+<p>{{ .Message }}</p>
+{{- if .Frame }}
+<pre>{{ range .Frame }}<span class="line{{ if .Error }} error{{ end }}"><span class="number">{{ .Number }}</span>{{ .Code }}</span>{{ end }}</pre>
+{{- end }}
+{{- end }}
+<pre>{{ $.Message }}</pre>
+{{- if $.Hot }}
+<script>
+var events = new EventSource({{ $.Hot }} + "?topics=app:ready,build:error")
+events.addEventListener("app:ready", function () { location.reload() })
+events.addEventListener("build:error", function () { location.reload() })
+</script>
+{{- end }}
+</body>
+</html>
+`))
@@ -0,0 +1,110 @@
+package overlay_test
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/overlay"
+)
+
+const controller = `package controller
+
+type Controller struct{}
+
+func (c *Controller) Index() string {
+	return "hello" +
+}
+`
+
+func writeController(t testing.TB) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "controller"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "controller", "controller.go"), []byte(controller), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestParseErrorList(t *testing.T) {
+	is := is.New(t)
+	dir := writeController(t)
+	_, err := parser.ParseFile(token.NewFileSet(), "controller/controller.go", controller, 0)
+	is.True(err != nil)
+	err = fmt.Errorf("framework: unable to load controllers. %w", err)
+	diagnostics := overlay.Parse(dir, err)
+	is.True(len(diagnostics) > 0)
+	is.Equal(diagnostics[0].Path, "controller/controller.go")
+	is.Equal(diagnostics[0].Line, 7)
+	is.Equal(diagnostics[0].Column, 1)
+	is.Equal(len(diagnostics[0].Frame), 3)
+	is.Equal(diagnostics[0].Frame[0].Number, 5)
+	is.Equal(diagnostics[0].Frame[2].Code, "}")
+	is.True(diagnostics[0].Frame[2].Error)
+}
+
+func TestParseCompiler(t *testing.T) {
+	is := is.New(t)
+	dir := writeController(t)
+	err := errors.New(`# app.com/controller
+./controller/controller.go:6:9: undefined: hello
+./controller/controller.go:6:9: undefined: hello
+/usr/local/go/src/fmt/print.go:1:1: outside the module`)
+	diagnostics := overlay.Parse(dir, err)
+	is.Equal(len(diagnostics), 2)
+	is.Equal(diagnostics[0].Path, "controller/controller.go")
+	is.Equal(diagnostics[0].Line, 6)
+	is.Equal(diagnostics[0].Column, 9)
+	is.Equal(diagnostics[0].Message, "undefined: hello")
+	is.Equal(len(diagnostics[0].Frame), 4)
+	is.Equal(diagnostics[0].Frame[2].Code, "\treturn \"hello\" +")
+	is.Equal(diagnostics[1].Path, "/usr/local/go/src/fmt/print.go")
+}
+
+func TestParseESBuild(t *testing.T) {
+	is := is.New(t)
+	err := errors.New("\x1b[31m✘ [ERROR] \x1b[1mCould not resolve \"missing\"\x1b[0m\n\n    view/index.svelte:3:7:\n      3 │ import x from \"missing\"\n        ╵        ~~~~~~~~~\n")
+	diagnostics := overlay.Parse(t.TempDir(), err)
+	is.Equal(len(diagnostics), 1)
+	is.Equal(diagnostics[0].Path, "view/index.svelte")
+	is.Equal(diagnostics[0].Line, 3)
+	is.Equal(diagnostics[0].Column, 7)
+	is.Equal(diagnostics[0].Message, `Could not resolve "missing"`)
+	is.Equal(len(diagnostics[0].Frame), 0)
+}
+
+func TestHandler(t *testing.T) {
+	is := is.New(t)
+	dir := writeController(t)
+	err := errors.New("./controller/controller.go:6:9: undefined: <hello>")
+	handler := overlay.Handler(dir, err, "http://127.0.0.1:35729/bud/events")
+	// Browsers get a page
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, http.StatusInternalServerError)
+	is.Equal(rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	body := rec.Body.String()
+	is.True(strings.Contains(body, "<h2>controller/controller.go:6:9</h2>"))
+	is.True(strings.Contains(body, "undefined: &lt;hello&gt;"))
+	is.True(strings.Contains(body, `<span class="line error"><span class="number">6</span>	return &#34;hello&#34; &#43;</span>`))
+	is.True(strings.Contains(body, `new EventSource("http://127.0.0.1:35729/bud/events" + "?topics=app:ready,build:error")`))
+	// Other clients get text
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	is.Equal(rec.Code, http.StatusInternalServerError)
+	is.Equal(strings.TrimSpace(rec.Body.String()), "./controller/controller.go:6:9: undefined: <hello>")
+}
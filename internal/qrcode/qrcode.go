@@ -0,0 +1,486 @@
+// Package qrcode encodes short text, like URLs, into QR codes that can be
+// printed to the terminal. It only supports byte mode with low error
+// correction in versions 1 through 10, which fits up to 271 bytes.
+package qrcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Code is an encoded QR code
+type Code struct {
+	size     int
+	modules  [][]bool // Dark modules
+	function [][]bool // Finder, timing, alignment and format modules
+}
+
+// versions with low error correction
+var versions = [...]struct {
+	codewords int   // Total codewords
+	ecc       int   // Error correction codewords per block
+	blocks    int   // Number of blocks
+	alignment []int // Alignment pattern positions
+}{
+	1:  {26, 7, 1, nil},
+	2:  {44, 10, 1, []int{6, 18}},
+	3:  {70, 15, 1, []int{6, 22}},
+	4:  {100, 20, 1, []int{6, 26}},
+	5:  {134, 26, 1, []int{6, 30}},
+	6:  {172, 18, 2, []int{6, 34}},
+	7:  {196, 20, 2, []int{6, 22, 38}},
+	8:  {242, 24, 2, []int{6, 24, 42}},
+	9:  {292, 30, 2, []int{6, 26, 46}},
+	10: {346, 18, 4, []int{6, 28, 50}},
+}
+
+// Encode the text into the smallest QR code that fits
+func Encode(text string) (*Code, error) {
+	for version := 1; version < len(versions); version++ {
+		v := versions[version]
+		capacity := v.codewords - v.ecc*v.blocks
+		// Mode, character count and the data itself
+		bits := 4 + countBits(version) + len(text)*8
+		if bits > capacity*8 {
+			continue
+		}
+		data := encodeData(text, version, capacity)
+		code := newCode(version)
+		code.drawCodewords(interleave(data, version))
+		code.applyBestMask()
+		return code, nil
+	}
+	return nil, fmt.Errorf("qrcode: %d bytes is too long to encode", len(text))
+}
+
+// Size is the number of modules along each side
+func (c *Code) Size() int {
+	return c.size
+}
+
+// Dark is true when the module at column x and row y is dark
+func (c *Code) Dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= c.size || y >= c.size {
+		return false
+	}
+	return c.modules[y][x]
+}
+
+// quietZone surrounding the code, in modules
+const quietZone = 4
+
+// String renders the code using half blocks, two rows per line. The colors
+// are set explicitly so the code scans in both light and dark terminals.
+func (c *Code) String() string {
+	sb := new(strings.Builder)
+	for y := -quietZone; y < c.size+quietZone; y += 2 {
+		sb.WriteString("\033[30;47m")
+		for x := -quietZone; x < c.size+quietZone; x++ {
+			top, bottom := c.Dark(x, y), c.Dark(x, y+1)
+			switch {
+			case top && bottom:
+				sb.WriteString("█")
+			case top:
+				sb.WriteString("▀")
+			case bottom:
+				sb.WriteString("▄")
+			default:
+				sb.WriteString(" ")
+			}
+		}
+		sb.WriteString("\033[0m\n")
+	}
+	return sb.String()
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+// encodeData in byte mode, padded out to the capacity
+func encodeData(text string, version, capacity int) []byte {
+	bb := new(bitBuffer)
+	bb.append(0b0100, 4)
+	bb.append(len(text), countBits(version))
+	for i := 0; i < len(text); i++ {
+		bb.append(int(text[i]), 8)
+	}
+	// Terminator, then pad to a byte boundary
+	bb.append(0, min(4, capacity*8-len(bb.bits)))
+	bb.append(0, (8-len(bb.bits)%8)%8)
+	for pad := 0xEC; len(bb.bits) < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+	return bb.bytes()
+}
+
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (value>>i)&1 == 1)
+	}
+}
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, len(b.bits)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return out
+}
+
+// interleave splits the data into blocks, adds error correction to each block
+// and interleaves the result
+func interleave(data []byte, version int) []byte {
+	v := versions[version]
+	shortBlocks := v.blocks - v.codewords%v.blocks
+	shortLen := v.codewords / v.blocks
+	divisor := rsDivisor(v.ecc)
+	blocks := make([][]byte, v.blocks)
+	for i, k := 0, 0; i < v.blocks; i++ {
+		n := shortLen - v.ecc
+		if i >= shortBlocks {
+			n++
+		}
+		block := append([]byte{}, data[k:k+n]...)
+		k += n
+		ecc := rsRemainder(block, divisor)
+		// Pad short blocks so the columns line up
+		if i < shortBlocks {
+			block = append(block, 0)
+		}
+		blocks[i] = append(block, ecc...)
+	}
+	out := make([]byte, 0, v.codewords)
+	for i := range blocks[0] {
+		for j, block := range blocks {
+			// Skip the padding in short blocks
+			if i == shortLen-v.ecc && j < shortBlocks {
+				continue
+			}
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given degree
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords for the data
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+// newCode draws the function patterns for the version
+func newCode(version int) *Code {
+	size := version*4 + 17
+	c := &Code{size: size}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	// Finder patterns with their separators
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+	// Alignment patterns, except where they'd overlap the finders
+	positions := versions[version].alignment
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+	// Reserve the format bits until the mask is chosen
+	c.drawFormat(0)
+	c.drawVersion(version)
+	return c
+}
+
+func (c *Code) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+func (c *Code) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || y < 0 || x >= c.size || y >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.set(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.set(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormat draws the error correction level and mask, twice
+func (c *Code) drawFormat(mask int) {
+	// Low error correction is 0b01
+	data := 1<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+	// Around the top-left finder
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+	// Split between the other finders
+	for i := 0; i < 8; i++ {
+		c.set(c.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, c.size-15+i, bit(i))
+	}
+	// Always dark
+	c.set(8, c.size-8, true)
+}
+
+// drawVersion draws the version next to the top-right and bottom-left finders
+func (c *Code) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := c.size-11+i%3, i/3
+		c.set(a, b, dark)
+		c.set(b, a, dark)
+	}
+}
+
+// drawCodewords zigzags the codewords up and down the columns, two at a time,
+// skipping function modules
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		// Skip the vertical timing pattern
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if c.function[y][x] || i >= len(data)*8 {
+					continue
+				}
+				c.modules[y][x] = (data[i>>3]>>(7-i&7))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyBestMask tries each mask and keeps the one with the lowest penalty
+func (c *Code) applyBestMask() {
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if penalty := c.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		// Masking twice undoes the mask
+		c.applyMask(mask)
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if c.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+var finderLike = [...][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// penalty scores how hard the code is to scan
+func (c *Code) penalty() (score int) {
+	dark := 0
+	for i := 0; i < c.size; i++ {
+		// Runs of five or more modules of the same color in rows and columns
+		rowRun, colRun := 1, 1
+		for j := 1; j < c.size; j++ {
+			rowRun = c.run(rowRun, c.modules[i][j] == c.modules[i][j-1], &score)
+			colRun = c.run(colRun, c.modules[j][i] == c.modules[j-1][i], &score)
+		}
+		c.run(rowRun, false, &score)
+		c.run(colRun, false, &score)
+		for j := 0; j < c.size; j++ {
+			if c.modules[i][j] {
+				dark++
+			}
+			// Patterns that look like finders
+			for _, pattern := range finderLike {
+				if c.matches(pattern, j, i, 1, 0) {
+					score += 40
+				}
+				if c.matches(pattern, i, j, 0, 1) {
+					score += 40
+				}
+			}
+			// 2x2 blocks of the same color
+			if i+1 < c.size && j+1 < c.size {
+				m := c.modules[i][j]
+				if m == c.modules[i][j+1] && m == c.modules[i+1][j] && m == c.modules[i+1][j+1] {
+					score += 3
+				}
+			}
+		}
+	}
+	// Balance of dark and light modules
+	percent := dark * 100 / (c.size * c.size)
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+// run extends the current run or scores it once it ends
+func (c *Code) run(length int, same bool, score *int) int {
+	if same {
+		return length + 1
+	}
+	if length >= 5 {
+		*score += length - 2
+	}
+	return 1
+}
+
+func (c *Code) matches(pattern [11]bool, x, y, dx, dy int) bool {
+	for i, dark := range pattern {
+		px, py := x+dx*i, y+dy*i
+		if px >= c.size || py >= c.size || c.modules[py][px] != dark {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package qrcode_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/qrcode"
+)
+
+func TestEncode(t *testing.T) {
+	is := is.New(t)
+	code, err := qrcode.Encode("http://192.168.1.20:3000")
+	is.NoErr(err)
+	// Version 2
+	is.Equal(code.Size(), 25)
+	// Finder patterns in three corners
+	for _, corner := range [][2]int{{0, 0}, {18, 0}, {0, 18}} {
+		x, y := corner[0], corner[1]
+		is.True(code.Dark(x, y))
+		is.True(code.Dark(x+6, y+6))
+		is.True(!code.Dark(x+1, y+1))
+		is.True(code.Dark(x+3, y+3))
+	}
+	// Always dark module
+	is.True(code.Dark(8, 17))
+	// Outside the code is light
+	is.True(!code.Dark(-1, 0))
+	is.True(!code.Dark(25, 0))
+}
+
+func TestEncodeVersions(t *testing.T) {
+	is := is.New(t)
+	code, err := qrcode.Encode("")
+	is.NoErr(err)
+	is.Equal(code.Size(), 21)
+	code, err = qrcode.Encode(strings.Repeat("a", 200))
+	is.NoErr(err)
+	is.Equal(code.Size(), 53)
+	code, err = qrcode.Encode(strings.Repeat("a", 271))
+	is.NoErr(err)
+	is.Equal(code.Size(), 57)
+}
+
+func TestEncodeTooLong(t *testing.T) {
+	is := is.New(t)
+	code, err := qrcode.Encode(strings.Repeat("a", 272))
+	is.Equal(err.Error(), "qrcode: 272 bytes is too long to encode")
+	is.Equal(code, nil)
+}
+
+func TestString(t *testing.T) {
+	is := is.New(t)
+	code, err := qrcode.Encode("http://192.168.1.20:3000")
+	is.NoErr(err)
+	lines := strings.Split(strings.TrimSuffix(code.String(), "\n"), "\n")
+	// Two rows per line, with a quiet zone of 4 modules
+	is.Equal(len(lines), (25+8+1)/2)
+	is.True(strings.Contains(lines[2], "█▀▀▀▀▀█"))
+}
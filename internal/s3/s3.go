@@ -0,0 +1,349 @@
+// Package s3 stores files in S3-compatible storage like AWS S3, Google Cloud
+// Storage (with HMAC keys), Cloudflare R2 and MinIO. Requests are signed with
+// AWS Signature Version 4.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Credentials sign the requests
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Optional
+}
+
+// Client for a bucket
+type Client struct {
+	Credentials Credentials
+	Region      string
+	Bucket      string
+	// Endpoint of the storage service, like https://storage.googleapis.com.
+	// Defaults to AWS S3 in the region.
+	Endpoint string
+	// PathStyle puts the bucket in the path rather than the host, which most
+	// S3-compatible services other than AWS expect
+	PathStyle bool
+	HTTP      *http.Client
+	Now       func() time.Time
+}
+
+// Load a client for the bucket at bucketURL, like s3://bucket/prefix or
+// gs://bucket/prefix, along with the prefix of its keys. The credentials come
+// from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+// Region defaults to AWS_REGION.
+func Load(getenv func(key string) string, bucketURL, endpoint, region string) (*Client, string, error) {
+	u, err := url.Parse(bucketURL)
+	if err != nil || (u.Scheme != "s3" && u.Scheme != "gs") || u.Host == "" {
+		return nil, "", fmt.Errorf("s3: invalid bucket %q. Expected a URL like s3://bucket/prefix", bucketURL)
+	}
+	client := &Client{
+		Credentials: Credentials{
+			AccessKeyID:     getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    getenv("AWS_SESSION_TOKEN"),
+		},
+		Bucket:   u.Host,
+		Region:   region,
+		Endpoint: endpoint,
+	}
+	if client.Region == "" {
+		client.Region = getenv("AWS_REGION")
+	}
+	// Google Cloud Storage accepts HMAC keys over its S3-compatible API
+	if u.Scheme == "gs" && client.Endpoint == "" {
+		client.Endpoint = "https://storage.googleapis.com"
+		if client.Region == "" {
+			client.Region = "auto"
+		}
+	}
+	if client.Region == "" {
+		client.Region = "us-east-1"
+	}
+	// Services other than AWS tend to expect the bucket in the path
+	client.PathStyle = client.Endpoint != ""
+	return client, strings.Trim(u.Path, "/"), nil
+}
+
+// ErrMissingCredentials is returned by Verify when the keys aren't set
+var ErrMissingCredentials = errors.New("s3: missing the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+
+// Verify that the client has credentials
+func (c *Client) Verify() error {
+	if c.Credentials.AccessKeyID == "" || c.Credentials.SecretAccessKey == "" {
+		return ErrMissingCredentials
+	}
+	return nil
+}
+
+// Object to upload
+type Object struct {
+	Key     string
+	Data    []byte
+	Headers http.Header // e.g. Content-Type and Cache-Control
+}
+
+// Put an object into the bucket
+func (c *Client) Put(ctx context.Context, object *Object) error {
+	u, err := c.url(object.Key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(object.Data))
+	if err != nil {
+		return fmt.Errorf("s3: unable to create request. %w", err)
+	}
+	for key, values := range object.Headers {
+		req.Header[key] = values
+	}
+	c.Sign(req, object.Data)
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("s3: unable to put %q. %w", object.Key, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return c.error(res, "put", object.Key)
+	}
+	return nil
+}
+
+func (c *Client) url(key string) (*url.URL, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + c.Region + ".amazonaws.com"
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", endpoint)
+	}
+	if c.PathStyle {
+		u.Path = "/" + c.Bucket + "/" + key
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	// Send the path exactly as it's signed
+	u.RawPath = escapePath(u.Path)
+	return u, nil
+}
+
+// Get an object from the bucket. The caller closes the body.
+func (c *Client) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	res, err := c.do(ctx, http.MethodGet, key)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		return nil, c.error(res, "get", key)
+	}
+	return res.Body, nil
+}
+
+// Delete an object from the bucket. Deleting a missing object isn't an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	res, err := c.do(ctx, http.MethodDelete, key)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
+		return c.error(res, "delete", key)
+	}
+	return nil
+}
+
+// do sends a request without a payload
+func (c *Client) do(ctx context.Context, method, key string) (*http.Response, error) {
+	u, err := c.url(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("s3: unable to create request. %w", err)
+	}
+	c.Sign(req, nil)
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3: unable to %s %q. %w", strings.ToLower(method), key, err)
+	}
+	return res, nil
+}
+
+// ErrNotFound is returned when the object doesn't exist
+var ErrNotFound = errors.New("s3: object not found")
+
+func (c *Client) error(res *http.Response, action, key string) error {
+	if res.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("s3: unable to %s %q. %w", action, key, ErrNotFound)
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4<<10))
+	return fmt.Errorf("s3: unable to %s %q. %s: %s", action, key, res.Status, strings.TrimSpace(string(body)))
+}
+
+// MaxExpires is the longest a presigned URL can last
+const MaxExpires = 7 * 24 * time.Hour
+
+// Presign returns a URL that gets the object until it expires, without
+// credentials
+func (c *Client) Presign(key string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > MaxExpires {
+		return "", fmt.Errorf("s3: presigned URLs must expire within %s, got %s", MaxExpires, expires)
+	}
+	u, err := c.url(key)
+	if err != nil {
+		return "", err
+	}
+	t := c.now().UTC()
+	scope := t.Format("20060102") + "/" + c.Region + "/s3/aws4_request"
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", c.Credentials.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if c.Credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", c.Credentials.SessionToken)
+	}
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		escapePath(u.Path),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	query.Set("X-Amz-Signature", c.signature(t, canonicalRequest))
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// Sign the request with the payload
+func (c *Client) Sign(req *http.Request, payload []byte) {
+	t := c.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	payloadHash := sha256Hex(payload)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if c.Credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.Credentials.SessionToken)
+	}
+	// Sign every header along with the host
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	canonicalHeaders := new(strings.Builder)
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := t.Format("20060102") + "/" + c.Region + "/s3/aws4_request"
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.Credentials.AccessKeyID, scope, signedHeaders, c.signature(t, canonicalRequest)))
+}
+
+func (c *Client) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// signature of the canonical request at time t
+func (c *Client) signature(t time.Time, canonicalRequest string) string {
+	date := t.Format("20060102")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		t.Format("20060102T150405Z"),
+		date + "/" + c.Region + "/s3/aws4_request",
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+c.Credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// escapePath escapes everything but the unreserved characters and slashes
+func escapePath(path string) string {
+	out := new(strings.Builder)
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if isUnreserved(c) || c == '/' {
+			out.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(out, "%%%02X", c)
+	}
+	return out.String()
+}
+
+func escape(s string) string {
+	return strings.ReplaceAll(escapePath(s), "/", "%2F")
+}
+
+func isUnreserved(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == '~'
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(key)+"="+escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
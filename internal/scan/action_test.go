@@ -7,6 +7,7 @@ import (
 
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/internal/scan"
+	"github.com/livebud/bud/internal/testdir"
 
 	"github.com/livebud/bud/package/vfs"
 )
@@ -73,3 +74,22 @@ func TestControllerScanError(t *testing.T) {
 	is.True(scanner.Err() != nil)
 	is.True(errors.Is(scanner.Err(), fs.ErrInvalid))
 }
+
+// Scan a tree of 55,986 controllers
+func BenchmarkControllerScan(b *testing.B) {
+	dir := b.TempDir()
+	if err := testdir.WriteTree(dir, 5, 6, 6); err != nil {
+		b.Fatal(err)
+	}
+	fsys := vfs.OS(dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		list, err := scan.List(fsys, ".", func(de fs.DirEntry) bool { return true })
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(list) != 9331 {
+			b.Fatalf("expected 9331 directories, got %d", len(list))
+		}
+	}
+}
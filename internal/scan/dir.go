@@ -4,66 +4,68 @@ import (
 	"errors"
 	"io/fs"
 	"path"
-)
-
-// Dir scans a directory
-func Dir(fs fs.FS, dir string, validFn func(de fs.DirEntry) bool) Scanner {
-	s := &dirScanner{
-		fs:      fs,
-		validFn: validFn,
-		textCh:  make(chan string),
-	}
-	go s.walk(dir)
-	return s
-}
+	"sync"
 
-type dirScanner struct {
-	fs      fs.FS
-	validFn func(de fs.DirEntry) bool
-	textCh  chan string
-
-	// The following start as empty values
-	text string
-	err  error
-}
+	"github.com/livebud/bud/package/vfs"
+)
 
-func (s *dirScanner) walk(dir string) {
-	s.walkAll(dir)
-	close(s.textCh)
+// Dir scans a directory for the subdirectories that contain a valid file. The
+// directories are read concurrently when the filesystem allows it, but they're
+// always scanned in the order they'd be walked.
+func Dir(fsys fs.FS, dir string, validFn func(de fs.DirEntry) bool) Scanner {
+	dirs, err := walkDirs(fsys, dir, validFn)
+	return &dirScanner{dirs: dirs, err: err, index: -1}
 }
 
-func (s *dirScanner) walkAll(dir string) {
-	des, err := fs.ReadDir(s.fs, dir)
-	if err != nil {
-		if errors.Is(err, fs.ErrNotExist) {
-			return
+func walkDirs(fsys fs.FS, dir string, validFn func(de fs.DirEntry) bool) ([]string, error) {
+	var mu sync.Mutex
+	seen := map[string]bool{}
+	var dirs []string
+	err := vfs.WalkParallel(fsys, dir, func(fpath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
 		}
-		s.err = err
-		return
-	}
-	// Check if the directory contains a valid file first
-	for _, de := range des {
-		if de.IsDir() || !s.validFn(de) {
-			continue
+		if fpath == dir || !validFn(de) {
+			// Prune invalid directories early
+			if fpath != dir && de.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
 		}
-		s.textCh <- dir
-		break
-	}
-	// Walk the subdirectories
-	for _, de := range des {
-		if !de.IsDir() || !s.validFn(de) {
-			continue
+		if de.IsDir() {
+			return nil
+		}
+		parent := path.Dir(fpath)
+		mu.Lock()
+		if !seen[parent] {
+			seen[parent] = true
+			dirs = append(dirs, parent)
 		}
-		s.walkAll(path.Join(dir, de.Name()))
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	vfs.SortWalk(dirs)
+	return dirs, nil
+}
+
+type dirScanner struct {
+	dirs  []string
+	err   error
+	index int
 }
 
 func (s *dirScanner) Scan() bool {
-	if text, more := <-s.textCh; more {
-		s.text = text
-		return true
+	if s.err != nil || s.index+1 >= len(s.dirs) {
+		return false
 	}
-	return false
+	s.index++
+	return true
 }
 
 func (s *dirScanner) Err() error {
@@ -71,5 +73,5 @@ func (s *dirScanner) Err() error {
 }
 
 func (s *dirScanner) Text() string {
-	return s.text
+	return s.dirs[s.index]
 }
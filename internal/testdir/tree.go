@@ -0,0 +1,31 @@
+package testdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteTree writes a tree of directories into dir that's depth deep, with
+// width subdirectories named d0, d1, ... and files Go files named 0.go, 1.go,
+// ... in each directory. It's used to benchmark walking large trees.
+func WriteTree(dir string, depth, width, files int) error {
+	for i := 0; i < files; i++ {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.go", i)), []byte("package main"), 0644); err != nil {
+			return err
+		}
+	}
+	if depth == 0 {
+		return nil
+	}
+	for i := 0; i < width; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("d%d", i))
+		if err := os.Mkdir(sub, 0755); err != nil {
+			return err
+		}
+		if err := WriteTree(sub, depth-1, width, files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
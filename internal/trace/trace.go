@@ -0,0 +1,153 @@
+// Package trace records a timeline of where the time goes during a build. The
+// timeline is written in Chrome's trace event format, which can be opened in
+// chrome://tracing or https://ui.perfetto.dev.
+//
+// Recorders can be nil, in which case nothing is recorded. This lets callers
+// instrument their code without checking whether profiling is on.
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// New recorder
+func New() *Recorder {
+	return &Recorder{start: time.Now()}
+}
+
+// Recorder of spans
+type Recorder struct {
+	start time.Time
+	mu    sync.Mutex
+	spans []*span
+}
+
+type span struct {
+	category string
+	name     string
+	start    time.Time
+	end      time.Time
+}
+
+// Start a span. Call the returned function when the span ends.
+func (r *Recorder) Start(category, name string) (end func()) {
+	if r == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		r.Add(category, name, start, time.Now())
+	}
+}
+
+// Add a span that was timed elsewhere
+func (r *Recorder) Add(category, name string, start, end time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.spans = append(r.spans, &span{category, name, start, end})
+	r.mu.Unlock()
+}
+
+type recorderKey struct{}
+
+// With returns a context that carries the recorder
+func With(ctx context.Context, r *Recorder) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// From returns the recorder in the context or nil if there isn't one
+func From(ctx context.Context) *Recorder {
+	if ctx == nil {
+		return nil
+	}
+	r, _ := ctx.Value(recorderKey{}).(*Recorder)
+	return r
+}
+
+// event in Chrome's trace event format
+type event struct {
+	Name     string            `json:"name"`
+	Category string            `json:"cat,omitempty"`
+	Phase    string            `json:"ph"`
+	Time     int64             `json:"ts"`            // Microseconds
+	Duration int64             `json:"dur,omitempty"` // Microseconds
+	PID      int               `json:"pid"`
+	TID      int               `json:"tid"`
+	Args     map[string]string `json:"args,omitempty"`
+}
+
+// Write the timeline as a Chrome trace. Each category gets its own rows and
+// spans that overlap, like packages compiled in parallel, are spread across
+// rows so they don't hide each other.
+func (r *Recorder) Write(w io.Writer) error {
+	r.mu.Lock()
+	spans := make([]*span, len(r.spans))
+	copy(spans, r.spans)
+	r.mu.Unlock()
+	// Parents come before the spans nested inside of them
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start.Equal(spans[j].start) {
+			return spans[i].end.After(spans[j].end)
+		}
+		return spans[i].start.Before(spans[j].start)
+	})
+	type row struct {
+		tid   int
+		stack []time.Time // End times of the spans that are still open
+	}
+	rows := map[string][]*row{}
+	events := []*event{}
+	tid := 0
+	for _, s := range spans {
+		var lane *row
+		for _, row := range rows[s.category] {
+			// Close the spans that ended before this one started
+			for len(row.stack) > 0 && !row.stack[len(row.stack)-1].After(s.start) {
+				row.stack = row.stack[:len(row.stack)-1]
+			}
+			// Spans nest within the open span when they end inside of it
+			if len(row.stack) == 0 || !s.end.After(row.stack[len(row.stack)-1]) {
+				lane = row
+				break
+			}
+		}
+		if lane == nil {
+			tid++
+			lane = &row{tid: tid}
+			rows[s.category] = append(rows[s.category], lane)
+			events = append(events, &event{
+				Name:  "thread_name",
+				Phase: "M",
+				PID:   1,
+				TID:   tid,
+				Args:  map[string]string{"name": s.category},
+			})
+		}
+		lane.stack = append(lane.stack, s.end)
+		events = append(events, &event{
+			Name:     s.name,
+			Category: s.category,
+			Phase:    "X",
+			Time:     s.start.Sub(r.start).Microseconds(),
+			Duration: s.end.Sub(s.start).Microseconds(),
+			PID:      1,
+			TID:      lane.tid,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{
+		"traceEvents":     events,
+		"displayTimeUnit": "ms",
+	})
+}
@@ -0,0 +1,87 @@
+package trace_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/trace"
+)
+
+type event struct {
+	Name     string            `json:"name"`
+	Phase    string            `json:"ph"`
+	Time     int64             `json:"ts"`
+	Duration int64             `json:"dur"`
+	TID      int               `json:"tid"`
+	Args     map[string]string `json:"args"`
+}
+
+func read(t testing.TB, r *trace.Recorder) (events []*event) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := r.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	var timeline struct {
+		TraceEvents []*event `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &timeline); err != nil {
+		t.Fatal(err)
+	}
+	return timeline.TraceEvents
+}
+
+func TestRows(t *testing.T) {
+	is := is.New(t)
+	r := trace.New()
+	start := time.Now()
+	r.Add("generate", "bud/internal/app/main.go", start, start.Add(10*time.Millisecond))
+	r.Add("generate", "bud/internal/web/web.go", start.Add(2*time.Millisecond), start.Add(5*time.Millisecond))
+	// Packages compiled at the same time are on separate rows
+	r.Add("compile", "net/http", start.Add(10*time.Millisecond), start.Add(30*time.Millisecond))
+	r.Add("compile", "fmt", start.Add(12*time.Millisecond), start.Add(40*time.Millisecond))
+	r.Add("compile", "main", start.Add(40*time.Millisecond), start.Add(50*time.Millisecond))
+	events := read(t, r)
+	tids := map[string]int{}
+	rows := map[int]string{}
+	for _, e := range events {
+		if e.Phase == "M" {
+			rows[e.TID] = e.Args["name"]
+			continue
+		}
+		is.Equal(e.Phase, "X")
+		tids[e.Name] = e.TID
+	}
+	is.Equal(len(rows), 3)
+	// Nested generators share a row
+	is.Equal(tids["bud/internal/app/main.go"], tids["bud/internal/web/web.go"])
+	is.Equal(rows[tids["bud/internal/app/main.go"]], "generate")
+	is.True(tids["net/http"] != tids["fmt"])
+	is.Equal(tids["net/http"], tids["main"])
+	is.Equal(rows[tids["fmt"]], "compile")
+}
+
+func TestNil(t *testing.T) {
+	var r *trace.Recorder
+	r.Start("generate", "bud/internal/app/main.go")()
+	r.Add("compile", "main", time.Now(), time.Now())
+	ctx := trace.With(context.Background(), r)
+	if trace.From(ctx) != nil {
+		t.Fatal("expected no recorder")
+	}
+}
+
+func TestContext(t *testing.T) {
+	is := is.New(t)
+	r := trace.New()
+	ctx := trace.With(context.Background(), r)
+	trace.From(ctx).Start("esbuild", "bud/view/_ssr.js")()
+	events := read(t, r)
+	is.Equal(len(events), 2)
+	is.Equal(events[1].Name, "bud/view/_ssr.js")
+	is.True(events[1].Time >= 0)
+}
@@ -4,32 +4,128 @@ import { parse } from "../../url"
  * Hot reload
  */
 
+// Topics on bud's event stream that the page reacts to
+const topics = [
+  "frontend:update",
+  "frontend:update:css",
+  "backend:update",
+  "build:error",
+  "app:ready",
+  "app:error",
+]
+
+// Reconnect quickly at first, then back off while the server is down
+const minRetry = 250
+const maxRetry = 5000
+
 export default class Hot {
   private subs: Array<() => void> = []
   private sse: EventSource
   private queue = new Queue()
+  private retry = minRetry
+  private timer?: ReturnType<typeof setTimeout>
+  private disconnected = false
+  private restarting = false
 
-  constructor(path: string, private readonly components: Record<string, any>) {
-    this.sse = new EventSource(path)
-    this.sse.addEventListener("message", this.onmessage)
+  // Subscribe to bud's event stream at url (e.g.
+  // http://127.0.0.1:35729/bud/events) for changes to the page (e.g.
+  // view/index.svelte)
+  constructor(
+    private readonly url: string,
+    private readonly page: string,
+    private readonly components: Record<string, any>
+  ) {
+    this.sse = this.connect()
   }
 
   listen(fn: () => void) {
     this.subs.push(fn)
   }
 
-  private onmessage = (e: MessageEvent) => {
-    // TODO: define a protocol
-    const payload: { scripts: string[]; reload: boolean } = JSON.parse(e.data)
-    if (payload.reload) {
+  private connect(): EventSource {
+    const pageTopic = "frontend:update:" + this.page
+    const query = [...topics, pageTopic].map(encodeURIComponent).join(",")
+    const sse = new EventSource(this.url + "?topics=" + query)
+    sse.addEventListener("open", this.onopen)
+    sse.addEventListener("error", this.onerror)
+    sse.addEventListener("frontend:update", this.onupdate)
+    sse.addEventListener(pageTopic, this.onupdate)
+    sse.addEventListener("frontend:update:css", this.onstylesheets)
+    sse.addEventListener("backend:update", this.onrestart)
+    sse.addEventListener("build:error", this.onfailure)
+    sse.addEventListener("app:error", this.onfailure)
+    sse.addEventListener("app:ready", this.onready)
+    return sse
+  }
+
+  private disconnect() {
+    const pageTopic = "frontend:update:" + this.page
+    this.sse.removeEventListener("open", this.onopen)
+    this.sse.removeEventListener("error", this.onerror)
+    this.sse.removeEventListener("frontend:update", this.onupdate)
+    this.sse.removeEventListener(pageTopic, this.onupdate)
+    this.sse.removeEventListener("frontend:update:css", this.onstylesheets)
+    this.sse.removeEventListener("backend:update", this.onrestart)
+    this.sse.removeEventListener("build:error", this.onfailure)
+    this.sse.removeEventListener("app:error", this.onfailure)
+    this.sse.removeEventListener("app:ready", this.onready)
+    this.sse.close()
+  }
+
+  private onopen = () => {
+    this.retry = minRetry
+    // Changes may have been missed while we were disconnected
+    if (this.disconnected) {
       location.reload()
-      return
     }
+  }
+
+  // Reconnect ourselves, since browsers give up on some errors
+  private onerror = () => {
+    this.disconnected = true
+    this.disconnect()
+    this.timer = setTimeout(() => {
+      this.sse = this.connect()
+    }, this.retry)
+    this.retry = Math.min(this.retry * 2, maxRetry)
+  }
+
+  // Import the page again, busting the browser's cache
+  private onupdate = () => {
+    const script = "/bud/" + this.page + "?ts=" + Date.now()
     this.queue.enqueue(() => {
-      this.loadScripts(payload.scripts).catch((err) => console.error(err))
+      this.loadScripts([script]).catch((err) => console.error(err))
     })
   }
 
+  private onstylesheets = (e: MessageEvent) => {
+    let paths: string[]
+    try {
+      paths = JSON.parse(e.data)
+    } catch (err) {
+      location.reload()
+      return
+    }
+    const ts = Date.now()
+    this.swapStylesheets(paths.map((path) => path + "?ts=" + ts))
+  }
+
+  // The app is restarting, so reload once it's ready
+  private onrestart = () => {
+    this.restarting = true
+  }
+
+  // Reload to show the error
+  private onfailure = () => {
+    location.reload()
+  }
+
+  private onready = () => {
+    if (this.restarting) {
+      location.reload()
+    }
+  }
+
   private async loadScripts(scripts: string[]) {
     for (let scriptPath of scripts) {
       const imported = await import(scriptPath)
@@ -41,9 +137,38 @@ export default class Hot {
     }
   }
 
+  // Swap the stylesheets without reloading the page. The old stylesheet is
+  // removed once the new one loads to avoid a flash of unstyled content.
+  // Stylesheets that aren't linked from the page, like those pulled in with
+  // @import, need a reload.
+  private swapStylesheets(hrefs: string[]) {
+    const links = Array.from(
+      document.querySelectorAll<HTMLLinkElement>('link[rel="stylesheet"]')
+    )
+    for (let href of hrefs) {
+      const pathname = parse(href).pathname
+      const matches = links.filter(
+        (link) => parse(link.href).pathname === pathname
+      )
+      if (matches.length === 0) {
+        location.reload()
+        return
+      }
+      for (let link of matches) {
+        const next = link.cloneNode() as HTMLLinkElement
+        next.href = href
+        next.addEventListener("load", () => link.remove())
+        next.addEventListener("error", () => link.remove())
+        link.after(next)
+      }
+    }
+  }
+
   close() {
-    this.sse.removeEventListener("message", this.onmessage)
-    this.sse.close()
+    if (this.timer) {
+      clearTimeout(this.timer)
+    }
+    this.disconnect()
   }
 }
 
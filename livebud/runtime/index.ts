@@ -5,10 +5,22 @@ export type HydrateInput<Props = Record<string, any>> = {
   frames: any[]
   error?: any
   props: Props
+  // context that components read with getContext(key)
+  context: Record<string, any>
   target: HTMLElement | null
 }
 
-type Hydrate<Props = Record<string, any>> = (input: HydrateInput<Props>) => void
+// View is the mounted page. Svelte components compiled in development can
+// capture and restore their state.
+export type View = {
+  $capture_state?: () => Record<string, any>
+  $inject_state?: (state: Record<string, any>) => void
+  $destroy?: () => void
+}
+
+type Hydrate<Props = Record<string, any>> = (
+  input: HydrateInput<Props>
+) => View | void
 
 /**
  * Mount function
@@ -26,26 +38,63 @@ type MountInput = {
 
 export function mount(input: MountInput): void {
   const props = getProps(document.getElementById("bud_props"))
-  input.createView({
+  const context = getProps(document.getElementById("bud_context"))
+  let view = input.createView({
     page: input.components[input.page],
     frames: input.frames.map((frame) => input.components[frame]),
     error: input.error ? input.components[input.error] : undefined,
     target: input.target,
     props: props,
+    context: context,
   })
   if (input.hot) {
     input.hot.listen(() => {
-      input.createView({
+      // Swap in the updated components, keeping the page's state and scroll
+      // position where we can
+      const state = captureState(view)
+      const scrollX = window.scrollX
+      const scrollY = window.scrollY
+      if (view && view.$destroy) {
+        view.$destroy()
+      }
+      view = input.createView({
         page: input.components[input.page],
         frames: input.frames.map((frame) => input.components[frame]),
         error: input.error ? input.components[input.error] : undefined,
         target: input.target,
         props: props,
+        context: context,
       })
+      injectState(view, state)
+      window.scrollTo(scrollX, scrollY)
     })
   }
 }
 
+function captureState(view: View | void): Record<string, any> | undefined {
+  if (!view || !view.$capture_state) {
+    return undefined
+  }
+  try {
+    return view.$capture_state()
+  } catch (err) {
+    return undefined
+  }
+}
+
+// The component may have changed in ways that its old state no longer fits, in
+// which case it keeps its initial state
+function injectState(view: View | void, state?: Record<string, any>) {
+  if (!view || !view.$inject_state || !state) {
+    return
+  }
+  try {
+    view.$inject_state(state)
+  } catch (err) {
+    console.warn("hot: unable to restore the component's state", err)
+  }
+}
+
 function getProps(node: HTMLElement | null) {
   if (!node || !node.textContent) {
     return {}
@@ -0,0 +1,169 @@
+/**
+ * Imports
+ */
+
+import assert from "internal/assert"
+import Hot from "./hot"
+import { mount, HydrateInput, View } from "."
+
+describe("runtime/mount", () => {
+  let scroll: { x: number; y: number }
+  let warnings: any[][]
+  let warn: typeof console.warn
+
+  beforeEach(() => {
+    scroll = { x: 0, y: 0 }
+    warnings = []
+    warn = console.warn
+    console.warn = (...args: any[]) => warnings.push(args)
+    const g = globalThis as any
+    g.document = {
+      getElementById(id: string) {
+        if (id === "bud_props") {
+          return { textContent: `{"start":1}` }
+        }
+        return null
+      },
+    }
+    g.window = {
+      get scrollX() {
+        return scroll.x
+      },
+      get scrollY() {
+        return scroll.y
+      },
+      scrollTo(x: number, y: number) {
+        scroll = { x, y }
+      },
+    }
+  })
+
+  afterEach(() => {
+    console.warn = warn
+    const g = globalThis as any
+    delete g.document
+    delete g.window
+  })
+
+  it("keeps the component state across hot updates", () => {
+    const hot = new FakeHot()
+    const views: Counter[] = []
+    mount(input(hot, (input) => push(views, new Counter(input))))
+    assert.equal(views.length, 1)
+    assert.equal(views[0].count, 1)
+    views[0].count = 5
+    hot.update()
+    assert.equal(views.length, 2)
+    assert.ok(views[0].destroyed)
+    assert.ok(!views[1].destroyed)
+    assert.equal(views[1].count, 5)
+    // And the next update after that
+    views[1].count = 6
+    hot.update()
+    assert.equal(views[2].count, 6)
+  })
+
+  it("keeps the scroll position across hot updates", () => {
+    const hot = new FakeHot()
+    mount(input(hot, (input) => new Counter(input)))
+    scroll = { x: 10, y: 400 }
+    hot.update()
+    assert.deepEqual(scroll, { x: 10, y: 400 })
+  })
+
+  it("keeps the initial state when the old state doesn't fit", () => {
+    const hot = new FakeHot()
+    const views: Counter[] = []
+    mount(
+      input(hot, (input) => {
+        const view = new Counter(input)
+        // The updated component no longer accepts the old state
+        if (views.length > 0) {
+          view.$inject_state = () => {
+            throw new Error("count is not defined")
+          }
+        }
+        return push(views, view)
+      })
+    )
+    views[0].count = 5
+    hot.update()
+    assert.equal(views[1].count, 1)
+    assert.equal(warnings.length, 1)
+    assert.equal(warnings[0][0], "hot: unable to restore the component's state")
+  })
+
+  it("remounts components that can't capture their state", () => {
+    const hot = new FakeHot()
+    let mounts = 0
+    mount(
+      input(hot, () => {
+        mounts++
+        return {}
+      })
+    )
+    hot.update()
+    assert.equal(mounts, 2)
+    assert.equal(warnings.length, 0)
+  })
+})
+
+/**
+ * Counter is a component compiled in development, which can capture and
+ * restore its state
+ */
+
+class Counter implements View {
+  count: number
+  destroyed = false
+
+  constructor(input: HydrateInput) {
+    this.count = input.props.start
+  }
+
+  $capture_state() {
+    return { count: this.count }
+  }
+
+  $inject_state(state: Record<string, any>) {
+    if ("count" in state) {
+      this.count = state.count
+    }
+  }
+
+  $destroy() {
+    this.destroyed = true
+  }
+}
+
+/**
+ * FakeHot triggers hot updates without a server
+ */
+
+class FakeHot {
+  private subs: Array<() => void> = []
+
+  listen(fn: () => void) {
+    this.subs.push(fn)
+  }
+
+  update() {
+    this.subs.forEach((fn) => fn())
+  }
+}
+
+function input(hot: FakeHot, createView: (input: HydrateInput) => View) {
+  return {
+    components: { "/": {} },
+    page: "/",
+    frames: [],
+    target: {} as HTMLElement,
+    createView,
+    hot: (hot as unknown) as Hot,
+  }
+}
+
+function push<T>(list: T[], item: T): T {
+  list.push(item)
+  return item
+}
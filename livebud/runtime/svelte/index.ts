@@ -1,9 +1,9 @@
-import { HydrateInput } from ".."
+import { HydrateInput, View } from ".."
 
 // TODO:
 // - Support frames
 // - Handle errors
-export default function createView(input: HydrateInput) {
+export default function createView(input: HydrateInput): View {
   if (input.target != null) {
     // TODO: for some reason Svelte isn't able to re-hydrate over itself during
     // a live reload. I wonder if they've figured this out in SvelteKit, but you
@@ -12,9 +12,10 @@ export default function createView(input: HydrateInput) {
     // For now, we'll clear the DOM in our target before hydrating.
     input.target.innerHTML = ""
   }
-  new input.page({
+  return new input.page({
     target: input.target,
     props: input.props,
+    context: new Map(Object.entries(input.context || {})),
     hydrate: true,
   })
 }
@@ -0,0 +1,65 @@
+/**
+ * Imports
+ */
+
+import assert from "internal/assert"
+import createView from "."
+
+describe("runtime/svelte", () => {
+  it("returns the page so its state can be captured", () => {
+    const target = { innerHTML: "<h1>server rendered</h1>" } as HTMLElement
+    const view = createView({
+      page: Page,
+      frames: [],
+      props: { count: 3 },
+      context: { theme: "dark" },
+      target,
+    }) as Page
+    assert.ok(view instanceof Page)
+    assert.equal(target.innerHTML, "")
+    assert.equal(view.options.target, target)
+    assert.deepEqual(view.options.props, { count: 3 })
+    assert.equal(view.options.context.get("theme"), "dark")
+    assert.equal(view.options.hydrate, true)
+    assert.deepEqual(view.$capture_state(), { count: 3 })
+  })
+
+  it("restores the captured state into the new page", () => {
+    const target = { innerHTML: "" } as HTMLElement
+    const input = { page: Page, frames: [], props: { count: 3 }, context: {}, target }
+    const before = createView(input) as Page
+    before.count = 7
+    const state = before.$capture_state()
+    before.$destroy()
+    const after = createView(input) as Page
+    after.$inject_state(state)
+    assert.equal(after.count, 7)
+  })
+})
+
+/**
+ * Page mimics a Svelte component compiled in development
+ */
+
+class Page {
+  count: number
+  destroyed = false
+
+  constructor(readonly options: any) {
+    this.count = options.props.count
+  }
+
+  $capture_state() {
+    return { count: this.count }
+  }
+
+  $inject_state(state: Record<string, any>) {
+    if ("count" in state) {
+      this.count = state.count
+    }
+  }
+
+  $destroy() {
+    this.destroyed = true
+  }
+}
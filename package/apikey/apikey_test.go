@@ -0,0 +1,151 @@
+package apikey_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/apikey"
+	"github.com/livebud/bud/package/clock"
+)
+
+type store map[string]*apikey.Key
+
+func (s store) FindAPIKey(ctx context.Context, prefix string) (*apikey.Key, error) {
+	if prefix == "bud_000000000000" {
+		return nil, fmt.Errorf("database is down")
+	}
+	key, ok := s[prefix]
+	if !ok {
+		return nil, apikey.ErrNotFound
+	}
+	return key, nil
+}
+
+func TestGenerate(t *testing.T) {
+	is := is.New(t)
+	token, key, err := apikey.Generate("posts:read")
+	is.NoErr(err)
+	is.True(strings.HasPrefix(token, key.Prefix+"_"))
+	is.True(strings.HasPrefix(key.Prefix, "bud_"))
+	is.True(!strings.Contains(key.Hash, token))
+	is.True(key.Matches(token))
+	is.True(!key.Matches(token + "x"))
+	is.Equal(key.Display(), key.Prefix+"_…")
+	prefix, ok := apikey.Prefix(token)
+	is.True(ok)
+	is.Equal(prefix, key.Prefix)
+	// Keys are random
+	other, _, err := apikey.Generate()
+	is.NoErr(err)
+	is.True(token != other)
+	// Other tokens aren't keys
+	_, ok = apikey.Prefix("eyJhbGciOiJIUzI1NiJ9.e30.sig")
+	is.True(!ok)
+	_, ok = apikey.Prefix("bud_abc_secret")
+	is.True(!ok)
+	_, ok = apikey.Prefix(key.Prefix + "_")
+	is.True(!ok)
+}
+
+func TestScopes(t *testing.T) {
+	is := is.New(t)
+	key := &apikey.Key{Scopes: []string{"posts:*", "users:read"}}
+	is.True(key.Allows("posts:write"))
+	is.True(key.Allows("users:read"))
+	is.True(!key.Allows("users:write"))
+	is.True((&apikey.Key{Scopes: []string{"*"}}).Allows("users:write"))
+	ctx := apikey.With(context.Background(), key)
+	is.NoErr(apikey.Require(ctx, "posts:read"))
+	is.Equal(apikey.Require(ctx, "users:write").Error(), `apikey: key is missing the "users:write" scope`)
+	is.Equal(apikey.Require(context.Background(), "posts:read").Error(), "apikey: request wasn't made with an api key")
+}
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	token, key, err := apikey.Generate("posts:read")
+	is.NoErr(err)
+	key.Subject = "42"
+	revokedToken, revoked, err := apikey.Generate()
+	is.NoErr(err)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	revoked.RevokedAt = &yesterday
+	expiredToken, expired, err := apikey.Generate()
+	is.NoErr(err)
+	expired.ExpiresAt = &yesterday
+	keys := store{key.Prefix: key, revoked.Prefix: revoked, expired.Prefix: expired}
+	h := apikey.New(keys, "").Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := apikey.From(r.Context()); key != nil {
+			w.Write([]byte(key.Subject))
+		}
+	}))
+	serve := func(path string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name := range header {
+			req.Header.Set(name, header.Get(name))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	errorOf := func(rec *httptest.ResponseRecorder) string {
+		var body struct{ Error string }
+		is.NoErr(json.Unmarshal(rec.Body.Bytes(), &body))
+		return body.Error
+	}
+	// Pages outside of /api don't need a key
+	rec := serve("/", nil)
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "")
+	// API routes do
+	rec = serve("/api/posts", nil)
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(rec.Header().Get("WWW-Authenticate"), "Bearer")
+	is.Equal(errorOf(rec), "missing api key")
+	// Either header works
+	rec = serve("/api/posts", http.Header{"Authorization": {"Bearer " + token}})
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "42")
+	rec = serve("/api/posts", http.Header{"X-Api-Key": {token}})
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "42")
+	// Invalid keys
+	for _, invalid := range []string{"nope", key.Prefix + "_wrong", "bud_unknown_secret"} {
+		rec = serve("/api/posts", http.Header{"X-Api-Key": {invalid}})
+		is.Equal(rec.Code, http.StatusUnauthorized)
+		is.Equal(rec.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`)
+		is.Equal(errorOf(rec), "apikey: invalid key")
+	}
+	rec = serve("/api/posts", http.Header{"X-Api-Key": {revokedToken}})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(errorOf(rec), "apikey: key has been revoked")
+	rec = serve("/api/posts", http.Header{"X-Api-Key": {expiredToken}})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(errorOf(rec), "apikey: key has expired")
+	// Store errors
+	rec = serve("/api/posts", http.Header{"X-Api-Key": {"bud_000000000000_secret"}})
+	is.Equal(rec.Code, http.StatusInternalServerError)
+	is.Equal(errorOf(rec), "database is down")
+}
+
+func TestAuthenticate(t *testing.T) {
+	is := is.New(t)
+	token, key, err := apikey.Generate()
+	is.NoErr(err)
+	m := apikey.New(store{key.Prefix: key}, "/v1")
+	found, err := m.Authenticate(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(found, key)
+	expires := time.Now().Add(time.Hour)
+	key.ExpiresAt = &expires
+	m.Clock = clock.Freeze(expires)
+	_, err = m.Authenticate(context.Background(), token)
+	is.True(errors.Is(err, apikey.ErrExpired))
+}
@@ -0,0 +1,111 @@
+package apikey
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/livebud/bud/package/clock"
+)
+
+// Store looks up keys by their prefix. Return ErrNotFound for unknown keys.
+type Store interface {
+	FindAPIKey(ctx context.Context, prefix string) (*Key, error)
+}
+
+// New middleware that requires an API key for the paths under prefix. The
+// prefix defaults to "/api".
+func New(store Store, prefix string) *Middleware {
+	if prefix == "" {
+		prefix = "/api"
+	}
+	return &Middleware{
+		store:  store,
+		prefix: strings.TrimSuffix(prefix, "/"),
+		Clock:  clock.Load(),
+	}
+}
+
+// Middleware authenticates requests with an API key and adds the key to the
+// context
+type Middleware struct {
+	store  Store
+	prefix string
+
+	// Clock tells the time that keys expire by. Defaults to the system clock.
+	Clock clock.Clock
+}
+
+// Authenticate the token, returning its key
+func (m *Middleware) Authenticate(ctx context.Context, token string) (*Key, error) {
+	prefix, ok := Prefix(token)
+	if !ok {
+		return nil, errInvalid
+	}
+	key, err := m.store.FindAPIKey(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errInvalid
+		}
+		return nil, err
+	}
+	if !key.Matches(token) {
+		return nil, errInvalid
+	}
+	if err := key.Valid(m.Clock.Now()); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// errInvalid is returned for unknown keys without saying why
+var errInvalid = errors.New("apikey: invalid key")
+
+// Middleware requires a valid API key for paths under the prefix
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != m.prefix && !strings.HasPrefix(r.URL.Path, m.prefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := fromRequest(r)
+		if !ok {
+			unauthorized(w, `Bearer`, "missing api key")
+			return
+		}
+		key, err := m.Authenticate(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, errInvalid) || errors.Is(err, ErrRevoked) || errors.Is(err, ErrExpired) {
+				unauthorized(w, `Bearer error="invalid_token"`, err.Error())
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), key)))
+	})
+}
+
+// fromRequest reads the key from the X-API-Key or Authorization header
+func fromRequest(r *http.Request) (string, bool) {
+	if token := strings.TrimSpace(r.Header.Get("X-API-Key")); token != "" {
+		return token, true
+	}
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, challenge, message string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
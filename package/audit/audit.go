@@ -0,0 +1,149 @@
+// Package audit keeps an append-only record of sensitive actions, like who
+// changed a user's role and when. Entries carry the actor, tenant and request
+// ID of the request that made the change:
+//
+//	func (c *Controller) Update(ctx context.Context, id int, role string) error {
+//		user, err := c.DB.FindUser(ctx, id)
+//		// ...
+//		updated := *user
+//		updated.Role = role
+//		// ...save the user...
+//		return audit.Record(ctx, "users:update", "user:"+strconv.Itoa(id), audit.Compare(user, &updated))
+//	}
+//
+// Entries are never updated or removed by the app.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/jwt"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/tenant"
+)
+
+// ErrMissing is returned by Record when the context doesn't have a recorder
+var ErrMissing = errors.New("audit: context doesn't have a recorder")
+
+// Entry in the audit log
+type Entry struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Actor     string    `json:"actor,omitempty"`
+	Tenant    string    `json:"tenant,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	Diff      Diff      `json:"diff,omitempty"`
+}
+
+// Load the middleware from the environment
+func Load(ids idgen.IDGenerator) (*Middleware, error) {
+	store, err := LoadStore(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return New(store, ids), nil
+}
+
+// LoadStore loads the store from the environment:
+//
+//	AUDIT_STORE=file
+//	AUDIT_FILE=log/audit.log
+//
+// AUDIT_STORE may be file or sql. The file store writes to AUDIT_FILE, which
+// defaults to log/audit.log. The SQL store connects using DATABASE_URL.
+func LoadStore(getenv func(key string) string) (Store, error) {
+	switch store := getenv("AUDIT_STORE"); store {
+	case "", "file":
+		path := getenv("AUDIT_FILE")
+		if path == "" {
+			path = "log/audit.log"
+		}
+		return NewFileStore(path), nil
+	case "sql":
+		databaseURL := getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("audit: the sql store requires the DATABASE_URL environment variable")
+		}
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("audit: expected AUDIT_STORE to be file or sql, got %q", store)
+	}
+}
+
+// New recorder that appends entries to the store
+func New(store Store, ids idgen.IDGenerator) *Middleware {
+	return &Middleware{
+		store: store,
+		ids:   ids,
+		now:   time.Now,
+	}
+}
+
+// Middleware adds the recorder to the request's context
+type Middleware struct {
+	store Store
+	ids   idgen.IDGenerator
+	now   func() time.Time
+}
+
+// Middleware implements middleware.Middleware
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), m)))
+	})
+}
+
+// Record an entry. The actor, tenant and request ID come from the context.
+func (m *Middleware) Record(ctx context.Context, action, target string, diff Diff) error {
+	entry := &Entry{
+		ID:        m.ids.NewID(),
+		Time:      m.now().UTC(),
+		Action:    action,
+		Target:    target,
+		RequestID: reqlog.ID(ctx),
+		Diff:      diff,
+	}
+	if userID, ok := auth.UserID(ctx); ok {
+		entry.Actor = strconv.Itoa(userID)
+	} else if subject := jwt.From(ctx).Subject(); subject != "" {
+		entry.Actor = subject
+	}
+	entry.Tenant, _ = tenant.From(ctx)
+	if err := m.store.Append(ctx, entry); err != nil {
+		return fmt.Errorf("audit: unable to record %q. %w", action, err)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// With adds the recorder to the context. Use this to record entries outside
+// of requests, like in jobs.
+func With(ctx context.Context, recorder *Middleware) context.Context {
+	return context.WithValue(ctx, contextKey{}, recorder)
+}
+
+// Record an entry with the context's recorder. Returns ErrMissing when the
+// context doesn't have one.
+func Record(ctx context.Context, action, target string, diff Diff) error {
+	recorder, ok := ctx.Value(contextKey{}).(*Middleware)
+	if !ok {
+		return ErrMissing
+	}
+	return recorder.Record(ctx, action, target, diff)
+}
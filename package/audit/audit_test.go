@@ -0,0 +1,162 @@
+package audit_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/audit"
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/session"
+	"github.com/livebud/bud/package/tenant"
+)
+
+type discard struct{}
+
+func (discard) Log(log.Entry) {}
+
+type User struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	Password string `json:"password" audit:"redact"`
+	Visits   int    `json:"-"`
+	Notes    string `audit:"-"`
+	Email    string `json:"email,omitempty"`
+}
+
+func TestCompare(t *testing.T) {
+	is := is.New(t)
+	before := &User{ID: 1, Name: "Alice", Role: "user", Password: "a", Visits: 1, Notes: "a"}
+	after := &User{ID: 1, Name: "Alice", Role: "admin", Password: "b", Visits: 2, Notes: "b", Email: "a@b.co"}
+	diff := audit.Compare(before, after)
+	is.Equal(diff, audit.Diff{
+		"role":     {From: "user", To: "admin"},
+		"password": {From: "[redacted]", To: "[redacted]"},
+		"email":    {From: "", To: "a@b.co"},
+	})
+	// Created
+	diff = audit.Compare(nil, &User{ID: 2, Name: "Bob"})
+	is.Equal(diff, audit.Diff{
+		"id":       {From: nil, To: 2},
+		"name":     {From: nil, To: "Bob"},
+		"role":     {From: nil, To: ""},
+		"password": {From: "[redacted]", To: "[redacted]"},
+		"email":    {From: nil, To: ""},
+	})
+	// Deleted
+	var deleted *User
+	diff = audit.Compare(User{ID: 3}, deleted)
+	is.Equal(diff["id"], audit.Change{From: 3, To: nil})
+	// Not structs
+	is.Equal(len(audit.Compare(1, 2)), 0)
+}
+
+func TestRecord(t *testing.T) {
+	is := is.New(t)
+	store := audit.NewMemoryStore()
+	recorder := audit.New(store, idgen.Sequential("audit-"))
+	sessions, err := session.New(&session.Config{Secret: "secret"})
+	is.NoErr(err)
+	requestLog := reqlog.Load(log.New(discard{}), idgen.Sequential("req-"))
+	handler := middleware.Compose(sessions, requestLog, recorder).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := tenant.With(r.Context(), "acme")
+		is.NoErr(auth.Login(ctx, 7))
+		diff := audit.Compare(&User{Role: "user"}, &User{Role: "admin"})
+		is.NoErr(audit.Record(ctx, "users:update", "user:1", diff))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/1", nil))
+	entries := store.Entries()
+	is.Equal(len(entries), 1)
+	entry := entries[0]
+	is.Equal(entry.ID, "audit-1")
+	is.Equal(entry.Action, "users:update")
+	is.Equal(entry.Target, "user:1")
+	is.Equal(entry.Actor, "7")
+	is.Equal(entry.Tenant, "acme")
+	is.Equal(entry.RequestID, "req-1")
+	is.Equal(entry.Diff, audit.Diff{"role": {From: "user", To: "admin"}})
+	is.True(!entry.Time.IsZero())
+	// Recording needs a recorder
+	err = audit.Record(context.Background(), "users:update", "user:1", nil)
+	is.Equal(err, audit.ErrMissing)
+	// Unless it's added to the context
+	ctx := audit.With(context.Background(), recorder)
+	is.NoErr(audit.Record(ctx, "users:delete", "user:1", nil))
+	is.Equal(len(store.Entries()), 2)
+}
+
+func TestFileStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "log", "audit.log")
+	store, err := audit.LoadStore(func(key string) string {
+		if key == "AUDIT_FILE" {
+			return path
+		}
+		return ""
+	})
+	is.NoErr(err)
+	ctx = audit.With(ctx, audit.New(store, idgen.Sequential("audit-")))
+	is.NoErr(audit.Record(ctx, "users:update", "user:1", audit.Diff{"role": {From: "user", To: "admin"}}))
+	is.NoErr(audit.Record(ctx, "users:delete", "user:1", nil))
+	file, err := os.Open(path)
+	is.NoErr(err)
+	defer file.Close()
+	var entries []*audit.Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := new(audit.Entry)
+		is.NoErr(json.Unmarshal(scanner.Bytes(), entry))
+		entries = append(entries, entry)
+	}
+	is.Equal(len(entries), 2)
+	is.Equal(entries[0].Action, "users:update")
+	is.Equal(entries[0].Diff["role"].To, "admin")
+	is.Equal(entries[1].ID, "audit-2")
+	is.Equal(len(entries[1].Diff), 0)
+	// Other stores
+	_, err = audit.LoadStore(func(key string) string {
+		if key == "AUDIT_STORE" {
+			return "sql"
+		}
+		return ""
+	})
+	is.Equal(err.Error(), "audit: the sql store requires the DATABASE_URL environment variable")
+}
+
+func TestSQLStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	_, err = db.ExecContext(ctx, `create table bud_audit (id text primary key, time timestamp not null, action text not null, target text not null, actor text, tenant text, request_id text, diff text)`)
+	is.NoErr(err)
+	ctx = audit.With(tenant.With(ctx, "acme"), audit.New(audit.NewSQLStore(db), idgen.Sequential("audit-")))
+	is.NoErr(audit.Record(ctx, "users:update", "user:1", audit.Diff{"role": {From: "user", To: "admin"}}))
+	is.NoErr(audit.Record(ctx, "users:delete", "user:1", nil))
+	var action, target, diff string
+	var actor *string
+	is.NoErr(db.QueryRowContext(ctx, `select action, target, actor, diff from bud_audit where id = 'audit-1'`).Scan(&action, &target, &actor, &diff))
+	is.Equal(action, "users:update")
+	is.Equal(target, "user:1")
+	is.Equal(actor, nil)
+	is.Equal(diff, `{"role":{"from":"user","to":"admin"}}`)
+	var tenantID string
+	var noDiff *string
+	is.NoErr(db.QueryRowContext(ctx, `select tenant, diff from bud_audit where id = 'audit-2'`).Scan(&tenantID, &noDiff))
+	is.Equal(tenantID, "acme")
+	is.Equal(noDiff, nil)
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Diff maps fields to how they changed
+type Diff map[string]Change
+
+// Change of a field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Compare the exported fields of two structs, like a model before and after
+// an update. Fields are named after their JSON tag. Fields tagged with
+// `audit:"-"` are skipped and fields tagged with `audit:"redact"` record that
+// they changed without their values, which is useful for secrets. Either side
+// may be nil, like when a model is created or deleted.
+func Compare(before, after interface{}) Diff {
+	diff := Diff{}
+	from, to := indirect(before), indirect(after)
+	var typ reflect.Type
+	switch {
+	case from.IsValid():
+		typ = from.Type()
+	case to.IsValid():
+		typ = to.Type()
+	default:
+		return diff
+	}
+	if typ.Kind() != reflect.Struct || (to.IsValid() && to.Type() != typ) {
+		return diff
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("audit")
+		if tag == "-" {
+			continue
+		}
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		var change Change
+		if from.IsValid() {
+			change.From = from.Field(i).Interface()
+		}
+		if to.IsValid() {
+			change.To = to.Field(i).Interface()
+		}
+		if reflect.DeepEqual(change.From, change.To) {
+			continue
+		}
+		if tag == "redact" {
+			change = Change{"[redacted]", "[redacted]"}
+		}
+		diff[name] = change
+	}
+	return diff
+}
+
+// indirect dereferences pointers, returning an invalid value for nil
+func indirect(v interface{}) reflect.Value {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return reflect.Value{}
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+// fieldName returns the field's JSON name. It's empty for fields skipped by
+// encoding/json.
+func fieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name
+	}
+	return field.Name
+}
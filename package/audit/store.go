@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+)
+
+// Store appends entries to the audit log
+type Store interface {
+	Append(ctx context.Context, entry *Entry) error
+}
+
+// NewFileStore appends entries to the file as JSON lines. Each entry is synced
+// to disk before Append returns.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// FileStore keeps entries in a file
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+func (s *FileStore) Append(ctx context.Context, entry *Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	// Opened for appending only, so entries are never overwritten
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// NewSQLStore keeps entries in the bud_audit table, which needs to be created
+// with a migration:
+//
+//	create table bud_audit (
+//		id text primary key,
+//		time timestamp not null,
+//		action text not null,
+//		target text not null,
+//		actor text,
+//		tenant text,
+//		request_id text,
+//		diff text
+//	);
+//	create index bud_audit_target on bud_audit (target);
+//
+// Entries are inserted with the request's transaction when there is one, so
+// they're only kept when the change they describe is committed.
+func NewSQLStore(db *dbrt.DB) *SQLStore {
+	return &SQLStore{db}
+}
+
+// SQLStore keeps entries in a Postgres or SQLite database
+type SQLStore struct {
+	db *dbrt.DB
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func (s *SQLStore) Append(ctx context.Context, entry *Entry) error {
+	var diff interface{}
+	if len(entry.Diff) > 0 {
+		data, err := json.Marshal(entry.Diff)
+		if err != nil {
+			return err
+		}
+		diff = string(data)
+	}
+	query := s.db.Dialect().Rebind(`insert into "bud_audit" ("id", "time", "action", "target", "actor", "tenant", "request_id", "diff") values (?, ?, ?, ?, ?, ?, ?, ?)`)
+	_, err := s.db.ExecContext(ctx, query, entry.ID, entry.Time, entry.Action, entry.Target,
+		nullable(entry.Actor), nullable(entry.Tenant), nullable(entry.RequestID), diff)
+	if err != nil {
+		return fmt.Errorf("unable to insert entry. %w", err)
+	}
+	return nil
+}
+
+// nullable stores empty strings as null
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// NewMemoryStore keeps entries in memory, which is useful for tests
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// MemoryStore keeps entries in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries []*Entry
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Append(ctx context.Context, entry *Entry) error {
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+	return nil
+}
+
+// Entries returns the entries from oldest to newest
+func (s *MemoryStore) Entries() []*Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Entry(nil), s.entries...)
+}
@@ -0,0 +1,118 @@
+// Package auth keeps track of the logged in user within the session. It
+// requires the session middleware from package session:
+//
+//	func (c *Controller) Create(ctx context.Context, email, password string) error {
+//		// ...check the email and password...
+//		return auth.Login(ctx, user.ID)
+//	}
+//
+// Run `bud new auth` to scaffold signup, login and logout controllers that use
+// this package.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/livebud/bud/framework/controller/controllerrt/request"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/session"
+)
+
+// Key is the session key that holds the logged in user's ID
+const Key = "user_id"
+
+// ErrNoSession is returned when the session middleware isn't in use
+var ErrNoSession = errors.New("auth: no session within the context. Import github.com/livebud/bud/package/session to enable sessions")
+
+// Login the user. The session is renewed to prevent session fixation.
+func Login(ctx context.Context, userID int) error {
+	s := session.From(ctx)
+	if s == nil {
+		return ErrNoSession
+	}
+	s.Renew()
+	s.Set(Key, userID)
+	return nil
+}
+
+// Logout the user, clearing the session
+func Logout(ctx context.Context) error {
+	s := session.From(ctx)
+	if s == nil {
+		return ErrNoSession
+	}
+	s.Destroy()
+	return nil
+}
+
+// UserID returns the logged in user's ID. It returns false when nobody's
+// logged in.
+func UserID(ctx context.Context) (int, bool) {
+	s := session.From(ctx)
+	if s == nil || s.Get(Key) == nil {
+		return 0, false
+	}
+	return s.Int(Key), true
+}
+
+// NewToken returns a random token for links like email verification, along with
+// its hash. Send the token and store the hash, so a leaked database can't be
+// used to verify accounts.
+func NewToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("auth: unable to generate a token. %w", err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hash of a token to look it up
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequireLogin protects paths from visitors who aren't logged in. Paths match
+// themselves and everything below them, so "/account" protects
+// "/account/settings" too. Browsers are redirected to loginPath, while other
+// clients get a 401 Unauthorized.
+func RequireLogin(loginPath string, paths ...string) middleware.Middleware {
+	return middleware.Function(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == loginPath || !matchAny(paths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := UserID(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if request.Accepts(r).Accepts("text/html") {
+				http.Redirect(w, r, loginPath, http.StatusSeeOther)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"login required"}`))
+		})
+	})
+}
+
+func matchAny(paths []string, urlPath string) bool {
+	for _, path := range paths {
+		path = strings.TrimSuffix(path, "/")
+		if path == "" || urlPath == path || strings.HasPrefix(urlPath, path+"/") {
+			return true
+		}
+	}
+	return false
+}
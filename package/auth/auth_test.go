@@ -0,0 +1,87 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/session"
+)
+
+func TestLoginLogout(t *testing.T) {
+	is := is.New(t)
+	sessions, err := session.New(&session.Config{Secret: "secret"})
+	is.NoErr(err)
+	handler := middleware.Compose(sessions, auth.RequireLogin("/login", "/account")).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		switch r.URL.Path {
+		case "/login":
+			is.NoErr(auth.Login(ctx, 10))
+		case "/logout":
+			is.NoErr(auth.Logout(ctx))
+		}
+		id, ok := auth.UserID(ctx)
+		fmt.Fprintf(w, "%d %v", id, ok)
+	}))
+	var cookie *http.Cookie
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept", accept)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		for _, c := range rec.Result().Cookies() {
+			cookie = c
+		}
+		return rec
+	}
+	// Unprotected
+	rec := get("/", "text/html")
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), "0 false")
+	// Protected
+	rec = get("/account/settings", "text/html")
+	is.Equal(rec.Code, http.StatusSeeOther)
+	is.Equal(rec.Header().Get("Location"), "/login")
+	rec = get("/account", "application/json")
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(rec.Body.String(), `{"error":"login required"}`)
+	// Logged in
+	rec = get("/login", "text/html")
+	is.Equal(rec.Body.String(), "10 true")
+	rec = get("/account", "text/html")
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), "10 true")
+	// Logged out
+	rec = get("/logout", "text/html")
+	is.Equal(rec.Body.String(), "0 false")
+	rec = get("/account", "text/html")
+	is.Equal(rec.Code, http.StatusSeeOther)
+}
+
+func TestNoSession(t *testing.T) {
+	is := is.New(t)
+	err := auth.Login(context.Background(), 1)
+	is.True(errors.Is(err, auth.ErrNoSession))
+	_, ok := auth.UserID(context.Background())
+	is.True(!ok)
+}
+
+func TestToken(t *testing.T) {
+	is := is.New(t)
+	token, hash, err := auth.NewToken()
+	is.NoErr(err)
+	is.True(token != hash)
+	is.Equal(auth.HashToken(token), hash)
+	other, _, err := auth.NewToken()
+	is.NoErr(err)
+	is.True(token != other)
+}
@@ -0,0 +1,127 @@
+// Package authz checks that the current user is allowed to call an action.
+// Controllers declare the permissions their actions require with a directive:
+//
+//	// Delete a post
+//	//authz:require posts:delete
+//	func (c *Controller) Delete(ctx context.Context, id int) error {
+//
+// A directive on the Controller struct applies to every action. The app's
+// policy/policy.go resolves permissions for each request:
+//
+//	type Policy struct {
+//		DB *db.DB
+//	}
+//
+//	func (p *Policy) Allow(ctx context.Context, permission string) (bool, error) {
+//		// ...look up the user's role...
+//	}
+//
+// Requests that aren't allowed get a 403 Forbidden. Add a Forbidden method to
+// the policy to render your own page.
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/livebud/bud/framework/controller/controllerrt/request"
+	"github.com/livebud/bud/package/errors"
+)
+
+// Directive declares the permissions that an action requires
+const Directive = "authz:require"
+
+// ErrForbidden is returned when the policy doesn't allow a permission
+var ErrForbidden = errors.Forbidden.New("authz: forbidden")
+
+// Policy resolves permissions for the current request
+type Policy interface {
+	Allow(ctx context.Context, permission string) (bool, error)
+}
+
+// ForbiddenHandler is an optional interface for policies that render their
+// own 403 page
+type ForbiddenHandler interface {
+	Forbidden(w http.ResponseWriter, r *http.Request)
+}
+
+// Check that the policy allows every permission
+func Check(ctx context.Context, policy Policy, permissions ...string) error {
+	for _, permission := range permissions {
+		allowed, err := policy.Allow(ctx, permission)
+		if err != nil {
+			return fmt.Errorf("authz: unable to check %q. %w", permission, err)
+		}
+		if !allowed {
+			return fmt.Errorf("%w. missing the %q permission", ErrForbidden, permission)
+		}
+	}
+	return nil
+}
+
+// Deny returns the response for a failed check. Forbidden requests get the
+// policy's 403 page when it has one. Other errors get the status of their
+// kind, so a policy can return errors.Unauthorized for a 401.
+func Deny(policy Policy, err error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := errors.Status(err)
+		if errors.Is(err, ErrForbidden) {
+			if handler, ok := policy.(ForbiddenHandler); ok {
+				handler.Forbidden(w, r)
+				return
+			}
+		}
+		if request.Accepts(r).Accepts("text/html") {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	})
+}
+
+// Roles maps roles to the permissions they grant. Permissions may end in a
+// wildcard, so "posts:*" grants "posts:delete" and "*" grants everything.
+//
+//	var roles = authz.Roles{
+//		"admin":  {"*"},
+//		"editor": {"posts:*"},
+//		"user":   {"posts:read"},
+//	}
+type Roles map[string][]string
+
+// Allows checks if any of the roles grant the permission
+func (r Roles) Allows(permission string, roles ...string) bool {
+	for _, role := range roles {
+		for _, granted := range r[role] {
+			if match(granted, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func match(granted, permission string) bool {
+	if granted == "*" || granted == permission {
+		return true
+	}
+	if prefix := strings.TrimSuffix(granted, "*"); prefix != granted {
+		return strings.HasPrefix(permission, prefix)
+	}
+	return false
+}
+
+// Parse the permissions from a directive like "authz:require posts:delete".
+// It returns false for other directives.
+func Parse(directive string) (permissions []string, ok bool) {
+	fields := strings.Fields(directive)
+	if len(fields) == 0 || fields[0] != Directive {
+		return nil, false
+	}
+	return fields[1:], true
+}
@@ -0,0 +1,99 @@
+package authz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/authz"
+	"github.com/livebud/bud/package/errors"
+)
+
+type policy map[string]bool
+
+func (p policy) Allow(ctx context.Context, permission string) (bool, error) {
+	if permission == "broken" {
+		return false, errors.New("database is down")
+	}
+	if permission == "account:read" && !p["loggedIn"] {
+		return false, errors.Unauthorized.New("not logged in")
+	}
+	return p[permission], nil
+}
+
+type customPolicy struct{ policy }
+
+func (customPolicy) Forbidden(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte("go away"))
+}
+
+func TestCheck(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	p := policy{"posts:read": true, "posts:delete": true}
+	is.NoErr(authz.Check(ctx, p, "posts:read", "posts:delete"))
+	err := authz.Check(ctx, p, "posts:read", "users:delete")
+	is.True(errors.Is(err, authz.ErrForbidden))
+	is.Equal(err.Error(), `authz: forbidden. missing the "users:delete" permission`)
+	err = authz.Check(ctx, p, "broken")
+	is.True(!errors.Is(err, authz.ErrForbidden))
+	is.Equal(err.Error(), `authz: unable to check "broken". database is down`)
+}
+
+func deny(policy authz.Policy, err error, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	authz.Deny(policy, err).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDeny(t *testing.T) {
+	is := is.New(t)
+	forbidden := authz.Check(context.Background(), policy{}, "admin")
+	rec := deny(policy{}, forbidden, "application/json")
+	is.Equal(rec.Code, http.StatusForbidden)
+	is.Equal(strings.TrimSpace(rec.Body.String()), `{"error":"authz: forbidden. missing the \"admin\" permission"}`)
+	rec = deny(policy{}, forbidden, "text/html")
+	is.Equal(rec.Code, http.StatusForbidden)
+	is.Equal(strings.TrimSpace(rec.Body.String()), "Forbidden")
+	// Policies can render their own 403 page
+	rec = deny(customPolicy{}, forbidden, "text/html")
+	is.Equal(rec.Code, http.StatusForbidden)
+	is.Equal(rec.Body.String(), "go away")
+	// Errors aren't forbidden
+	rec = deny(customPolicy{}, errors.New("oops"), "text/html")
+	is.Equal(rec.Code, http.StatusInternalServerError)
+	// Policies can return errors with other kinds
+	unauthorized := authz.Check(context.Background(), policy{}, "account:read")
+	rec = deny(customPolicy{}, unauthorized, "application/json")
+	is.Equal(rec.Code, http.StatusUnauthorized)
+}
+
+func TestRoles(t *testing.T) {
+	is := is.New(t)
+	roles := authz.Roles{
+		"admin":  {"*"},
+		"editor": {"posts:*", "comments:read"},
+	}
+	is.True(roles.Allows("users:delete", "admin"))
+	is.True(roles.Allows("posts:delete", "editor"))
+	is.True(roles.Allows("comments:read", "editor"))
+	is.True(!roles.Allows("comments:delete", "editor"))
+	is.True(!roles.Allows("posts:delete"))
+	is.True(!roles.Allows("posts:delete", "unknown"))
+	is.True(roles.Allows("posts:delete", "unknown", "editor"))
+}
+
+func TestParse(t *testing.T) {
+	is := is.New(t)
+	permissions, ok := authz.Parse("authz:require posts:read  posts:delete")
+	is.True(ok)
+	is.Equal(permissions, []string{"posts:read", "posts:delete"})
+	_, ok = authz.Parse("go:noinline")
+	is.True(!ok)
+}
@@ -0,0 +1,312 @@
+// Package bench sends concurrent traffic to routes and measures their
+// latency. It powers `bud bench`.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/router/lex"
+)
+
+// Target is a route to send traffic to
+type Target struct {
+	Method string
+	Route  string // e.g. "/posts/:id"
+	// Params fill in the route's parameters. Values are templates, so
+	// "{{.N}}" spreads requests across IDs.
+	Params map[string]string
+	// Body is a template for the request body. Bodies starting with "{" are
+	// sent as JSON, otherwise they're sent as a form.
+	Body string
+}
+
+// Data passed to the templates
+type Data struct {
+	N int // Number of the request, starting at 1
+}
+
+// Config for a run
+type Config struct {
+	// Concurrency is the number of requests in flight at once
+	Concurrency int
+	// Requests is the number of requests to send
+	Requests int
+	// Duration stops the run early when it's over zero
+	Duration time.Duration
+	// Header is sent with every request
+	Header http.Header
+	// Client sends the requests. Defaults to a client that doesn't follow
+	// redirects.
+	Client *http.Client
+}
+
+// Result of benchmarking a target
+type Result struct {
+	Method string
+	Route  string
+	// Requests that got a response
+	Requests int
+	// Errors are requests that failed or got a 4xx or 5xx status
+	Errors int
+	// Statuses counts the responses by status code
+	Statuses map[int]int
+	// Elapsed time of the run
+	Elapsed time.Duration
+	// Latencies of the requests that got a response, sorted
+	Latencies []time.Duration
+	// Err is the first request error
+	Err error
+}
+
+// Run sends traffic to the target at baseURL until the requests are sent,
+// the duration is over or the context is canceled
+func Run(ctx context.Context, baseURL string, target *Target, config *Config) (*Result, error) {
+	requests, err := compile(baseURL, target)
+	if err != nil {
+		return nil, err
+	}
+	client := config.Client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: config.Concurrency,
+			},
+		}
+	}
+	if config.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Duration)
+		defer cancel()
+	}
+	concurrency := config.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	result := &Result{
+		Method:   target.Method,
+		Route:    target.Route,
+		Statuses: map[int]int{},
+	}
+	var mu sync.Mutex
+	var sent int64
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				n := int(atomic.AddInt64(&sent, 1))
+				if config.Requests > 0 && n > config.Requests {
+					return
+				}
+				req, err := requests.new(ctx, n, config.Header)
+				if err != nil {
+					mu.Lock()
+					result.record(0, 0, err)
+					mu.Unlock()
+					return
+				}
+				began := time.Now()
+				status, err := send(client, req)
+				latency := time.Since(began)
+				// Requests cut off at the end of the run don't count
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				result.record(status, latency, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	sort.Slice(result.Latencies, func(i, j int) bool {
+		return result.Latencies[i] < result.Latencies[j]
+	})
+	return result, nil
+}
+
+func (r *Result) record(status int, latency time.Duration, err error) {
+	if err != nil {
+		r.Errors++
+		if r.Err == nil {
+			r.Err = err
+		}
+		return
+	}
+	r.Requests++
+	r.Statuses[status]++
+	r.Latencies = append(r.Latencies, latency)
+	if status >= 400 {
+		r.Errors++
+	}
+}
+
+// send the request and read the response, so the latency includes the body
+func send(client *http.Client, req *http.Request) (int, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if _, err := io.Copy(io.Discard, res.Body); err != nil {
+		return 0, err
+	}
+	return res.StatusCode, nil
+}
+
+// Percentile returns the latency that p percent of requests were faster
+// than, like 99 for the p99
+func (r *Result) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.Latencies))*p/100+0.5) - 1
+	if i < 0 {
+		i = 0
+	} else if i >= len(r.Latencies) {
+		i = len(r.Latencies) - 1
+	}
+	return r.Latencies[i]
+}
+
+// Throughput in requests per second
+func (r *Result) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// requests creates the target's requests
+type requests struct {
+	method  string
+	baseURL string
+	route   string
+	params  map[string]*template.Template
+	body    *template.Template
+	json    bool
+}
+
+func compile(baseURL string, target *Target) (*requests, error) {
+	r := &requests{
+		method:  strings.ToUpper(target.Method),
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		route:   target.Route,
+		params:  map[string]*template.Template{},
+	}
+	if r.method == "" {
+		r.method = http.MethodGet
+	}
+	slots := slots(target.Route)
+	for key, value := range target.Params {
+		// Other routes' params would end up in the query string
+		if !slots[key] {
+			continue
+		}
+		tpl, err := template.New(key).Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("bench: unable to parse the %q param. %w", key, err)
+		}
+		r.params[key] = tpl
+	}
+	if target.Body != "" {
+		tpl, err := template.New("body").Parse(target.Body)
+		if err != nil {
+			return nil, fmt.Errorf("bench: unable to parse the body for %s %s. %w", r.method, target.Route, err)
+		}
+		r.body = tpl
+		r.json = strings.HasPrefix(strings.TrimSpace(target.Body), "{")
+	}
+	// Check that the route can be filled in
+	if _, err := r.path(1); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// slots returns the names of the route's slots
+func slots(route string) map[string]bool {
+	slots := map[string]bool{}
+	lexer := lex.New(route)
+	for token := lexer.Next(); token.Type != lex.EndToken && token.Type != lex.ErrorToken; token = lexer.Next() {
+		switch token.Type {
+		case lex.SlotToken, lex.QuestionToken, lex.StarToken:
+			slots[strings.Trim(token.Value, ":?*")] = true
+		}
+	}
+	return slots
+}
+
+// path fills in the route's params for the nth request
+func (r *requests) path(n int) (string, error) {
+	params := map[string]string{}
+	for key, tpl := range r.params {
+		value, err := execute(tpl, n)
+		if err != nil {
+			return "", fmt.Errorf("bench: unable to render the %q param. %w", key, err)
+		}
+		params[key] = value
+	}
+	path, err := router.Path(r.route, params)
+	if err != nil {
+		return "", fmt.Errorf("bench: unable to fill in %s. %w", r.route, err)
+	}
+	return path, nil
+}
+
+func (r *requests) new(ctx context.Context, n int, header http.Header) (*http.Request, error) {
+	path, err := r.path(n)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	if r.body != nil {
+		data, err := execute(r.body, n)
+		if err != nil {
+			return nil, fmt.Errorf("bench: unable to render the body. %w", err)
+		}
+		body = strings.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if r.body != nil {
+		if r.json {
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", "application/json")
+		} else {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	return req, nil
+}
+
+func execute(tpl *template.Template, n int) (string, error) {
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, &Data{N: n}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
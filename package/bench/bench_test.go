@@ -0,0 +1,154 @@
+package bench_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/bench"
+)
+
+func TestRun(t *testing.T) {
+	is := is.New(t)
+	var mu sync.Mutex
+	paths := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths[r.URL.RequestURI()]++
+		mu.Unlock()
+		if r.URL.Path == "/posts/3" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	target := &bench.Target{
+		Method: "get",
+		Route:  "/posts/:id",
+		// The slug isn't in the route, so it's ignored
+		Params: map[string]string{"id": "{{.N}}", "slug": "hello"},
+	}
+	result, err := bench.Run(context.Background(), server.URL, target, &bench.Config{
+		Concurrency: 2,
+		Requests:    3,
+	})
+	is.NoErr(err)
+	is.Equal(result.Requests, 3)
+	is.Equal(result.Errors, 1)
+	is.Equal(result.Statuses[200], 2)
+	is.Equal(result.Statuses[404], 1)
+	is.Equal(len(result.Latencies), 3)
+	is.Equal(paths, map[string]int{"/posts/1": 1, "/posts/2": 1, "/posts/3": 1})
+	is.True(result.Percentile(50) <= result.Percentile(99))
+	is.True(result.Throughput() > 0)
+}
+
+func TestBody(t *testing.T) {
+	is := is.New(t)
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.Method+" "+r.Header.Get("Content-Type")+" "+r.Header.Get("X-Test")+" "+string(body))
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+	result, err := bench.Run(context.Background(), server.URL, &bench.Target{
+		Method: "POST",
+		Route:  "/posts",
+		Body:   `{"title": "post {{.N}}"}`,
+	}, &bench.Config{
+		Requests: 1,
+		Header:   http.Header{"X-Test": {"yes"}},
+	})
+	is.NoErr(err)
+	is.Equal(result.Statuses[201], 1)
+	result, err = bench.Run(context.Background(), server.URL, &bench.Target{
+		Method: "POST",
+		Route:  "/posts",
+		Body:   `title=post+{{.N}}`,
+	}, &bench.Config{Requests: 1})
+	is.NoErr(err)
+	is.Equal(result.Requests, 1)
+	is.Equal(bodies, []string{
+		`POST application/json yes {"title": "post 1"}`,
+		`POST application/x-www-form-urlencoded  title=post+1`,
+	})
+}
+
+func TestDuration(t *testing.T) {
+	is := is.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+	result, err := bench.Run(context.Background(), server.URL, &bench.Target{Route: "/"}, &bench.Config{
+		Concurrency: 2,
+		Duration:    100 * time.Millisecond,
+	})
+	is.NoErr(err)
+	is.True(result.Requests > 2)
+	is.Equal(result.Errors, 0)
+	is.True(result.Elapsed < time.Second)
+}
+
+func TestMissingParam(t *testing.T) {
+	is := is.New(t)
+	_, err := bench.Run(context.Background(), "http://localhost", &bench.Target{Route: "/posts/:id"}, &bench.Config{})
+	is.True(err != nil)
+	is.In(err.Error(), `missing the "id" param`)
+}
+
+func TestConnectionError(t *testing.T) {
+	is := is.New(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	result, err := bench.Run(context.Background(), server.URL, &bench.Target{Route: "/"}, &bench.Config{Requests: 2})
+	is.NoErr(err)
+	is.Equal(result.Requests, 0)
+	is.Equal(result.Errors, 2)
+	is.True(result.Err != nil)
+}
+
+func TestPercentile(t *testing.T) {
+	is := is.New(t)
+	result := &bench.Result{}
+	for i := 1; i <= 100; i++ {
+		result.Latencies = append(result.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	is.Equal(result.Percentile(50), 50*time.Millisecond)
+	is.Equal(result.Percentile(99), 99*time.Millisecond)
+	is.Equal(result.Percentile(100), 100*time.Millisecond)
+	is.Equal((&bench.Result{}).Percentile(50), time.Duration(0))
+}
+
+func TestReport(t *testing.T) {
+	is := is.New(t)
+	out := new(strings.Builder)
+	is.NoErr(bench.Report(out, &bench.Result{
+		Method:    "GET",
+		Route:     "/posts/:id",
+		Requests:  2,
+		Errors:    1,
+		Statuses:  map[int]int{500: 1, 200: 1},
+		Elapsed:   time.Second,
+		Latencies: []time.Duration{time.Millisecond, 3 * time.Millisecond},
+		Err:       fmt.Errorf("connection refused"),
+	}))
+	lines := strings.Split(out.String(), "\n")
+	is.In(lines[0], "ROUTE")
+	is.In(lines[1], "GET /posts/:id")
+	is.In(lines[1], "2.0")
+	is.In(lines[1], "1ms")
+	is.In(lines[1], "3ms")
+	is.In(lines[1], "200:1 500:1")
+	is.In(out.String(), "GET /posts/:id failed: connection refused")
+}
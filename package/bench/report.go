@@ -0,0 +1,57 @@
+package bench
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Report writes a table of the results
+func Report(w io.Writer, results ...*Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "ROUTE\tREQUESTS\tERRORS\tREQ/S\tP50\tP90\tP99\tMAX\tSTATUS")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s %s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\n",
+			r.Method, r.Route, r.Requests, r.Errors, r.Throughput(),
+			round(r.Percentile(50)), round(r.Percentile(90)), round(r.Percentile(99)), round(r.Percentile(100)),
+			statuses(r.Statuses))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Fprintf(w, "\n%s %s failed: %s\n", r.Method, r.Route, r.Err)
+		}
+	}
+	return nil
+}
+
+// round the latency to make it readable
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+// statuses formats the status counts, like "200:98 500:2"
+func statuses(counts map[int]int) string {
+	codes := make([]int, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%d:%d", code, counts[code])
+	}
+	return strings.Join(parts, " ")
+}
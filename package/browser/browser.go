@@ -0,0 +1,359 @@
+// Package browser drives a headless Chrome or Chromium over the Chrome
+// DevTools Protocol (CDP). It powers the browser tests in budtest, so
+// hydration and client-side behavior can be tested along with the server.
+//
+//	browser, err := browser.Launch(ctx)
+//	defer browser.Close()
+//	page, err := browser.NewPage(ctx)
+//	err = page.Navigate(ctx, "http://localhost:3000")
+//	var title string
+//	err = page.Evaluate(ctx, "document.title", &title)
+package browser
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// defaultTimeout for shutting down
+const defaultTimeout = 5 * time.Second
+
+// ErrNotFound is returned when Chrome or Chromium isn't installed
+var ErrNotFound = errors.New("browser: unable to find Chrome or Chromium. Install one or set $BUD_CHROME to its path")
+
+// Find the path to Chrome or Chromium. $BUD_CHROME takes precedence, then
+// browsers in $PATH, then the Chromium that Playwright installs.
+func Find() (string, error) {
+	if path := os.Getenv("BUD_CHROME"); path != "" {
+		return path, nil
+	}
+	for _, name := range []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	var paths []string
+	if runtime.GOOS == "darwin" {
+		paths = append(paths,
+			"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+			"/Applications/Chromium.app/Contents/MacOS/Chromium",
+		)
+	}
+	// Chromium installed by "npx playwright install chromium"
+	if cache, err := os.UserCacheDir(); err == nil {
+		matches, _ := filepath.Glob(filepath.Join(cache, "ms-playwright", "chromium-*", "chrome-*", "chrome"))
+		paths = append(paths, matches...)
+	}
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrNotFound
+}
+
+// Launch a headless browser. It runs until it's closed.
+func Launch(ctx context.Context) (*Browser, error) {
+	path, err := Find()
+	if err != nil {
+		return nil, err
+	}
+	dataDir, err := os.MkdirTemp("", "bud-browser-*")
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(path,
+		"--headless=new",
+		"--remote-debugging-port=0",
+		"--remote-allow-origins=*",
+		"--user-data-dir="+dataDir,
+		"--no-first-run",
+		"--no-default-browser-check",
+		"--disable-gpu",
+		"--disable-extensions",
+		"--mute-audio",
+		"about:blank",
+	)
+	// Chrome refuses to run as root with its sandbox, like within containers
+	if os.Geteuid() == 0 {
+		cmd.Args = append(cmd.Args[:1], append([]string{"--no-sandbox"}, cmd.Args[1:]...)...)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		os.RemoveAll(dataDir)
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dataDir)
+		return nil, fmt.Errorf("browser: unable to launch %s. %w", path, err)
+	}
+	url, err := waitForURL(ctx, stderr)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(dataDir)
+		return nil, err
+	}
+	browser, err := Connect(ctx, url)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		os.RemoveAll(dataDir)
+		return nil, err
+	}
+	browser.cmd = cmd
+	browser.dataDir = dataDir
+	return browser, nil
+}
+
+// waitForURL reads the DevTools URL from Chrome's output
+func waitForURL(ctx context.Context, stderr io.Reader) (string, error) {
+	const prefix = "DevTools listening on "
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	found := make(chan string, 1)
+	var output strings.Builder
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, prefix) {
+				found <- strings.TrimSpace(strings.TrimPrefix(line, prefix))
+				// Keep draining, so Chrome doesn't block on a full pipe
+				io.Copy(io.Discard, stderr)
+				return
+			}
+			output.WriteString(line + "\n")
+		}
+		close(found)
+	}()
+	select {
+	case url, ok := <-found:
+		if !ok {
+			return "", fmt.Errorf("browser: exited before it started.\n%s", output.String())
+		}
+		return url, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("browser: timed out waiting for the browser to start. %w", ctx.Err())
+	}
+}
+
+// Connect to a browser that's already running, using its DevTools URL like
+// "ws://127.0.0.1:9222/devtools/browser/<id>"
+func Connect(ctx context.Context, url string) (*Browser, error) {
+	ws, err := websocket.Dial(url, "", "http://localhost")
+	if err != nil {
+		return nil, fmt.Errorf("browser: unable to connect to %s. %w", url, err)
+	}
+	conn := &conn{
+		ws:       ws,
+		pending:  map[int64]chan *message{},
+		sessions: map[string]chan *message{},
+		closed:   make(chan struct{}),
+	}
+	go conn.read()
+	return &Browser{url: url, conn: conn}, nil
+}
+
+// Browser that's driven over CDP
+type Browser struct {
+	url     string
+	conn    *conn
+	cmd     *exec.Cmd // Only set for launched browsers
+	dataDir string
+}
+
+// URL of the browser's DevTools endpoint. Other processes can Connect to it.
+func (b *Browser) URL() string {
+	return b.url
+}
+
+// Close the connection. Launched browsers are shut down too.
+func (b *Browser) Close() error {
+	if b.cmd != nil {
+		// Ask nicely, then make sure it's gone
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		b.conn.call(ctx, "", "Browser.close", nil, nil)
+		cancel()
+	}
+	err := b.conn.close()
+	if b.cmd != nil {
+		exited := make(chan struct{})
+		go func() {
+			b.cmd.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(defaultTimeout):
+			b.cmd.Process.Kill()
+			<-exited
+		}
+		os.RemoveAll(b.dataDir)
+	}
+	return err
+}
+
+// NewPage opens a blank tab
+func (b *Browser) NewPage(ctx context.Context) (*Page, error) {
+	var target struct {
+		TargetID string `json:"targetId"`
+	}
+	if err := b.conn.call(ctx, "", "Target.createTarget", map[string]interface{}{"url": "about:blank"}, &target); err != nil {
+		return nil, err
+	}
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := b.conn.call(ctx, "", "Target.attachToTarget", map[string]interface{}{"targetId": target.TargetID, "flatten": true}, &attached); err != nil {
+		return nil, err
+	}
+	page := &Page{
+		conn:      b.conn,
+		targetID:  target.TargetID,
+		sessionID: attached.SessionID,
+		events:    b.conn.subscribe(attached.SessionID),
+		loaded:    make(chan struct{}, 1),
+	}
+	go page.listen()
+	for _, domain := range []string{"Page.enable", "Runtime.enable"} {
+		if err := page.call(ctx, domain, nil, nil); err != nil {
+			page.Close()
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// message sent or received over CDP
+type message struct {
+	ID        int64           `json:"id,omitempty"`
+	SessionID string          `json:"sessionId,omitempty"`
+	Method    string          `json:"method,omitempty"`
+	Params    interface{}     `json:"params,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     *protocolError  `json:"error,omitempty"`
+	// Params of received events
+	RawParams json.RawMessage `json:"-"`
+}
+
+type protocolError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *protocolError) Error() string {
+	return e.Message
+}
+
+// conn multiplexes calls and events over the websocket
+type conn struct {
+	ws *websocket.Conn
+
+	mu       sync.Mutex
+	nextID   int64
+	pending  map[int64]chan *message
+	sessions map[string]chan *message
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// call a method and decode its result into result, if it's not nil
+func (c *conn) call(ctx context.Context, sessionID, method string, params, result interface{}) error {
+	c.mu.Lock()
+	c.nextID++
+	id := c.nextID
+	reply := make(chan *message, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+	msg := &message{ID: id, SessionID: sessionID, Method: method, Params: params}
+	if err := websocket.JSON.Send(c.ws, msg); err != nil {
+		return fmt.Errorf("browser: unable to call %s. %w", method, err)
+	}
+	select {
+	case res := <-reply:
+		if res.Error != nil {
+			return fmt.Errorf("browser: unable to call %s. %w", method, res.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(res.Result, result); err != nil {
+				return fmt.Errorf("browser: unable to decode the result of %s. %w", method, err)
+			}
+		}
+		return nil
+	case <-c.closed:
+		return fmt.Errorf("browser: unable to call %s. The connection closed", method)
+	case <-ctx.Done():
+		return fmt.Errorf("browser: unable to call %s. %w", method, ctx.Err())
+	}
+}
+
+// subscribe to the events of a session
+func (c *conn) subscribe(sessionID string) chan *message {
+	events := make(chan *message, 64)
+	c.mu.Lock()
+	c.sessions[sessionID] = events
+	c.mu.Unlock()
+	return events
+}
+
+func (c *conn) unsubscribe(sessionID string) {
+	c.mu.Lock()
+	delete(c.sessions, sessionID)
+	c.mu.Unlock()
+}
+
+// read replies and events until the connection closes
+func (c *conn) read() {
+	defer c.close()
+	for {
+		var raw struct {
+			message
+			Params json.RawMessage `json:"params,omitempty"`
+		}
+		if err := websocket.JSON.Receive(c.ws, &raw); err != nil {
+			return
+		}
+		msg := raw.message
+		msg.RawParams = raw.Params
+		c.mu.Lock()
+		if msg.ID != 0 {
+			if reply, ok := c.pending[msg.ID]; ok {
+				reply <- &msg
+			}
+		} else if events, ok := c.sessions[msg.SessionID]; ok {
+			select {
+			case events <- &msg:
+			default:
+				// Drop events nobody is keeping up with, rather than block replies
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *conn) close() (err error) {
+	c.once.Do(func() {
+		close(c.closed)
+		err = c.ws.Close()
+	})
+	return err
+}
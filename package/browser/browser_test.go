@@ -0,0 +1,200 @@
+package browser_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/browser"
+	"golang.org/x/net/websocket"
+)
+
+type request struct {
+	ID        int64           `json:"id"`
+	SessionID string          `json:"sessionId"`
+	Method    string          `json:"method"`
+	Params    json.RawMessage `json:"params"`
+}
+
+// fakeBrowser speaks enough CDP to test the client without Chrome
+type fakeBrowser struct {
+	mu       sync.Mutex
+	methods  []string
+	evaluate func(expr string) interface{}
+}
+
+func (f *fakeBrowser) Methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.methods...)
+}
+
+func (f *fakeBrowser) serve(ws *websocket.Conn) {
+	send := func(v interface{}) { websocket.JSON.Send(ws, v) }
+	reply := func(req *request, result interface{}) {
+		send(map[string]interface{}{"id": req.ID, "sessionId": req.SessionID, "result": result})
+	}
+	for {
+		req := new(request)
+		if err := websocket.JSON.Receive(ws, req); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.methods = append(f.methods, req.Method)
+		f.mu.Unlock()
+		switch req.Method {
+		case "Target.createTarget":
+			reply(req, map[string]string{"targetId": "target-1"})
+		case "Target.attachToTarget":
+			reply(req, map[string]string{"sessionId": "session-1"})
+		case "Page.navigate":
+			var params struct {
+				URL string `json:"url"`
+			}
+			json.Unmarshal(req.Params, &params)
+			if strings.Contains(params.URL, "unreachable") {
+				reply(req, map[string]string{"errorText": "net::ERR_CONNECTION_REFUSED"})
+				continue
+			}
+			reply(req, map[string]string{"frameId": "frame-1"})
+			send(map[string]interface{}{
+				"sessionId": req.SessionID,
+				"method":    "Runtime.exceptionThrown",
+				"params": map[string]interface{}{
+					"exceptionDetails": map[string]interface{}{
+						"text":      "Uncaught",
+						"exception": map[string]string{"description": "Error: hydration failed\n    at main.js:1:1"},
+					},
+				},
+			})
+			send(map[string]interface{}{"sessionId": req.SessionID, "method": "Page.loadEventFired", "params": map[string]float64{"timestamp": 1}})
+		case "Runtime.evaluate":
+			var params struct {
+				Expression string `json:"expression"`
+			}
+			json.Unmarshal(req.Params, &params)
+			value := f.evaluate(params.Expression)
+			if err, ok := value.(error); ok {
+				reply(req, map[string]interface{}{
+					"result": map[string]string{"type": "object"},
+					"exceptionDetails": map[string]interface{}{
+						"text":      "Uncaught",
+						"exception": map[string]string{"description": "Error: " + err.Error() + "\n    at <anonymous>:1:1"},
+					},
+				})
+				continue
+			}
+			reply(req, map[string]interface{}{"result": map[string]interface{}{"type": "string", "value": value}})
+		default:
+			reply(req, map[string]string{})
+		}
+	}
+}
+
+func startFake(t testing.TB, evaluate func(expr string) interface{}) (*fakeBrowser, string) {
+	t.Helper()
+	fake := &fakeBrowser{evaluate: evaluate}
+	server := httptest.NewServer(websocket.Handler(fake.serve))
+	t.Cleanup(server.Close)
+	return fake, "ws" + strings.TrimPrefix(server.URL, "http") + "/devtools/browser/1"
+}
+
+func TestPage(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	fake, url := startFake(t, func(expr string) interface{} {
+		switch {
+		case expr == "document.title":
+			return "Hello"
+		case strings.Contains(expr, `"#missing"`):
+			return errors.New("no element matches #missing")
+		case strings.Contains(expr, "textContent"):
+			return "Count: 1"
+		}
+		return nil
+	})
+	b, err := browser.Connect(ctx, url)
+	is.NoErr(err)
+	defer b.Close()
+	is.Equal(b.URL(), url)
+	page, err := b.NewPage(ctx)
+	is.NoErr(err)
+	is.NoErr(page.Navigate(ctx, "http://localhost:3000"))
+	var title string
+	is.NoErr(page.Evaluate(ctx, "document.title", &title))
+	is.Equal(title, "Hello")
+	text, err := page.Text(ctx, "#count")
+	is.NoErr(err)
+	is.Equal(text, "Count: 1")
+	err = page.Click(ctx, "#missing")
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "no element matches #missing"))
+	errs := page.Errors()
+	is.Equal(len(errs), 1)
+	is.Equal(errs[0].Error(), "Error: hydration failed")
+	is.NoErr(page.Close())
+	is.Equal(strings.Join(fake.Methods(), " "), "Target.createTarget Target.attachToTarget Page.enable Runtime.enable Page.navigate Runtime.evaluate Runtime.evaluate Runtime.evaluate Target.closeTarget")
+}
+
+func TestNavigateError(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, url := startFake(t, func(string) interface{} { return nil })
+	b, err := browser.Connect(ctx, url)
+	is.NoErr(err)
+	defer b.Close()
+	page, err := b.NewPage(ctx)
+	is.NoErr(err)
+	err = page.Navigate(ctx, "http://unreachable")
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "net::ERR_CONNECTION_REFUSED"))
+}
+
+func TestClosed(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, url := startFake(t, func(string) interface{} { return nil })
+	b, err := browser.Connect(ctx, url)
+	is.NoErr(err)
+	page, err := b.NewPage(ctx)
+	is.NoErr(err)
+	is.NoErr(b.Close())
+	err = page.Evaluate(ctx, "1", nil)
+	is.True(err != nil)
+}
+
+func TestLaunch(t *testing.T) {
+	if _, err := browser.Find(); err != nil {
+		t.Skip("skipping because Chrome isn't installed")
+	}
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Hi</title></head><body><button onclick="this.textContent='clicked'">click</button></body></html>`))
+	}))
+	defer server.Close()
+	b, err := browser.Launch(ctx)
+	is.NoErr(err)
+	defer b.Close()
+	page, err := b.NewPage(ctx)
+	is.NoErr(err)
+	is.NoErr(page.Navigate(ctx, server.URL))
+	var title string
+	is.NoErr(page.Evaluate(ctx, "document.title", &title))
+	is.Equal(title, "Hi")
+	is.NoErr(page.Click(ctx, "button"))
+	text, err := page.Text(ctx, "button")
+	is.NoErr(err)
+	is.Equal(text, "clicked")
+}
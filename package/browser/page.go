@@ -0,0 +1,206 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Page is a browser tab
+type Page struct {
+	conn      *conn
+	targetID  string
+	sessionID string
+	events    chan *message
+	loaded    chan struct{}
+
+	mu     sync.Mutex
+	errors []error
+}
+
+// listen for the page's events
+func (p *Page) listen() {
+	for {
+		select {
+		case event := <-p.events:
+			p.handle(event)
+		case <-p.conn.closed:
+			return
+		}
+	}
+}
+
+func (p *Page) handle(event *message) {
+	switch event.Method {
+	case "Page.loadEventFired":
+		select {
+		case p.loaded <- struct{}{}:
+		default:
+		}
+	case "Runtime.exceptionThrown":
+		var params struct {
+			ExceptionDetails exceptionDetails `json:"exceptionDetails"`
+		}
+		if err := json.Unmarshal(event.RawParams, &params); err != nil {
+			return
+		}
+		p.mu.Lock()
+		p.errors = append(p.errors, params.ExceptionDetails.err())
+		p.mu.Unlock()
+	}
+}
+
+func (p *Page) call(ctx context.Context, method string, params, result interface{}) error {
+	return p.conn.call(ctx, p.sessionID, method, params, result)
+}
+
+// Navigate to the url and wait for the page to load
+func (p *Page) Navigate(ctx context.Context, url string) error {
+	// Forget about earlier loads
+	select {
+	case <-p.loaded:
+	default:
+	}
+	var result struct {
+		ErrorText string `json:"errorText"`
+	}
+	if err := p.call(ctx, "Page.navigate", map[string]interface{}{"url": url}, &result); err != nil {
+		return err
+	}
+	if result.ErrorText != "" {
+		return fmt.Errorf("browser: unable to navigate to %s. %s", url, result.ErrorText)
+	}
+	select {
+	case <-p.loaded:
+		return nil
+	case <-p.conn.closed:
+		return fmt.Errorf("browser: unable to navigate to %s. The connection closed", url)
+	case <-ctx.Done():
+		return fmt.Errorf("browser: timed out waiting for %s to load. %w", url, ctx.Err())
+	}
+}
+
+// Evaluate the JavaScript expression in the page and decode its value into
+// result, if it's not nil. Promises are awaited.
+func (p *Page) Evaluate(ctx context.Context, expr string, result interface{}) error {
+	var res struct {
+		Result struct {
+			Type  string          `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *exceptionDetails `json:"exceptionDetails"`
+	}
+	params := map[string]interface{}{
+		"expression":    expr,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}
+	if err := p.call(ctx, "Runtime.evaluate", params, &res); err != nil {
+		return err
+	}
+	if res.ExceptionDetails != nil {
+		return fmt.Errorf("browser: unable to evaluate %q. %w", expr, res.ExceptionDetails.err())
+	}
+	if result == nil || res.Result.Type == "undefined" || len(res.Result.Value) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(res.Result.Value, result); err != nil {
+		return fmt.Errorf("browser: unable to decode the value of %q. %w", expr, err)
+	}
+	return nil
+}
+
+// Click the first element matching the CSS selector
+func (p *Page) Click(ctx context.Context, selector string) error {
+	return p.Evaluate(ctx, fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s)
+		if (!el) throw new Error("no element matches " + %[1]s)
+		el.click()
+	})()`, quote(selector)), nil)
+}
+
+// Fill the input matching the CSS selector with the value. Input and change
+// events are dispatched, so frameworks see the change like they would when
+// typing.
+func (p *Page) Fill(ctx context.Context, selector, value string) error {
+	return p.Evaluate(ctx, fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s)
+		if (!el) throw new Error("no element matches " + %[1]s)
+		el.focus()
+		el.value = %s
+		el.dispatchEvent(new Event("input", { bubbles: true }))
+		el.dispatchEvent(new Event("change", { bubbles: true }))
+	})()`, quote(selector), quote(value)), nil)
+}
+
+// Text returns the text of the first element matching the CSS selector
+func (p *Page) Text(ctx context.Context, selector string) (string, error) {
+	var text string
+	err := p.Evaluate(ctx, fmt.Sprintf(`(() => {
+		const el = document.querySelector(%s)
+		if (!el) throw new Error("no element matches " + %[1]s)
+		return el.textContent
+	})()`, quote(selector)), &text)
+	return text, err
+}
+
+// Exists reports whether an element matches the CSS selector
+func (p *Page) Exists(ctx context.Context, selector string) (bool, error) {
+	var exists bool
+	err := p.Evaluate(ctx, fmt.Sprintf(`document.querySelector(%s) !== null`, quote(selector)), &exists)
+	return exists, err
+}
+
+// URL of the page
+func (p *Page) URL(ctx context.Context) (string, error) {
+	var url string
+	err := p.Evaluate(ctx, `location.href`, &url)
+	return url, err
+}
+
+// HTML of the page
+func (p *Page) HTML(ctx context.Context) (string, error) {
+	var html string
+	err := p.Evaluate(ctx, `document.documentElement.outerHTML`, &html)
+	return html, err
+}
+
+// Errors that were thrown and not caught in the page
+func (p *Page) Errors() []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]error(nil), p.errors...)
+}
+
+// Close the tab
+func (p *Page) Close() error {
+	p.conn.unsubscribe(p.sessionID)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	return p.conn.call(ctx, "", "Target.closeTarget", map[string]interface{}{"targetId": p.targetID}, nil)
+}
+
+// exceptionDetails of an uncaught error
+type exceptionDetails struct {
+	Text      string `json:"text"`
+	Exception *struct {
+		Description string `json:"description"`
+	} `json:"exception"`
+}
+
+func (e exceptionDetails) err() error {
+	if e.Exception != nil && e.Exception.Description != "" {
+		// Descriptions include the stack, the first line is the message
+		message, _, _ := strings.Cut(e.Exception.Description, "\n")
+		return fmt.Errorf("%s", message)
+	}
+	return fmt.Errorf("%s", e.Text)
+}
+
+// quote a string as a JavaScript string literal
+func quote(s string) string {
+	out, _ := json.Marshal(s)
+	return string(out)
+}
@@ -0,0 +1,105 @@
+// Package bud describes the running app's build. Apps built by bud are
+// stamped with their build ID, the commit they were built from and when they
+// were generated.
+package bud
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Set at link time with -ldflags "-X github.com/livebud/bud/package/bud.commit=..."
+var (
+	buildID   string
+	commit    string
+	generated string
+)
+
+// Info about the build
+type Info struct {
+	// BuildID is a hash of the app's code, so builds of the same code have the
+	// same ID
+	BuildID string `json:"build_id,omitempty"`
+	// Commit the app was built from. Empty outside of a git repository.
+	Commit string `json:"commit,omitempty"`
+	// Generated is when the app was built
+	Generated time.Time `json:"generated"`
+	// Bud is the version of bud the app was built with
+	Bud string `json:"bud,omitempty"`
+	// Go is the version of Go the app was built with
+	Go string `json:"go"`
+}
+
+// Version returns information about the app's build. Apps that weren't built
+// by bud fall back to what Go records, like the commit.
+func Version() *Info {
+	info := &Info{
+		BuildID: buildID,
+		Commit:  commit,
+		Go:      runtime.Version(),
+	}
+	if generated != "" {
+		info.Generated, _ = time.Parse(time.RFC3339, generated)
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, dep := range build.Deps {
+		if dep.Path == "github.com/livebud/bud" {
+			info.Bud = dep.Version
+		}
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Generated.IsZero() {
+				info.Generated, _ = time.Parse(time.RFC3339, setting.Value)
+			}
+		}
+	}
+	return info
+}
+
+// String formats the info like "build 1a2b3c (commit 4d5e6f, generated
+// 2022-01-01T00:00:00Z, bud v0.2.8, go1.18)"
+func (i *Info) String() string {
+	var details []string
+	if i.Commit != "" {
+		details = append(details, "commit "+i.Commit)
+	}
+	if !i.Generated.IsZero() {
+		details = append(details, "generated "+i.Generated.UTC().Format(time.RFC3339))
+	}
+	if i.Bud != "" {
+		details = append(details, "bud "+i.Bud)
+	}
+	details = append(details, i.Go)
+	build := i.BuildID
+	if build == "" {
+		build = "unknown"
+	}
+	return "build " + build + " (" + strings.Join(details, ", ") + ")"
+}
+
+// Flags returns the -ldflags that stamp a build with its info
+func Flags(info *Info) string {
+	const pkg = "github.com/livebud/bud/package/bud"
+	var flags []string
+	if info.BuildID != "" {
+		flags = append(flags, "-X "+pkg+".buildID="+info.BuildID)
+	}
+	if info.Commit != "" {
+		flags = append(flags, "-X "+pkg+".commit="+info.Commit)
+	}
+	if !info.Generated.IsZero() {
+		flags = append(flags, "-X "+pkg+".generated="+info.Generated.UTC().Format(time.RFC3339))
+	}
+	return strings.Join(flags, " ")
+}
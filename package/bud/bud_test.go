@@ -0,0 +1,44 @@
+package bud_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/bud"
+)
+
+func TestVersion(t *testing.T) {
+	is := is.New(t)
+	info := bud.Version()
+	is.Equal(info.Go, runtime.Version())
+	// Tests aren't stamped
+	is.Equal(info.BuildID, "")
+}
+
+func TestString(t *testing.T) {
+	is := is.New(t)
+	info := &bud.Info{
+		BuildID:   "1a2b3c",
+		Commit:    "4d5e6f",
+		Generated: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Bud:       "v0.2.8",
+		Go:        "go1.18",
+	}
+	is.Equal(info.String(), "build 1a2b3c (commit 4d5e6f, generated 2022-01-01T00:00:00Z, bud v0.2.8, go1.18)")
+	is.Equal((&bud.Info{Go: "go1.18"}).String(), "build unknown (go1.18)")
+}
+
+func TestFlags(t *testing.T) {
+	is := is.New(t)
+	flags := bud.Flags(&bud.Info{
+		BuildID:   "1a2b3c",
+		Commit:    "4d5e6f",
+		Generated: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	is.Equal(flags, "-X github.com/livebud/bud/package/bud.buildID=1a2b3c "+
+		"-X github.com/livebud/bud/package/bud.commit=4d5e6f "+
+		"-X github.com/livebud/bud/package/bud.generated=2022-01-01T00:00:00Z")
+	is.Equal(bud.Flags(&bud.Info{}), "")
+}
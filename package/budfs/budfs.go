@@ -8,7 +8,9 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/livebud/bud/package/budfs/diskcache"
 	"github.com/livebud/bud/package/budfs/linkmap"
 
 	"github.com/livebud/bud/package/virtual/vcache"
@@ -16,20 +18,25 @@ import (
 	"github.com/livebud/bud/package/virtual"
 
 	"github.com/livebud/bud/internal/dsync"
+	"github.com/livebud/bud/internal/gitignore"
 	"github.com/livebud/bud/internal/glob"
 	"github.com/livebud/bud/internal/once"
 	"github.com/livebud/bud/internal/orderedset"
+	"github.com/livebud/bud/internal/trace"
 	"github.com/livebud/bud/internal/valid"
 	"github.com/livebud/bud/package/budfs/mergefs"
 	"github.com/livebud/bud/package/budfs/treefs"
 	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/vfs"
 )
 
 func New(fsys fs.FS, log log.Interface) *FileSystem {
 	// Exclude the underlying filesystem (often os) from contributing bud/* files.
-	// The bud/* directory is owned by the generator filesytem.
+	// The bud/* directory is owned by the generator filesytem. Files matched by
+	// .gitignore or .budignore are never scanned.
+	ignore := gitignore.FromFS(fsys)
 	fsys = virtual.Exclude(fsys, func(path string) bool {
-		return path == "bud" || strings.HasPrefix(path, "bud/")
+		return path == "bud" || strings.HasPrefix(path, "bud/") || ignore(path)
 	})
 	cache := vcache.New()
 	node := treefs.New(".")
@@ -43,6 +50,9 @@ func New(fsys fs.FS, log log.Interface) *FileSystem {
 		node,
 		linkmap.New(log),
 		log,
+		new(timings),
+		nil,
+		nil,
 	}
 }
 
@@ -54,6 +64,20 @@ type FileSystem struct {
 	node    *treefs.Node
 	lmap    *linkmap.Map
 	log     log.Interface
+	timings *timings
+	trace   *trace.Recorder  // Can be nil
+	disk    *diskcache.Cache // Can be nil
+}
+
+// Trace the generators with the recorder. Generators can find the recorder in
+// their context to trace the work they do, like bundling with esbuild.
+func (f *FileSystem) Trace(recorder *trace.Recorder) {
+	f.trace = recorder
+}
+
+// context passed to the generators
+func (f *FileSystem) context() context.Context {
+	return trace.With(context.TODO(), f.trace)
 }
 
 type File struct {
@@ -223,18 +247,32 @@ func (g *fileGenerator) Generate(target string) (fs.File, error) {
 	if entry, ok := g.fsys.cache.Get(target); ok {
 		return virtual.New(entry), nil
 	}
-	fctx := &fileSystem{context.TODO(), g.fsys, g.fsys.lmap.Scope(target)}
+	// Reuse the file from an earlier session if nothing changed since
+	if data, ok := g.fsys.restore(target); ok {
+		vfile := &virtual.File{
+			Path: g.node.Path(),
+			Mode: g.node.Mode(),
+			Data: data,
+		}
+		g.fsys.cache.Set(target, vfile)
+		return virtual.New(vfile), nil
+	}
+	fctx := g.fsys.scope(target)
 	file := &File{nil, g.path, g.node.Mode(), target}
 	g.fsys.log.Debug("budfs: running file generator function", "target", target)
+	defer g.fsys.trace.Start("generate", target)()
+	start := time.Now()
 	if err := g.fn(fctx, file); err != nil {
 		return nil, err
 	}
+	g.fsys.timings.record(target, start)
 	vfile := &virtual.File{
 		Path: g.node.Path(),
 		Mode: g.node.Mode(),
 		Data: file.Data,
 	}
 	g.fsys.cache.Set(target, vfile)
+	g.fsys.persist(target, file.Data, fctx.deps)
 	return virtual.New(vfile), nil
 }
 
@@ -259,12 +297,15 @@ func (g *dirGenerator) Generate(target string) (fs.File, error) {
 	}
 	// Clear the subdirectories
 	g.node.Clear()
-	fctx := &fileSystem{context.TODO(), g.fsys, g.fsys.lmap.Scope(target)}
+	fctx := g.fsys.scope(target)
 	dir := &Dir{g.fsys, g.node, target}
 	g.fsys.log.Debug("budfs: running dir generator function", "path", g.node.Path(), "target", target)
+	defer g.fsys.trace.Start("generate", g.node.Path())()
+	start := time.Now()
 	if err := g.fn(fctx, dir); err != nil {
 		return nil, err
 	}
+	g.fsys.timings.record(g.node.Path(), start)
 	g.fsys.cache.Set(g.node.Path(), &virtual.Dir{
 		Path:    g.node.Path(),
 		Mode:    g.node.Mode(),
@@ -301,11 +342,22 @@ func (g *fileServer) Generate(target string) (fs.File, error) {
 			Mode: fs.ModeDir,
 		}), nil
 	}
-	fctx := &fileSystem{context.TODO(), g.fsys, g.fsys.lmap.Scope(target)}
+	// Reuse the file from an earlier session if nothing changed since
+	if data, ok := g.fsys.restore(target); ok {
+		vfile := &virtual.File{
+			Path: target,
+			Mode: fs.FileMode(0),
+			Data: data,
+		}
+		g.fsys.cache.Set(target, vfile)
+		return virtual.New(vfile), nil
+	}
+	fctx := g.fsys.scope(target)
 	// File differs slightly than others because g.node.Path() is the directory
 	// path, but we want the target path for serving files.
 	file := &File{nil, g.path, g.node.Mode(), target}
 	g.fsys.log.Debug("budfs: running file server function", "path", g.node.Path(), "target", target)
+	defer g.fsys.trace.Start("generate", target)()
 	if err := g.fn(fctx, file); err != nil {
 		return nil, err
 	}
@@ -315,6 +367,7 @@ func (g *fileServer) Generate(target string) (fs.File, error) {
 		Data: file.Data,
 	}
 	g.fsys.cache.Set(target, vfile)
+	g.fsys.persist(target, file.Data, fctx.deps)
 	return virtual.New(vfile), nil
 }
 
@@ -356,7 +409,7 @@ func (f *FileSystem) Mount(fsys fs.FS) {
 }
 
 // Sync the overlay to the filesystem
-func (f *FileSystem) Sync(writable virtual.FS, to string, options ...dsync.Option) error {
+func (f *FileSystem) Sync(writable vfs.ReadWritable, to string, options ...dsync.Option) error {
 	// Temporarily replace the underlying fs.FS with a cached fs.FS
 	cache := vcache.New()
 	fsys := f.fsys
@@ -387,6 +440,7 @@ type fileSystem struct {
 	ctx  context.Context
 	fsys *FileSystem
 	link *linkmap.List
+	deps *dependencies // Recorded when persisting
 }
 
 var _ FS = (*fileSystem)(nil)
@@ -394,6 +448,7 @@ var _ FS = (*fileSystem)(nil)
 // Open implements fs.FS
 func (f *fileSystem) Open(name string) (fs.File, error) {
 	f.link.Link("open", name)
+	f.deps.add("open", name)
 	file, err := f.fsys.Open(name)
 	if err != nil {
 		return nil, err
@@ -404,6 +459,7 @@ func (f *fileSystem) Open(name string) (fs.File, error) {
 // Watch the paths for changes
 func (f *fileSystem) Watch(paths ...string) error {
 	for _, path := range paths {
+		f.deps.add("watch", path)
 		// Not a glob
 		if glob.Base(path) == path {
 			f.link.Link("watch", path)
@@ -440,6 +496,7 @@ func (f *fileSystem) Glob(pattern string) (matches []string, err error) {
 		return nil, err
 	}
 	// Watch for changes to the pattern
+	f.deps.add("glob", pattern)
 	f.link.Select("glob", func(path string) bool {
 		return matcher.Match(path)
 	})
@@ -465,6 +522,7 @@ func (f *fileSystem) Glob(pattern string) (matches []string, err error) {
 
 // ReadDir implements fs.ReadDirFS
 func (f *fileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
+	f.deps.add("readdir", name)
 	f.link.Select("readdir", func(path string) bool {
 		return path == name || filepath.Dir(path) == name
 	})
@@ -477,7 +535,7 @@ func (f *fileSystem) ReadDir(name string) ([]fs.DirEntry, error) {
 
 func (f *fileSystem) glob(matcher glob.Matcher, base string) (matches []string, err error) {
 	// Walk the directory tree, filtering out non-valid paths
-	err = fs.WalkDir(f.fsys, base, valid.WalkDirFunc(func(path string, de fs.DirEntry, err error) error {
+	err = vfs.WalkDir(f.fsys, base, valid.WalkDirFunc(func(path string, de fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
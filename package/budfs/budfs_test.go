@@ -23,6 +23,7 @@ import (
 
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/budfs/diskcache"
 	"github.com/livebud/bud/package/log/testlog"
 )
 
@@ -76,6 +77,119 @@ func (t *svelte) GenerateFile(fs budfs.FS, file *budfs.File) error {
 	return nil
 }
 
+func TestTimings(t *testing.T) {
+	is := is.New(t)
+	fsys := virtual.Map{}
+	log := testlog.New()
+	bfs := budfs.New(fsys, log)
+	bfs.GenerateFile("bud/b.txt", func(fsys budfs.FS, file *budfs.File) error {
+		time.Sleep(10 * time.Millisecond)
+		file.Data = []byte("b")
+		return nil
+	})
+	bfs.GenerateFile("bud/a.txt", func(fsys budfs.FS, file *budfs.File) error {
+		b, err := fs.ReadFile(fsys, "bud/b.txt")
+		if err != nil {
+			return err
+		}
+		file.Data = append([]byte("a"), b...)
+		return nil
+	})
+	is.Equal(len(bfs.Timings()), 0)
+	code, err := fs.ReadFile(bfs, "bud/a.txt")
+	is.NoErr(err)
+	is.Equal(string(code), "ab")
+	timings := bfs.Timings()
+	is.Equal(len(timings), 2)
+	is.Equal(timings[0].Path, "bud/a.txt")
+	is.Equal(timings[1].Path, "bud/b.txt")
+	// Timings include the generators they depend on
+	is.True(timings[1].Duration >= 10*time.Millisecond)
+	is.True(timings[0].Duration >= timings[1].Duration)
+}
+
+func TestPersist(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	fsys := fstest.MapFS{
+		"view/index.svelte": &fstest.MapFile{Data: []byte("<h1>index</h1>")},
+		"view/about.svelte": &fstest.MapFile{Data: []byte("<h1>about</h1>")},
+	}
+	cache := diskcache.New(t.TempDir(), "v1")
+	count := map[string]int{}
+	load := func() *budfs.FileSystem {
+		bfs := budfs.New(fsys, log)
+		bfs.Persist(cache)
+		bfs.GenerateFile("bud/view.txt", func(fsys budfs.FS, file *budfs.File) error {
+			count["bud/view.txt"]++
+			des, err := fs.ReadDir(fsys, "view")
+			if err != nil {
+				return err
+			}
+			for _, de := range des {
+				file.Data = append(file.Data, de.Name()+" "...)
+			}
+			return nil
+		})
+		bfs.GenerateFile("bud/main.txt", func(fsys budfs.FS, file *budfs.File) error {
+			count["bud/main.txt"]++
+			view, err := fs.ReadFile(fsys, "bud/view.txt")
+			if err != nil {
+				return err
+			}
+			file.Data = append([]byte("main: "), view...)
+			return nil
+		})
+		bfs.GenerateFile("bud/list.txt", func(fsys budfs.FS, file *budfs.File) error {
+			count["bud/list.txt"]++
+			// Reads a generated directory, so it's not persisted
+			des, err := fs.ReadDir(fsys, "bud")
+			if err != nil {
+				return err
+			}
+			file.Data = []byte(strconv.Itoa(len(des)))
+			return nil
+		})
+		return bfs
+	}
+	bfs := load()
+	code, err := fs.ReadFile(bfs, "bud/main.txt")
+	is.NoErr(err)
+	is.Equal(string(code), "main: about.svelte index.svelte ")
+	_, err = fs.ReadFile(bfs, "bud/list.txt")
+	is.NoErr(err)
+	is.Equal(count["bud/main.txt"], 1)
+	is.Equal(count["bud/view.txt"], 1)
+	is.Equal(count["bud/list.txt"], 1)
+	// The next session reuses the generated files
+	bfs = load()
+	code, err = fs.ReadFile(bfs, "bud/main.txt")
+	is.NoErr(err)
+	is.Equal(string(code), "main: about.svelte index.svelte ")
+	_, err = fs.ReadFile(bfs, "bud/list.txt")
+	is.NoErr(err)
+	is.Equal(count["bud/main.txt"], 1)
+	is.Equal(count["bud/view.txt"], 1)
+	is.Equal(count["bud/list.txt"], 2)
+	// Restored files still update when their inputs change
+	fsys["view/contact.svelte"] = &fstest.MapFile{Data: []byte("<h1>contact</h1>")}
+	bfs.Change("view/contact.svelte")
+	code, err = fs.ReadFile(bfs, "bud/main.txt")
+	is.NoErr(err)
+	is.Equal(string(code), "main: about.svelte contact.svelte index.svelte ")
+	is.Equal(count["bud/main.txt"], 2)
+	is.Equal(count["bud/view.txt"], 2)
+	// Changing the contents of an input outdates the cache too
+	fsys["view/index.svelte"] = &fstest.MapFile{Data: []byte("<h1>home</h1>")}
+	bfs = load()
+	code, err = fs.ReadFile(bfs, "bud/main.txt")
+	is.NoErr(err)
+	is.Equal(string(code), "main: about.svelte contact.svelte index.svelte ")
+	is.Equal(count["bud/view.txt"], 3)
+	// The view didn't change, so main is reused
+	is.Equal(count["bud/main.txt"], 2)
+}
+
 func TestFS(t *testing.T) {
 	is := is.New(t)
 	fsys := virtual.Map{}
@@ -549,6 +663,32 @@ func TestReadFsys(t *testing.T) {
 	is.Equal(string(code), "a")
 }
 
+func TestIgnoredFsys(t *testing.T) {
+	is := is.New(t)
+	fsys := virtual.Map{
+		".gitignore":                   &virtual.File{Data: []byte("/bud\n/tmp\n")},
+		".budignore":                   &virtual.File{Data: []byte("/drafts\n")},
+		"view/index.svelte":            &virtual.File{Data: []byte("<h1>index</h1>")},
+		"view/.index.svelte.swp":       &virtual.File{Data: []byte("swap")},
+		"node_modules/svelte/index.js": &virtual.File{Data: []byte("svelte")},
+		"tmp/scratch.go":               &virtual.File{Data: []byte("package tmp")},
+		"drafts/controller/draft.go":   &virtual.File{Data: []byte("package drafts")},
+	}
+	log := testlog.New()
+	bfs := budfs.New(fsys, log)
+	code, err := fs.ReadFile(bfs, "view/index.svelte")
+	is.NoErr(err)
+	is.Equal(string(code), "<h1>index</h1>")
+	_, err = fs.ReadFile(bfs, "view/.index.svelte.swp")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	_, err = fs.ReadFile(bfs, "tmp/scratch.go")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	_, err = fs.ReadFile(bfs, "drafts/controller/draft.go")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	_, err = fs.ReadFile(bfs, "node_modules/svelte/index.js")
+	is.True(errors.Is(err, fs.ErrNotExist))
+}
+
 func TestGenerateFileError(t *testing.T) {
 	is := is.New(t)
 	fsys := virtual.Map{}
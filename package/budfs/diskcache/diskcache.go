@@ -0,0 +1,96 @@
+// Package diskcache stores generated files on disk along with the inputs they
+// were generated from, so they can be reused across sessions.
+package diskcache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cespare/xxhash"
+)
+
+// Entry is a generated file
+type Entry struct {
+	Data []byte
+	Deps []*Dep // Inputs that were used to generate the data
+}
+
+// Dep is an input to a generator, along with a hash of what it was
+type Dep struct {
+	Kind string // open, watch, glob or readdir
+	Path string // Path or pattern
+	Hash string
+}
+
+// New cache stored in dir. Entries that were stored with a different salt are
+// ignored, so the salt should change whenever generators might generate
+// something different from the same inputs, like after upgrading.
+func New(dir, salt string) *Cache {
+	return &Cache{dir, salt}
+}
+
+// Cache of generated files. The cache is safe for concurrent use.
+type Cache struct {
+	dir  string
+	salt string
+}
+
+type stored struct {
+	Salt  string
+	Path  string
+	Entry *Entry
+}
+
+func (c *Cache) filePath(path string) string {
+	return filepath.Join(c.dir, strconv.FormatUint(xxhash.Sum64String(path), 36))
+}
+
+// Get the entry for a path. Missing, corrupt and outdated entries aren't in
+// the cache.
+func (c *Cache) Get(path string) (*Entry, bool) {
+	data, err := os.ReadFile(c.filePath(path))
+	if err != nil {
+		return nil, false
+	}
+	var s stored
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err != nil {
+		return nil, false
+	}
+	if s.Salt != c.salt || s.Path != path || s.Entry == nil {
+		return nil, false
+	}
+	return s.Entry, true
+}
+
+// Set the entry for a path
+func (c *Cache) Set(path string, entry *Entry) error {
+	buf := new(bytes.Buffer)
+	if err := gob.NewEncoder(buf).Encode(&stored{c.salt, path, entry}); err != nil {
+		return fmt.Errorf("diskcache: unable to encode %q. %w", path, err)
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("diskcache: unable to store %q. %w", path, err)
+	}
+	// Write to a temporary file first so readers never see a partial entry
+	filePath := c.filePath(path)
+	tmp, err := os.CreateTemp(c.dir, filepath.Base(filePath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("diskcache: unable to store %q. %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("diskcache: unable to store %q. %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("diskcache: unable to store %q. %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("diskcache: unable to store %q. %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package diskcache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/budfs/diskcache"
+)
+
+func TestGetSet(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	cache := diskcache.New(dir, "v1")
+	_, ok := cache.Get("bud/view/_ssr.js")
+	is.True(!ok)
+	err := cache.Set("bud/view/_ssr.js", &diskcache.Entry{
+		Data: []byte("ssr"),
+		Deps: []*diskcache.Dep{{Kind: "watch", Path: "view/index.svelte", Hash: "abc"}},
+	})
+	is.NoErr(err)
+	entry, ok := cache.Get("bud/view/_ssr.js")
+	is.True(ok)
+	is.Equal(string(entry.Data), "ssr")
+	is.Equal(len(entry.Deps), 1)
+	is.Equal(entry.Deps[0].Path, "view/index.svelte")
+	is.Equal(entry.Deps[0].Hash, "abc")
+	// Entries persist
+	entry, ok = diskcache.New(dir, "v1").Get("bud/view/_ssr.js")
+	is.True(ok)
+	is.Equal(string(entry.Data), "ssr")
+	// Entries with a different salt are outdated
+	_, ok = diskcache.New(dir, "v2").Get("bud/view/_ssr.js")
+	is.True(!ok)
+}
+
+func TestCorrupt(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	cache := diskcache.New(dir, "v1")
+	is.NoErr(cache.Set("bud/view/_ssr.js", &diskcache.Entry{Data: []byte("ssr")}))
+	des, err := os.ReadDir(dir)
+	is.NoErr(err)
+	is.Equal(len(des), 1)
+	is.NoErr(os.WriteFile(filepath.Join(dir, des[0].Name()), []byte("corrupt"), 0644))
+	_, ok := cache.Get("bud/view/_ssr.js")
+	is.True(!ok)
+}
@@ -0,0 +1,227 @@
+package budfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash"
+	"github.com/livebud/bud/internal/glob"
+	"github.com/livebud/bud/package/budfs/diskcache"
+	"github.com/livebud/bud/package/budfs/linkmap"
+)
+
+// Persist generated files to the disk cache, so later sessions can reuse them
+// instead of generating them again. Files are reused when the inputs their
+// generator read are unchanged.
+//
+// Only file generators and file servers are persisted. Generators that glob or
+// read generated directories are always run, since their inputs can't be
+// checked without generating them first.
+func (f *FileSystem) Persist(cache *diskcache.Cache) {
+	f.disk = cache
+}
+
+// scope a generator's filesystem to its target
+func (f *FileSystem) scope(target string) *fileSystem {
+	fsys := &fileSystem{f.context(), f, f.lmap.Scope(target), nil}
+	if f.disk != nil {
+		fsys.deps = new(dependencies)
+	}
+	return fsys
+}
+
+// dependencies are the inputs a generator read
+type dependencies struct {
+	mu   sync.Mutex
+	list []*diskcache.Dep
+}
+
+func (d *dependencies) add(kind, path string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	d.list = append(d.list, &diskcache.Dep{Kind: kind, Path: path})
+	d.mu.Unlock()
+}
+
+// restore the generated file from the disk cache when its inputs haven't
+// changed. Checking the inputs links them to the target again, so changes to
+// them still update the cache.
+func (f *FileSystem) restore(target string) ([]byte, bool) {
+	if f.disk == nil {
+		return nil, false
+	}
+	entry, ok := f.disk.Get(target)
+	if !ok {
+		return nil, false
+	}
+	defer f.trace.Start("restore", target)()
+	fsys := &fileSystem{f.context(), f, f.lmap.Scope(target), nil}
+	for _, dep := range entry.Deps {
+		hash, err := fsys.fingerprint(dep.Kind, dep.Path)
+		if err != nil || hash != dep.Hash {
+			f.log.Debug("budfs: disk cache is outdated", "target", target, "input", dep.Path)
+			return nil, false
+		}
+	}
+	f.log.Debug("budfs: restored from disk cache", "target", target)
+	return entry.Data, true
+}
+
+// persist the generated file to the disk cache
+func (f *FileSystem) persist(target string, data []byte, deps *dependencies) {
+	if f.disk == nil || deps == nil {
+		return
+	}
+	deps.mu.Lock()
+	list := deps.list
+	deps.mu.Unlock()
+	// Fingerprint with links of our own, the generator already linked its inputs
+	fsys := &fileSystem{f.context(), f, linkmap.New(f.log).Scope(target), nil}
+	entry := &diskcache.Entry{Data: data}
+	seen := map[string]bool{}
+	for _, dep := range list {
+		key := dep.Kind + ":" + dep.Path
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if dep.Kind != "open" && isGenerated(glob.Base(dep.Path)) {
+			f.log.Debug("budfs: not persisting", "target", target, "input", dep.Path)
+			return
+		}
+		hash, err := fsys.fingerprint(dep.Kind, dep.Path)
+		if err != nil {
+			f.log.Debug("budfs: not persisting", "target", target, "input", dep.Path, "err", err)
+			return
+		}
+		entry.Deps = append(entry.Deps, &diskcache.Dep{Kind: dep.Kind, Path: dep.Path, Hash: hash})
+	}
+	if err := f.disk.Set(target, entry); err != nil {
+		f.log.Debug("budfs: unable to persist", "target", target, "err", err)
+	}
+}
+
+func isGenerated(path string) bool {
+	return path == "bud" || strings.HasPrefix(path, "bud/")
+}
+
+// fingerprint an input the same way the generator read it. Inputs include the
+// contents of everything that would update the target when it changes.
+func (f *fileSystem) fingerprint(kind, name string) (string, error) {
+	h := xxhash.New()
+	switch kind {
+	case "open":
+		file, err := f.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "missing", nil
+			}
+			return "", err
+		}
+		file.Close()
+		if err := f.hash(h, name); err != nil {
+			return "", err
+		}
+	case "watch":
+		if err := f.Watch(name); err != nil {
+			return "", err
+		}
+		if glob.Base(name) == name {
+			if err := f.hash(h, name); err != nil {
+				return "", err
+			}
+			break
+		}
+		matcher, err := glob.Compile(name)
+		if err != nil {
+			return "", err
+		}
+		matches, err := f.glob(matcher, glob.Base(name))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		for _, match := range matches {
+			if err := f.hash(h, match); err != nil {
+				return "", err
+			}
+		}
+	case "glob":
+		matches, err := f.Glob(name)
+		if err != nil {
+			return "", err
+		}
+		for _, match := range matches {
+			if err := f.hash(h, match); err != nil {
+				return "", err
+			}
+		}
+	case "readdir":
+		if _, err := f.ReadDir(name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return "missing", nil
+			}
+			return "", err
+		}
+		if err := f.hash(h, name); err != nil {
+			return "", err
+		}
+	default:
+		return "", fs.ErrInvalid
+	}
+	return strconv.FormatUint(h.Sum64(), 36), nil
+}
+
+// hash the path's contents without linking it. Directories are hashed with
+// the contents of the files directly within them, since generators often
+// parse a directory after finding it. Generated directories are only hashed
+// by name, since their files would need to be generated first.
+func (f *fileSystem) hash(h io.Writer, name string) error {
+	info, err := fs.Stat(f.fsys.fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			io.WriteString(h, name+":missing\n")
+			return nil
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return f.hashFile(h, name)
+	}
+	des, err := fs.ReadDir(f.fsys.fsys, name)
+	if err != nil {
+		return err
+	}
+	sort.Slice(des, func(i, j int) bool { return des[i].Name() < des[j].Name() })
+	io.WriteString(h, name+":dir\n")
+	for _, de := range des {
+		if de.IsDir() {
+			io.WriteString(h, de.Name()+"/\n")
+			continue
+		} else if isGenerated(name) {
+			io.WriteString(h, de.Name()+"\n")
+			continue
+		}
+		if err := f.hashFile(h, path.Join(name, de.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fileSystem) hashFile(h io.Writer, name string) error {
+	data, err := fs.ReadFile(f.fsys.fsys, name)
+	if err != nil {
+		return err
+	}
+	io.WriteString(h, name+":"+strconv.Itoa(len(data))+"\n")
+	h.Write(data)
+	return nil
+}
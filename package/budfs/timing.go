@@ -0,0 +1,43 @@
+package budfs
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Timing is how long a generator took the last time it ran, including the time
+// spent generating the files it depends on
+type Timing struct {
+	Path     string
+	Duration time.Duration
+}
+
+type timings struct {
+	mu sync.Mutex
+	m  map[string]time.Duration
+}
+
+func (t *timings) record(path string, start time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.m == nil {
+		t.m = map[string]time.Duration{}
+	}
+	t.m[path] = time.Since(start)
+}
+
+// Timings returns how long each generator took the last time it ran, sorted by
+// path. Generators that are served from the cache keep their last timing.
+func (f *FileSystem) Timings() []*Timing {
+	f.timings.mu.Lock()
+	defer f.timings.mu.Unlock()
+	timings := make([]*Timing, 0, len(f.timings.m))
+	for path, duration := range f.timings.m {
+		timings = append(timings, &Timing{path, duration})
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Path < timings[j].Path
+	})
+	return timings
+}
@@ -0,0 +1,419 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             (unknown)
+// source: budgrpc.proto
+
+package budgrpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// BudClient is the client API for Bud service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BudClient interface {
+	Open(ctx context.Context, in *OpenRequest, opts ...grpc.CallOption) (*OpenResponse, error)
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
+	// Read streams a file's contents in chunks starting at the offset
+	Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (Bud_ReadClient, error)
+	Script(ctx context.Context, in *ScriptRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	Eval(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error)
+	RenderBatch(ctx context.Context, in *RenderBatchRequest, opts ...grpc.CallOption) (*RenderBatchResponse, error)
+	Publish(ctx context.Context, in *Event, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Subscribe streams events until the client goes away. The server sends the
+	// headers once it's subscribed.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Bud_SubscribeClient, error)
+}
+
+type budClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBudClient(cc grpc.ClientConnInterface) BudClient {
+	return &budClient{cc}
+}
+
+func (c *budClient) Open(ctx context.Context, in *OpenRequest, opts ...grpc.CallOption) (*OpenResponse, error) {
+	out := new(OpenResponse)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/Open", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error) {
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/Stat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) Read(ctx context.Context, in *ReadRequest, opts ...grpc.CallOption) (Bud_ReadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Bud_ServiceDesc.Streams[0], "/budhttp.Bud/Read", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &budReadClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bud_ReadClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type budReadClient struct {
+	grpc.ClientStream
+}
+
+func (x *budReadClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *budClient) Script(ctx context.Context, in *ScriptRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/Script", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) Eval(ctx context.Context, in *EvalRequest, opts ...grpc.CallOption) (*EvalResponse, error) {
+	out := new(EvalResponse)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/Eval", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) RenderBatch(ctx context.Context, in *RenderBatchRequest, opts ...grpc.CallOption) (*RenderBatchResponse, error) {
+	out := new(RenderBatchResponse)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/RenderBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) Publish(ctx context.Context, in *Event, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/budhttp.Bud/Publish", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *budClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (Bud_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Bud_ServiceDesc.Streams[1], "/budhttp.Bud/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &budSubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Bud_SubscribeClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type budSubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *budSubscribeClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BudServer is the server API for Bud service.
+// All implementations must embed UnimplementedBudServer
+// for forward compatibility
+type BudServer interface {
+	Open(context.Context, *OpenRequest) (*OpenResponse, error)
+	Stat(context.Context, *StatRequest) (*StatResponse, error)
+	// Read streams a file's contents in chunks starting at the offset
+	Read(*ReadRequest, Bud_ReadServer) error
+	Script(context.Context, *ScriptRequest) (*emptypb.Empty, error)
+	Eval(context.Context, *EvalRequest) (*EvalResponse, error)
+	RenderBatch(context.Context, *RenderBatchRequest) (*RenderBatchResponse, error)
+	Publish(context.Context, *Event) (*emptypb.Empty, error)
+	// Subscribe streams events until the client goes away. The server sends the
+	// headers once it's subscribed.
+	Subscribe(*SubscribeRequest, Bud_SubscribeServer) error
+	mustEmbedUnimplementedBudServer()
+}
+
+// UnimplementedBudServer must be embedded to have forward compatible implementations.
+type UnimplementedBudServer struct {
+}
+
+func (UnimplementedBudServer) Open(context.Context, *OpenRequest) (*OpenResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Open not implemented")
+}
+func (UnimplementedBudServer) Stat(context.Context, *StatRequest) (*StatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (UnimplementedBudServer) Read(*ReadRequest, Bud_ReadServer) error {
+	return status.Errorf(codes.Unimplemented, "method Read not implemented")
+}
+func (UnimplementedBudServer) Script(context.Context, *ScriptRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Script not implemented")
+}
+func (UnimplementedBudServer) Eval(context.Context, *EvalRequest) (*EvalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Eval not implemented")
+}
+func (UnimplementedBudServer) RenderBatch(context.Context, *RenderBatchRequest) (*RenderBatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenderBatch not implemented")
+}
+func (UnimplementedBudServer) Publish(context.Context, *Event) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Publish not implemented")
+}
+func (UnimplementedBudServer) Subscribe(*SubscribeRequest, Bud_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (UnimplementedBudServer) mustEmbedUnimplementedBudServer() {}
+
+// UnsafeBudServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BudServer will
+// result in compilation errors.
+type UnsafeBudServer interface {
+	mustEmbedUnimplementedBudServer()
+}
+
+func RegisterBudServer(s grpc.ServiceRegistrar, srv BudServer) {
+	s.RegisterService(&Bud_ServiceDesc, srv)
+}
+
+func _Bud_Open_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OpenRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).Open(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/Open",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).Open(ctx, req.(*OpenRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/Stat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_Read_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BudServer).Read(m, &budReadServer{stream})
+}
+
+type Bud_ReadServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type budReadServer struct {
+	grpc.ServerStream
+}
+
+func (x *budReadServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Bud_Script_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ScriptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).Script(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/Script",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).Script(ctx, req.(*ScriptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_Eval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).Eval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/Eval",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).Eval(ctx, req.(*EvalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_RenderBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenderBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).RenderBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/RenderBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).RenderBatch(ctx, req.(*RenderBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_Publish_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Event)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BudServer).Publish(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/budhttp.Bud/Publish",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BudServer).Publish(ctx, req.(*Event))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Bud_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BudServer).Subscribe(m, &budSubscribeServer{stream})
+}
+
+type Bud_SubscribeServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type budSubscribeServer struct {
+	grpc.ServerStream
+}
+
+func (x *budSubscribeServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Bud_ServiceDesc is the grpc.ServiceDesc for Bud service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Bud_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "budhttp.Bud",
+	HandlerType: (*BudServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Open",
+			Handler:    _Bud_Open_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _Bud_Stat_Handler,
+		},
+		{
+			MethodName: "Script",
+			Handler:    _Bud_Script_Handler,
+		},
+		{
+			MethodName: "Eval",
+			Handler:    _Bud_Eval_Handler,
+		},
+		{
+			MethodName: "RenderBatch",
+			Handler:    _Bud_RenderBatch_Handler,
+		},
+		{
+			MethodName: "Publish",
+			Handler:    _Bud_Publish_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Read",
+			Handler:       _Bud_Read_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Subscribe",
+			Handler:       _Bud_Subscribe_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "budgrpc.proto",
+}
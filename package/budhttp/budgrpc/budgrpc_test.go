@@ -0,0 +1,143 @@
+package budgrpc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/package/budhttp"
+	"github.com/livebud/bud/package/budhttp/budgrpc"
+	"github.com/livebud/bud/package/js"
+	v8 "github.com/livebud/bud/package/js/v8"
+	"github.com/livebud/bud/package/log/testlog"
+	"github.com/livebud/bud/package/socket"
+	"github.com/livebud/bud/package/virtual"
+)
+
+func load(t testing.TB, fsys fs.FS, bus pubsub.Client, vm js.VM) *budgrpc.Client {
+	t.Helper()
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	log := testlog.New()
+	ln, err := socket.Listen(filepath.Join(t.TempDir(), "bud.sock"))
+	is.NoErr(err)
+	server := budgrpc.New(fsys, bus, log, vm)
+	go budgrpc.Serve(ctx, ln, server)
+	client, err := budgrpc.Dial(ctx, log, ln.Addr().String())
+	is.NoErr(err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestOpen(t *testing.T) {
+	is := is.New(t)
+	client := load(t, virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: []byte("console.log('hi')")},
+	}, pubsub.New(), nil)
+	data, err := fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.NoErr(err)
+	is.Equal(string(data), "console.log('hi')")
+	file, err := client.Open("public/favicon.ico")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	is.Equal(file, nil)
+}
+
+func TestLoadScheme(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	log := testlog.New()
+	ln, err := socket.Listen(filepath.Join(t.TempDir(), "bud.sock"))
+	is.NoErr(err)
+	fsys := virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: []byte("console.log('hi')")},
+	}
+	go budgrpc.Serve(ctx, ln, budgrpc.New(fsys, pubsub.New(), log, nil))
+	// budhttp dials gRPC addresses once budgrpc is imported
+	client, err := budhttp.Load(log, "grpc://"+ln.Addr().String())
+	is.NoErr(err)
+	_, ok := client.(*budgrpc.Client)
+	is.True(ok)
+	data, err := fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.NoErr(err)
+	is.Equal(string(data), "console.log('hi')")
+}
+
+func TestStream(t *testing.T) {
+	is := is.New(t)
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	client := load(t, virtual.Map{
+		"public/video.mp4": &virtual.File{Data: data},
+	}, pubsub.New(), nil)
+	file, err := client.Stream("public/video.mp4")
+	is.NoErr(err)
+	defer file.Close()
+	stat, err := file.Stat()
+	is.NoErr(err)
+	is.Equal(stat.Name(), "video.mp4")
+	is.Equal(stat.Size(), int64(len(data)))
+	seeker, ok := file.(io.Seeker)
+	is.True(ok)
+	_, err = seeker.Seek(-15, io.SeekEnd)
+	is.NoErr(err)
+	rest, err := io.ReadAll(file)
+	is.NoErr(err)
+	is.Equal(string(rest), "567890123456789")
+	_, err = seeker.Seek(0, io.SeekStart)
+	is.NoErr(err)
+	all, err := io.ReadAll(file)
+	is.NoErr(err)
+	is.True(bytes.Equal(all, data))
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ps := pubsub.New()
+	client := load(t, virtual.Map{}, ps, nil)
+	sub, err := client.Subscribe("build:finish")
+	is.NoErr(err)
+	defer sub.Close()
+	is.NoErr(client.Publish("build:finish", []byte("ok")))
+	event, err := sub.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "build:finish")
+	is.Equal(string(event.Data), "ok")
+}
+
+func TestRenderBatch(t *testing.T) {
+	is := is.New(t)
+	vm, err := v8.Load()
+	is.NoErr(err)
+	client := load(t, virtual.Map{
+		"bud/view/_ssr.js": &virtual.File{Data: []byte(`
+			var bud = {
+				render(route, props, context) {
+					const body = route + ":" + props.name + (context ? ":" + context.theme : "")
+					return JSON.stringify({ status: 200, headers: { "Content-Type": "text/html" }, body })
+				}
+			}
+		`)},
+	}, pubsub.New(), vm)
+	responses, err := client.RenderBatch(
+		&ssr.Request{Route: "/layout", Props: map[string]string{"name": "layout"}, Context: map[string]interface{}{"theme": "dark"}},
+		&ssr.Request{Route: "/island", Props: map[string]string{"name": "island"}},
+	)
+	is.NoErr(err)
+	is.Equal(len(responses), 2)
+	is.Equal(responses[0].Status, 200)
+	is.Equal(responses[0].Headers["Content-Type"], "text/html")
+	is.Equal(responses[0].Body, "/layout:layout:dark")
+	is.Equal(responses[1].Status, 200)
+	is.Equal(responses[1].Body, "/island:island")
+}
@@ -0,0 +1,299 @@
+package budgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"path"
+	"time"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/package/budhttp"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/socket"
+	"github.com/livebud/bud/package/virtual"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	budhttp.Register("grpc", Load)
+}
+
+// Load a client for addresses like grpc://127.0.0.1:35730, once this package is
+// imported
+func Load(log log.Interface, addr string) (budhttp.Client, error) {
+	return Dial(context.Background(), log, addr)
+}
+
+// Dial the bud runtime over gRPC. The address may be a TCP address or a unix
+// domain socket path.
+func Dial(ctx context.Context, log log.Interface, addr string) (*Client, error) {
+	conn, err := grpc.DialContext(ctx, addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return socket.Dial(ctx, addr)
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("budgrpc: unable to dial %q. %w", addr, err)
+	}
+	return &Client{conn, NewBudClient(conn), log}, nil
+}
+
+// Client implements budhttp.Client over gRPC
+type Client struct {
+	conn *grpc.ClientConn
+	bud  BudClient
+	log  log.Interface
+}
+
+var _ budhttp.Client = (*Client)(nil)
+
+func (c *Client) Open(name string) (fs.File, error) {
+	res, err := c.bud.Open(context.Background(), &OpenRequest{Path: name})
+	if err != nil {
+		return nil, fromStatus("open", name, err)
+	}
+	return virtual.UnmarshalJSON(res.Entry)
+}
+
+// Stream a file without buffering it into memory. Reads are served by a
+// server-side stream starting at the current offset.
+func (c *Client) Stream(name string) (fs.File, error) {
+	res, err := c.bud.Stat(context.Background(), &StatRequest{Path: name})
+	if err != nil {
+		return nil, fromStatus("stream", name, err)
+	}
+	return &streamFile{
+		client: c,
+		path:   name,
+		info: &fileInfo{
+			name:    path.Base(name),
+			size:    res.Size,
+			modTime: res.ModTime.AsTime(),
+		},
+	}, nil
+}
+
+func (c *Client) Script(path, script string) error {
+	if _, err := c.bud.Script(context.Background(), &ScriptRequest{Path: path, Script: script}); err != nil {
+		return fromStatus("script", path, err)
+	}
+	return nil
+}
+
+func (c *Client) Eval(path, expr string) (string, error) {
+	res, err := c.bud.Eval(context.Background(), &EvalRequest{Path: path, Expr: expr})
+	if err != nil {
+		return "", fromStatus("eval", path, err)
+	}
+	return res.Result, nil
+}
+
+func (c *Client) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	renders, err := toRenderRequests(requests)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.bud.RenderBatch(context.Background(), &RenderBatchRequest{Requests: renders})
+	if err != nil {
+		return nil, fromStatus("render batch", "bud/view/_ssr.js", err)
+	}
+	if len(res.Responses) != len(requests) {
+		return nil, fmt.Errorf("budgrpc: render batch expected %d responses but got %d", len(requests), len(res.Responses))
+	}
+	return fromRenderResponses(res.Responses), nil
+}
+
+func (c *Client) Publish(topic string, data []byte) error {
+	if _, err := c.bud.Publish(context.Background(), &Event{Topic: topic, Data: data}); err != nil {
+		return fromStatus("publish", topic, err)
+	}
+	return nil
+}
+
+func (c *Client) Subscribe(topics ...string) (budhttp.Subscription, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := c.bud.Subscribe(ctx, &SubscribeRequest{Topics: topics})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("budgrpc: unable to subscribe to %v. %w", topics, err)
+	}
+	// Wait for the server to subscribe before returning
+	if _, err := stream.Header(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("budgrpc: unable to subscribe to %v. %w", topics, err)
+	}
+	return newSubscription(ctx, stream, cancel), nil
+}
+
+// Close the connection to the server
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+type subscription struct {
+	eventCh chan *budhttp.Event
+	errorCh chan error
+	cancel  context.CancelFunc
+}
+
+var _ budhttp.Subscription = (*subscription)(nil)
+
+func newSubscription(ctx context.Context, stream Bud_SubscribeClient, cancel context.CancelFunc) *subscription {
+	s := &subscription{
+		eventCh: make(chan *budhttp.Event, 1),
+		errorCh: make(chan error, 1),
+		cancel:  cancel,
+	}
+	go s.loop(ctx, stream)
+	return s
+}
+
+// loop receives events until the stream is closed
+func (s *subscription) loop(ctx context.Context, stream Bud_SubscribeClient) {
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			s.errorCh <- err
+			return
+		}
+		select {
+		case s.eventCh <- &budhttp.Event{Topic: event.Topic, Data: event.Data}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *subscription) Next(ctx context.Context) (*budhttp.Event, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case event := <-s.eventCh:
+		return event, nil
+	case err := <-s.errorCh:
+		return nil, err
+	}
+}
+
+func (s *subscription) Close() error {
+	s.cancel()
+	return nil
+}
+
+type streamFile struct {
+	client *Client
+	path   string
+	info   *fileInfo
+	offset int64
+	stream Bud_ReadClient // Stream positioned at offset
+	cancel context.CancelFunc
+	buf    []byte // Unread data from the last chunk
+}
+
+var _ fs.File = (*streamFile)(nil)
+var _ io.ReadSeeker = (*streamFile)(nil)
+
+func (f *streamFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *streamFile) Read(p []byte) (int, error) {
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if len(f.buf) == 0 {
+		if f.stream == nil {
+			if err := f.open(); err != nil {
+				return 0, err
+			}
+		}
+		chunk, err := f.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		f.buf = chunk.Data
+	}
+	n := copy(p, f.buf)
+	f.buf = f.buf[n:]
+	f.offset += int64(n)
+	return n, nil
+}
+
+// open a read stream starting at the current offset
+func (f *streamFile) open() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := f.client.bud.Read(ctx, &ReadRequest{Path: f.path, Offset: f.offset})
+	if err != nil {
+		cancel()
+		return err
+	}
+	f.stream = stream
+	f.cancel = cancel
+	return nil
+}
+
+func (f *streamFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// offset += 0
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.path, Err: fs.ErrInvalid}
+	}
+	// Drop the current stream when we move, the next read will re-request
+	if offset != f.offset {
+		f.Close()
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *streamFile) Close() error {
+	if f.cancel != nil {
+		f.cancel()
+	}
+	f.stream = nil
+	f.cancel = nil
+	f.buf = nil
+	return nil
+}
+
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+var _ fs.FileInfo = (*fileInfo)(nil)
+
+func (i *fileInfo) Name() string       { return i.name }
+func (i *fileInfo) Size() int64        { return i.size }
+func (i *fileInfo) Mode() fs.FileMode  { return 0 }
+func (i *fileInfo) ModTime() time.Time { return i.modTime }
+func (i *fileInfo) IsDir() bool        { return false }
+func (i *fileInfo) Sys() interface{}   { return nil }
+
+// fromStatus converts a gRPC status error back into an error. Not found errors
+// are converted back into fs.ErrNotExist.
+func fromStatus(op, name string, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("budgrpc: %s %q. %w", op, name, err)
+	}
+	if st.Code() == codes.NotFound {
+		return fmt.Errorf("budgrpc: %s %q. %w", op, name, fs.ErrNotExist)
+	}
+	return errors.New(st.Message())
+}
@@ -0,0 +1,221 @@
+package budgrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/package/budhttp/budsvr"
+	"github.com/livebud/bud/package/js"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/virtual"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// chunkSize is the maximum size of each chunk when reading files
+const chunkSize = 32 * 1024
+
+// New gRPC server that serves the same protocol as budsvr
+func New(fsys fs.FS, bus pubsub.Client, log log.Interface, vm js.VM) *grpc.Server {
+	server := grpc.NewServer()
+	RegisterBudServer(server, &Server{fsys: fsys, bus: bus, log: log, vm: vm})
+	return server
+}
+
+// Serve the gRPC server on the listener until the context is canceled
+func Serve(ctx context.Context, ln net.Listener, server *grpc.Server) error {
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+	if err := server.Serve(ln); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+type Server struct {
+	UnimplementedBudServer
+	fsys fs.FS
+	bus  pubsub.Client
+	log  log.Interface
+	vm   js.VM
+}
+
+var _ BudServer = (*Server)(nil)
+
+func (s *Server) Open(ctx context.Context, req *OpenRequest) (*OpenResponse, error) {
+	s.log.Debug("budgrpc: opening", "file", req.Path)
+	file, err := s.fsys.Open(req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	defer file.Close()
+	entry, err := virtual.MarshalJSON(file)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &OpenResponse{Entry: entry}, nil
+}
+
+func (s *Server) Stat(ctx context.Context, req *StatRequest) (*StatResponse, error) {
+	stat, err := fs.Stat(s.fsys, req.Path)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if stat.IsDir() {
+		return nil, status.Errorf(codes.InvalidArgument, "budgrpc: %q is a directory", req.Path)
+	}
+	return &StatResponse{
+		Name:    stat.Name(),
+		Size:    stat.Size(),
+		ModTime: timestamppb.New(stat.ModTime()),
+	}, nil
+}
+
+// Read streams a file's contents in chunks starting at the offset
+func (s *Server) Read(req *ReadRequest, stream Bud_ReadServer) error {
+	file, err := s.fsys.Open(req.Path)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer file.Close()
+	if req.Offset > 0 {
+		seeker, ok := file.(io.Seeker)
+		if !ok {
+			return status.Errorf(codes.Unimplemented, "budgrpc: %q is not seekable", req.Path)
+		}
+		if _, err := seeker.Seek(req.Offset, io.SeekStart); err != nil {
+			return toStatus(err)
+		}
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := file.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&Chunk{Data: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return toStatus(err)
+		}
+	}
+}
+
+func (s *Server) Script(ctx context.Context, req *ScriptRequest) (*emptypb.Empty, error) {
+	if err := s.vm.Script(req.Path, req.Script); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Eval(ctx context.Context, req *EvalRequest) (*EvalResponse, error) {
+	result, err := s.vm.Eval(req.Path, req.Expr)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &EvalResponse{Result: result}, nil
+}
+
+func (s *Server) RenderBatch(ctx context.Context, req *RenderBatchRequest) (*RenderBatchResponse, error) {
+	script, err := fs.ReadFile(s.fsys, "bud/view/_ssr.js")
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	requests, err := fromRenderRequests(req.Requests)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	expr, err := ssr.BatchExpr(string(script), requests)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	result, err := s.vm.Eval("_ssr.js", expr)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	responses, err := ssr.UnmarshalBatch([]byte(result))
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &RenderBatchResponse{Responses: toRenderResponses(responses)}, nil
+}
+
+func (s *Server) Publish(ctx context.Context, req *Event) (*emptypb.Empty, error) {
+	s.bus.Publish(req.Topic, req.Data)
+	return &emptypb.Empty{}, nil
+}
+
+// Subscribe streams events until the client goes away
+func (s *Server) Subscribe(req *SubscribeRequest, stream Bud_SubscribeServer) error {
+	topics := req.Topics
+	if len(topics) == 0 {
+		topics = budsvr.DefaultTopics
+	}
+	ctx := stream.Context()
+	eventCh := make(chan *Event)
+	for _, topic := range topics {
+		subscription := s.bus.Subscribe(topic)
+		defer subscription.Close()
+		go forward(ctx, topic, subscription, eventCh)
+	}
+	s.log.Debug("budgrpc: subscribed to topics", "topics", topics)
+	// Send the headers to let the client know we're subscribed
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-eventCh:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// forward events from a subscription until the context is canceled
+func forward(ctx context.Context, topic string, subscription pubsub.Subscription, eventCh chan<- *Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-subscription.Wait():
+			if !ok {
+				return
+			}
+			select {
+			case eventCh <- &Event{Topic: topic, Data: data}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// toStatus converts an error into a gRPC status error
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, fs.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, fmt.Sprintf("budgrpc: %s", err))
+	}
+}
@@ -0,0 +1,63 @@
+package budgrpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative budgrpc.proto
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/livebud/bud/framework/view/ssr"
+)
+
+// toRenderRequests encodes the render requests. Props and context can be any
+// JSON value, so they're sent as JSON.
+func toRenderRequests(requests []*ssr.Request) ([]*RenderRequest, error) {
+	out := make([]*RenderRequest, len(requests))
+	for i, req := range requests {
+		props, err := json.Marshal(req.Props)
+		if err != nil {
+			return nil, fmt.Errorf("budgrpc: unable to encode the props for %q. %w", req.Route, err)
+		}
+		values, err := json.Marshal(req.Context)
+		if err != nil {
+			return nil, fmt.Errorf("budgrpc: unable to encode the context for %q. %w", req.Route, err)
+		}
+		out[i] = &RenderRequest{Route: req.Route, Props: props, Context: values}
+	}
+	return out, nil
+}
+
+// fromRenderRequests decodes the render requests
+func fromRenderRequests(requests []*RenderRequest) ([]*ssr.Request, error) {
+	out := make([]*ssr.Request, len(requests))
+	for i, req := range requests {
+		out[i] = &ssr.Request{Route: req.Route}
+		if len(req.Props) > 0 {
+			if err := json.Unmarshal(req.Props, &out[i].Props); err != nil {
+				return nil, fmt.Errorf("budgrpc: unable to decode the props for %q. %w", req.Route, err)
+			}
+		}
+		if len(req.Context) > 0 {
+			if err := json.Unmarshal(req.Context, &out[i].Context); err != nil {
+				return nil, fmt.Errorf("budgrpc: unable to decode the context for %q. %w", req.Route, err)
+			}
+		}
+	}
+	return out, nil
+}
+
+func toRenderResponses(responses []*ssr.Response) []*RenderResponse {
+	out := make([]*RenderResponse, len(responses))
+	for i, res := range responses {
+		out[i] = &RenderResponse{Status: int32(res.Status), Headers: res.Headers, Body: res.Body}
+	}
+	return out
+}
+
+func fromRenderResponses(responses []*RenderResponse) []*ssr.Response {
+	out := make([]*ssr.Response, len(responses))
+	for i, res := range responses {
+		out[i] = &ssr.Response{Status: int(res.Status), Headers: res.Headers, Body: res.Body}
+	}
+	return out
+}
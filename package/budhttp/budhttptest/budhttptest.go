@@ -0,0 +1,268 @@
+// Package budhttptest provides a fake budhttp.Client for testing code that
+// talks to the dev server, like the view middleware and renderer, without
+// running one.
+package budhttptest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/fstest"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/package/budhttp"
+	"github.com/livebud/bud/package/js/jstest"
+)
+
+// New fake client with no files or views
+func New() *Client {
+	return &Client{
+		VM:    jstest.New(),
+		Files: fstest.MapFS{},
+		views: map[string]*ssr.Response{},
+	}
+}
+
+// Client is an in-memory budhttp.Client. Files are served from Files, views
+// are rendered from the responses given to View and everything else is
+// evaluated by VM. Events that are published are delivered to subscribers.
+// Calls are recorded so tests can check them.
+type Client struct {
+	*jstest.VM
+	// Files served by Open and Stream
+	Files fstest.MapFS
+
+	mu          sync.Mutex
+	views       map[string]*ssr.Response
+	renders     []*ssr.Request
+	published   []*budhttp.Event
+	subscribers []*subscription
+}
+
+var _ budhttp.Client = (*Client)(nil)
+
+// View responds with res when rendering the route. Responses without a
+// status are 200 OK.
+func (c *Client) View(route string, res *ssr.Response) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.views[route] = res
+	// The renderer reads the script before evaluating it
+	if _, ok := c.Files["bud/view/_ssr.js"]; !ok {
+		c.Files["bud/view/_ssr.js"] = &fstest.MapFile{}
+	}
+	return c
+}
+
+// Open a file
+func (c *Client) Open(name string) (fs.File, error) {
+	return c.Files.Open(name)
+}
+
+// Stream a file. Unlike the real client, it's read from memory like Open.
+func (c *Client) Stream(name string) (fs.File, error) {
+	return c.Files.Open(name)
+}
+
+// RenderBatch renders each request with the responses given to View
+func (c *Client) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	// Requests are sent to the dev server as JSON, so decode the props and
+	// context the way the renderer would see them
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, err
+	}
+	var decoded []*ssr.Request
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, err
+	}
+	responses := make([]*ssr.Response, len(decoded))
+	for i, req := range decoded {
+		res, err := c.render(req)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = res
+	}
+	return responses, nil
+}
+
+func (c *Client) render(req *ssr.Request) (*ssr.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.renders = append(c.renders, req)
+	res, ok := c.views[req.Route]
+	if !ok {
+		return nil, fmt.Errorf("budhttptest: no view for %q", req.Route)
+	}
+	if res.Status == 0 {
+		return &ssr.Response{Status: 200, Headers: res.Headers, Body: res.Body}, nil
+	}
+	return res, nil
+}
+
+// Eval renders views when the expression ends in bud.render(route, props,
+// context), like the renderer's. Other expressions are evaluated by VM.
+func (c *Client) Eval(path, expr string) (string, error) {
+	req, ok := parseRender(expr)
+	if !ok {
+		return c.VM.Eval(path, expr)
+	}
+	res, err := c.render(req)
+	if err != nil {
+		return "", err
+	}
+	result, err := json.Marshal(res)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// parseRender parses expressions ending in bud.render("route", props) or
+// bud.render("route", props, context), where props and context are JSON
+func parseRender(expr string) (*ssr.Request, bool) {
+	i := strings.LastIndex(expr, "bud.render(")
+	if i < 0 || !strings.HasSuffix(expr, ")") {
+		return nil, false
+	}
+	args := expr[i+len("bud.render(") : len(expr)-1]
+	quoted, err := strconv.QuotedPrefix(args)
+	if err != nil {
+		return nil, false
+	}
+	route, err := strconv.Unquote(quoted)
+	if err != nil {
+		return nil, false
+	}
+	rest := strings.TrimPrefix(args[len(quoted):], ", ")
+	dec := json.NewDecoder(strings.NewReader(rest))
+	var props interface{}
+	if err := dec.Decode(&props); err != nil {
+		return nil, false
+	}
+	req := &ssr.Request{Route: route, Props: props}
+	rest = strings.TrimSpace(rest[dec.InputOffset():])
+	if rest == "" {
+		return req, true
+	}
+	if !strings.HasPrefix(rest, ",") {
+		return nil, false
+	}
+	if err := json.Unmarshal([]byte(rest[1:]), &req.Context); err != nil {
+		return nil, false
+	}
+	return req, true
+}
+
+// Renders returns the views that were rendered in order. Props and context are
+// decoded from JSON.
+func (c *Client) Renders() []*ssr.Request {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	renders := make([]*ssr.Request, len(c.renders))
+	copy(renders, c.renders)
+	return renders
+}
+
+// Publish records the event and delivers it to subscribers of the topic
+func (c *Client) Publish(topic string, data []byte) error {
+	event := &budhttp.Event{Topic: topic, Data: data}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.published = append(c.published, event)
+	for _, sub := range c.subscribers {
+		sub.send(event)
+	}
+	return nil
+}
+
+// Published returns the events that were published in order
+func (c *Client) Published() []*budhttp.Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	published := make([]*budhttp.Event, len(c.published))
+	copy(published, c.published)
+	return published
+}
+
+// Subscribe to events published after subscribing. If no topics are passed
+// in, every event is delivered.
+func (c *Client) Subscribe(topics ...string) (budhttp.Subscription, error) {
+	sub := &subscription{
+		client: c,
+		topics: topics,
+		events: make(chan *budhttp.Event, 64),
+		closed: make(chan struct{}),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscribers = append(c.subscribers, sub)
+	return sub, nil
+}
+
+type subscription struct {
+	client *Client
+	topics []string
+	events chan *budhttp.Event
+	once   sync.Once
+	closed chan struct{}
+}
+
+var _ budhttp.Subscription = (*subscription)(nil)
+
+// send the event if the subscription is interested in it. Events are dropped
+// when nobody is reading them.
+func (s *subscription) send(event *budhttp.Event) {
+	if !s.wants(event.Topic) {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+func (s *subscription) wants(topic string) bool {
+	if len(s.topics) == 0 {
+		return true
+	}
+	for _, t := range s.topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *subscription) Next(ctx context.Context) (*budhttp.Event, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.closed:
+		return nil, io.EOF
+	case event := <-s.events:
+		return event, nil
+	}
+}
+
+func (s *subscription) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		c := s.client
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, sub := range c.subscribers {
+			if sub == s {
+				c.subscribers = append(c.subscribers[:i], c.subscribers[i+1:]...)
+				break
+			}
+		}
+	})
+	return nil
+}
@@ -0,0 +1,138 @@
+package budhttptest_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/framework/view/viewrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/budhttp/budhttptest"
+	"github.com/livebud/bud/package/log/testlog"
+)
+
+func TestRender(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New().View("/", &ssr.Response{
+		Headers: map[string]string{"Content-Type": "text/html"},
+		Body:    "<h1>hi</h1>",
+	})
+	server := viewrt.Proxy(client, testlog.New())
+	rec := httptest.NewRecorder()
+	server.Handler("/", map[string]string{"name": "alice"}).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Header().Get("Content-Type"), "text/html")
+	is.Equal(rec.Body.String(), "<h1>hi</h1>")
+	renders := client.Renders()
+	is.Equal(len(renders), 1)
+	is.Equal(renders[0].Route, "/")
+	is.Equal(renders[0].Props, map[string]interface{}{"name": "alice"})
+}
+
+func TestRenderContext(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New().View("/", &ssr.Response{Body: "<h1>hi</h1>"})
+	server := viewrt.Proxy(client, testlog.New())
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(viewrt.WithContext(req.Context(), "flags", map[string]bool{"beta": true}))
+	server.Handler("/", nil).ServeHTTP(rec, req)
+	is.Equal(rec.Code, 200)
+	renders := client.Renders()
+	is.Equal(len(renders), 1)
+	is.Equal(renders[0].Props, nil)
+	is.Equal(renders[0].Context, map[string]interface{}{"flags": map[string]interface{}{"beta": true}})
+}
+
+func TestRenderBatch(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New().
+		View("/", &ssr.Response{Body: "a"}).
+		View("/about", &ssr.Response{Status: 404, Body: "b"})
+	server := viewrt.Proxy(client, testlog.New())
+	responses, err := server.RenderBatch(&ssr.Request{Route: "/"}, &ssr.Request{Route: "/about"})
+	is.NoErr(err)
+	is.Equal(len(responses), 2)
+	is.Equal(responses[0].Status, 200)
+	is.Equal(responses[0].Body, "a")
+	is.Equal(responses[1].Status, 404)
+	_, err = server.RenderBatch(&ssr.Request{Route: "/missing"})
+	is.True(err != nil)
+	is.Equal(len(client.Renders()), 3)
+}
+
+func TestMissingView(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New().View("/", &ssr.Response{Body: "a"})
+	server := viewrt.Proxy(client, testlog.New())
+	rec := httptest.NewRecorder()
+	server.Handler("/missing", nil).ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	is.Equal(rec.Code, 500)
+}
+
+func TestFiles(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New()
+	client.Files["bud/view/_index.svelte.js"] = &fstest.MapFile{Data: []byte("console.log('hi')")}
+	server := viewrt.Proxy(client, testlog.New())
+	handler := server.Middleware(http.NotFoundHandler())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bud/view/_index.svelte.js", nil))
+	is.Equal(rec.Code, 200)
+	is.Equal(rec.Body.String(), "console.log('hi')")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bud/view/_about.svelte.js", nil))
+	is.Equal(rec.Code, 404)
+	file, err := client.Stream("bud/view/_index.svelte.js")
+	is.NoErr(err)
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	is.NoErr(err)
+	is.Equal(string(data), "console.log('hi')")
+	_, err = client.Open("missing.js")
+	is.True(errors.Is(err, fs.ErrNotExist))
+}
+
+func TestEval(t *testing.T) {
+	is := is.New(t)
+	client := budhttptest.New()
+	client.On("1 + 1", "2")
+	result, err := client.Eval("add.js", "1 + 1")
+	is.NoErr(err)
+	is.Equal(result, "2")
+	is.Equal(len(client.Calls()), 1)
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	client := budhttptest.New()
+	all, err := client.Subscribe()
+	is.NoErr(err)
+	builds, err := client.Subscribe("build:finish")
+	is.NoErr(err)
+	is.NoErr(client.Publish("file:change", []byte("a.go")))
+	is.NoErr(client.Publish("build:finish", nil))
+	event, err := all.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "file:change")
+	is.Equal(string(event.Data), "a.go")
+	event, err = all.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "build:finish")
+	event, err = builds.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "build:finish")
+	is.NoErr(builds.Close())
+	_, err = builds.Next(ctx)
+	is.Equal(err, io.EOF)
+	is.Equal(len(client.Published()), 2)
+}
@@ -1,18 +1,24 @@
 package budsvr
 
 import (
+	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"strings"
+	"sync"
 
+	"github.com/livebud/bud/framework/view/ssr"
 	"github.com/livebud/bud/package/virtual"
 
 	"github.com/livebud/bud/package/budhttp"
 	"github.com/livebud/bud/package/hot"
 	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/middleware"
 
 	"github.com/livebud/bud/internal/pubsub"
 
@@ -20,26 +26,73 @@ import (
 	"github.com/livebud/bud/package/router"
 )
 
-func New(fsys fs.FS, bus pubsub.Client, log log.Interface, vm js.VM) *Server {
+// DefaultTopics are streamed to subscribers that don't specify any topics
+var DefaultTopics = []string{
+	"build:start",
+	"build:finish",
+	"build:error",
+	"file:change",
+	"app:ready",
+	"app:error",
+}
+
+// Option configures the server
+type Option func(s *Server)
+
+// WithStats serves stats from a collector that was started earlier, so events
+// that were published before the server started aren't missed
+func WithStats(stats *Collector) Option {
+	return func(s *Server) {
+		s.stats = stats
+	}
+}
+
+// WithToken requires clients to send the token in an "Authorization: Bearer"
+// header. Servers listening beyond loopback need a token, since anyone who can
+// reach them can read files and evaluate scripts.
+func WithToken(token string) Option {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+func New(fsys fs.FS, bus pubsub.Client, log log.Interface, vm js.VM, options ...Option) *Server {
 	router := router.New()
 	server := &Server{
-		Handler: router,
-		fsys:    fsys,
-		hfs:     http.FS(fsys),
-		log:     log,
-		bus:     bus,
-		vm:      vm,
+		fsys: fsys,
+		hfs:  http.FS(fsys),
+		log:  log,
+		bus:  bus,
+		vm:   vm,
+	}
+	for _, option := range options {
+		option(server)
+	}
+	if server.stats == nil {
+		server.stats = Collect(bus)
 	}
 	// Routes that are proxied to from the browser through the app to bud
 	router.Post("/bud/view/:route*", http.HandlerFunc(server.render))
 	router.Get("/open/:path*", http.HandlerFunc(server.open))
-	// Routes that are directly requested by the browser to
-	router.Get("/bud/hot/:page*", hot.New(log, bus))
-	// Private routes between the app and bud
+	// Stream large files with support for range requests
+	router.Get("/stream/:path*", http.HandlerFunc(server.stream))
+	router.Add(http.MethodHead, "/stream/:path*", http.HandlerFunc(server.stream))
+	// Events for the app and the browser
 	router.Post("/bud/events", http.HandlerFunc(server.publish))
+	router.Get("/bud/events", http.HandlerFunc(server.subscribe))
+	router.Post("/bud/render", http.HandlerFunc(server.renderBatch))
+	// Stats for the dashboard
+	router.Get("/bud/stats", server.stats)
 	// Support eval
 	router.Post("/js/script", http.HandlerFunc(server.script))
 	router.Post("/js/eval", http.HandlerFunc(server.eval))
+	// Compress responses, which are often large JS bundles
+	server.Handler = server.announceBuild(middleware.Gzip().Middleware(router))
+	if server.token != "" {
+		server.Handler = server.authenticate(server.Handler)
+	}
+	// Keep track of the latest build to announce it to clients
+	go server.watchBuilds(bus.Subscribe("build:finish"))
 	return server
 }
 
@@ -47,13 +100,42 @@ type Server struct {
 	http.Handler
 	fsys fs.FS
 	hfs  http.FileSystem
-	bus  pubsub.Publisher
+	bus  pubsub.Client
 	log  log.Interface
 	vm   js.VM
+
+	stats *Collector
+	token string // Bearer token that clients must send (optional)
+
+	mu      sync.RWMutex
+	buildID string // Latest build ID, empty until the first build finishes
 }
 
 var _ http.Handler = (*Server)(nil)
 
+// watchBuilds records the build ID sent with each build:finish event
+func (s *Server) watchBuilds(subscription pubsub.Subscription) {
+	for data := range subscription.Wait() {
+		s.mu.Lock()
+		s.buildID = string(data)
+		s.mu.Unlock()
+	}
+}
+
+// announceBuild adds the current build ID to each response. Clients use this
+// to cache responses until the next build.
+func (s *Server) announceBuild(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		buildID := s.buildID
+		s.mu.RUnlock()
+		if buildID != "" {
+			w.Header().Set(budhttp.BuildHeader, buildID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) render(w http.ResponseWriter, r *http.Request) {
 	// Read the body
 	body, err := io.ReadAll(r.Body)
@@ -86,6 +168,36 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(result))
 }
 
+// renderBatch renders multiple routes within a single round-trip
+func (s *Server) renderBatch(w http.ResponseWriter, r *http.Request) {
+	var requests []*ssr.Request
+	if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	script, err := fs.ReadFile(s.fsys, "bud/view/_ssr.js")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	expr, err := ssr.BatchExpr(string(script), requests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := s.vm.Eval("_ssr.js", expr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(result))
+}
+
 func (s *Server) open(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Query().Get("path")
 	s.log.Debug("devserver: opening", "file", path)
@@ -108,6 +220,34 @@ func (s *Server) open(w http.ResponseWriter, r *http.Request) {
 	s.log.Debug("devserver: opened", "file", path)
 }
 
+// stream a file's contents directly from the filesystem rather than buffering
+// it into a JSON payload. Range and conditional requests are supported.
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	s.log.Debug("devserver: streaming", "file", path)
+	file, err := s.hfs.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.Error(w, err.Error(), 404)
+			return
+		}
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if stat.IsDir() {
+		http.Error(w, fmt.Sprintf("devserver: unable to stream directory %q", path), 400)
+		return
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, stat.Name(), stat.ModTime(), file)
+}
+
 func (s *Server) publish(w http.ResponseWriter, r *http.Request) {
 	// Read the body
 	body, err := io.ReadAll(r.Body)
@@ -127,6 +267,80 @@ func (s *Server) publish(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// authenticate checks the bearer token before serving the request
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	expect := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(actual, expect) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bud"`)
+			http.Error(w, "devserver: unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// subscribe streams dev server events as server-sent events. The event type is
+// the topic, so both the app runtime and the browser can filter on it.
+func (s *Server) subscribe(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "devserver: response writer is not a flusher", http.StatusInternalServerError)
+		return
+	}
+	topics := DefaultTopics
+	if query := r.URL.Query().Get("topics"); query != "" {
+		topics = strings.Split(query, ",")
+	}
+	// Subscribe to each topic individually so we know which topic an event
+	// came from. Subscribe before flushing the headers so no events are missed.
+	ctx := r.Context()
+	eventCh := make(chan *budhttp.Event)
+	for _, topic := range topics {
+		subscription := s.bus.Subscribe(topic)
+		defer subscription.Close()
+		go forward(ctx, topic, subscription, eventCh)
+	}
+	s.log.Debug("devserver: subscribed to topics", "topics", topics)
+	headers := w.Header()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("Connection", "keep-alive")
+	headers.Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-eventCh:
+			sse := &hot.Event{Type: event.Topic, Data: event.Data}
+			w.Write(sse.Format().Bytes())
+			flusher.Flush()
+		}
+	}
+}
+
+// forward events from a subscription until the context is canceled
+func forward(ctx context.Context, topic string, subscription pubsub.Subscription, eventCh chan<- *budhttp.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-subscription.Wait():
+			if !ok {
+				return
+			}
+			select {
+			case eventCh <- &budhttp.Event{Topic: topic, Data: data}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
 func (s *Server) script(w http.ResponseWriter, r *http.Request) {
 	// Read the body
 	body, err := io.ReadAll(r.Body)
@@ -0,0 +1,122 @@
+package budsvr
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/package/budhttp"
+)
+
+// Number of recent builds and errors to keep
+const recentStats = 10
+
+// Collect stats from the event bus for the dashboard. Stats are collected
+// from the time Collect is called.
+func Collect(bus pubsub.Client) *Collector {
+	c := new(Collector)
+	// Subscribe to each topic individually so we know which topic an event
+	// came from
+	go c.collect(
+		bus.Subscribe("build:start"),
+		bus.Subscribe("build:finish"),
+		bus.Subscribe("build:error"),
+		bus.Subscribe("build:timings"),
+		bus.Subscribe("app:error"),
+		bus.Subscribe("watch:files"),
+	)
+	return c
+}
+
+// Collector of stats, served as JSON
+type Collector struct {
+	mu    sync.Mutex
+	stats budhttp.Stats
+}
+
+var _ http.Handler = (*Collector)(nil)
+
+func (s *Collector) collect(starts, finishes, buildErrors, timings, appErrors, watching pubsub.Subscription) {
+	for {
+		select {
+		case <-starts.Wait():
+			s.buildStart(time.Now())
+		case <-finishes.Wait():
+			s.buildFinish(time.Now(), "")
+		case data := <-buildErrors.Wait():
+			now := time.Now()
+			s.buildFinish(now, string(data))
+			s.addError(now, "build:error", string(data))
+		case data := <-timings.Wait():
+			var generators []*budhttp.GeneratorStat
+			if err := json.Unmarshal(data, &generators); err != nil {
+				continue
+			}
+			s.mu.Lock()
+			s.stats.Generators = generators
+			s.mu.Unlock()
+		case data := <-appErrors.Wait():
+			// App errors without a message don't have anything to show
+			if len(data) > 0 {
+				s.addError(time.Now(), "app:error", string(data))
+			}
+		case data := <-watching.Wait():
+			n, err := strconv.Atoi(string(data))
+			if err != nil {
+				continue
+			}
+			s.mu.Lock()
+			s.stats.Watching = n
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Collector) buildStart(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	build := &budhttp.BuildStat{Start: now}
+	s.stats.Builds = append([]*budhttp.BuildStat{build}, s.stats.Builds...)
+	if len(s.stats.Builds) > recentStats {
+		s.stats.Builds = s.stats.Builds[:recentStats]
+	}
+}
+
+// buildFinish finishes the running build. Incremental reloads finish without
+// starting, so they're ignored.
+func (s *Collector) buildFinish(now time.Time, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.stats.Builds) == 0 || s.stats.Builds[0].Duration > 0 {
+		return
+	}
+	build := s.stats.Builds[0]
+	build.Duration = now.Sub(build.Start)
+	build.Error = message
+}
+
+func (s *Collector) addError(now time.Time, topic, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := &budhttp.ErrorStat{Time: now, Topic: topic, Message: message}
+	s.stats.Errors = append([]*budhttp.ErrorStat{stat}, s.stats.Errors...)
+	if len(s.stats.Errors) > recentStats {
+		s.stats.Errors = s.stats.Errors[:recentStats]
+	}
+}
+
+// ServeHTTP serves the stats as JSON
+func (s *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	body, err := json.Marshal(&s.stats)
+	s.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
@@ -0,0 +1,97 @@
+package budhttp
+
+import (
+	"context"
+	"sync"
+)
+
+// BuildHeader contains the dev server's current build ID. Responses are cached
+// by the client until the dev server announces a new build.
+const BuildHeader = "Bud-Build"
+
+func newCache() *cache {
+	return &cache{
+		entries: map[string][]byte{},
+	}
+}
+
+// cache of responses from the dev server keyed by build ID
+type cache struct {
+	once    sync.Once
+	mu      sync.RWMutex
+	live    bool   // True while we're subscribed to build events
+	buildID string // Current build ID
+	entries map[string][]byte
+}
+
+// Watch for build events in the background. Until we're subscribed, nothing
+// is served from the cache.
+func (c *cache) Watch(client *client) {
+	c.once.Do(func() {
+		sub, err := client.Subscribe("build:start", "build:finish", "file:change")
+		if err != nil {
+			client.log.Debug("budhttp: unable to subscribe to builds, caching disabled", "err", err)
+			return
+		}
+		c.mu.Lock()
+		c.live = true
+		c.mu.Unlock()
+		go c.loop(client, sub)
+	})
+}
+
+func (c *cache) loop(client *client, sub Subscription) {
+	defer sub.Close()
+	for {
+		event, err := sub.Next(context.Background())
+		if err != nil {
+			client.log.Debug("budhttp: build subscription closed, caching disabled", "err", err)
+			c.mu.Lock()
+			c.live = false
+			c.entries = map[string][]byte{}
+			c.mu.Unlock()
+			return
+		}
+		// Files are changing, so drop everything until the next build finishes
+		buildID := ""
+		if event.Topic == "build:finish" {
+			buildID = string(event.Data)
+		}
+		c.mu.Lock()
+		c.buildID = buildID
+		c.entries = map[string][]byte{}
+		c.mu.Unlock()
+	}
+}
+
+// Get a cached response
+func (c *cache) Get(key string) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if !c.live || c.buildID == "" {
+		return nil, false
+	}
+	value, ok := c.entries[key]
+	return value, ok
+}
+
+// Set a cached response for the build that it came from. Responses from
+// previous builds are ignored.
+func (c *cache) Set(buildID, key string, value []byte) {
+	if buildID == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.live {
+		return
+	}
+	// Adopt the build ID if we haven't seen a build finish yet
+	if c.buildID == "" {
+		c.buildID = buildID
+	}
+	if c.buildID != buildID {
+		return
+	}
+	c.entries[key] = value
+}
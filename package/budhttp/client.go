@@ -2,11 +2,13 @@ package budhttp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"strings"
 
 	"github.com/livebud/bud/package/js"
 
@@ -14,26 +16,50 @@ import (
 	"github.com/livebud/bud/internal/urlx"
 	"github.com/livebud/bud/package/log"
 	"github.com/livebud/bud/package/socket"
+	"github.com/livebud/bud/package/tracing"
 	"github.com/livebud/bud/package/virtual"
 )
 
 type Client interface {
 	Publish(topic string, data []byte) error
+	Subscribe(topics ...string) (Subscription, error)
 	Open(name string) (fs.File, error)
+	Stream(name string) (fs.File, error)
+	RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error)
 	js.VM
 }
 
 // Try tries loading a dev client from an environment variable or returns an
 // empty client if no environment variable is set
-func Try(log log.Interface, addr string) (Client, error) {
+func Try(log log.Interface, addr string, options ...Option) (Client, error) {
 	if addr == "" {
 		return discard{}, nil
 	}
-	return Load(log, addr)
+	return Load(log, addr, options...)
+}
+
+// Dialer connects to the dev server over another transport
+type Dialer func(log log.Interface, addr string) (Client, error)
+
+var dialers = map[string]Dialer{}
+
+// Register a dialer for addresses with the scheme. Transports register
+// themselves when they're imported, like budgrpc for grpc://127.0.0.1:35730.
+func Register(scheme string, dialer Dialer) {
+	dialers[scheme] = dialer
 }
 
 // Load a client from an address
-func Load(log log.Interface, addr string) (Client, error) {
+func Load(log log.Interface, addr string, options ...Option) (Client, error) {
+	config := new(config)
+	for _, option := range options {
+		option(config)
+	}
+	if scheme, rest, ok := strings.Cut(addr, "://"); ok {
+		if dial, ok := dialers[scheme]; ok {
+			return dial(log, rest)
+		}
+	}
 	url, err := urlx.Parse(addr)
 	if err != nil {
 		return nil, err
@@ -42,26 +68,49 @@ func Load(log log.Interface, addr string) (Client, error) {
 	if err != nil {
 		return nil, fmt.Errorf("budhttp: unable to create transport from listener. %w", err)
 	}
+	// Configure TLS when connecting to a remote dev server
+	if url.Scheme == "https" {
+		tlsConfig, err := config.tlsConfig()
+		if err != nil {
+			return nil, fmt.Errorf("budhttp: unable to configure tls. %w", err)
+		}
+		httpTransport, ok := transport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("budhttp: unable to configure tls for %q", addr)
+		}
+		tlsConfig.ServerName = url.Hostname()
+		httpTransport.TLSClientConfig = tlsConfig
+	}
+	if config.token != "" {
+		transport = &tokenTransport{config.token, transport}
+	}
 	httpClient := &http.Client{
-		Transport: transport,
+		// Pass the trace along to the dev server
+		Transport: tracing.Transport(transport),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
-	return &client{
+	client := &client{
 		baseURL:    url.String(),
 		httpClient: httpClient,
 		log:        log,
-	}, nil
+	}
+	if config.cache {
+		client.cache = newCache()
+	}
+	return client, nil
 }
 
 type client struct {
 	baseURL    string
 	httpClient *http.Client
 	log        log.Interface
+	cache      *cache // Can be nil
 }
 
 var _ Client = (*client)(nil)
+var _ js.ContextVM = (*client)(nil)
 
 // Render a path with props on the dev server
 func (c *client) Render(route string, props interface{}) (*ssr.Response, error) {
@@ -85,7 +134,45 @@ func (c *client) Render(route string, props interface{}) (*ssr.Response, error)
 	return &response, nil
 }
 
+// RenderBatch renders multiple routes on the dev server in a single round-trip.
+// Responses are returned in the same order as the requests.
+func (c *client) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: render batch. %w", err)
+	}
+	key := "render:" + string(body)
+	if resBody, ok := c.cached(key); ok {
+		return ssr.UnmarshalBatch(resBody)
+	}
+	res, err := c.httpClient.Post(c.baseURL+"/bud/render", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: render batch. %w", err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: render batch. %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("budhttp: render batch returned unexpected %d. %s", res.StatusCode, resBody)
+	}
+	responses, err := ssr.UnmarshalBatch(resBody)
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: render batch. %w", err)
+	}
+	if len(responses) != len(requests) {
+		return nil, fmt.Errorf("budhttp: render batch expected %d responses but got %d", len(requests), len(responses))
+	}
+	c.store(res, key, resBody)
+	return responses, nil
+}
+
 func (c *client) Open(name string) (fs.File, error) {
+	key := "open:" + name
+	if body, ok := c.cached(key); ok {
+		return virtual.UnmarshalJSON(body)
+	}
 	res, err := c.httpClient.Get(c.baseURL + "/open/" + name)
 	if err != nil {
 		return nil, err
@@ -101,9 +188,27 @@ func (c *client) Open(name string) (fs.File, error) {
 		}
 		return nil, fmt.Errorf("budhttp: open returned unexpected %d. %s", res.StatusCode, body)
 	}
+	c.store(res, key, body)
 	return virtual.UnmarshalJSON(body)
 }
 
+// cached returns a cached response body for the current build
+func (c *client) cached(key string) ([]byte, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	c.cache.Watch(c)
+	return c.cache.Get(key)
+}
+
+// store the response body for the build it came from
+func (c *client) store(res *http.Response, key string, body []byte) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.Set(res.Header.Get(BuildHeader), key, body)
+}
+
 type Event struct {
 	Topic string `json:"topic,omitempty"`
 	Data  []byte `json:"data,omitempty"`
@@ -162,12 +267,21 @@ type Eval struct {
 }
 
 func (c *client) Eval(path, expr string) (string, error) {
+	return c.EvalContext(context.Background(), path, expr)
+}
+
+// EvalContext evaluates the expression on the dev server within the context
+func (c *client) EvalContext(ctx context.Context, path, expr string) (string, error) {
 	body, err := json.Marshal(Eval{path, expr})
 	if err != nil {
 		return "", err
 	}
-	url := c.baseURL + "/js/eval"
-	res, err := c.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/js/eval", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -1,12 +1,18 @@
 package budhttp_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"io"
 	"io/fs"
+	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,6 +31,7 @@ import (
 	"github.com/livebud/bud/package/gomod"
 	v8 "github.com/livebud/bud/package/js/v8"
 	"github.com/livebud/bud/package/svelte"
+	"github.com/livebud/bud/package/virtual"
 )
 
 func loadServer(bus pubsub.Client, dir string) (*httptest.Server, error) {
@@ -201,3 +208,288 @@ func TestScriptEval(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(val, "1")
 }
+
+func TestStream(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	data := bytes.Repeat([]byte("0123456789"), 100_000)
+	modTime := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	fsys := virtual.Map{
+		"public/video.mp4": &virtual.File{Data: data, ModTime: modTime},
+	}
+	server := httptest.NewServer(budsvr.New(fsys, pubsub.New(), log, nil))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	file, err := client.Stream("public/video.mp4")
+	is.NoErr(err)
+	defer file.Close()
+	stat, err := file.Stat()
+	is.NoErr(err)
+	is.Equal(stat.Name(), "video.mp4")
+	is.Equal(stat.Size(), int64(len(data)))
+	is.Equal(stat.ModTime(), modTime)
+	is.Equal(stat.IsDir(), false)
+	// Read from the middle of the file
+	seeker, ok := file.(io.Seeker)
+	is.True(ok)
+	offset, err := seeker.Seek(-15, io.SeekEnd)
+	is.NoErr(err)
+	is.Equal(offset, int64(len(data)-15))
+	rest, err := io.ReadAll(file)
+	is.NoErr(err)
+	is.Equal(string(rest), "567890123456789")
+	// Read the whole file
+	_, err = seeker.Seek(0, io.SeekStart)
+	is.NoErr(err)
+	all, err := io.ReadAll(file)
+	is.NoErr(err)
+	is.Equal(len(all), len(data))
+	is.True(bytes.Equal(all, data))
+}
+
+func TestStream404(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	server := httptest.NewServer(budsvr.New(virtual.Map{}, pubsub.New(), log, nil))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	file, err := client.Stream("public/favicon.ico")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	is.Equal(file, nil)
+}
+
+func TestToken(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	fsys := virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: []byte("console.log('hi')")},
+	}
+	server := httptest.NewServer(budsvr.New(fsys, pubsub.New(), log, nil, budsvr.WithToken("s3cret")))
+	defer server.Close()
+	// Clients without the token are turned away
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	_, err = fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.True(err != nil)
+	_, err = client.Eval("eval.js", "1+1")
+	is.True(err != nil)
+	_, err = client.Subscribe("build:finish")
+	is.True(err != nil)
+	client, err = budhttp.Load(log, server.URL, budhttp.WithToken("wrong"))
+	is.NoErr(err)
+	_, err = fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.True(err != nil)
+	// Clients with the token get through
+	client, err = budhttp.Load(log, server.URL, budhttp.WithToken("s3cret"))
+	is.NoErr(err)
+	data, err := fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.NoErr(err)
+	is.Equal(string(data), "console.log('hi')")
+	sub, err := client.Subscribe("build:finish")
+	is.NoErr(err)
+	is.NoErr(sub.Close())
+}
+
+func TestRenderBatch(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	vm, err := v8.Load()
+	is.NoErr(err)
+	fsys := virtual.Map{
+		"bud/view/_ssr.js": &virtual.File{Data: []byte(`
+			var bud = {
+				render(route, props) {
+					return JSON.stringify({ status: 200, body: route + ":" + props.name })
+				}
+			}
+		`)},
+	}
+	server := httptest.NewServer(budsvr.New(fsys, pubsub.New(), log, vm))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	responses, err := client.RenderBatch(
+		&ssr.Request{Route: "/layout", Props: map[string]string{"name": "layout"}},
+		&ssr.Request{Route: "/island", Props: map[string]string{"name": "island"}},
+	)
+	is.NoErr(err)
+	is.Equal(len(responses), 2)
+	is.Equal(responses[0].Status, 200)
+	is.Equal(responses[0].Body, "/layout:layout")
+	is.Equal(responses[1].Status, 200)
+	is.Equal(responses[1].Body, "/island:island")
+}
+
+func TestSubscribe(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ps := pubsub.New()
+	server := httptest.NewServer(budsvr.New(virtual.Map{}, ps, log, nil))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	sub, err := client.Subscribe("build:error", "file:change")
+	is.NoErr(err)
+	defer sub.Close()
+	ps.Publish("build:error", []byte("unable to compile"))
+	event, err := sub.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "build:error")
+	is.Equal(string(event.Data), "unable to compile")
+	ps.Publish("file:change", []byte(`["view/index.svelte"]`))
+	event, err = sub.Next(ctx)
+	is.NoErr(err)
+	is.Equal(event.Topic, "file:change")
+	is.Equal(string(event.Data), `["view/index.svelte"]`)
+}
+
+// waitStats polls the stats until ok returns true
+func waitStats(t testing.TB, client budhttp.Client, ok func(stats *budhttp.Stats) bool) *budhttp.Stats {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats, err := client.(budhttp.StatsClient).Stats()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for stats")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStats(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ps := pubsub.New()
+	server := httptest.NewServer(budsvr.New(virtual.Map{}, ps, log, nil))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	stats := waitStats(t, client, func(stats *budhttp.Stats) bool { return true })
+	is.Equal(len(stats.Builds), 0)
+	// A successful build
+	ps.Publish("build:start", nil)
+	waitStats(t, client, func(stats *budhttp.Stats) bool { return len(stats.Builds) == 1 })
+	ps.Publish("build:timings", []byte(`[{"path":"bud/internal/app/main.go","duration":1000}]`))
+	ps.Publish("watch:files", []byte("42"))
+	ps.Publish("build:finish", []byte("1"))
+	stats = waitStats(t, client, func(stats *budhttp.Stats) bool {
+		return stats.Builds[0].Duration > 0 && len(stats.Generators) == 1 && stats.Watching == 42
+	})
+	is.Equal(stats.Builds[0].Error, "")
+	is.Equal(stats.Generators[0].Path, "bud/internal/app/main.go")
+	is.Equal(stats.Generators[0].Duration, time.Microsecond)
+	// A failed build
+	ps.Publish("build:start", nil)
+	waitStats(t, client, func(stats *budhttp.Stats) bool { return len(stats.Builds) == 2 })
+	ps.Publish("build:error", []byte("unable to compile"))
+	stats = waitStats(t, client, func(stats *budhttp.Stats) bool { return len(stats.Errors) == 1 })
+	is.Equal(stats.Builds[0].Error, "unable to compile")
+	is.Equal(stats.Errors[0].Topic, "build:error")
+	is.Equal(stats.Errors[0].Message, "unable to compile")
+	// App errors
+	ps.Publish("app:error", []byte("unable to start"))
+	stats = waitStats(t, client, func(stats *budhttp.Stats) bool { return len(stats.Errors) == 2 })
+	is.Equal(stats.Errors[0].Topic, "app:error")
+	is.Equal(stats.Errors[1].Topic, "build:error")
+}
+
+func TestRemoteTLS(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	fsys := virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: []byte("console.log('hi')")},
+	}
+	server := httptest.NewTLSServer(budsvr.New(fsys, pubsub.New(), log, nil))
+	defer server.Close()
+	// Untrusted certificates fail
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	_, err = client.Open("bud/view/_index.svelte.js")
+	is.True(err != nil)
+	is.In(err.Error(), "certificate")
+	// Trusted certificates succeed
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	client, err = budhttp.Load(log, server.URL, budhttp.WithTLS(&tls.Config{RootCAs: pool}))
+	is.NoErr(err)
+	data, err := fs.ReadFile(client, "bud/view/_index.svelte.js")
+	is.NoErr(err)
+	is.Equal(string(data), "console.log('hi')")
+}
+
+func TestCacheByBuild(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	ps := pubsub.New()
+	var opens int32
+	fsys := virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: []byte("console.log('hi')")},
+	}
+	handler := budsvr.New(fsys, ps, log, nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/open/") {
+			atomic.AddInt32(&opens, 1)
+		}
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+	defer server.CloseClientConnections()
+	// Announce the first build
+	ps.Publish("build:finish", []byte("build1"))
+	client, err := budhttp.Load(log, server.URL, budhttp.WithCache(true))
+	is.NoErr(err)
+	readFile := func() string {
+		data, err := fs.ReadFile(client, "bud/view/_index.svelte.js")
+		is.NoErr(err)
+		return string(data)
+	}
+	// Wait for the server to pick up the build
+	for i := 0; i < 100; i++ {
+		res, err := http.Get(server.URL + "/open/bud/view/_index.svelte.js")
+		is.NoErr(err)
+		res.Body.Close()
+		if res.Header.Get(budhttp.BuildHeader) == "build1" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	atomic.StoreInt32(&opens, 0)
+	is.Equal(readFile(), "console.log('hi')")
+	is.Equal(readFile(), "console.log('hi')")
+	is.Equal(atomic.LoadInt32(&opens), int32(1))
+	// A new build invalidates the cache
+	fsys["bud/view/_index.svelte.js"] = &virtual.File{Data: []byte("console.log('bye')")}
+	ps.Publish("build:finish", []byte("build2"))
+	for i := 0; i < 100 && readFile() != "console.log('bye')"; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	is.Equal(readFile(), "console.log('bye')")
+}
+
+func BenchmarkOpen(b *testing.B) {
+	is := is.New(b)
+	log := testlog.New()
+	fsys := virtual.Map{
+		"bud/view/_index.svelte.js": &virtual.File{Data: bytes.Repeat([]byte("console.log('hello world');\n"), 20_000)},
+	}
+	server := httptest.NewServer(budsvr.New(fsys, pubsub.New(), log, nil))
+	defer server.Close()
+	client, err := budhttp.Load(log, server.URL)
+	is.NoErr(err)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := fs.ReadFile(client, "bud/view/_index.svelte.js"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -17,6 +17,10 @@ func (discard) Render(route string, props interface{}) (*ssr.Response, error) {
 	return nil, fmt.Errorf("budhttp: discard client does not support render")
 }
 
+func (discard) RenderBatch(requests ...*ssr.Request) ([]*ssr.Response, error) {
+	return nil, fmt.Errorf("budhttp: discard client does not support render batch")
+}
+
 func (discard) Script(path, script string) error {
 	return fmt.Errorf("budhttp: discard client does not support script")
 }
@@ -29,6 +33,14 @@ func (discard) Open(name string) (fs.File, error) {
 	return nil, fmt.Errorf("budhttp: discard client does not support open")
 }
 
+func (discard) Stream(name string) (fs.File, error) {
+	return nil, fmt.Errorf("budhttp: discard client does not support stream")
+}
+
+func (discard) Subscribe(topics ...string) (Subscription, error) {
+	return nil, fmt.Errorf("budhttp: discard client does not support subscribe")
+}
+
 // Publish nothing
 func (discard) Publish(topic string, data []byte) error {
 	return nil
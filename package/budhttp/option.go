@@ -0,0 +1,86 @@
+package budhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Option configures the client
+type Option func(c *config)
+
+type config struct {
+	tls    *tls.Config
+	caFile string
+	cache  bool
+	token  string
+}
+
+// WithCache caches file and render responses until the dev server announces a
+// new build. The client keeps a subscription open to the dev server to know
+// when to invalidate the cache.
+func WithCache(enable bool) Option {
+	return func(c *config) {
+		c.cache = enable
+	}
+}
+
+// WithToken sends the token in an "Authorization: Bearer" header to dev servers
+// that require one. An empty token is ignored.
+func WithToken(token string) Option {
+	return func(c *config) {
+		c.token = token
+	}
+}
+
+// WithTLS configures the TLS settings used to connect to a remote dev server
+// over https
+func WithTLS(tls *tls.Config) Option {
+	return func(c *config) {
+		c.tls = tls
+	}
+}
+
+// WithCertificateAuthority trusts the PEM-encoded certificate authority at
+// path when connecting to a remote dev server over https. This is useful for
+// self-signed certificates. An empty path is ignored.
+func WithCertificateAuthority(path string) Option {
+	return func(c *config) {
+		c.caFile = path
+	}
+}
+
+func (c *config) tlsConfig() (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.tls != nil {
+		config = c.tls.Clone()
+	}
+	if c.caFile == "" {
+		return config, nil
+	}
+	pem, err := os.ReadFile(c.caFile)
+	if err != nil {
+		return nil, err
+	}
+	if config.RootCAs == nil {
+		config.RootCAs = x509.NewCertPool()
+	}
+	if !config.RootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("budhttp: no certificates found in %q", c.caFile)
+	}
+	return config, nil
+}
+
+// tokenTransport adds the bearer token to each request
+type tokenTransport struct {
+	token string
+	next  http.RoundTripper
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.next.RoundTrip(req)
+}
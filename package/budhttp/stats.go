@@ -0,0 +1,73 @@
+package budhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Stats about the development server, collected from the event bus
+type Stats struct {
+	Builds     []*BuildStat     `json:"builds,omitempty"`     // Newest first
+	Generators []*GeneratorStat `json:"generators,omitempty"` // From the last build
+	Watching   int              `json:"watching,omitempty"`   // Number of watched files
+	Errors     []*ErrorStat     `json:"errors,omitempty"`     // Newest first
+}
+
+// StatsClient is implemented by clients that can get the stats from the dev
+// server
+type StatsClient interface {
+	Stats() (*Stats, error)
+}
+
+var _ StatsClient = (*client)(nil)
+var _ StatsClient = discard{}
+
+// BuildStat is a build that started. Builds that are still running have no
+// duration.
+type BuildStat struct {
+	Start    time.Time     `json:"start,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// GeneratorStat is how long a generator took
+type GeneratorStat struct {
+	Path     string        `json:"path,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// ErrorStat is an error that was published to the event bus
+type ErrorStat struct {
+	Time    time.Time `json:"time,omitempty"`
+	Topic   string    `json:"topic,omitempty"`
+	Message string    `json:"message,omitempty"`
+}
+
+// Stats returns the stats collected by the dev server
+func (c *client) Stats() (*Stats, error) {
+	res, err := c.httpClient.Get(c.baseURL + "/bud/stats")
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: unable to get stats. %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: unable to read stats. %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("budhttp: stats returned unexpected %d. %s", res.StatusCode, body)
+	}
+	stats := new(Stats)
+	if err := json.Unmarshal(body, stats); err != nil {
+		return nil, fmt.Errorf("budhttp: unable to parse stats. %w", err)
+	}
+	return stats, nil
+}
+
+// Stats are empty without a dev server
+func (discard) Stats() (*Stats, error) {
+	return new(Stats), nil
+}
@@ -0,0 +1,152 @@
+package budhttp
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Stream a file from the dev server. Unlike Open, the file's contents aren't
+// buffered into memory. Instead each read is served by a ranged request, so
+// seeking within large files (e.g. videos or fonts) only transfers the bytes
+// that are actually read.
+func (c *client) Stream(name string) (fs.File, error) {
+	req, err := http.NewRequest(http.MethodHead, c.baseURL+"/stream/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		if res.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("budhttp: stream %q. %w", name, fs.ErrNotExist)
+		}
+		return nil, fmt.Errorf("budhttp: stream returned unexpected %d", res.StatusCode)
+	}
+	info := &streamInfo{
+		name: path.Base(name),
+		size: res.ContentLength,
+	}
+	if lastModified := res.Header.Get("Last-Modified"); lastModified != "" {
+		modTime, err := http.ParseTime(lastModified)
+		if err != nil {
+			return nil, fmt.Errorf("budhttp: stream %q has an invalid modtime. %w", name, err)
+		}
+		info.modTime = modTime
+	}
+	return &streamFile{
+		client: c,
+		path:   name,
+		info:   info,
+	}, nil
+}
+
+type streamFile struct {
+	client *client
+	path   string
+	info   *streamInfo
+	offset int64
+	body   io.ReadCloser // Response body positioned at offset
+}
+
+var _ fs.File = (*streamFile)(nil)
+var _ io.ReadSeeker = (*streamFile)(nil)
+
+func (f *streamFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *streamFile) Read(p []byte) (int, error) {
+	if f.offset >= f.info.size {
+		return 0, io.EOF
+	}
+	if f.body == nil {
+		body, err := f.request(f.offset)
+		if err != nil {
+			return 0, err
+		}
+		f.body = body
+	}
+	n, err := f.body.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Request the remainder of the file starting at offset
+func (f *streamFile) request(offset int64) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, f.client.baseURL+"/stream/"+f.path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	res, err := f.client.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		return res.Body, nil
+	case http.StatusOK:
+		// The server ignored the range, so skip ahead ourselves
+		if _, err := io.CopyN(io.Discard, res.Body, offset); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		return res.Body, nil
+	default:
+		res.Body.Close()
+		return nil, fmt.Errorf("budhttp: stream returned unexpected %d", res.StatusCode)
+	}
+}
+
+func (f *streamFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		// offset += 0
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.info.size
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: f.path, Err: fs.ErrInvalid}
+	}
+	// Drop the current response when we move, the next read will re-request
+	if offset != f.offset && f.body != nil {
+		f.body.Close()
+		f.body = nil
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *streamFile) Close() error {
+	if f.body == nil {
+		return nil
+	}
+	err := f.body.Close()
+	f.body = nil
+	return err
+}
+
+type streamInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+var _ fs.FileInfo = (*streamInfo)(nil)
+
+func (i *streamInfo) Name() string       { return i.name }
+func (i *streamInfo) Size() int64        { return i.size }
+func (i *streamInfo) Mode() fs.FileMode  { return 0 }
+func (i *streamInfo) ModTime() time.Time { return i.modTime }
+func (i *streamInfo) IsDir() bool        { return false }
+func (i *streamInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,56 @@
+package budhttp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/livebud/bud/package/hot"
+)
+
+// Subscription to events published by the dev server
+type Subscription interface {
+	Next(ctx context.Context) (*Event, error)
+	Close() error
+}
+
+// Subscribe to events published by the dev server (e.g. build:start,
+// build:finish, build:error and file:change). If no topics are passed in, the
+// dev server's default topics are used.
+func (c *client) Subscribe(topics ...string) (Subscription, error) {
+	u := c.baseURL + "/bud/events"
+	if len(topics) > 0 {
+		u += "?topics=" + url.QueryEscape(strings.Join(topics, ","))
+	}
+	stream, err := hot.DialWith(c.httpClient, c.log, u)
+	if err != nil {
+		return nil, fmt.Errorf("budhttp: unable to subscribe to %v. %w", topics, err)
+	}
+	return &subscription{stream}, nil
+}
+
+type subscription struct {
+	stream *hot.Stream
+}
+
+var _ Subscription = (*subscription)(nil)
+
+func (s *subscription) Next(ctx context.Context) (*Event, error) {
+	event, err := s.stream.Next(ctx)
+	if err != nil {
+		return nil, err
+	} else if event == nil {
+		// The dev server closed the stream
+		return nil, io.EOF
+	}
+	return &Event{
+		Topic: event.Type,
+		Data:  event.Data,
+	}, nil
+}
+
+func (s *subscription) Close() error {
+	return s.stream.Close()
+}
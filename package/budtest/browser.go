@@ -0,0 +1,260 @@
+package budtest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/package/browser"
+)
+
+// browsers keeps a connection to each browser, so the tests in a package
+// share a connection
+var browsers sync.Map
+
+type browserResult struct {
+	once    sync.Once
+	browser *browser.Browser
+	err     error
+}
+
+// connectBrowser connects to the browser that "bud test --browser" launched
+func connectBrowser(t testing.TB) *browser.Browser {
+	t.Helper()
+	url := os.Getenv("BUD_BROWSER")
+	if url == "" {
+		t.Skip("budtest: skipping browser test. Run \"bud test --browser\" to run it")
+	}
+	result, _ := browsers.LoadOrStore(url, new(browserResult))
+	conn := result.(*browserResult)
+	conn.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+		conn.browser, conn.err = browser.Connect(ctx, url)
+	})
+	if conn.err != nil {
+		t.Fatal(conn.err)
+	}
+	return conn.browser
+}
+
+// defaultTimeout for the page to load or match an expectation
+const defaultTimeout = 10 * time.Second
+
+// Visit the path in a headless browser. The test is skipped unless it's run
+// with "bud test --browser". The page is closed when the test finishes.
+//
+// The browser runs the page's JavaScript, so hydration and client-side
+// behavior can be tested:
+//
+//	app.Visit("/counter").
+//		ExpectText("#count", "0").
+//		Click("button").
+//		ExpectText("#count", "1").
+//		ExpectNoErrors()
+func (c *Client) Visit(path string) *Page {
+	c.t.Helper()
+	b := connectBrowser(c.t)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	page, err := b.NewPage(ctx)
+	if err != nil {
+		c.t.Fatalf("budtest: unable to open a page. %s", err)
+	}
+	c.t.Cleanup(func() { page.Close() })
+	p := &Page{
+		Timeout: defaultTimeout,
+		t:       c.t,
+		page:    page,
+		baseURL: c.baseURL,
+		output:  c.output,
+	}
+	return p.Visit(path)
+}
+
+// Page in the browser. Failed actions and expectations fail the test.
+// Expectations are retried until they pass or time out, since pages may
+// still be rendering.
+type Page struct {
+	// Timeout for the page to load or match an expectation
+	Timeout time.Duration
+	t       testing.TB
+	page    *browser.Page
+	baseURL string
+	output  *syncBuffer // Nil for handlers
+}
+
+// fail the test, showing the app's output
+func (p *Page) fail(format string, args ...interface{}) {
+	p.t.Helper()
+	if p.output != nil {
+		args = append(args, p.output)
+		format += "\n\nThe app's output:\n%s"
+	}
+	p.t.Fatalf(format, args...)
+}
+
+func (p *Page) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), p.Timeout)
+}
+
+// retry fn until it returns nil or the page times out. The last error is
+// returned.
+func (p *Page) retry(fn func(ctx context.Context) error) error {
+	ctx, cancel := p.context()
+	defer cancel()
+	for {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// Visit the path in the same page
+func (p *Page) Visit(path string) *Page {
+	p.t.Helper()
+	ctx, cancel := p.context()
+	defer cancel()
+	if err := p.page.Navigate(ctx, p.baseURL+path); err != nil {
+		p.fail("budtest: unable to visit %s. %s", path, err)
+	}
+	return p
+}
+
+// WaitFor an element to match the selector
+func (p *Page) WaitFor(selector string) *Page {
+	p.t.Helper()
+	err := p.retry(func(ctx context.Context) error {
+		exists, err := p.page.Exists(ctx, selector)
+		if err != nil {
+			return err
+		} else if !exists {
+			return errors.New("it doesn't exist")
+		}
+		return nil
+	})
+	if err != nil {
+		p.fail("budtest: timed out waiting for an element matching %q. %s", selector, err)
+	}
+	return p
+}
+
+// Click the first element that matches the selector
+func (p *Page) Click(selector string) *Page {
+	p.t.Helper()
+	p.WaitFor(selector)
+	ctx, cancel := p.context()
+	defer cancel()
+	if err := p.page.Click(ctx, selector); err != nil {
+		p.fail("budtest: unable to click %q. %s", selector, err)
+	}
+	return p
+}
+
+// Fill the first input that matches the selector with the value
+func (p *Page) Fill(selector, value string) *Page {
+	p.t.Helper()
+	p.WaitFor(selector)
+	ctx, cancel := p.context()
+	defer cancel()
+	if err := p.page.Fill(ctx, selector, value); err != nil {
+		p.fail("budtest: unable to fill %q. %s", selector, err)
+	}
+	return p
+}
+
+// ExpectText checks that the first element that matches the selector has the
+// text, ignoring surrounding whitespace
+func (p *Page) ExpectText(selector, text string) *Page {
+	p.t.Helper()
+	actual := ""
+	err := p.retry(func(ctx context.Context) (err error) {
+		actual, err = p.page.Text(ctx, selector)
+		if err != nil {
+			return err
+		}
+		actual = strings.TrimSpace(actual)
+		if actual != text {
+			return errors.New("the text doesn't match")
+		}
+		return nil
+	})
+	if err != nil {
+		p.fail("budtest: expected %q to have the text %q, got %q. %s", selector, text, actual, err)
+	}
+	return p
+}
+
+// ExpectURL checks that the page is at the path, like "/posts/1?page=2"
+func (p *Page) ExpectURL(path string) *Page {
+	p.t.Helper()
+	actual := ""
+	err := p.retry(func(ctx context.Context) (err error) {
+		actual, err = p.page.URL(ctx)
+		if err != nil {
+			return err
+		}
+		actual = strings.TrimPrefix(actual, p.baseURL)
+		if actual != path {
+			return errors.New("the url doesn't match")
+		}
+		return nil
+	})
+	if err != nil {
+		p.fail("budtest: expected the page to be at %q, got %q. %s", path, actual, err)
+	}
+	return p
+}
+
+// ExpectNoErrors checks that the page hasn't thrown any uncaught errors, like
+// errors while hydrating
+func (p *Page) ExpectNoErrors() *Page {
+	p.t.Helper()
+	if errs := p.page.Errors(); len(errs) > 0 {
+		messages := make([]string, len(errs))
+		for i, err := range errs {
+			messages[i] = err.Error()
+		}
+		p.fail("budtest: expected no errors in the page, got:\n%s", strings.Join(messages, "\n"))
+	}
+	return p
+}
+
+// Eval evaluates the JavaScript expression in the page and decodes its value
+// into result, if it's not nil. Promises are awaited.
+func (p *Page) Eval(expr string, result interface{}) *Page {
+	p.t.Helper()
+	ctx, cancel := p.context()
+	defer cancel()
+	if err := p.page.Evaluate(ctx, expr, result); err != nil {
+		p.fail("budtest: unable to evaluate %q. %s", expr, err)
+	}
+	return p
+}
+
+// HTML of the page as it's currently rendered
+func (p *Page) HTML() string {
+	p.t.Helper()
+	ctx, cancel := p.context()
+	defer cancel()
+	html, err := p.page.HTML(ctx)
+	if err != nil {
+		p.fail("budtest: unable to get the page's HTML. %s", err)
+	}
+	return html
+}
+
+// Browser returns the underlying page for anything the helpers don't cover
+func (p *Page) Browser() *browser.Page {
+	return p.page
+}
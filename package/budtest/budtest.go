@@ -0,0 +1,361 @@
+// Package budtest tests Bud apps from end to end. Start builds the app and
+// runs it until the test finishes, then the client sends it requests:
+//
+//	func TestCreatePost(t *testing.T) {
+//		app := budtest.Start(t, ".")
+//		app.Post("/posts", url.Values{"title": {"Hello"}}).
+//			ExpectStatus(http.StatusSeeOther).
+//			ExpectRedirect("/posts/1")
+//		app.Get("/posts/1").
+//			ExpectStatus(http.StatusOK).
+//			ExpectText("h1", "Hello")
+//	}
+//
+// Handlers can be tested without building the app using New.
+package budtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/cli"
+	"github.com/livebud/bud/internal/cli/bud"
+	"github.com/livebud/bud/internal/extrafile"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/socket"
+)
+
+// Option configures the app
+type Option func(c *config)
+
+type config struct {
+	env []string
+}
+
+// Env sets an environment variable for the app, like the DATABASE_URL of a
+// test database
+func Env(key, value string) Option {
+	return func(c *config) {
+		c.env = append(c.env, key+"="+value)
+	}
+}
+
+// builds keeps the result of building each app, so apps are built once for
+// all the tests in a package
+var builds sync.Map
+
+type buildResult struct {
+	once sync.Once
+	err  error
+}
+
+// Start builds the app in dir and runs it until the test finishes. The app
+// may be in a parent directory of dir. The app inherits the test's
+// environment, along with the environment variables in the options.
+//
+// Under "bud test -coverprofile", the app is built with coverage and its
+// coverage is merged with the tests'.
+func Start(t testing.TB, dir string, options ...Option) *Client {
+	t.Helper()
+	config := new(config)
+	for _, option := range options {
+		option(config)
+	}
+	module, err := gomod.Find(dir)
+	if err != nil {
+		t.Fatalf("budtest: unable to find the app in %s. %s", dir, err)
+	}
+	dir = module.Directory()
+	result, _ := builds.LoadOrStore(dir, new(buildResult))
+	build := result.(*buildResult)
+	coverDir := os.Getenv("BUD_COVERDIR")
+	build.once.Do(func() { build.err = buildApp(dir, coverDir != "") })
+	if build.err != nil {
+		t.Fatal(build.err)
+	}
+	listener, err := socket.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("budtest: unable to listen. %s", err)
+	}
+	file, err := listener.File()
+	if err != nil {
+		t.Fatalf("budtest: unable to get the listener's file. %s", err)
+	}
+	output := new(syncBuffer)
+	cmd := exec.Command(filepath.Join(dir, "bud", "app"))
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), config.env...)
+	if coverDir != "" {
+		cmd.Env = append(cmd.Env, "GOCOVERDIR="+coverDir)
+	}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	extrafile.Inject(&cmd.ExtraFiles, &cmd.Env, "WEB", file)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("budtest: unable to start the app. %s", err)
+	}
+	// The app has its own copy of the listener. Closing ours means requests are
+	// refused instead of hanging if the app exits.
+	file.Close()
+	listener.Close()
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-exited:
+		case <-time.After(10 * time.Second):
+			cmd.Process.Kill()
+			<-exited
+		}
+	})
+	client := newClient(t, "http://"+listener.Addr().String())
+	client.output = output
+	return client
+}
+
+// buildApp builds the app in dir like "bud build"
+func buildApp(dir string, cover bool) error {
+	stderr := new(bytes.Buffer)
+	cli := cli.New(&bud.Input{
+		Dir:    dir,
+		Env:    os.Environ(),
+		Stdout: io.Discard,
+		Stderr: stderr,
+	})
+	args := []string{"build", "--minify=false"}
+	if cover {
+		args = append(args, "--cover")
+	}
+	if err := cli.Run(context.Background(), args...); err != nil {
+		return fmt.Errorf("budtest: unable to build %s. %w\n%s", dir, err, stderr)
+	}
+	return nil
+}
+
+// New client that sends requests to the handler, which is served with
+// httptest until the test finishes
+func New(t testing.TB, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return newClient(t, server.URL)
+}
+
+func newClient(t testing.TB, baseURL string) *Client {
+	jar, _ := cookiejar.New(nil)
+	client := &Client{
+		Header:  http.Header{},
+		t:       t,
+		baseURL: baseURL,
+	}
+	client.http = &http.Client{
+		Jar:     jar,
+		Timeout: time.Minute,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !client.follow {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("budtest: stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	return client
+}
+
+// Client sends requests to the app. Cookies are kept between requests, like
+// a browser. Failed requests and expectations fail the test.
+type Client struct {
+	// Header is sent with every request
+	Header  http.Header
+	t       testing.TB
+	http    *http.Client
+	baseURL string
+	follow  bool
+	output  *syncBuffer // Nil for handlers
+}
+
+// URL of the app
+func (c *Client) URL() string {
+	return c.baseURL
+}
+
+// FollowRedirects turns following redirects on or off. It's off by default,
+// so redirects can be checked with ExpectRedirect.
+func (c *Client) FollowRedirects(follow bool) *Client {
+	c.follow = follow
+	return c
+}
+
+// NewSession returns a client for the same app with its own cookie jar, like
+// a second browser
+func (c *Client) NewSession() *Client {
+	client := newClient(c.t, c.baseURL)
+	client.Header = c.Header.Clone()
+	client.follow = c.follow
+	client.output = c.output
+	return client
+}
+
+// Cookie returns the cookie that will be sent to the app or nil if there's no
+// cookie with that name
+func (c *Client) Cookie(name string) *http.Cookie {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil
+	}
+	for _, cookie := range c.http.Jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// Get the page at path
+func (c *Client) Get(path string) *Response {
+	c.t.Helper()
+	return c.Do(c.request(http.MethodGet, path, nil, ""))
+}
+
+// GetJSON gets the JSON at path
+func (c *Client) GetJSON(path string) *Response {
+	c.t.Helper()
+	req := c.request(http.MethodGet, path, nil, "")
+	req.Header.Set("Accept", "application/json")
+	return c.Do(req)
+}
+
+// Post the form to path, like submitting a form in the browser
+func (c *Client) Post(path string, form url.Values) *Response {
+	c.t.Helper()
+	return c.Do(c.request(http.MethodPost, path, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"))
+}
+
+// PostJSON posts the value encoded as JSON to path
+func (c *Client) PostJSON(path string, v interface{}) *Response {
+	c.t.Helper()
+	return c.Do(c.jsonRequest(http.MethodPost, path, v))
+}
+
+// Patch the form to path
+func (c *Client) Patch(path string, form url.Values) *Response {
+	c.t.Helper()
+	return c.Do(c.request(http.MethodPatch, path, strings.NewReader(form.Encode()), "application/x-www-form-urlencoded"))
+}
+
+// PatchJSON patches the value encoded as JSON to path
+func (c *Client) PatchJSON(path string, v interface{}) *Response {
+	c.t.Helper()
+	return c.Do(c.jsonRequest(http.MethodPatch, path, v))
+}
+
+// Delete the resource at path
+func (c *Client) Delete(path string) *Response {
+	c.t.Helper()
+	return c.Do(c.request(http.MethodDelete, path, nil, ""))
+}
+
+func (c *Client) request(method, path string, body io.Reader, contentType string) *http.Request {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		c.t.Fatalf("budtest: unable to create the request. %s", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req
+}
+
+func (c *Client) jsonRequest(method, path string, v interface{}) *http.Request {
+	c.t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatalf("budtest: unable to encode the request body. %s", err)
+	}
+	req := c.request(method, path, bytes.NewReader(body), "application/json")
+	req.Header.Set("Accept", "application/json")
+	return req
+}
+
+// Do sends the request. Requests to a path are sent to the app.
+func (c *Client) Do(req *http.Request) *Response {
+	c.t.Helper()
+	if req.URL.Host == "" {
+		u, err := url.Parse(c.baseURL + req.URL.RequestURI())
+		if err != nil {
+			c.t.Fatalf("budtest: invalid path %q. %s", req.URL, err)
+		}
+		req.URL = u
+	}
+	for key, values := range c.Header {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		if c.output != nil {
+			c.t.Fatalf("budtest: %s %s failed. %s\n%s", req.Method, req.URL.Path, err, c.output)
+		}
+		c.t.Fatalf("budtest: %s %s failed. %s", req.Method, req.URL.Path, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		c.t.Fatalf("budtest: unable to read the response to %s %s. %s", req.Method, req.URL.Path, err)
+	}
+	return &Response{
+		Status: res.StatusCode,
+		Header: res.Header,
+		Body:   body,
+		t:      c.t,
+		req:    req,
+	}
+}
+
+// Output returns what the app has written to stdout and stderr so far
+func (c *Client) Output() string {
+	if c.output == nil {
+		return ""
+	}
+	return c.output.String()
+}
+
+// syncBuffer is a buffer that's safe to write to from the app's process
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
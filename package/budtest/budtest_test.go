@@ -0,0 +1,145 @@
+package budtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/internal/versions"
+	"github.com/livebud/bud/package/budtest"
+)
+
+func handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<h1> Hello </h1><ul><li>a</li><li>b</li></ul>")
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: r.FormValue("name"), Path: "/"})
+		http.Redirect(w, r, "/me", http.StatusSeeOther)
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil {
+			http.Error(w, "not logged in", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"name": cookie.Value, "accept": r.Header.Get("Accept")})
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), body)
+	})
+	return mux
+}
+
+func TestHTML(t *testing.T) {
+	is := is.New(t)
+	app := budtest.New(t, handler())
+	res := app.Get("/").
+		ExpectStatus(http.StatusOK).
+		ExpectContains("Hello").
+		ExpectText("h1", "Hello").
+		ExpectCount("li", 2)
+	is.Equal(res.Find("li").Last().Text(), "b")
+}
+
+func TestRedirectAndCookies(t *testing.T) {
+	is := is.New(t)
+	app := budtest.New(t, handler())
+	app.Post("/login", url.Values{"name": {"alice"}}).ExpectRedirect("/me")
+	is.Equal(app.Cookie("session").Value, "alice")
+	app.Get("/me").ExpectJSON(`{ "accept": "", "name": "alice" }`)
+	// Sessions don't share cookies
+	app.NewSession().Get("/me").ExpectStatus(http.StatusUnauthorized)
+}
+
+func TestFollowRedirects(t *testing.T) {
+	is := is.New(t)
+	app := budtest.New(t, handler()).FollowRedirects(true)
+	var me struct{ Name string }
+	app.Post("/login", url.Values{"name": {"bob"}}).
+		ExpectStatus(http.StatusOK).
+		DecodeJSON(&me)
+	is.Equal(me.Name, "bob")
+}
+
+func TestJSON(t *testing.T) {
+	app := budtest.New(t, handler())
+	app.PostJSON("/echo", map[string]int{"a": 1}).
+		ExpectContains(`POST application/json {"a":1}`)
+	app.Patch("/echo", url.Values{"b": {"2"}}).
+		ExpectContains(`PATCH application/x-www-form-urlencoded b=2`)
+	app.Delete("/echo").ExpectContains("DELETE")
+}
+
+func TestHeader(t *testing.T) {
+	app := budtest.New(t, handler())
+	app.Post("/login", url.Values{"name": {"alice"}})
+	app.Header.Set("Accept", "text/html")
+	app.Get("/me").ExpectJSON(`{"name":"alice","accept":"text/html"}`)
+	app.GetJSON("/me").ExpectJSON(`{"name":"alice","accept":"application/json"}`)
+}
+
+func TestNormalize(t *testing.T) {
+	is := is.New(t)
+	html := budtest.Normalize([]byte("<script src=\"/bud/view/chunk-5GQ2XLVE.js\"></script>\r\n<link href=\"/app.3f2a1b9c.css\">\n\n"))
+	is.Equal(string(html), "<script src=\"/bud/view/chunk-[hash].js\"></script>\n<link href=\"/app.[hash].css\">\n")
+	// Regular names are left alone
+	html = budtest.Normalize([]byte(`<script src="/bud/view/_index.svelte.js"></script>`))
+	is.Equal(string(html), "<script src=\"/bud/view/_index.svelte.js\"></script>\n")
+}
+
+func TestSnapshot(t *testing.T) {
+	app := budtest.New(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<h1>Hello</h1><script src="/bud/view/chunk-%s.js"></script>`, r.URL.Query().Get("hash"))
+	}))
+	app.Get("/?hash=5GQ2XLVE").ExpectSnapshot("hello")
+	app.Get("/?hash=ABCDEFGH").ExpectSnapshot("hello")
+}
+
+func TestRender(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	td := testdir.New(dir)
+	td.Files["view/index.svelte"] = `<script>export let name = ""</script><h1>Hello {name}</h1>`
+	td.NodeModules["svelte"] = versions.Svelte
+	is.NoErr(td.Write(context.Background()))
+	res := budtest.Render(t, dir, "/", map[string]string{"name": "Alice"}).
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "text/html").
+		ExpectText("h1", "Hello Alice")
+	is.Equal(res.Find(`script[type="module"]`).AttrOr("src", ""), "/bud/view/_index.svelte.js")
+}
+
+func TestVisitSkipsWithoutBrowser(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("BUD_BROWSER", "")
+	skipped := false
+	t.Run("visit", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		budtest.New(t, handler()).Visit("/")
+	})
+	is.True(skipped)
+}
+
+func TestVisit(t *testing.T) {
+	app := budtest.New(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<h1 id="count">0</h1><input name="name"><button onclick="count.textContent++">+</button>`)
+	}))
+	app.Visit("/").
+		ExpectText("#count", "0").
+		Click("button").
+		Click("button").
+		ExpectText("#count", "2").
+		Fill("input", "alice").
+		ExpectURL("/").
+		ExpectNoErrors()
+}
@@ -0,0 +1,83 @@
+package budtest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/livebud/bud/framework/transform/transformrt"
+	"github.com/livebud/bud/framework/view/ssr"
+	"github.com/livebud/bud/framework/view/viewrt"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	v8 "github.com/livebud/bud/package/js/v8"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/console"
+	"github.com/livebud/bud/package/svelte"
+)
+
+// renderers keeps a view server for each app, so views are compiled once for
+// all the tests in a package
+var renderers sync.Map
+
+type rendererResult struct {
+	once   sync.Once
+	server viewrt.Server
+	err    error
+}
+
+// Render the view at route with the props, the same way the app renders it on
+// the server. The app may be in a parent directory of dir. Views are compiled
+// the first time they're rendered, so changes to them during the test aren't
+// picked up.
+//
+//	budtest.Render(t, ".", "/posts/:id", map[string]interface{}{
+//		"post": map[string]interface{}{"title": "Hello"},
+//	}).ExpectSnapshot("post")
+func Render(t testing.TB, dir, route string, props interface{}) *Response {
+	t.Helper()
+	module, err := gomod.Find(dir)
+	if err != nil {
+		t.Fatalf("budtest: unable to find the app in %s. %s", dir, err)
+	}
+	dir = module.Directory()
+	result, _ := renderers.LoadOrStore(dir, new(rendererResult))
+	renderer := result.(*rendererResult)
+	renderer.once.Do(func() { renderer.server, renderer.err = loadRenderer(module) })
+	if renderer.err != nil {
+		t.Fatal(renderer.err)
+	}
+	req := httptest.NewRequest(http.MethodGet, route, nil)
+	rec := httptest.NewRecorder()
+	renderer.server.Handler(route, props).ServeHTTP(rec, req)
+	return &Response{
+		Status: rec.Code,
+		Header: rec.Header(),
+		Body:   rec.Body.Bytes(),
+		t:      t,
+		req:    req,
+	}
+}
+
+// loadRenderer compiles the app's views for the server
+func loadRenderer(module *gomod.Module) (viewrt.Server, error) {
+	log := log.New(console.New(io.Discard))
+	vm, err := v8.Load()
+	if err != nil {
+		return nil, fmt.Errorf("budtest: unable to load v8. %w", err)
+	}
+	svelteCompiler, err := svelte.Load(vm)
+	if err != nil {
+		return nil, fmt.Errorf("budtest: unable to load svelte. %w", err)
+	}
+	transforms, err := transformrt.Load(svelte.NewTransformable(svelteCompiler))
+	if err != nil {
+		return nil, fmt.Errorf("budtest: unable to load transforms. %w", err)
+	}
+	fsys := budfs.New(module, log)
+	fsys.FileGenerator("bud/view/_ssr.js", ssr.New(module, transforms.SSR))
+	return viewrt.Static(fsys, log, vm, nil), nil
+}
@@ -0,0 +1,128 @@
+package budtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Response from the app. The Expect methods fail the test when the response
+// doesn't match and return the response, so they can be chained.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+	t      testing.TB
+	req    *http.Request
+}
+
+// fail the test, showing the response
+func (r *Response) fail(format string, args ...interface{}) {
+	r.t.Helper()
+	body := string(r.Body)
+	if len(body) > 2000 {
+		body = body[:2000] + "..."
+	}
+	args = append(args, r.req.Method, r.req.URL.Path, r.Status, body)
+	r.t.Fatalf(format+"\n\n%s %s responded with %d:\n%s", args...)
+}
+
+// ExpectStatus checks the status code
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.Status != status {
+		r.fail("budtest: expected status %d, got %d", status, r.Status)
+	}
+	return r
+}
+
+// ExpectHeader checks a header's value
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if actual := r.Header.Get(key); actual != value {
+		r.fail("budtest: expected the %s header to be %q, got %q", key, value, actual)
+	}
+	return r
+}
+
+// ExpectRedirect checks that the response redirects to location
+func (r *Response) ExpectRedirect(location string) *Response {
+	r.t.Helper()
+	if r.Status < 300 || r.Status > 399 {
+		r.fail("budtest: expected a redirect to %s, got status %d", location, r.Status)
+	}
+	return r.ExpectHeader("Location", location)
+}
+
+// ExpectContains checks that the body contains the text
+func (r *Response) ExpectContains(text string) *Response {
+	r.t.Helper()
+	if !bytes.Contains(r.Body, []byte(text)) {
+		r.fail("budtest: expected the body to contain %q", text)
+	}
+	return r
+}
+
+// ExpectJSON checks that the body is the same JSON as expected. Whitespace
+// and the order of keys don't matter.
+func (r *Response) ExpectJSON(expected string) *Response {
+	r.t.Helper()
+	var want, got interface{}
+	if err := json.Unmarshal([]byte(expected), &want); err != nil {
+		r.t.Fatalf("budtest: invalid expected JSON. %s", err)
+	}
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.fail("budtest: expected a JSON body. %s", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		r.fail("budtest: expected the JSON body to be %s", expected)
+	}
+	return r
+}
+
+// DecodeJSON decodes the body into v
+func (r *Response) DecodeJSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.fail("budtest: unable to decode the JSON body. %s", err)
+	}
+	return r
+}
+
+// Find the HTML elements that match the CSS selector
+func (r *Response) Find(selector string) *goquery.Selection {
+	r.t.Helper()
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+	if err != nil {
+		r.fail("budtest: unable to parse the HTML body. %s", err)
+	}
+	return doc.Find(selector)
+}
+
+// ExpectText checks that the first element that matches the selector has the
+// text, ignoring surrounding whitespace
+func (r *Response) ExpectText(selector, text string) *Response {
+	r.t.Helper()
+	selection := r.Find(selector)
+	if selection.Length() == 0 {
+		r.fail("budtest: expected an element matching %q", selector)
+	}
+	if actual := strings.TrimSpace(selection.First().Text()); actual != text {
+		r.fail("budtest: expected %q to have the text %q, got %q", selector, text, actual)
+	}
+	return r
+}
+
+// ExpectCount checks the number of elements that match the selector
+func (r *Response) ExpectCount(selector string, count int) *Response {
+	r.t.Helper()
+	if actual := r.Find(selector).Length(); actual != count {
+		r.fail("budtest: expected %d elements matching %q, got %d", count, selector, actual)
+	}
+	return r
+}
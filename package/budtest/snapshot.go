@@ -0,0 +1,61 @@
+package budtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/livebud/bud/package/golden"
+	"github.com/matthewmueller/diff"
+)
+
+// hashedAsset matches the hash in asset names like "chunk-5GQ2XLVE.js" or
+// "app.3f2a1b9c.css"
+var hashedAsset = regexp.MustCompile(`([-.])(?:[A-Z2-7]{8}|[0-9a-f]{8,64})(\.(?:js|mjs|css|map|png|jpg|jpeg|gif|svg|webp|woff2?))\b`)
+
+// Normalize the HTML for a snapshot. Hashes in asset names are replaced, so
+// snapshots don't change whenever an asset does.
+func Normalize(html []byte) []byte {
+	html = bytes.ReplaceAll(html, []byte("\r\n"), []byte("\n"))
+	html = hashedAsset.ReplaceAll(html, []byte("${1}[hash]${2}"))
+	return append(bytes.TrimRight(html, " \t\n"), '\n')
+}
+
+// ExpectSnapshot checks the normalized body against the snapshot in
+// testdata/snapshots/<name>.html. Run the tests with -update to create or
+// update snapshots, like golden files.
+func (r *Response) ExpectSnapshot(name string) *Response {
+	r.t.Helper()
+	filename := filepath.Join("testdata", "snapshots", name+".html")
+	actual := Normalize(r.Body)
+	expected, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.t.Fatalf("budtest: unable to read the snapshot %s. %s", filename, err)
+		}
+		if !golden.Update() {
+			r.fail("budtest: missing the snapshot %s. Run the tests with -update to create it", filename)
+		}
+	}
+	if bytes.Equal(expected, actual) {
+		return r
+	}
+	if golden.Update() {
+		if err := writeFile(filename, actual); err != nil {
+			r.t.Fatalf("budtest: unable to write the snapshot %s. %s", filename, err)
+		}
+		r.t.Logf("budtest: updated the snapshot %s", filename)
+		return r
+	}
+	r.t.Fatalf("budtest: %s has unexpected changes. Run the tests with -update if they're expected.\n%s",
+		filename, diff.String(string(expected), string(actual)))
+	return r
+}
+
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
@@ -0,0 +1,71 @@
+// Package clock tells the time. Depend on a Clock instead of calling time.Now,
+// so tests can freeze time.
+//
+//	type Controller struct {
+//		Clock clock.Clock
+//	}
+//
+// Clocks are injected like any other dependency. In tests, pass in a frozen
+// clock instead:
+//
+//	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+//	controller := &Controller{Clock: frozen}
+//	frozen.Advance(time.Hour)
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time
+type Clock interface {
+	Now() time.Time
+}
+
+// Load the system clock
+func Load() Clock {
+	return system{}
+}
+
+type system struct{}
+
+func (system) Now() time.Time {
+	return time.Now()
+}
+
+// Freeze returns a clock that's stopped at t. Its time only changes when it's
+// set or advanced.
+func Freeze(t time.Time) *Frozen {
+	return &Frozen{now: t}
+}
+
+// Frozen clock for tests. It's safe for concurrent use.
+type Frozen struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+var _ Clock = (*Frozen)(nil)
+
+// Now returns the frozen time
+func (f *Frozen) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Set the time
+func (f *Frozen) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = t
+}
+
+// Advance the time by d and return the new time
+func (f *Frozen) Advance(d time.Duration) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	return f.now
+}
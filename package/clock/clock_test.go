@@ -0,0 +1,28 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+)
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	before := time.Now()
+	now := clock.Load().Now()
+	is.True(!now.Before(before))
+}
+
+func TestFreeze(t *testing.T) {
+	is := is.New(t)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	frozen := clock.Freeze(start)
+	is.Equal(frozen.Now(), start)
+	is.Equal(frozen.Now(), start)
+	is.Equal(frozen.Advance(time.Hour), start.Add(time.Hour))
+	is.Equal(frozen.Now(), start.Add(time.Hour))
+	frozen.Set(start)
+	is.Equal(frozen.Now(), start)
+}
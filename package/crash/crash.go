@@ -0,0 +1,118 @@
+// Package crash reports panics and server errors to a crash reporter, like
+// Sentry. Reports carry the request, the stack of a panic, the release, the
+// build and the logs that led up to the crash, called breadcrumbs.
+//
+// Set $SENTRY_DSN to report crashes to Sentry or a Sentry-compatible service.
+// Without a reporter, panics are still recovered and logged.
+package crash
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/bud"
+)
+
+// Reporter sends crash reports somewhere
+type Reporter interface {
+	Report(ctx context.Context, event *Event) error
+}
+
+// Event is a crash report
+type Event struct {
+	ID          string
+	Time        time.Time
+	Level       string // "error" or "fatal" for panics
+	Message     string
+	Panic       bool
+	Stack       []Frame // Innermost first. Only panics have a stack.
+	Request     *Request
+	RequestID   string
+	Route       string
+	UserID      string
+	TenantID    string
+	Release     string
+	BuildID     string
+	Environment string
+	Breadcrumbs []Breadcrumb
+}
+
+// Request that crashed. Headers with credentials, like cookies, are removed.
+type Request struct {
+	Method  string
+	URL     string
+	Query   string
+	Headers map[string]string
+}
+
+// Frame of a stack
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// newID returns a random ID in the format Sentry uses
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// private headers aren't sent with reports
+var private = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"X-Api-Key":           true,
+}
+
+func newRequest(r *http.Request) *Request {
+	headers := map[string]string{}
+	for key, values := range r.Header {
+		if private[key] {
+			continue
+		}
+		headers[key] = strings.Join(values, ", ")
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return &Request{
+		Method:  r.Method,
+		URL:     scheme + "://" + r.Host + r.URL.Path,
+		Query:   r.URL.RawQuery,
+		Headers: headers,
+	}
+}
+
+// stack returns the frames of the caller, skipping the runtime's own frames
+func stack(skip int) (frames []Frame) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	iter := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := iter.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			frames = append(frames, Frame{frame.Function, frame.File, frame.Line})
+		}
+		if !more {
+			return frames
+		}
+	}
+}
+
+// Release returns the version of the app, from $SENTRY_RELEASE or the commit
+// the app was built from. It's empty when neither are available.
+func Release(getenv func(key string) string) string {
+	if release := getenv("SENTRY_RELEASE"); release != "" {
+		return release
+	}
+	return bud.Version().Commit
+}
@@ -0,0 +1,187 @@
+package crash
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/bud"
+	"github.com/livebud/bud/package/log/reqlog"
+)
+
+// NewDumper writes crash reports to dir when the app panics or exits with an
+// error, so postmortems don't depend on what the supervisor kept of stderr.
+// Nothing is written when dir is empty.
+func NewDumper(dir string, trail *Trail) *Dumper {
+	return &Dumper{
+		Dir:      dir,
+		Trail:    trail,
+		Now:      time.Now,
+		requests: map[*OpenRequest]struct{}{},
+	}
+}
+
+// Dumper keeps track of the open requests and writes them along with the
+// goroutines and recent logs when the app crashes
+type Dumper struct {
+	Dir   string
+	Trail *Trail // Optional
+	Now   func() time.Time
+
+	mu       sync.Mutex
+	requests map[*OpenRequest]struct{}
+}
+
+// Dump is a crash report
+type Dump struct {
+	Time       time.Time      `json:"time"`
+	Message    string         `json:"message"`
+	Panic      bool           `json:"panic"`
+	Stack      string         `json:"stack,omitempty"` // Stack of the panic
+	BuildID    string         `json:"build_id,omitempty"`
+	Commit     string         `json:"commit,omitempty"`
+	GoVersion  string         `json:"go_version"`
+	PID        int            `json:"pid"`
+	Requests   []*OpenRequest `json:"requests"` // Requests being served, oldest first
+	Logs       []*DumpLog     `json:"logs"`
+	Goroutines string         `json:"goroutines"`
+}
+
+// OpenRequest is a request that was being served when the app crashed
+type OpenRequest struct {
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	RequestID string    `json:"request_id,omitempty"`
+	Start     time.Time `json:"start"`
+}
+
+// DumpLog is a log that was written before the crash
+type DumpLog struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Logger  string            `json:"logger,omitempty"`
+	Message string            `json:"msg"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// PanicError is a panic that was recovered from a goroutine, so the app can
+// write a crash report before it exits
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Recover turns a panic into a *PanicError. Defer it in goroutines that
+// return an error:
+//
+//	defer crash.Recover(&err)
+func Recover(err *error) {
+	if e := recover(); e != nil {
+		*err = &PanicError{e, debug.Stack()}
+	}
+}
+
+// open tracks the request until the returned function is called
+func (d *Dumper) open(r *http.Request) (close func()) {
+	req := &OpenRequest{
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		RequestID: reqlog.ID(r.Context()),
+		Start:     d.Now(),
+	}
+	d.mu.Lock()
+	d.requests[req] = struct{}{}
+	d.mu.Unlock()
+	return func() {
+		d.mu.Lock()
+		delete(d.requests, req)
+		d.mu.Unlock()
+	}
+}
+
+// Write a crash report for the error, unless the directory is empty. Panics
+// should be passed in as a *PanicError. It returns the path to the report.
+func (d *Dumper) Write(err error) (path string, _ error) {
+	if d.Dir == "" {
+		return "", nil
+	}
+	version := bud.Version()
+	dump := &Dump{
+		Time:       d.Now().UTC(),
+		Message:    err.Error(),
+		BuildID:    version.BuildID,
+		Commit:     version.Commit,
+		GoVersion:  runtime.Version(),
+		PID:        os.Getpid(),
+		Requests:   []*OpenRequest{},
+		Logs:       []*DumpLog{},
+		Goroutines: string(goroutines()),
+	}
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		dump.Panic = true
+		dump.Stack = string(panicErr.Stack)
+	}
+	d.mu.Lock()
+	for req := range d.requests {
+		dump.Requests = append(dump.Requests, req)
+	}
+	d.mu.Unlock()
+	sort.Slice(dump.Requests, func(i, j int) bool {
+		return dump.Requests[i].Start.Before(dump.Requests[j].Start)
+	})
+	if d.Trail != nil {
+		for _, crumb := range d.Trail.Breadcrumbs("") {
+			entry := &DumpLog{
+				Time:    crumb.Time,
+				Level:   crumb.Level.String(),
+				Logger:  crumb.Name,
+				Message: crumb.Message,
+			}
+			if len(crumb.Fields) > 0 {
+				entry.Fields = make(map[string]string, len(crumb.Fields))
+				for _, field := range crumb.Fields {
+					entry.Fields[field.Key] = field.Value
+				}
+			}
+			dump.Logs = append(dump.Logs, entry)
+		}
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("crash: unable to encode the crash report. %w", err)
+	}
+	if err := os.MkdirAll(d.Dir, 0755); err != nil {
+		return "", fmt.Errorf("crash: unable to create the crash report directory. %w", err)
+	}
+	path = filepath.Join(d.Dir, fmt.Sprintf("crash-%s-%d.json", dump.Time.Format("20060102T150405Z"), dump.PID))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("crash: unable to write the crash report. %w", err)
+	}
+	return path, nil
+}
+
+// goroutines returns the stacks of all the goroutines
+func goroutines() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		// Give up growing the buffer at 64MB
+		if n < len(buf) || len(buf) >= 64<<20 {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
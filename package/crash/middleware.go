@@ -0,0 +1,179 @@
+package crash
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/bud"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/tenant"
+)
+
+// Load the middleware, reporting to Sentry when $SENTRY_DSN is set. Open
+// requests are tracked for the dumper's crash reports.
+func Load(trail *Trail, dumper *Dumper, log log.Interface) (*Middleware, error) {
+	m := New(nil, trail, log)
+	m.Dumper = dumper
+	if dsn := os.Getenv("SENTRY_DSN"); dsn != "" {
+		sentry, err := Sentry(dsn)
+		if err != nil {
+			return nil, err
+		}
+		m.Reporter = sentry
+	}
+	m.Release = Release(os.Getenv)
+	m.BuildID = bud.Version().BuildID
+	m.Environment = os.Getenv("SENTRY_ENVIRONMENT")
+	return m, nil
+}
+
+// New crash middleware. The reporter may be nil.
+func New(reporter Reporter, trail *Trail, log log.Interface) *Middleware {
+	return &Middleware{
+		Reporter: reporter,
+		Trail:    trail,
+		Timeout:  10 * time.Second,
+		log:      log.Named("crash"),
+		inflight: make(chan struct{}, maxInflight),
+	}
+}
+
+// Middleware recovers from panics and reports them along with server errors
+type Middleware struct {
+	Reporter    Reporter
+	Trail       *Trail
+	Dumper      *Dumper // Optional
+	Release     string
+	BuildID     string
+	Environment string
+	Timeout     time.Duration
+
+	log      log.Interface
+	inflight chan struct{}
+}
+
+// maxInflight reports before new reports are dropped, so a flood of errors
+// doesn't pile up goroutines
+const maxInflight = 16
+
+// Middleware implements middleware.Middleware
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, route := router.Track(r.Context())
+		r = r.WithContext(ctx)
+		sw := &errorWriter{StatusWriter: middleware.RecordStatus(w)}
+		if m.Dumper != nil {
+			defer m.Dumper.open(r)()
+		}
+		defer func() {
+			e := recover()
+			if e == nil {
+				if sw.Status() >= 500 {
+					m.report(r, route(), &Event{
+						Level:   "error",
+						Message: serverError(r, sw),
+					})
+				}
+				return
+			}
+			// Let net/http handle aborted requests
+			if e == http.ErrAbortHandler {
+				panic(e)
+			}
+			message := fmt.Sprint(e)
+			if err, ok := e.(error); ok {
+				message = err.Error()
+			}
+			log.From(ctx).Error("crash: recovered from a panic", "error", message)
+			if !sw.Written() {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+			m.report(r, route(), &Event{
+				Level:   "fatal",
+				Message: message,
+				Panic:   true,
+				// Skip the deferred function and the runtime's panic
+				Stack: stack(2),
+			})
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// serverError describes a 5xx response, using the error in JSON responses
+func serverError(r *http.Request, sw *errorWriter) string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(sw.body, &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return fmt.Sprintf("%s %s responded with %d", r.Method, r.URL.Path, sw.Status())
+}
+
+// report the event in the background
+func (m *Middleware) report(r *http.Request, route string, event *Event) {
+	if m.Reporter == nil {
+		return
+	}
+	ctx := r.Context()
+	event.ID = newID()
+	event.Time = time.Now()
+	event.Request = newRequest(r)
+	event.Route = route
+	event.RequestID = reqlog.ID(ctx)
+	if userID, ok := auth.UserID(ctx); ok {
+		event.UserID = strconv.Itoa(userID)
+	}
+	event.TenantID, _ = tenant.From(ctx)
+	event.Release = m.Release
+	event.BuildID = m.BuildID
+	event.Environment = m.Environment
+	if m.Trail != nil {
+		event.Breadcrumbs = m.Trail.Breadcrumbs(event.RequestID)
+	}
+	select {
+	case m.inflight <- struct{}{}:
+	default:
+		m.log.Warn("crash: dropped a report. Too many reports are being sent", "event_id", event.ID)
+		return
+	}
+	go func() {
+		defer func() { <-m.inflight }()
+		ctx, cancel := context.WithTimeout(context.Background(), m.Timeout)
+		defer cancel()
+		if err := m.Reporter.Report(ctx, event); err != nil {
+			m.log.Warn("crash: unable to report", "event_id", event.ID, "error", err)
+		}
+	}()
+}
+
+// maxBody is how much of a server error's response is kept to find the error
+const maxBody = 4096
+
+// errorWriter keeps the start of server error responses
+type errorWriter struct {
+	*middleware.StatusWriter
+	body []byte
+}
+
+func (w *errorWriter) Write(p []byte) (int, error) {
+	n, err := w.StatusWriter.Write(p)
+	if w.Status() >= 500 && len(w.body) < maxBody {
+		keep := maxBody - len(w.body)
+		if keep > n {
+			keep = n
+		}
+		w.body = append(w.body, p[:keep]...)
+	}
+	return n, err
+}
@@ -0,0 +1,172 @@
+package crash
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Sentry reports crashes to Sentry. The DSN looks like
+// "https://key@o0.ingest.sentry.io/123".
+func Sentry(dsn string) (*SentryReporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("crash: invalid sentry DSN. %w", err)
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, fmt.Errorf("crash: sentry DSN is missing the key")
+	}
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		return nil, fmt.Errorf("crash: sentry DSN is missing the project ID")
+	}
+	secret, _ := u.User.Password()
+	return &SentryReporter{
+		Endpoint: u.Scheme + "://" + u.Host + path.Join(dir, "api", project, "store") + "/",
+		Key:      u.User.Username(),
+		Secret:   secret,
+		Client:   http.DefaultClient,
+	}, nil
+}
+
+// SentryReporter sends events to Sentry's store endpoint
+type SentryReporter struct {
+	Endpoint string
+	Key      string
+	Secret   string
+	Client   *http.Client
+}
+
+var _ Reporter = (*SentryReporter)(nil)
+
+// Report implements Reporter
+func (s *SentryReporter) Report(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(sentryEvent(event))
+	if err != nil {
+		return fmt.Errorf("crash: unable to encode the event. %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	auth := "Sentry sentry_version=7, sentry_client=bud, sentry_key=" + s.Key
+	if s.Secret != "" {
+		auth += ", sentry_secret=" + s.Secret
+	}
+	req.Header.Set("X-Sentry-Auth", auth)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("crash: sentry responded with %d", res.StatusCode)
+	}
+	return nil
+}
+
+// sentryEvent converts the event into Sentry's event payload
+func sentryEvent(event *Event) map[string]interface{} {
+	payload := map[string]interface{}{
+		"event_id":  event.ID,
+		"timestamp": event.Time.UTC().Format("2006-01-02T15:04:05.000Z"),
+		"level":     event.Level,
+		"platform":  "go",
+		"logger":    "bud",
+	}
+	if event.Release != "" {
+		payload["release"] = event.Release
+	}
+	if event.Environment != "" {
+		payload["environment"] = event.Environment
+	}
+	if event.Panic {
+		// Sentry wants the outermost frame first
+		frames := make([]map[string]interface{}, len(event.Stack))
+		for i, frame := range event.Stack {
+			frames[len(frames)-1-i] = map[string]interface{}{
+				"function": frame.Function,
+				"abs_path": frame.File,
+				"filename": path.Base(frame.File),
+				"lineno":   frame.Line,
+			}
+		}
+		payload["exception"] = map[string]interface{}{
+			"values": []interface{}{map[string]interface{}{
+				"type":       "panic",
+				"value":      event.Message,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		}
+	} else {
+		payload["message"] = event.Message
+	}
+	if event.Request != nil {
+		payload["request"] = map[string]interface{}{
+			"method":       event.Request.Method,
+			"url":          event.Request.URL,
+			"query_string": event.Request.Query,
+			"headers":      event.Request.Headers,
+		}
+	}
+	tags := map[string]string{}
+	if event.RequestID != "" {
+		tags["request_id"] = event.RequestID
+	}
+	if event.Route != "" {
+		tags["route"] = event.Route
+	}
+	if event.TenantID != "" {
+		tags["tenant_id"] = event.TenantID
+	}
+	if event.BuildID != "" {
+		tags["build_id"] = event.BuildID
+	}
+	payload["tags"] = tags
+	if event.UserID != "" {
+		payload["user"] = map[string]string{"id": event.UserID}
+	}
+	crumbs := make([]map[string]interface{}, len(event.Breadcrumbs))
+	for i, crumb := range event.Breadcrumbs {
+		data := map[string]string{}
+		for _, field := range crumb.Fields {
+			data[field.Key] = field.Value
+		}
+		category := crumb.Name
+		if category == "" {
+			category = "log"
+		}
+		crumbs[i] = map[string]interface{}{
+			"timestamp": float64(crumb.Time.UnixNano()) / 1e9,
+			"category":  category,
+			"level":     sentryLevel(crumb.Level),
+			"message":   crumb.Message,
+			"data":      data,
+		}
+	}
+	payload["breadcrumbs"] = map[string]interface{}{"values": crumbs}
+	return payload
+}
+
+func sentryLevel(level log.Level) string {
+	switch level {
+	case log.DebugLevel:
+		return "debug"
+	case log.WarnLevel:
+		return "warning"
+	case log.ErrorLevel:
+		return "error"
+	default:
+		return "info"
+	}
+}
@@ -0,0 +1,81 @@
+package crash
+
+import (
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Breadcrumb is a log that was written before a crash
+type Breadcrumb struct {
+	Time    time.Time
+	Level   log.Level
+	Name    string
+	Message string
+	Fields  []log.Field
+}
+
+// NewTrail keeps the last size logs as breadcrumbs before passing them to the
+// handler. Logs are kept whatever their level, so debug logs show up in
+// reports even when they aren't written.
+func NewTrail(handler log.Handler, size int) *Trail {
+	return &Trail{
+		Handler: handler,
+		Now:     time.Now,
+		crumbs:  make([]Breadcrumb, 0, size),
+		size:    size,
+	}
+}
+
+// Trail of breadcrumbs
+type Trail struct {
+	Handler log.Handler
+	Now     func() time.Time
+
+	mu     sync.Mutex
+	crumbs []Breadcrumb
+	size   int
+	next   int
+}
+
+var _ log.Handler = (*Trail)(nil)
+
+// Log implements log.Handler
+func (t *Trail) Log(entry log.Entry) {
+	crumb := Breadcrumb{t.Now(), entry.Level, entry.Name, entry.Message, entry.Fields}
+	t.mu.Lock()
+	if len(t.crumbs) < t.size {
+		t.crumbs = append(t.crumbs, crumb)
+	} else if t.size > 0 {
+		t.crumbs[t.next] = crumb
+		t.next = (t.next + 1) % t.size
+	}
+	t.mu.Unlock()
+	t.Handler.Log(entry)
+}
+
+// Breadcrumbs returns the logs from oldest to newest. With a request ID, only
+// the request's logs are returned.
+func (t *Trail) Breadcrumbs(requestID string) []Breadcrumb {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	crumbs := make([]Breadcrumb, 0, len(t.crumbs))
+	for i := 0; i < len(t.crumbs); i++ {
+		crumb := t.crumbs[(t.next+i)%len(t.crumbs)]
+		if requestID != "" && field(crumb.Fields, "request_id") != requestID {
+			continue
+		}
+		crumbs = append(crumbs, crumb)
+	}
+	return crumbs
+}
+
+func field(fields []log.Field, key string) string {
+	for _, field := range fields {
+		if field.Key == key {
+			return field.Value
+		}
+	}
+	return ""
+}
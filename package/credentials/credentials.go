@@ -0,0 +1,154 @@
+// Package credentials keeps the app's secrets, like API keys, in an encrypted
+// YAML file that's checked into the repository:
+//
+//	config/credentials.yml.enc
+//
+// The file is encrypted with a key derived from the app's master key, which is
+// in $MASTER_KEY or config/master.key and stays out of the repository. Edit the
+// file with bud credentials edit, then depend on the credentials in your app:
+//
+//	type Controller struct {
+//	  Credentials *credentials.Credentials
+//	}
+//
+//	secretKey := c.Credentials.Get("stripe.secret_key")
+package credentials
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/livebud/bud/package/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Path to the encrypted credentials, relative to the app
+const Path = "config/credentials.yml.enc"
+
+// Template for new credentials files
+const Template = `# Credentials for your app, encrypted with the master key in $MASTER_KEY or
+# config/master.key. Read them with credentials.Get("aws.access_key_id").
+#
+# aws:
+#   access_key_id: 123
+#   secret_access_key: 456
+`
+
+// Load the credentials in the working directory with the app's master key.
+// Apps without a credentials file have no credentials.
+func Load() (*Credentials, error) {
+	keys, err := secrets.Load()
+	if err != nil {
+		return nil, err
+	}
+	return Read(keys, Path)
+}
+
+// Read the credentials at path. Missing files have no credentials.
+func Read(keys *secrets.Keys, path string) (*Credentials, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &Credentials{}, nil
+		}
+		return nil, fmt.Errorf("credentials: unable to read %s. %w", path, err)
+	}
+	plain, err := Decrypt(keys, data)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(plain)
+}
+
+// Encrypt the YAML into the contents of a credentials file. Invalid YAML isn't
+// encrypted.
+func Encrypt(keys *secrets.Keys, plain []byte) ([]byte, error) {
+	if _, err := Parse(plain); err != nil {
+		return nil, err
+	}
+	sealed, err := keys.Derive("credentials").Encrypt(plain, nil)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sealed) + "\n"), nil
+}
+
+// Decrypt the contents of a credentials file into YAML
+func Decrypt(keys *secrets.Keys, data []byte) ([]byte, error) {
+	sealed, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return nil, fmt.Errorf("credentials: unable to decode. %w", secrets.ErrInvalid)
+	}
+	plain, err := keys.Derive("credentials").Decrypt(sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("credentials: unable to decrypt with the master key. %w", err)
+	}
+	return plain, nil
+}
+
+// Parse the decrypted YAML
+func Parse(plain []byte) (*Credentials, error) {
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(plain, &values); err != nil {
+		return nil, fmt.Errorf("credentials: unable to parse. %w", err)
+	}
+	return &Credentials{values}, nil
+}
+
+// Credentials are the app's decrypted secrets
+type Credentials struct {
+	values map[string]interface{}
+}
+
+// Lookup the value at a dotted path like "aws.access_key_id"
+func (c *Credentials) Lookup(path string) (interface{}, bool) {
+	var value interface{} = c.values
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// Get the value at a dotted path like "aws.access_key_id" as a string. Missing
+// values and objects are empty.
+func (c *Credentials) Get(path string) string {
+	value, ok := c.Lookup(path)
+	if !ok || value == nil {
+		return ""
+	}
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// Decode the value at a dotted path into v, which is a pointer to a struct
+// with yaml tags, a map or a slice. An empty path decodes all the credentials.
+func (c *Credentials) Decode(path string, v interface{}) error {
+	var value interface{} = c.values
+	if path != "" {
+		var ok bool
+		if value, ok = c.Lookup(path); !ok {
+			return fmt.Errorf("credentials: unable to decode %q. %w", path, os.ErrNotExist)
+		}
+	}
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("credentials: unable to decode %q. %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("credentials: unable to decode %q. %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package credentials_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/credentials"
+	"github.com/livebud/bud/package/secrets"
+)
+
+const plain = `
+aws:
+  access_key_id: abc
+  secret_access_key: "123"
+stripe:
+  secret_key: sk_test
+port: 5432
+hosts:
+  - a.example.com
+  - b.example.com
+`
+
+func TestEncryptDecrypt(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("master")
+	is.NoErr(err)
+	data, err := credentials.Encrypt(keys, []byte(plain))
+	is.NoErr(err)
+	is.True(len(data) > 0)
+	decrypted, err := credentials.Decrypt(keys, data)
+	is.NoErr(err)
+	is.Equal(string(decrypted), plain)
+	// Other keys can't decrypt the credentials
+	other, err := secrets.New("other")
+	is.NoErr(err)
+	_, err = credentials.Decrypt(other, data)
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	// Previous keys can
+	rotated, err := secrets.New("new", "master")
+	is.NoErr(err)
+	decrypted, err = credentials.Decrypt(rotated, data)
+	is.NoErr(err)
+	is.Equal(string(decrypted), plain)
+	// Invalid YAML isn't encrypted
+	_, err = credentials.Encrypt(keys, []byte("a: [b"))
+	is.True(err != nil)
+	is.In(err.Error(), "credentials: unable to parse")
+}
+
+func TestGet(t *testing.T) {
+	is := is.New(t)
+	creds, err := credentials.Parse([]byte(plain))
+	is.NoErr(err)
+	is.Equal(creds.Get("aws.access_key_id"), "abc")
+	is.Equal(creds.Get("aws.secret_access_key"), "123")
+	is.Equal(creds.Get("port"), "5432")
+	is.Equal(creds.Get("aws"), "")
+	is.Equal(creds.Get("aws.missing"), "")
+	is.Equal(creds.Get("stripe.secret_key.nested"), "")
+	value, ok := creds.Lookup("hosts")
+	is.True(ok)
+	is.Equal(len(value.([]interface{})), 2)
+	_, ok = creds.Lookup("missing")
+	is.True(!ok)
+}
+
+func TestDecode(t *testing.T) {
+	is := is.New(t)
+	creds, err := credentials.Parse([]byte(plain))
+	is.NoErr(err)
+	var aws struct {
+		AccessKeyID     string `yaml:"access_key_id"`
+		SecretAccessKey string `yaml:"secret_access_key"`
+	}
+	is.NoErr(creds.Decode("aws", &aws))
+	is.Equal(aws.AccessKeyID, "abc")
+	is.Equal(aws.SecretAccessKey, "123")
+	var all struct {
+		Port  int      `yaml:"port"`
+		Hosts []string `yaml:"hosts"`
+	}
+	is.NoErr(creds.Decode("", &all))
+	is.Equal(all.Port, 5432)
+	is.Equal(all.Hosts, []string{"a.example.com", "b.example.com"})
+	err = creds.Decode("missing", &all)
+	is.True(errors.Is(err, os.ErrNotExist))
+}
+
+func TestRead(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("master")
+	is.NoErr(err)
+	dir := t.TempDir()
+	path := filepath.Join(dir, credentials.Path)
+	// Missing files have no credentials
+	creds, err := credentials.Read(keys, path)
+	is.NoErr(err)
+	is.Equal(creds.Get("aws.access_key_id"), "")
+	data, err := credentials.Encrypt(keys, []byte(plain))
+	is.NoErr(err)
+	is.NoErr(os.MkdirAll(filepath.Dir(path), 0755))
+	is.NoErr(os.WriteFile(path, data, 0644))
+	creds, err = credentials.Read(keys, path)
+	is.NoErr(err)
+	is.Equal(creds.Get("stripe.secret_key"), "sk_test")
+	// The template has no credentials
+	creds, err = credentials.Parse([]byte(credentials.Template))
+	is.NoErr(err)
+	is.Equal(creds.Get("aws.access_key_id"), "")
+}
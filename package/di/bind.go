@@ -0,0 +1,91 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+
+	"github.com/livebud/bud/package/parser"
+)
+
+// BindDir is the directory where apps bind interfaces to the implementations
+// that the injector provides for them. Bindings are interface assertions:
+//
+//	package inject
+//
+//	var _ mail.Sender = (*smtp.Client)(nil)
+//
+// Bindings in a subdirectory named after the environment, like inject/test,
+// override the others in that environment.
+const BindDir = "inject"
+
+// Environment sets the environment, which picks the bindings that override
+// the app's other bindings
+func (i *Injector) Environment(environment string) {
+	i.environment = environment
+}
+
+// loadBindings loads the app's bindings. The function's aliases take
+// precedence over the bindings.
+func (i *Injector) loadBindings() (map[string]Dependency, error) {
+	bindings, err := i.readBindings(BindDir)
+	if err != nil {
+		return nil, err
+	}
+	if i.environment == "" {
+		return bindings, nil
+	}
+	overrides, err := i.readBindings(path.Join(BindDir, i.environment))
+	if err != nil {
+		return nil, err
+	}
+	for id, to := range overrides {
+		bindings[id] = to
+	}
+	return bindings, nil
+}
+
+// readBindings reads the bindings in dir. Missing directories have no
+// bindings.
+func (i *Injector) readBindings(dir string) (map[string]Dependency, error) {
+	bindings := map[string]Dependency{}
+	if _, err := fs.Stat(i.fsys, dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return bindings, nil
+		}
+		return nil, fmt.Errorf("di: unable to read bindings in %q. %w", dir, err)
+	}
+	pkg, err := i.parser.Parse(dir)
+	if err != nil {
+		return nil, fmt.Errorf("di: unable to read bindings in %q. %w", dir, err)
+	}
+	for _, assertion := range pkg.Assertions() {
+		from, err := bindingType(assertion.Type())
+		if err != nil {
+			return nil, fmt.Errorf("di: unable to bind in %q. %w", assertion.File().Path(), err)
+		}
+		impl := assertion.Implementation()
+		if impl == nil {
+			return nil, fmt.Errorf("di: unable to bind %s in %q. Expected a binding like var _ %s = (*Type)(nil)", from.ID(), assertion.File().Path(), assertion.Type())
+		}
+		to, err := bindingType(impl)
+		if err != nil {
+			return nil, fmt.Errorf("di: unable to bind %s in %q. %w", from.ID(), assertion.File().Path(), err)
+		}
+		if prev, ok := bindings[from.ID()]; ok && prev.ID() != to.ID() {
+			return nil, fmt.Errorf("di: %s is bound to both %s and %s in %q", from.ID(), prev.ID(), to.ID(), dir)
+		}
+		i.log.Debug("di: bound interface", "from", from.ID(), "to", to.ID())
+		bindings[from.ID()] = to
+	}
+	return bindings, nil
+}
+
+func bindingType(t parser.Type) (*Type, error) {
+	importPath, err := parser.ImportPath(t)
+	if err != nil {
+		return nil, err
+	}
+	return ToType(importPath, parser.Unqualify(t).String()), nil
+}
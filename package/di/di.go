@@ -20,6 +20,13 @@ type Generator interface {
 	Identifier(importPath, name string) string
 	Variable(importPath, name string) string
 	MarkError(hasError bool)
+	// Closers are the variables created so far that are closed by the cleanup
+	Closers() []string
+	// ReturnError returns the code to return an error from the generated
+	// function, closing the dependencies created so far
+	ReturnError(err string) string
+	// Hooks are the variables created so far that have lifecycle hooks
+	Hooks() []string
 }
 
 type Variable struct {
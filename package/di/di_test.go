@@ -41,9 +41,10 @@ func goRun(ctx context.Context, cacheDir, appDir string) (string, error) {
 }
 
 type Test struct {
-	Function *di.Function
-	Files    map[string]string
-	Expect   string
+	Function    *di.Function
+	Files       map[string]string
+	Expect      string
+	Environment string
 }
 
 func runTest(t testing.TB, test Test) {
@@ -67,6 +68,7 @@ func runTest(t testing.TB, test Test) {
 	is.NoErr(err)
 	parser := parser.New(appFS, module)
 	injector := di.New(appFS, log, module, parser)
+	injector.Environment(test.Environment)
 	node, err := injector.Load(test.Function)
 	if err != nil {
 		is.Equal(test.Expect, err.Error())
@@ -2338,3 +2340,592 @@ func TestHoistable(t *testing.T) {
 // IDEA: consider renaming Target to Import
 // IDEA: consider moving Hoist outside of Function
 // IDEA: consider transitioning to a builder pattern input
+
+const bindingJS = `
+	package js
+
+	type VM interface {
+		Eval(input string) (string, error)
+	}
+`
+
+const bindingWeb = `
+	package web
+
+	import (
+		"app.com/js"
+	)
+
+	type Web struct {
+		VM js.VM
+	}
+`
+
+const bindingV8 = `
+	package v8
+
+	type V8 struct {}
+
+	func (v *V8) Eval(input string) (string, error) {
+		return "", nil
+	}
+`
+
+const bindingGoja = `
+	package goja
+
+	type Goja struct {}
+
+	func (g *Goja) Eval(input string) (string, error) {
+		return "", nil
+	}
+`
+
+func TestBinding(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `
+			&web.Web{VM: &v8.V8{}}
+		`,
+		Files: map[string]string{
+			"go.mod":      goMod,
+			"main.go":     mainGo,
+			"web/web.go":  bindingWeb,
+			"js/js.go":    bindingJS,
+			"js/v8/v8.go": bindingV8,
+			"inject/inject.go": `
+				package inject
+
+				import (
+					"app.com/js"
+					v8 "app.com/js/v8"
+				)
+
+				var _ js.VM = (*v8.V8)(nil)
+			`,
+		},
+	})
+}
+
+func TestBindingEnvironment(t *testing.T) {
+	runTest(t, Test{
+		Environment: "test",
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `
+			&web.Web{VM: &goja.Goja{}}
+		`,
+		Files: map[string]string{
+			"go.mod":          goMod,
+			"main.go":         mainGo,
+			"web/web.go":      bindingWeb,
+			"js/js.go":        bindingJS,
+			"js/v8/v8.go":     bindingV8,
+			"js/goja/goja.go": bindingGoja,
+			"inject/inject.go": `
+				package inject
+
+				import (
+					"app.com/js"
+					v8 "app.com/js/v8"
+				)
+
+				var _ js.VM = (*v8.V8)(nil)
+			`,
+			"inject/test/test.go": `
+				package test
+
+				import (
+					"app.com/js"
+					"app.com/js/goja"
+				)
+
+				var _ js.VM = &goja.Goja{}
+			`,
+		},
+	})
+}
+
+func TestBindingAliasOverride(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+			Aliases: di.Aliases{
+				di.ToType("app.com/js", "VM"): di.ToType("app.com/js/goja", "*Goja"),
+			},
+		},
+		Expect: `
+			&web.Web{VM: &goja.Goja{}}
+		`,
+		Files: map[string]string{
+			"go.mod":          goMod,
+			"main.go":         mainGo,
+			"web/web.go":      bindingWeb,
+			"js/js.go":        bindingJS,
+			"js/v8/v8.go":     bindingV8,
+			"js/goja/goja.go": bindingGoja,
+			"inject/inject.go": `
+				package inject
+
+				import (
+					"app.com/js"
+					v8 "app.com/js/v8"
+				)
+
+				var _ js.VM = (*v8.V8)(nil)
+			`,
+		},
+	})
+}
+
+func TestBindingConflict(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `di: "app.com/js".VM is bound to both "app.com/js/v8".*V8 and "app.com/js/goja".*Goja in "inject"`,
+		Files: map[string]string{
+			"go.mod":          goMod,
+			"main.go":         mainGo,
+			"web/web.go":      bindingWeb,
+			"js/js.go":        bindingJS,
+			"js/v8/v8.go":     bindingV8,
+			"js/goja/goja.go": bindingGoja,
+			"inject/inject.go": `
+				package inject
+
+				import (
+					"app.com/js"
+					"app.com/js/goja"
+					v8 "app.com/js/v8"
+				)
+
+				var _ js.VM = (*v8.V8)(nil)
+				var _ js.VM = (*goja.Goja)(nil)
+			`,
+		},
+	})
+}
+
+func TestScopes(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Hoist:  true,
+			Target: "app.com/gen/web",
+			Params: []*di.Param{
+				{Import: "app.com/web", Type: "*Request"},
+			},
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+				&di.Cleanup{},
+				&di.Error{},
+			},
+		},
+		Expect: `
+			pools=1 ids=3 closed=0 sameTx=true
+			pools=1 ids=7 closed=1 sameTx=true
+			closed=2
+		`,
+		Files: map[string]string{
+			"go.mod": goMod,
+			"main.go": `
+				package main
+
+				import (
+					"fmt"
+					web "app.com/web"
+					genweb "app.com/gen/web"
+				)
+
+				func main() {
+					pool := web.NewPool()
+					for i := 0; i < 2; i++ {
+						w, cleanup, err := genweb.Load(pool)
+						if err != nil {
+							panic(err)
+						}
+						fmt.Printf("pools=%d ids=%d closed=%d sameTx=%t\n", web.Pools, w.Repo.ID.N+w.ID.N, web.Closed, w.Tx == w.Repo.Tx)
+						cleanup()
+					}
+					fmt.Printf("closed=%d\n", web.Closed)
+				}
+			`,
+			"web/web.go": `
+				package web
+
+				var Pools, Closed, IDs int
+
+				type Request struct {}
+
+				type Pool struct {}
+
+				func NewPool() *Pool {
+					Pools++
+					return &Pool{}
+				}
+
+				// Tx is created for each request
+				//
+				//di:scope request
+				type Tx struct {
+					Pool *Pool
+				}
+
+				func (t *Tx) Close() error {
+					Closed++
+					return nil
+				}
+
+				// NewID creates a new ID each time
+				//
+				//di:scope transient
+				func NewID() *ID {
+					IDs++
+					return &ID{IDs}
+				}
+
+				type ID struct {
+					N int
+				}
+
+				type Repo struct {
+					Tx *Tx
+					ID *ID
+				}
+
+				type Web struct {
+					Tx   *Tx
+					Repo *Repo
+					ID   *ID
+				}
+			`,
+		},
+	})
+}
+
+func TestScopeCleanupOnError(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Hoist:  true,
+			Target: "app.com/gen/web",
+			Params: []*di.Param{
+				{Import: "app.com/web", Type: "*Request"},
+			},
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+				&di.Cleanup{},
+				&di.Error{},
+			},
+		},
+		Expect: `
+			unable to load session closed=1
+		`,
+		Files: map[string]string{
+			"go.mod": goMod,
+			"main.go": `
+				package main
+
+				import (
+					"fmt"
+					web "app.com/web"
+					genweb "app.com/gen/web"
+				)
+
+				func main() {
+					_, _, err := genweb.Load(&web.Request{})
+					fmt.Printf("%s closed=%d\n", err, web.Closed)
+				}
+			`,
+			"web/web.go": `
+				package web
+
+				import "errors"
+
+				var Closed int
+
+				type Request struct {}
+
+				//di:scope request
+				type Tx struct {}
+
+				func (t *Tx) Close() {
+					Closed++
+				}
+
+				type Session struct {}
+
+				func LoadSession(tx *Tx, r *Request) (*Session, error) {
+					return nil, errors.New("unable to load session")
+				}
+
+				type Web struct {
+					Tx      *Tx
+					Session *Session
+				}
+			`,
+		},
+	})
+}
+
+func TestScopeNoCleanup(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Hoist:  true,
+			Target: "app.com/gen/web",
+			Params: []*di.Param{
+				{Import: "app.com/web", Type: "*Request"},
+			},
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+				&di.Cleanup{},
+				&di.Error{},
+			},
+		},
+		Expect: `
+			request=true
+		`,
+		Files: map[string]string{
+			"go.mod": goMod,
+			"main.go": `
+				package main
+
+				import (
+					"fmt"
+					web "app.com/web"
+					genweb "app.com/gen/web"
+				)
+
+				func main() {
+					// Without anything to close, there's no cleanup function
+					actual, err := genweb.Load(&web.Request{})
+					if err != nil {
+						panic(err)
+					}
+					fmt.Printf("request=%t\n", actual.Request != nil)
+				}
+			`,
+			"web/web.go": `
+				package web
+
+				type Request struct {}
+
+				type Web struct {
+					Request *Request
+				}
+			`,
+		},
+	})
+}
+
+func TestScopeSingletonRequest(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Hoist:  true,
+			Target: "app.com/gen/web",
+			Params: []*di.Param{
+				{Import: "app.com/web", Type: "*Request"},
+			},
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `di: "app.com/web".*Session is a singleton, but it depends on the request`,
+		Files: map[string]string{
+			"go.mod":  goMod,
+			"main.go": mainGo,
+			"web/web.go": `
+				package web
+
+				type Request struct {}
+
+				//di:scope singleton
+				type Session struct {
+					Request *Request
+				}
+
+				type Web struct {
+					Session *Session
+				}
+			`,
+		},
+	})
+}
+
+func TestScopeUnknown(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `di: unknown scope "session". Expected singleton, request or transient in "app.com/web".Web`,
+		Files: map[string]string{
+			"go.mod":  goMod,
+			"main.go": mainGo,
+			"web/web.go": `
+				package web
+
+				//di:scope session
+				type Web struct {}
+			`,
+		},
+	})
+}
+
+func TestMissingInterface(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `di: unclear how to provide "app.com/js".VM
+  needed by param vm of "app.com/web".New
+VM is an interface. Bind it to an implementation in inject/, like:
+  var _ js.VM = (*goja.Goja)(nil)
+  var _ js.VM = (*v8.V8)(nil)`,
+		Files: map[string]string{
+			"go.mod":  goMod,
+			"main.go": mainGo,
+			"web/web.go": `
+				package web
+
+				import (
+					"app.com/js"
+				)
+
+				func New(vm js.VM) *Web {
+					return &Web{vm}
+				}
+
+				type Web struct {
+					vm js.VM
+				}
+			`,
+			"js/js.go":        bindingJS,
+			"js/v8/v8.go":     bindingV8,
+			"js/goja/goja.go": bindingGoja,
+		},
+	})
+}
+
+func TestMissingUnexportedField(t *testing.T) {
+	runTest(t, Test{
+		Function: &di.Function{
+			Name:   "Load",
+			Target: "app.com/gen/web",
+			Results: []di.Dependency{
+				di.ToType("app.com/web", "*Web"),
+			},
+		},
+		Expect: `di: unclear how to provide "app.com/log".*Logger
+  needed by field Log of "app.com/web".*Web
+Logger has the unexported field level, so it can't be created automatically.
+  Add a function to package log that returns it, like func Load() (*log.Logger, error)`,
+		Files: map[string]string{
+			"go.mod":  goMod,
+			"main.go": mainGo,
+			"web/web.go": `
+				package web
+
+				import (
+					"app.com/log"
+				)
+
+				type Web struct {
+					Log *log.Logger
+				}
+			`,
+			"log/log.go": `
+				package log
+
+				type Logger struct {
+					level string
+				}
+			`,
+		},
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	is := is.New(t)
+	log := testlog.New()
+	appDir := t.TempDir()
+	err := vfs.Write(appDir, vfs.Map{
+		"go.mod": []byte(goMod),
+		"web/web.go": []byte(redent(`
+			package web
+
+			import "context"
+
+			type Pool struct {}
+
+			func (p *Pool) Start(ctx context.Context) error { return nil }
+			func (p *Pool) Stop(ctx context.Context) error { return nil }
+
+			type Cache struct {}
+
+			func LoadWorker(pool *Pool, cache *Cache) *Worker {
+				return &Worker{pool}
+			}
+
+			type Worker struct {
+				pool *Pool
+			}
+
+			func (w *Worker) Stop(ctx context.Context) error { return nil }
+
+			type Web struct {
+				Worker *Worker
+			}
+		`)),
+	})
+	is.NoErr(err)
+	appFS := os.DirFS(appDir)
+	module, err := gomod.Find(appDir)
+	is.NoErr(err)
+	injector := di.New(appFS, log, module, parser.New(appFS, module))
+	fn := &di.Function{
+		Name:   "Load",
+		Target: "app.com/gen/web",
+		Results: []di.Dependency{
+			di.ToType("app.com/web", "*Web"),
+			&di.Lifecycle{},
+			&di.Error{},
+		},
+	}
+	node, err := injector.Load(fn)
+	is.NoErr(err)
+	provider := node.Generate(imports.New(), fn.Name, fn.Target)
+	code := provider.Function()
+	// Services are started in the order they're created and stopped in reverse
+	is.True(strings.Contains(code, "lifecycleHooks := lifecycle.New(webPool, webWorker)\n"))
+	is.True(strings.Contains(code, "func Load() (*web.Web, *lifecycle.Hooks, error) {\n"))
+}
@@ -75,6 +75,5 @@ func (i *Injector) Find(currModule *gomod.Module, dep Dependency) (Declaration,
 		i.log.Debug("di: found struct declaration", "id", decl.ID(), "for", dep.ID())
 		return decl, nil
 	}
-	// TODO: add breadcrumbs to help with finding the root of this error
-	return nil, fmt.Errorf("di: unclear how to provide %s", dep.ID())
+	return nil, i.missing(pkg, dep)
 }
@@ -135,6 +135,7 @@ func tryFunction(fn *parser.Function, importPath, dataType string) (*function, e
 			Type:   parser.Unqualify(pt).String(),
 			kind:   def.Kind(),
 			module: module,
+			name:   param.Name(),
 		})
 	}
 	for _, result := range results {
@@ -152,6 +153,18 @@ func tryFunction(fn *parser.Function, importPath, dataType string) (*function, e
 			return nil, fmt.Errorf("di: unable to find definition for result %q.%s in %q.%s . %w", imPath, parser.Unqualify(rt).String(), importPath, dataType, err)
 		}
 		unqualified := parser.Unqualify(rt)
+		if len(function.Results) == 0 {
+			// The function's directive takes precedence over the type's
+			var typeDirectives []string
+			if stct, ok := def.(*parser.Struct); ok {
+				typeDirectives = stct.Directives()
+			}
+			if function.scope, err = scopeOf(fn.Directives(), typeDirectives); err != nil {
+				return nil, fmt.Errorf("%w in %q.%s", err, fileImportPath, fn.Name())
+			}
+			function.closes = hasClose(def)
+			function.hooks = hasHooks(def)
+		}
 		function.Results = append(function.Results, &Type{
 			Import: importPath,
 			Type:   unqualified.String(),
@@ -169,14 +182,32 @@ type function struct {
 	Name    string
 	Params  []*Type
 	Results []*Type
+
+	scope  Scope
+	closes bool
+	hooks  bool
 }
 
 var _ Declaration = (*function)(nil)
+var _ scoped = (*function)(nil)
+var _ hooked = (*function)(nil)
 
 func (fn *function) ID() string {
 	return `"` + fn.Import + `".` + fn.Name
 }
 
+func (fn *function) Scope() Scope {
+	return fn.scope
+}
+
+func (fn *function) Closes() bool {
+	return fn.closes
+}
+
+func (fn *function) Hooks() bool {
+	return fn.hooks
+}
+
 // Dependencies are the values that the funcDecl depends on to run
 func (fn *function) Dependencies() (deps []Dependency) {
 	for _, param := range fn.Params {
@@ -218,7 +249,7 @@ func (fn *function) Generate(gen Generator, inputs []*Variable) (outputs []*Vari
 		// Mark the code as having an error
 		gen.MarkError(true)
 		errvar := outputs[len(outputs)-1]
-		gen.WriteString(fmt.Sprintf("if %[1]s != nil {\n\treturn nil, %[1]s\n}\n", errvar.Name))
+		gen.WriteString(fmt.Sprintf("if %s != nil {\n%s}\n", errvar.Name, gen.ReturnError(errvar.Name)))
 	}
 	return outputs
 }
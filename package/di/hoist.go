@@ -24,6 +24,11 @@ func hoist(node *Node) (shouldHoist bool) {
 	if node.External && !node.Hoist {
 		return false
 	}
+	// Request and transient dependencies are created on each call, along with
+	// the dependencies that rely on them.
+	if node.Scope == Request || node.Scope == Transient {
+		shouldHoist = false
+	}
 	// Loop over the inputs. If any input is non-hoistable, this node is becomes
 	// non-hoistable. Order of the conditional matters here. We intentionally call
 	// hoist(dep) before shouldHoist because we don't want the algorithm skipping
@@ -1,6 +1,7 @@
 package di
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 
@@ -28,6 +29,8 @@ type Injector struct {
 	module *gomod.Module
 	// Go parser
 	parser *parser.Parser
+	// Environment that picks the bindings to use
+	environment string
 }
 
 // Load the dependency graph, but don't generate any code. Load is intentionally
@@ -38,8 +41,11 @@ func (i *Injector) Load(fn *Function) (*Node, error) {
 	if err := fn.Validate(); err != nil {
 		return nil, err
 	}
-	// Setup the aliases
-	aliases := map[string]Dependency{}
+	// Setup the aliases, starting from the app's bindings
+	aliases, err := i.loadBindings()
+	if err != nil {
+		return nil, err
+	}
 	for from, to := range fn.Aliases {
 		aliases[from.ID()] = to
 	}
@@ -64,6 +70,11 @@ func (i *Injector) Load(fn *Function) (*Node, error) {
 	}
 	if fn.Hoist {
 		root = Hoist(root)
+		for _, result := range root.Dependencies {
+			if err := checkScopes(result); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return root, nil
 }
@@ -98,13 +109,21 @@ func (i *Injector) load(externals map[string]*Param, aliases map[string]Dependen
 		Type:        typeName,
 		Declaration: decl,
 	}
+	if decl, ok := decl.(scoped); ok {
+		node.Scope = decl.Scope()
+	}
 	// Get the Declaration's dependencies
 	deps := decl.Dependencies()
 	// Find and load the dependencies
-	for _, dep := range deps {
+	for n, dep := range deps {
 		i.log.Debug("di: finding dependency", "id", dep.ID(), "for", decl.ID())
 		child, err := i.load(externals, aliases, dep)
 		if err != nil {
+			// Add a breadcrumb to help find what needs the missing dependency
+			missing := new(MissingError)
+			if errors.As(err, &missing) {
+				missing.Chain = append(missing.Chain, neededBy(decl, n))
+			}
 			return nil, err
 		}
 		node.Dependencies = append(node.Dependencies, child)
@@ -0,0 +1,69 @@
+package di
+
+import (
+	"strings"
+
+	"github.com/livebud/bud/package/parser"
+)
+
+const lifecycleImport = "github.com/livebud/bud/package/lifecycle"
+
+// hooked is implemented by declarations that may have lifecycle hooks
+type hooked interface {
+	// Hooks is true if the dependency has a Start or Stop method
+	Hooks() bool
+}
+
+// hasHooks is true if the struct or interface has a Start or Stop method. The
+// signature is checked when the app starts, so a mismatch is ignored.
+func hasHooks(decl parser.Declaration) bool {
+	switch d := decl.(type) {
+	case *parser.Struct:
+		return d.Method("Start") != nil || d.Method("Stop") != nil
+	case *parser.Interface:
+		return d.Method("Start") != nil || d.Method("Stop") != nil
+	default:
+		return false
+	}
+}
+
+// Lifecycle is a *lifecycle.Hooks result that starts the dependencies with a
+// Start(ctx) method in the order they were created and stops the ones with a
+// Stop(ctx) method in reverse. Lifecycle should come after the other results
+// and before the error.
+type Lifecycle struct {
+}
+
+var _ Dependency = (*Lifecycle)(nil)
+var _ Declaration = (*Lifecycle)(nil)
+
+func (*Lifecycle) ID() string {
+	return "lifecycle"
+}
+
+func (*Lifecycle) ImportPath() string {
+	return lifecycleImport
+}
+
+func (*Lifecycle) TypeName() string {
+	return "*Hooks"
+}
+
+func (l *Lifecycle) Find(Finder) (Declaration, error) {
+	return l, nil
+}
+
+func (*Lifecycle) Dependencies() (deps []Dependency) {
+	return deps
+}
+
+func (*Lifecycle) Generate(gen Generator, inputs []*Variable) (outputs []*Variable) {
+	variable := gen.Variable(lifecycleImport, "*Hooks")
+	gen.WriteString(variable + " := " + gen.Identifier(lifecycleImport, "New") + "(" + strings.Join(gen.Hooks(), ", ") + ")\n")
+	return append(outputs, &Variable{
+		Import: lifecycleImport,
+		Name:   variable,
+		Type:   "*Hooks",
+		Kind:   parser.KindStruct,
+	})
+}
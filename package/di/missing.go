@@ -0,0 +1,140 @@
+package di
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/livebud/bud/internal/gois"
+	"github.com/livebud/bud/package/parser"
+)
+
+// MissingError explains why a dependency can't be provided, what needs it and
+// what might provide it instead
+type MissingError struct {
+	// ID of the dependency that can't be provided
+	ID string
+	// Reason it can't be provided
+	Reason string
+	// Chain of what needs the dependency, starting from the closest, like
+	// `field DB of "app.com/web".*Web`
+	Chain []string
+	// Suggestions to fix the problem
+	Suggestions []string
+}
+
+func (e *MissingError) Error() string {
+	s := new(strings.Builder)
+	fmt.Fprintf(s, "di: unclear how to provide %s", e.ID)
+	for _, need := range e.Chain {
+		fmt.Fprintf(s, "\n  needed by %s", need)
+	}
+	if e.Reason != "" {
+		fmt.Fprintf(s, "\n%s", e.Reason)
+	}
+	for _, suggestion := range e.Suggestions {
+		fmt.Fprintf(s, "\n  %s", suggestion)
+	}
+	return s.String()
+}
+
+// neededBy describes the nth dependency of the declaration
+func neededBy(decl Declaration, nth int) string {
+	switch d := decl.(type) {
+	case *Struct:
+		return fmt.Sprintf("field %s of %s", d.Fields[nth].Name, d.ID())
+	case *function:
+		if name := d.Params[nth].name; name != "" && name != "_" {
+			return fmt.Sprintf("param %s of %s", name, d.ID())
+		}
+		return fmt.Sprintf("param %d of %s", nth+1, d.ID())
+	default:
+		return decl.ID()
+	}
+}
+
+// missing explains why the package doesn't provide the dependency
+func (i *Injector) missing(pkg *parser.Package, dep Dependency) *MissingError {
+	name := strings.TrimPrefix(dep.TypeName(), "*")
+	err := &MissingError{ID: dep.ID()}
+	if iface := pkg.Interface(name); iface != nil {
+		err.Reason = fmt.Sprintf("%s is an interface. Bind it to an implementation in %s/, like:", name, BindDir)
+		implementations := i.implementations(iface)
+		if len(implementations) == 0 {
+			err.Suggestions = append(err.Suggestions, fmt.Sprintf("var _ %s.%s = (*Type)(nil)", pkg.Name(), name))
+			return err
+		}
+		for _, impl := range implementations {
+			err.Suggestions = append(err.Suggestions, fmt.Sprintf("var _ %s.%s = (%s)(nil)", pkg.Name(), name, impl))
+		}
+		return err
+	}
+	if stct := pkg.Struct(name); stct != nil {
+		for _, field := range stct.Fields() {
+			if field.Private() {
+				err.Reason = fmt.Sprintf("%s has the unexported field %s, so it can't be created automatically.", name, field.Name())
+				break
+			} else if gois.Builtin(field.Type().String()) {
+				err.Reason = fmt.Sprintf("%s has the field %s of type %s, so it can't be created automatically.", name, field.Name(), field.Type())
+				break
+			}
+		}
+		result := pkg.Name() + "." + name
+		if strings.HasPrefix(dep.TypeName(), "*") {
+			result = "*" + result
+		}
+		err.Suggestions = append(err.Suggestions, fmt.Sprintf("Add a function to package %s that returns it, like func Load() (%s, error)", pkg.Name(), result))
+		return err
+	}
+	err.Reason = fmt.Sprintf("There's no type called %s in %q, nor a function that returns it.", name, dep.ImportPath())
+	return err
+}
+
+// implementations finds the structs in the app that have the interface's
+// methods, like "*smtp.Client"
+func (i *Injector) implementations(iface *parser.Interface) (types []string) {
+	var methods []string
+	for _, method := range iface.Methods() {
+		methods = append(methods, method.Name())
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+	fs.WalkDir(i.fsys, ".", func(dir string, de fs.DirEntry, err error) error {
+		if err != nil || !de.IsDir() {
+			return nil
+		}
+		base := path.Base(dir)
+		if dir != "." && (base == "bud" || base == "node_modules" || base == "vendor" || base == "testdata" || strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+			return fs.SkipDir
+		}
+		pkg, err := i.parser.Parse(dir)
+		if err != nil {
+			return nil
+		}
+		for _, stct := range pkg.Structs() {
+			if stct.Private() || !hasMethods(stct, methods) {
+				continue
+			}
+			types = append(types, "*"+pkg.Name()+"."+stct.Name())
+		}
+		return nil
+	})
+	sort.Strings(types)
+	return types
+}
+
+func hasMethods(stct *parser.Struct, names []string) bool {
+	methods := map[string]bool{}
+	for _, method := range stct.Methods() {
+		methods[method.Name()] = true
+	}
+	for _, name := range names {
+		if !methods[name] {
+			return false
+		}
+	}
+	return true
+}
@@ -3,6 +3,7 @@ package di
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/livebud/bud/internal/imports"
@@ -25,6 +26,9 @@ type Node struct {
 	// Hoisted is true if the dependency has been hoisted up. Hoisted types are
 	// passed in, not instantiated.
 	Hoist bool
+	// Scope is the lifetime of the dependency. It's empty when the declaration
+	// doesn't choose a scope.
+	Scope Scope
 }
 
 // type Mode uint8
@@ -53,6 +57,14 @@ func (n *Node) Generate(imports *imports.Set, fnName, target string) *Provider {
 		Code:    new(strings.Builder),
 		Imports: imports,
 		Target:  target,
+		Names:   map[string]int{},
+		Results: len(n.Dependencies),
+	}
+	// The cleanup result is only returned when there's something to close
+	for _, dep := range n.Dependencies {
+		if _, ok := dep.Declaration.(*Cleanup); ok && !hasClosers(n) {
+			g.Results--
+		}
 	}
 	// Wire everything up!
 	outputs := g.Generate(n)
@@ -87,11 +99,16 @@ type generator struct {
 	Code       *strings.Builder
 	HasContext bool
 	HasError   bool
+	Names      map[string]int // Variable names in use
+	Results    int            // Number of results
+	closers    []string
+	hooks      []string
 }
 
 func (g *generator) Generate(node *Node, params ...*Variable) []*Variable {
 	id := node.ID()
-	if outputs, ok := g.Seen[id]; ok {
+	// Transient dependencies aren't shared, so they're created each time
+	if outputs, ok := g.Seen[id]; ok && node.Scope != Transient {
 		return outputs
 	}
 	var results []*Variable
@@ -103,13 +120,50 @@ func (g *generator) Generate(node *Node, params ...*Variable) []*Variable {
 	}
 	for _, dep := range node.Dependencies {
 		outputs := g.Generate(dep, params...)
+		if len(outputs) == 0 {
+			continue
+		}
 		results = append(results, outputs[0])
 	}
 	outputs := node.Declaration.Generate(g, results)
+	if closes(node) && len(outputs) > 0 {
+		g.closers = append(g.closers, outputs[0].Name)
+	}
+	if decl, ok := node.Declaration.(hooked); ok && decl.Hooks() && len(outputs) > 0 {
+		g.hooks = append(g.hooks, outputs[0].Name)
+	}
 	g.Seen[id] = outputs
 	return outputs
 }
 
+// closes is true if the node is closed by the cleanup
+func closes(node *Node) bool {
+	if node.External || node.Hoist {
+		return false
+	}
+	if node.Scope != Request && node.Scope != Transient {
+		return false
+	}
+	decl, ok := node.Declaration.(scoped)
+	return ok && decl.Closes()
+}
+
+// hasClosers is true if any of the nodes are closed by the cleanup
+func hasClosers(node *Node) bool {
+	if closes(node) {
+		return true
+	}
+	if node.External || node.Hoist {
+		return false
+	}
+	for _, dep := range node.Dependencies {
+		if hasClosers(dep) {
+			return true
+		}
+	}
+	return false
+}
+
 // Helper to mark a dependency as external returning a variable to that external
 // value
 func (g *generator) External(n *Node) *External {
@@ -192,19 +246,53 @@ func (g *generator) WriteString(code string) (n int, err error) {
 //	  webWeb := Load(consoleConsole)
 //	  return webWeb
 //	}
+//
+// Variables are unique, so dependencies that are created more than once get a
+// number at the end, like consoleConsole2.
 func (g *generator) Variable(importPath, typeName string) string {
 	if typeName == "error" {
 		return "err"
 	}
 	name := strings.TrimLeft(typeName, "*[]")
 	pkg := g.Imports.Reserve(importPath)
-	return pkg + name
+	variable := pkg + name
+	g.Names[variable]++
+	if n := g.Names[variable]; n > 1 {
+		return variable + strconv.Itoa(n)
+	}
+	return variable
 }
 
 func (g *generator) MarkError(hasError bool) {
 	g.HasError = hasError
 }
 
+// Closers are the variables created so far that are closed by the cleanup
+func (g *generator) Closers() []string {
+	return g.closers
+}
+
+// Hooks are the variables created so far that have lifecycle hooks
+func (g *generator) Hooks() []string {
+	return g.hooks
+}
+
+// ReturnError returns the code to return an error from the generated function,
+// closing the dependencies created so far
+func (g *generator) ReturnError(err string) string {
+	code := new(strings.Builder)
+	for i := len(g.closers) - 1; i >= 0; i-- {
+		fmt.Fprintf(code, "\t%s.Close()\n", g.closers[i])
+	}
+	results := make([]string, 0, g.Results)
+	for i := 1; i < g.Results; i++ {
+		results = append(results, "nil")
+	}
+	results = append(results, err)
+	fmt.Fprintf(code, "\treturn %s\n", strings.Join(results, ", "))
+	return code.String()
+}
+
 func (node *Node) Print() string {
 	out := "digraph G {\n"
 	seen := map[string]bool{}
@@ -230,6 +318,8 @@ func (node *Node) print(seen map[string]bool) string {
 		fmt.Fprintf(str, `%q -> %q`, dep.format(), id)
 		if dep.External {
 			label += " (external)"
+		} else if dep.Scope != "" {
+			label += " (" + string(dep.Scope) + ")"
 		}
 		fmt.Fprintf(str, ` [label=%q];`, label)
 		outs = append(outs, str.String())
@@ -242,6 +332,53 @@ func (node *Node) print(seen map[string]bool) string {
 	return strings.Join(outs, "\n  ")
 }
 
+// Mermaid prints the graph as a Mermaid flowchart
+func (node *Node) Mermaid() string {
+	m := &mermaid{ids: map[string]string{}, seen: map[string]bool{}}
+	m.WriteString("graph LR\n")
+	m.print(node)
+	return m.String()
+}
+
+type mermaid struct {
+	strings.Builder
+	ids  map[string]string
+	seen map[string]bool
+}
+
+// id returns the node's Mermaid identifier, declaring the node the first time
+func (m *mermaid) id(node *Node) string {
+	key := node.format()
+	if id, ok := m.ids[key]; ok {
+		return id
+	}
+	id := "n" + strconv.Itoa(len(m.ids))
+	m.ids[key] = id
+	label := node.Import + "." + toTypeName(node.Type)
+	if node.Import == "" {
+		label = toTypeName(node.Type)
+	}
+	if node.External {
+		label += " (external)"
+	} else if node.Scope != "" {
+		label += " (" + string(node.Scope) + ")"
+	}
+	fmt.Fprintf(m, "  %s[\"%s\"]\n", id, label)
+	return id
+}
+
+func (m *mermaid) print(node *Node) {
+	id := m.id(node)
+	if m.seen[id] {
+		return
+	}
+	m.seen[id] = true
+	for _, dep := range node.Dependencies {
+		fmt.Fprintf(m, "  %s --> %s\n", m.id(dep), id)
+		m.print(dep)
+	}
+}
+
 // Helper function to turn *Web into *web.Web
 func toDataType(packageName string, dataType string) string {
 	if strings.Contains(dataType, ".") {
@@ -68,6 +68,16 @@ func (p *Provider) Params() (params Params) {
 	return params
 }
 
+// Cleanup is true if the provider returns a cleanup function
+func (p *Provider) Cleanup() bool {
+	for _, result := range p.Results {
+		if result.Name == "cleanup" && result.Type == "func()" {
+			return true
+		}
+	}
+	return false
+}
+
 // Hoisted returns a list of hoisted externals
 func (p *Provider) Hoisted() (externals []*External) {
 	for _, external := range p.Externals {
@@ -0,0 +1,133 @@
+package di
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/livebud/bud/package/parser"
+)
+
+// Scope is the lifetime of a dependency. Providers choose their scope with a
+// directive on the function or the type:
+//
+//	//di:scope request
+//	func Load(db *sql.DB) (*Tx, error)
+//
+// Without a directive, dependencies are singletons when they can be, which is
+// when they don't depend on the request.
+type Scope string
+
+const (
+	// Singleton dependencies are created once and shared. Singletons can't
+	// depend on the request.
+	Singleton Scope = "singleton"
+	// Request dependencies are created once per request and shared within the
+	// request, even if they don't depend on the request
+	Request Scope = "request"
+	// Transient dependencies are created each time they're needed
+	Transient Scope = "transient"
+)
+
+// scoped is implemented by declarations with a scope
+type scoped interface {
+	Scope() Scope
+	// Closes is true if the dependency has a Close method that's called when
+	// the scope ends
+	Closes() bool
+}
+
+// scopeOf reads the scope from the directives
+func scopeOf(directives ...[]string) (Scope, error) {
+	for _, list := range directives {
+		for _, directive := range list {
+			name, value, _ := strings.Cut(directive, " ")
+			if name != "di:scope" {
+				continue
+			}
+			switch scope := Scope(strings.TrimSpace(value)); scope {
+			case Singleton, Request, Transient:
+				return scope, nil
+			default:
+				return "", fmt.Errorf("di: unknown scope %q. Expected singleton, request or transient", value)
+			}
+		}
+	}
+	return "", nil
+}
+
+// hasClose is true if the struct or interface has a Close method without
+// params
+func hasClose(decl parser.Declaration) bool {
+	switch d := decl.(type) {
+	case *parser.Struct:
+		method := d.Method("Close")
+		return method != nil && len(method.Params()) == 0
+	case *parser.Interface:
+		method := d.Method("Close")
+		return method != nil && len(method.Params()) == 0
+	default:
+		return false
+	}
+}
+
+// Cleanup is a func() result that closes the request and transient
+// dependencies with a Close method, in the reverse order they were created.
+// Cleanup should come after the other results and before the error. There's
+// no cleanup result when there's nothing to close.
+type Cleanup struct {
+}
+
+var _ Dependency = (*Cleanup)(nil)
+var _ Declaration = (*Cleanup)(nil)
+
+func (*Cleanup) ID() string {
+	return "cleanup"
+}
+
+func (*Cleanup) ImportPath() string {
+	return ""
+}
+
+func (*Cleanup) TypeName() string {
+	return "func()"
+}
+
+func (c *Cleanup) Find(Finder) (Declaration, error) {
+	return c, nil
+}
+
+func (*Cleanup) Dependencies() (deps []Dependency) {
+	return deps
+}
+
+func (*Cleanup) Generate(gen Generator, inputs []*Variable) (outputs []*Variable) {
+	closers := gen.Closers()
+	if len(closers) == 0 {
+		return nil
+	}
+	gen.WriteString("cleanup := func() {\n")
+	for i := len(closers) - 1; i >= 0; i-- {
+		gen.WriteString(fmt.Sprintf("\t%s.Close()\n", closers[i]))
+	}
+	gen.WriteString("}\n")
+	return append(outputs, &Variable{
+		Import: "",
+		Name:   "cleanup",
+		Type:   "func()",
+		Kind:   0, // Unknown kind
+	})
+}
+
+// checkScopes ensures that hoisted functions don't have singletons that depend
+// on the request
+func checkScopes(node *Node) error {
+	for _, dep := range node.Dependencies {
+		if dep.Scope == Singleton && !dep.Hoist && !dep.External {
+			return fmt.Errorf("di: %s is a singleton, but it depends on the request", dep.ID())
+		}
+		if err := checkScopes(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -15,10 +15,16 @@ type Struct struct {
 	Import string
 	Type   string
 	Fields []*StructField
+
+	scope  Scope
+	closes bool
+	hooks  bool
 }
 
 var _ Dependency = (*Struct)(nil)
 var _ Declaration = (*Struct)(nil)
+var _ scoped = (*Struct)(nil)
+var _ hooked = (*Struct)(nil)
 
 func (s *Struct) ID() string {
 	return `"` + s.Import + `".` + s.Type
@@ -32,6 +38,21 @@ func (s *Struct) TypeName() string {
 	return s.Type
 }
 
+// Scope of the struct from its //di:scope directive
+func (s *Struct) Scope() Scope {
+	return s.scope
+}
+
+// Closes is true if the struct has a Close method
+func (s *Struct) Closes() bool {
+	return s.closes
+}
+
+// Hooks is true if the struct has a Start or Stop method
+func (s *Struct) Hooks() bool {
+	return s.hooks
+}
+
 // Find a declaration that provides this type
 func (s *Struct) Find(finder Finder) (Declaration, error) {
 	return s, nil
@@ -110,9 +131,16 @@ func tryStruct(stct *parser.Struct, dataType string) (*Struct, error) {
 	if err != nil {
 		return nil, err
 	}
+	scope, err := scopeOf(stct.Directives())
+	if err != nil {
+		return nil, fmt.Errorf("%w in %q.%s", err, importPath, stct.Name())
+	}
 	decl := &Struct{
 		Import: importPath,
 		Type:   dataType,
+		scope:  scope,
+		closes: hasClose(stct),
+		hooks:  hasHooks(stct),
 		// needsRef: strings.HasPrefix(dataType, "*"),
 	}
 	for _, field := range stct.Fields() {
@@ -0,0 +1,363 @@
+// Package dotenv loads environment variables from .env files. The files are
+// loaded in order, with later files overriding earlier ones:
+//
+//	.env                 shared defaults, usually checked in
+//	.env.development     or .env.production, depending on $BUD_ENV
+//	.env.local           machine-specific overrides, kept out of git
+//
+// Variables that are already set in the environment always win, so the files
+// never override the environment that the app was started with.
+//
+// Each line sets a variable, like KEY=value or export KEY=value. Values may be
+// single quoted to keep them as-is, or double quoted to expand escapes like \n.
+// Unquoted and double-quoted values expand ${KEY} and $KEY.
+package dotenv
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// Development is the environment of bud run
+	Development = "development"
+	// Production is the environment of bud build
+	Production = "production"
+	// Test is the environment of bud test
+	Test = "test"
+)
+
+// Environment returns $BUD_ENV, falling back to fallback when it's not set
+func Environment(getenv func(key string) string, fallback string) string {
+	if environment := getenv("BUD_ENV"); environment != "" {
+		return environment
+	}
+	return fallback
+}
+
+// Files returns the .env files for the environment, in loading order
+func Files(environment string) []string {
+	return []string{".env", ".env." + environment, ".env.local"}
+}
+
+// Read the .env files for the environment in fsys. Missing files are skipped.
+// Variables reference each other and the environment through lookup.
+func Read(fsys fs.FS, environment string, lookup func(key string) (string, bool)) (map[string]string, error) {
+	values := map[string]string{}
+	for _, name := range Files(environment) {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("dotenv: unable to read %s. %w", name, err)
+		}
+		if err := parse(name, string(data), values, lookup); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// loaded are the variables that Load set from the files
+var loaded = struct {
+	sync.Mutex
+	keys map[string]bool
+}{keys: map[string]bool{}}
+
+// Load the .env files for the environment in the working directory into the
+// process's environment, without overriding the variables that are set.
+// Calling Load again reloads the files, updating the variables that came from
+// them and unsetting the ones that were removed.
+func Load(environment string) error {
+	loaded.Lock()
+	defer loaded.Unlock()
+	// Variables from the files are looked up in the files, not in the
+	// environment that an earlier Load set
+	lookup := func(key string) (string, bool) {
+		if loaded.keys[key] {
+			return "", false
+		}
+		return os.LookupEnv(key)
+	}
+	values, err := Read(os.DirFS("."), environment, lookup)
+	if err != nil {
+		return err
+	}
+	for key := range loaded.keys {
+		if _, ok := values[key]; ok {
+			continue
+		}
+		if err := os.Unsetenv(key); err != nil {
+			return fmt.Errorf("dotenv: unable to unset %s. %w", key, err)
+		}
+		delete(loaded.keys, key)
+	}
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok && !loaded.keys[key] {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("dotenv: unable to set %s. %w", key, err)
+		}
+		loaded.keys[key] = true
+	}
+	return nil
+}
+
+// Merge the .env files for the environment in fsys into an environment list
+// like os.Environ(), without overriding the variables that are set
+func Merge(fsys fs.FS, environment string, env []string) ([]string, error) {
+	set := map[string]string{}
+	for _, kv := range env {
+		if i := strings.IndexByte(kv, '='); i > 0 {
+			set[kv[:i]] = kv[i+1:]
+		}
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := set[key]
+		return value, ok
+	}
+	values, err := Read(fsys, environment, lookup)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if _, ok := set[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	merged := append([]string{}, env...)
+	for _, key := range keys {
+		merged = append(merged, key+"="+values[key])
+	}
+	return merged, nil
+}
+
+// Parse a .env file into a map. Variables reference each other and the
+// environment through lookup, which may be nil.
+func Parse(data string, lookup func(key string) (string, bool)) (map[string]string, error) {
+	values := map[string]string{}
+	if err := parse(".env", data, values, lookup); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// parser for a single file. Values are added to values, overriding the values
+// from earlier files.
+type parser struct {
+	name   string
+	data   string
+	pos    int
+	line   int
+	values map[string]string
+	lookup func(key string) (string, bool)
+}
+
+func parse(name, data string, values map[string]string, lookup func(key string) (string, bool)) error {
+	p := &parser{
+		name:   name,
+		data:   strings.ReplaceAll(data, "\r\n", "\n"),
+		line:   1,
+		values: values,
+		lookup: lookup,
+	}
+	for p.pos < len(p.data) {
+		if err := p.parseLine(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("dotenv: invalid %s on line %d. %s", p.name, p.line, fmt.Sprintf(format, args...))
+}
+
+// parseLine parses the next assignment, comment or blank line
+func (p *parser) parseLine() error {
+	p.skipSpaces()
+	if p.pos >= len(p.data) {
+		return nil
+	}
+	switch p.data[p.pos] {
+	case '\n':
+		p.pos++
+		p.line++
+		return nil
+	case '#':
+		p.skipLine()
+		return nil
+	}
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] != '=' && p.data[p.pos] != '\n' {
+		p.pos++
+	}
+	if p.pos >= len(p.data) || p.data[p.pos] != '=' {
+		return p.errorf("Expected KEY=value")
+	}
+	key := strings.TrimSpace(p.data[start:p.pos])
+	key = strings.TrimSpace(strings.TrimPrefix(key, "export "))
+	if !validKey(key) {
+		return p.errorf("Invalid key %q", key)
+	}
+	p.pos++ // Skip the "="
+	p.skipSpaces()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	p.values[key] = value
+	return nil
+}
+
+func (p *parser) parseValue() (string, error) {
+	if p.pos >= len(p.data) {
+		return "", nil
+	}
+	switch quote := p.data[p.pos]; quote {
+	case '\'', '"':
+		line := p.line
+		p.pos++
+		value := new(strings.Builder)
+		for {
+			if p.pos >= len(p.data) {
+				p.line = line
+				return "", p.errorf("Missing the closing %c", quote)
+			}
+			c := p.data[p.pos]
+			if c == quote {
+				p.pos++
+				break
+			}
+			if c == '\n' {
+				p.line++
+			}
+			if quote == '"' && c == '\\' && p.pos+1 < len(p.data) {
+				p.pos++
+				value.WriteString(unescape(p.data[p.pos]))
+				p.pos++
+				continue
+			}
+			if quote == '"' && c == '$' {
+				p.expand(value)
+				continue
+			}
+			value.WriteByte(c)
+			p.pos++
+		}
+		// Only a comment may follow the closing quote
+		p.skipSpaces()
+		if p.pos < len(p.data) && p.data[p.pos] != '\n' && p.data[p.pos] != '#' {
+			return "", p.errorf("Unexpected %q after the closing %c", p.data[p.pos], quote)
+		}
+		p.skipLine()
+		return value.String(), nil
+	}
+	// Unquoted values end at the line or at a comment that follows a space
+	value := new(strings.Builder)
+	for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+		c := p.data[p.pos]
+		if c == '#' && p.pos > 0 && (p.data[p.pos-1] == ' ' || p.data[p.pos-1] == '\t') {
+			break
+		}
+		if c == '$' {
+			p.expand(value)
+			continue
+		}
+		value.WriteByte(c)
+		p.pos++
+	}
+	p.skipLine()
+	return strings.TrimSpace(value.String()), nil
+}
+
+// expand ${KEY} and $KEY at the current position. Variables that are set in
+// the environment win over the values from the files, just like in Load.
+func (p *parser) expand(value *strings.Builder) {
+	p.pos++ // Skip the "$"
+	var key string
+	switch {
+	case p.pos < len(p.data) && p.data[p.pos] == '{':
+		end := strings.IndexAny(p.data[p.pos:], "}\n")
+		if end < 0 || p.data[p.pos+end] != '}' {
+			value.WriteByte('$')
+			return
+		}
+		key = p.data[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+	default:
+		start := p.pos
+		for p.pos < len(p.data) && isKeyChar(p.data[p.pos], p.pos == start) {
+			p.pos++
+		}
+		key = p.data[start:p.pos]
+		if key == "" {
+			value.WriteByte('$')
+			return
+		}
+	}
+	if p.lookup != nil {
+		if env, ok := p.lookup(key); ok {
+			value.WriteString(env)
+			return
+		}
+	}
+	value.WriteString(p.values[key])
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.data) && (p.data[p.pos] == ' ' || p.data[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipLine skips past the end of the current line
+func (p *parser) skipLine() {
+	for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+		p.pos++
+	}
+	if p.pos < len(p.data) {
+		p.pos++
+		p.line++
+	}
+}
+
+func unescape(c byte) string {
+	switch c {
+	case 'n':
+		return "\n"
+	case 'r':
+		return "\r"
+	case 't':
+		return "\t"
+	case '"', '\\', '$':
+		return string(c)
+	}
+	// Keep unknown escapes as they are
+	return "\\" + string(c)
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isKeyChar(key[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isKeyChar(c byte, first bool) bool {
+	return c == '_' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || !first && '0' <= c && c <= '9'
+}
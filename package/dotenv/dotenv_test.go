@@ -0,0 +1,157 @@
+package dotenv_test
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/dotenv"
+)
+
+func TestParse(t *testing.T) {
+	is := is.New(t)
+	values, err := dotenv.Parse(`
+# Comments and blank lines are skipped
+A=1
+export B = two words  # trailing comment
+C='single $A\n'
+D="double $A\n${B}"
+E=
+F=a#b
+G="multi
+line"
+H=$HOME/x
+I=$A.$B
+J="\$A"
+`, nil)
+	is.NoErr(err)
+	is.Equal(values["A"], "1")
+	is.Equal(values["B"], "two words")
+	is.Equal(values["C"], `single $A\n`)
+	is.Equal(values["D"], "double 1\ntwo words")
+	is.Equal(values["E"], "")
+	is.Equal(values["F"], "a#b")
+	is.Equal(values["G"], "multi\nline")
+	is.Equal(values["H"], "/x")
+	is.Equal(values["I"], "1.two words")
+	is.Equal(values["J"], "$A")
+}
+
+func TestParseLookup(t *testing.T) {
+	is := is.New(t)
+	lookup := func(key string) (string, bool) {
+		if key == "HOME" {
+			return "/home/bud", true
+		}
+		return "", false
+	}
+	values, err := dotenv.Parse("DIR=${HOME}/app\n", lookup)
+	is.NoErr(err)
+	is.Equal(values["DIR"], "/home/bud/app")
+}
+
+func TestParseError(t *testing.T) {
+	is := is.New(t)
+	_, err := dotenv.Parse("A=1\nnope\n", nil)
+	is.True(err != nil)
+	is.Equal(err.Error(), `dotenv: invalid .env on line 2. Expected KEY=value`)
+	_, err = dotenv.Parse("A=1\n1A=2\n", nil)
+	is.True(err != nil)
+	is.Equal(err.Error(), `dotenv: invalid .env on line 2. Invalid key "1A"`)
+	_, err = dotenv.Parse("A=\"open\n\n", nil)
+	is.True(err != nil)
+	is.Equal(err.Error(), `dotenv: invalid .env on line 1. Missing the closing "`)
+	_, err = dotenv.Parse("A='a' b\n", nil)
+	is.True(err != nil)
+	is.Equal(err.Error(), `dotenv: invalid .env on line 1. Unexpected 'b' after the closing '`)
+}
+
+func TestRead(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		".env":             {Data: []byte("A=env\nB=env\nC=env\nD=env\n")},
+		".env.development": {Data: []byte("B=development\nC=development\n")},
+		".env.production":  {Data: []byte("B=production\n")},
+		".env.local":       {Data: []byte("C=local\nE=$B\n")},
+	}
+	values, err := dotenv.Read(fsys, dotenv.Development, nil)
+	is.NoErr(err)
+	is.Equal(values["A"], "env")
+	is.Equal(values["B"], "development")
+	is.Equal(values["C"], "local")
+	is.Equal(values["E"], "development")
+	values, err = dotenv.Read(fsys, dotenv.Production, nil)
+	is.NoErr(err)
+	is.Equal(values["B"], "production")
+	is.Equal(values["C"], "local")
+	// Missing files are skipped
+	values, err = dotenv.Read(fstest.MapFS{}, dotenv.Production, nil)
+	is.NoErr(err)
+	is.Equal(len(values), 0)
+}
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+	fsys := fstest.MapFS{
+		".env": {Data: []byte("A=file\nB=file\nURL=http://$HOST/\n")},
+	}
+	env, err := dotenv.Merge(fsys, dotenv.Development, []string{"A=env", "HOST=example.com"})
+	is.NoErr(err)
+	is.Equal(len(env), 4)
+	is.Equal(env[0], "A=env")
+	is.Equal(env[1], "HOST=example.com")
+	is.Equal(env[2], "B=file")
+	is.Equal(env[3], "URL=http://example.com/")
+}
+
+func TestEnvironment(t *testing.T) {
+	is := is.New(t)
+	getenv := func(key string) string { return "" }
+	is.Equal(dotenv.Environment(getenv, dotenv.Development), "development")
+	getenv = func(key string) string { return map[string]string{"BUD_ENV": "staging"}[key] }
+	is.Equal(dotenv.Environment(getenv, dotenv.Development), "staging")
+	is.Equal(dotenv.Files("staging"), []string{".env", ".env.staging", ".env.local"})
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.WriteFile(dir+"/.env", []byte("DOTENV_TEST_A=file\nDOTENV_TEST_B=file\n"), 0644))
+	wd, err := os.Getwd()
+	is.NoErr(err)
+	is.NoErr(os.Chdir(dir))
+	defer os.Chdir(wd)
+	t.Setenv("DOTENV_TEST_A", "env")
+	t.Setenv("DOTENV_TEST_B", "")
+	os.Unsetenv("DOTENV_TEST_B")
+	is.NoErr(dotenv.Load(dotenv.Development))
+	is.Equal(os.Getenv("DOTENV_TEST_A"), "env")
+	is.Equal(os.Getenv("DOTENV_TEST_B"), "file")
+}
+
+func TestReload(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.WriteFile(dir+"/.env", []byte("DOTENV_RELOAD_A=1\nDOTENV_RELOAD_B=1\nDOTENV_RELOAD_C=1\n"), 0644))
+	wd, err := os.Getwd()
+	is.NoErr(err)
+	is.NoErr(os.Chdir(dir))
+	defer os.Chdir(wd)
+	t.Setenv("DOTENV_RELOAD_A", "env")
+	for _, key := range []string{"DOTENV_RELOAD_B", "DOTENV_RELOAD_C"} {
+		t.Setenv(key, "")
+		os.Unsetenv(key)
+	}
+	is.NoErr(dotenv.Load(dotenv.Development))
+	is.Equal(os.Getenv("DOTENV_RELOAD_B"), "1")
+	is.Equal(os.Getenv("DOTENV_RELOAD_C"), "1")
+	// Reloading updates the variables from the files, but the environment
+	// still wins
+	is.NoErr(os.WriteFile(dir+"/.env", []byte("DOTENV_RELOAD_A=2\nDOTENV_RELOAD_B=2\n"), 0644))
+	is.NoErr(dotenv.Load(dotenv.Development))
+	is.Equal(os.Getenv("DOTENV_RELOAD_A"), "env")
+	is.Equal(os.Getenv("DOTENV_RELOAD_B"), "2")
+	_, ok := os.LookupEnv("DOTENV_RELOAD_C")
+	is.True(!ok)
+}
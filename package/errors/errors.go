@@ -0,0 +1,171 @@
+// Package errors adds kinds to errors, so errors can be mapped to HTTP status
+// codes without the code that returns them knowing about HTTP.
+//
+//	func (c *Controller) Show(ctx context.Context, id int) (*Post, error) {
+//		post, err := c.DB.Find(ctx, id)
+//		if err != nil {
+//			return nil, err // 404 when the post doesn't exist
+//		}
+//		if post.Locked {
+//			return nil, errors.Conflict.Errorf("posts: %d is locked", id)
+//		}
+//		return post, nil
+//	}
+//
+// Kinds are errors themselves, so errors.Is(err, errors.NotFound) checks the
+// kind of err. The package can be used in place of the standard library's
+// errors package.
+package errors
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Kind of error
+type Kind uint8
+
+const (
+	// Internal is an unexpected error. Errors without a kind are internal.
+	Internal Kind = iota
+	// Invalid is a bad request, like malformed input
+	Invalid
+	// Unauthorized is a request that needs to log in first
+	Unauthorized
+	// Forbidden is a request that isn't allowed
+	Forbidden
+	// NotFound is a request for something that doesn't exist
+	NotFound
+	// Conflict is a request that conflicts with the current state, like
+	// creating something that already exists
+	Conflict
+	// TooManyRequests is a request that's been rate limited
+	TooManyRequests
+	// Unavailable is a request that can be retried later
+	Unavailable
+)
+
+var kinds = [...]struct {
+	text   string
+	status int
+}{
+	Internal:        {"internal error", http.StatusInternalServerError},
+	Invalid:         {"invalid", http.StatusBadRequest},
+	Unauthorized:    {"unauthorized", http.StatusUnauthorized},
+	Forbidden:       {"forbidden", http.StatusForbidden},
+	NotFound:        {"not found", http.StatusNotFound},
+	Conflict:        {"conflict", http.StatusConflict},
+	TooManyRequests: {"too many requests", http.StatusTooManyRequests},
+	Unavailable:     {"unavailable", http.StatusServiceUnavailable},
+}
+
+// Error implements error, so kinds can be used as sentinel errors
+func (k Kind) Error() string {
+	if int(k) < len(kinds) {
+		return kinds[k].text
+	}
+	return fmt.Sprintf("errors: unknown kind %d", k)
+}
+
+// Status is the HTTP status code of the kind
+func (k Kind) Status() int {
+	if int(k) < len(kinds) {
+		return kinds[k].status
+	}
+	return http.StatusInternalServerError
+}
+
+// New error of this kind
+func (k Kind) New(message string) error {
+	return &Error{k, errors.New(message)}
+}
+
+// Errorf formats an error of this kind. Errors can be wrapped with %w.
+func (k Kind) Errorf(format string, args ...interface{}) error {
+	return &Error{k, fmt.Errorf(format, args...)}
+}
+
+// Wrap the error with this kind, keeping its message. Wrapping nil returns
+// nil.
+func (k Kind) Wrap(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{k, err}
+}
+
+// Error has a kind
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the error's kind
+func (e *Error) Is(target error) bool {
+	kind, ok := target.(Kind)
+	return ok && kind == e.Kind
+}
+
+// KindOf returns the kind of the first error in the chain that has one. Errors
+// from the standard library that have an obvious kind, like sql.ErrNoRows, are
+// mapped too. Errors without a kind are Internal.
+func KindOf(err error) Kind {
+	if err == nil {
+		return Internal
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	var kind Kind
+	if errors.As(err, &kind) {
+		return kind
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound
+	}
+	return Internal
+}
+
+// Status returns the HTTP status code for the error
+func Status(err error) int {
+	return KindOf(err).Status()
+}
+
+// New error without a kind. Same as the standard library.
+func New(message string) error {
+	return errors.New(message)
+}
+
+// Errorf formats an error without a kind. Same as fmt.Errorf.
+func Errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(format, args...)
+}
+
+// Is reports whether any error in err's chain matches target. Same as the
+// standard library.
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first error in err's chain that matches target. Same as the
+// standard library.
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
+
+// Unwrap returns the error that err wraps. Same as the standard library.
+func Unwrap(err error) error {
+	return errors.Unwrap(err)
+}
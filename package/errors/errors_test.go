@@ -0,0 +1,57 @@
+package errors_test
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/errors"
+)
+
+func TestKind(t *testing.T) {
+	is := is.New(t)
+	err := errors.NotFound.Errorf("posts: %d not found", 10)
+	is.Equal(err.Error(), "posts: 10 not found")
+	is.True(errors.Is(err, errors.NotFound))
+	is.True(!errors.Is(err, errors.Conflict))
+	is.Equal(errors.KindOf(err), errors.NotFound)
+	is.Equal(errors.Status(err), 404)
+	// Kinds survive wrapping
+	wrapped := fmt.Errorf("controller: unable to show. %w", err)
+	is.True(errors.Is(wrapped, errors.NotFound))
+	is.Equal(errors.Status(wrapped), 404)
+	// Kinds can be returned as is
+	is.Equal(errors.Status(errors.Unauthorized), 401)
+	is.Equal(errors.Unauthorized.Error(), "unauthorized")
+}
+
+func TestWrap(t *testing.T) {
+	is := is.New(t)
+	is.Equal(errors.Invalid.Wrap(nil), nil)
+	cause := errors.New("bad json")
+	err := errors.Invalid.Wrap(cause)
+	is.Equal(err.Error(), "bad json")
+	is.True(errors.Is(err, cause))
+	is.True(errors.Is(err, errors.Invalid))
+	is.Equal(errors.Status(err), 400)
+	var e *errors.Error
+	is.True(errors.As(err, &e))
+	is.Equal(e.Kind, errors.Invalid)
+	is.Equal(errors.Unwrap(err), cause)
+	// The outermost kind wins
+	err = errors.Conflict.Wrap(errors.NotFound.New("not here"))
+	is.Equal(errors.KindOf(err), errors.Conflict)
+	is.True(errors.Is(err, errors.NotFound))
+}
+
+func TestStatus(t *testing.T) {
+	is := is.New(t)
+	is.Equal(errors.Status(errors.New("oops")), 500)
+	is.Equal(errors.Status(fmt.Errorf("db: unable to find. %w", sql.ErrNoRows)), 404)
+	is.Equal(errors.Status(errors.Forbidden.New("no")), 403)
+	is.Equal(errors.Status(errors.Conflict.New("no")), 409)
+	is.Equal(errors.Status(errors.TooManyRequests.New("no")), 429)
+	is.Equal(errors.Status(errors.Unavailable.New("no")), 503)
+	is.Equal(errors.Status(errors.Internal.New("no")), 500)
+}
@@ -0,0 +1,382 @@
+// Package feature turns features on and off at runtime, so teams can ship
+// dark-launched features and roll them out gradually. Each flag is on for
+// everyone, for some users or tenants, or for a percentage of users:
+//
+//	# config/features.yml
+//	new_checkout:
+//	  users: ["1", "2"]
+//	  tenants: [acme]
+//	  percentage: 25
+//	dark_mode:
+//	  enabled: true
+//
+// The environment overrides the file, so flags can change without a deploy:
+//
+//	FEATURE_NEW_CHECKOUT=on
+//	FEATURE_DARK_MODE=off
+//	FEATURE_SEARCH="10%;users:1,2;tenants:acme"
+//
+// Depend on the features in your controllers:
+//
+//	type Controller struct {
+//	  Features *feature.Features
+//	}
+//
+//	if c.Features.Enabled(ctx, "new_checkout") { ... }
+//
+// Svelte views read the request's flags with getContext("features").
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/jwt"
+	"github.com/livebud/bud/package/reload"
+	"github.com/livebud/bud/package/tenant"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile holds the static flags when $FEATURES_FILE isn't set
+const DefaultFile = "config/features.yml"
+
+// Flag is a feature's rollout. A flag is on for a request when any rule
+// matches.
+type Flag struct {
+	// Enabled turns the feature on for everyone
+	Enabled bool `json:"enabled,omitempty" yaml:"enabled"`
+	// Users that have the feature, by ID
+	Users []string `json:"users,omitempty" yaml:"users"`
+	// Tenants that have the feature, by ID
+	Tenants []string `json:"tenants,omitempty" yaml:"tenants"`
+	// Percentage of users that have the feature, from 0 to 100. Each user
+	// keeps the same answer as the percentage grows. Requests without a user
+	// are bucketed by their tenant.
+	Percentage float64 `json:"percentage,omitempty" yaml:"percentage"`
+}
+
+// Provider looks up the flags by name. Implement Provider to load flags from
+// a remote service.
+type Provider interface {
+	Flags(ctx context.Context) (map[string]*Flag, error)
+}
+
+// Static flags, like the flags from a file or the environment
+type Static map[string]*Flag
+
+var _ Provider = Static(nil)
+
+// Flags returns the static flags
+func (s Static) Flags(ctx context.Context) (map[string]*Flag, error) {
+	return s, nil
+}
+
+// Load the features from the environment:
+//
+//	FEATURES_FILE=config/features.yml  # static flags, the default
+//	FEATURES_URL=https://flags.example.com/flags.json  # remote flags
+//	FEATURES_REFRESH=30s  # how often to fetch the remote flags
+//	FEATURE_<NAME>=on  # overrides the flag called <name>
+//
+// The features are loaded again when the reloader reloads, like after the
+// flags file changes.
+func Load(reloader *reload.Reloader) (*Features, error) {
+	features, err := LoadEnv(os.Getenv, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+	reloader.Watch(filePath(os.Getenv))
+	reloader.Subscribe(func(ctx context.Context) error {
+		next, err := LoadEnv(os.Getenv, os.Environ())
+		if err != nil {
+			return err
+		}
+		features.Set(next.Provider())
+		return nil
+	})
+	return features, nil
+}
+
+// filePath returns the path to the static flags
+func filePath(getenv func(key string) string) string {
+	if path := getenv("FEATURES_FILE"); path != "" {
+		return path
+	}
+	return DefaultFile
+}
+
+// LoadEnv loads the features with getenv. The FEATURE_<NAME> overrides come
+// from environ.
+func LoadEnv(getenv func(key string) string, environ []string) (*Features, error) {
+	var providers []Provider
+	file, err := ReadFile(filePath(getenv))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || getenv("FEATURES_FILE") != "" {
+			return nil, err
+		}
+	} else {
+		providers = append(providers, file)
+	}
+	if url := getenv("FEATURES_URL"); url != "" {
+		remote := NewRemote(url)
+		if refresh := getenv("FEATURES_REFRESH"); refresh != "" {
+			remote.Refresh, err = time.ParseDuration(refresh)
+			if err != nil {
+				return nil, fmt.Errorf("feature: invalid FEATURES_REFRESH %q. %w", refresh, err)
+			}
+		}
+		providers = append(providers, remote)
+	}
+	env, err := FromEnv(environ)
+	if err != nil {
+		return nil, err
+	}
+	providers = append(providers, env)
+	return New(Merge(providers...)), nil
+}
+
+// ReadFile reads static flags from a YAML or JSON file
+func ReadFile(path string) (Static, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("feature: unable to read %s. %w", path, err)
+	}
+	flags := Static{}
+	if err := yaml.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("feature: unable to parse %s. %w", path, err)
+	}
+	for name, flag := range flags {
+		if flag == nil {
+			flags[name] = new(Flag)
+		}
+	}
+	return flags, nil
+}
+
+// FromEnv reads the FEATURE_<NAME> variables from environ. The flag's name is
+// the lowercase <name>, so FEATURE_NEW_CHECKOUT sets new_checkout. Values are
+// "on" or "off", or rules separated by semicolons, like
+// "25%;users:1,2;tenants:acme".
+func FromEnv(environ []string) (Static, error) {
+	flags := Static{}
+	for _, kv := range environ {
+		if !strings.HasPrefix(kv, "FEATURE_") {
+			continue
+		}
+		key, value, _ := strings.Cut(kv, "=")
+		name := strings.ToLower(strings.TrimPrefix(key, "FEATURE_"))
+		if name == "" {
+			continue
+		}
+		flag, err := ParseFlag(value)
+		if err != nil {
+			return nil, fmt.Errorf("feature: invalid %s. %w", key, err)
+		}
+		flags[name] = flag
+	}
+	return flags, nil
+}
+
+// ParseFlag parses a flag from the environment, like "on", "off" or
+// "25%;users:1,2;tenants:acme"
+func ParseFlag(value string) (*Flag, error) {
+	flag := new(Flag)
+	for _, rule := range strings.Split(value, ";") {
+		rule = strings.TrimSpace(rule)
+		switch {
+		case rule == "":
+		case rule == "on" || rule == "true" || rule == "1":
+			flag.Enabled = true
+		case rule == "off" || rule == "false" || rule == "0":
+		case strings.HasSuffix(rule, "%"):
+			percentage, err := strconv.ParseFloat(strings.TrimSuffix(rule, "%"), 64)
+			if err != nil || percentage < 0 || percentage > 100 {
+				return nil, fmt.Errorf("expected a percentage from 0%% to 100%%, got %q", rule)
+			}
+			flag.Percentage = percentage
+		case strings.HasPrefix(rule, "users:"):
+			flag.Users = append(flag.Users, splitList(strings.TrimPrefix(rule, "users:"))...)
+		case strings.HasPrefix(rule, "tenants:"):
+			flag.Tenants = append(flag.Tenants, splitList(strings.TrimPrefix(rule, "tenants:"))...)
+		default:
+			return nil, fmt.Errorf(`unknown rule %q. Expected "on", "off", a percentage like "25%%", "users:1,2" or "tenants:acme"`, rule)
+		}
+	}
+	return flag, nil
+}
+
+func splitList(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Merge the flags from the providers. Later providers override the flags of
+// earlier providers with the same name.
+func Merge(providers ...Provider) Provider {
+	return merged(providers)
+}
+
+type merged []Provider
+
+func (m merged) Flags(ctx context.Context) (map[string]*Flag, error) {
+	flags := map[string]*Flag{}
+	for _, provider := range m {
+		more, err := provider.Flags(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for name, flag := range more {
+			flags[name] = flag
+		}
+	}
+	return flags, nil
+}
+
+// New features from the provider
+func New(provider Provider) *Features {
+	return &Features{provider: provider}
+}
+
+// Features evaluates the flags for each request
+type Features struct {
+	mu       sync.RWMutex
+	provider Provider
+}
+
+// Provider returns the provider of the flags
+func (f *Features) Provider() Provider {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.provider
+}
+
+// Set the provider of the flags, like when the flags are reloaded
+func (f *Features) Set(provider Provider) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.provider = provider
+}
+
+// Enabled is true if the feature is on for the context's user or tenant.
+// Unknown features are off, as are features whose provider fails.
+func (f *Features) Enabled(ctx context.Context, name string) bool {
+	flags, err := f.Provider().Flags(ctx)
+	if err != nil {
+		return false
+	}
+	flag, ok := flags[name]
+	if !ok || flag == nil {
+		return false
+	}
+	return flag.enabled(name, subjectFrom(ctx))
+}
+
+// All returns whether each feature is on for the context's user or tenant
+func (f *Features) All(ctx context.Context) (map[string]bool, error) {
+	flags, err := f.Provider().Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subject := subjectFrom(ctx)
+	all := make(map[string]bool, len(flags))
+	for name, flag := range flags {
+		all[name] = flag != nil && flag.enabled(name, subject)
+	}
+	return all, nil
+}
+
+// Names of the known features in alphabetical order
+func (f *Features) Names(ctx context.Context) ([]string, error) {
+	flags, err := f.Provider().Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// subject that the flags are evaluated for
+type subject struct {
+	user   string
+	tenant string
+}
+
+func (f *Flag) enabled(name string, s subject) bool {
+	if f.Enabled {
+		return true
+	}
+	if s.user != "" && contains(f.Users, s.user) {
+		return true
+	}
+	if s.tenant != "" && contains(f.Tenants, s.tenant) {
+		return true
+	}
+	if f.Percentage <= 0 {
+		return false
+	} else if f.Percentage >= 100 {
+		return true
+	}
+	key := s.user
+	if key == "" {
+		key = "tenant:" + s.tenant
+		if s.tenant == "" {
+			return false
+		}
+	}
+	return bucket(name, key) < f.Percentage
+}
+
+// bucket places the key between 0 and 100. Hashing the feature's name along
+// with the key gives each feature a different sample of users.
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name + ":" + key))
+	return float64(h.Sum32()%10000) / 100
+}
+
+func contains(list []string, item string) bool {
+	for _, value := range list {
+		if value == item {
+			return true
+		}
+	}
+	return false
+}
+
+type userKey struct{}
+
+// WithUser sets the user that the flags are evaluated for. Use it for users
+// that aren't logged in through package auth or package jwt, like API clients.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// subjectFrom finds the request's user and tenant
+func subjectFrom(ctx context.Context) (s subject) {
+	if userID, ok := ctx.Value(userKey{}).(string); ok {
+		s.user = userID
+	} else if userID, ok := auth.UserID(ctx); ok {
+		s.user = strconv.Itoa(userID)
+	} else if claims := jwt.From(ctx); claims != nil {
+		s.user = claims.Subject()
+	}
+	s.tenant, _ = tenant.From(ctx)
+	return s
+}
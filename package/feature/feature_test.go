@@ -0,0 +1,206 @@
+package feature_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/feature"
+	"github.com/livebud/bud/package/reload"
+	"github.com/livebud/bud/package/tenant"
+)
+
+func TestParseFlag(t *testing.T) {
+	is := is.New(t)
+	flag, err := feature.ParseFlag("on")
+	is.NoErr(err)
+	is.True(flag.Enabled)
+	flag, err = feature.ParseFlag("off")
+	is.NoErr(err)
+	is.True(!flag.Enabled)
+	flag, err = feature.ParseFlag("25%; users:1, 2 ;tenants:acme")
+	is.NoErr(err)
+	is.Equal(flag.Percentage, 25.0)
+	is.Equal(flag.Users, []string{"1", "2"})
+	is.Equal(flag.Tenants, []string{"acme"})
+	_, err = feature.ParseFlag("150%")
+	is.True(err != nil)
+	_, err = feature.ParseFlag("maybe")
+	is.True(err != nil)
+}
+
+func TestFromEnv(t *testing.T) {
+	is := is.New(t)
+	flags, err := feature.FromEnv([]string{
+		"HOME=/root",
+		"FEATURE_NEW_CHECKOUT=on",
+		"FEATURE_DARK_MODE=users:7",
+		"FEATURES_FILE=features.yml",
+	})
+	is.NoErr(err)
+	is.Equal(len(flags), 2)
+	is.True(flags["new_checkout"].Enabled)
+	is.Equal(flags["dark_mode"].Users, []string{"7"})
+	_, err = feature.FromEnv([]string{"FEATURE_SEARCH=sometimes"})
+	is.True(err != nil)
+	is.In(err.Error(), "FEATURE_SEARCH")
+}
+
+func TestReadFile(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(t.TempDir(), "features.yml")
+	is.NoErr(os.WriteFile(path, []byte(`
+new_checkout:
+  users: ["1", "2"]
+  percentage: 10
+dark_mode:
+  enabled: true
+search:
+`), 0644))
+	flags, err := feature.ReadFile(path)
+	is.NoErr(err)
+	is.Equal(len(flags), 3)
+	is.Equal(flags["new_checkout"].Users, []string{"1", "2"})
+	is.Equal(flags["new_checkout"].Percentage, 10.0)
+	is.True(flags["dark_mode"].Enabled)
+	is.True(!flags["search"].Enabled)
+}
+
+func TestLoadEnv(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "features.yml")
+	is.NoErr(os.WriteFile(path, []byte("dark_mode:\n  enabled: true\nsearch:\n  enabled: true\n"), 0644))
+	env := map[string]string{"FEATURES_FILE": path}
+	features, err := feature.LoadEnv(func(key string) string { return env[key] }, []string{"FEATURE_SEARCH=off"})
+	is.NoErr(err)
+	ctx := context.Background()
+	is.True(features.Enabled(ctx, "dark_mode"))
+	// The environment overrides the file
+	is.True(!features.Enabled(ctx, "search"))
+	// Missing files that were asked for are an error
+	env["FEATURES_FILE"] = filepath.Join(dir, "missing.yml")
+	_, err = feature.LoadEnv(func(key string) string { return env[key] }, nil)
+	is.True(err != nil)
+}
+
+func TestLoadReload(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(t.TempDir(), "features.yml")
+	is.NoErr(os.WriteFile(path, []byte("dark_mode:\n  enabled: false\n"), 0644))
+	t.Setenv("FEATURES_FILE", path)
+	reloader := reload.New()
+	features, err := feature.Load(reloader)
+	is.NoErr(err)
+	ctx := context.Background()
+	is.True(!features.Enabled(ctx, "dark_mode"))
+	is.NoErr(os.WriteFile(path, []byte("dark_mode:\n  enabled: true\n"), 0644))
+	is.NoErr(reloader.Reload(ctx))
+	is.True(features.Enabled(ctx, "dark_mode"))
+	// Invalid flags keep the previous flags
+	is.NoErr(os.WriteFile(path, []byte("dark_mode: [\n"), 0644))
+	is.True(reloader.Reload(ctx) != nil)
+	is.True(features.Enabled(ctx, "dark_mode"))
+}
+
+func TestEnabled(t *testing.T) {
+	is := is.New(t)
+	features := feature.New(feature.Static{
+		"everyone": {Enabled: true},
+		"users":    {Users: []string{"1"}},
+		"tenants":  {Tenants: []string{"acme"}},
+		"nobody":   {},
+	})
+	ctx := context.Background()
+	is.True(features.Enabled(ctx, "everyone"))
+	is.True(!features.Enabled(ctx, "users"))
+	is.True(!features.Enabled(ctx, "tenants"))
+	is.True(!features.Enabled(ctx, "nobody"))
+	is.True(!features.Enabled(ctx, "unknown"))
+	is.True(features.Enabled(feature.WithUser(ctx, "1"), "users"))
+	is.True(!features.Enabled(feature.WithUser(ctx, "2"), "users"))
+	is.True(features.Enabled(tenant.With(ctx, "acme"), "tenants"))
+	is.True(!features.Enabled(tenant.With(ctx, "globex"), "tenants"))
+	all, err := features.All(feature.WithUser(ctx, "1"))
+	is.NoErr(err)
+	is.Equal(all, map[string]bool{"everyone": true, "users": true, "tenants": false, "nobody": false})
+	names, err := features.Names(ctx)
+	is.NoErr(err)
+	is.Equal(names, []string{"everyone", "nobody", "tenants", "users"})
+}
+
+func TestPercentage(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	quarter := feature.New(feature.Static{"rollout": {Percentage: 25}})
+	half := feature.New(feature.Static{"rollout": {Percentage: 50}})
+	enabled := 0
+	for i := 0; i < 10000; i++ {
+		userCtx := feature.WithUser(ctx, fmt.Sprint(i))
+		if quarter.Enabled(userCtx, "rollout") {
+			enabled++
+			// Users in the rollout stay in as it grows
+			is.True(half.Enabled(userCtx, "rollout"))
+		}
+	}
+	is.True(enabled > 2300 && enabled < 2700)
+	// Requests without a user or tenant are left out
+	is.True(!half.Enabled(ctx, "rollout"))
+}
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+	provider := feature.Merge(
+		feature.Static{"a": {Enabled: true}, "b": {Enabled: true}},
+		feature.Static{"b": {}},
+	)
+	flags, err := provider.Flags(context.Background())
+	is.NoErr(err)
+	is.True(flags["a"].Enabled)
+	is.True(!flags["b"].Enabled)
+}
+
+func TestRemote(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	requests := 0
+	body := `{"new_checkout": {"enabled": true}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if body == "" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	remote := feature.NewRemote(server.URL)
+	remote.Refresh = time.Minute
+	remote.Now = func() time.Time { return now }
+	features := feature.New(remote)
+	is.True(features.Enabled(ctx, "new_checkout"))
+	is.True(features.Enabled(ctx, "new_checkout"))
+	is.Equal(requests, 1)
+	// Refetch once the flags are stale
+	now = now.Add(2 * time.Minute)
+	body = `{"new_checkout": {}}`
+	is.True(!features.Enabled(ctx, "new_checkout"))
+	is.Equal(requests, 2)
+	// Keep the previous flags when the service is down
+	now = now.Add(2 * time.Minute)
+	body = ""
+	_, err := remote.Flags(ctx)
+	is.NoErr(err)
+	is.True(!features.Enabled(ctx, "new_checkout"))
+	is.Equal(requests, 3)
+	// Fail without previous flags
+	_, err = feature.NewRemote(server.URL).Flags(ctx)
+	is.True(err != nil)
+}
@@ -0,0 +1,39 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/livebud/bud/framework/view/viewrt"
+)
+
+// ViewKey is the key that Svelte views read the flags with, like
+// getContext("features").new_checkout
+const ViewKey = "features"
+
+// Middleware passes the request's flags to the views. It should come after
+// the middleware that finds the user and tenant.
+func (f *Features) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		ctx = viewrt.WithContext(ctx, ViewKey, &snapshot{f, ctx})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// snapshot of the request's flags, which are only evaluated when a view
+// renders
+type snapshot struct {
+	features *Features
+	ctx      context.Context
+}
+
+func (s *snapshot) MarshalJSON() ([]byte, error) {
+	all, err := s.features.All(s.ctx)
+	if err != nil {
+		// Turn the features off rather than failing the page
+		all = map[string]bool{}
+	}
+	return json.Marshal(all)
+}
@@ -0,0 +1,80 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// NewRemote fetches the flags from a URL that returns them as JSON, like
+// {"new_checkout": {"percentage": 25}}
+func NewRemote(url string) *Remote {
+	return &Remote{
+		URL:     url,
+		Refresh: 30 * time.Second,
+		HTTP:    http.DefaultClient,
+		Now:     time.Now,
+	}
+}
+
+// Remote flags are fetched at most once per refresh interval. When a fetch
+// fails, the previous flags are kept until the next refresh.
+type Remote struct {
+	URL     string
+	Refresh time.Duration
+	HTTP    *http.Client
+	Now     func() time.Time
+
+	mu      sync.Mutex
+	flags   map[string]*Flag
+	fetched time.Time
+}
+
+var _ Provider = (*Remote)(nil)
+
+// Flags returns the remote flags, fetching them when they're stale
+func (r *Remote) Flags(ctx context.Context) (map[string]*Flag, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.Now()
+	if !r.fetched.IsZero() && now.Sub(r.fetched) < r.Refresh {
+		return r.flags, nil
+	}
+	flags, err := r.fetch(ctx)
+	if err != nil {
+		if r.flags != nil {
+			// Try again after the next interval rather than on every request
+			r.fetched = now
+			return r.flags, nil
+		}
+		return nil, err
+	}
+	r.flags = flags
+	r.fetched = now
+	return flags, nil
+}
+
+func (r *Remote) fetch(ctx context.Context) (map[string]*Flag, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("feature: unable to create request. %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := r.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feature: unable to fetch flags. %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("feature: unable to fetch flags. %s", res.Status)
+	}
+	flags := map[string]*Flag{}
+	if err := json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(&flags); err != nil {
+		return nil, fmt.Errorf("feature: unable to decode flags. %w", err)
+	}
+	return flags, nil
+}
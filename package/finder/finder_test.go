@@ -0,0 +1,124 @@
+package finder_test
+
+import (
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/package/finder"
+	"github.com/livebud/bud/package/vfs"
+)
+
+var fsys = vfs.Memory{
+	"controller/controller.go":       &vfs.File{Data: []byte("package controller")},
+	"controller/users/users.go":      &vfs.File{Data: []byte("package users")},
+	"view/index.svelte":              &vfs.File{Data: []byte("<h1>index</h1>")},
+	"view/layout.svelte":             &vfs.File{Data: []byte("<slot />")},
+	"view/posts/index.svelte":        &vfs.File{Data: []byte("<h1>posts</h1>")},
+	"view/posts/comments/new.svelte": &vfs.File{Data: []byte("<form></form>")},
+	"view/shared/button.svelte":      &vfs.File{Data: []byte("<button />")},
+}
+
+func files(path string, isDir bool) []string {
+	if isDir {
+		return nil
+	}
+	return []string{path}
+}
+
+func find(patterns ...string) ([]string, error) {
+	p, err := finder.Compile(patterns...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Find(fsys, files)
+}
+
+func TestFind(t *testing.T) {
+	is := is.New(t)
+	matches, err := finder.Find(fsys, "controller/**.go", files)
+	is.NoErr(err)
+	is.Equal(matches, []string{"controller/controller.go", "controller/users/users.go"})
+	// * doesn't cross directories
+	matches, err = finder.Find(fsys, "controller/*.go", files)
+	is.NoErr(err)
+	is.Equal(matches, []string{"controller/controller.go"})
+}
+
+func TestFindBraces(t *testing.T) {
+	is := is.New(t)
+	matches, err := finder.Find(fsys, "{controller,view/posts}/*.{go,svelte}", files)
+	is.NoErr(err)
+	is.Equal(matches, []string{"controller/controller.go", "view/posts/index.svelte"})
+}
+
+func TestFindNegate(t *testing.T) {
+	is := is.New(t)
+	matches, err := find("view/**.svelte", "!view/shared/**", "!view/posts/comments/*")
+	is.NoErr(err)
+	is.Equal(matches, []string{"view/index.svelte", "view/layout.svelte", "view/posts/index.svelte"})
+	// Later patterns bring back excluded paths
+	matches, err = find("view/**.svelte", "!view/*/**", "view/shared/**")
+	is.NoErr(err)
+	is.Equal(matches, []string{"view/index.svelte", "view/layout.svelte", "view/shared/button.svelte"})
+	// Only negations select nothing
+	matches, err = find("!view/**")
+	is.NoErr(err)
+	is.Equal(len(matches), 0)
+}
+
+func TestFindDepth(t *testing.T) {
+	is := is.New(t)
+	matches, err := find("view/**{1}/*.svelte")
+	is.NoErr(err)
+	is.Equal(matches, []string{"view/index.svelte", "view/layout.svelte", "view/posts/index.svelte", "view/shared/button.svelte"})
+	matches, err = find("view/**{0}/*.svelte")
+	is.NoErr(err)
+	is.Equal(matches, []string{"view/index.svelte", "view/layout.svelte"})
+	matches, err = find("view/**{2}")
+	is.NoErr(err)
+	is.Equal(matches, []string{"view/index.svelte", "view/layout.svelte", "view/posts/index.svelte", "view/shared/button.svelte"})
+	_, err = find("view/**{0}")
+	is.True(err != nil)
+}
+
+func TestPatternMatch(t *testing.T) {
+	is := is.New(t)
+	p, err := finder.Compile("view/**{1}/*.svelte", "!view/layout.svelte")
+	is.NoErr(err)
+	is.True(p.Match("view/index.svelte"))
+	is.True(p.Match("view/posts/index.svelte"))
+	is.True(!p.Match("view/posts/comments/new.svelte"))
+	is.True(!p.Match("view/layout.svelte"))
+	is.True(!p.Match("controller/controller.go"))
+}
+
+// Find within a tree of 55,986 files
+func BenchmarkFind(b *testing.B) {
+	dir := b.TempDir()
+	if err := testdir.WriteTree(dir, 5, 6, 6); err != nil {
+		b.Fatal(err)
+	}
+	fsys := vfs.OS(dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := finder.Find(fsys, "**/0.go", files); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// Pruning skips the directories that the pattern can't match
+func BenchmarkFindDepth(b *testing.B) {
+	dir := b.TempDir()
+	if err := testdir.WriteTree(dir, 5, 6, 6); err != nil {
+		b.Fatal(err)
+	}
+	fsys := vfs.OS(dir)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := finder.Find(fsys, "**{2}/0.go", files); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package finder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/livebud/bud/internal/glob"
+	"github.com/livebud/bud/internal/orderedset"
+	"github.com/livebud/bud/internal/valid"
+	"github.com/livebud/bud/package/vfs"
+)
+
+// Pattern is a compiled list of globs that select files. Patterns support
+// the usual glob syntax plus the following:
+//
+//   - {a,b} matches either alternative
+//   - !pattern excludes the paths that pattern matches
+//   - **{n} matches at most n directories, so view/**{1}/*.svelte matches
+//     view/index.svelte and view/posts/index.svelte, but not deeper files
+//
+// A * or ? only matches within a path segment, while ** matches across them.
+//
+// Patterns are applied in order and the last pattern that matches a path
+// decides whether it's selected, so a later pattern can bring back paths that
+// an earlier negation excluded.
+type Pattern struct {
+	globs []*globPattern
+	walks []*walk
+}
+
+// globPattern is a single compiled pattern
+type globPattern struct {
+	negate   bool
+	matchers []glob.Matcher // One for each expanded alternative
+}
+
+func (g *globPattern) Match(path string) bool {
+	for _, matcher := range g.matchers {
+		if matcher.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// walk is a directory to search and the deepest it needs to go. A depth of
+// -1 walks the whole tree.
+type walk struct {
+	base  string
+	depth int
+}
+
+// Compile the patterns
+func Compile(patterns ...string) (*Pattern, error) {
+	p := new(Pattern)
+	depths := map[string]int{}
+	var bases []string
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		if negate {
+			pattern = pattern[1:]
+		}
+		expanded, err := capDepths(pattern)
+		if err != nil {
+			return nil, err
+		}
+		expands, err := glob.Expand(expanded)
+		if err != nil {
+			return nil, fmt.Errorf("finder: unable to compile %q. %w", pattern, err)
+		}
+		g := &globPattern{negate: negate}
+		for _, expand := range expands {
+			matcher, err := glob.Compile(expand, '/')
+			if err != nil {
+				return nil, fmt.Errorf("finder: unable to compile %q. %w", pattern, err)
+			}
+			g.matchers = append(g.matchers, matcher)
+			// Only the included paths need to be walked
+			if negate {
+				continue
+			}
+			base := glob.Base(expand)
+			depth := depthOf(base, expand)
+			if prev, ok := depths[base]; !ok {
+				bases = append(bases, base)
+				depths[base] = depth
+			} else if prev >= 0 && (depth < 0 || depth > prev) {
+				depths[base] = depth
+			}
+		}
+		p.globs = append(p.globs, g)
+	}
+	for _, base := range orderedset.Strings(bases...) {
+		p.walks = append(p.walks, &walk{base, depths[base]})
+	}
+	return p, nil
+}
+
+// Match returns true if the path is selected by the patterns
+func (p *Pattern) Match(path string) (match bool) {
+	for _, g := range p.globs {
+		if g.Match(path) {
+			match = !g.negate
+		}
+	}
+	return match
+}
+
+// Find files that match the patterns and are added as entries to the selector.
+// The directories are read concurrently when the filesystem allows it, but the
+// selector is always called one path at a time, in the order they'd be walked.
+func (p *Pattern) Find(fsys fs.FS, selector func(path string, isDir bool) (entries []string)) (matches []string, err error) {
+	var mu sync.Mutex
+	found := map[string]bool{} // path -> isDir
+	// Compute the matches for each base
+	for _, w := range p.walks {
+		w := w
+		// Walk the directory tree, following symlinks and filtering out
+		// non-valid paths
+		err = vfs.WalkParallel(fsys, w.base, valid.WalkDirFunc(func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if p.Match(path) {
+				mu.Lock()
+				found[path] = de.IsDir()
+				mu.Unlock()
+			}
+			// Don't walk deeper than the patterns can match
+			if de.IsDir() && w.depth >= 0 && depthOf(w.base, path) >= w.depth {
+				return fs.SkipDir
+			}
+			return nil
+		}))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+	}
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	vfs.SortWalk(paths)
+	for _, path := range paths {
+		matched := selector(path, found[path])
+		if len(matched) == 0 {
+			continue
+		}
+		// Ensure all matches paths exist
+		if _, err := fs.Stat(fsys, path); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		matches = append(matches, matched...)
+	}
+	return orderedset.Strings(matches...), nil
+}
+
+// depthCap matches **{n}
+var depthCap = regexp.MustCompile(`\*\*\{(\d+)\}(/?)`)
+
+// capDepths rewrites each **{n} into an alternation of up to n directories,
+// so view/**{2}/*.svelte becomes view/{,*/,*/*/}*.svelte
+func capDepths(pattern string) (string, error) {
+	var err error
+	expanded := depthCap.ReplaceAllStringFunc(pattern, func(match string) string {
+		sub := depthCap.FindStringSubmatch(match)
+		n, _ := strconv.Atoi(sub[1])
+		var alts []string
+		// Followed by a directory: match zero to n directories
+		if sub[2] == "/" {
+			for i := 0; i <= n; i++ {
+				alts = append(alts, strings.Repeat("*/", i))
+			}
+			return "{" + strings.Join(alts, ",") + "}"
+		}
+		// Otherwise match one to n path segments
+		if n == 0 {
+			err = fmt.Errorf("finder: unable to compile %q. **{0} must be followed by a \"/\"", pattern)
+			return match
+		}
+		for i := 1; i <= n; i++ {
+			alts = append(alts, strings.TrimSuffix(strings.Repeat("*/", i), "/"))
+		}
+		return "{" + strings.Join(alts, ",") + "}"
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// depthOf returns the number of path segments after base, or -1 if the path
+// contains a ** that can match any depth
+func depthOf(base, path string) int {
+	if base == path {
+		return 0
+	}
+	rel := path
+	if base != "." {
+		rel = strings.TrimPrefix(path, base+"/")
+	}
+	if strings.Contains(rel, "**") {
+		return -1
+	}
+	return strings.Count(rel, "/") + 1
+}
@@ -0,0 +1,188 @@
+// Package fixture loads YAML fixtures into the database. Each file is a list of
+// rows for the table with the same name as the file:
+//
+//	# users.yml
+//	- id: 1
+//	  name: Alice
+//	- id: 2
+//	  name: Bob
+//
+// Files are loaded in lexical order, so prefix them with numbers to insert
+// parent tables before their children (e.g. 1_users.yml, 2_posts.yml).
+package fixture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"gopkg.in/yaml.v3"
+)
+
+// Table of rows loaded from a fixture file
+type Table struct {
+	Name string
+	Rows []Row
+}
+
+// Row maps column names to values
+type Row map[string]interface{}
+
+// Load the fixtures at the root of fsys
+func Load(fsys fs.FS) (tables []*Table, err error) {
+	des, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fixture: unable to read fixtures. %w", err)
+	}
+	seen := map[string]string{}
+	for _, de := range des {
+		ext := path.Ext(de.Name())
+		if de.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, de.Name())
+		if err != nil {
+			return nil, fmt.Errorf("fixture: unable to read %q. %w", de.Name(), err)
+		}
+		table, err := Parse(de.Name(), data)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[table.Name]; ok {
+			return nil, fmt.Errorf("fixture: %q and %q both contain fixtures for the %q table", other, de.Name(), table.Name)
+		}
+		seen[table.Name] = de.Name()
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+var orderPrefix = regexp.MustCompile(`^\d+[_-]`)
+
+// Parse a fixture file. The table name is the file name without the extension
+// or ordering prefix.
+func Parse(filename string, data []byte) (*Table, error) {
+	base := path.Base(filename)
+	table := &Table{
+		Name: orderPrefix.ReplaceAllString(strings.TrimSuffix(base, path.Ext(base)), ""),
+	}
+	var rows []map[string]interface{}
+	if err := yaml.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("fixture: unable to parse %q. Expected a list of rows. %w", filename, err)
+	}
+	for _, row := range rows {
+		for column, value := range row {
+			// Store nested values as JSON
+			switch value.(type) {
+			case map[string]interface{}, []interface{}:
+				data, err := json.Marshal(value)
+				if err != nil {
+					return nil, fmt.Errorf("fixture: unable to encode %q in %q. %w", column, filename, err)
+				}
+				row[column] = string(data)
+			}
+		}
+		table.Rows = append(table.Rows, Row(row))
+	}
+	return table, nil
+}
+
+// Insert the rows into their tables within a transaction
+func Insert(ctx context.Context, db *dbrt.DB, tables ...*Table) error {
+	return db.Transact(ctx, func(ctx context.Context) error {
+		for _, table := range tables {
+			if err := insert(ctx, db, table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func insert(ctx context.Context, db *dbrt.DB, table *Table) error {
+	hasID := false
+	for i, row := range table.Rows {
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		names := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			names[i] = quote(column)
+			args[i] = row[column]
+			if column == "id" {
+				hasID = true
+			}
+		}
+		query := "INSERT INTO " + quote(table.Name) + " DEFAULT VALUES"
+		if len(columns) > 0 {
+			query = "INSERT INTO " + quote(table.Name) + " (" + strings.Join(names, ", ") + ") VALUES (" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		}
+		if _, err := db.ExecContext(ctx, db.Dialect().Rebind(query), args...); err != nil {
+			return fmt.Errorf("fixture: unable to insert row %d into %q. %w", i+1, table.Name, err)
+		}
+	}
+	// Postgres doesn't advance sequences when ids are inserted explicitly, so
+	// move the sequence past the fixtures
+	if hasID && db.Dialect() == dbrt.Postgres {
+		query := `SELECT setval(pg_get_serial_sequence($1, 'id'), MAX("id")) FROM ` + quote(table.Name)
+		if _, err := db.ExecContext(ctx, query, table.Name); err != nil {
+			return fmt.Errorf("fixture: unable to reset the id sequence of %q. %w", table.Name, err)
+		}
+	}
+	return nil
+}
+
+// Truncate deletes every row in the tables. Tables are truncated in reverse
+// order, so children are deleted before their parents.
+func Truncate(ctx context.Context, db *dbrt.DB, names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	return db.Transact(ctx, func(ctx context.Context) error {
+		if db.Dialect() == dbrt.Postgres {
+			quoted := make([]string, len(names))
+			for i, name := range names {
+				quoted[i] = quote(name)
+			}
+			if _, err := db.ExecContext(ctx, "TRUNCATE "+strings.Join(quoted, ", ")+" RESTART IDENTITY CASCADE"); err != nil {
+				return fmt.Errorf("fixture: unable to truncate %s. %w", strings.Join(names, ", "), err)
+			}
+			return nil
+		}
+		for i := len(names) - 1; i >= 0; i-- {
+			if _, err := db.ExecContext(ctx, "DELETE FROM "+quote(names[i])); err != nil {
+				return fmt.Errorf("fixture: unable to truncate %q. %w", names[i], err)
+			}
+		}
+		return nil
+	})
+}
+
+// Reload truncates the tables and inserts their rows again within a single
+// transaction
+func Reload(ctx context.Context, db *dbrt.DB, tables ...*Table) error {
+	names := make([]string, len(tables))
+	for i, table := range tables {
+		names[i] = table.Name
+	}
+	return db.Transact(ctx, func(ctx context.Context) error {
+		if err := Truncate(ctx, db, names...); err != nil {
+			return err
+		}
+		return Insert(ctx, db, tables...)
+	})
+}
+
+// quote an identifier. Double quotes work for both Postgres and SQLite.
+func quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
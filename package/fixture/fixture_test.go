@@ -0,0 +1,101 @@
+package fixture_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/fixture"
+)
+
+func open(t testing.TB) *dbrt.DB {
+	t.Helper()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	_, err = db.Exec(`
+		CREATE TABLE users (id integer primary key, name text not null, settings text);
+		CREATE TABLE posts (id integer primary key, user_id integer not null references users (id), title text not null);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func count(t testing.TB, db *dbrt.DB, table string) (n int) {
+	t.Helper()
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+var fsys = fstest.MapFS{
+	"1_users.yml": &fstest.MapFile{Data: []byte(`
+- id: 1
+  name: Alice
+  settings:
+    theme: dark
+- id: 2
+  name: Bob
+`)},
+	"2_posts.yaml": &fstest.MapFile{Data: []byte(`
+- user_id: 1
+  title: Hello
+`)},
+	"Readme.md": &fstest.MapFile{Data: []byte(`# Fixtures`)},
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	tables, err := fixture.Load(fsys)
+	is.NoErr(err)
+	is.Equal(len(tables), 2)
+	is.Equal(tables[0].Name, "users")
+	is.Equal(len(tables[0].Rows), 2)
+	is.Equal(tables[0].Rows[0]["name"], "Alice")
+	is.Equal(tables[0].Rows[0]["settings"], `{"theme":"dark"}`)
+	is.Equal(tables[1].Name, "posts")
+}
+
+func TestLoadDuplicate(t *testing.T) {
+	is := is.New(t)
+	_, err := fixture.Load(fstest.MapFS{
+		"1_users.yml": &fstest.MapFile{Data: []byte(`- name: Alice`)},
+		"users.yml":   &fstest.MapFile{Data: []byte(`- name: Bob`)},
+	})
+	is.True(err != nil)
+	is.In(err.Error(), `both contain fixtures for the "users" table`)
+}
+
+func TestParseInvalid(t *testing.T) {
+	is := is.New(t)
+	_, err := fixture.Parse("users.yml", []byte(`name: Alice`))
+	is.True(err != nil)
+	is.In(err.Error(), `unable to parse "users.yml". Expected a list of rows`)
+}
+
+func TestInsert(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := open(t)
+	tables, err := fixture.Load(fsys)
+	is.NoErr(err)
+	is.NoErr(fixture.Insert(ctx, db, tables...))
+	is.Equal(count(t, db, "users"), 2)
+	is.Equal(count(t, db, "posts"), 1)
+	var settings string
+	is.NoErr(db.QueryRow(`SELECT settings FROM users WHERE id = 1`).Scan(&settings))
+	is.Equal(settings, `{"theme":"dark"}`)
+	// Inserting again conflicts and nothing is inserted
+	err = fixture.Insert(ctx, db, tables...)
+	is.True(err != nil)
+	is.In(err.Error(), `unable to insert row 1 into "users"`)
+	is.Equal(count(t, db, "users"), 2)
+}
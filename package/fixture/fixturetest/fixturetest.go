@@ -0,0 +1,25 @@
+// Package fixturetest loads fixtures in tests. It's kept apart from the fixture
+// package, so apps that seed their database don't import testing.
+package fixturetest
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/fixture"
+)
+
+// Setup reloads the fixtures in fsys before a test, so each test starts with
+// the same data
+func Setup(t testing.TB, db *dbrt.DB, fsys fs.FS) {
+	t.Helper()
+	tables, err := fixture.Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.Reload(context.Background(), db, tables...); err != nil {
+		t.Fatal(err)
+	}
+}
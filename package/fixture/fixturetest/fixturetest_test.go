@@ -0,0 +1,43 @@
+package fixturetest_test
+
+import (
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/fixture/fixturetest"
+)
+
+func count(t testing.TB, db *dbrt.DB, table string) (n int) {
+	t.Helper()
+	if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSetupReloads(t *testing.T) {
+	is := is.New(t)
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE users (id integer primary key, name text not null);
+		CREATE TABLE posts (id integer primary key, user_id integer not null references users (id), title text not null);
+	`)
+	is.NoErr(err)
+	fsys := fstest.MapFS{
+		"1_users.yml": &fstest.MapFile{Data: []byte("- id: 1\n  name: Alice\n- id: 2\n  name: Bob\n")},
+		"2_posts.yml": &fstest.MapFile{Data: []byte("- user_id: 1\n  title: Hello\n")},
+	}
+	fixturetest.Setup(t, db, fsys)
+	_, err = db.Exec(`INSERT INTO users (name) VALUES ('Eve')`)
+	is.NoErr(err)
+	_, err = db.Exec(`DELETE FROM posts`)
+	is.NoErr(err)
+	fixturetest.Setup(t, db, fsys)
+	is.Equal(count(t, db, "users"), 2)
+	is.Equal(count(t, db, "posts"), 1)
+}
@@ -0,0 +1,215 @@
+// Package golden tests generators against golden files. Generators run
+// against an app in memory and their output is compared with the files in
+// testdata. Run the tests with -update to create or update the golden files.
+//
+//	func TestGenerate(t *testing.T) {
+//		app := golden.New(t, map[string]string{
+//			"controller/controller.go": "package controller\ntype Controller struct {}",
+//		})
+//		code := app.Generate(t, "bud/internal/routes.go", routes.New(app.Module, app.Parser))
+//		golden.TestFile(t, "routes.go", code)
+//	}
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/console"
+	"github.com/livebud/bud/package/parser"
+	"github.com/livebud/bud/package/virtual"
+	"github.com/matthewmueller/diff"
+
+	"golang.org/x/tools/txtar"
+)
+
+var shouldUpdate = flag.Bool("update", false, "update golden files")
+
+// Update reports whether the tests were run with -update
+func Update() bool {
+	return *shouldUpdate
+}
+
+// App in memory that generators run against
+type App struct {
+	Module *gomod.Module
+	Parser *parser.Parser
+	fsys   *budfs.FileSystem
+}
+
+// New app with the files, a map of paths to their contents. The go.mod
+// defaults to "module app.com".
+func New(t testing.TB, files map[string]string) *App {
+	t.Helper()
+	tree := virtual.Tree{}
+	for path, data := range files {
+		tree[path] = &virtual.File{Data: []byte(data), Mode: 0644}
+	}
+	if _, ok := tree["go.mod"]; !ok {
+		tree["go.mod"] = &virtual.File{Data: []byte("module app.com\n"), Mode: 0644}
+	}
+	dir := t.TempDir()
+	module, err := gomod.Parse(filepath.Join(dir, "go.mod"), tree["go.mod"].Data)
+	if err != nil {
+		t.Fatalf("golden: unable to parse go.mod. %s", err)
+	}
+	fsys := budfs.New(tree, log.New(console.New(io.Discard)))
+	t.Cleanup(func() { fsys.Close() })
+	return &App{
+		Module: module,
+		Parser: parser.New(fsys, module),
+		fsys:   fsys,
+	}
+}
+
+// FS returns the app's filesystem, including generated files
+func (a *App) FS() fs.FS {
+	return a.fsys
+}
+
+// Generate the file at path with the generator
+func (a *App) Generate(t testing.TB, path string, generator budfs.FileGenerator) []byte {
+	t.Helper()
+	a.fsys.FileGenerator(path, generator)
+	code, err := fs.ReadFile(a.fsys, path)
+	if err != nil {
+		t.Fatalf("golden: unable to generate %s. %s", path, err)
+	}
+	return code
+}
+
+// GenerateDir generates the directory with the generator and returns every
+// file within it
+func (a *App) GenerateDir(t testing.TB, dir string, generator budfs.DirGenerator) *txtar.Archive {
+	t.Helper()
+	a.fsys.DirGenerator(dir, generator)
+	archive := new(txtar.Archive)
+	err := fs.WalkDir(a.fsys, dir, func(path string, de fs.DirEntry, err error) error {
+		if err != nil || de.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(a.fsys, path)
+		if err != nil {
+			return err
+		}
+		archive.Files = append(archive.Files, txtar.File{Name: path, Data: data})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("golden: unable to generate %s. %s", dir, err)
+	}
+	return archive
+}
+
+// TestGenerator compares the generator's state and code with the golden file
+func TestGenerator(t testing.TB, state interface{}, code []byte) {
+	t.Helper()
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		t.Fatalf("golden: unable to marshal state. %s", err)
+	}
+	Test(t, &txtar.Archive{
+		Files: []txtar.File{
+			{
+				Name: "state.json",
+				Data: data,
+			},
+			{
+				Name: "code.txt",
+				Data: code,
+			},
+		},
+	})
+}
+
+// TestFile compares a single file with the golden file
+func TestFile(t testing.TB, name string, data []byte) {
+	t.Helper()
+	Test(t, &txtar.Archive{
+		Files: []txtar.File{{Name: name, Data: data}},
+	})
+}
+
+// Test compares the files with the golden file in testdata/<test name>.golden
+func Test(t testing.TB, actual *txtar.Archive) {
+	t.Helper()
+	filename := filepath.Join("testdata", t.Name()+".golden")
+	formatted := txtar.Format(actual)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if len(formatted) == 0 {
+			return
+		}
+		data = []byte("")
+	}
+	if bytes.Equal(data, formatted) {
+		return
+	}
+	if *shouldUpdate {
+		if err := writeFile(filename, formatted); err != nil {
+			t.Fatalf("golden: unable to write golden file %s. %s", filename, err)
+		}
+		t.Logf("golden: updated %s", filename)
+		return
+	}
+	t.Fatalf("golden: %s has unexpected changes. Run the tests with -update if they're expected.\n%s",
+		filename, difference(txtar.Parse(data), txtar.Parse(formatted)))
+}
+
+func writeFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(name, data, 0644)
+}
+
+// difference diffs the archives file by file
+func difference(expected, actual *txtar.Archive) string {
+	var b bytes.Buffer
+	seen := map[string]bool{}
+	for _, file := range actual.Files {
+		seen[file.Name] = true
+		data, ok := find(expected, file.Name)
+		if !ok {
+			b.WriteString("\n\x1b[4mAdded " + file.Name + "\x1b[0m:\n")
+			b.Write(file.Data)
+			continue
+		}
+		if bytes.Equal(data, file.Data) {
+			continue
+		}
+		b.WriteString("\n\x1b[4mChanged " + file.Name + "\x1b[0m:\n")
+		b.WriteString(diff.String(string(data), string(file.Data)))
+		b.WriteString("\n")
+	}
+	for _, file := range expected.Files {
+		if !seen[file.Name] {
+			b.WriteString("\n\x1b[4mRemoved " + file.Name + "\x1b[0m\n")
+		}
+	}
+	if !bytes.Equal(expected.Comment, actual.Comment) {
+		b.WriteString("\n\x1b[4mChanged comment\x1b[0m:\n")
+		b.WriteString(diff.String(string(expected.Comment), string(actual.Comment)))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func find(archive *txtar.Archive, name string) ([]byte, bool) {
+	for _, file := range archive.Files {
+		if path.Clean(file.Name) == path.Clean(name) {
+			return file.Data, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,118 @@
+package golden_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/budfs"
+	"github.com/livebud/bud/package/golden"
+	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/parser"
+	"golang.org/x/tools/txtar"
+)
+
+// routes generates a route for each of the controller's methods
+type routes struct {
+	module *gomod.Module
+	parser *parser.Parser
+}
+
+func (r *routes) GenerateFile(fsys budfs.FS, file *budfs.File) error {
+	pkg, err := r.parser.Parse("controller")
+	if err != nil {
+		return err
+	}
+	stct := pkg.Struct("Controller")
+	if stct == nil {
+		return fmt.Errorf("routes: missing controller")
+	}
+	var b strings.Builder
+	b.WriteString("package routes\n\n")
+	fmt.Fprintf(&b, "// Controller is %q\n", r.module.Import("controller"))
+	b.WriteString("var Routes = []string{\n")
+	for _, method := range stct.PublicMethods() {
+		fmt.Fprintf(&b, "\t%q,\n", method.Name())
+	}
+	b.WriteString("}\n")
+	file.Data = []byte(b.String())
+	return nil
+}
+
+func TestGenerate(t *testing.T) {
+	is := is.New(t)
+	app := golden.New(t, map[string]string{
+		"controller/controller.go": `
+			package controller
+			type Controller struct {}
+			func (c *Controller) Index() {}
+			func (c *Controller) Show(id int) {}
+		`,
+	})
+	code := app.Generate(t, "bud/internal/routes/routes.go", &routes{app.Module, app.Parser})
+	is.True(strings.Contains(string(code), `"app.com/controller"`))
+	golden.TestFile(t, "routes.go", code)
+}
+
+func TestGenerateDir(t *testing.T) {
+	app := golden.New(t, map[string]string{
+		"go.mod": "module example.com/blog\n",
+		"controller/controller.go": `
+			package controller
+			type Controller struct {}
+			func (c *Controller) Index() {}
+		`,
+	})
+	archive := app.GenerateDir(t, "bud/internal/routes", budfs.GenerateDir(func(fsys budfs.FS, dir *budfs.Dir) error {
+		dir.FileGenerator("routes.go", &routes{app.Module, app.Parser})
+		dir.GenerateFile("README.md", func(fsys budfs.FS, file *budfs.File) error {
+			file.Data = []byte("# Routes\n")
+			return nil
+		})
+		return nil
+	}))
+	golden.Test(t, archive)
+}
+
+func TestGenerator(t *testing.T) {
+	golden.TestGenerator(t, map[string]string{"name": "routes"}, []byte("package routes\n"))
+}
+
+// recorder records failures instead of failing the test
+type recorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestDifference(t *testing.T) {
+	if golden.Update() {
+		t.Skip("the golden file must stay out of date")
+	}
+	is := is.New(t)
+	rec := &recorder{TB: t}
+	// Compared against testdata/TestDifference.golden
+	golden.Test(rec, &txtar.Archive{
+		Files: []txtar.File{
+			{Name: "a.go", Data: []byte("package a\n\nvar A = 2\n")},
+			{Name: "c.go", Data: []byte("package c\n")},
+		},
+	})
+	is.True(strings.Contains(rec.failure, "testdata/TestDifference.golden has unexpected changes"))
+	is.True(strings.Contains(rec.failure, "Changed a.go"))
+	is.True(strings.Contains(rec.failure, "Removed b.go"))
+	is.True(strings.Contains(rec.failure, "Added c.go"))
+	is.True(!strings.Contains(rec.failure, "Changed c.go"))
+}
+
+func TestMissingGenerator(t *testing.T) {
+	is := is.New(t)
+	app := golden.New(t, map[string]string{})
+	rec := &recorder{TB: t}
+	app.Generate(rec, "bud/internal/routes/routes.go", &routes{app.Module, app.Parser})
+	is.True(strings.Contains(rec.failure, "golden: unable to generate bud/internal/routes/routes.go"))
+}
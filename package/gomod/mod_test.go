@@ -136,6 +136,23 @@ func TestResolveImport(t *testing.T) {
 	is.Equal(module.Import("package", base), im)
 }
 
+func TestResolveImportSymlink(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"app/go.mod":          []byte("module app.com"),
+		"app/view/index.html": []byte("<h1>index</h1>"),
+	})
+	is.NoErr(err)
+	link := filepath.Join(dir, "link")
+	is.NoErr(os.Symlink(filepath.Join(dir, "app"), link))
+	module, err := gomod.Find(link)
+	is.NoErr(err)
+	im, err := module.ResolveImport(filepath.Join(link, "view"))
+	is.NoErr(err)
+	is.Equal(im, "app.com/view")
+}
+
 func TestModuleFindStdlib(t *testing.T) {
 	is := is.New(t)
 	wd, err := os.Getwd()
@@ -11,6 +11,7 @@ import (
 	"github.com/cespare/xxhash"
 	"github.com/livebud/bud/internal/gois"
 	"github.com/livebud/bud/internal/goroot"
+	"github.com/livebud/bud/package/vfs"
 	"github.com/livebud/bud/package/virtual"
 )
 
@@ -91,12 +92,29 @@ func (m *Module) ReadDir(name string) ([]fs.DirEntry, error) {
 	return os.ReadDir(filepath.Join(m.dir, name))
 }
 
-// ResolveImport returns an import path from a local directory.
+var _ vfs.ConcurrentFS = (*Module)(nil)
+
+// Concurrent is true because the module is read straight from the OS
+func (m *Module) Concurrent() bool {
+	return true
+}
+
+// ResolveImport returns an import path from a local directory. The module
+// directory has its symlinks resolved, so the directory may need the same.
 func (m *Module) ResolveImport(directory string) (importPath string, err error) {
 	relPath, err := filepath.Rel(m.dir, filepath.Clean(directory))
 	if err != nil {
 		return "", err
-	} else if strings.HasPrefix(relPath, "..") {
+	}
+	if strings.HasPrefix(relPath, "..") {
+		if realDir, err := filepath.EvalSymlinks(directory); err == nil {
+			relPath, err = filepath.Rel(m.dir, realDir)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	if strings.HasPrefix(relPath, "..") {
 		return "", fmt.Errorf("%q can't be outside the module directory %q", directory, m.dir)
 	}
 	return m.Import(relPath), nil
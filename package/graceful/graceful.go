@@ -0,0 +1,40 @@
+// Package graceful gives work that's running time to finish after a shutdown
+// starts.
+package graceful
+
+import (
+	"context"
+	"time"
+)
+
+// Context returns a context that carries the parent's values but outlives the
+// parent by the grace period. Pass it to work that should finish up, rather
+// than stop, when the parent is canceled. Call cancel once the work is done.
+func Context(parent context.Context, grace time.Duration) (ctx context.Context, cancel context.CancelFunc) {
+	ctx, cancel = context.WithCancel(detached{parent})
+	go func() {
+		select {
+		case <-ctx.Done():
+			return
+		case <-parent.Done():
+		}
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+			cancel()
+		}
+	}()
+	return ctx, cancel
+}
+
+// detached keeps the parent's values without its cancellation
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detached) Done() <-chan struct{}               { return nil }
+func (detached) Err() error                          { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,42 @@
+package graceful_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/graceful"
+)
+
+type key struct{}
+
+func TestOutlivesParent(t *testing.T) {
+	is := is.New(t)
+	parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	ctx, cancel := graceful.Context(parent, 50*time.Millisecond)
+	defer cancel()
+	is.Equal(ctx.Value(key{}), "value")
+	cancelParent()
+	// Still running during the grace period
+	select {
+	case <-ctx.Done():
+		t.Fatal("canceled before the grace period ended")
+	case <-time.After(10 * time.Millisecond):
+	}
+	// Canceled once the grace period ends
+	select {
+	case <-ctx.Done():
+		is.Equal(ctx.Err(), context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("not canceled after the grace period")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := graceful.Context(context.Background(), time.Hour)
+	cancel()
+	<-ctx.Done()
+	is.Equal(ctx.Err(), context.Canceled)
+}
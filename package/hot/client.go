@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/http"
@@ -35,6 +36,10 @@ func DialWith(client *http.Client, log log.Interface, url string) (*Stream, erro
 	if err != nil {
 		return nil, err
 	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("hot: unable to stream events from %q. %s", url, res.Status)
+	}
 	stream := &Stream{
 		log:     log,
 		res:     res,
@@ -0,0 +1,37 @@
+package hot
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// Event is a server-sent event (SSE). The dev server streams its events to the
+// browser and the app on /bud/events.
+//
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+type Event struct {
+	ID    string // id (optional)
+	Type  string // event type (optional)
+	Data  []byte // data
+	Retry int    // retry (optional)
+}
+
+// Format the event for the event stream. The data line is always written
+// because browsers don't dispatch events with an empty data buffer.
+func (e *Event) Format() *bytes.Buffer {
+	b := new(bytes.Buffer)
+	if e.ID != "" {
+		b.WriteString("id: " + e.ID + "\n")
+	}
+	if e.Type != "" {
+		b.WriteString("event: " + e.Type + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(e.Data)
+	b.WriteByte('\n')
+	if e.Retry > 0 {
+		b.WriteString("retry: " + strconv.Itoa(e.Retry) + "\n")
+	}
+	b.WriteByte('\n')
+	return b
+}
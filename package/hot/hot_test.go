@@ -12,91 +12,48 @@ import (
 
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/internal/pubsub"
+	"github.com/livebud/bud/package/budhttp/budsvr"
 	"github.com/livebud/bud/package/hot"
 	"github.com/livebud/bud/package/log/testlog"
 	"github.com/livebud/bud/package/socket"
+	"github.com/livebud/bud/package/virtual"
 )
 
-var now = time.Date(2021, 8, 4, 14, 56, 0, 0, time.UTC)
-
-func TestNoPathUpdate(t *testing.T) {
+func TestEvents(t *testing.T) {
 	is := is.New(t)
 	log := testlog.New()
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
-	testServer := httptest.NewServer(hotServer)
-	hotClient, err := hot.Dial(log, testServer.URL)
-	is.NoErr(err)
-	ps.Publish("frontend:update", nil)
-	event, err := hotClient.Next(ctx)
-	is.NoErr(err)
-	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"reload":true}`)
-	is.Equal(event.Retry, 0)
-	is.NoErr(hotClient.Close())
-	testServer.Close()
-}
-
-func TestPage(t *testing.T) {
-	is := is.New(t)
-	log := testlog.New()
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
-	testServer := httptest.NewServer(hotServer)
-	hotClient, err := hot.Dial(log, testServer.URL+"/bud/hot/view/index.svelte")
+	testServer := httptest.NewServer(budsvr.New(virtual.Map{}, ps, log, nil))
+	defer testServer.Close()
+	hotClient, err := hot.Dial(log, testServer.URL+"/bud/events?topics=frontend:update:view/index.svelte,frontend:update:css,backend:update")
 	is.NoErr(err)
 	ps.Publish("frontend:update:view/index.svelte", nil)
 	event, err := hotClient.Next(ctx)
 	is.NoErr(err)
 	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=1628088960000"]}`)
+	is.Equal(event.Type, "frontend:update:view/index.svelte")
+	is.Equal(string(event.Data), "")
 	is.Equal(event.Retry, 0)
-	ps.Publish("frontend:update:view/index.svelte", nil)
+	ps.Publish("frontend:update:css", []byte(`["/main.css","/css/theme.css"]`))
 	event, err = hotClient.Next(ctx)
 	is.NoErr(err)
-	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=1628088960000"]}`)
-	is.Equal(event.Retry, 0)
-	ps.Publish("frontend:update", nil)
+	is.Equal(event.Type, "frontend:update:css")
+	is.Equal(string(event.Data), `["/main.css","/css/theme.css"]`)
+	ps.Publish("backend:update", nil)
 	event, err = hotClient.Next(ctx)
 	is.NoErr(err)
-	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"scripts":["/bud/view/index.svelte?ts=1628088960000"]}`)
-	is.Equal(event.Retry, 0)
+	is.Equal(event.Type, "backend:update")
 	is.NoErr(hotClient.Close())
-	testServer.Close()
 }
 
-func TestReload(t *testing.T) {
+func TestFormat(t *testing.T) {
 	is := is.New(t)
-	log := testlog.New()
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
-	testServer := httptest.NewServer(hotServer)
-	hotClient, err := hot.Dial(log, testServer.URL+`/bud/hot/view/index.svelte`)
-	is.NoErr(err)
-	ps.Publish("backend:update", nil)
-	event, err := hotClient.Next(ctx)
-	is.NoErr(err)
-	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"reload":true}`)
-	is.Equal(event.Retry, 0)
-	is.NoErr(hotClient.Close())
-	testServer.Close()
+	event := &hot.Event{Type: "app:ready"}
+	is.Equal(event.Format().String(), "event: app:ready\ndata: \n\n")
+	event = &hot.Event{ID: "1", Type: "build:error", Data: []byte(`"oops"`), Retry: 1000}
+	is.Equal(event.Format().String(), "id: 1\nevent: build:error\ndata: \"oops\"\nretry: 1000\n\n")
 }
 
 // TODO: consolidate function. This is duplicated in multiple places.
@@ -127,23 +84,21 @@ func TestUnixListener(t *testing.T) {
 	listener, client, err := listen(filepath.Join(t.TempDir(), "test.sock"))
 	is.NoErr(err)
 	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
 	server := &http.Server{
 		Addr:    listener.Addr().String(),
-		Handler: hotServer,
+		Handler: budsvr.New(virtual.Map{}, ps, log, nil),
 	}
 	defer server.Shutdown(ctx)
 	eg := new(errgroup.Group)
 	eg.Go(func() error { return server.Serve(listener) })
-	hotClient, err := hot.DialWith(client, log, "http://host/")
+	hotClient, err := hot.DialWith(client, log, "http://host/bud/events?topics=frontend:update")
 	is.NoErr(err)
 	ps.Publish("frontend:update", nil)
 	event, err := hotClient.Next(ctx)
 	is.NoErr(err)
 	is.Equal(event.ID, "")
-	is.Equal(event.Type, "")
-	is.Equal(string(event.Data), `{"reload":true}`)
+	is.Equal(event.Type, "frontend:update")
+	is.Equal(string(event.Data), "")
 	is.Equal(event.Retry, 0)
 	is.NoErr(hotClient.Close())
 	is.NoErr(server.Shutdown(ctx))
@@ -157,16 +112,14 @@ func TestNoWait(t *testing.T) {
 	listener, client, err := listen(filepath.Join(t.TempDir(), "test.sock"))
 	is.NoErr(err)
 	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
 	server := &http.Server{
 		Addr:    listener.Addr().String(),
-		Handler: hotServer,
+		Handler: budsvr.New(virtual.Map{}, ps, log, nil),
 	}
 	defer server.Shutdown(ctx)
 	eg := new(errgroup.Group)
 	eg.Go(func() error { return server.Serve(listener) })
-	hotClient, err := hot.DialWith(client, log, "http://host/")
+	hotClient, err := hot.DialWith(client, log, "http://host/bud/events?topics=frontend:update")
 	is.NoErr(err)
 	is.NoErr(hotClient.Close())
 }
@@ -179,16 +132,14 @@ func TestDrainBeforeClose(t *testing.T) {
 	listener, client, err := listen(filepath.Join(t.TempDir(), "test.sock"))
 	is.NoErr(err)
 	ps := pubsub.New()
-	hotServer := hot.New(log, ps)
-	hotServer.Now = func() time.Time { return now }
 	server := &http.Server{
 		Addr:    listener.Addr().String(),
-		Handler: hotServer,
+		Handler: budsvr.New(virtual.Map{}, ps, log, nil),
 	}
 	defer server.Shutdown(ctx)
 	eg := new(errgroup.Group)
 	eg.Go(func() error { return server.Serve(listener) })
-	hotClient, err := hot.DialWith(client, log, "http://host/")
+	hotClient, err := hot.DialWith(client, log, "http://host/bud/events?topics=frontend:update")
 	is.NoErr(err)
 	ps.Publish("frontend:update", nil)
 	ps.Publish("frontend:update", nil)
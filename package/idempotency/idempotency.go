@@ -0,0 +1,277 @@
+// Package idempotency makes mutations safe to retry. Clients send a unique
+// Idempotency-Key header with POST, PUT, PATCH and DELETE requests. The first
+// response for a key is recorded and replayed when the request is retried,
+// so retrying a payment doesn't charge twice:
+//
+//	func (c *Controller) Create(ctx context.Context, amount int) (*Payment, error) {
+//		if err := idempotency.Require(ctx); err != nil {
+//			return nil, err
+//		}
+//		// ...charge the card...
+//	}
+//
+// Keys are scoped to the request's method and path. Keys should be random,
+// like a UUID, so they can't be guessed.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/redis"
+)
+
+// Header holds the idempotency key
+const Header = "Idempotency-Key"
+
+// ErrMissingKey is returned by Require when the request doesn't have an
+// idempotency key
+var ErrMissingKey = errors.New("idempotency: missing the Idempotency-Key header")
+
+// Load the middleware from the environment
+func Load() (*Middleware, error) {
+	store, err := LoadStore(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	middleware := New(store)
+	if value := os.Getenv("IDEMPOTENCY_TTL"); value != "" {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("idempotency: expected IDEMPOTENCY_TTL to be a duration like 24h, got %q", value)
+		}
+		middleware.TTL = ttl
+	}
+	return middleware, nil
+}
+
+// LoadStore loads the store from the environment:
+//
+//	IDEMPOTENCY_STORE=memory
+//
+// IDEMPOTENCY_STORE may be memory, redis or sql. The Redis store connects
+// using REDIS_URL and the SQL store connects using DATABASE_URL.
+func LoadStore(getenv func(key string) string) (Store, error) {
+	switch store := getenv("IDEMPOTENCY_STORE"); store {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		config, err := redis.LoadConfig(getenv)
+		if err != nil {
+			return nil, err
+		}
+		client, err := redis.Dial(config)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisStore(client), nil
+	case "sql":
+		databaseURL := getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("idempotency: the sql store requires the DATABASE_URL environment variable")
+		}
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		return NewSQLStore(db), nil
+	default:
+		return nil, fmt.Errorf("idempotency: expected IDEMPOTENCY_STORE to be memory, redis or sql, got %q", store)
+	}
+}
+
+// New middleware that records responses in the store
+func New(store Store) *Middleware {
+	return &Middleware{
+		TTL:     24 * time.Hour,
+		Timeout: time.Minute,
+		store:   store,
+	}
+}
+
+// Middleware records and replays responses by their idempotency key
+type Middleware struct {
+	// TTL is how long responses are replayed for
+	TTL time.Duration
+	// Timeout is how long a request holds its key. Retries that arrive while
+	// the key is held get a 409 Conflict. Once the timeout passes, like when
+	// the server crashed, retries run the request again.
+	Timeout time.Duration
+	store   Store
+}
+
+// Response is a recorded response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Fingerprint of the request body, so the key can't be reused for a
+	// different request
+	Fingerprint string `json:"fingerprint"`
+}
+
+type contextKey struct{}
+
+// Middleware replays the recorded response for requests with a key that was
+// already used. Responses with a 5xx status aren't recorded, so those
+// requests can be retried. Requests without a key pass through.
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if key == "" || !isMutation(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := readBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fingerprint := sha256.Sum256(body)
+		scoped := scope(r.Method, r.URL.Path, key)
+		ctx := r.Context()
+		reserved, err := m.store.Reserve(ctx, scoped, time.Now().Add(m.Timeout))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !reserved {
+			m.replay(w, r, scoped, hex.EncodeToString(fingerprint[:]))
+			return
+		}
+		recorder := &recorder{ResponseWriter: w, status: http.StatusOK}
+		// Release the key when the request fails, so it can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				m.store.Delete(context.Background(), scoped)
+			}
+		}()
+		next.ServeHTTP(recorder, r.WithContext(context.WithValue(ctx, contextKey{}, key)))
+		recorder.WriteHeader(http.StatusOK)
+		if recorder.status >= 500 {
+			return
+		}
+		res := &Response{
+			Status:      recorder.status,
+			Header:      recorder.header,
+			Body:        recorder.body.Bytes(),
+			Fingerprint: hex.EncodeToString(fingerprint[:]),
+		}
+		if err := m.store.Save(context.Background(), scoped, res, time.Now().Add(m.TTL)); err != nil {
+			return
+		}
+		completed = true
+	})
+}
+
+// replay the recorded response
+func (m *Middleware) replay(w http.ResponseWriter, r *http.Request, key, fingerprint string) {
+	res, err := m.store.Load(r.Context(), key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if res == nil {
+		http.Error(w, "idempotency: a request with this key is in progress", http.StatusConflict)
+		return
+	}
+	if res.Fingerprint != fingerprint {
+		http.Error(w, "idempotency: the key was already used for a different request", http.StatusUnprocessableEntity)
+		return
+	}
+	header := w.Header()
+	for key, values := range res.Header {
+		header[key] = values
+	}
+	header.Set("Idempotent-Replayed", "true")
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// readBody reads the request body, leaving it in place for the handler. Forms
+// that were already parsed, like by the method override middleware, are read
+// from the parsed values.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.PostForm != nil {
+		return []byte(r.PostForm.Encode()), nil
+	}
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// scope the key to the method and path
+func scope(method, path, key string) string {
+	hash := sha256.Sum256([]byte(method + " " + path + " " + key))
+	return hex.EncodeToString(hash[:])
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// Key returns the request's idempotency key. It returns false when the
+// request doesn't have one.
+func Key(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(contextKey{}).(string)
+	return key, ok
+}
+
+// Require returns ErrMissingKey unless the request has an idempotency key. Use
+// it in actions that shouldn't run twice.
+func Require(ctx context.Context) error {
+	if _, ok := Key(ctx); !ok {
+		return ErrMissingKey
+	}
+	return nil
+}
+
+// recorder writes the response while keeping a copy
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	header      http.Header
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *recorder) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.status = status
+	// Copy the headers before the outer middleware adds theirs, like the
+	// session cookie
+	w.header = w.Header().Clone()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recorder) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
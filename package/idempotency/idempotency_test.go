@@ -0,0 +1,198 @@
+package idempotency_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/idempotency"
+	"github.com/livebud/bud/package/middleware"
+)
+
+func send(h http.Handler, method, path, key, body string) *http.Response {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(idempotency.Header, key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Result()
+}
+
+func body(res *http.Response) string {
+	b, _ := io.ReadAll(res.Body)
+	return string(b)
+}
+
+// charge counts the number of charges
+func charge(count *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := idempotency.Require(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		amount, _ := io.ReadAll(r.Body)
+		n := atomic.AddInt32(count, 1)
+		w.Header().Set("Location", fmt.Sprintf("/payments/%d", n))
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "charged %s", amount)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	h := idempotency.New(idempotency.NewMemoryStore()).Middleware(charge(&count))
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	is.Equal(body(res), "charged 10")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "")
+	// Retries replay the response
+	res = send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	is.Equal(body(res), "charged 10")
+	is.Equal(res.Header.Get("Location"), "/payments/1")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "true")
+	is.Equal(atomic.LoadInt32(&count), int32(1))
+	// Keys are scoped to the path
+	res = send(h, http.MethodPost, "/refunds", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	is.Equal(atomic.LoadInt32(&count), int32(2))
+	// A new key charges again
+	res = send(h, http.MethodPost, "/payments", "def", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	is.Equal(res.Header.Get("Location"), "/payments/3")
+}
+
+func TestMissingKey(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	h := idempotency.New(idempotency.NewMemoryStore()).Middleware(charge(&count))
+	res := send(h, http.MethodPost, "/payments", "", "10")
+	is.Equal(res.StatusCode, http.StatusBadRequest)
+	is.True(strings.Contains(body(res), idempotency.ErrMissingKey.Error()))
+	// Reads don't use the key
+	res = send(h, http.MethodGet, "/payments", "abc", "")
+	is.Equal(res.StatusCode, http.StatusBadRequest)
+	is.Equal(atomic.LoadInt32(&count), int32(0))
+}
+
+func TestDifferentBody(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	h := idempotency.New(idempotency.NewMemoryStore()).Middleware(charge(&count))
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	res = send(h, http.MethodPost, "/payments", "abc", "20")
+	is.Equal(res.StatusCode, http.StatusUnprocessableEntity)
+	is.Equal(atomic.LoadInt32(&count), int32(1))
+}
+
+func TestParsedForm(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	h := middleware.Compose(
+		middleware.MethodOverride(),
+		idempotency.New(idempotency.NewMemoryStore()),
+	).Middleware(charge(&count))
+	post := func(amount string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/payments", strings.NewReader("amount="+amount))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(idempotency.Header, "abc")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+	res := post("10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	res = post("10")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "true")
+	res = post("20")
+	is.Equal(res.StatusCode, http.StatusUnprocessableEntity)
+	is.Equal(atomic.LoadInt32(&count), int32(1))
+}
+
+func TestInProgress(t *testing.T) {
+	is := is.New(t)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := idempotency.New(idempotency.NewMemoryStore()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	}))
+	done := make(chan *http.Response)
+	go func() { done <- send(h, http.MethodPost, "/payments", "abc", "10") }()
+	<-started
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusConflict)
+	close(release)
+	res = <-done
+	is.Equal(res.StatusCode, http.StatusOK)
+	is.Equal(body(res), "done")
+}
+
+func TestServerErrorNotRecorded(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	h := idempotency.New(idempotency.NewMemoryStore()).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) == 1 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusServiceUnavailable)
+	res = send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusOK)
+	is.Equal(body(res), "ok")
+	res = send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "true")
+	is.Equal(atomic.LoadInt32(&count), int32(2))
+}
+
+func TestExpired(t *testing.T) {
+	is := is.New(t)
+	var count int32
+	m := idempotency.New(idempotency.NewMemoryStore())
+	m.TTL = time.Millisecond
+	h := m.Middleware(charge(&count))
+	send(h, http.MethodPost, "/payments", "abc", "10")
+	time.Sleep(5 * time.Millisecond)
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "")
+	is.Equal(atomic.LoadInt32(&count), int32(2))
+}
+
+func TestKey(t *testing.T) {
+	is := is.New(t)
+	_, ok := idempotency.Key(context.Background())
+	is.True(!ok)
+	is.Equal(idempotency.Require(context.Background()), idempotency.ErrMissingKey)
+}
+
+func TestLoadStore(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	store, err := idempotency.LoadStore(getenv)
+	is.NoErr(err)
+	_, ok := store.(*idempotency.MemoryStore)
+	is.True(ok)
+	env["IDEMPOTENCY_STORE"] = "memcached"
+	_, err = idempotency.LoadStore(getenv)
+	is.True(err != nil)
+	is.Equal(err.Error(), `idempotency: expected IDEMPOTENCY_STORE to be memory, redis or sql, got "memcached"`)
+	env["IDEMPOTENCY_STORE"] = "sql"
+	_, err = idempotency.LoadStore(getenv)
+	is.True(err != nil)
+	is.Equal(err.Error(), "idempotency: the sql store requires the DATABASE_URL environment variable")
+}
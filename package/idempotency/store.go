@@ -0,0 +1,230 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Store keeps responses by their idempotency key. Stores must be safe for
+// concurrent use.
+type Store interface {
+	// Reserve the key for a request that's in progress until expires. Reserve
+	// returns false when the key is already reserved or has a response.
+	Reserve(ctx context.Context, key string, expires time.Time) (bool, error)
+	// Load the key's response. Load returns nil when the key is reserved but
+	// doesn't have a response yet.
+	Load(ctx context.Context, key string) (*Response, error)
+	// Save the key's response until it expires
+	Save(ctx context.Context, key string, res *Response, expires time.Time) error
+	// Delete the key
+	Delete(ctx context.Context, key string) error
+}
+
+// NewMemoryStore keeps responses in memory. Responses are lost on restart and
+// aren't shared between processes, so it's only suitable for development and
+// testing.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]*memoryEntry{}}
+}
+
+// MemoryStore keeps responses in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+type memoryEntry struct {
+	res     *Response
+	expires time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Reserve(ctx context.Context, key string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, ok := s.entries[key]; ok && time.Now().Before(entry.expires) {
+		return false, nil
+	}
+	s.entries[key] = &memoryEntry{nil, expires}
+	return true, nil
+}
+
+func (s *MemoryStore) Load(ctx context.Context, key string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.entries[key]
+	if !ok || !time.Now().Before(entry.expires) {
+		return nil, nil
+	}
+	return entry.res, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, res *Response, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = &memoryEntry{res, expires}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// NewRedisStore keeps responses in Redis. Keys expire using Redis' TTLs.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client, "idempotency:"}
+}
+
+// RedisStore keeps responses in Redis
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+func (s *RedisStore) Reserve(ctx context.Context, key string, expires time.Time) (bool, error) {
+	// An empty value marks a request that's in progress
+	ok, err := s.client.SetNX(ctx, s.prefix+key, "", time.Until(expires)).Result()
+	if err != nil {
+		return false, fmt.Errorf("idempotency: unable to reserve key. %w", err)
+	}
+	return ok, nil
+}
+
+func (s *RedisStore) Load(ctx context.Context, key string) (*Response, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("idempotency: unable to load response. %w", err)
+	}
+	return decode(data)
+}
+
+func (s *RedisStore) Save(ctx context.Context, key string, res *Response, expires time.Time) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.prefix+key, data, time.Until(expires)).Err(); err != nil {
+		return fmt.Errorf("idempotency: unable to save response. %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, s.prefix+key).Err(); err != nil {
+		return fmt.Errorf("idempotency: unable to delete key. %w", err)
+	}
+	return nil
+}
+
+// NewSQLStore keeps responses in the bud_idempotency table, which needs to be
+// created with a migration:
+//
+//	create table bud_idempotency (
+//		key text primary key,
+//		response text,
+//		expires_at timestamp not null
+//	);
+//	create index bud_idempotency_expires_at on bud_idempotency (expires_at);
+//
+// Expired keys are ignored. Remove them with Prune.
+func NewSQLStore(db *dbrt.DB) *SQLStore {
+	return &SQLStore{db}
+}
+
+// SQLStore keeps responses in a Postgres or SQLite database
+type SQLStore struct {
+	db *dbrt.DB
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func (s *SQLStore) Reserve(ctx context.Context, key string, expires time.Time) (bool, error) {
+	// Take over keys that have expired
+	query := s.db.Dialect().Rebind(`insert into "bud_idempotency" ("key", "expires_at") values (?, ?) ` +
+		`on conflict ("key") do update set "response" = null, "expires_at" = excluded."expires_at" ` +
+		`where "bud_idempotency"."expires_at" <= ?`)
+	// Reserve outside of the request's transaction, so other requests see it
+	result, err := s.db.ExecContext(context.Background(), query, key, expires.UTC(), dbrt.Now())
+	if err != nil {
+		return false, fmt.Errorf("idempotency: unable to reserve key. %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (s *SQLStore) Load(ctx context.Context, key string) (*Response, error) {
+	var data sql.NullString
+	query := s.db.Dialect().Rebind(`select "response" from "bud_idempotency" where "key" = ? and "expires_at" > ?`)
+	if err := s.db.QueryRowContext(context.Background(), query, key, dbrt.Now()).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("idempotency: unable to load response. %w", err)
+	}
+	if !data.Valid {
+		return nil, nil
+	}
+	return decode([]byte(data.String))
+}
+
+func (s *SQLStore) Save(ctx context.Context, key string, res *Response, expires time.Time) error {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	query := s.db.Dialect().Rebind(`update "bud_idempotency" set "response" = ?, "expires_at" = ? where "key" = ?`)
+	if _, err := s.db.ExecContext(context.Background(), query, string(data), expires.UTC(), key); err != nil {
+		return fmt.Errorf("idempotency: unable to save response. %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, key string) error {
+	query := s.db.Dialect().Rebind(`delete from "bud_idempotency" where "key" = ?`)
+	if _, err := s.db.ExecContext(context.Background(), query, key); err != nil {
+		return fmt.Errorf("idempotency: unable to delete key. %w", err)
+	}
+	return nil
+}
+
+// Prune deletes expired keys
+func (s *SQLStore) Prune(ctx context.Context) (int64, error) {
+	query := s.db.Dialect().Rebind(`delete from "bud_idempotency" where "expires_at" <= ?`)
+	result, err := s.db.ExecContext(ctx, query, dbrt.Now())
+	if err != nil {
+		return 0, fmt.Errorf("idempotency: unable to prune keys. %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func decode(data []byte) (*Response, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	res := new(Response)
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, fmt.Errorf("idempotency: unable to decode response. %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,58 @@
+package idempotency_test
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/idempotency"
+)
+
+func TestSQLStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	_, err = db.ExecContext(ctx, `create table bud_idempotency (key text primary key, response text, expires_at timestamp not null)`)
+	is.NoErr(err)
+	store := idempotency.NewSQLStore(db)
+	var count int32
+	h := idempotency.New(store).Middleware(charge(&count))
+	res := send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	res = send(h, http.MethodPost, "/payments", "abc", "10")
+	is.Equal(res.StatusCode, http.StatusCreated)
+	is.Equal(body(res), "charged 10")
+	is.Equal(res.Header.Get("Idempotent-Replayed"), "true")
+	is.Equal(atomic.LoadInt32(&count), int32(1))
+	// Reserved keys don't have a response yet
+	ok, err := store.Reserve(ctx, "a", time.Now().Add(time.Minute))
+	is.NoErr(err)
+	is.True(ok)
+	ok, err = store.Reserve(ctx, "a", time.Now().Add(time.Minute))
+	is.NoErr(err)
+	is.True(!ok)
+	saved, err := store.Load(ctx, "a")
+	is.NoErr(err)
+	is.Equal(saved, nil)
+	// Expired keys can be reserved again and pruned
+	ok, err = store.Reserve(ctx, "b", time.Now().Add(-time.Minute))
+	is.NoErr(err)
+	is.True(ok)
+	ok, err = store.Reserve(ctx, "b", time.Now().Add(-time.Minute))
+	is.NoErr(err)
+	is.True(ok)
+	pruned, err := store.Prune(ctx)
+	is.NoErr(err)
+	is.Equal(pruned, int64(1))
+	is.NoErr(store.Delete(ctx, "a"))
+	var rows int
+	is.NoErr(db.QueryRowContext(ctx, `select count(*) from bud_idempotency`).Scan(&rows))
+	is.Equal(rows, 1)
+}
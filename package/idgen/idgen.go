@@ -0,0 +1,68 @@
+// Package idgen generates IDs. Depend on an IDGenerator instead of reading
+// from crypto/rand, so tests can produce predictable IDs.
+//
+// IDGenerators are injected like any other dependency. In tests, pass in a
+// sequence instead:
+//
+//	ids := idgen.Sequential("session-")
+//	ids.NewID() // "session-1"
+//	ids.NewID() // "session-2"
+package idgen
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"sync"
+)
+
+// IDGenerator generates unique IDs
+type IDGenerator interface {
+	NewID() string
+}
+
+// Load the random ID generator
+func Load() IDGenerator {
+	return random{}
+}
+
+// random IDs are 32 bytes from crypto/rand, encoded as URL-safe base64. They
+// can't be guessed, so they're safe to use as session IDs.
+type random struct{}
+
+func (random) NewID() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("idgen: unable to generate an id. " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Sequential returns IDs for tests that count up from 1, after the prefix
+func Sequential(prefix string) *Sequence {
+	return &Sequence{prefix: prefix}
+}
+
+// Sequence of IDs for tests. It's safe for concurrent use.
+type Sequence struct {
+	mu     sync.Mutex
+	prefix string
+	n      int
+}
+
+var _ IDGenerator = (*Sequence)(nil)
+
+// NewID returns the next ID in the sequence
+func (s *Sequence) NewID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n++
+	return s.prefix + strconv.Itoa(s.n)
+}
+
+// Reset the sequence, so the next ID ends in 1
+func (s *Sequence) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n = 0
+}
@@ -0,0 +1,25 @@
+package idgen_test
+
+import (
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/idgen"
+)
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	ids := idgen.Load()
+	a, b := ids.NewID(), ids.NewID()
+	is.Equal(len(a), 43)
+	is.True(a != b)
+}
+
+func TestSequential(t *testing.T) {
+	is := is.New(t)
+	ids := idgen.Sequential("job-")
+	is.Equal(ids.NewID(), "job-1")
+	is.Equal(ids.NewID(), "job-2")
+	ids.Reset()
+	is.Equal(ids.NewID(), "job-1")
+}
@@ -0,0 +1,42 @@
+package imageset
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// encodeAVIF encodes the image with libavif's avifenc. There's no AV1 encoder
+// written in Go, so the image is passed through temporary files.
+func encodeAVIF(img image.Image) ([]byte, error) {
+	avifenc, err := exec.LookPath("avifenc")
+	if err != nil {
+		return nil, fmt.Errorf("imageset: unable to find avifenc to encode AVIF images. %w", err)
+	}
+	dir, err := os.MkdirTemp("", "imageset-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	input := filepath.Join(dir, "image.png")
+	output := filepath.Join(dir, "image.avif")
+	buf := new(bytes.Buffer)
+	if err := (&png.Encoder{CompressionLevel: png.NoCompression}).Encode(buf, img); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		return nil, err
+	}
+	// Quantizers between 0 (lossless) and 63 roughly match the JPEG quality
+	stderr := new(bytes.Buffer)
+	cmd := exec.Command(avifenc, "--speed", "6", "--min", "18", "--max", "30", input, output)
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("imageset: avifenc failed. %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return os.ReadFile(output)
+}
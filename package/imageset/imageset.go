@@ -0,0 +1,283 @@
+// Package imageset serves responsive variants of the images in public/. Each
+// JPEG and PNG is available at a set of widths, named after the original:
+//
+//	/images/cat.jpg => /images/cat.320w.jpg, /images/cat.640w.jpg, ...
+//
+// Production builds embed the variants. During development, variants are
+// resized on demand. Production builds that read public/ from disk serve the
+// original in place of the variants. Variants at or above the original's width are served as
+// the original, so every variant in a srcset resolves.
+//
+// Images and their variants are also converted to AVIF and WebP, named after
+// the image they're converted from:
+//
+//	/images/cat.640w.jpg => /images/cat.640w.jpg.avif, /images/cat.640w.jpg.webp
+//
+// The converted images are served in place of the image to browsers that
+// accept them, so the markup doesn't change. Like the variants, they're
+// generated at build time or on demand during development. AVIF images are encoded by
+// libavif's avifenc, so they're skipped where it isn't installed.
+package imageset
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/chai2010/webp"
+)
+
+// Widths of the variants
+var Widths = []int{320, 640, 960, 1280, 1920}
+
+// Formats that images are converted to, smallest first
+var Formats = []string{"avif", "webp"}
+
+// quality of resized JPEGs and WebPs
+const quality = 82
+
+// Supported is true for images that have variants
+func Supported(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	default:
+		return false
+	}
+}
+
+// Variant returns the path of the image at width.
+// e.g. Variant("/images/cat.jpg", 640) => "/images/cat.640w.jpg"
+func Variant(name string, width int) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + strconv.Itoa(width) + "w" + ext
+}
+
+// Variants returns the paths of every width of the image
+func Variants(name string) []string {
+	variants := make([]string, len(Widths))
+	for i, width := range Widths {
+		variants[i] = Variant(name, width)
+	}
+	return variants
+}
+
+// Converted returns the path of the image converted to format.
+// e.g. Converted("/images/cat.640w.jpg", "webp") => "/images/cat.640w.jpg.webp"
+func Converted(name, format string) string {
+	return name + "." + format
+}
+
+// ContentType of the format
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// CanEncode is true if images can be converted to the format. AVIF images are
+// encoded by libavif's avifenc, so they're only available where it's
+// installed.
+func CanEncode(format string) bool {
+	switch format {
+	case "jpeg", "png", "webp":
+		return true
+	case "avif":
+		_, err := exec.LookPath("avifenc")
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// Accepted returns the formats that the Accept header names, smallest first.
+// Wildcards don't count, since browsers send "*/*" whether or not they can
+// display the format.
+func Accepted(header string) (formats []string) {
+	for _, format := range Formats {
+		for _, part := range strings.Split(header, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			if strings.EqualFold(strings.TrimSpace(name), ContentType(format)) && !zeroQuality(params) {
+				formats = append(formats, format)
+				break
+			}
+		}
+	}
+	return formats
+}
+
+// zeroQuality is true for types marked as unacceptable with "q=0"
+func zeroQuality(params string) bool {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.TrimSpace(key) != "q" {
+			continue
+		}
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return err == nil && q == 0
+	}
+	return false
+}
+
+// Parse a variant's path into the original's path and the width. Only the
+// standard widths are variants, so resizing can't be abused.
+func Parse(name string) (original string, width int, ok bool) {
+	ext := path.Ext(name)
+	if !Supported(name) {
+		return "", 0, false
+	}
+	base := strings.TrimSuffix(name, ext)
+	dot := strings.LastIndexByte(base, '.')
+	if dot < 0 || !strings.HasSuffix(base, "w") {
+		return "", 0, false
+	}
+	width, err := strconv.Atoi(base[dot+1 : len(base)-1])
+	if err != nil || !isWidth(width) {
+		return "", 0, false
+	}
+	return base[:dot] + ext, width, true
+}
+
+func isWidth(width int) bool {
+	for _, w := range Widths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// Srcset returns the srcset attribute for an image in public/.
+// e.g. Srcset("/images/cat.jpg") => "/images/cat.320w.jpg 320w, ..."
+func Srcset(src string) string {
+	candidates := make([]string, len(Widths))
+	for i, width := range Widths {
+		candidates[i] = Variant(src, width) + " " + strconv.Itoa(width) + "w"
+	}
+	return strings.Join(candidates, ", ")
+}
+
+// Img returns the markup for a responsive image. Sizes tells the browser how
+// wide the image is displayed, like "(min-width: 960px) 50vw, 100vw".
+func Img(src, alt, sizes string) string {
+	if !Supported(src) {
+		return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(src), html.EscapeString(alt))
+	}
+	if sizes == "" {
+		sizes = "100vw"
+	}
+	return fmt.Sprintf(`<img src="%s" srcset="%s" sizes="%s" alt="%s">`,
+		html.EscapeString(src),
+		html.EscapeString(Srcset(src)),
+		html.EscapeString(sizes),
+		html.EscapeString(alt),
+	)
+}
+
+// Width of an encoded image, without decoding the pixels
+func Width(r io.Reader) (int, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, fmt.Errorf("imageset: unable to decode image. %w", err)
+	}
+	return config.Width, nil
+}
+
+// Resize an encoded image to width, keeping the aspect ratio and format.
+// Images that are already narrow enough are returned as is.
+func Resize(data []byte, width int) ([]byte, error) {
+	return Convert(data, width, "")
+}
+
+// Convert an encoded image to the format, resizing it to width if it's wider.
+// An empty format keeps the image's format and a width of 0 keeps its size.
+func Convert(data []byte, width int, format string) ([]byte, error) {
+	src, original, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("imageset: unable to decode image. %w", err)
+	}
+	if format == "" {
+		format = original
+	}
+	bounds := src.Bounds()
+	if width > 0 && bounds.Dx() > width {
+		height := bounds.Dy() * width / bounds.Dx()
+		if height < 1 {
+			height = 1
+		}
+		src = scale(src, width, height)
+	} else if format == original {
+		return data, nil
+	}
+	return encode(src, format)
+}
+
+func encode(img image.Image, format string) (data []byte, err error) {
+	buf := new(bytes.Buffer)
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: quality})
+		data = buf.Bytes()
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(buf, img)
+		data = buf.Bytes()
+	case "webp":
+		data, err = webp.EncodeRGBA(img, quality)
+	case "avif":
+		data, err = encodeAVIF(img)
+	default:
+		return nil, fmt.Errorf("imageset: unable to encode %s images", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("imageset: unable to encode image. %w", err)
+	}
+	return data, nil
+}
+
+// scale down by averaging the source pixels that each destination pixel
+// covers, which avoids the aliasing of nearest-neighbor sampling
+func scale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	// Convert once, so pixels can be read directly
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				i := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[i])
+					g += uint32(rgba.Pix[i+1])
+					b += uint32(rgba.Pix[i+2])
+					a += uint32(rgba.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
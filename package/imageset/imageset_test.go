@@ -0,0 +1,148 @@
+package imageset_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/imageset"
+)
+
+func TestVariant(t *testing.T) {
+	is := is.New(t)
+	is.Equal(imageset.Variant("/images/cat.jpg", 640), "/images/cat.640w.jpg")
+	is.Equal(imageset.Variant("/my.cat.png", 320), "/my.cat.320w.png")
+	original, width, ok := imageset.Parse("/images/cat.640w.jpg")
+	is.True(ok)
+	is.Equal(original, "/images/cat.jpg")
+	is.Equal(width, 640)
+	original, width, ok = imageset.Parse("/my.cat.320w.png")
+	is.True(ok)
+	is.Equal(original, "/my.cat.png")
+	is.Equal(width, 320)
+	// Only standard widths are variants
+	_, _, ok = imageset.Parse("/images/cat.641w.jpg")
+	is.True(!ok)
+	_, _, ok = imageset.Parse("/images/cat.jpg")
+	is.True(!ok)
+	_, _, ok = imageset.Parse("/images/cat.640w.gif")
+	is.True(!ok)
+}
+
+func TestSrcset(t *testing.T) {
+	is := is.New(t)
+	is.Equal(imageset.Srcset("/cat.jpg"), "/cat.320w.jpg 320w, /cat.640w.jpg 640w, /cat.960w.jpg 960w, /cat.1280w.jpg 1280w, /cat.1920w.jpg 1920w")
+	is.Equal(imageset.Img("/cat.gif", `a "cat"`, ""), `<img src="/cat.gif" alt="a &#34;cat&#34;">`)
+	img := imageset.Img("/cat.jpg", "cat", "50vw")
+	is.In(img, `srcset="/cat.320w.jpg 320w, `)
+	is.In(img, `sizes="50vw"`)
+}
+
+func checkerboard(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if (x+y)%2 == 0 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+	return img
+}
+
+func TestResizePNG(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, checkerboard(800, 400)))
+	data, err := imageset.Resize(buf.Bytes(), 320)
+	is.NoErr(err)
+	img, err := png.Decode(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(img.Bounds().Dx(), 320)
+	is.Equal(img.Bounds().Dy(), 160)
+	// Averaging blends the checkerboard into gray
+	r, _, _, _ := img.At(10, 10).RGBA()
+	is.True(r>>8 > 96 && r>>8 < 160)
+	width, err := imageset.Width(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(width, 320)
+}
+
+func TestResizeJPEG(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(jpeg.Encode(buf, checkerboard(1000, 500), nil))
+	data, err := imageset.Resize(buf.Bytes(), 640)
+	is.NoErr(err)
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(format, "jpeg")
+	is.Equal(config.Width, 640)
+	is.Equal(config.Height, 320)
+}
+
+func TestResizeNarrow(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, checkerboard(200, 100)))
+	data, err := imageset.Resize(buf.Bytes(), 320)
+	is.NoErr(err)
+	is.Equal(data, buf.Bytes())
+}
+
+func TestConvertWebP(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	is.NoErr(jpeg.Encode(buf, checkerboard(1000, 500), nil))
+	data, err := imageset.Convert(buf.Bytes(), 640, "webp")
+	is.NoErr(err)
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(format, "webp")
+	is.Equal(config.Width, 640)
+	is.Equal(config.Height, 320)
+	// Narrow images are converted without resizing
+	data, err = imageset.Convert(buf.Bytes(), 1920, "webp")
+	is.NoErr(err)
+	config, format, err = image.DecodeConfig(bytes.NewReader(data))
+	is.NoErr(err)
+	is.Equal(format, "webp")
+	is.Equal(config.Width, 1000)
+	is.Equal(imageset.Converted("/cat.640w.jpg", "webp"), "/cat.640w.jpg.webp")
+}
+
+func TestConvertAVIF(t *testing.T) {
+	is := is.New(t)
+	// Fake avifenc that writes "avif" to the output
+	dir := t.TempDir()
+	script := "#!/bin/sh\nfor last; do :; done\nprintf avif > \"$last\"\n"
+	is.NoErr(os.WriteFile(filepath.Join(dir, "avifenc"), []byte(script), 0755))
+	t.Setenv("PATH", dir)
+	is.True(imageset.CanEncode("avif"))
+	buf := new(bytes.Buffer)
+	is.NoErr(png.Encode(buf, checkerboard(800, 400)))
+	data, err := imageset.Convert(buf.Bytes(), 320, "avif")
+	is.NoErr(err)
+	is.Equal(string(data), "avif")
+	// Without avifenc, images aren't converted to AVIF
+	t.Setenv("PATH", t.TempDir())
+	is.True(!imageset.CanEncode("avif"))
+	_, err = imageset.Convert(buf.Bytes(), 320, "avif")
+	is.True(err != nil)
+}
+
+func TestAccepted(t *testing.T) {
+	is := is.New(t)
+	is.Equal(imageset.Accepted("image/avif,image/webp,image/apng,image/*,*/*;q=0.8"), []string{"avif", "webp"})
+	is.Equal(imageset.Accepted("image/webp,*/*"), []string{"webp"})
+	is.Equal(imageset.Accepted("image/avif;q=0, image/webp"), []string{"webp"})
+	is.Equal(len(imageset.Accepted("image/*,*/*")), 0)
+}
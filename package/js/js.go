@@ -1,7 +1,66 @@
 package js
 
+import (
+	"context"
+	"fmt"
+
+	"github.com/livebud/bud/package/tracing"
+)
+
 // VM for evaluating javascript
 type VM interface {
 	Script(path, script string) error
 	Eval(path, expression string) (string, error)
 }
+
+// ContextVM is a VM that evaluates within a context, like a VM on another
+// server that's called within a traced request
+type ContextVM interface {
+	EvalContext(ctx context.Context, path, expression string) (string, error)
+}
+
+// StackVM is a VM that samples the stack while evaluating, like V8 with its
+// CPU profiler
+type StackVM interface {
+	EvalStack(path, expression string) (string, []Frame, error)
+}
+
+// Frame is a function call in a stack
+type Frame struct {
+	Function string
+	Path     string
+	Line     int
+	Column   int
+}
+
+func (f Frame) String() string {
+	return fmt.Sprintf("%s (%s:%d:%d)", f.Function, f.Path, f.Line, f.Column)
+}
+
+// Eval evaluates the expression within a span of the request's trace
+func Eval(ctx context.Context, vm VM, path, expression string) (string, error) {
+	result, _, err := eval(ctx, vm, path, expression, false)
+	return result, err
+}
+
+// EvalStack evaluates the expression like Eval and also returns the deepest
+// stack that was sampled, innermost call first. The stack is empty when the VM
+// can't sample stacks.
+func EvalStack(ctx context.Context, vm VM, path, expression string) (string, []Frame, error) {
+	return eval(ctx, vm, path, expression, true)
+}
+
+func eval(ctx context.Context, vm VM, path, expression string, sample bool) (result string, stack []Frame, err error) {
+	ctx, span := tracing.Start(ctx, "js.eval")
+	defer span.End()
+	span.SetAttribute("code.filepath", path)
+	if svm, ok := vm.(StackVM); ok && sample {
+		result, stack, err = svm.EvalStack(path, expression)
+	} else if cvm, ok := vm.(ContextVM); ok {
+		result, err = cvm.EvalContext(ctx, path, expression)
+	} else {
+		result, err = vm.Eval(path, expression)
+	}
+	span.RecordError(err)
+	return result, stack, err
+}
@@ -0,0 +1,128 @@
+// Package jstest provides a fake js.VM for testing code that evaluates
+// javascript without starting V8.
+package jstest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/livebud/bud/package/js"
+)
+
+// Call to the VM
+type Call struct {
+	Method string // "Script" or "Eval"
+	Path   string
+	Code   string
+}
+
+// New fake VM. Scripts succeed and evaluations fail until they're given
+// results with On.
+func New() *VM {
+	return &VM{}
+}
+
+// VM is an in-memory js.VM that returns scripted results and records every
+// call. It's safe for concurrent use.
+type VM struct {
+	mu    sync.Mutex
+	rules []*rule
+	calls []*Call
+	eval  func(path, expr string) (string, error)
+}
+
+var _ js.VM = (*VM)(nil)
+
+type rule struct {
+	contains string
+	result   string
+	err      error
+}
+
+// On returns the result when evaluating code that contains the substring.
+// Results added first take precedence.
+func (vm *VM) On(contains, result string) *VM {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.rules = append(vm.rules, &rule{contains: contains, result: result})
+	return vm
+}
+
+// OnError returns the error when running or evaluating code that contains
+// the substring
+func (vm *VM) OnError(contains string, err error) *VM {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.rules = append(vm.rules, &rule{contains: contains, err: err})
+	return vm
+}
+
+// Func evaluates expressions that don't match any results
+func (vm *VM) Func(eval func(path, expr string) (string, error)) *VM {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.eval = eval
+	return vm
+}
+
+// Script records the script. It only fails when given an error with OnError.
+func (vm *VM) Script(path, script string) error {
+	rule := vm.record("Script", path, script)
+	if rule != nil && rule.err != nil {
+		return rule.err
+	}
+	return nil
+}
+
+// Eval returns the scripted result for the expression
+func (vm *VM) Eval(path, expr string) (string, error) {
+	rule := vm.record("Eval", path, expr)
+	if rule != nil {
+		return rule.result, rule.err
+	}
+	vm.mu.Lock()
+	eval := vm.eval
+	vm.mu.Unlock()
+	if eval != nil {
+		return eval(path, expr)
+	}
+	return "", fmt.Errorf("jstest: no result for evaluating %q in %s", shorten(expr), path)
+}
+
+// record the call and find the first rule that matches
+func (vm *VM) record(method, path, code string) *rule {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.calls = append(vm.calls, &Call{method, path, code})
+	for _, rule := range vm.rules {
+		if strings.Contains(code, rule.contains) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Calls returns the calls to the VM in order
+func (vm *VM) Calls() []*Call {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	calls := make([]*Call, len(vm.calls))
+	copy(calls, vm.calls)
+	return calls
+}
+
+// Reset forgets the calls, keeping the results
+func (vm *VM) Reset() {
+	vm.mu.Lock()
+	defer vm.mu.Unlock()
+	vm.calls = nil
+}
+
+// shorten long code for error messages
+func shorten(code string) string {
+	if len(code) > 80 {
+		return "..." + code[len(code)-77:]
+	}
+	return code
+}
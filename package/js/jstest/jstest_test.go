@@ -0,0 +1,52 @@
+package jstest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/js/jstest"
+)
+
+func TestEval(t *testing.T) {
+	is := is.New(t)
+	vm := jstest.New().
+		On("1 + 1", "2").
+		OnError("throw", errors.New("boom"))
+	result, err := vm.Eval("add.js", "1 + 1")
+	is.NoErr(err)
+	is.Equal(result, "2")
+	_, err = vm.Eval("throw.js", "throw new Error('boom')")
+	is.Equal(err.Error(), "boom")
+	_, err = vm.Eval("other.js", "2 + 2")
+	is.True(err != nil)
+	is.NoErr(vm.Script("setup.js", "var a = 1"))
+	calls := vm.Calls()
+	is.Equal(len(calls), 4)
+	is.Equal(calls[0].Method, "Eval")
+	is.Equal(calls[0].Path, "add.js")
+	is.Equal(calls[3].Method, "Script")
+	is.Equal(calls[3].Code, "var a = 1")
+	vm.Reset()
+	is.Equal(len(vm.Calls()), 0)
+}
+
+func TestFunc(t *testing.T) {
+	is := is.New(t)
+	vm := jstest.New().On("first", "1").Func(func(path, expr string) (string, error) {
+		return path + ":" + expr, nil
+	})
+	result, err := vm.Eval("a.js", "first")
+	is.NoErr(err)
+	is.Equal(result, "1")
+	result, err = vm.Eval("a.js", "second")
+	is.NoErr(err)
+	is.Equal(result, "a.js:second")
+}
+
+func TestScriptError(t *testing.T) {
+	is := is.New(t)
+	vm := jstest.New().OnError("syntax", errors.New("syntax error"))
+	err := vm.Script("bad.js", "syntax }")
+	is.Equal(err.Error(), "syntax error")
+}
@@ -2,6 +2,7 @@ package v8
 
 import (
 	"os"
+	"strings"
 
 	"github.com/livebud/bud/package/js"
 	"go.kuoruan.net/v8go-polyfills/console"
@@ -130,6 +131,53 @@ func (vm *VM) Eval(path, expr string) (string, error) {
 	return value.String(), nil
 }
 
+var _ js.StackVM = (*VM)(nil)
+
+// EvalStack evaluates the expression with the CPU profiler on and returns the
+// deepest stack it sampled. Profiling slows evaluation down.
+func (vm *VM) EvalStack(path, expr string) (string, []js.Frame, error) {
+	profiler := v8go.NewCPUProfiler(vm.isolate)
+	defer profiler.Dispose()
+	profiler.StartProfiling(path)
+	result, err := vm.Eval(path, expr)
+	profile := profiler.StopProfiling(path)
+	defer profile.Delete()
+	return result, deepestStack(profile.GetTopDownRoot()), err
+}
+
+// deepestStack finds the longest call path in the profile and returns it
+// innermost call first
+func deepestStack(root *v8go.CPUProfileNode) (stack []js.Frame) {
+	leaf, depth := root, 0
+	var walk func(node *v8go.CPUProfileNode, d int)
+	walk = func(node *v8go.CPUProfileNode, d int) {
+		if d > depth {
+			leaf, depth = node, d
+		}
+		for i := 0; i < node.GetChildrenCount(); i++ {
+			walk(node.GetChild(i), d+1)
+		}
+	}
+	walk(root, 0)
+	for node := leaf; node != nil; node = node.GetParent() {
+		name := node.GetFunctionName()
+		// Skip V8's own nodes, like (root), (program) and (garbage collector)
+		if strings.HasPrefix(name, "(") {
+			continue
+		}
+		if name == "" {
+			name = "(anonymous)"
+		}
+		stack = append(stack, js.Frame{
+			Function: name,
+			Path:     node.GetScriptResourceName(),
+			Line:     node.GetLineNumber(),
+			Column:   node.GetColumnNumber(),
+		})
+	}
+	return stack
+}
+
 func (vm *VM) Close() {
 	vm.context.Close()
 	vm.isolate.TerminateExecution()
@@ -0,0 +1,295 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Verifier checks the signature of a token
+type Verifier interface {
+	Verify(ctx context.Context, token string) (Claims, error)
+}
+
+// KeySet holds the keys for signing and verifying tokens. The first key that
+// can sign is used to sign new tokens, while every key is used to verify
+// them. Keep previous keys in the set until their tokens expire.
+type KeySet struct {
+	keys []*Key
+}
+
+// NewKeySet creates a key set
+func NewKeySet(keys ...*Key) *KeySet {
+	return &KeySet{keys}
+}
+
+// Keys in the set
+func (s *KeySet) Keys() []*Key {
+	return s.keys
+}
+
+// Sign the claims with the first key that can sign
+func (s *KeySet) Sign(claims Claims) (string, error) {
+	for _, key := range s.keys {
+		if key.CanSign() {
+			return sign(key, claims)
+		}
+	}
+	return "", fmt.Errorf("jwt: no keys that can sign tokens")
+}
+
+// Verify the token's signature. The key is looked up by the token's key ID
+// and algorithm, so a token can't pick a weaker algorithm than its key.
+func (s *KeySet) Verify(ctx context.Context, token string) (Claims, error) {
+	h, claims, input, signature, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(s.keys, h, input, signature); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func verify(keys []*Key, h *header, input, signature []byte) error {
+	found := false
+	for _, key := range keys {
+		if key.Algorithm != h.Algorithm || (h.KeyID != "" && key.ID != h.KeyID) {
+			continue
+		}
+		found = true
+		if key.verify(input, signature) == nil {
+			return nil
+		}
+	}
+	if !found {
+		return fmt.Errorf("%w. unknown %s key %q", ErrInvalid, h.Algorithm, h.KeyID)
+	}
+	return fmt.Errorf("%w. signature doesn't match", ErrInvalid)
+}
+
+// jwk is a JSON Web Key, as described in RFC 7517
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+func toJWK(key *Key) *jwk {
+	switch public := key.public.(type) {
+	case *rsa.PublicKey:
+		return &jwk{
+			KeyType:   "RSA",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: RS256,
+			N:         encoding.EncodeToString(public.N.Bytes()),
+			E:         encoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+		}
+	case ed25519.PublicKey:
+		return &jwk{
+			KeyType:   "OKP",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: EdDSA,
+			Curve:     "Ed25519",
+			X:         encoding.EncodeToString(public),
+		}
+	default:
+		return nil
+	}
+}
+
+// thumbprint of the key, as described in RFC 7638
+func thumbprint(k *jwk) string {
+	var canonical string
+	switch k.KeyType {
+	case "RSA":
+		canonical = fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, k.E, k.N)
+	case "OKP":
+		canonical = fmt.Sprintf(`{"crv":%q,"kty":"OKP","x":%q}`, k.Curve, k.X)
+	}
+	sum := sha256.Sum256([]byte(canonical))
+	return encoding.EncodeToString(sum[:])
+}
+
+func fromJWK(k *jwk) (*Key, error) {
+	var public interface{}
+	switch k.KeyType {
+	case "RSA":
+		n, err := encoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid RSA modulus in key %q", k.KeyID)
+		}
+		e, err := encoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: invalid RSA exponent in key %q", k.KeyID)
+		}
+		public = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "OKP":
+		x, err := encoding.DecodeString(k.X)
+		if err != nil || k.Curve != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("jwt: invalid Ed25519 key %q", k.KeyID)
+		}
+		public = ed25519.PublicKey(x)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported key type %q", k.KeyType)
+	}
+	key, err := newKey(public)
+	if err != nil {
+		return nil, err
+	}
+	if k.KeyID != "" {
+		key.ID = k.KeyID
+	}
+	return key, nil
+}
+
+type jwks struct {
+	Keys []*jwk `json:"keys"`
+}
+
+// MarshalJSON encodes the public keys as a JSON Web Key Set. HMAC keys are
+// secret, so they're left out.
+func (s *KeySet) MarshalJSON() ([]byte, error) {
+	set := jwks{Keys: []*jwk{}}
+	for _, key := range s.keys {
+		if k := toJWK(key); k != nil {
+			set.Keys = append(set.Keys, k)
+		}
+	}
+	return json.Marshal(set)
+}
+
+// ParseJWKS parses a JSON Web Key Set. Keys with unsupported types are
+// skipped.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: unable to parse the key set. %w", err)
+	}
+	keys := new(KeySet)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := fromJWK(k)
+		if err != nil {
+			continue
+		}
+		keys.keys = append(keys.keys, key)
+	}
+	return keys, nil
+}
+
+// RemoteKeySet verifies tokens with the keys published at a JWKS URL, like
+// https://example.com/.well-known/jwks.json. Keys are cached and fetched
+// again when a token uses a key that isn't in the cache.
+type RemoteKeySet struct {
+	URL    string
+	Client *http.Client
+	// MaxAge is how long keys are cached
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	keys    *KeySet
+	fetched time.Time
+}
+
+// NewRemoteKeySet creates a key set from a JWKS URL
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		URL:    url,
+		Client: http.DefaultClient,
+		MaxAge: time.Hour,
+	}
+}
+
+// minRefresh limits how often unknown keys trigger a fetch
+const minRefresh = time.Minute
+
+// Verify the token's signature with the remote keys
+func (r *RemoteKeySet) Verify(ctx context.Context, token string) (Claims, error) {
+	h, claims, input, signature, err := parse(token)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := r.load(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if !has(keys, h) {
+		// The keys may have rotated since they were fetched
+		if keys, err = r.load(ctx, true); err != nil {
+			return nil, err
+		}
+	}
+	if err := verify(keys, h, input, signature); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func has(keys []*Key, h *header) bool {
+	for _, key := range keys {
+		if key.Algorithm == h.Algorithm && (h.KeyID == "" || key.ID == h.KeyID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RemoteKeySet) load(ctx context.Context, refresh bool) ([]*Key, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	age := time.Since(r.fetched)
+	if r.keys != nil && age < r.MaxAge && (!refresh || age < minRefresh) {
+		return r.keys.keys, nil
+	}
+	keys, err := r.fetch(ctx)
+	if err != nil {
+		// Keep using the cached keys when the server is down
+		if r.keys != nil {
+			return r.keys.keys, nil
+		}
+		return nil, err
+	}
+	r.keys, r.fetched = keys, time.Now()
+	return keys.keys, nil
+}
+
+func (r *RemoteKeySet) fetch(ctx context.Context) (*KeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := r.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: unable to fetch keys from %s. %w", r.URL, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: unexpected status %d fetching keys from %s", res.StatusCode, r.URL)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("jwt: unable to read keys from %s. %w", r.URL, err)
+	}
+	return ParseJWKS(data)
+}
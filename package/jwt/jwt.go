@@ -0,0 +1,168 @@
+// Package jwt issues and verifies JSON Web Tokens for API controllers. Tokens
+// are signed with HS256, RS256 or EdDSA. Keys can be rotated by publishing the
+// previous public keys in a JSON Web Key Set (JWKS) until their tokens expire.
+//
+// When a controller imports this package, bud requires a valid bearer token
+// for requests under /api and exposes the token's claims on the context:
+//
+//	func (c *Controller) Index(ctx context.Context) (posts []*Post, err error) {
+//		userID := jwt.From(ctx).Subject()
+//		// ...
+//	}
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned when a token is malformed or its signature doesn't
+// match
+var ErrInvalid = errors.New("jwt: invalid token")
+
+// Claims within the token
+type Claims map[string]interface{}
+
+// Subject of the token, usually the user's ID
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer of the token
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience that the token was issued for. It may be a string or a list.
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		var auds []string
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				auds = append(auds, s)
+			}
+		}
+		return auds
+	case []string:
+		return aud
+	default:
+		return nil
+	}
+}
+
+// ExpiresAt returns when the token expires. It's zero when the token doesn't
+// expire.
+func (c Claims) ExpiresAt() time.Time {
+	return c.Time("exp")
+}
+
+// String returns a string claim
+func (c Claims) String(key string) string {
+	s, _ := c[key].(string)
+	return s
+}
+
+// Time returns a numeric date claim
+func (c Claims) Time(key string) time.Time {
+	switch n := c[key].(type) {
+	case float64:
+		return time.Unix(int64(n), 0)
+	case int64:
+		return time.Unix(n, 0)
+	case int:
+		return time.Unix(int64(n), 0)
+	case json.Number:
+		i, err := n.Int64()
+		if err != nil {
+			return time.Time{}
+		}
+		return time.Unix(i, 0)
+	default:
+		return time.Time{}
+	}
+}
+
+type contextKey struct{}
+
+// From returns the claims of the request's bearer token. It returns nil when
+// the request doesn't have a valid token.
+func From(ctx context.Context) Claims {
+	claims, _ := ctx.Value(contextKey{}).(Claims)
+	return claims
+}
+
+// With returns a context that carries the claims
+func With(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// header of the token
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// sign the claims with the key
+func sign(key *Key, claims Claims) (string, error) {
+	head, err := json.Marshal(header{key.Algorithm, "JWT", key.ID})
+	if err != nil {
+		return "", err
+	}
+	body, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("jwt: unable to encode claims. %w", err)
+	}
+	input := encoding.EncodeToString(head) + "." + encoding.EncodeToString(body)
+	signature, err := key.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return input + "." + encoding.EncodeToString(signature), nil
+}
+
+// parse splits the token into its parts without checking the signature
+func parse(token string) (h *header, claims Claims, input, signature []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	head, err := encoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	if err := json.Unmarshal(head, &h); err != nil || h == nil {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	body, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	if err := json.Unmarshal(body, &claims); err != nil || claims == nil {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	signature, err = encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, nil, nil, ErrInvalid
+	}
+	return h, claims, []byte(parts[0] + "." + parts[1]), signature, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/jwt"
+)
+
+func rsaPEM(t testing.TB) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func ed25519PEM(t testing.TB) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+func load(t testing.TB, env map[string]string) *jwt.Middleware {
+	t.Helper()
+	config, err := jwt.LoadConfig(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jwt.New(config)
+}
+
+func TestAlgorithms(t *testing.T) {
+	envs := map[string]map[string]string{
+		"HS256": {"JWT_SECRET": "secret"},
+		"RS256": {"JWT_PRIVATE_KEY": rsaPEM(t)},
+		"EdDSA": {"JWT_PRIVATE_KEY": ed25519PEM(t)},
+	}
+	for alg, env := range envs {
+		t.Run(alg, func(t *testing.T) {
+			is := is.New(t)
+			m := load(t, env)
+			token, err := m.Issue(jwt.Claims{"sub": "42", "role": "admin"})
+			is.NoErr(err)
+			header, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[0])
+			is.NoErr(err)
+			is.In(string(header), `"alg":"`+alg+`"`)
+			claims, err := m.Parse(context.Background(), token)
+			is.NoErr(err)
+			is.Equal(claims.Subject(), "42")
+			is.Equal(claims.String("role"), "admin")
+			// Tampering with the claims breaks the signature
+			parts := strings.Split(token, ".")
+			parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","exp":9999999999}`))
+			_, err = m.Parse(context.Background(), strings.Join(parts, "."))
+			is.True(errors.Is(err, jwt.ErrInvalid))
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	is := is.New(t)
+	old := load(t, map[string]string{"JWT_SECRET": "old"})
+	token, err := old.Issue(jwt.Claims{"sub": "1"})
+	is.NoErr(err)
+	m := load(t, map[string]string{"JWT_SECRET": "new", "JWT_PREVIOUS_SECRETS": "old"})
+	claims, err := m.Parse(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(claims.Subject(), "1")
+	// New tokens are signed with the new secret
+	token, err = m.Issue(jwt.Claims{"sub": "2"})
+	is.NoErr(err)
+	_, err = old.Parse(context.Background(), token)
+	is.True(errors.Is(err, jwt.ErrInvalid))
+}
+
+func TestRejectsNone(t *testing.T) {
+	is := is.New(t)
+	m := load(t, map[string]string{"JWT_SECRET": "secret"})
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	body := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"1","exp":9999999999}`))
+	_, err := m.Parse(context.Background(), header+"."+body+".")
+	is.True(errors.Is(err, jwt.ErrInvalid))
+}
+
+func TestExpiry(t *testing.T) {
+	is := is.New(t)
+	m := load(t, map[string]string{"JWT_SECRET": "secret", "JWT_TTL": "10m"})
+	now := time.Now()
+	frozen := clock.Freeze(now)
+	m.Clock = frozen
+	token, err := m.Issue(jwt.Claims{"sub": "1"})
+	is.NoErr(err)
+	claims, err := m.Parse(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(claims.ExpiresAt().Unix(), now.Add(10*time.Minute).Unix())
+	frozen.Advance(12 * time.Minute)
+	_, err = m.Parse(context.Background(), token)
+	is.Equal(err.Error(), "jwt: token has expired")
+	// Tokens must expire
+	keys := jwt.NewKeySet(jwt.HMAC([]byte("secret")))
+	token, err = keys.Sign(jwt.Claims{"sub": "1"})
+	is.NoErr(err)
+	_, err = m.Parse(context.Background(), token)
+	is.Equal(err.Error(), "jwt: token is missing an expiry")
+}
+
+func TestIssuerAudience(t *testing.T) {
+	is := is.New(t)
+	m := load(t, map[string]string{"JWT_SECRET": "secret", "JWT_ISSUER": "https://example.com", "JWT_AUDIENCE": "api"})
+	token, err := m.Issue(jwt.Claims{"sub": "1"})
+	is.NoErr(err)
+	claims, err := m.Parse(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(claims.Issuer(), "https://example.com")
+	is.Equal(claims.Audience(), []string{"api"})
+	other := load(t, map[string]string{"JWT_SECRET": "secret", "JWT_ISSUER": "https://example.com", "JWT_AUDIENCE": "admin"})
+	_, err = other.Parse(context.Background(), token)
+	is.Equal(err.Error(), `jwt: token wasn't issued for "admin"`)
+}
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	m := load(t, map[string]string{"JWT_SECRET": "secret"})
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jwt.From(r.Context()))
+	}))
+	serve := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	// Pages outside of /api don't need a token
+	rec := serve("/", "")
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(strings.TrimSpace(rec.Body.String()), "null")
+	rec = serve("/apis", "")
+	is.Equal(rec.Code, http.StatusOK)
+	// API routes do
+	rec = serve("/api/posts", "")
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(rec.Header().Get("WWW-Authenticate"), "Bearer")
+	is.Equal(strings.TrimSpace(rec.Body.String()), `{"error":"missing bearer token"}`)
+	rec = serve("/api/posts", "Bearer nope")
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(rec.Header().Get("WWW-Authenticate"), `Bearer error="invalid_token"`)
+	token, err := m.Issue(jwt.Claims{"sub": "42"})
+	is.NoErr(err)
+	rec = serve("/api/posts", "Bearer "+token)
+	is.Equal(rec.Code, http.StatusOK)
+	var claims jwt.Claims
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &claims))
+	is.Equal(claims.Subject(), "42")
+}
+
+func TestJWKS(t *testing.T) {
+	is := is.New(t)
+	previous := ed25519PEM(t)
+	issuer := load(t, map[string]string{"JWT_PRIVATE_KEY": rsaPEM(t), "JWT_PUBLIC_KEYS": previous})
+	// Publish the public keys
+	server := httptest.NewServer(issuer.Middleware(http.NotFoundHandler()))
+	defer server.Close()
+	res, err := http.Get(server.URL + jwt.JWKSPath)
+	is.NoErr(err)
+	defer res.Body.Close()
+	is.Equal(res.StatusCode, http.StatusOK)
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	is.NoErr(json.NewDecoder(res.Body).Decode(&set))
+	is.Equal(len(set.Keys), 2)
+	is.Equal(set.Keys[0]["kty"], "RSA")
+	is.Equal(set.Keys[1]["kty"], "OKP")
+	for _, key := range set.Keys {
+		is.Equal(key["d"], "")
+	}
+	// Verify tokens from another server with its published keys
+	m := load(t, map[string]string{"JWT_JWKS_URL": server.URL + jwt.JWKSPath})
+	token, err := issuer.Issue(jwt.Claims{"sub": "7"})
+	is.NoErr(err)
+	claims, err := m.Parse(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(claims.Subject(), "7")
+	// Tokens signed with the previous key still verify
+	old := load(t, map[string]string{"JWT_PRIVATE_KEY": previous})
+	token, err = old.Issue(jwt.Claims{"sub": "8"})
+	is.NoErr(err)
+	claims, err = m.Parse(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(claims.Subject(), "8")
+	// Remote keys can't sign
+	_, err = m.Issue(jwt.Claims{"sub": "9"})
+	is.Equal(err.Error(), "jwt: no keys that can sign tokens")
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	_, err := jwt.LoadConfig(func(string) string { return "" })
+	is.Equal(err.Error(), "jwt: missing the JWT_SECRET, JWT_PRIVATE_KEY or JWT_JWKS_URL environment variable")
+	_, err = jwt.LoadConfig(func(key string) string {
+		return map[string]string{"JWT_SECRET": "secret", "JWT_TTL": "soon"}[key]
+	})
+	is.Equal(err.Error(), `jwt: expected JWT_TTL to be a duration like 1h, got "soon"`)
+}
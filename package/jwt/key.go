@@ -0,0 +1,161 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// Supported algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// Key signs or verifies tokens. Keys parsed from public keys can only verify.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	secret  []byte
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+// HMAC returns a HS256 key from a shared secret. The key's ID is derived from
+// the secret.
+func HMAC(secret []byte) *Key {
+	sum := sha256.Sum256(append([]byte("jwt:"), secret...))
+	return &Key{
+		ID:        hex.EncodeToString(sum[:8]),
+		Algorithm: HS256,
+		secret:    secret,
+	}
+}
+
+// ParsePEM parses a RSA or Ed25519 key in PEM format. Private keys may be in
+// PKCS #1 or PKCS #8 form and public keys in PKIX form. The key's ID is its
+// JWK thumbprint.
+func ParsePEM(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt: unable to decode the PEM key")
+	}
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("jwt: unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwt: unable to parse the PEM key. %w", err)
+	}
+	return newKey(parsed)
+}
+
+// newKey wraps a RSA or Ed25519 key
+func newKey(parsed interface{}) (*Key, error) {
+	key := new(Key)
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm, key.private, key.public = RS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Algorithm, key.public = RS256, k
+	case ed25519.PrivateKey:
+		key.Algorithm, key.private, key.public = EdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Algorithm, key.public = EdDSA, k
+	default:
+		return nil, fmt.Errorf("jwt: unsupported key type %T", parsed)
+	}
+	if k, ok := key.public.(*rsa.PublicKey); ok && k.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("jwt: RSA keys must be at least 2048 bits")
+	}
+	key.ID = thumbprint(toJWK(key))
+	return key, nil
+}
+
+// CanSign is true for secrets and private keys
+func (k *Key) CanSign() bool {
+	return k.secret != nil || k.private != nil
+}
+
+// Public returns the key without its private half
+func (k *Key) Public() *Key {
+	return &Key{ID: k.ID, Algorithm: k.Algorithm, public: k.public}
+}
+
+func (k *Key) sign(input []byte) ([]byte, error) {
+	switch k.Algorithm {
+	case HS256:
+		if k.secret == nil {
+			break
+		}
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case RS256:
+		private, ok := k.private.(*rsa.PrivateKey)
+		if !ok {
+			break
+		}
+		sum := sha256.Sum256(input)
+		return rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, sum[:])
+	case EdDSA:
+		private, ok := k.private.(ed25519.PrivateKey)
+		if !ok {
+			break
+		}
+		return ed25519.Sign(private, input), nil
+	}
+	return nil, fmt.Errorf("jwt: key %q can't sign tokens", k.ID)
+}
+
+var errSignature = errors.New("signature doesn't match")
+
+func (k *Key) verify(input, signature []byte) error {
+	switch k.Algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(input)
+		if k.secret == nil || !hmac.Equal(mac.Sum(nil), signature) {
+			return errSignature
+		}
+		return nil
+	case RS256:
+		public, ok := k.public.(*rsa.PublicKey)
+		if !ok {
+			return errSignature
+		}
+		sum := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(public, crypto.SHA256, sum[:], signature); err != nil {
+			return errSignature
+		}
+		return nil
+	case EdDSA:
+		public, ok := k.public.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(public, input, signature) {
+			return errSignature
+		}
+		return nil
+	default:
+		return errSignature
+	}
+}
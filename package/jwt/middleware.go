@@ -0,0 +1,271 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+)
+
+// Load the middleware from the environment
+func Load() (*Middleware, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return New(config), nil
+}
+
+// Config for the middleware
+type Config struct {
+	// Keys sign and verify tokens
+	Keys *KeySet
+	// Remote verifies tokens issued by another server, like an identity
+	// provider
+	Remote *RemoteKeySet
+	// Prefix of the paths that require a token. Defaults to "/api".
+	Prefix string
+	// Issuer is set on new tokens and checked on incoming tokens
+	Issuer string
+	// Audience is set on new tokens and checked on incoming tokens
+	Audience string
+	// TTL is how long new tokens last. Defaults to an hour.
+	TTL time.Duration
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	JWT_SECRET=change-me
+//	JWT_PREVIOUS_SECRETS=old-secret,older-secret
+//	JWT_PRIVATE_KEY=/path/to/private.pem
+//	JWT_PUBLIC_KEYS=/path/to/old.pem,/path/to/older.pem
+//	JWT_JWKS_URL=https://example.com/.well-known/jwks.json
+//	JWT_ISSUER=https://example.com
+//	JWT_AUDIENCE=api
+//	JWT_TTL=1h
+//	JWT_PREFIX=/api
+//
+// JWT_SECRET signs tokens with HS256. JWT_PRIVATE_KEY signs tokens with RS256
+// or EdDSA, depending on the key, and may be a PEM file or the PEM itself.
+// Previous secrets and public keys verify tokens that were signed before the
+// keys were rotated. JWT_JWKS_URL verifies tokens issued by another server.
+func LoadConfig(getenv func(key string) string) (*Config, error) {
+	config := &Config{
+		Prefix:   getenv("JWT_PREFIX"),
+		Issuer:   getenv("JWT_ISSUER"),
+		Audience: getenv("JWT_AUDIENCE"),
+	}
+	keys := new(KeySet)
+	if pem := getenv("JWT_PRIVATE_KEY"); pem != "" {
+		key, err := readPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		if !key.CanSign() {
+			return nil, fmt.Errorf("jwt: expected JWT_PRIVATE_KEY to be a private key")
+		}
+		keys.keys = append(keys.keys, key)
+	}
+	if secret := getenv("JWT_SECRET"); secret != "" {
+		keys.keys = append(keys.keys, HMAC([]byte(secret)))
+	}
+	for _, secret := range split(getenv("JWT_PREVIOUS_SECRETS")) {
+		keys.keys = append(keys.keys, HMAC([]byte(secret)))
+	}
+	for _, pem := range split(getenv("JWT_PUBLIC_KEYS")) {
+		key, err := readPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		keys.keys = append(keys.keys, key.Public())
+	}
+	if len(keys.keys) > 0 {
+		config.Keys = keys
+	}
+	if url := getenv("JWT_JWKS_URL"); url != "" {
+		config.Remote = NewRemoteKeySet(url)
+	}
+	if config.Keys == nil && config.Remote == nil {
+		return nil, fmt.Errorf("jwt: missing the JWT_SECRET, JWT_PRIVATE_KEY or JWT_JWKS_URL environment variable")
+	}
+	if ttl := getenv("JWT_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("jwt: expected JWT_TTL to be a duration like 1h, got %q", ttl)
+		}
+		config.TTL = d
+	}
+	return config, nil
+}
+
+func split(list string) (items []string) {
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// readPEM reads a PEM key or a file containing one
+func readPEM(value string) (*Key, error) {
+	data := []byte(value)
+	if !strings.HasPrefix(strings.TrimSpace(value), "-----BEGIN") {
+		var err error
+		data, err = os.ReadFile(value)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: unable to read key. %w", err)
+		}
+	}
+	return ParsePEM(data)
+}
+
+// New middleware
+func New(config *Config) *Middleware {
+	m := &Middleware{
+		keys:     config.Keys,
+		remote:   config.Remote,
+		prefix:   strings.TrimSuffix(config.Prefix, "/"),
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		ttl:      config.TTL,
+		Clock:    clock.Load(),
+	}
+	if m.keys == nil {
+		m.keys = new(KeySet)
+	}
+	if m.prefix == "" {
+		m.prefix = "/api"
+	}
+	if m.ttl == 0 {
+		m.ttl = time.Hour
+	}
+	return m
+}
+
+// Middleware requires a valid bearer token for requests under the prefix and
+// publishes the public keys at /.well-known/jwks.json
+type Middleware struct {
+	keys     *KeySet
+	remote   *RemoteKeySet
+	prefix   string
+	issuer   string
+	audience string
+	ttl      time.Duration
+
+	// Clock tells the time that tokens are issued and checked at. Defaults to
+	// the system clock.
+	Clock clock.Clock
+}
+
+// leeway allows for clock skew between servers
+const leeway = time.Minute
+
+// JWKSPath is where the public keys are published
+const JWKSPath = "/.well-known/jwks.json"
+
+// Issue a token with the claims. The issuer, audience, issued at and expiry
+// claims are filled in unless they're already set.
+func (m *Middleware) Issue(claims Claims) (string, error) {
+	out := Claims{}
+	now := m.Clock.Now()
+	out["iat"] = now.Unix()
+	out["exp"] = now.Add(m.ttl).Unix()
+	if m.issuer != "" {
+		out["iss"] = m.issuer
+	}
+	if m.audience != "" {
+		out["aud"] = m.audience
+	}
+	for key, value := range claims {
+		out[key] = value
+	}
+	return m.keys.Sign(out)
+}
+
+// Parse verifies the token's signature and checks its expiry, issuer and
+// audience. Tokens must expire.
+func (m *Middleware) Parse(ctx context.Context, token string) (Claims, error) {
+	claims, err := m.keys.Verify(ctx, token)
+	if err != nil && m.remote != nil && errors.Is(err, ErrInvalid) {
+		claims, err = m.remote.Verify(ctx, token)
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := m.Clock.Now()
+	exp := claims.ExpiresAt()
+	if exp.IsZero() {
+		return nil, fmt.Errorf("jwt: token is missing an expiry")
+	} else if now.After(exp.Add(leeway)) {
+		return nil, fmt.Errorf("jwt: token has expired")
+	}
+	if nbf := claims.Time("nbf"); !nbf.IsZero() && now.Add(leeway).Before(nbf) {
+		return nil, fmt.Errorf("jwt: token isn't valid yet")
+	}
+	if m.issuer != "" && claims.Issuer() != m.issuer {
+		return nil, fmt.Errorf("jwt: unexpected issuer %q", claims.Issuer())
+	}
+	if m.audience != "" && !contains(claims.Audience(), m.audience) {
+		return nil, fmt.Errorf("jwt: token wasn't issued for %q", m.audience)
+	}
+	return claims, nil
+}
+
+// Middleware checks the bearer token and adds its claims to the context
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == JWKSPath && r.Method == http.MethodGet {
+			m.serveJWKS(w)
+			return
+		}
+		if r.URL.Path != m.prefix && !strings.HasPrefix(r.URL.Path, m.prefix+"/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := bearer(r)
+		if !ok {
+			unauthorized(w, `Bearer`, "missing bearer token")
+			return
+		}
+		claims, err := m.Parse(r.Context(), token)
+		if err != nil {
+			unauthorized(w, `Bearer error="invalid_token"`, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(With(r.Context(), claims)))
+	})
+}
+
+func (m *Middleware) serveJWKS(w http.ResponseWriter) {
+	data, err := json.Marshal(m.keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(data)
+}
+
+func bearer(r *http.Request) (string, bool) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
+
+func unauthorized(w http.ResponseWriter, challenge, message string) {
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,97 @@
+// Package lifecycle starts and stops long-lived services, like database pools
+// and job workers, in dependency order.
+//
+//	type Pool struct { ... }
+//
+//	func (p *Pool) Start(ctx context.Context) error { ... }
+//	func (p *Pool) Stop(ctx context.Context) error { ... }
+//
+// Bud's generated main starts the services it creates after loading them and
+// stops them in reverse when the app shuts down.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/livebud/bud/internal/errs"
+)
+
+// Starter is a service that starts when the app boots
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper is a service that stops when the app shuts down
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// New hooks for the services, ordered so that each service comes after the
+// services it depends on. Services that are neither a Starter nor a Stopper
+// are ignored.
+func New(services ...interface{}) *Hooks {
+	hooks := new(Hooks)
+	for _, service := range services {
+		starter, _ := service.(Starter)
+		stopper, _ := service.(Stopper)
+		if starter == nil && stopper == nil {
+			continue
+		}
+		hooks.services = append(hooks.services, &hook{service, starter, stopper})
+	}
+	return hooks
+}
+
+// Hooks start and stop services
+type Hooks struct {
+	services []*hook
+	mu       sync.Mutex
+	started  int // Number of services that have started
+}
+
+type hook struct {
+	service interface{}
+	starter Starter
+	stopper Stopper
+}
+
+// Start the services in order. If a service fails to start, the services that
+// already started are stopped in reverse.
+func (h *Hooks) Start(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for h.started < len(h.services) {
+		hook := h.services[h.started]
+		if hook.starter != nil {
+			if err := hook.starter.Start(ctx); err != nil {
+				err = fmt.Errorf("lifecycle: unable to start %T. %w", hook.service, err)
+				return errs.Join(err, h.stop(ctx))
+			}
+		}
+		h.started++
+	}
+	return nil
+}
+
+// Stop the services that started in reverse. Every service is stopped, even
+// when one of them fails to stop.
+func (h *Hooks) Stop(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.stop(ctx)
+}
+
+func (h *Hooks) stop(ctx context.Context) (err error) {
+	for ; h.started > 0; h.started-- {
+		hook := h.services[h.started-1]
+		if hook.stopper == nil {
+			continue
+		}
+		if e := hook.stopper.Stop(ctx); e != nil {
+			err = errs.Join(err, fmt.Errorf("lifecycle: unable to stop %T. %w", hook.service, e))
+		}
+	}
+	return err
+}
@@ -0,0 +1,92 @@
+package lifecycle_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/lifecycle"
+)
+
+type service struct {
+	name    string
+	calls   *[]string
+	failing string
+}
+
+func (s *service) Start(ctx context.Context) error {
+	*s.calls = append(*s.calls, "start "+s.name)
+	if s.failing == "start" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+func (s *service) Stop(ctx context.Context) error {
+	*s.calls = append(*s.calls, "stop "+s.name)
+	if s.failing == "stop" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+type stopper struct {
+	calls *[]string
+}
+
+func (s *stopper) Stop(ctx context.Context) error {
+	*s.calls = append(*s.calls, "stop stopper")
+	return nil
+}
+
+func TestStartStop(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	var calls []string
+	hooks := lifecycle.New(
+		&service{name: "db", calls: &calls},
+		"not a service",
+		&stopper{calls: &calls},
+		&service{name: "worker", calls: &calls},
+	)
+	is.NoErr(hooks.Start(ctx))
+	is.NoErr(hooks.Stop(ctx))
+	is.Equal(calls, []string{"start db", "start worker", "stop worker", "stop stopper", "stop db"})
+	// Stopping again is a no-op
+	is.NoErr(hooks.Stop(ctx))
+	is.Equal(len(calls), 5)
+}
+
+func TestStartFails(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	var calls []string
+	hooks := lifecycle.New(
+		&service{name: "db", calls: &calls},
+		&service{name: "cache", calls: &calls, failing: "start"},
+		&service{name: "worker", calls: &calls},
+	)
+	err := hooks.Start(ctx)
+	is.True(err != nil)
+	is.Equal(err.Error(), "lifecycle: unable to start *lifecycle_test.service. boom")
+	is.Equal(calls, []string{"start db", "start cache", "stop db"})
+	// Nothing left to stop
+	is.NoErr(hooks.Stop(ctx))
+	is.Equal(len(calls), 3)
+}
+
+func TestStopFails(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	var calls []string
+	hooks := lifecycle.New(
+		&service{name: "db", calls: &calls, failing: "stop"},
+		&service{name: "worker", calls: &calls, failing: "stop"},
+	)
+	is.NoErr(hooks.Start(ctx))
+	err := hooks.Stop(ctx)
+	is.True(err != nil)
+	is.Equal(err.Error(), "lifecycle: unable to stop *lifecycle_test.service. boom. lifecycle: unable to stop *lifecycle_test.service. boom")
+	is.Equal(calls, []string{"start db", "start worker", "stop worker", "stop db"})
+}
@@ -0,0 +1,325 @@
+// Package accesslog writes a line for every request the app serves, apart from
+// the app's own logs. Access logs are off unless $ACCESS_LOG picks a format:
+//
+//	ACCESS_LOG=combined    Apache's combined log format
+//	ACCESS_LOG=common      Apache's common log format
+//	ACCESS_LOG=json        one JSON object per line
+//
+// Access logs are written to stdout or to $ACCESS_LOG_FILE.
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/log/sink"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+// Fields that can be written in the JSON format
+var Fields = []string{
+	"time",
+	"method",
+	"path",
+	"query",
+	"route",
+	"status",
+	"bytes",
+	"latency",
+	"client_ip",
+	"user_agent",
+	"referer",
+	"host",
+	"proto",
+	"request_id",
+}
+
+// DefaultFields are written in the JSON format when $ACCESS_LOG_FIELDS isn't
+// set. The query is left out because it can hold secrets, like tokens.
+var DefaultFields = []string{
+	"time",
+	"method",
+	"path",
+	"route",
+	"status",
+	"bytes",
+	"latency",
+	"client_ip",
+	"user_agent",
+	"request_id",
+}
+
+// Load the middleware from the environment
+func Load() (*Middleware, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	if config.Format == "" {
+		return New(io.Discard, config), nil
+	}
+	if config.File == "" {
+		return New(os.Stdout, config), nil
+	}
+	file := &sink.RotatingFile{Path: config.File, MaxSize: 100 << 20, MaxBackups: 5}
+	return New(file, config), nil
+}
+
+// Config for the middleware
+type Config struct {
+	// Format is "combined", "common" or "json". Empty turns access logs off.
+	Format string
+	// Fields to write in the JSON format. Defaults to DefaultFields.
+	Fields []string
+	// File to write to instead of stdout
+	File string
+	// TrustProxy uses the client IP from X-Forwarded-For. Only turn this on
+	// when the app is behind a proxy that sets it, otherwise clients can send
+	// any IP they like.
+	TrustProxy bool
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	ACCESS_LOG=json
+//	ACCESS_LOG_FIELDS=time,method,path,status,latency
+//	ACCESS_LOG_FILE=log/access.log
+//	ACCESS_LOG_TRUST_PROXY=true
+func LoadConfig(getenv func(key string) string) (*Config, error) {
+	config := &Config{
+		Format: getenv("ACCESS_LOG"),
+		File:   getenv("ACCESS_LOG_FILE"),
+	}
+	switch config.Format {
+	case "", "combined", "common", "json":
+	default:
+		return nil, errors.New(`accesslog: expected ACCESS_LOG to be "combined", "common" or "json"`)
+	}
+	if value := getenv("ACCESS_LOG_FIELDS"); value != "" {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if !validField(field) {
+				return nil, fmt.Errorf("accesslog: unknown field %q in ACCESS_LOG_FIELDS. Expected one of %s", field, strings.Join(Fields, ", "))
+			}
+			config.Fields = append(config.Fields, field)
+		}
+	}
+	if value := getenv("ACCESS_LOG_TRUST_PROXY"); value != "" {
+		trust, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("accesslog: invalid ACCESS_LOG_TRUST_PROXY %q", value)
+		}
+		config.TrustProxy = trust
+	}
+	return config, nil
+}
+
+func validField(field string) bool {
+	for _, known := range Fields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// New access log middleware that writes to w
+func New(w io.Writer, config *Config) *Middleware {
+	m := &Middleware{
+		w:          w,
+		format:     config.Format,
+		fields:     config.Fields,
+		trustProxy: config.TrustProxy,
+		now:        time.Now,
+	}
+	if len(m.fields) == 0 {
+		m.fields = DefaultFields
+	}
+	return m
+}
+
+// Middleware logs each request after it's served
+type Middleware struct {
+	w          io.Writer
+	format     string
+	fields     []string
+	trustProxy bool
+	now        func() time.Time
+
+	mu sync.Mutex
+}
+
+// Middleware implements middleware.Middleware
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	if m.format == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := m.now()
+		ctx, route := router.Track(r.Context())
+		// Keep the original path, since middleware may change it
+		path, query := r.URL.Path, r.URL.RawQuery
+		rw := middleware.RecordStatus(w)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		m.write(&Record{
+			Time:      start,
+			Method:    r.Method,
+			Path:      path,
+			Query:     query,
+			Route:     route(),
+			Status:    rw.Status(),
+			Bytes:     rw.Bytes(),
+			Latency:   m.now().Sub(start),
+			ClientIP:  m.clientIP(r),
+			UserAgent: r.UserAgent(),
+			Referer:   r.Referer(),
+			Host:      r.Host,
+			Proto:     r.Proto,
+			RequestID: w.Header().Get(reqlog.Header),
+		})
+	})
+}
+
+// Record of a request that was served
+type Record struct {
+	Time      time.Time
+	Method    string
+	Path      string
+	Query     string
+	Route     string
+	Status    int
+	Bytes     int64
+	Latency   time.Duration
+	ClientIP  string
+	UserAgent string
+	Referer   string
+	Host      string
+	Proto     string
+	RequestID string
+}
+
+func (m *Middleware) write(record *Record) {
+	buf := new(bytes.Buffer)
+	switch m.format {
+	case "json":
+		writeJSON(buf, record, m.fields)
+	case "common":
+		writeCommon(buf, record)
+	default:
+		writeCommon(buf, record)
+		buf.WriteString(" " + quote(record.Referer) + " " + quote(record.UserAgent))
+	}
+	buf.WriteByte('\n')
+	// Writes are serialized so lines don't interleave
+	m.mu.Lock()
+	m.w.Write(buf.Bytes())
+	m.mu.Unlock()
+}
+
+// writeCommon writes the common log format, like:
+//
+//	127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /posts HTTP/1.1" 200 2326
+func writeCommon(buf *bytes.Buffer, record *Record) {
+	uri := record.Path
+	if record.Query != "" {
+		uri += "?" + record.Query
+	}
+	buf.WriteString(dash(record.ClientIP))
+	buf.WriteString(" - - [")
+	buf.WriteString(record.Time.Format("02/Jan/2006:15:04:05 -0700"))
+	buf.WriteString("] ")
+	buf.WriteString(quote(record.Method + " " + uri + " " + record.Proto))
+	buf.WriteString(" " + strconv.Itoa(record.Status) + " ")
+	if record.Bytes == 0 {
+		buf.WriteString("-")
+	} else {
+		buf.WriteString(strconv.FormatInt(record.Bytes, 10))
+	}
+}
+
+// writeJSON writes the fields in order. Latency is in milliseconds.
+func writeJSON(buf *bytes.Buffer, record *Record, fields []string) {
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(`"` + field + `":`)
+		var value interface{}
+		switch field {
+		case "time":
+			value = record.Time.Format(time.RFC3339Nano)
+		case "method":
+			value = record.Method
+		case "path":
+			value = record.Path
+		case "query":
+			value = record.Query
+		case "route":
+			value = record.Route
+		case "status":
+			value = record.Status
+		case "bytes":
+			value = record.Bytes
+		case "latency":
+			value = float64(record.Latency.Microseconds()) / 1000
+		case "client_ip":
+			value = record.ClientIP
+		case "user_agent":
+			value = record.UserAgent
+		case "referer":
+			value = record.Referer
+		case "host":
+			value = record.Host
+		case "proto":
+			value = record.Proto
+		case "request_id":
+			value = record.RequestID
+		}
+		data, _ := json.Marshal(value)
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+}
+
+// quote a value for the common log formats. Quotes and control characters are
+// escaped so clients can't forge log lines.
+func quote(s string) string {
+	if s == "" {
+		return `"-"`
+	}
+	return strconv.Quote(s)
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (m *Middleware) clientIP(r *http.Request) string {
+	if m.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(ip)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
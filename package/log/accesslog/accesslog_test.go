@@ -0,0 +1,123 @@
+package accesslog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log/accesslog"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/router"
+)
+
+func serve(m *accesslog.Middleware, req *http.Request) *httptest.ResponseRecorder {
+	rt := router.New()
+	rt.Get("/posts/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(reqlog.Header, "abc")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+	rec := httptest.NewRecorder()
+	m.Middleware(rt).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestJSON(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	m := accesslog.New(buf, &accesslog.Config{Format: "json"})
+	req := httptest.NewRequest(http.MethodGet, "/posts/10?token=secret", nil)
+	req.Header.Set("User-Agent", "test")
+	serve(m, req)
+	var record map[string]interface{}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &record))
+	is.Equal(len(record), len(accesslog.DefaultFields))
+	is.Equal(record["method"], "GET")
+	is.Equal(record["path"], "/posts/10")
+	is.Equal(record["route"], "/posts/:id")
+	is.Equal(record["status"], 201.0)
+	is.Equal(record["bytes"], 5.0)
+	is.Equal(record["client_ip"], "192.0.2.1")
+	is.Equal(record["user_agent"], "test")
+	is.Equal(record["request_id"], "abc")
+	_, ok := record["latency"].(float64)
+	is.True(ok)
+	// The query is left out by default
+	_, ok = record["query"]
+	is.True(!ok)
+}
+
+func TestFields(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	m := accesslog.New(buf, &accesslog.Config{Format: "json", Fields: []string{"status", "query", "route"}})
+	serve(m, httptest.NewRequest(http.MethodGet, "/posts/10?page=2", nil))
+	is.Equal(buf.String(), `{"status":201,"query":"page=2","route":"/posts/:id"}`+"\n")
+	// Unmatched routes
+	buf.Reset()
+	serve(m, httptest.NewRequest(http.MethodGet, "/users", nil))
+	is.Equal(buf.String(), `{"status":404,"query":"","route":""}`+"\n")
+}
+
+func TestCombined(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	m := accesslog.New(buf, &accesslog.Config{Format: "combined", TrustProxy: true})
+	req := httptest.NewRequest(http.MethodGet, "/posts/10?page=2", nil)
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("Referer", "http://example.com/")
+	req.Header.Set("User-Agent", `evil"agent`)
+	serve(m, req)
+	line := buf.String()
+	is.True(strings.HasPrefix(line, "203.0.113.7 - - ["))
+	is.True(strings.HasSuffix(line, `] "GET /posts/10?page=2 HTTP/1.1" 201 5 "http://example.com/" "evil\"agent"`+"\n"))
+}
+
+func TestCommon(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	m := accesslog.New(buf, &accesslog.Config{Format: "common"})
+	req := httptest.NewRequest(http.MethodGet, "/posts/10", nil)
+	// Untrusted proxies are ignored
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	serve(m, req)
+	line := buf.String()
+	is.True(strings.HasPrefix(line, "192.0.2.1 - - ["))
+	is.True(strings.HasSuffix(line, `] "GET /posts/10 HTTP/1.1" 201 5`+"\n"))
+}
+
+func TestOff(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	m := accesslog.New(buf, &accesslog.Config{})
+	rec := serve(m, httptest.NewRequest(http.MethodGet, "/posts/10", nil))
+	is.Equal(rec.Code, 201)
+	is.Equal(buf.Len(), 0)
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	config, err := accesslog.LoadConfig(getenv)
+	is.NoErr(err)
+	is.Equal(config.Format, "")
+	env["ACCESS_LOG"] = "apache"
+	_, err = accesslog.LoadConfig(getenv)
+	is.Equal(err.Error(), `accesslog: expected ACCESS_LOG to be "combined", "common" or "json"`)
+	env["ACCESS_LOG"] = "json"
+	env["ACCESS_LOG_FIELDS"] = "status, latency,size"
+	_, err = accesslog.LoadConfig(getenv)
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), `unknown field "size"`))
+	env["ACCESS_LOG_FIELDS"] = "status, latency"
+	env["ACCESS_LOG_TRUST_PROXY"] = "true"
+	config, err = accesslog.LoadConfig(getenv)
+	is.NoErr(err)
+	is.Equal(config.Fields, []string{"status", "latency"})
+	is.True(config.TrustProxy)
+}
@@ -0,0 +1,19 @@
+package log
+
+import "context"
+
+type contextKey struct{}
+
+// With returns a context that carries the logger
+func With(ctx context.Context, log Interface) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// From returns the logger within the context, like the request's logger. It
+// returns Discard when the context doesn't have a logger.
+func From(ctx context.Context) Interface {
+	if log, ok := ctx.Value(contextKey{}).(Interface); ok {
+		return log
+	}
+	return Discard
+}
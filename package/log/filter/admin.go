@@ -0,0 +1,77 @@
+package filter
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Admin lets you see and change the log levels of a running app at
+// /bud/log. It's turned off without a password.
+//
+//	GET /bud/log  shows the pattern
+//	PUT /bud/log  sets the pattern to the body, like "warn,view=debug"
+//
+// Requests use basic auth with the password and any username.
+type Admin struct {
+	Filter   *Filter
+	Password string
+}
+
+// Middleware serves /bud/log
+func (a *Admin) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.Password == "" || r.URL.Path != "/bud/log" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		_, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(a.Password)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="log", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			// Browsers send basic auth along with cross-site forms, so only accept
+			// changes from the same site or from outside a browser
+			if !sameOrigin(r) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			pattern, err := io.ReadAll(io.LimitReader(r.Body, 4096))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if err := a.Filter.Set(strings.TrimSpace(string(pattern))); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, a.Filter.Pattern())
+	})
+}
+
+// sameOrigin checks that changes don't come from another site. Requests
+// without an origin, like from curl, are allowed.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
@@ -1,27 +1,166 @@
 package filter
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
 	"github.com/livebud/bud/package/log"
 )
 
-// Load console handler
-func Load(handler log.Handler, pattern string) (log.Handler, error) {
-	level, err := log.ParseLevel(pattern)
-	if err != nil {
+// Load a filter from a pattern. Patterns are a level, like "info", or levels
+// for named loggers, like "warn,view=debug,web=info". Named loggers inherit
+// the level of their parents, so "view=debug" applies to "view.ssr" too. An
+// empty pattern falls back to $LOG_LEVEL, then to "info".
+func Load(handler log.Handler, pattern string) (*Filter, error) {
+	if pattern == "" {
+		pattern = os.Getenv("LOG_LEVEL")
+	}
+	filter := &Filter{Handler: handler}
+	if err := filter.Set(pattern); err != nil {
 		return nil, err
 	}
-	return &Filter{handler, level}, nil
+	return filter, nil
+}
+
+// Parse a pattern into the default level and the levels of named loggers.
+// The default level is "info" unless it's in the pattern.
+func Parse(pattern string) (log.Level, map[string]log.Level, error) {
+	level := log.InfoLevel
+	levels := map[string]log.Level{}
+	for _, part := range strings.Split(pattern, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, named := strings.Cut(part, "=")
+		lvl, err := log.ParseLevel(strings.TrimSpace(value))
+		if !named {
+			lvl, err = log.ParseLevel(part)
+		}
+		if err != nil {
+			return 0, nil, fmt.Errorf("filter: invalid pattern %q. %w", pattern, err)
+		}
+		if !named {
+			level = lvl
+			continue
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return 0, nil, fmt.Errorf("filter: invalid pattern %q. Missing a name before %q", pattern, "="+value)
+		}
+		levels[name] = lvl
+	}
+	return level, levels, nil
 }
 
 // Filter logs by level. Can be initialized manually or by the Load function.
+// Levels can be changed while logging.
 type Filter struct {
 	Handler log.Handler
 	Level   log.Level
+
+	mu       sync.RWMutex
+	levels   map[string]log.Level // Levels of named loggers
+	previous string               // Pattern before toggling debug logs
 }
 
+var _ log.Handler = (*Filter)(nil)
+
 func (f *Filter) Log(entry log.Entry) {
-	if entry.Level < f.Level {
+	if entry.Level < f.level(entry.Name) {
 		return
 	}
 	f.Handler.Log(entry)
 }
+
+// level finds the level of the closest named logger
+func (f *Filter) level(name string) log.Level {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for name != "" {
+		if level, ok := f.levels[name]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return f.Level
+}
+
+// Set the levels to the pattern
+func (f *Filter) Set(pattern string) error {
+	level, levels, err := Parse(pattern)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.Level = level
+	f.levels = levels
+	f.previous = ""
+	return nil
+}
+
+// Pattern returns the current levels as a pattern
+func (f *Filter) Pattern() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.pattern()
+}
+
+func (f *Filter) pattern() string {
+	parts := []string{f.Level.String()}
+	names := make([]string, 0, len(f.levels))
+	for name := range f.levels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parts = append(parts, name+"="+f.levels[name].String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// Toggle between debug logs and the levels before. It returns the new pattern.
+func (f *Filter) Toggle() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.previous != "" {
+		// Patterns were valid when they were set
+		level, levels, _ := Parse(f.previous)
+		f.Level, f.levels, f.previous = level, levels, ""
+		return f.pattern()
+	}
+	f.previous = f.pattern()
+	f.Level, f.levels = log.DebugLevel, nil
+	return f.pattern()
+}
+
+// Listen toggles debug logs when the process receives SIGUSR1, like
+// "kill -USR1 <pid>", until the context is canceled. It does nothing on
+// Windows, which doesn't have the signal.
+func (f *Filter) Listen(ctx context.Context) {
+	if toggleSignal == nil {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, toggleSignal)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-signals:
+			pattern := f.Toggle()
+			f.Handler.Log(log.Entry{Level: log.NoticeLevel, Message: "filter: changed the log levels", Fields: []log.Field{{Key: "pattern", Value: pattern}}})
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package filter_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/filter"
+)
+
+type recorder []log.Entry
+
+func (r *recorder) Log(entry log.Entry) {
+	*r = append(*r, entry)
+}
+
+// messages that were logged
+func (r *recorder) messages() string {
+	var messages []string
+	for _, entry := range *r {
+		messages = append(messages, entry.Message)
+	}
+	*r = nil
+	return strings.Join(messages, " ")
+}
+
+func TestParse(t *testing.T) {
+	is := is.New(t)
+	level, levels, err := filter.Parse("warn, view=debug,web.router=error")
+	is.NoErr(err)
+	is.Equal(level, log.WarnLevel)
+	is.Equal(len(levels), 2)
+	is.Equal(levels["view"], log.DebugLevel)
+	is.Equal(levels["web.router"], log.ErrorLevel)
+	// The default level is info
+	level, _, err = filter.Parse("view=debug")
+	is.NoErr(err)
+	is.Equal(level, log.InfoLevel)
+	_, _, err = filter.Parse("view=loud")
+	is.True(err != nil)
+	_, _, err = filter.Parse("=debug")
+	is.True(err != nil)
+}
+
+func TestNamed(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	f, err := filter.Load(rec, "warn,view=debug,view.ssr=error")
+	is.NoErr(err)
+	logger := log.New(f)
+	logger.Info("a")
+	logger.Warn("b")
+	is.Equal(rec.messages(), "b")
+	// Named loggers inherit the level of their parents
+	view := logger.Named("view")
+	view.Debug("c")
+	view.Named("dom").Debug("d")
+	view.Named("ssr").Warn("e")
+	view.Named("ssr").Error("f")
+	is.Equal(rec.messages(), "c d f")
+	logger.Named("view").Named("dom").Info("g")
+	is.Equal((*rec)[0].Name, "view.dom")
+}
+
+func TestSet(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	f, err := filter.Load(rec, "info")
+	is.NoErr(err)
+	logger := log.New(f).Named("web")
+	logger.Debug("a")
+	is.Equal(rec.messages(), "")
+	is.NoErr(f.Set("web=debug"))
+	is.Equal(f.Pattern(), "info,web=debug")
+	logger.Debug("b")
+	is.Equal(rec.messages(), "b")
+	is.True(f.Set("web=loud") != nil)
+	// Invalid patterns leave the levels alone
+	is.Equal(f.Pattern(), "info,web=debug")
+}
+
+func TestToggle(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	f, err := filter.Load(rec, "error,job=warn")
+	is.NoErr(err)
+	is.Equal(f.Toggle(), "debug")
+	log.New(f).Named("job").Debug("a")
+	is.Equal(rec.messages(), "a")
+	is.Equal(f.Toggle(), "error,job=warn")
+	log.New(f).Named("job").Debug("b")
+	is.Equal(rec.messages(), "")
+}
+
+func TestLevelFromEnv(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("LOG_LEVEL", "error,view=info")
+	f, err := filter.Load(new(recorder), "")
+	is.NoErr(err)
+	is.Equal(f.Pattern(), "error,view=info")
+	// Patterns take precedence
+	f, err = filter.Load(new(recorder), "debug")
+	is.NoErr(err)
+	is.Equal(f.Pattern(), "debug")
+}
+
+func TestAdmin(t *testing.T) {
+	is := is.New(t)
+	f, err := filter.Load(new(recorder), "info")
+	is.NoErr(err)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("next"))
+	})
+	request := func(admin *filter.Admin, method, body, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/bud/log", strings.NewReader(body))
+		if password != "" {
+			req.SetBasicAuth("admin", password)
+		}
+		rec := httptest.NewRecorder()
+		admin.Middleware(next).ServeHTTP(rec, req)
+		return rec
+	}
+	// Turned off without a password
+	res := request(&filter.Admin{Filter: f}, http.MethodGet, "", "")
+	is.Equal(res.Body.String(), "next")
+	admin := &filter.Admin{Filter: f, Password: "secret"}
+	res = request(admin, http.MethodGet, "", "")
+	is.Equal(res.Code, http.StatusUnauthorized)
+	res = request(admin, http.MethodGet, "", "wrong")
+	is.Equal(res.Code, http.StatusUnauthorized)
+	res = request(admin, http.MethodGet, "", "secret")
+	is.Equal(res.Code, http.StatusOK)
+	is.Equal(res.Body.String(), "info\n")
+	res = request(admin, http.MethodPut, "warn,view=debug\n", "secret")
+	is.Equal(res.Code, http.StatusOK)
+	is.Equal(res.Body.String(), "warn,view=debug\n")
+	is.Equal(f.Pattern(), "warn,view=debug")
+	res = request(admin, http.MethodPut, "view=loud", "secret")
+	is.Equal(res.Code, http.StatusBadRequest)
+	is.Equal(f.Pattern(), "warn,view=debug")
+}
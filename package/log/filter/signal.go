@@ -0,0 +1,11 @@
+//go:build !windows
+
+package filter
+
+import (
+	"os"
+	"syscall"
+)
+
+// toggleSignal toggles debug logs
+var toggleSignal os.Signal = syscall.SIGUSR1
@@ -0,0 +1,6 @@
+package filter
+
+import "os"
+
+// toggleSignal is nil, since Windows doesn't have SIGUSR1
+var toggleSignal os.Signal
@@ -0,0 +1,36 @@
+// Package format picks how logs are written. Console logs are easy to read
+// while developing, while JSON logs are easy to collect and search in
+// production.
+package format
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/console"
+	"github.com/livebud/bud/package/log/jsonlog"
+)
+
+// Log formats
+const (
+	Console = "console"
+	JSON    = "json"
+)
+
+// Load the handler for the format. An empty format falls back to
+// $LOG_FORMAT, then to console logs.
+func Load(w io.Writer, format string) (log.Handler, error) {
+	if format == "" {
+		format = os.Getenv("LOG_FORMAT")
+	}
+	switch format {
+	case "", Console:
+		return console.New(w), nil
+	case JSON:
+		return jsonlog.New(w), nil
+	default:
+		return nil, fmt.Errorf("format: unknown log format %q. Use %q or %q", format, Console, JSON)
+	}
+}
@@ -0,0 +1,36 @@
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/format"
+)
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("LOG_FORMAT", "")
+	out := new(bytes.Buffer)
+	handler, err := format.Load(out, "")
+	is.NoErr(err)
+	log.New(handler).Info("hello")
+	is.True(!strings.HasPrefix(out.String(), "{"))
+	// $LOG_FORMAT picks the format
+	t.Setenv("LOG_FORMAT", "json")
+	out.Reset()
+	handler, err = format.Load(out, "")
+	is.NoErr(err)
+	log.New(handler).Info("hello")
+	is.True(strings.HasPrefix(out.String(), `{"time":`))
+	// Formats passed in take precedence
+	out.Reset()
+	handler, err = format.Load(out, "console")
+	is.NoErr(err)
+	log.New(handler).Info("hello")
+	is.True(!strings.HasPrefix(out.String(), "{"))
+	_, err = format.Load(out, "xml")
+	is.True(err != nil)
+}
@@ -0,0 +1,140 @@
+// Package jsonlog writes logs as JSON, one object per line, for production
+// where logs are collected and searched. Every entry has the same keys:
+//
+//	{"time":"2022-01-01T00:00:00Z","level":"info","msg":"posts: created","id":"1"}
+//
+// The name of the logger and fields follow, in order. Errors include a "stack" of where they were logged.
+package jsonlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Keys that are in every entry. Fields with these keys are prefixed with
+// "fields." so they don't clash.
+const (
+	TimeKey    = "time"
+	LevelKey   = "level"
+	MessageKey = "msg"
+	NameKey    = "logger"
+	PathKey    = "caller"
+	StackKey   = "stack"
+)
+
+// New JSON handler
+func New(w io.Writer) log.Handler {
+	return &Handler{Writer: w, Now: time.Now}
+}
+
+// Handler writes JSON logs. Can be initialized manually or by the New
+// function.
+type Handler struct {
+	mu     sync.Mutex
+	Writer io.Writer
+	Now    func() time.Time
+}
+
+var _ log.Handler = (*Handler)(nil)
+
+// Log implements log.Handler
+func (h *Handler) Log(entry log.Entry) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	writeKey(buf, TimeKey, false)
+	writeString(buf, h.Now().UTC().Format(time.RFC3339Nano))
+	writeKey(buf, LevelKey, true)
+	writeString(buf, entry.Level.String())
+	writeKey(buf, MessageKey, true)
+	writeString(buf, entry.Message)
+	if entry.Name != "" {
+		writeKey(buf, NameKey, true)
+		writeString(buf, entry.Name)
+	}
+	if entry.Path != "" {
+		writeKey(buf, PathKey, true)
+		writeString(buf, entry.Path)
+	}
+	for _, field := range entry.Fields {
+		writeKey(buf, fieldKey(field.Key), true)
+		writeString(buf, field.Value)
+	}
+	if entry.Level == log.ErrorLevel {
+		writeKey(buf, StackKey, true)
+		stack, _ := json.Marshal(callers())
+		buf.Write(stack)
+	}
+	buf.WriteString("}\n")
+	// Write out
+	h.mu.Lock()
+	h.Writer.Write(buf.Bytes())
+	h.mu.Unlock()
+}
+
+// fieldKey moves fields out of the way of the keys in every entry
+func fieldKey(key string) string {
+	switch key {
+	case TimeKey, LevelKey, MessageKey, NameKey, PathKey, StackKey:
+		return "fields." + key
+	default:
+		return key
+	}
+}
+
+func writeKey(buf *bytes.Buffer, key string, comma bool) {
+	if comma {
+		buf.WriteByte(',')
+	}
+	writeString(buf, key)
+	buf.WriteByte(':')
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	// Strings always encode
+	out, _ := json.Marshal(s)
+	buf.Write(out)
+}
+
+// callers returns the stack that led to the log, leaving out the logger
+func callers() []string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []string
+	for {
+		frame, more := frames.Next()
+		if !internal(frame.Function) {
+			stack = append(stack, fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// internal frames are from the logger or the runtime
+func internal(function string) bool {
+	const logPackage = "github.com/livebud/bud/package/log"
+	// Functions look like "github.com/livebud/bud/package/log.(*logger).Error"
+	pkg := function
+	if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+		if j := strings.IndexByte(pkg[i:], '.'); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	} else if j := strings.IndexByte(pkg, '.'); j >= 0 {
+		pkg = pkg[:j]
+	}
+	if strings.HasSuffix(pkg, "_test") {
+		return false
+	}
+	return pkg == "runtime" || pkg == logPackage || strings.HasPrefix(pkg, logPackage+"/")
+}
@@ -0,0 +1,49 @@
+package jsonlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/jsonlog"
+)
+
+func TestJSON(t *testing.T) {
+	is := is.New(t)
+	out := new(bytes.Buffer)
+	handler := &jsonlog.Handler{
+		Writer: out,
+		Now:    func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+	logger := log.New(handler)
+	logger.Info("posts: created", "id", 1, "title", `say "hi"`)
+	logger.Warn("posts: slow", "level", "high")
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	is.Equal(len(lines), 2)
+	is.Equal(lines[0], `{"time":"2022-01-01T00:00:00Z","level":"info","msg":"posts: created","id":"1","title":"say \"hi\""}`)
+	// Fields don't clash with the keys in every entry
+	is.Equal(lines[1], `{"time":"2022-01-01T00:00:00Z","level":"warn","msg":"posts: slow","fields.level":"high"}`)
+}
+
+func TestErrorStack(t *testing.T) {
+	is := is.New(t)
+	out := new(bytes.Buffer)
+	logger := log.New(jsonlog.New(out))
+	logger.Error("posts: unable to create", "error", "boom")
+	var entry struct {
+		Level string
+		Msg   string
+		Error string
+		Stack []string
+	}
+	is.NoErr(json.Unmarshal(out.Bytes(), &entry))
+	is.Equal(entry.Level, "error")
+	is.Equal(entry.Error, "boom")
+	is.True(len(entry.Stack) > 0)
+	// The stack starts where the error was logged
+	is.True(strings.HasPrefix(entry.Stack[0], "github.com/livebud/bud/package/log/jsonlog_test.TestErrorStack "))
+}
@@ -28,6 +28,7 @@ type Field struct {
 
 type Entry struct {
 	Level   Level
+	Name    string // Name of the logger, like "view.ssr". Can be empty
 	Message string
 	Fields  []Field
 	Path    string // File path can be empty
@@ -48,6 +49,12 @@ type Interface interface {
 	Notice(message string, args ...interface{})
 	Warn(message string, args ...interface{})
 	Error(message string, args ...interface{})
+	// Named returns a logger for part of the app. Names are nested with dots,
+	// so log.Named("view").Named("ssr") is named "view.ssr". Filters can set
+	// the level of each name.
+	Named(name string) Interface
+	// New returns a logger that adds the fields to every log, like a request ID
+	New(fields ...interface{}) Interface
 }
 
 type dispatcher func(log Entry)
@@ -85,6 +92,7 @@ func New(handler Handler, options ...Option) Interface {
 
 type logger struct {
 	Handler     Handler
+	name        string
 	fields      []Field
 	includePath bool
 }
@@ -101,14 +109,15 @@ func (l *logger) path() string {
 	return filepath.Dir(filename)
 }
 
-// Turns a list of key values into an array of fields
+// Turns a list of key values into an array of fields, along with the fields
+// of the logger
 func (l *logger) keyValues(kvs ...interface{}) (list Fields) {
 	size := len(kvs)
 	// Special cases
-	if size == 0 {
+	if size == 0 && len(l.fields) == 0 {
 		return nil
 	} else if size == 1 {
-		return []Field{{Key: fmt.Sprintf("%s", kvs[0])}}
+		list = append(list, Field{Key: fmt.Sprintf("%s", kvs[0])})
 	}
 	for i := 1; i < size; i += 2 {
 		list = append(list, Field{
@@ -127,14 +136,29 @@ func (l *logger) keyValues(kvs ...interface{}) (list Fields) {
 func (l *logger) New(fields ...interface{}) Interface {
 	return &logger{
 		Handler:     l.Handler,
+		name:        l.name,
 		includePath: l.includePath,
-		fields:      append(l.keyValues(fields...), l.fields...),
+		fields:      l.keyValues(fields...),
+	}
+}
+
+// Named sub logger
+func (l *logger) Named(name string) Interface {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &logger{
+		Handler:     l.Handler,
+		name:        name,
+		includePath: l.includePath,
+		fields:      l.fields,
 	}
 }
 
 // Debug message is written to the console
 func (l *logger) Debug(message string, fields ...interface{}) {
 	l.Handler.Log(Entry{
+		Name:    l.name,
 		Message: message,
 		Fields:  l.keyValues(fields...),
 		Level:   DebugLevel,
@@ -145,6 +169,7 @@ func (l *logger) Debug(message string, fields ...interface{}) {
 // Info message is written to the console
 func (l *logger) Info(message string, fields ...interface{}) {
 	l.Handler.Log(Entry{
+		Name:    l.name,
 		Message: message,
 		Fields:  l.keyValues(fields...),
 		Level:   InfoLevel,
@@ -155,6 +180,7 @@ func (l *logger) Info(message string, fields ...interface{}) {
 // Notice message is written to the console
 func (l *logger) Notice(message string, fields ...interface{}) {
 	l.Handler.Log(Entry{
+		Name:    l.name,
 		Message: message,
 		Fields:  l.keyValues(fields...),
 		Level:   NoticeLevel,
@@ -165,6 +191,7 @@ func (l *logger) Notice(message string, fields ...interface{}) {
 // Warn message is written to the console
 func (l *logger) Warn(message string, fields ...interface{}) {
 	l.Handler.Log(Entry{
+		Name:    l.name,
 		Message: message,
 		Fields:  l.keyValues(fields...),
 		Level:   WarnLevel,
@@ -175,6 +202,7 @@ func (l *logger) Warn(message string, fields ...interface{}) {
 // Error message is written to the console
 func (l *logger) Error(message string, fields ...interface{}) {
 	l.Handler.Log(Entry{
+		Name:    l.name,
 		Message: message,
 		Fields:  l.keyValues(fields...),
 		Level:   ErrorLevel,
@@ -1,7 +1,38 @@
 package log_test
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+)
 
 func TestTime(t *testing.T) {
 	// defer timer.Debug("TestTime(%s)", t).Error(&err)
 }
+
+type recorder []log.Entry
+
+func (r *recorder) Log(entry log.Entry) {
+	*r = append(*r, entry)
+}
+
+func TestContext(t *testing.T) {
+	is := is.New(t)
+	// Logs are discarded without a logger on the context
+	is.Equal(log.From(context.Background()), log.Discard)
+	rec := new(recorder)
+	ctx := log.With(context.Background(), log.New(rec).New("request_id", "1"))
+	log.From(ctx).Info("a")
+	log.From(ctx).Info("b", "user_id", 2)
+	log.From(ctx).New("route", "/").Named("web").Info("c")
+	is.Equal(len(*rec), 3)
+	is.Equal(len((*rec)[0].Fields), 1)
+	is.Equal((*rec)[0].Fields[0].Key, "request_id")
+	is.Equal(len((*rec)[1].Fields), 2)
+	is.Equal((*rec)[1].Fields[1].Key, "user_id")
+	// Fields are kept by nested loggers without being repeated
+	is.Equal(len((*rec)[2].Fields), 2)
+	is.Equal((*rec)[2].Name, "web")
+}
@@ -0,0 +1,83 @@
+// Package reqlog gives each request its own logger, so every line logged while
+// handling a request can be correlated. The logger carries the request's ID,
+// method and path, along with the tenant and the logged in user when there
+// are any. The router adds the matched route.
+//
+//	func (c *Controller) Create(ctx context.Context, title string) error {
+//		log.From(ctx).Info("posts: creating", "title", title)
+//		// ...
+//	}
+package reqlog
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/livebud/bud/package/auth"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/jwt"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/tenant"
+)
+
+// Header carries the request ID. IDs from proxies and load balancers are kept,
+// so the app's logs match theirs. The ID is sent back in the response.
+const Header = "X-Request-Id"
+
+// Load the middleware
+func Load(log log.Interface, ids idgen.IDGenerator) *Middleware {
+	return &Middleware{log, ids}
+}
+
+// Middleware puts a logger for the request on the context
+type Middleware struct {
+	log log.Interface
+	ids idgen.IDGenerator
+}
+
+// Middleware implements middleware.Middleware
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id := r.Header.Get(Header)
+		if !validID(id) {
+			id = m.ids.NewID()
+		}
+		w.Header().Set(Header, id)
+		fields := []interface{}{"request_id", id, "method", r.Method, "path", r.URL.Path}
+		if tenantID, ok := tenant.From(ctx); ok {
+			fields = append(fields, "tenant_id", tenantID)
+		}
+		if userID, ok := auth.UserID(ctx); ok {
+			fields = append(fields, "user_id", strconv.Itoa(userID))
+		} else if subject := jwt.From(ctx).Subject(); subject != "" {
+			fields = append(fields, "user_id", subject)
+		}
+		ctx = context.WithValue(ctx, contextKey{}, id)
+		ctx = log.With(ctx, m.log.New(fields...))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type contextKey struct{}
+
+// ID returns the request's ID. It's empty outside of a request.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// validID checks that IDs from other services are safe to log
+func validID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package reqlog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/tenant"
+)
+
+type recorder []log.Entry
+
+func (r *recorder) Log(entry log.Entry) {
+	*r = append(*r, entry)
+}
+
+// fields of the entry, like "method=GET path=/"
+func fields(entry log.Entry) string {
+	var fields []string
+	for _, field := range entry.Fields {
+		fields = append(fields, field.Key+"="+field.Value)
+	}
+	return strings.Join(fields, " ")
+}
+
+func TestRequestLogger(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	m := reqlog.Load(log.New(rec), idgen.Sequential("req-"))
+	rt := router.New()
+	rt.Get("/posts/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(reqlog.ID(r.Context()), "req-1")
+		log.From(r.Context()).Info("posts: showing", "id", r.URL.Query().Get("id"))
+	}))
+	handler := middleware.Compose(m, rt).Middleware(http.NotFoundHandler())
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/posts/10", nil))
+	is.Equal(res.Code, http.StatusOK)
+	is.Equal(res.Header().Get(reqlog.Header), "req-1")
+	is.Equal(len(*rec), 1)
+	is.Equal(fields((*rec)[0]), "id=10 method=GET path=/posts/10 request_id=req-1 route=/posts/:id")
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	m := reqlog.Load(log.New(rec), idgen.Sequential("req-"))
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.From(r.Context()).Info("hello")
+	}))
+	// IDs from proxies are kept
+	res := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(reqlog.Header, "abc-123")
+	handler.ServeHTTP(res, req)
+	is.Equal(res.Header().Get(reqlog.Header), "abc-123")
+	// Unsafe IDs are replaced
+	res = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(reqlog.Header, "abc\nlevel=error")
+	handler.ServeHTTP(res, req)
+	is.Equal(res.Header().Get(reqlog.Header), "req-1")
+	is.Equal(fields((*rec)[1]), "method=GET path=/ request_id=req-1")
+}
+
+func TestTenant(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	m := reqlog.Load(log.New(rec), idgen.Sequential("req-"))
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.From(r.Context()).Info("hello")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(tenant.With(req.Context(), "acme"))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	is.Equal(fields((*rec)[0]), "method=GET path=/ request_id=req-1 tenant_id=acme")
+}
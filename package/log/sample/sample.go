@@ -0,0 +1,157 @@
+// Package sample keeps noisy logs in check. When the same log is written over
+// and over, like an error from a failing render in a hot route, only the
+// first logs in each interval are written, then one in every few. The next
+// log that's written has a "suppressed" field with the number of logs that
+// were dropped.
+package sample
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Load the sampler from the environment. Logs aren't sampled unless
+// $LOG_SAMPLE_FIRST is set.
+//
+//	LOG_SAMPLE_FIRST=100       write the first 100 of each log per interval
+//	LOG_SAMPLE_THEREAFTER=100  then write 1 in every 100. Defaults to none
+//	LOG_SAMPLE_INTERVAL=1s     the interval. Defaults to 1s
+func Load(handler log.Handler, getenv func(key string) string) (log.Handler, error) {
+	if getenv("LOG_SAMPLE_FIRST") == "" {
+		return handler, nil
+	}
+	first, err := strconv.Atoi(getenv("LOG_SAMPLE_FIRST"))
+	if err != nil || first < 0 {
+		return nil, fmt.Errorf("sample: invalid LOG_SAMPLE_FIRST %q", getenv("LOG_SAMPLE_FIRST"))
+	}
+	sampler := New(handler, first, 0, time.Second)
+	if value := getenv("LOG_SAMPLE_THEREAFTER"); value != "" {
+		thereafter, err := strconv.Atoi(value)
+		if err != nil || thereafter < 0 {
+			return nil, fmt.Errorf("sample: invalid LOG_SAMPLE_THEREAFTER %q", value)
+		}
+		sampler.Thereafter = thereafter
+	}
+	if value := getenv("LOG_SAMPLE_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("sample: invalid LOG_SAMPLE_INTERVAL %q", value)
+		}
+		sampler.Interval = interval
+	}
+	return sampler, nil
+}
+
+// New sampler that writes the first logs in each interval, then one in every
+// thereafter. A thereafter of 0 drops the rest.
+func New(handler log.Handler, first, thereafter int, interval time.Duration) *Sampler {
+	return &Sampler{
+		Handler:    handler,
+		First:      first,
+		Thereafter: thereafter,
+		Interval:   interval,
+		Now:        time.Now,
+	}
+}
+
+// Sampler samples logs by their level, logger and message. Fields aren't part
+// of the key, so the same error with different request IDs is sampled
+// together. Can be initialized manually or by the New function.
+type Sampler struct {
+	Handler    log.Handler
+	First      int
+	Thereafter int
+	Interval   time.Duration
+	Now        func() time.Time
+
+	mu         sync.Mutex
+	counters   map[key]*counter
+	suppressed uint64
+}
+
+var _ log.Handler = (*Sampler)(nil)
+
+type key struct {
+	level   log.Level
+	name    string
+	message string
+}
+
+type counter struct {
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// maxCounters before counters from past intervals are cleared out
+const maxCounters = 1000
+
+// Log implements log.Handler
+func (s *Sampler) Log(entry log.Entry) {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	k := key{entry.Level, entry.Name, entry.Message}
+	s.mu.Lock()
+	if s.counters == nil {
+		s.counters = map[key]*counter{}
+	}
+	c, ok := s.counters[k]
+	if !ok || now.Sub(c.start) >= s.Interval {
+		if !ok && len(s.counters) >= maxCounters {
+			s.prune(now)
+		}
+		// Carry over what was suppressed in the last interval
+		suppressed := 0
+		if ok {
+			suppressed = c.suppressed
+		}
+		c = &counter{start: now, suppressed: suppressed}
+		s.counters[k] = c
+	}
+	c.count++
+	if !s.allow(c.count) {
+		c.suppressed++
+		s.suppressed++
+		s.mu.Unlock()
+		return
+	}
+	suppressed := c.suppressed
+	c.suppressed = 0
+	s.mu.Unlock()
+	if suppressed > 0 {
+		fields := make([]log.Field, len(entry.Fields), len(entry.Fields)+1)
+		copy(fields, entry.Fields)
+		entry.Fields = append(fields, log.Field{Key: "suppressed", Value: strconv.Itoa(suppressed)})
+	}
+	s.Handler.Log(entry)
+}
+
+// allow the nth log in an interval
+func (s *Sampler) allow(n int) bool {
+	if n <= s.First {
+		return true
+	}
+	return s.Thereafter > 0 && (n-s.First)%s.Thereafter == 0
+}
+
+// prune counters from past intervals that have nothing to report
+func (s *Sampler) prune(now time.Time) {
+	for k, c := range s.counters {
+		if now.Sub(c.start) >= s.Interval && c.suppressed == 0 {
+			delete(s.counters, k)
+		}
+	}
+}
+
+// Suppressed returns the number of logs that were dropped
+func (s *Sampler) Suppressed() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.suppressed
+}
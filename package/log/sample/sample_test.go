@@ -0,0 +1,88 @@
+package sample_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/sample"
+)
+
+type recorder []log.Entry
+
+func (r *recorder) Log(entry log.Entry) {
+	*r = append(*r, entry)
+}
+
+// lines that were logged, like "render failed suppressed=2"
+func (r *recorder) lines() string {
+	var lines []string
+	for _, entry := range *r {
+		line := entry.Message
+		for _, field := range entry.Fields {
+			line += " " + field.Key + "=" + field.Value
+		}
+		lines = append(lines, line)
+	}
+	*r = nil
+	return strings.Join(lines, "\n")
+}
+
+func TestSample(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	sampler := sample.New(rec, 2, 3, time.Second)
+	sampler.Now = frozen.Now
+	logger := log.New(sampler)
+	for i := 0; i < 10; i++ {
+		logger.Error("render failed", "i", i)
+	}
+	// Different messages are sampled separately
+	logger.Error("other")
+	is.Equal(rec.lines(), "render failed i=0\nrender failed i=1\nrender failed i=4 suppressed=2\nrender failed i=7 suppressed=2\nother")
+	is.Equal(sampler.Suppressed(), uint64(6))
+	// The count starts over in the next interval, reporting what was suppressed
+	frozen.Advance(time.Second)
+	logger.Error("render failed", "i", 10)
+	logger.Error("render failed", "i", 11)
+	is.Equal(rec.lines(), "render failed i=10 suppressed=2\nrender failed i=11")
+}
+
+func TestDropRest(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	sampler := sample.New(rec, 1, 0, time.Hour)
+	logger := log.New(sampler)
+	for i := 0; i < 100; i++ {
+		logger.Warn("slow")
+		logger.Named("view").Warn("slow")
+	}
+	is.Equal(rec.lines(), "slow\nslow")
+	is.Equal(sampler.Suppressed(), uint64(198))
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	env := map[string]string{}
+	handler, err := sample.Load(rec, func(key string) string { return env[key] })
+	is.NoErr(err)
+	// Logs aren't sampled by default
+	is.Equal(handler, log.Handler(rec))
+	env["LOG_SAMPLE_FIRST"] = "10"
+	env["LOG_SAMPLE_THEREAFTER"] = "100"
+	env["LOG_SAMPLE_INTERVAL"] = "5s"
+	handler, err = sample.Load(rec, func(key string) string { return env[key] })
+	is.NoErr(err)
+	sampler := handler.(*sample.Sampler)
+	is.Equal(sampler.First, 10)
+	is.Equal(sampler.Thereafter, 100)
+	is.Equal(sampler.Interval, 5*time.Second)
+	env["LOG_SAMPLE_INTERVAL"] = "soon"
+	_, err = sample.Load(rec, func(key string) string { return env[key] })
+	is.True(err != nil)
+}
@@ -0,0 +1,169 @@
+package sink
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/jsonlog"
+)
+
+// File writes JSON logs to the rotating file
+func File(rotator *RotatingFile) (Sink, error) {
+	if err := rotator.open(); err != nil {
+		return nil, err
+	}
+	return &fileSink{queue: newQueue(queueSize), rotator: rotator}, nil
+}
+
+type fileSink struct {
+	*queue
+	rotator *RotatingFile
+}
+
+func (f *fileSink) Log(entry log.Entry) {
+	// Format right away, so errors have the stack of the caller
+	buf := new(bytes.Buffer)
+	(&jsonlog.Handler{Writer: buf, Now: time.Now}).Log(entry)
+	f.push(func() error {
+		_, err := f.rotator.Write(buf.Bytes())
+		return err
+	})
+}
+
+func (f *fileSink) Close() error {
+	f.queue.close()
+	return f.rotator.Close()
+}
+
+// RotatingFile is a file that's moved aside and started over when it's too
+// big or too old. Rotated files are named after the time they were rotated,
+// like "app.log.2022-01-01T00-00-00.000".
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64         // In bytes. Zero for no limit
+	MaxAge     time.Duration // Zero for no limit
+	MaxBackups int           // Rotated files to keep. Zero keeps them all
+	Now        func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func (r *RotatingFile) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+// open the file, appending to it
+func (r *RotatingFile) open() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.openFile()
+}
+
+func (r *RotatingFile) openFile() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return fmt.Errorf("sink: unable to create the directory for %s. %w", r.Path, err)
+	}
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("sink: unable to open %s. %w", r.Path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("sink: unable to stat %s. %w", r.Path, err)
+	}
+	r.file = file
+	r.size = info.Size()
+	r.opened = r.now()
+	return nil
+}
+
+// Write to the file, rotating it first if the write would make it too big or
+// the file is too old
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		if err := r.openFile(); err != nil {
+			return 0, err
+		}
+	}
+	tooBig := r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize
+	tooOld := r.MaxAge > 0 && r.now().Sub(r.opened) >= r.MaxAge
+	if tooBig || tooOld {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the file aside, starts a new one and removes old backups
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	backup := r.Path + "." + r.now().UTC().Format("2006-01-02T15-04-05.000")
+	if err := os.Rename(r.Path, backup); err != nil {
+		return fmt.Errorf("sink: unable to rotate %s. %w", r.Path, err)
+	}
+	if err := r.openFile(); err != nil {
+		return err
+	}
+	return r.removeBackups()
+}
+
+// Backups returns the rotated files from oldest to newest
+func (r *RotatingFile) Backups() ([]string, error) {
+	backups, err := filepath.Glob(r.Path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	// Timestamps sort in the order they were rotated
+	sort.Strings(backups)
+	return backups, nil
+}
+
+func (r *RotatingFile) removeBackups() error {
+	if r.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := r.Backups()
+	if err != nil {
+		return err
+	}
+	for len(backups) > r.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("sink: unable to remove an old log file. %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Close the file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,232 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// OTLP exports logs to an OpenTelemetry collector over HTTP, like
+// "http://localhost:4318/v1/logs". Logs are sent in batches.
+func OTLP(endpoint, serviceName string) *OTLPSink {
+	o := &OTLPSink{
+		Endpoint:    endpoint,
+		ServiceName: serviceName,
+		Header:      map[string]string{},
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		BatchSize:   512,
+		flush:       make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+// OTLPSink sends batches of logs to an OpenTelemetry collector
+type OTLPSink struct {
+	dropped     uint64 // First for atomic alignment on 32-bit platforms
+	Endpoint    string
+	ServiceName string
+	Header      map[string]string
+	Client      *http.Client
+	BatchSize   int
+
+	mu      sync.Mutex
+	records []otlpRecord
+	flush   chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+var _ Sink = (*OTLPSink)(nil)
+
+// maxRecords waiting to be sent before logs are dropped
+const maxRecords = 8192
+
+// Log implements log.Handler
+func (o *OTLPSink) Log(entry log.Entry) {
+	record := otlpRecord{
+		TimeUnixNano:   strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber: severity(entry.Level),
+		SeverityText:   entry.Level.String(),
+		Body:           otlpValue{StringValue: entry.Message},
+	}
+	if entry.Name != "" {
+		record.Attributes = append(record.Attributes, otlpAttribute{"logger.name", otlpValue{entry.Name}})
+	}
+	for _, field := range entry.Fields {
+		record.Attributes = append(record.Attributes, otlpAttribute{field.Key, otlpValue{field.Value}})
+	}
+	o.mu.Lock()
+	if len(o.records) >= maxRecords {
+		o.mu.Unlock()
+		atomic.AddUint64(&o.dropped, 1)
+		return
+	}
+	o.records = append(o.records, record)
+	full := len(o.records) >= o.BatchSize
+	o.mu.Unlock()
+	if full {
+		select {
+		case o.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// flushInterval is how often logs are sent when batches aren't full
+const flushInterval = time.Second
+
+// run sends a batch every interval or when a batch fills up
+func (o *OTLPSink) run() {
+	defer close(o.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.send()
+		case <-o.flush:
+			o.send()
+		case <-o.stop:
+			o.send()
+			return
+		}
+	}
+}
+
+// send the queued logs in batches
+func (o *OTLPSink) send() {
+	for {
+		o.mu.Lock()
+		n := len(o.records)
+		if n > o.BatchSize {
+			n = o.BatchSize
+		}
+		batch := o.records[:n:n]
+		o.records = o.records[n:]
+		o.mu.Unlock()
+		if len(batch) == 0 {
+			return
+		}
+		if err := o.export(batch); err != nil {
+			atomic.AddUint64(&o.dropped, uint64(len(batch)))
+		}
+	}
+}
+
+func (o *OTLPSink) export(records []otlpRecord) error {
+	body, err := json.Marshal(otlpRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{Attributes: []otlpAttribute{{"service.name", otlpValue{o.ServiceName}}}},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "github.com/livebud/bud/package/log"},
+				LogRecords: records,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), o.Client.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range o.Header {
+		req.Header.Set(key, value)
+	}
+	res, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("sink: the collector responded with %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Close sends the remaining logs
+func (o *OTLPSink) Close() error {
+	o.once.Do(func() { close(o.stop) })
+	<-o.done
+	return nil
+}
+
+// Dropped returns the number of logs that couldn't be sent
+func (o *OTLPSink) Dropped() uint64 {
+	return atomic.LoadUint64(&o.dropped)
+}
+
+// severity numbers from the OpenTelemetry log data model
+func severity(level log.Level) int {
+	switch level {
+	case log.DebugLevel:
+		return 5
+	case log.InfoLevel:
+		return 9
+	case log.NoticeLevel:
+		return 10
+	case log.WarnLevel:
+		return 13
+	case log.ErrorLevel:
+		return 17
+	default:
+		return 0
+	}
+}
+
+// OTLP's JSON encoding of ExportLogsServiceRequest
+type otlpRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope    `json:"scope"`
+	LogRecords []otlpRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
@@ -0,0 +1,237 @@
+// Package sink sends logs somewhere besides stderr, like a rotating file,
+// syslog or an OpenTelemetry collector. Sinks are configured with environment
+// variables. See Load.
+//
+// Sinks never block the app. Logs are queued and written in the background.
+// When a sink can't keep up, logs are dropped and counted instead.
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Sink is a handler that needs to be closed to flush its logs
+type Sink interface {
+	log.Handler
+	// Close flushes the queued logs
+	Close() error
+	// Dropped returns the number of logs that were dropped because the sink
+	// couldn't keep up or failed to write them
+	Dropped() uint64
+}
+
+// Load the sinks configured in the environment and tee them with the handler.
+//
+//	LOG_FILE=log/app.log              write JSON logs to a file
+//	LOG_FILE_MAX_SIZE=100             rotate the file after 100 MB. Defaults to 100
+//	LOG_FILE_MAX_AGE=24h              rotate the file after a day
+//	LOG_FILE_MAX_BACKUPS=5            keep 5 rotated files. Defaults to 5
+//	LOG_SYSLOG=local                  write to the local syslog or journald
+//	LOG_SYSLOG=udp://localhost:514    write to a remote syslog
+//	LOG_SYSLOG_TAG=app                tag syslog messages. Defaults to the program
+//	OTEL_EXPORTER_OTLP_ENDPOINT=...   export to an OpenTelemetry collector
+func Load(handler log.Handler, getenv func(key string) string) (*Tee, error) {
+	tee := &Tee{Handler: handler}
+	if path := getenv("LOG_FILE"); path != "" {
+		file, err := loadFile(path, getenv)
+		if err != nil {
+			tee.Close()
+			return nil, err
+		}
+		tee.Sinks = append(tee.Sinks, file)
+	}
+	if address := getenv("LOG_SYSLOG"); address != "" {
+		tag := getenv("LOG_SYSLOG_TAG")
+		if tag == "" {
+			tag = filepath.Base(os.Args[0])
+		}
+		syslog, err := Syslog(address, tag)
+		if err != nil {
+			tee.Close()
+			return nil, err
+		}
+		tee.Sinks = append(tee.Sinks, syslog)
+	}
+	if endpoint := otlpEndpoint(getenv); endpoint != "" {
+		serviceName := getenv("OTEL_SERVICE_NAME")
+		if serviceName == "" {
+			serviceName = filepath.Base(os.Args[0])
+		}
+		otlp := OTLP(endpoint, serviceName)
+		otlp.Header = parseHeaders(getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+		tee.Sinks = append(tee.Sinks, otlp)
+	}
+	return tee, nil
+}
+
+func loadFile(path string, getenv func(key string) string) (Sink, error) {
+	rotator := &RotatingFile{Path: path, MaxSize: 100 << 20, MaxBackups: 5}
+	if value := getenv("LOG_FILE_MAX_SIZE"); value != "" {
+		mb, err := strconv.Atoi(value)
+		if err != nil || mb < 0 {
+			return nil, fmt.Errorf("sink: invalid LOG_FILE_MAX_SIZE %q. Expected a number of megabytes", value)
+		}
+		rotator.MaxSize = int64(mb) << 20
+	}
+	if value := getenv("LOG_FILE_MAX_AGE"); value != "" {
+		age, err := time.ParseDuration(value)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("sink: invalid LOG_FILE_MAX_AGE %q", value)
+		}
+		rotator.MaxAge = age
+	}
+	if value := getenv("LOG_FILE_MAX_BACKUPS"); value != "" {
+		backups, err := strconv.Atoi(value)
+		if err != nil || backups < 0 {
+			return nil, fmt.Errorf("sink: invalid LOG_FILE_MAX_BACKUPS %q", value)
+		}
+		rotator.MaxBackups = backups
+	}
+	return File(rotator)
+}
+
+// otlpEndpoint follows OpenTelemetry's environment variables
+func otlpEndpoint(getenv func(key string) string) string {
+	if endpoint := getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/logs"
+	}
+	return ""
+}
+
+// parseHeaders like "api-key=123,x-tenant=acme"
+func parseHeaders(value string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return headers
+}
+
+// Tee sends logs to the handler and each of the sinks
+type Tee struct {
+	Handler log.Handler
+	Sinks   []Sink
+}
+
+var _ log.Handler = (*Tee)(nil)
+
+// Log implements log.Handler
+func (t *Tee) Log(entry log.Entry) {
+	t.Handler.Log(entry)
+	for _, sink := range t.Sinks {
+		sink.Log(entry)
+	}
+}
+
+// Close the sinks, flushing their logs
+func (t *Tee) Close() error {
+	var errs []string
+	for _, sink := range t.Sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ". "))
+	}
+	return nil
+}
+
+// Dropped returns the number of logs the sinks dropped
+func (t *Tee) Dropped() (dropped uint64) {
+	for _, sink := range t.Sinks {
+		dropped += sink.Dropped()
+	}
+	return dropped
+}
+
+// queueSize is how many logs can wait to be written
+const queueSize = 1024
+
+// queue runs writes in the background, dropping them when it's full
+type queue struct {
+	dropped uint64 // First for atomic alignment on 32-bit platforms
+	mu      sync.RWMutex
+	closed  bool
+	writes  chan func() error
+	done    chan struct{}
+}
+
+func newQueue(size int) *queue {
+	q := &queue{
+		writes: make(chan func() error, size),
+		done:   make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+func (q *queue) run() {
+	defer close(q.done)
+	for write := range q.writes {
+		if err := write(); err != nil {
+			atomic.AddUint64(&q.dropped, 1)
+		}
+	}
+}
+
+// push the write onto the queue without blocking. Writes after the queue is
+// closed are dropped.
+func (q *queue) push(write func() error) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		atomic.AddUint64(&q.dropped, 1)
+		return
+	}
+	select {
+	case q.writes <- write:
+	default:
+		atomic.AddUint64(&q.dropped, 1)
+	}
+}
+
+// close the queue once the queued writes are done
+func (q *queue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.writes)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+func (q *queue) Dropped() uint64 {
+	return atomic.LoadUint64(&q.dropped)
+}
+
+// text formats the entry like "posts: created id=1"
+func text(entry log.Entry) string {
+	var b strings.Builder
+	if entry.Name != "" {
+		b.WriteString("[" + entry.Name + "] ")
+	}
+	b.WriteString(entry.Message)
+	for _, field := range entry.Fields {
+		b.WriteString(" " + field.Key + "=" + field.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,202 @@
+package sink_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/sink"
+)
+
+func TestRotatingFile(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(t.TempDir(), "log", "app.log")
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	rotator := &sink.RotatingFile{Path: path, MaxSize: 10, MaxAge: time.Hour, MaxBackups: 2, Now: frozen.Now}
+	write := func(s string) {
+		_, err := rotator.Write([]byte(s))
+		is.NoErr(err)
+		frozen.Advance(time.Second)
+	}
+	write("12345")
+	write("67890")
+	// Too big
+	write("abc")
+	data, err := os.ReadFile(path)
+	is.NoErr(err)
+	is.Equal(string(data), "abc")
+	backups, err := rotator.Backups()
+	is.NoErr(err)
+	is.Equal(len(backups), 1)
+	is.Equal(filepath.Base(backups[0]), "app.log.2022-01-01T00-00-02.000")
+	// Too old
+	frozen.Advance(time.Hour)
+	write("def")
+	write("ghi")
+	frozen.Advance(time.Hour)
+	write("jkl")
+	data, err = os.ReadFile(path)
+	is.NoErr(err)
+	is.Equal(string(data), "jkl")
+	// Only 2 backups are kept
+	backups, err = rotator.Backups()
+	is.NoErr(err)
+	is.Equal(len(backups), 2)
+	data, err = os.ReadFile(backups[0])
+	is.NoErr(err)
+	is.Equal(string(data), "abc")
+	is.NoErr(rotator.Close())
+}
+
+func TestFile(t *testing.T) {
+	is := is.New(t)
+	path := filepath.Join(t.TempDir(), "app.log")
+	file, err := sink.File(&sink.RotatingFile{Path: path})
+	is.NoErr(err)
+	logger := log.New(file)
+	logger.Info("posts: created", "id", 1)
+	logger.Error("posts: failed")
+	is.NoErr(file.Close())
+	// Logs after closing are dropped
+	logger.Info("late")
+	is.Equal(file.Dropped(), uint64(1))
+	data, err := os.ReadFile(path)
+	is.NoErr(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	is.Equal(len(lines), 2)
+	var entry struct {
+		Level string
+		Msg   string
+		ID    string
+		Stack []string
+	}
+	is.NoErr(json.Unmarshal([]byte(lines[0]), &entry))
+	is.Equal(entry.Msg, "posts: created")
+	is.Equal(entry.ID, "1")
+	is.NoErr(json.Unmarshal([]byte(lines[1]), &entry))
+	// Stacks are from where the error was logged, not the background writer
+	is.True(len(entry.Stack) > 0)
+	is.True(strings.Contains(entry.Stack[0], "sink_test.TestFile"))
+}
+
+func TestSyslog(t *testing.T) {
+	is := is.New(t)
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer conn.Close()
+	syslog, err := sink.Syslog("udp://"+conn.LocalAddr().String(), "myapp")
+	is.NoErr(err)
+	log.New(syslog).Named("view").Warn("view: slow render", "route", "/")
+	is.NoErr(syslog.Close())
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	is.NoErr(err)
+	message := string(buf[:n])
+	// Warnings in the user facility have a priority of 12
+	is.True(strings.HasPrefix(message, "<12>"))
+	is.True(strings.Contains(message, "myapp["))
+	is.True(strings.HasSuffix(strings.TrimSpace(message), "[view] view: slow render route=/"))
+	_, err = sink.Syslog("localhost", "myapp")
+	is.True(err != nil)
+}
+
+func TestOTLP(t *testing.T) {
+	is := is.New(t)
+	var mu sync.Mutex
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/v1/logs")
+		is.Equal(r.Header.Get("Api-Key"), "123")
+		var body map[string]interface{}
+		is.NoErr(json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		requests = append(requests, body)
+		mu.Unlock()
+	}))
+	defer server.Close()
+	env := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": server.URL,
+		"OTEL_EXPORTER_OTLP_HEADERS":  "api-key=123",
+		"OTEL_SERVICE_NAME":           "blog",
+	}
+	tee, err := sink.Load(discard{}, func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(len(tee.Sinks), 1)
+	otlp := tee.Sinks[0].(*sink.OTLPSink)
+	otlp.BatchSize = 2
+	logger := log.New(tee)
+	logger.Info("a", "id", 1)
+	logger.Named("job").Error("b")
+	logger.Warn("c")
+	is.NoErr(tee.Close())
+	is.Equal(tee.Dropped(), uint64(0))
+	mu.Lock()
+	defer mu.Unlock()
+	is.Equal(len(requests), 2)
+	data, err := json.Marshal(requests[0])
+	is.NoErr(err)
+	is.Equal(string(data), `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"blog"}}]},"scopeLogs":[{"logRecords":[`+
+		`{"attributes":[{"key":"id","value":{"stringValue":"1"}}],"body":{"stringValue":"a"},"severityNumber":9,"severityText":"info","timeUnixNano":"`+otlpTime(requests[0], 0)+`"},`+
+		`{"attributes":[{"key":"logger.name","value":{"stringValue":"job"}}],"body":{"stringValue":"b"},"severityNumber":17,"severityText":"error","timeUnixNano":"`+otlpTime(requests[0], 1)+`"}`+
+		`],"scope":{"name":"github.com/livebud/bud/package/log"}}]}]}`)
+}
+
+// otlpTime returns the time of the nth record in the request
+func otlpTime(request map[string]interface{}, n int) string {
+	resourceLogs := request["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	scopeLogs := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	record := scopeLogs["logRecords"].([]interface{})[n].(map[string]interface{})
+	return record["timeUnixNano"].(string)
+}
+
+func TestOTLPFailed(t *testing.T) {
+	is := is.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	otlp := sink.OTLP(server.URL, "blog")
+	log.New(otlp).Info("a")
+	log.New(otlp).Info("b")
+	is.NoErr(otlp.Close())
+	is.Equal(otlp.Dropped(), uint64(2))
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	// Nothing is configured by default
+	tee, err := sink.Load(discard{}, getenv)
+	is.NoErr(err)
+	is.Equal(len(tee.Sinks), 0)
+	env["LOG_FILE"] = filepath.Join(dir, "app.log")
+	env["LOG_FILE_MAX_SIZE"] = "big"
+	_, err = sink.Load(discard{}, getenv)
+	is.True(err != nil)
+	env["LOG_FILE_MAX_SIZE"] = "1"
+	tee, err = sink.Load(discard{}, getenv)
+	is.NoErr(err)
+	is.Equal(len(tee.Sinks), 1)
+	log.New(tee).Info("hello")
+	is.NoErr(tee.Close())
+	data, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	is.NoErr(err)
+	is.True(strings.Contains(string(data), `"msg":"hello"`))
+}
+
+type discard struct{}
+
+func (discard) Log(log.Entry) {}
@@ -0,0 +1,57 @@
+//go:build !windows
+
+package sink
+
+import (
+	"fmt"
+	"log/syslog"
+	"net/url"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Syslog writes logs to syslog. The address is "local" for the local syslog,
+// which journald reads from too, or a URL like "udp://localhost:514".
+func Syslog(address, tag string) (Sink, error) {
+	network, raddr := "", ""
+	if address != "local" {
+		u, err := url.Parse(address)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("sink: invalid syslog address %q. Expected \"local\" or a URL like \"udp://localhost:514\"", address)
+		}
+		network, raddr = u.Scheme, u.Host
+	}
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, fmt.Errorf("sink: unable to connect to syslog at %q. %w", address, err)
+	}
+	return &syslogSink{queue: newQueue(queueSize), writer: writer}, nil
+}
+
+type syslogSink struct {
+	*queue
+	writer *syslog.Writer
+}
+
+func (s *syslogSink) Log(entry log.Entry) {
+	message := text(entry)
+	var write func(string) error
+	switch entry.Level {
+	case log.DebugLevel:
+		write = s.writer.Debug
+	case log.NoticeLevel:
+		write = s.writer.Notice
+	case log.WarnLevel:
+		write = s.writer.Warning
+	case log.ErrorLevel:
+		write = s.writer.Err
+	default:
+		write = s.writer.Info
+	}
+	s.push(func() error { return write(message) })
+}
+
+func (s *syslogSink) Close() error {
+	s.queue.close()
+	return s.writer.Close()
+}
@@ -0,0 +1,8 @@
+package sink
+
+import "errors"
+
+// Syslog isn't supported on Windows
+func Syslog(address, tag string) (Sink, error) {
+	return nil, errors.New("sink: syslog isn't supported on Windows")
+}
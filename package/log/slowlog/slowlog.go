@@ -0,0 +1,215 @@
+// Package slowlog warns about requests and server-side renders that take longer
+// than a threshold. It's off unless a threshold is set:
+//
+//	SLOW_REQUEST=1s          warn about requests that take longer than 1s
+//	SLOW_RENDER=200ms        warn about renders that take longer than 200ms
+//	SLOW_RENDER_STACK=true   sample the JavaScript stack of slow renders
+//
+// Slow requests break their time down into the middleware, the handler and the
+// render, so you know where to look.
+package slowlog
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/js"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/reqlog"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+// Load the monitor from the environment
+func Load(log log.Interface) (*Monitor, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return New(log, config), nil
+}
+
+// Config for the monitor
+type Config struct {
+	// Request is the threshold for slow requests. Zero turns them off.
+	Request time.Duration
+	// Render is the threshold for slow renders. Zero turns them off.
+	Render time.Duration
+	// Stack samples the JavaScript stack of renders, so slow renders can show
+	// where the time went. Sampling slows every render down, so only turn it on
+	// while you're looking into slow renders.
+	Stack bool
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	SLOW_REQUEST=1s
+//	SLOW_RENDER=200ms
+//	SLOW_RENDER_STACK=true
+func LoadConfig(getenv func(key string) string) (*Config, error) {
+	config := new(Config)
+	var err error
+	if config.Request, err = parseDuration(getenv, "SLOW_REQUEST"); err != nil {
+		return nil, err
+	}
+	if config.Render, err = parseDuration(getenv, "SLOW_RENDER"); err != nil {
+		return nil, err
+	}
+	if value := getenv("SLOW_RENDER_STACK"); value != "" {
+		config.Stack, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("slowlog: expected SLOW_RENDER_STACK to be true or false. %w", err)
+		}
+	}
+	return config, nil
+}
+
+func parseDuration(getenv func(key string) string, key string) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("slowlog: expected %s to be a duration like 500ms. %w", key, err)
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("slowlog: expected %s to be positive", key)
+	}
+	return duration, nil
+}
+
+// New monitor
+func New(log log.Interface, config *Config) *Monitor {
+	return &Monitor{
+		Clock:  clock.Load(),
+		log:    log.Named("slow"),
+		config: config,
+	}
+}
+
+// Monitor times requests and renders and warns about the slow ones
+type Monitor struct {
+	Clock  clock.Clock
+	log    log.Interface
+	config *Config
+}
+
+// enabled is true when there's a threshold to check
+func (m *Monitor) enabled() bool {
+	return m.config.Request > 0 || m.config.Render > 0
+}
+
+type contextKey struct{}
+
+// timing of a request, shared with the handlers and renders down the stack
+type timing struct {
+	monitor  *Monitor
+	mu       sync.Mutex
+	handlers time.Duration
+	render   time.Duration
+}
+
+func (t *timing) add(d *time.Duration, elapsed time.Duration) {
+	t.mu.Lock()
+	*d += elapsed
+	t.mu.Unlock()
+}
+
+// Middleware times the requests. It should go at the top of the stack, so it
+// covers all the middleware.
+func (m *Monitor) Middleware(next http.Handler) http.Handler {
+	if !m.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := m.Clock.Now()
+		t := &timing{monitor: m}
+		ctx, route := router.Track(r.Context())
+		ctx = context.WithValue(ctx, contextKey{}, t)
+		next.ServeHTTP(w, r.WithContext(ctx))
+		duration := m.Clock.Now().Sub(start)
+		if m.config.Request <= 0 || duration < m.config.Request {
+			return
+		}
+		t.mu.Lock()
+		handlers, render := t.handlers, t.render
+		t.mu.Unlock()
+		m.log.Warn("slowlog: slow request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", route(),
+			"request_id", w.Header().Get(reqlog.Header),
+			"duration_ms", milliseconds(duration),
+			"middleware_ms", milliseconds(duration-handlers),
+			"handler_ms", milliseconds(handlers-render),
+			"render_ms", milliseconds(render),
+		)
+	})
+}
+
+// Handlers marks where the middleware ends and the handlers begin, so slow
+// requests can tell the time spent in each apart
+func (m *Monitor) Handlers() middleware.Middleware {
+	return middleware.Function(func(next http.Handler) http.Handler {
+		if !m.enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, ok := r.Context().Value(contextKey{}).(*timing)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := m.Clock.Now()
+			next.ServeHTTP(w, r)
+			t.add(&t.handlers, m.Clock.Now().Sub(start))
+		})
+	})
+}
+
+// Stack is true when renders within the request should sample the JavaScript
+// stack
+func Stack(ctx context.Context) bool {
+	t, ok := ctx.Value(contextKey{}).(*timing)
+	return ok && t.monitor.config.Render > 0 && t.monitor.config.Stack
+}
+
+// Render records the time it took to render the route on the server and warns
+// if the render was slow. Slow renders include the stack when it was sampled.
+func Render(ctx context.Context, route string, duration time.Duration, stack []js.Frame) {
+	t, ok := ctx.Value(contextKey{}).(*timing)
+	if !ok {
+		return
+	}
+	t.add(&t.render, duration)
+	m := t.monitor
+	if m.config.Render <= 0 || duration < m.config.Render {
+		return
+	}
+	fields := []interface{}{
+		"route", route,
+		"request_id", reqlog.ID(ctx),
+		"duration_ms", milliseconds(duration),
+	}
+	if len(stack) > 0 {
+		frames := make([]string, len(stack))
+		for i, frame := range stack {
+			frames[i] = frame.String()
+		}
+		fields = append(fields, "js_stack", strings.Join(frames, "\n"))
+	}
+	m.log.Warn("slowlog: slow render", fields...)
+}
+
+// milliseconds with microsecond precision
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
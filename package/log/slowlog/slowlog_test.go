@@ -0,0 +1,135 @@
+package slowlog_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/js"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/log/slowlog"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+type recorder []log.Entry
+
+func (r *recorder) Log(entry log.Entry) {
+	*r = append(*r, entry)
+}
+
+// fields of the entry, like "method=GET path=/"
+func fields(entry log.Entry) string {
+	var fields []string
+	for _, field := range entry.Fields {
+		fields = append(fields, field.Key+"="+field.Value)
+	}
+	return strings.Join(fields, " ")
+}
+
+// slowMiddleware takes d to run
+func slowMiddleware(frozen *clock.Frozen, d time.Duration) middleware.Middleware {
+	return middleware.Function(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			frozen.Advance(d)
+			next.ServeHTTP(w, r)
+		})
+	})
+}
+
+func TestSlowRequest(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := slowlog.New(log.New(rec), &slowlog.Config{Request: time.Second, Render: time.Second})
+	monitor.Clock = frozen
+	rt := router.New()
+	rt.Get("/posts/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		frozen.Advance(200 * time.Millisecond)
+		slowlog.Render(r.Context(), "/posts/:id", 500*time.Millisecond, nil)
+		frozen.Advance(500 * time.Millisecond)
+	}))
+	handler := middleware.Compose(
+		monitor,
+		slowMiddleware(frozen, 300*time.Millisecond),
+		monitor.Handlers(),
+		rt,
+	).Middleware(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts/10", nil))
+	is.Equal(len(*rec), 1)
+	entry := (*rec)[0]
+	is.Equal(entry.Level, log.WarnLevel)
+	is.Equal(entry.Name, "slow")
+	is.Equal(entry.Message, "slowlog: slow request")
+	is.Equal(fields(entry), "duration_ms=1000 handler_ms=200 method=GET middleware_ms=300 path=/posts/10 render_ms=500 request_id= route=/posts/:id")
+}
+
+func TestFastRequest(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	monitor := slowlog.New(log.New(rec), &slowlog.Config{Request: time.Second})
+	monitor.Clock = frozen
+	handler := middleware.Compose(
+		monitor,
+		slowMiddleware(frozen, 999*time.Millisecond),
+	).Middleware(http.NotFoundHandler())
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	is.Equal(len(*rec), 0)
+}
+
+func TestSlowRender(t *testing.T) {
+	is := is.New(t)
+	rec := new(recorder)
+	monitor := slowlog.New(log.New(rec), &slowlog.Config{Render: 100 * time.Millisecond, Stack: true})
+	handler := monitor.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.True(slowlog.Stack(r.Context()))
+		slowlog.Render(r.Context(), "/", 50*time.Millisecond, nil)
+		slowlog.Render(r.Context(), "/posts", 150*time.Millisecond, []js.Frame{
+			{Function: "each", Path: "_ssr.js", Line: 10, Column: 3},
+			{Function: "render", Path: "_ssr.js", Line: 2, Column: 1},
+		})
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/posts", nil))
+	is.Equal(len(*rec), 1)
+	entry := (*rec)[0]
+	is.Equal(entry.Message, "slowlog: slow render")
+	is.Equal(fields(entry), "duration_ms=150 js_stack=each (_ssr.js:10:3)\nrender (_ssr.js:2:1) request_id= route=/posts")
+}
+
+func TestDisabled(t *testing.T) {
+	is := is.New(t)
+	monitor := slowlog.New(log.Discard, &slowlog.Config{})
+	handler := http.NotFoundHandler()
+	is.Equal(monitor.Middleware(handler), handler)
+	is.Equal(monitor.Handlers().Middleware(handler), handler)
+	// Renders outside of a request are ignored
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	is.True(!slowlog.Stack(req.Context()))
+	slowlog.Render(req.Context(), "/", time.Hour, nil)
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{
+		"SLOW_REQUEST":      "1s",
+		"SLOW_RENDER":       "250ms",
+		"SLOW_RENDER_STACK": "true",
+	}
+	config, err := slowlog.LoadConfig(func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(config.Request, time.Second)
+	is.Equal(config.Render, 250*time.Millisecond)
+	is.True(config.Stack)
+	config, err = slowlog.LoadConfig(func(string) string { return "" })
+	is.NoErr(err)
+	is.Equal(config.Request, time.Duration(0))
+	env["SLOW_REQUEST"] = "soon"
+	_, err = slowlog.LoadConfig(func(key string) string { return env[key] })
+	is.True(err != nil)
+	is.True(strings.Contains(err.Error(), "SLOW_REQUEST"))
+}
@@ -0,0 +1,418 @@
+// Package metrics keeps counters, gauges and histograms and serves them in the
+// Prometheus text format:
+//
+//	var signups = metrics.NewCounter("signups_total", "Accounts created", "plan")
+//
+//	func init() {
+//		metrics.Register(signups)
+//	}
+//
+//	signups.Inc("pro")
+//
+// Bud registers its own metrics with the default registry too, like request
+// counts and latency per route, render timings, job queue depths and Go
+// runtime metrics. The app serves them at /metrics on its internal listener.
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Type of metric
+type Type string
+
+const (
+	CounterType   Type = "counter"
+	GaugeType     Type = "gauge"
+	HistogramType Type = "histogram"
+)
+
+// Desc describes a metric
+type Desc struct {
+	Name string
+	Help string
+	Type Type
+}
+
+// Label of a sample
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is a value of a metric. Histograms have samples with the _bucket, _sum
+// and _count suffixes.
+type Sample struct {
+	Name   string
+	Labels []Label
+	Value  float64
+}
+
+// Collector collects the samples of a metric when it's scraped
+type Collector interface {
+	Describe() Desc
+	Collect(ctx context.Context) []Sample
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]Collector{}}
+}
+
+// Registry of metrics
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+// Default registry, which has Bud's metrics and the Go runtime metrics
+var Default = func() *Registry {
+	registry := NewRegistry()
+	registerRuntime(registry)
+	return registry
+}()
+
+// Register a collector with the default registry
+func Register(collector Collector) {
+	Default.Register(collector)
+}
+
+// Handler serves the metrics in the default registry
+func Handler() http.Handler {
+	return Default
+}
+
+// Register a collector. A collector with the same name is replaced.
+func (r *Registry) Register(collector Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors[collector.Describe().Name] = collector
+}
+
+// Write the metrics in the Prometheus text format
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.RLock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, collector := range r.collectors {
+		collectors = append(collectors, collector)
+	}
+	r.mu.RUnlock()
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].Describe().Name < collectors[j].Describe().Name
+	})
+	bw := bufio.NewWriter(w)
+	for _, collector := range collectors {
+		desc := collector.Describe()
+		samples := collector.Collect(ctx)
+		if len(samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "# HELP %s %s\n", desc.Name, escapeHelp(desc.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", desc.Name, desc.Type)
+		for _, sample := range samples {
+			bw.WriteString(sample.Name)
+			writeLabels(bw, sample.Labels)
+			bw.WriteByte(' ')
+			bw.WriteString(formatFloat(sample.Value))
+			bw.WriteByte('\n')
+		}
+	}
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics to Prometheus
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.Write(req.Context(), w)
+}
+
+func writeLabels(w *bufio.Writer, labels []Label) {
+	if len(labels) == 0 {
+		return
+	}
+	w.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.WriteString(label.Name + `="` + escapeLabel(label.Value) + `"`)
+	}
+	w.WriteByte('}')
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// series of a metric with label values, keyed by the joined label values
+type series struct {
+	mu     sync.Mutex
+	labels []string
+	values map[string]*value
+}
+
+type value struct {
+	labels  []string
+	value   float64
+	buckets []uint64 // Only for histograms
+	count   uint64
+}
+
+func newSeries(labels []string) series {
+	values := map[string]*value{}
+	// Metrics without labels start at zero
+	if len(labels) == 0 {
+		values[""] = &value{}
+	}
+	return series{labels: labels, values: values}
+}
+
+// get the value for the label values. The caller holds the lock.
+func (s *series) get(name string, labelValues []string, buckets int) *value {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values but got %d", name, len(s.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	v, ok := s.values[key]
+	if !ok {
+		v = &value{labels: append([]string(nil), labelValues...)}
+		if buckets > 0 {
+			v.buckets = make([]uint64, buckets)
+		}
+		s.values[key] = v
+	}
+	return v
+}
+
+// sorted returns the values ordered by their label values
+func (s *series) sorted() []*value {
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	values := make([]*value, len(keys))
+	for i, key := range keys {
+		v := *s.values[key]
+		v.buckets = append([]uint64(nil), v.buckets...)
+		values[i] = &v
+	}
+	return values
+}
+
+func (s *series) pairs(labelValues []string) []Label {
+	labels := make([]Label, len(s.labels))
+	for i, name := range s.labels {
+		labels[i] = Label{name, labelValues[i]}
+	}
+	return labels
+}
+
+// NewCounter creates a counter, which only goes up. Label values are passed in
+// the order of the label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{Desc{name, help, CounterType}, newSeries(labels)}
+}
+
+// Counter counts events, like requests
+type Counter struct {
+	desc Desc
+	series
+}
+
+var _ Collector = (*Counter)(nil)
+
+// Inc adds one to the counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add a positive delta to the counter
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("metrics: %s can't go down", c.desc.Name))
+	}
+	c.mu.Lock()
+	c.get(c.desc.Name, labelValues, 0).value += delta
+	c.mu.Unlock()
+}
+
+func (c *Counter) Describe() Desc {
+	return c.desc
+}
+
+func (c *Counter) Collect(ctx context.Context) (samples []Sample) {
+	c.mu.Lock()
+	values := c.sorted()
+	c.mu.Unlock()
+	for _, v := range values {
+		samples = append(samples, Sample{c.desc.Name, c.pairs(v.labels), v.value})
+	}
+	return samples
+}
+
+// NewGauge creates a gauge, which goes up and down
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{Desc{name, help, GaugeType}, newSeries(labels)}
+}
+
+// Gauge measures a value that goes up and down, like requests in flight
+type Gauge struct {
+	desc Desc
+	series
+}
+
+var _ Collector = (*Gauge)(nil)
+
+// Set the gauge
+func (g *Gauge) Set(value float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(g.desc.Name, labelValues, 0).value = value
+	g.mu.Unlock()
+}
+
+// Add delta to the gauge
+func (g *Gauge) Add(delta float64, labelValues ...string) {
+	g.mu.Lock()
+	g.get(g.desc.Name, labelValues, 0).value += delta
+	g.mu.Unlock()
+}
+
+// Inc adds one to the gauge
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+// Dec subtracts one from the gauge
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+func (g *Gauge) Describe() Desc {
+	return g.desc
+}
+
+func (g *Gauge) Collect(ctx context.Context) (samples []Sample) {
+	g.mu.Lock()
+	values := g.sorted()
+	g.mu.Unlock()
+	for _, v := range values {
+		samples = append(samples, Sample{g.desc.Name, g.pairs(v.labels), v.value})
+	}
+	return samples
+}
+
+// NewGaugeFunc creates a gauge that calls fn when it's scraped
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{Desc{name, help, GaugeType}, fn}
+}
+
+// GaugeFunc is a gauge whose value is read when it's scraped, like the number
+// of goroutines
+type GaugeFunc struct {
+	desc Desc
+	fn   func() float64
+}
+
+var _ Collector = (*GaugeFunc)(nil)
+
+func (g *GaugeFunc) Describe() Desc {
+	return g.desc
+}
+
+func (g *GaugeFunc) Collect(ctx context.Context) []Sample {
+	return []Sample{{Name: g.desc.Name, Value: g.fn()}}
+}
+
+// DefaultBuckets are the upper bounds of histogram buckets in seconds, from
+// 5ms to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewHistogram creates a histogram with buckets, which are sorted upper bounds
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := &Histogram{Desc{name, help, HistogramType}, newSeries(labels), buckets}
+	if v, ok := h.values[""]; ok {
+		v.buckets = make([]uint64, len(buckets))
+	}
+	return h
+}
+
+// Histogram counts observations in buckets, like request latency
+type Histogram struct {
+	desc Desc
+	series
+	buckets []float64
+}
+
+var _ Collector = (*Histogram)(nil)
+
+// Observe a value, like a duration in seconds
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	// The first bucket that fits, or past the end for +Inf
+	i := sort.SearchFloat64s(h.buckets, value)
+	h.mu.Lock()
+	v := h.get(h.desc.Name, labelValues, len(h.buckets))
+	if i < len(h.buckets) {
+		v.buckets[i]++
+	}
+	v.count++
+	v.value += value
+	h.mu.Unlock()
+}
+
+func (h *Histogram) Describe() Desc {
+	return h.desc
+}
+
+func (h *Histogram) Collect(ctx context.Context) (samples []Sample) {
+	h.mu.Lock()
+	values := h.sorted()
+	h.mu.Unlock()
+	for _, v := range values {
+		labels := h.pairs(v.labels)
+		// Buckets are cumulative
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += v.buckets[i]
+			samples = append(samples, Sample{h.desc.Name + "_bucket", append(labels[:len(labels):len(labels)], Label{"le", formatFloat(bound)}), float64(cumulative)})
+		}
+		samples = append(samples,
+			Sample{h.desc.Name + "_bucket", append(labels[:len(labels):len(labels)], Label{"le", "+Inf"}), float64(v.count)},
+			Sample{h.desc.Name + "_sum", labels, v.value},
+			Sample{h.desc.Name + "_count", labels, float64(v.count)},
+		)
+	}
+	return samples
+}
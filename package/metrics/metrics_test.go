@@ -0,0 +1,147 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/metrics"
+	"github.com/livebud/bud/package/router"
+)
+
+func write(t testing.TB, registry *metrics.Registry) string {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if err := registry.Write(context.Background(), buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestCounter(t *testing.T) {
+	is := is.New(t)
+	registry := metrics.NewRegistry()
+	signups := metrics.NewCounter("signups_total", "Accounts created.", "plan")
+	registry.Register(signups)
+	is.Equal(write(t, registry), "")
+	signups.Inc("pro")
+	signups.Add(2, "free")
+	signups.Inc("pro")
+	is.Equal(write(t, registry), `# HELP signups_total Accounts created.
+# TYPE signups_total counter
+signups_total{plan="free"} 2
+signups_total{plan="pro"} 2
+`)
+}
+
+func TestCounterNegative(t *testing.T) {
+	is := is.New(t)
+	counter := metrics.NewCounter("errors_total", "Errors.")
+	defer func() {
+		is.True(recover() != nil)
+	}()
+	counter.Add(-1)
+}
+
+func TestGauge(t *testing.T) {
+	is := is.New(t)
+	registry := metrics.NewRegistry()
+	gauge := metrics.NewGauge("connections", "Open connections.")
+	registry.Register(gauge)
+	is.Equal(write(t, registry), `# HELP connections Open connections.
+# TYPE connections gauge
+connections 0
+`)
+	gauge.Inc()
+	gauge.Inc()
+	gauge.Dec()
+	gauge.Add(0.5)
+	is.Equal(write(t, registry), `# HELP connections Open connections.
+# TYPE connections gauge
+connections 1.5
+`)
+}
+
+func TestEscape(t *testing.T) {
+	is := is.New(t)
+	registry := metrics.NewRegistry()
+	gauge := metrics.NewGauge("paths", "Paths with\n\"quotes\".", "path")
+	registry.Register(gauge)
+	gauge.Set(1, "a\"b\\c\nd")
+	is.Equal(write(t, registry), `# HELP paths Paths with\n"quotes".
+# TYPE paths gauge
+paths{path="a\"b\\c\nd"} 1
+`)
+}
+
+func TestHistogram(t *testing.T) {
+	is := is.New(t)
+	registry := metrics.NewRegistry()
+	histogram := metrics.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1})
+	registry.Register(histogram)
+	histogram.Observe(0.05)
+	histogram.Observe(0.1)
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	is.Equal(write(t, registry), `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 2
+latency_seconds_bucket{le="1"} 3
+latency_seconds_bucket{le="+Inf"} 4
+latency_seconds_sum 3.65
+latency_seconds_count 4
+`)
+}
+
+func TestLabelMismatch(t *testing.T) {
+	is := is.New(t)
+	counter := metrics.NewCounter("jobs_total", "Jobs.", "queue")
+	defer func() {
+		is.True(recover() != nil)
+	}()
+	counter.Inc()
+}
+
+func TestHandler(t *testing.T) {
+	is := is.New(t)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	res := rec.Result()
+	is.Equal(res.StatusCode, http.StatusOK)
+	is.Equal(res.Header.Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8")
+	body, err := io.ReadAll(res.Body)
+	is.NoErr(err)
+	is.True(strings.Contains(string(body), "# TYPE go_goroutines gauge\n"))
+	is.True(strings.Contains(string(body), "# TYPE go_gc_cycles_total counter\n"))
+	is.True(strings.Contains(string(body), `go_info{version="go`))
+}
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	registry := metrics.NewRegistry()
+	middleware := metrics.NewMiddleware(registry)
+	rt := router.New()
+	rt.Get("/posts/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/posts/0" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("post"))
+	}))
+	handler := middleware.Middleware(rt)
+	for _, path := range []string{"/posts/1", "/posts/2", "/posts/0"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/posts/1", nil))
+	out := write(t, registry)
+	is.True(strings.Contains(out, `http_requests_total{method="GET",route="/posts/:id",status="200"} 2`+"\n"))
+	is.True(strings.Contains(out, `http_requests_total{method="GET",route="/posts/:id",status="404"} 1`+"\n"))
+	is.True(strings.Contains(out, `http_requests_total{method="OTHER",route="",status="404"} 1`+"\n"))
+	is.True(strings.Contains(out, `http_request_duration_seconds_count{method="GET",route="/posts/:id"} 3`+"\n"))
+	is.True(strings.Contains(out, "http_requests_in_flight 0\n"))
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+// Load the request metrics into the default registry
+func Load() *Middleware {
+	return NewMiddleware(Default)
+}
+
+// NewMiddleware creates request metrics that are registered with registry
+func NewMiddleware(registry *Registry) *Middleware {
+	m := &Middleware{
+		requests: NewCounter("http_requests_total", "Requests served by route and status.", "method", "route", "status"),
+		duration: NewHistogram("http_request_duration_seconds", "Time to serve requests by route.", DefaultBuckets, "method", "route"),
+		inflight: NewGauge("http_requests_in_flight", "Requests being served."),
+		now:      time.Now,
+	}
+	registry.Register(m.requests)
+	registry.Register(m.duration)
+	registry.Register(m.inflight)
+	return m
+}
+
+// Middleware counts and times requests by the route that matched, like
+// /posts/:id, so the number of series stays small
+type Middleware struct {
+	requests *Counter
+	duration *Histogram
+	inflight *Gauge
+	now      func() time.Time
+}
+
+// Middleware implements middleware.Middleware
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := m.now()
+		m.inflight.Inc()
+		defer m.inflight.Dec()
+		ctx, route := router.Track(r.Context())
+		sw := middleware.RecordStatus(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		method := normalizeMethod(r.Method)
+		m.requests.Inc(method, route(), strconv.Itoa(sw.Status()))
+		m.duration.Observe(m.now().Sub(start).Seconds(), method, route())
+	})
+}
+
+// normalizeMethod keeps clients from creating a series for every method name
+// they can think of
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+		http.MethodPatch, http.MethodDelete, http.MethodOptions:
+		return method
+	default:
+		return "OTHER"
+	}
+}
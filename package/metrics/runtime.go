@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// registerRuntime registers the Go runtime metrics
+func registerRuntime(registry *Registry) {
+	stats := &memStats{}
+	start := float64(time.Now().Unix())
+	registry.Register(NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	}))
+	registry.Register(NewGaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return start
+	}))
+	registry.Register(&goInfo{})
+	registry.Register(stats.gauge("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.", func(m *runtime.MemStats) float64 {
+		return float64(m.Alloc)
+	}))
+	registry.Register(stats.gauge("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.", func(m *runtime.MemStats) float64 {
+		return float64(m.HeapInuse)
+	}))
+	registry.Register(stats.gauge("go_memstats_heap_objects", "Number of allocated objects.", func(m *runtime.MemStats) float64 {
+		return float64(m.HeapObjects)
+	}))
+	registry.Register(stats.gauge("go_memstats_sys_bytes", "Number of bytes obtained from system.", func(m *runtime.MemStats) float64 {
+		return float64(m.Sys)
+	}))
+	registry.Register(stats.gauge("go_memstats_next_gc_bytes", "Number of heap bytes when next garbage collection will take place.", func(m *runtime.MemStats) float64 {
+		return float64(m.NextGC)
+	}))
+	registry.Register(stats.counter("go_gc_cycles_total", "Number of completed GC cycles.", func(m *runtime.MemStats) float64 {
+		return float64(m.NumGC)
+	}))
+	registry.Register(stats.counter("go_gc_pause_seconds_total", "Total time the world was stopped for garbage collection.", func(m *runtime.MemStats) float64 {
+		return float64(m.PauseTotalNs) / 1e9
+	}))
+}
+
+// memStats shares one read of the memory statistics between the metrics in
+// a scrape, since reading them briefly stops the world
+type memStats struct {
+	mu   sync.Mutex
+	read time.Time
+	m    runtime.MemStats
+}
+
+func (s *memStats) get(fn func(m *runtime.MemStats) float64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.read) > time.Second {
+		runtime.ReadMemStats(&s.m)
+		s.read = time.Now()
+	}
+	return fn(&s.m)
+}
+
+func (s *memStats) gauge(name, help string, fn func(m *runtime.MemStats) float64) Collector {
+	return NewGaugeFunc(name, help, func() float64 { return s.get(fn) })
+}
+
+func (s *memStats) counter(name, help string, fn func(m *runtime.MemStats) float64) Collector {
+	return &counterFunc{Desc{name, help, CounterType}, func() float64 { return s.get(fn) }}
+}
+
+// counterFunc is a counter that's read when it's scraped
+type counterFunc struct {
+	desc Desc
+	fn   func() float64
+}
+
+func (c *counterFunc) Describe() Desc {
+	return c.desc
+}
+
+func (c *counterFunc) Collect(ctx context.Context) []Sample {
+	return []Sample{{Name: c.desc.Name, Value: c.fn()}}
+}
+
+// goInfo reports the version of Go the app was built with
+type goInfo struct{}
+
+func (goInfo) Describe() Desc {
+	return Desc{"go_info", "Information about the Go environment.", GaugeType}
+}
+
+func (goInfo) Collect(ctx context.Context) []Sample {
+	return []Sample{{Name: "go_info", Labels: []Label{{"version", runtime.Version()}}, Value: 1}}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// gzipPool favors speed over ratio since most responses are dev-time bundles
+// that change on every build
+var gzipPool = sync.Pool{
+	New: func() interface{} {
+		gz, _ := gzip.NewWriterLevel(io.Discard, gzip.BestSpeed)
+		return gz
+	},
+}
+
+// Gzip compresses responses for clients that accept gzip. Range requests,
+// HEAD requests, server-sent events and already compressed content are passed
+// through untouched.
+func Gzip() Middleware {
+	return Function(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead ||
+				r.Header.Get("Range") != "" ||
+				!acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Accept-Encoding")
+			gw := &gzipWriter{ResponseWriter: w}
+			defer gw.Close()
+			next.ServeHTTP(gw, r)
+		})
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header includes gzip
+func acceptsGzip(acceptEncoding string) bool {
+	for _, encoding := range strings.Split(acceptEncoding, ",") {
+		encoding = strings.TrimSpace(encoding)
+		if encoding == "gzip" || strings.HasPrefix(encoding, "gzip;") && !strings.HasSuffix(encoding, "q=0") {
+			return true
+		}
+	}
+	return false
+}
+
+// compressible returns false for content that's already compressed or
+// streamed
+func compressible(header http.Header) bool {
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := header.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"),
+		strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "image/svg"),
+		strings.HasPrefix(contentType, "video/"),
+		strings.HasPrefix(contentType, "audio/"),
+		strings.HasPrefix(contentType, "font/woff"),
+		strings.HasPrefix(contentType, "application/zip"),
+		strings.HasPrefix(contentType, "application/gzip"):
+		return false
+	}
+	return true
+}
+
+type gzipWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer // nil if we're passing through
+	decided bool
+}
+
+var _ http.Flusher = (*gzipWriter)(nil)
+var _ http.Hijacker = (*gzipWriter)(nil)
+
+// decide whether or not to compress once the headers are known
+func (w *gzipWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	header := w.Header()
+	if !compressible(header) {
+		return
+	}
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	gz := gzipPool.Get().(*gzip.Writer)
+	gz.Reset(w.ResponseWriter)
+	w.gz = gz
+}
+
+func (w *gzipWriter) WriteHeader(status int) {
+	// Responses without bodies can't be compressed
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.decided = true
+	}
+	w.decide()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *gzipWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		// Sniff the content type before it's too late
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(p))
+		}
+		w.decide()
+	}
+	if w.gz == nil {
+		return w.ResponseWriter.Write(p)
+	}
+	return w.gz.Write(p)
+}
+
+func (w *gzipWriter) Flush() {
+	w.decide()
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("middleware: response writer is not a hijacker")
+	}
+	return hijacker.Hijack()
+}
+
+// Close the gzip writer and return it to the pool
+func (w *gzipWriter) Close() error {
+	if w.gz == nil {
+		return nil
+	}
+	err := w.gz.Close()
+	gzipPool.Put(w.gz)
+	w.gz = nil
+	return err
+}
@@ -0,0 +1,83 @@
+package middleware_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/middleware"
+)
+
+var bundle = strings.Repeat("console.log('hello world');\n", 1000)
+
+func script() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		w.Write([]byte(bundle))
+	})
+}
+
+func TestGzip(t *testing.T) {
+	is := is.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/bud/view/_index.svelte.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	w := httptest.NewRecorder()
+	middleware.Gzip().Middleware(script()).ServeHTTP(w, req)
+	res := w.Result()
+	is.Equal(res.StatusCode, 200)
+	is.Equal(res.Header.Get("Content-Encoding"), "gzip")
+	is.Equal(res.Header.Get("Vary"), "Accept-Encoding")
+	is.True(w.Body.Len() < len(bundle))
+	gz, err := gzip.NewReader(res.Body)
+	is.NoErr(err)
+	body, err := io.ReadAll(gz)
+	is.NoErr(err)
+	is.Equal(string(body), bundle)
+}
+
+func TestGzipNotAccepted(t *testing.T) {
+	is := is.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/bud/view/_index.svelte.js", nil)
+	w := httptest.NewRecorder()
+	middleware.Gzip().Middleware(script()).ServeHTTP(w, req)
+	res := w.Result()
+	is.Equal(res.Header.Get("Content-Encoding"), "")
+	is.Equal(w.Body.String(), bundle)
+}
+
+func TestGzipRange(t *testing.T) {
+	is := is.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/bud/view/_index.svelte.js", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Range", "bytes=0-10")
+	w := httptest.NewRecorder()
+	middleware.Gzip().Middleware(script()).ServeHTTP(w, req)
+	is.Equal(w.Result().Header.Get("Content-Encoding"), "")
+}
+
+func TestGzipEventStream(t *testing.T) {
+	is := is.New(t)
+	req := httptest.NewRequest(http.MethodGet, "/bud/events", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	middleware.Gzip().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: {}\n\n"))
+	})).ServeHTTP(w, req)
+	is.Equal(w.Result().Header.Get("Content-Encoding"), "")
+	is.Equal(w.Body.String(), "data: {}\n\n")
+}
+
+func BenchmarkGzip(b *testing.B) {
+	handler := middleware.Gzip().Middleware(script())
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/bud/view/_index.svelte.js", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import "net/http"
+
+// StatusWriter records the status and the number of bytes of a response. It's
+// shared by middleware that reports on responses after the handler returns or
+// needs to change the headers before they're written.
+type StatusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+	before func() error
+	failed bool
+}
+
+var _ http.Flusher = (*StatusWriter)(nil)
+
+// RecordStatus wraps the response writer to record the status
+func RecordStatus(w http.ResponseWriter) *StatusWriter {
+	return &StatusWriter{ResponseWriter: w}
+}
+
+// BeforeHeader calls fn once, right before the headers are written, so
+// middleware can change them or save state that sets them, like cookies. When
+// fn fails, the response is replaced with a 500 error and the handler's body is
+// discarded.
+func (w *StatusWriter) BeforeHeader(fn func() error) {
+	w.before = fn
+}
+
+func (w *StatusWriter) WriteHeader(status int) {
+	if w.failed {
+		return
+	}
+	// Skip informational responses, like 103 Early Hints
+	if w.status == 0 && status >= 200 {
+		if w.before != nil {
+			if err := w.before(); err != nil {
+				w.failed = true
+				w.status = http.StatusInternalServerError
+				http.Error(w.ResponseWriter, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *StatusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.failed {
+		return len(p), nil
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *StatusWriter) Flush() {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok && !w.failed {
+		flusher.Flush()
+	}
+}
+
+// Status of the response. Handlers that don't write anything respond with 200.
+func (w *StatusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Written is true once the handler has started the response
+func (w *StatusWriter) Written() bool {
+	return w.status != 0
+}
+
+// Bytes written to the response body
+func (w *StatusWriter) Bytes() int64 {
+	return w.bytes
+}
+
+// Unwrap returns the underlying writer for http.ResponseController
+func (w *StatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
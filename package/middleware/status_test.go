@@ -0,0 +1,80 @@
+package middleware_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/middleware"
+)
+
+func TestStatusImplicit(t *testing.T) {
+	is := is.New(t)
+	sw := middleware.RecordStatus(httptest.NewRecorder())
+	is.Equal(sw.Written(), false)
+	is.Equal(sw.Status(), 200)
+	sw.Write([]byte("hello"))
+	is.Equal(sw.Written(), true)
+	is.Equal(sw.Status(), 200)
+	is.Equal(sw.Bytes(), int64(5))
+}
+
+func TestStatusSkipsInformational(t *testing.T) {
+	is := is.New(t)
+	w := httptest.NewRecorder()
+	sw := middleware.RecordStatus(w)
+	sw.WriteHeader(http.StatusEarlyHints)
+	is.Equal(sw.Written(), false)
+	sw.WriteHeader(http.StatusServiceUnavailable)
+	sw.WriteHeader(http.StatusOK)
+	is.Equal(sw.Status(), 503)
+}
+
+func TestStatusUnwrap(t *testing.T) {
+	is := is.New(t)
+	w := httptest.NewRecorder()
+	sw := middleware.RecordStatus(w)
+	is.Equal(sw.Unwrap(), w)
+	sw.Flush()
+	is.Equal(w.Flushed, true)
+	is.Equal(sw.Status(), 200)
+}
+
+func TestStatusBeforeHeader(t *testing.T) {
+	is := is.New(t)
+	w := httptest.NewRecorder()
+	sw := middleware.RecordStatus(w)
+	calls := 0
+	sw.BeforeHeader(func() error {
+		calls++
+		sw.Header().Set("X-Before", "yes")
+		return nil
+	})
+	sw.WriteHeader(http.StatusEarlyHints)
+	is.Equal(calls, 0)
+	sw.Write([]byte("hello"))
+	sw.Write([]byte(" world"))
+	is.Equal(calls, 1)
+	is.Equal(w.Header().Get("X-Before"), "yes")
+	is.Equal(w.Body.String(), "hello world")
+}
+
+func TestStatusBeforeHeaderFails(t *testing.T) {
+	is := is.New(t)
+	w := httptest.NewRecorder()
+	sw := middleware.RecordStatus(w)
+	sw.BeforeHeader(func() error {
+		return errors.New("unable to save")
+	})
+	sw.WriteHeader(http.StatusCreated)
+	n, err := sw.Write([]byte("created"))
+	is.NoErr(err)
+	is.Equal(n, 7)
+	sw.Flush()
+	is.Equal(sw.Status(), 500)
+	is.Equal(w.Code, 500)
+	is.Equal(w.Body.String(), "unable to save\n")
+	is.Equal(w.Flushed, false)
+}
@@ -0,0 +1,400 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/log"
+	"github.com/matthewmueller/text"
+)
+
+// Dir is the directory within the application that contains migrations
+const Dir = "migrate"
+
+// table that keeps track of the applied migrations
+const table = "bud_migrations"
+
+// lockID is the Postgres advisory lock key that prevents concurrent migrations
+var lockID = int64(crc32.ChecksumIEEE([]byte(table)))
+
+// Migration changes the database schema. SQL migrations are loaded from the
+// filesystem, while Go migrations can be defined directly.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+	Down    func(ctx context.Context, tx *sql.Tx) error
+}
+
+// SQL creates a migration from up and down SQL statements
+func SQL(version int64, name, up, down string) *Migration {
+	return &Migration{
+		Version: version,
+		Name:    name,
+		Up:      execSQL(up),
+		Down:    execSQL(down),
+	}
+}
+
+func execSQL(query string) func(ctx context.Context, tx *sql.Tx) error {
+	if strings.TrimSpace(query) == "" {
+		return nil
+	}
+	return func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query)
+		return err
+	}
+}
+
+// Migration files are named {version}_{name}.{up,down}.sql
+var fileRe = regexp.MustCompile(`^(\d+)_([^.]+)\.(up|down)\.sql$`)
+
+// Load SQL migrations from a filesystem. This also works with embedded
+// filesystems.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	des, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: unable to read migrations. %w", err)
+	}
+	ups := map[int64]string{}
+	downs := map[int64]string{}
+	names := map[int64]string{}
+	for _, de := range des {
+		if de.IsDir() {
+			continue
+		}
+		matches := fileRe.FindStringSubmatch(de.Name())
+		if matches == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: invalid version in %q. %w", de.Name(), err)
+		}
+		if name, ok := names[version]; ok && name != matches[2] {
+			return nil, fmt.Errorf("migrate: duplicate version %d in %q and %q", version, name, matches[2])
+		}
+		names[version] = matches[2]
+		data, err := fs.ReadFile(fsys, de.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: unable to read %q. %w", de.Name(), err)
+		}
+		if matches[3] == "up" {
+			ups[version] = string(data)
+		} else {
+			downs[version] = string(data)
+		}
+	}
+	migrations := make([]*Migration, 0, len(names))
+	for version, name := range names {
+		migrations = append(migrations, SQL(version, name, ups[version], downs[version]))
+	}
+	sortMigrations(migrations)
+	return migrations, nil
+}
+
+func sortMigrations(migrations []*Migration) {
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// Create a new pair of up and down migration files in dir. The version is the
+// current time, so migrations created on different branches rarely collide.
+func Create(dir, name string, now time.Time) (up, down string, err error) {
+	name = strings.ToLower(text.Snake(name))
+	if name == "" {
+		return "", "", fmt.Errorf("migrate: missing migration name")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", err
+	}
+	prefix := now.UTC().Format("20060102150405") + "_" + name
+	up = filepath.Join(dir, prefix+".up.sql")
+	down = filepath.Join(dir, prefix+".down.sql")
+	if err := os.WriteFile(up, []byte("-- Write your up migration here\n"), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Write your down migration here\n"), 0644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
+
+// Open the database at databaseURL and load the SQL migrations from fsys
+func Open(log log.Interface, databaseURL string, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	db, err := dbrt.Open(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	return New(db, log, migrations...), nil
+}
+
+// New migrator
+func New(db *dbrt.DB, log log.Interface, migrations ...*Migration) *Migrator {
+	migrations = append([]*Migration{}, migrations...)
+	sortMigrations(migrations)
+	return &Migrator{db, log, migrations}
+}
+
+// Migrator applies and reverts migrations
+type Migrator struct {
+	db         *dbrt.DB
+	log        log.Interface
+	migrations []*Migration
+}
+
+// Status of a migration
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt time.Time // Zero if the migration is pending
+}
+
+// Pending returns true if the migration hasn't been applied yet
+func (s *Status) Pending() bool {
+	return s.AppliedAt.IsZero()
+}
+
+// Up applies up to n pending migrations. If n is less than one, all pending
+// migrations are applied.
+func (m *Migrator) Up(ctx context.Context, n int) (applied []*Migration, err error) {
+	err = m.withLock(ctx, func() error {
+		versions, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+			if n > 0 && len(applied) == n {
+				break
+			}
+			if err := m.apply(ctx, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last n applied migrations. If n is less than one, only the
+// last migration is reverted.
+func (m *Migrator) Down(ctx context.Context, n int) (reverted []*Migration, err error) {
+	if n < 1 {
+		n = 1
+	}
+	err = m.withLock(ctx, func() error {
+		versions, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		latest := make([]int64, 0, len(versions))
+		for version := range versions {
+			latest = append(latest, version)
+		}
+		sort.Slice(latest, func(i, j int) bool { return latest[i] > latest[j] })
+		for _, version := range latest {
+			if len(reverted) == n {
+				break
+			}
+			migration := m.find(version)
+			if migration == nil {
+				return fmt.Errorf("migrate: unable to find migration %d to revert", version)
+			}
+			if err := m.revert(ctx, migration); err != nil {
+				return err
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status returns the status of each migration
+func (m *Migrator) Status(ctx context.Context) (statuses []*Status, err error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.migrations {
+		statuses = append(statuses, &Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			AppliedAt: versions[migration.Version],
+		})
+	}
+	return statuses, nil
+}
+
+// Pending returns the migrations that haven't been applied yet
+func (m *Migrator) Pending(ctx context.Context) (pending []*Migration, err error) {
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+	versions, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range m.migrations {
+		if _, ok := versions[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Close the database connection
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	query := `CREATE TABLE IF NOT EXISTS "` + table + `" ("version" BIGINT PRIMARY KEY, "name" TEXT NOT NULL, "applied_at" TIMESTAMP NOT NULL)`
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("migrate: unable to create the %s table. %w", table, err)
+	}
+	return nil
+}
+
+// applied returns the applied versions and when they were applied
+func (m *Migrator) applied(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := m.db.QueryContext(ctx, `SELECT "version", "applied_at" FROM "`+table+`"`)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: unable to read applied migrations. %w", err)
+	}
+	defer rows.Close()
+	versions := map[int64]time.Time{}
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("migrate: unable to read applied migrations. %w", err)
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// withLock ensures only one migrator runs at a time. Postgres uses an advisory
+// lock. SQLite relies on its database-level write lock, so each migration
+// checks that it hasn't been applied within its transaction.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+	if m.db.Dialect() != dbrt.Postgres {
+		return fn()
+	}
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrate: unable to get a connection. %w", err)
+	}
+	defer conn.Close()
+	m.log.Debug("migrate: acquiring advisory lock", "id", lockID)
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("migrate: unable to acquire lock. %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+	return fn()
+}
+
+func (m *Migrator) apply(ctx context.Context, migration *Migration) error {
+	m.log.Debug("migrate: applying migration", "version", migration.Version, "name", migration.Name)
+	return m.transact(ctx, migration, func(tx *sql.Tx) error {
+		if exists, err := m.exists(ctx, tx, migration.Version); err != nil {
+			return err
+		} else if exists {
+			return nil
+		}
+		if migration.Up != nil {
+			if err := migration.Up(ctx, tx); err != nil {
+				return err
+			}
+		}
+		query := m.db.Dialect().Rebind(`INSERT INTO "` + table + `" ("version", "name", "applied_at") VALUES (?, ?, ?)`)
+		_, err := tx.ExecContext(ctx, query, migration.Version, migration.Name, time.Now().UTC())
+		return err
+	})
+}
+
+func (m *Migrator) revert(ctx context.Context, migration *Migration) error {
+	m.log.Debug("migrate: reverting migration", "version", migration.Version, "name", migration.Name)
+	return m.transact(ctx, migration, func(tx *sql.Tx) error {
+		if exists, err := m.exists(ctx, tx, migration.Version); err != nil {
+			return err
+		} else if !exists {
+			return nil
+		}
+		if migration.Down != nil {
+			if err := migration.Down(ctx, tx); err != nil {
+				return err
+			}
+		}
+		query := m.db.Dialect().Rebind(`DELETE FROM "` + table + `" WHERE "version" = ?`)
+		_, err := tx.ExecContext(ctx, query, migration.Version)
+		return err
+	})
+}
+
+func (m *Migrator) exists(ctx context.Context, tx *sql.Tx, version int64) (bool, error) {
+	var n int
+	query := m.db.Dialect().Rebind(`SELECT COUNT(*) FROM "` + table + `" WHERE "version" = ?`)
+	if err := tx.QueryRowContext(ctx, query, version).Scan(&n); err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// transact runs fn within a transaction, rolling back if fn fails
+func (m *Migrator) transact(ctx context.Context, migration *Migration, fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migrate: unable to begin transaction for %s. %w", Filename(migration), err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: unable to migrate %s. %w", Filename(migration), err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migrate: unable to commit %s. %w", Filename(migration), err)
+	}
+	return nil
+}
+
+// Filename returns the migration's name without the direction or extension
+// (e.g. 20221015120000_create_users)
+func Filename(migration *Migration) string {
+	return strconv.FormatInt(migration.Version, 10) + "_" + migration.Name
+}
@@ -0,0 +1,185 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log/testlog"
+	"github.com/livebud/bud/package/migrate"
+)
+
+func open(t testing.TB) *dbrt.DB {
+	t.Helper()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tables(t testing.TB, db *dbrt.DB) (names []string) {
+	t.Helper()
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'table' AND name != 'bud_migrations' ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+var fsys = fstest.MapFS{
+	"20221015120000_create_users.up.sql":   &fstest.MapFile{Data: []byte(`CREATE TABLE users (id integer primary key, name text)`)},
+	"20221015120000_create_users.down.sql": &fstest.MapFile{Data: []byte(`DROP TABLE users`)},
+	"20221016120000_create_posts.up.sql":   &fstest.MapFile{Data: []byte(`CREATE TABLE posts (id integer primary key, title text)`)},
+	"20221016120000_create_posts.down.sql": &fstest.MapFile{Data: []byte(`DROP TABLE posts`)},
+	"Readme.md":                            &fstest.MapFile{Data: []byte(`# Migrations`)},
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	migrations, err := migrate.Load(fsys)
+	is.NoErr(err)
+	is.Equal(len(migrations), 2)
+	is.Equal(migrations[0].Version, int64(20221015120000))
+	is.Equal(migrations[0].Name, "create_users")
+	is.Equal(migrate.Filename(migrations[1]), "20221016120000_create_posts")
+}
+
+func TestLoadDuplicate(t *testing.T) {
+	is := is.New(t)
+	_, err := migrate.Load(fstest.MapFS{
+		"1_create_users.up.sql": &fstest.MapFile{Data: []byte(`CREATE TABLE users (id integer)`)},
+		"1_create_posts.up.sql": &fstest.MapFile{Data: []byte(`CREATE TABLE posts (id integer)`)},
+	})
+	is.True(err != nil)
+	is.In(err.Error(), "duplicate version 1")
+}
+
+func TestUpDown(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := open(t)
+	migrations, err := migrate.Load(fsys)
+	is.NoErr(err)
+	migrator := migrate.New(db, testlog.New(), migrations...)
+	pending, err := migrator.Pending(ctx)
+	is.NoErr(err)
+	is.Equal(len(pending), 2)
+	// Apply one
+	applied, err := migrator.Up(ctx, 1)
+	is.NoErr(err)
+	is.Equal(len(applied), 1)
+	is.Equal(tables(t, db), []string{"users"})
+	// Apply the rest
+	applied, err = migrator.Up(ctx, 0)
+	is.NoErr(err)
+	is.Equal(len(applied), 1)
+	is.Equal(applied[0].Name, "create_posts")
+	is.Equal(tables(t, db), []string{"posts", "users"})
+	// Nothing left to apply
+	applied, err = migrator.Up(ctx, 0)
+	is.NoErr(err)
+	is.Equal(len(applied), 0)
+	// Check the status
+	statuses, err := migrator.Status(ctx)
+	is.NoErr(err)
+	is.Equal(len(statuses), 2)
+	is.True(!statuses[0].Pending())
+	is.True(!statuses[1].Pending())
+	// Revert the latest
+	reverted, err := migrator.Down(ctx, 1)
+	is.NoErr(err)
+	is.Equal(len(reverted), 1)
+	is.Equal(reverted[0].Name, "create_posts")
+	is.Equal(tables(t, db), []string{"users"})
+	statuses, err = migrator.Status(ctx)
+	is.NoErr(err)
+	is.True(!statuses[0].Pending())
+	is.True(statuses[1].Pending())
+}
+
+func TestGoMigration(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := open(t)
+	migrations, err := migrate.Load(fsys)
+	is.NoErr(err)
+	migrations = append(migrations, &migrate.Migration{
+		Version: 20221017120000,
+		Name:    "seed_admin",
+		Up: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `INSERT INTO users (name) VALUES ('admin')`)
+			return err
+		},
+		Down: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DELETE FROM users WHERE name = 'admin'`)
+			return err
+		},
+	})
+	migrator := migrate.New(db, testlog.New(), migrations...)
+	applied, err := migrator.Up(ctx, 0)
+	is.NoErr(err)
+	is.Equal(len(applied), 3)
+	var name string
+	is.NoErr(db.QueryRow(`SELECT name FROM users`).Scan(&name))
+	is.Equal(name, "admin")
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db := open(t)
+	migrator := migrate.New(db, testlog.New(),
+		migrate.SQL(1, "create_users", `CREATE TABLE users (id integer primary key)`, `DROP TABLE users`),
+		&migrate.Migration{
+			Version: 2,
+			Name:    "broken",
+			Up: func(ctx context.Context, tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, `CREATE TABLE posts (id integer primary key)`); err != nil {
+					return err
+				}
+				return errors.New("oops")
+			},
+		},
+	)
+	applied, err := migrator.Up(ctx, 0)
+	is.True(err != nil)
+	is.In(err.Error(), "2_broken")
+	is.In(err.Error(), "oops")
+	is.Equal(len(applied), 1)
+	is.Equal(tables(t, db), []string{"users"})
+	pending, err := migrator.Pending(ctx)
+	is.NoErr(err)
+	is.Equal(len(pending), 1)
+	is.Equal(pending[0].Name, "broken")
+}
+
+func TestCreate(t *testing.T) {
+	is := is.New(t)
+	dir := filepath.Join(t.TempDir(), "migrate")
+	now := time.Date(2022, 10, 15, 12, 0, 0, 0, time.UTC)
+	up, down, err := migrate.Create(dir, "CreateUsers", now)
+	is.NoErr(err)
+	is.Equal(filepath.Base(up), "20221015120000_create_users.up.sql")
+	is.Equal(filepath.Base(down), "20221015120000_create_users.down.sql")
+	migrations, err := migrate.Load(os.DirFS(dir))
+	is.NoErr(err)
+	is.Equal(len(migrations), 1)
+	is.Equal(migrations[0].Name, "create_users")
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Assertion checks that a type implements an interface at compile-time:
+//
+//	var _ Interface = (*Type)(nil)
+type Assertion struct {
+	file *File
+	vs   *ast.ValueSpec
+}
+
+var _ Fielder = (*Assertion)(nil)
+
+// File that the assertion is in
+func (a *Assertion) File() *File {
+	return a.file
+}
+
+// Name is always the blank identifier
+func (a *Assertion) Name() string {
+	return "_"
+}
+
+// Type is the interface that's being implemented
+func (a *Assertion) Type() Type {
+	return getType(a, a.vs.Type)
+}
+
+// Implementation is the type that implements the interface. Implementation
+// understands (*T)(nil), &T{}, T{} and T(nil). It's nil for other values.
+func (a *Assertion) Implementation() Type {
+	expr := implementation(a.vs.Values[0])
+	if expr == nil {
+		return nil
+	}
+	return getType(a, expr)
+}
+
+func implementation(x ast.Expr) ast.Expr {
+	switch v := x.(type) {
+	case *ast.CallExpr:
+		// (*T)(nil) or T(nil)
+		if len(v.Args) != 1 {
+			return nil
+		}
+		if ident, ok := v.Args[0].(*ast.Ident); !ok || ident.Name != "nil" {
+			return nil
+		}
+		fn := v.Fun
+		if paren, ok := fn.(*ast.ParenExpr); ok {
+			fn = paren.X
+		}
+		return namedType(fn)
+	case *ast.UnaryExpr:
+		// &T{}
+		lit, ok := v.X.(*ast.CompositeLit)
+		if !ok || v.Op != token.AND {
+			return nil
+		}
+		if name := namedType(lit.Type); name != nil {
+			return &ast.StarExpr{X: name}
+		}
+		return nil
+	case *ast.CompositeLit:
+		// T{}
+		return namedType(v.Type)
+	default:
+		return nil
+	}
+}
+
+// namedType returns the expression if it's a named type or a pointer to one
+func namedType(x ast.Expr) ast.Expr {
+	switch t := x.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		return t
+	case *ast.StarExpr:
+		if namedType(t.X) == nil {
+			return nil
+		}
+		return t
+	default:
+		return nil
+	}
+}
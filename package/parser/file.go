@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 	"strconv"
 
 	"github.com/livebud/bud/internal/imports"
@@ -130,8 +131,14 @@ func (f *File) Structs() (stcts []*Struct) {
 			if !ok {
 				continue
 			}
+			// Single declarations hold the doc comment on the GenDecl
+			var doc *ast.CommentGroup
+			if len(node.Specs) == 1 {
+				doc = node.Doc
+			}
 			stcts = append(stcts, &Struct{
 				file: f,
+				doc:  doc,
 				ts:   ts,
 				node: stct,
 			})
@@ -186,6 +193,28 @@ func (f *File) Interfaces() (ifaces []*Interface) {
 	return ifaces
 }
 
+// Assertions returns the interface assertions in a file, like
+// var _ Interface = (*Type)(nil)
+func (f *File) Assertions() (assertions []*Assertion) {
+	for _, decl := range f.node.Decls {
+		node, ok := decl.(*ast.GenDecl)
+		if !ok || node.Tok != token.VAR {
+			continue
+		}
+		for _, spec := range node.Specs {
+			vs, ok := spec.(*ast.ValueSpec)
+			if !ok || vs.Type == nil || len(vs.Names) != 1 || vs.Names[0].Name != "_" || len(vs.Values) != 1 {
+				continue
+			}
+			assertions = append(assertions, &Assertion{
+				file: f,
+				vs:   vs,
+			})
+		}
+	}
+	return assertions
+}
+
 func (f *File) Alias(name string) *Alias {
 	for _, alias := range f.Aliases() {
 		if alias.Name() == name {
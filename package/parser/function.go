@@ -36,6 +36,12 @@ func (fn *Function) Name() string {
 	return fn.node.Name.Name
 }
 
+// Directives returns the directives in the function's doc comment, like
+// "authz:require posts:delete" for //authz:require posts:delete
+func (fn *Function) Directives() []string {
+	return directives(fn.node.Doc)
+}
+
 // Receiver returns the receiver field, if any
 func (fn *Function) Receiver() *Receiver {
 	if fn.node.Recv == nil {
@@ -292,3 +298,23 @@ func (f *Result) IsError() bool {
 func (f *Result) String() string {
 	return fieldString(f)
 }
+
+// directives are comment lines without a space after the slashes, like
+// //go:embed. They're left out of the comment's text.
+func directives(doc *ast.CommentGroup) (lines []string) {
+	if doc == nil {
+		return nil
+	}
+	for _, comment := range doc.List {
+		line := strings.TrimPrefix(comment.Text, "//")
+		if line == comment.Text || line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		name := strings.SplitN(line, " ", 2)[0]
+		if !strings.Contains(name, ":") {
+			continue
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
@@ -215,3 +215,11 @@ func (pkg *Package) Aliases() (aliases []*Alias) {
 // func ErrIsBuiltin(err error) bool {
 // 	return errors.Is(err, errIsBuiltin)
 // }
+
+// Assertions returns the interface assertions in the package
+func (pkg *Package) Assertions() (assertions []*Assertion) {
+	for _, file := range pkg.Files() {
+		assertions = append(assertions, file.Assertions()...)
+	}
+	return assertions
+}
@@ -47,7 +47,7 @@ func (p *Parser) Parse(dir string) (*Package, error) {
 		if err != nil {
 			return nil, err
 		}
-		parsedFile, err := parser.ParseFile(fset, filename, code, parser.DeclarationErrors)
+		parsedFile, err := parser.ParseFile(fset, filename, code, parser.DeclarationErrors|parser.ParseComments)
 		if err != nil {
 			return nil, err
 		}
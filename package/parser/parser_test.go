@@ -246,3 +246,73 @@ func TestGenerate(t *testing.T) {
 	is.True(alias != nil)
 	is.Equal(alias.Name(), "Answer")
 }
+
+func TestDirectives(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module app.com\n"), 0644))
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "admin"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "admin", "admin.go"), []byte(`package admin
+
+// Controller for admins
+//authz:require admin
+type Controller struct {}
+
+// Delete a user
+//
+//authz:require users:delete users:read
+//go:noinline
+// not:adirective because of the space
+func (c *Controller) Delete() {}
+
+// Index has no directives
+func (c *Controller) Index() {}
+`), 0644))
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	p := parser.New(os.DirFS(dir), module)
+	pkg, err := p.Parse("admin")
+	is.NoErr(err)
+	stct := pkg.Struct("Controller")
+	is.True(stct != nil)
+	is.Equal(stct.Directives(), []string{"authz:require admin"})
+	is.Equal(stct.Method("Delete").Directives(), []string{"authz:require users:delete users:read", "go:noinline"})
+	is.Equal(len(stct.Method("Index").Directives()), 0)
+}
+
+func TestAssertions(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module app.com\n"), 0644))
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "inject"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "inject", "inject.go"), []byte(`package inject
+
+import (
+	"io"
+	"net/http"
+	"os"
+)
+
+var _ http.Handler = (*http.ServeMux)(nil)
+var _ io.Reader = &os.File{}
+var _ http.RoundTripper = http.Transport{}
+var _ io.Writer = os.Stdout
+
+var ignored io.Writer = os.Stdout
+`), 0644))
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	p := parser.New(os.DirFS(dir), module)
+	pkg, err := p.Parse("inject")
+	is.NoErr(err)
+	assertions := pkg.Assertions()
+	is.Equal(len(assertions), 4)
+	is.Equal(assertions[0].Type().String(), "http.Handler")
+	is.Equal(assertions[0].Implementation().String(), "*http.ServeMux")
+	importPath, err := parser.ImportPath(assertions[0].Implementation())
+	is.NoErr(err)
+	is.Equal(importPath, "net/http")
+	is.Equal(assertions[1].Implementation().String(), "*os.File")
+	is.Equal(assertions[2].Implementation().String(), "http.Transport")
+	is.Equal(assertions[3].Implementation(), nil)
+}
@@ -11,6 +11,7 @@ import (
 // Struct struct
 type Struct struct {
 	file *File
+	doc  *ast.CommentGroup
 	ts   *ast.TypeSpec
 	node *ast.StructType
 }
@@ -37,6 +38,14 @@ func (stct *Struct) Name() string {
 	return stct.ts.Name.Name
 }
 
+// Directives returns the directives in the struct's doc comment
+func (stct *Struct) Directives() []string {
+	if stct.ts.Doc != nil {
+		return directives(stct.ts.Doc)
+	}
+	return directives(stct.doc)
+}
+
 func (stct *Struct) Kind() Kind {
 	return KindStruct
 }
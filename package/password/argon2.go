@@ -0,0 +1,239 @@
+package password
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sync"
+)
+
+// argon2id derives a key, as described in RFC 9106
+
+const (
+	argon2Version    = 0x13
+	argon2idType     = 2
+	argon2SyncPoints = 4
+	argon2BlockWords = 128
+)
+
+type argon2Block [argon2BlockWords]uint64
+
+// argon2id derives a key of size bytes from the password and salt. Memory is
+// in KiB.
+func argon2id(password, salt []byte, time, memory uint32, threads uint8, size uint32) []byte {
+	lanes := uint32(threads)
+	h0 := argon2InitHash(password, salt, time, memory, lanes, size)
+	// Round memory down to a multiple of the segments, with at least 2 blocks
+	// per segment
+	memory = memory / (argon2SyncPoints * lanes) * (argon2SyncPoints * lanes)
+	if memory < 2*argon2SyncPoints*lanes {
+		memory = 2 * argon2SyncPoints * lanes
+	}
+	laneLength := memory / lanes
+	segmentLength := laneLength / argon2SyncPoints
+	B := make([]argon2Block, memory)
+	// Fill the first two blocks of each lane
+	for lane := uint32(0); lane < lanes; lane++ {
+		offset := lane * laneLength
+		for i := uint32(0); i < 2; i++ {
+			input := make([]byte, 8)
+			binary.LittleEndian.PutUint32(input, i)
+			binary.LittleEndian.PutUint32(input[4:], lane)
+			argon2ReadBlock(&B[offset+i], argon2Hash(1024, h0, input))
+		}
+	}
+	for pass := uint32(0); pass < time; pass++ {
+		for slice := uint32(0); slice < argon2SyncPoints; slice++ {
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < lanes; lane++ {
+				wg.Add(1)
+				go func(lane uint32) {
+					defer wg.Done()
+					argon2Segment(B, pass, slice, lane, lanes, laneLength, segmentLength, time, memory)
+				}(lane)
+			}
+			wg.Wait()
+		}
+	}
+	// XOR the last block of each lane together
+	final := B[laneLength-1]
+	for lane := uint32(1); lane < lanes; lane++ {
+		last := &B[lane*laneLength+laneLength-1]
+		for i := range final {
+			final[i] ^= last[i]
+		}
+	}
+	out := make([]byte, 1024)
+	for i, word := range final {
+		binary.LittleEndian.PutUint64(out[i*8:], word)
+	}
+	return argon2Hash(size, out)
+}
+
+func argon2InitHash(password, salt []byte, time, memory, lanes, size uint32) []byte {
+	params := make([]byte, 24)
+	binary.LittleEndian.PutUint32(params[0:], lanes)
+	binary.LittleEndian.PutUint32(params[4:], size)
+	binary.LittleEndian.PutUint32(params[8:], memory)
+	binary.LittleEndian.PutUint32(params[12:], time)
+	binary.LittleEndian.PutUint32(params[16:], argon2Version)
+	binary.LittleEndian.PutUint32(params[20:], argon2idType)
+	length := func(b []byte) []byte {
+		l := make([]byte, 4)
+		binary.LittleEndian.PutUint32(l, uint32(len(b)))
+		return l
+	}
+	// The secret and associated data are empty
+	empty := make([]byte, 4)
+	return blake2bSum(64, params, length(password), password, length(salt), salt, empty, empty)
+}
+
+// argon2Hash is Argon2's variable-length hash function, H'
+func argon2Hash(size uint32, inputs ...[]byte) []byte {
+	prefix := make([]byte, 4)
+	binary.LittleEndian.PutUint32(prefix, size)
+	inputs = append([][]byte{prefix}, inputs...)
+	if size <= 64 {
+		return blake2bSum(int(size), inputs...)
+	}
+	out := make([]byte, 0, size)
+	v := blake2bSum(64, inputs...)
+	out = append(out, v[:32]...)
+	for uint32(len(out))+64 < size {
+		v = blake2bSum(64, v)
+		out = append(out, v[:32]...)
+	}
+	v = blake2bSum(int(size)-len(out), v)
+	return append(out, v...)
+}
+
+func argon2ReadBlock(block *argon2Block, data []byte) {
+	for i := range block {
+		block[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+}
+
+func argon2Segment(B []argon2Block, pass, slice, lane, lanes, laneLength, segmentLength, time, memory uint32) {
+	var addresses, input, zero argon2Block
+	// Argon2id addresses blocks independently of the password for the first
+	// half of the first pass, which resists side-channel attacks
+	independent := pass == 0 && slice < argon2SyncPoints/2
+	if independent {
+		input[0] = uint64(pass)
+		input[1] = uint64(lane)
+		input[2] = uint64(slice)
+		input[3] = uint64(memory)
+		input[4] = uint64(time)
+		input[5] = argon2idType
+	}
+	nextAddresses := func() {
+		input[6]++
+		argon2Compress(&addresses, &zero, &input, false)
+		argon2Compress(&addresses, &zero, &addresses, false)
+	}
+	index := uint32(0)
+	if pass == 0 && slice == 0 {
+		// The first two blocks were already filled
+		index = 2
+		nextAddresses()
+	}
+	offset := lane*laneLength + slice*segmentLength + index
+	for ; index < segmentLength; index, offset = index+1, offset+1 {
+		prev := offset - 1
+		if index == 0 && slice == 0 {
+			// Wrap around to the end of the lane
+			prev += laneLength
+		}
+		var random uint64
+		if independent {
+			if index%argon2BlockWords == 0 {
+				nextAddresses()
+			}
+			random = addresses[index%argon2BlockWords]
+		} else {
+			random = B[prev][0]
+		}
+		ref := argon2Reference(random, pass, slice, lane, index, lanes, laneLength, segmentLength)
+		argon2Compress(&B[offset], &B[prev], &B[ref], true)
+	}
+}
+
+// argon2Reference picks the block to mix in, as described in section 3.4 of
+// RFC 9106
+func argon2Reference(random uint64, pass, slice, lane, index, lanes, laneLength, segmentLength uint32) uint32 {
+	refLane := uint32(random>>32) % lanes
+	if pass == 0 && slice == 0 {
+		refLane = lane
+	}
+	area, start := 3*segmentLength, ((slice+1)%argon2SyncPoints)*segmentLength
+	if lane == refLane {
+		area += index
+	}
+	if pass == 0 {
+		area, start = slice*segmentLength, 0
+		if slice == 0 || lane == refLane {
+			area += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		area--
+	}
+	x := random & 0xffffffff
+	x = (x * x) >> 32
+	x = (x * uint64(area)) >> 32
+	return refLane*laneLength + uint32((uint64(start)+uint64(area)-(x+1))%uint64(laneLength))
+}
+
+// argon2Compress sets out to G(x, y). When xor is true, the result is XORed
+// into out instead.
+func argon2Compress(out, x, y *argon2Block, xor bool) {
+	var r, z argon2Block
+	for i := range r {
+		r[i] = x[i] ^ y[i]
+	}
+	z = r
+	// Apply the permutation to each row of 16 words
+	for i := 0; i < argon2BlockWords; i += 16 {
+		argon2Permute(
+			&z[i], &z[i+1], &z[i+2], &z[i+3], &z[i+4], &z[i+5], &z[i+6], &z[i+7],
+			&z[i+8], &z[i+9], &z[i+10], &z[i+11], &z[i+12], &z[i+13], &z[i+14], &z[i+15],
+		)
+	}
+	// Then to each column of 8 word pairs
+	for i := 0; i < 16; i += 2 {
+		argon2Permute(
+			&z[i], &z[i+1], &z[16+i], &z[16+i+1], &z[32+i], &z[32+i+1], &z[48+i], &z[48+i+1],
+			&z[64+i], &z[64+i+1], &z[80+i], &z[80+i+1], &z[96+i], &z[96+i+1], &z[112+i], &z[112+i+1],
+		)
+	}
+	for i := range out {
+		if xor {
+			out[i] ^= z[i] ^ r[i]
+		} else {
+			out[i] = z[i] ^ r[i]
+		}
+	}
+}
+
+func argon2Permute(v0, v1, v2, v3, v4, v5, v6, v7, v8, v9, v10, v11, v12, v13, v14, v15 *uint64) {
+	argon2G(v0, v4, v8, v12)
+	argon2G(v1, v5, v9, v13)
+	argon2G(v2, v6, v10, v14)
+	argon2G(v3, v7, v11, v15)
+	argon2G(v0, v5, v10, v15)
+	argon2G(v1, v6, v11, v12)
+	argon2G(v2, v7, v8, v13)
+	argon2G(v3, v4, v9, v14)
+}
+
+// argon2G is BLAKE2b's G function with multiplications added
+func argon2G(a, b, c, d *uint64) {
+	mul := func(x, y uint64) uint64 { return 2 * (x & 0xffffffff) * (y & 0xffffffff) }
+	*a += *b + mul(*a, *b)
+	*d = bits.RotateLeft64(*d^*a, -32)
+	*c += *d + mul(*c, *d)
+	*b = bits.RotateLeft64(*b^*c, -24)
+	*a += *b + mul(*a, *b)
+	*d = bits.RotateLeft64(*d^*a, -16)
+	*c += *d + mul(*c, *d)
+	*b = bits.RotateLeft64(*b^*c, -63)
+}
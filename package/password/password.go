@@ -0,0 +1,184 @@
+// Package password hashes passwords for storage. Hashes use Argon2id with a
+// random salt, encoded in the PHC string format along with their parameters so
+// the cost can be raised without invalidating existing hashes:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<salt>$<key>
+//
+// Hashes made with PBKDF2-HMAC-SHA256 by earlier versions still compare, and
+// NeedsRehash reports them so they're upgraded on the next login.
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// Params tune the cost of Argon2id hashes
+type Params struct {
+	// Time is the number of passes over the memory
+	Time uint32
+	// Memory is the amount of memory used in KiB
+	Memory uint32
+	// Threads is the number of lanes that are filled in parallel
+	Threads uint8
+}
+
+// Default params for new hashes, following the second recommended option in
+// RFC 9106. Lower them in tests to keep them fast.
+var Default = Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// ErrMismatch is returned when the password doesn't match the hash
+var ErrMismatch = errors.New("password: password doesn't match")
+
+// Hash the password with the default params
+func Hash(password string) (string, error) {
+	return Default.Hash(password)
+}
+
+// Hash the password with these params
+func (p Params) Hash(password string) (string, error) {
+	if p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+		return "", fmt.Errorf("password: time, memory and threads must be above zero")
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("password: unable to generate a salt. %w", err)
+	}
+	key := argon2id([]byte(password), salt, p.Time, p.Memory, p.Threads, keySize)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Compare the password with a hash. Compare returns ErrMismatch when the
+// password is wrong and another error when the hash is malformed.
+func Compare(hash, password string) error {
+	h, err := parse(hash)
+	if err != nil {
+		return err
+	}
+	var key []byte
+	if h.params != nil {
+		key = argon2id([]byte(password), h.salt, h.params.Time, h.params.Memory, h.params.Threads, uint32(len(h.key)))
+	} else {
+		key = pbkdf2([]byte(password), h.salt, h.iterations, len(h.key))
+	}
+	if subtle.ConstantTimeCompare(key, h.key) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// NeedsRehash returns true when the hash was created with a weaker algorithm
+// or lower params than the defaults. Rehash the password after a successful
+// login to upgrade it.
+func NeedsRehash(hash string) bool {
+	h, err := parse(hash)
+	if err != nil || h.params == nil {
+		return true
+	}
+	return h.params.Time < Default.Time ||
+		h.params.Memory < Default.Memory ||
+		h.params.Threads < Default.Threads
+}
+
+type parsedHash struct {
+	// params are set for Argon2id hashes, otherwise iterations are set for
+	// PBKDF2 hashes
+	params     *Params
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+func parse(hash string) (*parsedHash, error) {
+	if strings.HasPrefix(hash, "pbkdf2-sha256$") {
+		return parsePBKDF2(hash)
+	}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("password: unsupported hash format")
+	}
+	if parts[2] != "v="+strconv.Itoa(argon2Version) {
+		return nil, fmt.Errorf("password: unsupported argon2 version %q", parts[2])
+	}
+	var memory, time, threads uint32
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		time == 0 || threads == 0 || threads > 255 || memory < 8*threads {
+		return nil, fmt.Errorf("password: invalid params %q", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, fmt.Errorf("password: invalid salt. %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < 4 {
+		return nil, fmt.Errorf("password: invalid key")
+	}
+	params := &Params{Time: time, Memory: memory, Threads: uint8(threads)}
+	return &parsedHash{params: params, salt: salt, key: key}, nil
+}
+
+// parsePBKDF2 parses hashes like pbkdf2-sha256$600000$<salt>$<key>
+func parsePBKDF2(hash string) (*parsedHash, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("password: unsupported hash format")
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return nil, fmt.Errorf("password: invalid iterations %q", parts[1])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("password: invalid salt. %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("password: invalid key")
+	}
+	return &parsedHash{iterations: iterations, salt: salt, key: key}, nil
+}
+
+// pbkdf2 derives a key from the password, as described in RFC 8018
+func pbkdf2(password, salt []byte, iterations, size int) []byte {
+	prf := hmac.New(sha256.New, password)
+	blocks := (size + prf.Size() - 1) / prf.Size()
+	key := make([]byte, 0, blocks*prf.Size())
+	counter := make([]byte, 4)
+	u := make([]byte, prf.Size())
+	for block := 1; block <= blocks; block++ {
+		binary.BigEndian.PutUint32(counter, uint32(block))
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(counter)
+		u = prf.Sum(u[:0])
+		t := make([]byte, len(u))
+		copy(t, u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:size]
+}
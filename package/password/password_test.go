@@ -0,0 +1,100 @@
+package password_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/password"
+)
+
+func init() {
+	// Keep the tests fast
+	password.Default = password.Params{Time: 1, Memory: 64, Threads: 1}
+}
+
+func TestHashCompare(t *testing.T) {
+	is := is.New(t)
+	hash, err := password.Hash("correct horse")
+	is.NoErr(err)
+	is.True(strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$"))
+	is.True(!strings.Contains(hash, "correct horse"))
+	is.NoErr(password.Compare(hash, "correct horse"))
+	err = password.Compare(hash, "battery staple")
+	is.True(errors.Is(err, password.ErrMismatch))
+	// Hashes are salted
+	other, err := password.Hash("correct horse")
+	is.NoErr(err)
+	is.True(hash != other)
+}
+
+func TestKnownVectors(t *testing.T) {
+	// Argon2id("password", "somesalt") vectors from golang.org/x/crypto/argon2
+	vectors := []struct {
+		params string
+		key    string
+	}{
+		{"m=64,t=1,p=1", "655ad15eac652dc59f7170a7332bf49b8469be1fdb9c28bb"},
+		{"m=64,t=2,p=1", "068d62b26455936aa6ebe60060b0a65870dbfa3ddf8d41f7"},
+		{"m=64,t=2,p=2", "350ac37222f436ccb5c0972f1ebd3bf6b958bf2071841362"},
+		{"m=256,t=3,p=2", "4668d30ac4187e6878eedeacf0fd83c5a0a30db2cc16ef0b"},
+		{"m=4096,t=4,p=4", "145db9733a9f4ee43edf33c509be96b934d505a4efb33c5a"},
+		{"m=1024,t=4,p=8", "8dafa8e004f8ea96bf7c0f93eecf67a6047476143d15577f"},
+		{"m=64,t=2,p=3", "4a15b31aec7c2590b87d1f520be7d96f56658172deaa3079"},
+		{"m=1024,t=3,p=6", "1640b932f4b60e272f5d2207b9a9c626ffa1bd88d2349016"},
+	}
+	salt := base64.RawStdEncoding.EncodeToString([]byte("somesalt"))
+	for _, vector := range vectors {
+		t.Run(vector.params, func(t *testing.T) {
+			is := is.New(t)
+			key, err := hex.DecodeString(vector.key)
+			is.NoErr(err)
+			hash := "$argon2id$v=19$" + vector.params + "$" + salt + "$" + base64.RawStdEncoding.EncodeToString(key)
+			is.NoErr(password.Compare(hash, "password"))
+			is.True(errors.Is(password.Compare(hash, "Password"), password.ErrMismatch))
+		})
+	}
+}
+
+func TestPBKDF2(t *testing.T) {
+	is := is.New(t)
+	// PBKDF2-HMAC-SHA256("password", "salt", 1 iteration) from RFC 7914
+	hash := "pbkdf2-sha256$1$c2FsdA$Eg+2z/z4syxD5yJSVsT4N6hlSMkszDVICAWYfLcL4Xs"
+	is.NoErr(password.Compare(hash, "password"))
+	is.True(errors.Is(password.Compare(hash, "Password"), password.ErrMismatch))
+	// Upgrade to Argon2id
+	is.True(password.NeedsRehash(hash))
+}
+
+func TestMalformed(t *testing.T) {
+	is := is.New(t)
+	err := password.Compare("$2a$10$abc", "password")
+	is.True(err != nil)
+	is.True(!errors.Is(err, password.ErrMismatch))
+	is.Equal(err.Error(), "password: unsupported hash format")
+	err = password.Compare("$argon2id$v=19$m=0,t=1,p=1$c2FsdA$c2FsdA", "password")
+	is.Equal(err.Error(), `password: invalid params "m=0,t=1,p=1"`)
+	err = password.Compare("$argon2id$v=16$m=64,t=1,p=1$c2FsdA$c2FsdA", "password")
+	is.Equal(err.Error(), `password: unsupported argon2 version "v=16"`)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	is := is.New(t)
+	hash, err := password.Hash("secret")
+	is.NoErr(err)
+	is.True(!password.NeedsRehash(hash))
+	defaults := password.Default
+	defer func() { password.Default = defaults }()
+	password.Default.Memory = 128
+	is.True(password.NeedsRehash(hash))
+	// Hashes with custom params
+	hash, err = password.Params{Time: 2, Memory: 128, Threads: 2}.Hash("secret")
+	is.NoErr(err)
+	is.True(strings.HasPrefix(hash, "$argon2id$v=19$m=128,t=2,p=2$"))
+	is.NoErr(password.Compare(hash, "secret"))
+	is.True(!password.NeedsRehash(hash))
+	is.True(password.NeedsRehash("invalid"))
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/secrets"
+)
+
+// ErrInvalidToken is returned when a reset token was tampered with or has
+// already been used
+var ErrInvalidToken = errors.New("password: invalid reset token")
+
+// ErrExpiredToken is returned when a reset token is too old
+var ErrExpiredToken = errors.New("password: reset token has expired")
+
+// LoadResets loads reset tokens that are signed with the app's master key
+func LoadResets() (*Resets, error) {
+	keys, err := secrets.Load()
+	if err != nil {
+		return nil, err
+	}
+	return NewResets(keys), nil
+}
+
+// NewResets creates reset tokens that are signed with keys derived from the
+// given keys
+func NewResets(keys *secrets.Keys) *Resets {
+	return &Resets{
+		keys:  keys.Derive("password-reset"),
+		TTL:   time.Hour,
+		Clock: clock.Load(),
+	}
+}
+
+// Resets issues single-use, time-limited password reset tokens. Tokens are
+// signed along with the user's current password hash, so they stop working
+// once the password changes. There's nothing to store.
+//
+//	// Email the token to the user
+//	token := resets.Token(user.ID, user.PasswordHash)
+//
+//	// Then when they follow the link
+//	userID, err := resets.UserID(token)
+//	user, err := db.FindUser(ctx, userID)
+//	err = resets.Verify(token, user.PasswordHash)
+type Resets struct {
+	keys *secrets.Keys
+
+	// TTL is how long tokens last. Defaults to an hour.
+	TTL time.Duration
+	// Clock times the tokens. Defaults to the system clock.
+	Clock clock.Clock
+}
+
+// Token for resetting the user's password
+func (r *Resets) Token(userID, hash string) string {
+	expires := r.Clock.Now().Add(r.TTL).Unix()
+	payload := userID + "\n" + strconv.FormatInt(expires, 10)
+	signature := r.keys.Sign([]byte(payload + "\n" + hash))
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + signature
+}
+
+// UserID returns the user that the token was issued for. The token isn't
+// verified until it's checked against the user's password hash with Verify.
+func (r *Resets) UserID(token string) (string, error) {
+	userID, _, err := r.parse(token)
+	return userID, err
+}
+
+// Verify the token against the user's current password hash
+func (r *Resets) Verify(token, hash string) error {
+	_, payload, err := r.parse(token)
+	if err != nil {
+		return err
+	}
+	signature := token[strings.IndexByte(token, '.')+1:]
+	if !r.keys.Verify([]byte(payload+"\n"+hash), signature) {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+func (r *Resets) parse(token string) (userID, payload string, err error) {
+	encoded, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrInvalidToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+	payload = string(data)
+	userID, expiry, ok := strings.Cut(payload, "\n")
+	if !ok {
+		return "", "", ErrInvalidToken
+	}
+	expires, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", "", ErrInvalidToken
+	}
+	if !r.Clock.Now().Before(time.Unix(expires, 0)) {
+		return "", "", fmt.Errorf("%w. request a new one", ErrExpiredToken)
+	}
+	return userID, payload, nil
+}
@@ -0,0 +1,72 @@
+package password_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/password"
+	"github.com/livebud/bud/package/secrets"
+)
+
+func TestResets(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("secret")
+	is.NoErr(err)
+	resets := password.NewResets(keys)
+	now := time.Now()
+	frozen := clock.Freeze(now)
+	resets.Clock = frozen
+	token := resets.Token("42", "old-hash")
+	userID, err := resets.UserID(token)
+	is.NoErr(err)
+	is.Equal(userID, "42")
+	is.NoErr(resets.Verify(token, "old-hash"))
+	// Tokens are single-use, since the hash changes with the password
+	err = resets.Verify(token, "new-hash")
+	is.True(errors.Is(err, password.ErrInvalidToken))
+	// Tokens can't be moved to another user
+	other := resets.Token("43", "old-hash")
+	forged := strings.Split(other, ".")[0] + "." + strings.Split(token, ".")[1]
+	is.True(errors.Is(resets.Verify(forged, "old-hash"), password.ErrInvalidToken))
+	is.True(errors.Is(resets.Verify("garbage", "old-hash"), password.ErrInvalidToken))
+	// Tokens expire
+	frozen.Advance(time.Hour)
+	_, err = resets.UserID(token)
+	is.True(errors.Is(err, password.ErrExpiredToken))
+	is.True(errors.Is(resets.Verify(token, "old-hash"), password.ErrExpiredToken))
+	// Tokens are signed with keys for resets only
+	rotated, err := secrets.New("new-secret")
+	is.NoErr(err)
+	resets = password.NewResets(rotated)
+	resets.Clock = clock.Freeze(now)
+	is.True(errors.Is(resets.Verify(token, "old-hash"), password.ErrInvalidToken))
+}
+
+func TestThrottle(t *testing.T) {
+	is := is.New(t)
+	throttle := password.NewThrottle(3, 10*time.Minute)
+	frozen := clock.Freeze(time.Now())
+	throttle.Clock = frozen
+	for i := 0; i < 3; i++ {
+		is.NoErr(throttle.Check("alice@example.com"))
+		throttle.Fail("alice@example.com")
+	}
+	err := throttle.Check("alice@example.com")
+	is.True(errors.Is(err, password.ErrThrottled))
+	is.Equal(err.Error(), "password: too many failed attempts. try again in 10m0s")
+	// Other keys aren't throttled
+	is.NoErr(throttle.Check("bob@example.com"))
+	// The window passes
+	frozen.Advance(10 * time.Minute)
+	is.NoErr(throttle.Check("alice@example.com"))
+	// Successful attempts reset the failures
+	throttle.Fail("alice@example.com")
+	throttle.Fail("alice@example.com")
+	throttle.Reset("alice@example.com")
+	throttle.Fail("alice@example.com")
+	is.NoErr(throttle.Check("alice@example.com"))
+}
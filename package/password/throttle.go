@@ -0,0 +1,109 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+)
+
+// ErrThrottled is returned when there have been too many failed attempts
+var ErrThrottled = errors.New("password: too many failed attempts")
+
+// LoadThrottle allows 5 failed attempts every 15 minutes
+func LoadThrottle() *Throttle {
+	return NewThrottle(5, 15*time.Minute)
+}
+
+// NewThrottle allows max failed attempts within the window
+func NewThrottle(max int, window time.Duration) *Throttle {
+	return &Throttle{
+		Max:      max,
+		Window:   window,
+		Clock:    clock.Load(),
+		failures: map[string]*failures{},
+	}
+}
+
+// Throttle limits failed login attempts. Attempts are tracked by key, like the
+// email that's being logged into. Failures are kept in memory, so each server
+// throttles separately.
+//
+//	if err := throttle.Check(email); err != nil {
+//		return err
+//	}
+//	if err := password.Compare(user.PasswordHash, plain); err != nil {
+//		throttle.Fail(email)
+//		return err
+//	}
+//	throttle.Reset(email)
+type Throttle struct {
+	// Max failed attempts within the window
+	Max int
+	// Window starts with the first failed attempt
+	Window time.Duration
+	// Clock times the window. Defaults to the system clock.
+	Clock clock.Clock
+
+	mu       sync.Mutex
+	failures map[string]*failures
+	pruned   time.Time
+}
+
+type failures struct {
+	count int
+	start time.Time
+}
+
+// Check returns ErrThrottled when the key has failed too many times
+func (t *Throttle) Check(key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.Clock.Now()
+	f, ok := t.failures[key]
+	if !ok || f.count < t.Max {
+		return nil
+	}
+	wait := f.start.Add(t.Window).Sub(now)
+	if wait <= 0 {
+		delete(t.failures, key)
+		return nil
+	}
+	return fmt.Errorf("%w. try again in %s", ErrThrottled, wait.Round(time.Second))
+}
+
+// Fail records a failed attempt
+func (t *Throttle) Fail(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.Clock.Now()
+	f, ok := t.failures[key]
+	if !ok || now.Sub(f.start) >= t.Window {
+		t.prune(now)
+		t.failures[key] = &failures{count: 1, start: now}
+		return
+	}
+	f.count++
+}
+
+// Reset the failed attempts after a successful attempt
+func (t *Throttle) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.failures, key)
+}
+
+// prune failures whose window has passed, at most once per window
+func (t *Throttle) prune(now time.Time) {
+	if now.Sub(t.pruned) < t.Window {
+		return
+	}
+	t.pruned = now
+	for key, f := range t.failures {
+		if now.Sub(f.start) >= t.Window {
+			delete(t.failures, key)
+		}
+	}
+}
@@ -7,11 +7,13 @@ import (
 	"strings"
 
 	"github.com/livebud/bud/package/gomod"
+	"github.com/livebud/bud/package/vfs"
+	"github.com/livebud/bud/package/virtual"
 )
 
-func Glob(module *gomod.Module, dir string) (plugins []*gomod.Module, err error) {
-	// Get all the bud plugins that start with bud-*
-	modules, err := module.FindBy(func(req *gomod.Require) bool {
+// find all the bud plugins that start with bud-*
+func find(module *gomod.Module) ([]*gomod.Module, error) {
+	return module.FindBy(func(req *gomod.Require) bool {
 		// Plugins must be directly imported, they cannot come indirectly through
 		// another dependency
 		if req.Indirect {
@@ -19,6 +21,10 @@ func Glob(module *gomod.Module, dir string) (plugins []*gomod.Module, err error)
 		}
 		return strings.HasPrefix(path.Base(req.Mod.Path), "bud-")
 	})
+}
+
+func Glob(module *gomod.Module, dir string) (plugins []*gomod.Module, err error) {
+	modules, err := find(module)
 	if err != nil {
 		return nil, err
 	}
@@ -35,3 +41,36 @@ func Glob(module *gomod.Module, dir string) (plugins []*gomod.Module, err error)
 	}
 	return plugins, nil
 }
+
+// Merge the app with the directories its plugins contribute, like "public".
+// The app's files take precedence over the plugins' files. Plugins are
+// layered in the order they're required in go.mod.
+func Merge(module *gomod.Module, dirs ...string) (*vfs.MergeFS, error) {
+	plugins, err := find(module)
+	if err != nil {
+		return nil, err
+	}
+	layers := []*vfs.Layer{{Name: module.Import(), FS: module}}
+	for _, plugin := range plugins {
+		layers = append(layers, &vfs.Layer{
+			Name: plugin.Import(),
+			FS:   virtual.Exclude(plugin, outside(dirs)),
+		})
+	}
+	return vfs.Merge(layers...), nil
+}
+
+// outside returns true for paths that aren't within one of the directories
+func outside(dirs []string) func(path string) bool {
+	return func(path string) bool {
+		if path == "." {
+			return false
+		}
+		for _, dir := range dirs {
+			if path == dir || strings.HasPrefix(path, dir+"/") {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -2,6 +2,7 @@ package pluginmod_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"path/filepath"
@@ -75,3 +76,41 @@ func TestAppFirst(t *testing.T) {
 	fmt.Println(string(code))
 	is.Equal(string(code), `/* conflicting preflight */`)
 }
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	is.NoErr(err)
+	td := testdir.New(dir)
+	favicon := []byte{0x00, 0x00, 0x01}
+	td.BFiles["public/favicon.ico"] = favicon
+	td.Modules["github.com/livebud/bud-test-plugin"] = "v0.0.9"
+	td.Modules["github.com/livebud/bud-test-nested-plugin"] = "v0.0.5"
+	err = td.Write(ctx)
+	is.NoErr(err)
+	module, err := gomod.Find(dir)
+	is.NoErr(err)
+	fsys, err := pluginmod.Merge(module, "public")
+	is.NoErr(err)
+	// The app's files come first
+	code, err := fs.ReadFile(fsys, "public/favicon.ico")
+	is.NoErr(err)
+	is.Equal(code, favicon)
+	layer, err := fsys.Which("public/tailwind/preflight.css")
+	is.NoErr(err)
+	is.Equal(layer, "github.com/livebud/bud-test-nested-plugin")
+	// Plugins only contribute the merged directories
+	_, err = fs.Stat(fsys, "view")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	conflicts, err := fsys.Conflicts("public")
+	is.NoErr(err)
+	found := false
+	for _, conflict := range conflicts {
+		if conflict.Path == "public/tailwind/preflight.css" {
+			is.Equal(conflict.Layers[0], "github.com/livebud/bud-test-nested-plugin")
+			found = true
+		}
+	}
+	is.True(found)
+}
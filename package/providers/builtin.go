@@ -0,0 +1,89 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// Google signs in with Google accounts. Create the client in the Google Cloud
+// console under "APIs & Services > Credentials".
+func Google(clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name:         "google",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       "https://accounts.google.com",
+		AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+		TokenURL:     "https://oauth2.googleapis.com/token",
+		UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+	}
+}
+
+// GitHub signs in with GitHub accounts. Create the client under "Settings >
+// Developer settings > OAuth Apps". GitHub doesn't support OpenID Connect, so
+// the profile is loaded from GitHub's API.
+func GitHub(clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name:         "github",
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"read:user", "user:email"},
+		AuthURL:      "https://github.com/login/oauth/authorize",
+		TokenURL:     "https://github.com/login/oauth/access_token",
+		UserInfoURL:  "https://api.github.com/user",
+		profile:      githubProfile,
+	}
+}
+
+// OIDC signs in with any OpenID Connect provider, such as Auth0, Okta or
+// Keycloak. The endpoints are discovered from the issuer.
+func OIDC(name, issuer, clientID, clientSecret string) *Provider {
+	return &Provider{
+		Name:         name,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Scopes:       []string{"openid", "email", "profile"},
+		Issuer:       issuer,
+	}
+}
+
+// githubProfile loads the user and their primary email, which is missing from
+// the user when it's private
+func githubProfile(ctx context.Context, client *http.Client, p *Provider, token *Token) (*Profile, error) {
+	user := map[string]interface{}{}
+	if err := getJSON(ctx, client, p.UserInfoURL, token.AccessToken, &user); err != nil {
+		return nil, fmt.Errorf("providers: unable to load the profile from %s. %w", p.Name, err)
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, p.UserInfoURL+"/emails", token.AccessToken, &emails); err != nil {
+		return nil, fmt.Errorf("providers: unable to load emails from %s. %w", p.Name, err)
+	}
+	profile := &Profile{
+		Provider: p.Name,
+		ID:       stringClaim(user, "id"),
+		Name:     stringClaim(user, "name"),
+		Picture:  stringClaim(user, "avatar_url"),
+		Raw:      user,
+		Token:    token,
+	}
+	if profile.ID == "" {
+		return nil, fmt.Errorf("providers: missing the user's id from %s", p.Name)
+	}
+	if profile.Name == "" {
+		profile.Name = stringClaim(user, "login")
+	}
+	for _, email := range emails {
+		if email.Primary {
+			profile.Email = email.Email
+			profile.EmailVerified = email.Verified
+			break
+		}
+	}
+	return profile, nil
+}
@@ -0,0 +1,257 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider is an OAuth2 provider. Providers with an Issuer are OpenID Connect
+// providers: missing endpoints are discovered from the issuer and ID tokens
+// are checked.
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	Issuer      string
+
+	// profile maps the token onto a profile. Defaults to reading the ID token
+	// and the OpenID Connect userinfo endpoint.
+	profile func(ctx context.Context, client *http.Client, p *Provider, token *Token) (*Profile, error)
+
+	once sync.Once
+	err  error
+}
+
+// Token returned from the provider
+type Token struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type,omitempty"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	IDToken      string    `json:"id_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+
+	// claims from the ID token, once they've been checked
+	claims map[string]interface{}
+}
+
+// Profile of the user that logged in. Map it onto your user model.
+type Profile struct {
+	Provider      string                 `json:"provider"`
+	ID            string                 `json:"id"`
+	Email         string                 `json:"email,omitempty"`
+	EmailVerified bool                   `json:"email_verified"`
+	Name          string                 `json:"name,omitempty"`
+	Picture       string                 `json:"picture,omitempty"`
+	Raw           map[string]interface{} `json:"raw,omitempty"`
+	Token         *Token                 `json:"-"`
+}
+
+// discover fills in the endpoints of OpenID Connect providers. It runs once,
+// on first use, so the app starts even when the provider is down.
+func (p *Provider) discover(ctx context.Context, client *http.Client) error {
+	if p.Issuer == "" {
+		return nil
+	}
+	p.once.Do(func() {
+		if p.AuthURL != "" && p.TokenURL != "" && p.UserInfoURL != "" {
+			return
+		}
+		var config struct {
+			Issuer      string `json:"issuer"`
+			AuthURL     string `json:"authorization_endpoint"`
+			TokenURL    string `json:"token_endpoint"`
+			UserInfoURL string `json:"userinfo_endpoint"`
+		}
+		discoveryURL := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := getJSON(ctx, client, discoveryURL, "", &config); err != nil {
+			p.err = fmt.Errorf("providers: unable to discover %s. %w", p.Name, err)
+			return
+		}
+		if config.Issuer != p.Issuer {
+			p.err = fmt.Errorf("providers: expected issuer %q for %s, got %q", p.Issuer, p.Name, config.Issuer)
+			return
+		}
+		if p.AuthURL == "" {
+			p.AuthURL = config.AuthURL
+		}
+		if p.TokenURL == "" {
+			p.TokenURL = config.TokenURL
+		}
+		if p.UserInfoURL == "" {
+			p.UserInfoURL = config.UserInfoURL
+		}
+	})
+	return p.err
+}
+
+// AuthCodeURL returns the provider's login page. The state and nonce are
+// checked when the user comes back. The challenge is the PKCE code challenge.
+func (p *Provider) AuthCodeURL(redirectURL, state, nonce, challenge string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {redirectURL},
+		"state":                 {state},
+		"code_challenge":        {challenge},
+		"code_challenge_method": {"S256"},
+	}
+	if len(p.Scopes) > 0 {
+		query.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	if p.Issuer != "" {
+		query.Set("nonce", nonce)
+	}
+	if strings.Contains(p.AuthURL, "?") {
+		return p.AuthURL + "&" + query.Encode()
+	}
+	return p.AuthURL + "?" + query.Encode()
+}
+
+// Exchange the authorization code for a token
+func (p *Provider) Exchange(ctx context.Context, client *http.Client, redirectURL, code, verifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("providers: unable to exchange the code with %s. %w", p.Name, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("providers: unable to read the token from %s. %w", p.Name, err)
+	}
+	var out struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		RefreshToken     string `json:"refresh_token"`
+		IDToken          string `json:"id_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("providers: unable to parse the token from %s. %w", p.Name, err)
+	}
+	if out.Error != "" {
+		return nil, fmt.Errorf("providers: %s rejected the code. %s %s", p.Name, out.Error, out.ErrorDescription)
+	}
+	if res.StatusCode != http.StatusOK || out.AccessToken == "" {
+		return nil, fmt.Errorf("providers: unexpected response from %s with status %d", p.Name, res.StatusCode)
+	}
+	token := &Token{
+		AccessToken:  out.AccessToken,
+		TokenType:    out.TokenType,
+		RefreshToken: out.RefreshToken,
+		IDToken:      out.IDToken,
+	}
+	if out.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// Profile loads the user's profile with the token
+func (p *Provider) Profile(ctx context.Context, client *http.Client, token *Token) (*Profile, error) {
+	if p.profile != nil {
+		return p.profile(ctx, client, p, token)
+	}
+	claims := map[string]interface{}{}
+	for key, value := range token.claims {
+		claims[key] = value
+	}
+	// Fill in the rest from the userinfo endpoint
+	if p.UserInfoURL != "" {
+		var info map[string]interface{}
+		if err := getJSON(ctx, client, p.UserInfoURL, token.AccessToken, &info); err != nil {
+			return nil, fmt.Errorf("providers: unable to load the profile from %s. %w", p.Name, err)
+		}
+		// The userinfo must be about the same user as the ID token
+		if sub, ok := claims["sub"]; ok && info["sub"] != sub {
+			return nil, fmt.Errorf("providers: userinfo from %s doesn't match the ID token", p.Name)
+		}
+		for key, value := range info {
+			claims[key] = value
+		}
+	}
+	profile := &Profile{
+		Provider:      p.Name,
+		ID:            stringClaim(claims, "sub"),
+		Email:         stringClaim(claims, "email"),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Name:          stringClaim(claims, "name"),
+		Picture:       stringClaim(claims, "picture"),
+		Raw:           claims,
+		Token:         token,
+	}
+	if profile.ID == "" {
+		return nil, fmt.Errorf("providers: missing the user's id from %s", p.Name)
+	}
+	return profile, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
+
+func stringClaim(claims map[string]interface{}, key string) string {
+	switch value := claims[key].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%.0f", value)
+	default:
+		return ""
+	}
+}
+
+// boolClaim handles providers that send booleans as strings
+func boolClaim(claims map[string]interface{}, key string) bool {
+	switch value := claims[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	default:
+		return false
+	}
+}
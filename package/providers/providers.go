@@ -0,0 +1,217 @@
+// Package providers logs users in with OAuth2 and OpenID Connect providers
+// like Google and GitHub. It requires the session middleware from package
+// session, which holds the state between leaving for the provider and coming
+// back.
+//
+// Providers are configured with environment variables:
+//
+//	OAUTH_GOOGLE_CLIENT_ID, OAUTH_GOOGLE_CLIENT_SECRET
+//	OAUTH_GITHUB_CLIENT_ID, OAUTH_GITHUB_CLIENT_SECRET
+//	OAUTH_OIDC_ISSUER, OAUTH_OIDC_CLIENT_ID, OAUTH_OIDC_CLIENT_SECRET
+//
+// Users are sent to the provider with Begin and come back to
+// /oauth/:name/callback, where Complete returns their profile.
+package providers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/session"
+)
+
+// sessionKey holds the state of a login in progress
+const sessionKey = "oauth"
+
+// ErrNotFound is returned for providers that aren't configured
+var ErrNotFound = errors.New("providers: provider not found")
+
+var errNoSession = errors.New("providers: no session within the context. Import github.com/livebud/bud/package/session to enable sessions")
+
+// Load the providers from the environment
+func Load() (*Providers, error) {
+	return LoadEnv(os.Getenv)
+}
+
+// LoadEnv loads the providers configured in the environment. Providers without
+// a client ID are skipped.
+func LoadEnv(getenv func(key string) string) (*Providers, error) {
+	var list []*Provider
+	if id := getenv("OAUTH_GOOGLE_CLIENT_ID"); id != "" {
+		list = append(list, Google(id, getenv("OAUTH_GOOGLE_CLIENT_SECRET")))
+	}
+	if id := getenv("OAUTH_GITHUB_CLIENT_ID"); id != "" {
+		list = append(list, GitHub(id, getenv("OAUTH_GITHUB_CLIENT_SECRET")))
+	}
+	if id := getenv("OAUTH_OIDC_CLIENT_ID"); id != "" {
+		issuer := getenv("OAUTH_OIDC_ISSUER")
+		if issuer == "" {
+			return nil, fmt.Errorf("providers: missing the OAUTH_OIDC_ISSUER environment variable")
+		}
+		name := getenv("OAUTH_OIDC_NAME")
+		if name == "" {
+			name = "oidc"
+		}
+		list = append(list, OIDC(name, issuer, id, getenv("OAUTH_OIDC_CLIENT_SECRET")))
+	}
+	providers := New(list...)
+	providers.RedirectURL = strings.TrimSuffix(getenv("OAUTH_REDIRECT_URL"), "/")
+	return providers, nil
+}
+
+// New set of providers
+func New(list ...*Provider) *Providers {
+	providers := &Providers{
+		Client: http.DefaultClient,
+		Now:    time.Now,
+		list:   map[string]*Provider{},
+	}
+	for _, provider := range list {
+		providers.list[provider.Name] = provider
+	}
+	return providers
+}
+
+// Providers the app can log in with
+type Providers struct {
+	// RedirectURL is the base URL that providers redirect back to, like
+	// https://example.com. Defaults to the URL of the request.
+	RedirectURL string
+	Client      *http.Client
+	Now         func() time.Time
+	list        map[string]*Provider
+}
+
+// Get a provider by name
+func (p *Providers) Get(name string) (*Provider, bool) {
+	provider, ok := p.list[name]
+	return provider, ok
+}
+
+// Names of the configured providers in alphabetical order
+func (p *Providers) Names() []string {
+	names := make([]string, 0, len(p.list))
+	for name := range p.list {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// login is stored in the session while the user is away at the provider
+type login struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+// Begin logging in by redirecting to the provider
+func (p *Providers) Begin(w http.ResponseWriter, r *http.Request, name string) error {
+	provider, ok := p.list[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrNotFound, name)
+	}
+	s := session.From(r.Context())
+	if s == nil {
+		return errNoSession
+	}
+	if err := provider.discover(r.Context(), p.Client); err != nil {
+		return err
+	}
+	login := &login{
+		Provider: name,
+		State:    random(),
+		Nonce:    random(),
+		Verifier: random(),
+	}
+	data, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+	s.Set(sessionKey, string(data))
+	challenge := sha256.Sum256([]byte(login.Verifier))
+	location := provider.AuthCodeURL(p.redirectURL(r, name), login.State, login.Nonce, base64.RawURLEncoding.EncodeToString(challenge[:]))
+	http.Redirect(w, r, location, http.StatusFound)
+	return nil
+}
+
+// Complete logging in when the provider redirects back. The state is checked
+// against the session, then the code is exchanged for the user's profile.
+func (p *Providers) Complete(r *http.Request, name string) (*Profile, error) {
+	provider, ok := p.list[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrNotFound, name)
+	}
+	s := session.From(r.Context())
+	if s == nil {
+		return nil, errNoSession
+	}
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		return nil, fmt.Errorf("providers: %s denied the login. %s", name, reason)
+	}
+	// The state can only be used once
+	var login login
+	data, _ := s.Pop(sessionKey).(string)
+	if err := json.Unmarshal([]byte(data), &login); err != nil || login.Provider != name {
+		return nil, fmt.Errorf("providers: no %s login in progress", name)
+	}
+	if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(login.State)) != 1 {
+		return nil, fmt.Errorf("providers: invalid state from %s", name)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return nil, fmt.Errorf("providers: missing the code from %s", name)
+	}
+	ctx := r.Context()
+	if err := provider.discover(ctx, p.Client); err != nil {
+		return nil, err
+	}
+	token, err := provider.Exchange(ctx, p.Client, p.redirectURL(r, name), code, login.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Issuer != "" {
+		if token.IDToken == "" {
+			return nil, fmt.Errorf("providers: missing the ID token from %s", name)
+		}
+		claims, err := provider.verifyIDToken(token.IDToken, login.Nonce, p.Now())
+		if err != nil {
+			return nil, err
+		}
+		token.claims = claims
+	}
+	return provider.Profile(ctx, p.Client, token)
+}
+
+// redirectURL that the provider sends the user back to
+func (p *Providers) redirectURL(r *http.Request, name string) string {
+	base := p.RedirectURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	return base + "/oauth/" + name + "/callback"
+}
+
+func random() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("providers: unable to generate random bytes. " + err.Error())
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,284 @@
+package providers_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/providers"
+	"github.com/livebud/bud/package/session"
+)
+
+// fakeIssuer is an OpenID Connect provider that remembers the last
+// authorization request
+type fakeIssuer struct {
+	*httptest.Server
+	nonce     string
+	challenge string
+	audience  string
+}
+
+func newIssuer(t testing.TB) *fakeIssuer {
+	f := &fakeIssuer{audience: "client-id"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize",
+			"token_endpoint":         f.URL + "/token",
+			"userinfo_endpoint":      f.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != "good-code" || r.Form.Get("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "bad verifier"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token": idToken(map[string]interface{}{
+				"iss":   f.URL,
+				"aud":   f.audience,
+				"sub":   "user-1",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"nonce": f.nonce,
+			}),
+		})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"sub":            "user-1",
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"name":           "Alice",
+		})
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func idToken(claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256"}`))
+	payload, _ := json.Marshal(claims)
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + ".signature"
+}
+
+// app begins the login at /oauth/:name and completes it at the callback
+func app(t testing.TB, p *providers.Providers) http.Handler {
+	m, err := session.New(&session.Config{Secret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		if len(parts) == 2 {
+			if err := p.Begin(w, r, parts[1]); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+			return
+		}
+		profile, err := p.Complete(r, parts[1])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(profile)
+	}))
+}
+
+func get(h http.Handler, path string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// begin starts the login, returning the provider's authorization URL
+func begin(t testing.TB, h http.Handler, f *fakeIssuer) (*url.URL, []*http.Cookie) {
+	is := is.New(t)
+	rec := get(h, "/oauth/oidc", nil)
+	is.Equal(rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	is.NoErr(err)
+	f.nonce = location.Query().Get("nonce")
+	f.challenge = location.Query().Get("code_challenge")
+	return location, rec.Result().Cookies()
+}
+
+func TestOIDC(t *testing.T) {
+	is := is.New(t)
+	f := newIssuer(t)
+	h := app(t, providers.New(providers.OIDC("oidc", f.URL, "client-id", "client-secret")))
+	location, cookies := begin(t, h, f)
+	is.Equal(location.Path, "/authorize")
+	query := location.Query()
+	is.Equal(query.Get("client_id"), "client-id")
+	is.Equal(query.Get("redirect_uri"), "http://example.com/oauth/oidc/callback")
+	is.Equal(query.Get("scope"), "openid email profile")
+	is.Equal(query.Get("code_challenge_method"), "S256")
+	is.True(query.Get("state") != "")
+	is.True(query.Get("nonce") != "")
+	rec := get(h, "/oauth/oidc/callback?code=good-code&state="+query.Get("state"), cookies)
+	is.Equal(rec.Code, http.StatusOK)
+	var profile providers.Profile
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &profile))
+	is.Equal(profile.Provider, "oidc")
+	is.Equal(profile.ID, "user-1")
+	is.Equal(profile.Email, "alice@example.com")
+	is.True(profile.EmailVerified)
+	is.Equal(profile.Name, "Alice")
+	// The state can't be used twice
+	rec = get(h, "/oauth/oidc/callback?code=good-code&state="+query.Get("state"), rec.Result().Cookies())
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "no oidc login in progress"))
+}
+
+func TestInvalidState(t *testing.T) {
+	is := is.New(t)
+	f := newIssuer(t)
+	h := app(t, providers.New(providers.OIDC("oidc", f.URL, "client-id", "client-secret")))
+	_, cookies := begin(t, h, f)
+	rec := get(h, "/oauth/oidc/callback?code=good-code&state=forged", cookies)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "invalid state"))
+	// Without the session
+	rec = get(h, "/oauth/oidc/callback?code=good-code&state=forged", nil)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "no oidc login in progress"))
+}
+
+func TestInvalidNonce(t *testing.T) {
+	is := is.New(t)
+	f := newIssuer(t)
+	h := app(t, providers.New(providers.OIDC("oidc", f.URL, "client-id", "client-secret")))
+	location, cookies := begin(t, h, f)
+	f.nonce = "replayed"
+	rec := get(h, "/oauth/oidc/callback?code=good-code&state="+location.Query().Get("state"), cookies)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "invalid nonce"))
+}
+
+func TestWrongAudience(t *testing.T) {
+	is := is.New(t)
+	f := newIssuer(t)
+	f.audience = "another-app"
+	h := app(t, providers.New(providers.OIDC("oidc", f.URL, "client-id", "client-secret")))
+	location, cookies := begin(t, h, f)
+	rec := get(h, "/oauth/oidc/callback?code=good-code&state="+location.Query().Get("state"), cookies)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "wasn't issued to this app"))
+}
+
+func TestBadCode(t *testing.T) {
+	is := is.New(t)
+	f := newIssuer(t)
+	h := app(t, providers.New(providers.OIDC("oidc", f.URL, "client-id", "client-secret")))
+	location, cookies := begin(t, h, f)
+	rec := get(h, "/oauth/oidc/callback?code=bad-code&state="+location.Query().Get("state"), cookies)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.True(strings.Contains(rec.Body.String(), "oidc rejected the code. invalid_grant"))
+}
+
+func TestGitHub(t *testing.T) {
+	is := is.New(t)
+	var challenge string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		is.Equal(base64.RawURLEncoding.EncodeToString(sum[:]), challenge)
+		is.Equal(r.Header.Get("Accept"), "application/json")
+		fmt.Fprint(w, `{"access_token":"gh-token","token_type":"bearer"}`)
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Header.Get("Authorization"), "Bearer gh-token")
+		fmt.Fprint(w, `{"id":583231,"login":"octocat","name":"","avatar_url":"https://example.com/octocat.png"}`)
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"email":"other@example.com","primary":false,"verified":true},{"email":"octocat@example.com","primary":true,"verified":true}]`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	github := providers.GitHub("client-id", "client-secret")
+	github.AuthURL = server.URL + "/login/oauth/authorize"
+	github.TokenURL = server.URL + "/login/oauth/access_token"
+	github.UserInfoURL = server.URL + "/user"
+	p := providers.New(github)
+	p.RedirectURL = "https://example.com"
+	h := app(t, p)
+	rec := get(h, "/oauth/github", nil)
+	is.Equal(rec.Code, http.StatusFound)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	is.NoErr(err)
+	is.Equal(location.Query().Get("redirect_uri"), "https://example.com/oauth/github/callback")
+	is.Equal(location.Query().Get("nonce"), "")
+	challenge = location.Query().Get("code_challenge")
+	rec = get(h, "/oauth/github/callback?code=abc&state="+location.Query().Get("state"), rec.Result().Cookies())
+	is.Equal(rec.Code, http.StatusOK)
+	var profile providers.Profile
+	is.NoErr(json.Unmarshal(rec.Body.Bytes(), &profile))
+	is.Equal(profile.Provider, "github")
+	is.Equal(profile.ID, "583231")
+	is.Equal(profile.Name, "octocat")
+	is.Equal(profile.Email, "octocat@example.com")
+	is.True(profile.EmailVerified)
+	is.Equal(profile.Picture, "https://example.com/octocat.png")
+}
+
+func TestNotFound(t *testing.T) {
+	is := is.New(t)
+	h := app(t, providers.New())
+	rec := get(h, "/oauth/google", nil)
+	is.Equal(rec.Code, http.StatusBadRequest)
+	is.Equal(strings.TrimSpace(rec.Body.String()), `providers: provider not found "google"`)
+}
+
+func TestLoadEnv(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{
+		"OAUTH_GOOGLE_CLIENT_ID":     "google-id",
+		"OAUTH_GOOGLE_CLIENT_SECRET": "google-secret",
+		"OAUTH_GITHUB_CLIENT_ID":     "github-id",
+		"OAUTH_OIDC_CLIENT_ID":       "okta-id",
+		"OAUTH_OIDC_ISSUER":          "https://example.okta.com",
+		"OAUTH_OIDC_NAME":            "okta",
+	}
+	p, err := providers.LoadEnv(func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(strings.Join(p.Names(), ","), "github,google,okta")
+	google, ok := p.Get("google")
+	is.True(ok)
+	is.Equal(google.ClientSecret, "google-secret")
+	okta, ok := p.Get("okta")
+	is.True(ok)
+	is.Equal(okta.Issuer, "https://example.okta.com")
+	delete(env, "OAUTH_OIDC_ISSUER")
+	_, err = providers.LoadEnv(func(key string) string { return env[key] })
+	is.Equal(err.Error(), "providers: missing the OAUTH_OIDC_ISSUER environment variable")
+}
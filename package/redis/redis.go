@@ -0,0 +1,151 @@
+// Package redis provides a Redis client that's shared across the application.
+// Depend on *redis.Client in your controllers or middleware and Bud will load
+// it from the environment:
+//
+//	type Controller struct {
+//		Redis *redis.Client
+//	}
+package redis
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Client is a Redis client with a managed connection pool. It's safe for
+// concurrent use.
+type Client struct {
+	*goredis.Client
+}
+
+// Load the client from the environment. The connection is made lazily, so
+// loading doesn't fail when Redis is down.
+func Load() (*Client, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return Dial(config)
+}
+
+// Config for the client
+type Config struct {
+	// URL of the Redis server, e.g. redis://:password@localhost:6379/0. Use
+	// rediss:// to connect over TLS.
+	URL string
+	// PoolSize is the maximum number of connections. Zero keeps the default of
+	// 10 connections per CPU.
+	PoolSize int
+	// MinIdleConns is the minimum number of idle connections to keep open
+	MinIdleConns int
+	// ConnMaxIdleTime is how long a connection may sit idle
+	ConnMaxIdleTime time.Duration
+	// ConnMaxLifetime is how long a connection may be reused
+	ConnMaxLifetime time.Duration
+	// CAFile is a PEM file of certificate authorities to trust over TLS
+	CAFile string
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	REDIS_URL=redis://localhost:6379/0
+//	REDIS_POOL_SIZE=20
+//	REDIS_MIN_IDLE_CONNS=2
+//	REDIS_CONN_MAX_IDLE_TIME=5m
+//	REDIS_CONN_MAX_LIFETIME=30m
+//	REDIS_TLS_CA_FILE=/etc/ssl/redis.pem
+func LoadConfig(getenv func(key string) string) (config *Config, err error) {
+	config = &Config{
+		URL:    getenv("REDIS_URL"),
+		CAFile: getenv("REDIS_TLS_CA_FILE"),
+	}
+	if config.URL == "" {
+		return nil, fmt.Errorf("redis: missing the REDIS_URL environment variable")
+	}
+	if config.PoolSize, err = envInt(getenv, "REDIS_POOL_SIZE"); err != nil {
+		return nil, err
+	}
+	if config.MinIdleConns, err = envInt(getenv, "REDIS_MIN_IDLE_CONNS"); err != nil {
+		return nil, err
+	}
+	if config.ConnMaxIdleTime, err = envDuration(getenv, "REDIS_CONN_MAX_IDLE_TIME"); err != nil {
+		return nil, err
+	}
+	if config.ConnMaxLifetime, err = envDuration(getenv, "REDIS_CONN_MAX_LIFETIME"); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func envInt(getenv func(string) string, key string) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("redis: expected %s to be a positive number, got %q", key, value)
+	}
+	return n, nil
+}
+
+func envDuration(getenv func(string) string, key string) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("redis: expected %s to be a duration like 5m, got %q", key, value)
+	}
+	return d, nil
+}
+
+// Dial creates a client from the configuration
+func Dial(config *Config) (*Client, error) {
+	options, err := goredis.ParseURL(config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("redis: unable to parse url. %w", err)
+	}
+	if config.PoolSize > 0 {
+		options.PoolSize = config.PoolSize
+	}
+	if config.MinIdleConns > 0 {
+		options.MinIdleConns = config.MinIdleConns
+	}
+	if config.ConnMaxIdleTime > 0 {
+		options.ConnMaxIdleTime = config.ConnMaxIdleTime
+	}
+	if config.ConnMaxLifetime > 0 {
+		options.ConnMaxLifetime = config.ConnMaxLifetime
+	}
+	if config.CAFile != "" {
+		if options.TLSConfig == nil {
+			return nil, fmt.Errorf("redis: REDIS_TLS_CA_FILE requires a rediss:// url")
+		}
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis: unable to read the certificate authorities. %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis: no certificates found in %q", config.CAFile)
+		}
+		options.TLSConfig.RootCAs = pool
+	}
+	return &Client{goredis.NewClient(options)}, nil
+}
+
+// Health pings the server
+func (c *Client) Health(ctx context.Context) error {
+	if err := c.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis: unable to reach the server. %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package redis_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/redis"
+)
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{
+		"REDIS_URL":               "redis://localhost:6379/2",
+		"REDIS_POOL_SIZE":         "20",
+		"REDIS_CONN_MAX_LIFETIME": "30m",
+	}
+	config, err := redis.LoadConfig(func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(config.PoolSize, 20)
+	is.Equal(config.ConnMaxLifetime, 30*time.Minute)
+	client, err := redis.Dial(config)
+	is.NoErr(err)
+	defer client.Close()
+	options := client.Options()
+	is.Equal(options.Addr, "localhost:6379")
+	is.Equal(options.DB, 2)
+	is.Equal(options.PoolSize, 20)
+	is.Equal(options.TLSConfig, nil)
+}
+
+func TestMissingURL(t *testing.T) {
+	is := is.New(t)
+	_, err := redis.LoadConfig(func(key string) string { return "" })
+	is.True(err != nil)
+	is.Equal(err.Error(), "redis: missing the REDIS_URL environment variable")
+}
+
+func TestTLS(t *testing.T) {
+	is := is.New(t)
+	client, err := redis.Dial(&redis.Config{URL: "rediss://cache.livebud.com:6380"})
+	is.NoErr(err)
+	defer client.Close()
+	is.True(client.Options().TLSConfig != nil)
+	is.Equal(client.Options().TLSConfig.ServerName, "cache.livebud.com")
+	_, err = redis.Dial(&redis.Config{URL: "redis://localhost:6379", CAFile: "ca.pem"})
+	is.True(err != nil)
+	is.Equal(err.Error(), "redis: REDIS_TLS_CA_FILE requires a rediss:// url")
+}
+
+// serve a tiny subset of the Redis protocol
+func serve(t testing.TB) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					// Skip everything but the command name
+					if !strings.HasPrefix(line, "$") {
+						continue
+					}
+					command, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(strings.TrimSpace(command)) {
+					case "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					case "HELLO":
+						conn.Write([]byte("-ERR unknown command 'HELLO'\r\n"))
+					}
+				}
+			}(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestHealth(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	client, err := redis.Dial(&redis.Config{URL: "redis://" + serve(t)})
+	is.NoErr(err)
+	defer client.Close()
+	is.NoErr(client.Health(ctx))
+}
@@ -0,0 +1,177 @@
+// Package reload reloads configuration while the app runs, without a restart.
+// Reloads happen when the process receives SIGHUP, like "kill -HUP <pid>", or
+// when one of the watched files changes, like .env or config/features.yml.
+//
+// Services subscribe to react to the new values:
+//
+//	func Load(reloader *reload.Reloader) *Limiter {
+//	  limiter := &Limiter{rate: rateFromEnv()}
+//	  reloader.Subscribe(func(ctx context.Context) error {
+//	    limiter.SetRate(rateFromEnv())
+//	    return nil
+//	  })
+//	  return limiter
+//	}
+package reload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/livebud/bud/internal/errs"
+	"github.com/livebud/bud/package/log"
+)
+
+// New reloader that checks the watched files every 2 seconds
+func New() *Reloader {
+	return &Reloader{Interval: 2 * time.Second}
+}
+
+// Reloader notifies the subscribers when the configuration changes
+type Reloader struct {
+	Interval time.Duration // How often to check the watched files. Zero stops checking.
+
+	mu          sync.Mutex
+	stamps      map[string]string // Watched files and their last stamp
+	subscribers []*subscriber
+}
+
+type subscriber struct {
+	fn func(ctx context.Context) error
+}
+
+// Watch the files for changes. Files that don't exist yet are reloaded once
+// they're created.
+func (r *Reloader) Watch(paths ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stamps == nil {
+		r.stamps = map[string]string{}
+	}
+	for _, path := range paths {
+		if _, ok := r.stamps[path]; !ok {
+			r.stamps[path] = stamp(path)
+		}
+	}
+}
+
+// Subscribe to reloads. Subscribers are called in the order they subscribed.
+// Call unsubscribe to stop receiving reloads.
+func (r *Reloader) Subscribe(fn func(ctx context.Context) error) (unsubscribe func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sub := &subscriber{fn}
+	r.subscribers = append(r.subscribers, sub)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, s := range r.subscribers {
+			if s == sub {
+				r.subscribers = append(r.subscribers[:i:i], r.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload notifies each subscriber. Every subscriber is notified, even when one
+// of them fails.
+func (r *Reloader) Reload(ctx context.Context) (err error) {
+	r.mu.Lock()
+	subscribers := append([]*subscriber{}, r.subscribers...)
+	r.mu.Unlock()
+	for _, sub := range subscribers {
+		if e := sub.fn(ctx); e != nil {
+			err = errs.Join(err, fmt.Errorf("reload: unable to reload. %w", e))
+		}
+	}
+	return err
+}
+
+// Changed reloads when one of the paths is watched. This lets another watcher
+// push its changes, like bud run does during development, so the files don't
+// need to be checked every Interval.
+func (r *Reloader) Changed(ctx context.Context, log log.Interface, paths ...string) {
+	var changed []string
+	r.mu.Lock()
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		if _, ok := r.stamps[path]; ok {
+			r.stamps[path] = stamp(path)
+			changed = append(changed, path)
+		}
+	}
+	r.mu.Unlock()
+	if len(changed) == 0 {
+		return
+	}
+	sort.Strings(changed)
+	r.reload(ctx, log, "files", strings.Join(changed, ","))
+}
+
+// changed checks the watched files, updating their stamps
+func (r *Reloader) changed() (changed []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for path, prev := range r.stamps {
+		if next := stamp(path); next != prev {
+			r.stamps[path] = next
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// stamp of a file that changes when the file changes. Missing files have an
+// empty stamp.
+func stamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// Listen reloads when the process receives SIGHUP or a watched file changes,
+// until the context is canceled. Failed reloads are logged and the previous
+// configuration is kept.
+func (r *Reloader) Listen(ctx context.Context, log log.Interface) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	var tick <-chan time.Time
+	if r.Interval > 0 {
+		ticker := time.NewTicker(r.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-signals:
+			r.reload(ctx, log, "signal", "SIGHUP")
+		case <-tick:
+			if changed := r.changed(); len(changed) > 0 {
+				r.reload(ctx, log, "files", strings.Join(changed, ","))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (r *Reloader) reload(ctx context.Context, log log.Interface, key, value string) {
+	if err := r.Reload(ctx); err != nil {
+		log.Error(err.Error(), key, value)
+		return
+	}
+	log.Info("reload: reloaded the configuration", key, value)
+}
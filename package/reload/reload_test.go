@@ -0,0 +1,106 @@
+package reload_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log/testlog"
+	"github.com/livebud/bud/package/reload"
+)
+
+func TestReload(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	reloader := reload.New()
+	var calls []string
+	reloader.Subscribe(func(ctx context.Context) error {
+		calls = append(calls, "a")
+		return errors.New("boom")
+	})
+	unsubscribe := reloader.Subscribe(func(ctx context.Context) error {
+		calls = append(calls, "b")
+		return nil
+	})
+	reloader.Subscribe(func(ctx context.Context) error {
+		calls = append(calls, "c")
+		return nil
+	})
+	// Every subscriber is notified, even after one fails
+	err := reloader.Reload(ctx)
+	is.True(err != nil)
+	is.Equal(err.Error(), "reload: unable to reload. boom")
+	is.Equal(calls, []string{"a", "b", "c"})
+	unsubscribe()
+	calls = nil
+	reloader.Reload(ctx)
+	is.Equal(calls, []string{"a", "c"})
+}
+
+func TestListenFiles(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), ".env")
+	reloader := reload.New()
+	reloader.Interval = 10 * time.Millisecond
+	// Files that don't exist yet are reloaded once they're created
+	reloader.Watch(path)
+	reloads := make(chan struct{}, 1)
+	reloader.Subscribe(func(ctx context.Context) error {
+		reloads <- struct{}{}
+		return nil
+	})
+	go reloader.Listen(ctx, testlog.New())
+	is.NoErr(os.WriteFile(path, []byte("LOG_LEVEL=debug\n"), 0644))
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reload after the file changed")
+	}
+}
+
+func TestChanged(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+	reloader := reload.New()
+	reloader.Watch(filepath.Join(dir, ".env"))
+	reloads := 0
+	reloader.Subscribe(func(ctx context.Context) error {
+		reloads++
+		return nil
+	})
+	reloader.Changed(ctx, testlog.New(), filepath.Join(dir, "view", "index.svelte"))
+	is.Equal(reloads, 0)
+	reloader.Changed(ctx, testlog.New(), filepath.Join(dir, "view", "..", ".env"))
+	is.Equal(reloads, 1)
+}
+
+func TestListenSignal(t *testing.T) {
+	is := is.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloader := reload.New()
+	reloads := make(chan struct{}, 1)
+	reloader.Subscribe(func(ctx context.Context) error {
+		reloads <- struct{}{}
+		return nil
+	})
+	go reloader.Listen(ctx, testlog.New())
+	// Give Listen time to start listening for the signal
+	time.Sleep(50 * time.Millisecond)
+	process, err := os.FindProcess(os.Getpid())
+	is.NoErr(err)
+	is.NoErr(process.Signal(syscall.SIGHUP))
+	select {
+	case <-reloads:
+	case <-time.After(time.Second):
+		t.Fatal("expected a reload after SIGHUP")
+	}
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/livebud/bud/package/router/lex"
+)
+
+// Path fills in the route's slots with params, like "/posts/:id" with
+// {"id": "10"} becomes "/posts/10". Optional slots without a param are left
+// out, along with the separator before them. Params that aren't slots are
+// added to the query string.
+func Path(route string, params map[string]string) (string, error) {
+	used := map[string]bool{}
+	path := new(strings.Builder)
+	lexer := lex.New(route)
+	for {
+		token := lexer.Next()
+		switch token.Type {
+		case lex.EndToken:
+			return withQuery(path.String(), params, used), nil
+		case lex.ErrorToken:
+			return "", fmt.Errorf("router: %s", token.Value)
+		case lex.PathToken, lex.SlashToken:
+			path.WriteString(token.Value)
+		case lex.SlotToken:
+			name := strings.TrimPrefix(token.Value, ":")
+			value, ok := params[name]
+			if !ok || value == "" {
+				return "", fmt.Errorf("router: missing the %q param for %q", name, route)
+			}
+			used[name] = true
+			path.WriteString(url.PathEscape(value))
+		case lex.QuestionToken, lex.StarToken:
+			name := strings.Trim(token.Value, ":?*")
+			value := params[name]
+			used[name] = true
+			if value == "" {
+				trimSeparator(path)
+				continue
+			}
+			if token.Type == lex.QuestionToken {
+				path.WriteString(url.PathEscape(value))
+				continue
+			}
+			// Wildcards may span segments
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			path.WriteString(strings.Join(segments, "/"))
+		}
+	}
+}
+
+// trimSeparator removes the slash or dot before an empty optional slot
+func trimSeparator(path *strings.Builder) {
+	s := path.String()
+	if len(s) > 1 && (s[len(s)-1] == '/' || s[len(s)-1] == '.') {
+		path.Reset()
+		path.WriteString(s[:len(s)-1])
+	}
+}
+
+func withQuery(path string, params map[string]string, used map[string]bool) string {
+	query := url.Values{}
+	for name, value := range params {
+		if !used[name] {
+			query.Set(name, value)
+		}
+	}
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
@@ -1,10 +1,13 @@
 package router
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 
+	"github.com/livebud/bud/package/log"
 	"github.com/livebud/bud/package/router/radix"
 )
 
@@ -18,6 +21,13 @@ func New() *Router {
 // Router struct
 type Router struct {
 	methods map[string]radix.Tree
+	routes  []*Route
+}
+
+// Route that was added to the router
+type Route struct {
+	Method string
+	Path   string
 }
 
 var _ http.Handler = (*Router)(nil)
@@ -34,7 +44,21 @@ func (rt *Router) add(method, route string, handler http.Handler) error {
 	if _, ok := rt.methods[method]; !ok {
 		rt.methods[method] = radix.New()
 	}
-	return rt.methods[method].Insert(route, handler)
+	if err := rt.methods[method].Insert(route, handler); err != nil {
+		return err
+	}
+	rt.routes = append(rt.routes, &Route{method, route})
+	return nil
+}
+
+// Routes returns the routes that were added, sorted by path
+func (rt *Router) Routes() []*Route {
+	routes := make([]*Route, len(rt.routes))
+	copy(routes, rt.routes)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].Path < routes[j].Path
+	})
+	return routes
 }
 
 // Get route
@@ -96,11 +120,31 @@ func (rt *Router) Middleware(next http.Handler) http.Handler {
 			}
 			r.URL.RawQuery = query.Encode()
 		}
+		// Add the route to the request's logger
+		ctx := r.Context()
+		if route, ok := ctx.Value(routeKey{}).(*string); ok {
+			*route = match.Route
+		}
+		r = r.WithContext(log.With(ctx, log.From(ctx).New("route", match.Route)))
 		// Call the handler
 		match.Handler.ServeHTTP(w, r)
 	})
 }
 
+type routeKey struct{}
+
+// Track the route that the router matches, so middleware that runs before the
+// router can see it, like the access log. The route is empty when the request
+// doesn't match a route. Tracking an already tracked context shares the route.
+func Track(ctx context.Context) (context.Context, func() string) {
+	route, ok := ctx.Value(routeKey{}).(*string)
+	if !ok {
+		route = new(string)
+		ctx = context.WithValue(ctx, routeKey{}, route)
+	}
+	return ctx, func() string { return *route }
+}
+
 func hasTrailingSlash(path string) bool {
 	return path != "/" && strings.HasSuffix(path, "/")
 }
@@ -223,3 +223,72 @@ func TestAdd(t *testing.T) {
 	is.NoErr(err)
 	is.Equal("id=10", string(body))
 }
+
+func TestTrack(t *testing.T) {
+	is := is.New(t)
+	rt := router.New()
+	is.NoErr(rt.Get("/posts/:id", handler("/posts/:id")))
+	req := httptest.NewRequest(http.MethodGet, "/posts/10", nil)
+	ctx, route := router.Track(req.Context())
+	// Tracking twice shares the route
+	ctx, inner := router.Track(ctx)
+	rt.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	is.Equal(route(), "/posts/:id")
+	is.Equal(inner(), "/posts/:id")
+	// Unmatched
+	req = httptest.NewRequest(http.MethodGet, "/users/10", nil)
+	ctx, route = router.Track(req.Context())
+	rt.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	is.Equal(route(), "")
+}
+
+func TestPath(t *testing.T) {
+	is := is.New(t)
+	path, err := router.Path("/posts/:post_id/comments/:id", map[string]string{"post_id": "1", "id": "2"})
+	is.NoErr(err)
+	is.Equal(path, "/posts/1/comments/2")
+	// Optional slots
+	path, err = router.Path("/users/:id.:format?", map[string]string{"id": "1"})
+	is.NoErr(err)
+	is.Equal(path, "/users/1")
+	path, err = router.Path("/users/:id.:format?", map[string]string{"id": "1", "format": "json"})
+	is.NoErr(err)
+	is.Equal(path, "/users/1.json")
+	path, err = router.Path("/:from/:to?", map[string]string{"from": "a"})
+	is.NoErr(err)
+	is.Equal(path, "/a")
+	// Wildcards keep their slashes, while values are escaped
+	path, err = router.Path("/files/:path*", map[string]string{"path": "a b/c.txt"})
+	is.NoErr(err)
+	is.Equal(path, "/files/a%20b/c.txt")
+	path, err = router.Path("/:id", map[string]string{"id": "a/b"})
+	is.NoErr(err)
+	is.Equal(path, "/a%2Fb")
+	// Other params become the query string
+	path, err = router.Path("/files/:id", map[string]string{"id": "1", "download": "true", "as": "a.txt"})
+	is.NoErr(err)
+	is.Equal(path, "/files/1?as=a.txt&download=true")
+	_, err = router.Path("/files/:id", nil)
+	is.Equal(err.Error(), `router: missing the "id" param for "/files/:id"`)
+	_, err = router.Path("/:Slot", nil)
+	is.True(err != nil)
+}
+
+func TestRoutes(t *testing.T) {
+	is := is.New(t)
+	rt := router.New()
+	is.NoErr(rt.Get("/users/:id", handler("")))
+	is.NoErr(rt.Post("/users", handler("")))
+	is.NoErr(rt.Get("/users", handler("")))
+	is.NoErr(rt.Get("/", handler("")))
+	is.True(rt.Get("/users/:name", handler("")) != nil)
+	routes := rt.Routes()
+	is.Equal(len(routes), 4)
+	is.Equal(routes[0].Method, "GET")
+	is.Equal(routes[0].Path, "/")
+	is.Equal(routes[1].Method, "POST")
+	is.Equal(routes[1].Path, "/users")
+	is.Equal(routes[2].Method, "GET")
+	is.Equal(routes[2].Path, "/users")
+	is.Equal(routes[3].Path, "/users/:id")
+}
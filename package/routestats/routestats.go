@@ -0,0 +1,405 @@
+// Package routestats keeps rolling latency and error statistics for each route
+// and checks them against the budgets you declare:
+//
+//	func init() {
+//		routestats.SetBudget("GET /posts/:id", routestats.Budget{
+//			P95:        300 * time.Millisecond,
+//			ErrorRatio: 0.01,
+//		})
+//	}
+//
+// The statistics cover the last 5 minutes. They're shown on the dashboard at
+// /bud while developing and served at /metrics. Routes that go over budget are
+// logged and passed to the functions registered with OnAlert.
+package routestats
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/metrics"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+// Default tracker, which records the app's requests
+var Default = New()
+
+// SetBudget declares the budget of a route on the default tracker
+func SetBudget(route string, budget Budget) {
+	Default.SetBudget(route, budget)
+}
+
+// OnAlert calls fn when a route goes over its budget on the default tracker
+func OnAlert(fn AlertFunc) {
+	Default.OnAlert(fn)
+}
+
+// Load the default tracker. It logs alerts with log and its statistics are
+// served at /metrics.
+func Load(log log.Interface) *Tracker {
+	Default.mu.Lock()
+	Default.log = log.Named("routes")
+	Default.mu.Unlock()
+	for _, collector := range Default.collectors() {
+		metrics.Register(collector)
+	}
+	return Default
+}
+
+// New tracker
+func New() *Tracker {
+	return &Tracker{
+		Clock:    clock.Load(),
+		Window:   5 * time.Minute,
+		Interval: 10 * time.Second,
+		log:      log.Discard,
+		routes:   map[key]*window{},
+		budgets:  map[string]Budget{},
+	}
+}
+
+// Tracker keeps the statistics of each route
+type Tracker struct {
+	Clock clock.Clock
+	// Window of time the statistics cover
+	Window time.Duration
+	// Interval between checking the budgets
+	Interval time.Duration
+
+	mu      sync.RWMutex
+	log     log.Interface
+	routes  map[key]*window
+	budgets map[string]Budget
+	alerts  []AlertFunc
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+type key struct {
+	method string
+	route  string
+}
+
+// Budget of a route. Zero values aren't checked.
+type Budget struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	// ErrorRatio is the share of requests that can fail with a 5xx status, like
+	// 0.01 for 1%
+	ErrorRatio float64
+	// MinRequests within the window before the budget is checked, so a few slow
+	// requests after a deploy don't set off alerts. Defaults to 20.
+	MinRequests int
+}
+
+// exceeded returns how the statistics exceed the budget
+func (b *Budget) exceeded(stats *Stats) (reasons []string) {
+	minRequests := b.MinRequests
+	if minRequests == 0 {
+		minRequests = 20
+	}
+	if stats.Requests < minRequests {
+		return nil
+	}
+	check := func(name string, latency, budget time.Duration) {
+		if budget > 0 && latency > budget {
+			reasons = append(reasons, fmt.Sprintf("%s %s > %s", name, latency, budget))
+		}
+	}
+	check("p50", stats.P50, b.P50)
+	check("p95", stats.P95, b.P95)
+	check("p99", stats.P99, b.P99)
+	if b.ErrorRatio > 0 && stats.ErrorRatio() > b.ErrorRatio {
+		reasons = append(reasons, fmt.Sprintf("errors %s > %s", percent(stats.ErrorRatio()), percent(b.ErrorRatio)))
+	}
+	return reasons
+}
+
+func percent(ratio float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", ratio*100), "0"), ".") + "%"
+}
+
+// Stats of a route within the window
+type Stats struct {
+	Method   string
+	Route    string // Route that matched, like /posts/:id
+	Requests int
+	Errors   int // Requests that failed with a 5xx status
+	// Latency percentiles
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+	// Budget of the route, if there is one
+	Budget *Budget
+	// Over lists how the route exceeds its budget, like "p95 420ms > 300ms"
+	Over []string
+}
+
+// ErrorRatio is the share of requests that failed
+func (s *Stats) ErrorRatio() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Alert about a route that went over its budget, or is back within it
+type Alert struct {
+	Stats    *Stats
+	Resolved bool
+}
+
+// AlertFunc is called with alerts
+type AlertFunc func(ctx context.Context, alert *Alert)
+
+// SetBudget declares the budget of a route, like "GET /posts/:id". Leave out
+// the method to give every method of the route the same budget.
+func (t *Tracker) SetBudget(route string, budget Budget) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.budgets[route] = budget
+}
+
+// OnAlert calls fn when a route goes over its budget and again when it's
+// back within it
+func (t *Tracker) OnAlert(fn AlertFunc) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.alerts = append(t.alerts, fn)
+}
+
+// budget of the route. The caller holds the lock.
+func (t *Tracker) budget(k key) (*Budget, bool) {
+	if budget, ok := t.budgets[k.method+" "+k.route]; ok {
+		return &budget, true
+	}
+	if budget, ok := t.budgets[k.route]; ok {
+		return &budget, true
+	}
+	return nil, false
+}
+
+// epoch is the slot of time that now falls in
+func (t *Tracker) epoch(now time.Time) int64 {
+	return now.UnixNano() / int64(t.Window/slots)
+}
+
+func (t *Tracker) window(k key) *window {
+	t.mu.RLock()
+	w, ok := t.routes[k]
+	t.mu.RUnlock()
+	if ok {
+		return w
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if w, ok := t.routes[k]; ok {
+		return w
+	}
+	w = new(window)
+	t.routes[k] = w
+	return w
+}
+
+// Middleware records the latency and status of requests that match a route
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := t.Clock.Now()
+		ctx, route := router.Track(r.Context())
+		sw := middleware.RecordStatus(w)
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		// Leave out requests that didn't match a route, like 404s
+		path := route()
+		if path == "" {
+			return
+		}
+		now := t.Clock.Now()
+		t.window(key{r.Method, path}).record(t.epoch(now), now.Sub(start), sw.Status() >= 500)
+	})
+}
+
+// Stats of the routes with requests in the window, sorted by route and method
+func (t *Tracker) Stats() []*Stats {
+	epoch := t.epoch(t.Clock.Now())
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	var list []*Stats
+	for k, w := range t.routes {
+		stats := &Stats{Method: k.method, Route: k.route}
+		w.summarize(epoch, stats)
+		if stats.Requests == 0 {
+			continue
+		}
+		if budget, ok := t.budget(k); ok {
+			stats.Budget = budget
+			stats.Over = budget.exceeded(stats)
+		}
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Route != list[j].Route {
+			return list[i].Route < list[j].Route
+		}
+		return list[i].Method < list[j].Method
+	})
+	return list
+}
+
+// Check the routes against their budgets. Routes that went over their budget
+// or are back within it since the last check are logged and alerted.
+func (t *Tracker) Check(ctx context.Context) {
+	stats := t.Stats()
+	t.mu.RLock()
+	log, alerts := t.log, t.alerts
+	routes := make(map[key]*window, len(t.routes))
+	for k, w := range t.routes {
+		routes[k] = w
+	}
+	t.mu.RUnlock()
+	seen := map[key]bool{}
+	for _, s := range stats {
+		k := key{s.Method, s.Route}
+		seen[k] = true
+		if s.Budget != nil {
+			t.transition(ctx, log, alerts, routes[k], s)
+		}
+	}
+	// Routes without requests in the window are within budget
+	for k, w := range routes {
+		if !seen[k] {
+			t.transition(ctx, log, alerts, w, &Stats{Method: k.method, Route: k.route})
+		}
+	}
+}
+
+// transition alerts when the route goes over budget or comes back within it
+func (t *Tracker) transition(ctx context.Context, log log.Interface, alerts []AlertFunc, w *window, stats *Stats) {
+	over := len(stats.Over) > 0
+	w.mu.Lock()
+	changed := w.over != over
+	w.over = over
+	w.mu.Unlock()
+	if !changed {
+		return
+	}
+	if over {
+		log.Warn("routestats: route over budget",
+			"method", stats.Method,
+			"route", stats.Route,
+			"over", strings.Join(stats.Over, ", "),
+			"requests", stats.Requests,
+		)
+	} else {
+		log.Info("routestats: route back within budget", "method", stats.Method, "route", stats.Route)
+	}
+	alert := &Alert{Stats: stats, Resolved: !over}
+	for _, fn := range alerts {
+		fn(ctx, alert)
+	}
+}
+
+// Start checking the budgets in the background
+func (t *Tracker) Start(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop != nil {
+		return nil
+	}
+	t.stop, t.done = make(chan struct{}), make(chan struct{})
+	go t.run(t.stop, t.done)
+	return nil
+}
+
+func (t *Tracker) run(stop, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.Interval)
+		t.Check(ctx)
+		cancel()
+	}
+}
+
+// Stop checking the budgets
+func (t *Tracker) Stop(ctx context.Context) error {
+	t.mu.Lock()
+	stop, done := t.stop, t.done
+	t.stop, t.done = nil, nil
+	t.mu.Unlock()
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// collector serves a metric of the statistics at /metrics
+type collector struct {
+	desc    metrics.Desc
+	tracker *Tracker
+	samples func(stats *Stats, labels []metrics.Label) []metrics.Sample
+}
+
+func (c *collector) Describe() metrics.Desc {
+	return c.desc
+}
+
+func (c *collector) Collect(ctx context.Context) (samples []metrics.Sample) {
+	for _, stats := range c.tracker.Stats() {
+		labels := []metrics.Label{{Name: "method", Value: stats.Method}, {Name: "route", Value: stats.Route}}
+		samples = append(samples, c.samples(stats, labels)...)
+	}
+	return samples
+}
+
+// collectors of the tracker's metrics
+func (t *Tracker) collectors() []metrics.Collector {
+	gauge := func(name, help string) metrics.Desc {
+		return metrics.Desc{Name: name, Help: help, Type: metrics.GaugeType}
+	}
+	return []metrics.Collector{
+		&collector{gauge("http_route_latency_seconds", "Latency percentiles by route over the last few minutes."), t, func(stats *Stats, labels []metrics.Label) []metrics.Sample {
+			quantile := func(q string, latency time.Duration) metrics.Sample {
+				return metrics.Sample{
+					Name:   "http_route_latency_seconds",
+					Labels: append(labels[:len(labels):len(labels)], metrics.Label{Name: "quantile", Value: q}),
+					Value:  latency.Seconds(),
+				}
+			}
+			return []metrics.Sample{quantile("0.5", stats.P50), quantile("0.95", stats.P95), quantile("0.99", stats.P99)}
+		}},
+		&collector{gauge("http_route_error_ratio", "Share of requests that failed by route over the last few minutes."), t, func(stats *Stats, labels []metrics.Label) []metrics.Sample {
+			return []metrics.Sample{{Name: "http_route_error_ratio", Labels: labels, Value: stats.ErrorRatio()}}
+		}},
+		&collector{gauge("http_route_over_budget", "Whether the route is over its budget."), t, func(stats *Stats, labels []metrics.Label) []metrics.Sample {
+			if stats.Budget == nil {
+				return nil
+			}
+			value := 0.0
+			if len(stats.Over) > 0 {
+				value = 1
+			}
+			return []metrics.Sample{{Name: "http_route_over_budget", Labels: labels, Value: value}}
+		}},
+	}
+}
@@ -0,0 +1,157 @@
+package routestats_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/metrics"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/routestats"
+)
+
+// serve requests that take latency to respond with status
+func serve(tracker *routestats.Tracker, frozen *clock.Frozen) func(method, path string, latency time.Duration, status int) {
+	rt := router.New()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ms, _ := strconv.Atoi(r.URL.Query().Get("ms"))
+		status, _ := strconv.Atoi(r.URL.Query().Get("status"))
+		frozen.Advance(time.Duration(ms) * time.Millisecond)
+		w.WriteHeader(status)
+	})
+	rt.Get("/posts/:id", handler)
+	rt.Post("/posts", handler)
+	h := tracker.Middleware(rt.Middleware(http.NotFoundHandler()))
+	return func(method, path string, latency time.Duration, status int) {
+		url := path + "?ms=" + strconv.Itoa(int(latency/time.Millisecond)) + "&status=" + strconv.Itoa(status)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, url, nil))
+	}
+}
+
+func newTracker() (*routestats.Tracker, *clock.Frozen) {
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	tracker := routestats.New()
+	tracker.Clock = frozen
+	return tracker, frozen
+}
+
+func TestStats(t *testing.T) {
+	is := is.New(t)
+	tracker, frozen := newTracker()
+	request := serve(tracker, frozen)
+	for i := 1; i <= 100; i++ {
+		status := http.StatusOK
+		if i%25 == 0 {
+			status = http.StatusInternalServerError
+		}
+		request(http.MethodGet, "/posts/10", time.Duration(i)*time.Millisecond, status)
+	}
+	request(http.MethodPost, "/posts", 5*time.Millisecond, http.StatusCreated)
+	// Requests that don't match a route are left out
+	request(http.MethodGet, "/missing", time.Second, http.StatusOK)
+	stats := tracker.Stats()
+	is.Equal(len(stats), 2)
+	is.Equal(stats[0].Method, http.MethodPost)
+	is.Equal(stats[0].Route, "/posts")
+	is.Equal(stats[0].Requests, 1)
+	show := stats[1]
+	is.Equal(show.Method, http.MethodGet)
+	is.Equal(show.Route, "/posts/:id")
+	is.Equal(show.Requests, 100)
+	is.Equal(show.Errors, 4)
+	is.Equal(show.ErrorRatio(), 0.04)
+	// Percentiles are within 15% of the real latency
+	within := func(got, want time.Duration) bool {
+		return got >= want && got <= want*115/100
+	}
+	is.True(within(show.P50, 50*time.Millisecond))
+	is.True(within(show.P95, 95*time.Millisecond))
+	is.True(within(show.P99, 99*time.Millisecond))
+}
+
+func TestWindow(t *testing.T) {
+	is := is.New(t)
+	tracker, frozen := newTracker()
+	request := serve(tracker, frozen)
+	request(http.MethodGet, "/posts/1", time.Millisecond, http.StatusOK)
+	frozen.Advance(4 * time.Minute)
+	request(http.MethodGet, "/posts/2", time.Millisecond, http.StatusOK)
+	is.Equal(tracker.Stats()[0].Requests, 2)
+	// The first request rolls out of the window
+	frozen.Advance(90 * time.Second)
+	is.Equal(tracker.Stats()[0].Requests, 1)
+	frozen.Advance(5 * time.Minute)
+	is.Equal(len(tracker.Stats()), 0)
+}
+
+func TestBudget(t *testing.T) {
+	is := is.New(t)
+	tracker, frozen := newTracker()
+	tracker.SetBudget("GET /posts/:id", routestats.Budget{P95: 100 * time.Millisecond, ErrorRatio: 0.01, MinRequests: 10})
+	tracker.SetBudget("/posts", routestats.Budget{P50: time.Second})
+	var alerts []*routestats.Alert
+	tracker.OnAlert(func(ctx context.Context, alert *routestats.Alert) {
+		alerts = append(alerts, alert)
+	})
+	request := serve(tracker, frozen)
+	// Not enough requests to check yet
+	for i := 0; i < 9; i++ {
+		request(http.MethodGet, "/posts/1", 300*time.Millisecond, http.StatusInternalServerError)
+	}
+	tracker.Check(context.Background())
+	is.Equal(len(alerts), 0)
+	request(http.MethodGet, "/posts/1", 300*time.Millisecond, http.StatusInternalServerError)
+	request(http.MethodPost, "/posts", time.Millisecond, http.StatusOK)
+	tracker.Check(context.Background())
+	is.Equal(len(alerts), 1)
+	is.True(!alerts[0].Resolved)
+	is.Equal(alerts[0].Stats.Route, "/posts/:id")
+	is.True(alerts[0].Stats.Budget != nil)
+	is.Equal(len(alerts[0].Stats.Over), 2)
+	is.True(strings.HasPrefix(alerts[0].Stats.Over[0], "p95 "))
+	is.True(strings.HasSuffix(alerts[0].Stats.Over[0], " > 100ms"))
+	is.Equal(alerts[0].Stats.Over[1], "errors 100% > 1%")
+	// Alerts only fire when the route goes over or comes back
+	tracker.Check(context.Background())
+	is.Equal(len(alerts), 1)
+	frozen.Advance(10 * time.Minute)
+	tracker.Check(context.Background())
+	is.Equal(len(alerts), 2)
+	is.True(alerts[1].Resolved)
+	is.Equal(alerts[1].Stats.Route, "/posts/:id")
+}
+
+func TestStartStop(t *testing.T) {
+	is := is.New(t)
+	tracker := routestats.New()
+	tracker.Interval = time.Millisecond
+	is.NoErr(tracker.Start(context.Background()))
+	is.NoErr(tracker.Start(context.Background()))
+	time.Sleep(5 * time.Millisecond)
+	is.NoErr(tracker.Stop(context.Background()))
+	is.NoErr(tracker.Stop(context.Background()))
+}
+
+func TestMetrics(t *testing.T) {
+	is := is.New(t)
+	routestats.SetBudget("/posts/:id", routestats.Budget{P99: time.Minute})
+	tracker := routestats.Load(log.Discard)
+	is.Equal(tracker, routestats.Default)
+	frozen := clock.Freeze(time.Now())
+	tracker.Clock = frozen
+	request := serve(tracker, frozen)
+	request(http.MethodGet, "/posts/1", 20*time.Millisecond, http.StatusOK)
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	is.True(strings.Contains(body, `http_route_latency_seconds{method="GET",route="/posts/:id",quantile="0.99"} 0.02`))
+	is.True(strings.Contains(body, `http_route_error_ratio{method="GET",route="/posts/:id"} 0`+"\n"))
+	is.True(strings.Contains(body, `http_route_over_budget{method="GET",route="/posts/:id"} 0`+"\n"))
+}
@@ -0,0 +1,98 @@
+package routestats
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// bounds are the upper bounds of the latency bins. They grow by 15% from
+// 0.5ms to a minute, so percentiles are within 15% of the real latency.
+var bounds = func() (bounds []time.Duration) {
+	for d := 500 * time.Microsecond; d < time.Minute; d = d * 115 / 100 {
+		bounds = append(bounds, d)
+	}
+	return append(bounds, time.Minute)
+}()
+
+// slots in a window. The window rolls forward one slot at a time, dropping the
+// oldest slot.
+const slots = 10
+
+// slot counts the requests in one slot of time
+type slot struct {
+	epoch    int64 // Which slot of time since the unix epoch
+	requests uint64
+	errors   uint64
+	bins     []uint64 // The last bin counts requests that took over a minute
+}
+
+// window of requests to a route
+type window struct {
+	mu    sync.Mutex
+	slots [slots]slot
+	over  bool // Over budget as of the last check
+}
+
+func (w *window) record(epoch int64, latency time.Duration, failed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := &w.slots[epoch%slots]
+	if s.bins == nil {
+		s.bins = make([]uint64, len(bounds)+1)
+	}
+	// Reuse the slot once its time has passed
+	if s.epoch != epoch {
+		s.epoch, s.requests, s.errors = epoch, 0, 0
+		for i := range s.bins {
+			s.bins[i] = 0
+		}
+	}
+	s.requests++
+	if failed {
+		s.errors++
+	}
+	s.bins[sort.Search(len(bounds), func(i int) bool { return bounds[i] >= latency })]++
+}
+
+// summarize the slots in the window that ends with epoch
+func (w *window) summarize(epoch int64, stats *Stats) {
+	bins := make([]uint64, len(bounds)+1)
+	var requests, errors uint64
+	w.mu.Lock()
+	for i := range w.slots {
+		s := &w.slots[i]
+		if s.bins == nil || s.epoch <= epoch-slots || s.epoch > epoch {
+			continue
+		}
+		requests += s.requests
+		errors += s.errors
+		for j, n := range s.bins {
+			bins[j] += n
+		}
+	}
+	w.mu.Unlock()
+	stats.Requests = int(requests)
+	stats.Errors = int(errors)
+	stats.P50 = percentile(bins, requests, 0.50)
+	stats.P95 = percentile(bins, requests, 0.95)
+	stats.P99 = percentile(bins, requests, 0.99)
+}
+
+// percentile returns the upper bound of the bin with the qth request
+func percentile(bins []uint64, total uint64, q float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var seen uint64
+	for i, n := range bins {
+		seen += n
+		if seen >= rank && i < len(bounds) {
+			return bounds[i]
+		}
+	}
+	// Over a minute
+	return bounds[len(bounds)-1]
+}
@@ -0,0 +1,162 @@
+package secretenv
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoadAWS loads the AWS Secrets Manager provider from the environment:
+//
+//	AWS_REGION=us-east-1
+//	AWS_ACCESS_KEY_ID=...
+//	AWS_SECRET_ACCESS_KEY=...
+//
+// AWS_SESSION_TOKEN is sent too when it's set.
+func LoadAWS(getenv func(key string) string) *AWS {
+	return &AWS{
+		Region:       getenv("AWS_REGION"),
+		AccessKeyID:  getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// AWS reads secrets from AWS Secrets Manager by their name or ARN, like
+// aws:prod/stripe#secret_key
+type AWS struct {
+	Region       string
+	AccessKeyID  string
+	SecretKey    string
+	SessionToken string
+	Endpoint     string // Defaults to https://secretsmanager.{region}.amazonaws.com
+	Client       *http.Client
+	Now          func() time.Time
+}
+
+var _ Provider = (*AWS)(nil)
+
+// Fetch the current version of the secret
+func (a *AWS) Fetch(ctx context.Context, path string) (*Secret, error) {
+	if a.Region == "" || a.AccessKeyID == "" || a.SecretKey == "" {
+		return nil, fmt.Errorf("aws: missing the AWS_REGION, AWS_ACCESS_KEY_ID or AWS_SECRET_ACCESS_KEY environment variable")
+	}
+	body, err := json.Marshal(map[string]string{"SecretId": path})
+	if err != nil {
+		return nil, err
+	}
+	endpoint := a.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + a.Region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	now := time.Now
+	if a.Now != nil {
+		now = a.Now
+	}
+	a.sign(req, body, now().UTC())
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("aws: unable to get %s. %w", path, err)
+	}
+	defer res.Body.Close()
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("aws: unable to get %s. %w", path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &failure)
+		return nil, fmt.Errorf("aws: unable to get %s. %d %s %s", path, res.StatusCode, failure.Type, failure.Message)
+	}
+	var secret struct {
+		SecretString string
+		SecretBinary []byte // Encoded as base64
+	}
+	if err := json.Unmarshal(data, &secret); err != nil {
+		return nil, fmt.Errorf("aws: unable to parse %s. %w", path, err)
+	}
+	if secret.SecretString == "" && secret.SecretBinary != nil {
+		return &Secret{Value: base64.StdEncoding.EncodeToString(secret.SecretBinary)}, nil
+	}
+	return &Secret{Value: secret.SecretString}, nil
+}
+
+// sign the request with AWS Signature Version 4
+func (a *AWS) sign(req *http.Request, body []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.SessionToken)
+	}
+	// The headers are in sorted order
+	signed := []string{"content-type", "host", "x-amz-date"}
+	if a.SessionToken != "" {
+		signed = append(signed, "x-amz-security-token")
+	}
+	signed = append(signed, "x-amz-target")
+	canonicalHeaders := new(strings.Builder)
+	for _, name := range signed {
+		value := req.Header.Get(name)
+		if name == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hashHex(body),
+	}, "\n")
+	scope := date + "/" + a.Region + "/secretsmanager/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hashHex([]byte(canonicalRequest)),
+	}, "\n")
+	key := hmacSHA256([]byte("AWS4"+a.SecretKey), date)
+	key = hmacSHA256(key, a.Region)
+	key = hmacSHA256(key, "secretsmanager")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		a.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
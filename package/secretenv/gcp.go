@@ -0,0 +1,146 @@
+package secretenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadGCP loads the Google Cloud Secret Manager provider from the environment:
+//
+//	GOOGLE_CLOUD_PROJECT=my-project
+//	GOOGLE_OAUTH_ACCESS_TOKEN=ya29...
+//
+// Without an access token, tokens come from the metadata server of the
+// instance the app runs on, like on Cloud Run or Compute Engine.
+func LoadGCP(getenv func(key string) string) *GCP {
+	return &GCP{
+		Project: getenv("GOOGLE_CLOUD_PROJECT"),
+		Token:   getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+}
+
+// GCP reads secrets from Google Cloud Secret Manager. Paths are a secret in
+// the project, like gcp:sentry-dsn, or a full resource name, like
+// gcp:projects/app/secrets/sentry-dsn/versions/3. The latest version is read
+// unless the path has a version.
+type GCP struct {
+	Project     string
+	Token       string // Access token. Defaults to tokens from the metadata server.
+	Endpoint    string // Defaults to https://secretmanager.googleapis.com
+	MetadataURL string // Defaults to http://metadata.google.internal
+	Client      *http.Client
+	Now         func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+var _ Provider = (*GCP)(nil)
+
+// Fetch a version of the secret
+func (g *GCP) Fetch(ctx context.Context, path string) (*Secret, error) {
+	name := path
+	if !strings.HasPrefix(name, "projects/") {
+		if g.Project == "" {
+			return nil, fmt.Errorf("gcp: missing the GOOGLE_CLOUD_PROJECT environment variable for %s", path)
+		}
+		name = "projects/" + g.Project + "/secrets/" + name
+	}
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := g.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"/v1/"+name+":access", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	var res struct {
+		Payload struct {
+			Data []byte `json:"data"` // Encoded as base64
+		} `json:"payload"`
+	}
+	if err := g.do(req, name, &res); err != nil {
+		return nil, err
+	}
+	return &Secret{Value: string(res.Payload.Data)}, nil
+}
+
+// accessToken returns the configured token or a token from the metadata
+// server, which is cached until shortly before it expires
+func (g *GCP) accessToken(ctx context.Context) (string, error) {
+	if g.Token != "" {
+		return g.Token, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := time.Now
+	if g.Now != nil {
+		now = g.Now
+	}
+	if g.token != "" && now().Before(g.expires) {
+		return g.token, nil
+	}
+	metadataURL := g.MetadataURL
+	if metadataURL == "" {
+		metadataURL = "http://metadata.google.internal"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL+"/computeMetadata/v1/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	var res struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := g.do(req, "an access token from the metadata server", &res); err != nil {
+		return "", fmt.Errorf("%w. Set GOOGLE_OAUTH_ACCESS_TOKEN when running outside of Google Cloud", err)
+	}
+	g.token = res.AccessToken
+	g.expires = now().Add(time.Duration(res.ExpiresIn)*time.Second - time.Minute)
+	return g.token, nil
+}
+
+func (g *GCP) do(req *http.Request, name string, result interface{}) error {
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gcp: unable to get %s. %w", name, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("gcp: unable to get %s. %w", name, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &failure)
+		return fmt.Errorf("gcp: unable to get %s. %d %s", name, res.StatusCode, failure.Error.Message)
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("gcp: unable to parse %s. %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Package secretenv resolves references to secrets in the environment, so real
+// secrets stay out of .env files:
+//
+//	DATABASE_PASSWORD=vault:kv/app#db_password
+//	STRIPE_SECRET_KEY=aws:prod/stripe#secret_key
+//	SENTRY_DSN=gcp:sentry-dsn
+//
+// References are a provider, a path and an optional field, like
+// <provider>:<path>#<field>. Fields are read from secrets that hold a JSON
+// object. The built-in providers are configured with environment variables:
+//
+//	vault: VAULT_ADDR, VAULT_TOKEN and VAULT_NAMESPACE
+//	aws:   AWS_REGION, AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+//	gcp:   GOOGLE_CLOUD_PROJECT and GOOGLE_OAUTH_ACCESS_TOKEN, falling back to
+//	       the metadata server when running on Google Cloud
+//
+// Secrets are cached until their lease runs out or for $SECRETS_REFRESH when
+// they don't have a lease, 1h by default. They're renewed before they expire.
+package secretenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/reload"
+)
+
+// Provider fetches secrets from a secret manager
+type Provider interface {
+	// Fetch the secret at path
+	Fetch(ctx context.Context, path string) (*Secret, error)
+}
+
+// Secret fetched from a provider
+type Secret struct {
+	// Value of the secret. Fields are read from values that are JSON objects.
+	Value string
+	// TTL is how long the secret is valid for. Zero uses the resolver's refresh
+	// interval.
+	TTL time.Duration
+}
+
+// Load the resolver with the providers configured in the environment
+func Load() (*Resolver, error) {
+	return LoadEnv(os.Getenv)
+}
+
+// LoadEnv loads the resolver with getenv. Providers are checked when they're
+// first used, so apps without references don't need to configure them.
+func LoadEnv(getenv func(key string) string) (*Resolver, error) {
+	resolver := New(map[string]Provider{
+		"vault": LoadVault(getenv),
+		"aws":   LoadAWS(getenv),
+		"gcp":   LoadGCP(getenv),
+	})
+	if refresh := getenv("SECRETS_REFRESH"); refresh != "" {
+		duration, err := time.ParseDuration(refresh)
+		if err != nil {
+			return nil, fmt.Errorf("secretenv: invalid SECRETS_REFRESH %q. %w", refresh, err)
+		}
+		resolver.Refresh = duration
+	}
+	return resolver, nil
+}
+
+// New resolver for the providers, keyed by their name in references
+func New(providers map[string]Provider) *Resolver {
+	return &Resolver{
+		Refresh:   time.Hour,
+		Now:       time.Now,
+		providers: providers,
+		cache:     map[string]*cached{},
+		refs:      map[string]*ref{},
+	}
+}
+
+// Resolver resolves references to secrets
+type Resolver struct {
+	Refresh time.Duration // How long secrets without a lease are cached
+	Now     func() time.Time
+
+	providers map[string]Provider
+	mu        sync.Mutex
+	cache     map[string]*cached // Secrets by provider and path
+	refs      map[string]*ref    // References in the environment by key
+}
+
+type cached struct {
+	secret  *Secret
+	renewAt time.Time // When to fetch the secret again
+	expires time.Time // When the secret stops being valid
+}
+
+type ref struct {
+	reference string
+	value     string // Resolved value
+}
+
+// retryDelay is how long to wait before fetching a secret again when renewing
+// it fails
+const retryDelay = 30 * time.Second
+
+// IsReference is true if the value refers to a secret, like
+// vault:kv/app#db_password
+func (r *Resolver) IsReference(value string) bool {
+	name, path, ok := strings.Cut(value, ":")
+	if !ok || path == "" {
+		return false
+	}
+	_, ok = r.providers[name]
+	return ok
+}
+
+// Resolve the reference into the secret's value
+func (r *Resolver) Resolve(ctx context.Context, reference string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resolve(ctx, reference)
+}
+
+func (r *Resolver) resolve(ctx context.Context, reference string) (string, error) {
+	name, rest, _ := strings.Cut(reference, ":")
+	provider, ok := r.providers[name]
+	if !ok {
+		return "", fmt.Errorf("secretenv: unknown provider in %q", reference)
+	}
+	path, field, hasField := strings.Cut(rest, "#")
+	secret, err := r.fetch(ctx, provider, name+":"+path)
+	if err != nil {
+		return "", err
+	}
+	if !hasField {
+		return secret.Value, nil
+	}
+	return readField(secret.Value, field, reference)
+}
+
+// fetch the secret, using the cache until the secret is due to be renewed. If
+// renewing fails, the cached secret is used until it expires.
+func (r *Resolver) fetch(ctx context.Context, provider Provider, key string) (*Secret, error) {
+	now := r.Now()
+	entry, ok := r.cache[key]
+	if ok && now.Before(entry.renewAt) && now.Before(entry.expires) {
+		return entry.secret, nil
+	}
+	_, path, _ := strings.Cut(key, ":")
+	secret, err := provider.Fetch(ctx, path)
+	if err != nil {
+		if ok {
+			entry.renewAt = now.Add(retryDelay)
+			if now.Before(entry.expires) {
+				return entry.secret, nil
+			}
+		}
+		return nil, fmt.Errorf("secretenv: unable to fetch %q. %w", key, err)
+	}
+	ttl := secret.TTL
+	if ttl <= 0 {
+		ttl = r.Refresh
+	}
+	// Renew a third of the way before the secret expires
+	r.cache[key] = &cached{
+		secret:  secret,
+		renewAt: now.Add(ttl * 2 / 3),
+		expires: now.Add(ttl),
+	}
+	return secret, nil
+}
+
+// readField reads a field from a secret that's a JSON object
+func readField(value, field, reference string) (string, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secretenv: unable to read the field in %q. The secret isn't a JSON object", reference)
+	}
+	v, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("secretenv: no field %q in %q", field, reference)
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// Setenv replaces the references in the process's environment with their
+// secrets. Calling Setenv again renews the secrets that are due and resolves
+// the references that were set since.
+func (r *Resolver) Setenv(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		reference := value
+		if !r.IsReference(value) {
+			// Variables that still hold the secret we set are renewed
+			ref, ok := r.refs[key]
+			if !ok || ref.value != value {
+				delete(r.refs, key)
+				continue
+			}
+			reference = ref.reference
+		}
+		secret, err := r.resolve(ctx, reference)
+		if err != nil {
+			return fmt.Errorf("secretenv: unable to resolve %s. %w", key, err)
+		}
+		if err := os.Setenv(key, secret); err != nil {
+			return fmt.Errorf("secretenv: unable to set %s. %w", key, err)
+		}
+		r.refs[key] = &ref{reference, secret}
+	}
+	return nil
+}
+
+// renewAt returns when the next secret in the environment is due to be
+// renewed. It's zero when there's nothing to renew.
+func (r *Resolver) renewAt() (renewAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ref := range r.refs {
+		name, rest, _ := strings.Cut(ref.reference, ":")
+		path, _, _ := strings.Cut(rest, "#")
+		entry, ok := r.cache[name+":"+path]
+		if !ok {
+			continue
+		}
+		if renewAt.IsZero() || entry.renewAt.Before(renewAt) {
+			renewAt = entry.renewAt
+		}
+	}
+	return renewAt
+}
+
+// Renew the secrets in the environment before they expire by reloading, so
+// the reloader's subscribers pick up the new secrets. Renew runs until the
+// context is canceled.
+func (r *Resolver) Renew(ctx context.Context, reloader *reload.Reloader, log log.Interface) {
+	for {
+		// Check at least once a minute, since reloads can add references
+		wait := time.Minute
+		if renewAt := r.renewAt(); !renewAt.IsZero() {
+			if until := renewAt.Sub(r.Now()); until < wait {
+				wait = until
+			}
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if renewAt := r.renewAt(); renewAt.IsZero() || r.Now().Before(renewAt) {
+			continue
+		}
+		if err := reloader.Reload(ctx); err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		log.Debug("secretenv: renewed the secrets")
+	}
+}
@@ -0,0 +1,255 @@
+package secretenv_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/secretenv"
+)
+
+// provider that counts its fetches
+type provider struct {
+	secrets map[string]*secretenv.Secret
+	fetches int
+	err     error
+}
+
+func (p *provider) Fetch(ctx context.Context, path string) (*secretenv.Secret, error) {
+	p.fetches++
+	if p.err != nil {
+		return nil, p.err
+	}
+	secret, ok := p.secrets[path]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return secret, nil
+}
+
+func TestResolve(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	fake := &provider{secrets: map[string]*secretenv.Secret{
+		"app":   {Value: `{"user":"admin","password":"hunter2","port":5432}`},
+		"token": {Value: "abc"},
+	}}
+	resolver := secretenv.New(map[string]secretenv.Provider{"fake": fake})
+	is.True(resolver.IsReference("fake:app#user"))
+	is.True(!resolver.IsReference("postgres://localhost:5432/app"))
+	is.True(!resolver.IsReference("fake:"))
+	value, err := resolver.Resolve(ctx, "fake:app#user")
+	is.NoErr(err)
+	is.Equal(value, "admin")
+	value, err = resolver.Resolve(ctx, "fake:app#port")
+	is.NoErr(err)
+	is.Equal(value, "5432")
+	value, err = resolver.Resolve(ctx, "fake:token")
+	is.NoErr(err)
+	is.Equal(value, "abc")
+	// Secrets are cached
+	is.Equal(fake.fetches, 2)
+	_, err = resolver.Resolve(ctx, "fake:app#missing")
+	is.Equal(err.Error(), `secretenv: no field "missing" in "fake:app#missing"`)
+	_, err = resolver.Resolve(ctx, "fake:token#field")
+	is.Equal(err.Error(), `secretenv: unable to read the field in "fake:token#field". The secret isn't a JSON object`)
+	_, err = resolver.Resolve(ctx, "fake:missing")
+	is.Equal(err.Error(), `secretenv: unable to fetch "fake:missing". not found`)
+}
+
+func TestRenew(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := &provider{secrets: map[string]*secretenv.Secret{
+		"creds": {Value: "v1", TTL: 30 * time.Minute},
+	}}
+	resolver := secretenv.New(map[string]secretenv.Provider{"fake": fake})
+	resolver.Now = func() time.Time { return now }
+	value, err := resolver.Resolve(ctx, "fake:creds")
+	is.NoErr(err)
+	is.Equal(value, "v1")
+	// Renewed a third of the way before the lease runs out
+	now = now.Add(19 * time.Minute)
+	fake.secrets["creds"] = &secretenv.Secret{Value: "v2", TTL: 30 * time.Minute}
+	value, err = resolver.Resolve(ctx, "fake:creds")
+	is.NoErr(err)
+	is.Equal(value, "v1")
+	now = now.Add(2 * time.Minute)
+	value, err = resolver.Resolve(ctx, "fake:creds")
+	is.NoErr(err)
+	is.Equal(value, "v2")
+	// Failed renewals keep the secret until it expires
+	fake.err = errors.New("sealed")
+	now = now.Add(25 * time.Minute)
+	value, err = resolver.Resolve(ctx, "fake:creds")
+	is.NoErr(err)
+	is.Equal(value, "v2")
+	now = now.Add(10 * time.Minute)
+	_, err = resolver.Resolve(ctx, "fake:creds")
+	is.Equal(err.Error(), `secretenv: unable to fetch "fake:creds". sealed`)
+}
+
+func TestSetenv(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := &provider{secrets: map[string]*secretenv.Secret{
+		"db": {Value: `{"password":"v1"}`},
+	}}
+	resolver := secretenv.New(map[string]secretenv.Provider{"fake": fake})
+	resolver.Now = func() time.Time { return now }
+	t.Setenv("SECRETENV_TEST_PASSWORD", "fake:db#password")
+	t.Setenv("SECRETENV_TEST_PLAIN", "plain")
+	is.NoErr(resolver.Setenv(ctx))
+	is.Equal(os.Getenv("SECRETENV_TEST_PASSWORD"), "v1")
+	is.Equal(os.Getenv("SECRETENV_TEST_PLAIN"), "plain")
+	// Resolved variables are renewed once their secret is due
+	fake.secrets["db"] = &secretenv.Secret{Value: `{"password":"v2"}`}
+	now = now.Add(time.Hour)
+	is.NoErr(resolver.Setenv(ctx))
+	is.Equal(os.Getenv("SECRETENV_TEST_PASSWORD"), "v2")
+	// Variables that were changed since are left alone
+	os.Setenv("SECRETENV_TEST_PASSWORD", "changed")
+	now = now.Add(time.Hour)
+	is.NoErr(resolver.Setenv(ctx))
+	is.Equal(os.Getenv("SECRETENV_TEST_PASSWORD"), "changed")
+	// Errors name the variable
+	t.Setenv("SECRETENV_TEST_MISSING", "fake:missing")
+	err := resolver.Setenv(ctx)
+	is.Equal(err.Error(), `secretenv: unable to resolve SECRETENV_TEST_MISSING. secretenv: unable to fetch "fake:missing". not found`)
+}
+
+func TestVault(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/sys/internal/ui/mounts/kv/app":
+			w.Write([]byte(`{"data":{"path":"kv/","type":"kv","options":{"version":"2"}}}`))
+		case "/v1/kv/data/app":
+			w.Write([]byte(`{"data":{"data":{"db_password":"hunter2"},"metadata":{"version":3}},"lease_duration":0}`))
+		case "/v1/sys/internal/ui/mounts/database/creds/app":
+			w.Write([]byte(`{"data":{"path":"database/","type":"database","options":null}}`))
+		case "/v1/database/creds/app":
+			w.Write([]byte(`{"data":{"username":"v-app","password":"p"},"lease_duration":3600}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+		}
+	}))
+	defer server.Close()
+	env := map[string]string{"VAULT_ADDR": server.URL + "/", "VAULT_TOKEN": "token"}
+	vault := secretenv.LoadVault(func(key string) string { return env[key] })
+	secret, err := vault.Fetch(ctx, "kv/app")
+	is.NoErr(err)
+	is.Equal(secret.Value, `{"db_password":"hunter2"}`)
+	is.Equal(secret.TTL, time.Duration(0))
+	// Dynamic secrets have a lease
+	secret, err = vault.Fetch(ctx, "database/creds/app")
+	is.NoErr(err)
+	is.Equal(secret.Value, `{"username":"v-app","password":"p"}`)
+	is.Equal(secret.TTL, time.Hour)
+	_, err = vault.Fetch(ctx, "kv/missing")
+	is.Equal(err.Error(), "vault: unable to read kv/missing. 404 Not Found")
+	vault.Token = "wrong"
+	_, err = vault.Fetch(ctx, "kv/app")
+	is.Equal(err.Error(), "vault: unable to read kv/app. 403 permission denied")
+	vault.Token = ""
+	_, err = vault.Fetch(ctx, "kv/app")
+	is.Equal(err.Error(), "vault: missing the VAULT_ADDR or VAULT_TOKEN environment variable")
+}
+
+func TestAWS(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.Method, http.MethodPost)
+		is.Equal(r.Header.Get("X-Amz-Target"), "secretsmanager.GetSecretValue")
+		is.Equal(r.Header.Get("Content-Type"), "application/x-amz-json-1.1")
+		is.Equal(r.Header.Get("X-Amz-Date"), "20260101T000000Z")
+		is.True(strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/20260101/us-east-1/secretsmanager/aws4_request, SignedHeaders=content-type;host;x-amz-date;x-amz-target, Signature="))
+		body, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		var input struct{ SecretId string }
+		is.NoErr(json.Unmarshal(body, &input))
+		if input.SecretId != "prod/stripe" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","message":"Secrets Manager can't find the specified secret."}`))
+			return
+		}
+		w.Write([]byte(`{"Name":"prod/stripe","SecretString":"{\"secret_key\":\"sk_live_123\"}"}`))
+	}))
+	defer server.Close()
+	env := map[string]string{"AWS_REGION": "us-east-1", "AWS_ACCESS_KEY_ID": "key", "AWS_SECRET_ACCESS_KEY": "secret"}
+	aws := secretenv.LoadAWS(func(key string) string { return env[key] })
+	aws.Endpoint = server.URL
+	aws.Now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+	secret, err := aws.Fetch(ctx, "prod/stripe")
+	is.NoErr(err)
+	is.Equal(secret.Value, `{"secret_key":"sk_live_123"}`)
+	_, err = aws.Fetch(ctx, "prod/missing")
+	is.Equal(err.Error(), "aws: unable to get prod/missing. 400 ResourceNotFoundException Secrets Manager can't find the specified secret.")
+}
+
+func TestGCP(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	tokens := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/computeMetadata/v1/instance/service-accounts/default/token":
+			is.Equal(r.Header.Get("Metadata-Flavor"), "Google")
+			tokens++
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3599,"token_type":"Bearer"}`))
+		case "/v1/projects/app/secrets/sentry-dsn/versions/latest:access":
+			is.Equal(r.Header.Get("Authorization"), "Bearer ya29.token")
+			w.Write([]byte(`{"name":"projects/1/secrets/sentry-dsn/versions/2","payload":{"data":"aHR0cHM6Ly9zZW50cnk="}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"code":404,"message":"Secret not found"}}`))
+		}
+	}))
+	defer server.Close()
+	env := map[string]string{"GOOGLE_CLOUD_PROJECT": "app"}
+	gcp := secretenv.LoadGCP(func(key string) string { return env[key] })
+	gcp.Endpoint = server.URL
+	gcp.MetadataURL = server.URL
+	secret, err := gcp.Fetch(ctx, "sentry-dsn")
+	is.NoErr(err)
+	is.Equal(secret.Value, "https://sentry")
+	secret, err = gcp.Fetch(ctx, "projects/app/secrets/sentry-dsn")
+	is.NoErr(err)
+	is.Equal(secret.Value, "https://sentry")
+	// The token is cached
+	is.Equal(tokens, 1)
+	_, err = gcp.Fetch(ctx, "missing")
+	is.Equal(err.Error(), "gcp: unable to get projects/app/secrets/missing/versions/latest. 404 Secret not found")
+}
+
+func TestLoadEnv(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{"SECRETS_REFRESH": "5m"}
+	resolver, err := secretenv.LoadEnv(func(key string) string { return env[key] })
+	is.NoErr(err)
+	is.Equal(resolver.Refresh, 5*time.Minute)
+	is.True(resolver.IsReference("vault:kv/app#db_password"))
+	is.True(resolver.IsReference("aws:prod/stripe"))
+	is.True(resolver.IsReference("gcp:sentry-dsn"))
+	env["SECRETS_REFRESH"] = "soon"
+	_, err = secretenv.LoadEnv(func(key string) string { return env[key] })
+	is.Equal(err.Error(), `secretenv: invalid SECRETS_REFRESH "soon". time: invalid duration "soon"`)
+}
@@ -0,0 +1,126 @@
+package secretenv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LoadVault loads the Vault provider from the environment:
+//
+//	VAULT_ADDR=https://vault.example.com:8200
+//	VAULT_TOKEN=hvs.123
+//
+// VAULT_NAMESPACE is sent too when it's set.
+func LoadVault(getenv func(key string) string) *Vault {
+	return &Vault{
+		Address:   strings.TrimSuffix(getenv("VAULT_ADDR"), "/"),
+		Token:     getenv("VAULT_TOKEN"),
+		Namespace: getenv("VAULT_NAMESPACE"),
+	}
+}
+
+// Vault reads secrets from HashiCorp Vault. Paths in key/value version 2
+// mounts are read without the data/ prefix, like vault:kv/app#db_password,
+// the same as "vault kv get". Other paths are read as-is, like dynamic
+// database credentials at vault:database/creds/app#password, which are
+// renewed before their lease runs out.
+type Vault struct {
+	Address   string
+	Token     string
+	Namespace string
+	Client    *http.Client
+}
+
+var _ Provider = (*Vault)(nil)
+
+// Fetch the secret at path. The secret's value is its data as a JSON object.
+func (v *Vault) Fetch(ctx context.Context, path string) (*Secret, error) {
+	if v.Address == "" || v.Token == "" {
+		return nil, fmt.Errorf("vault: missing the VAULT_ADDR or VAULT_TOKEN environment variable")
+	}
+	path = strings.Trim(path, "/")
+	mount, version := v.mount(ctx, path)
+	if version == "2" {
+		path = mount + "data/" + strings.TrimPrefix(path, mount)
+	}
+	var res struct {
+		Data          json.RawMessage `json:"data"`
+		LeaseDuration int             `json:"lease_duration"`
+	}
+	if err := v.get(ctx, path, &res); err != nil {
+		return nil, err
+	}
+	secret := &Secret{
+		Value: string(res.Data),
+		TTL:   time.Duration(res.LeaseDuration) * time.Second,
+	}
+	if version == "2" {
+		// Version 2 nests the data beside its metadata
+		var data struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(res.Data, &data); err != nil {
+			return nil, fmt.Errorf("vault: unable to parse %s. %w", path, err)
+		}
+		secret.Value = string(data.Data)
+	}
+	return secret, nil
+}
+
+// mount finds the mount of the path and its key/value version. Tokens that
+// can't look up mounts read paths as-is.
+func (v *Vault) mount(ctx context.Context, path string) (mount, version string) {
+	var res struct {
+		Data struct {
+			Path    string            `json:"path"`
+			Options map[string]string `json:"options"`
+		} `json:"data"`
+	}
+	if err := v.get(ctx, "sys/internal/ui/mounts/"+path, &res); err != nil {
+		return "", ""
+	}
+	return res.Data.Path, res.Data.Options["version"]
+}
+
+func (v *Vault) get(ctx context.Context, path string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.Address+"/v1/"+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+	client := v.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault: unable to read %s. %w", path, err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("vault: unable to read %s. %w", path, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Errors []string `json:"errors"`
+		}
+		json.Unmarshal(body, &failure)
+		if len(failure.Errors) == 0 {
+			failure.Errors = []string{http.StatusText(res.StatusCode)}
+		}
+		return fmt.Errorf("vault: unable to read %s. %d %s", path, res.StatusCode, strings.Join(failure.Errors, ". "))
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("vault: unable to parse %s. %w", path, err)
+	}
+	return nil
+}
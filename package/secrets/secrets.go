@@ -0,0 +1,263 @@
+// Package secrets encrypts cookies and other values with the app's master key.
+// The master key is loaded from the environment or a keyfile:
+//
+//	MASTER_KEY=change-me
+//	MASTER_PREVIOUS_KEYS=old-key,older-key
+//	MASTER_KEY_FILE=config/master.key
+//
+// Rotate the master key by moving the current key into MASTER_PREVIOUS_KEYS.
+// New values are encrypted with the current key, while the previous keys keep
+// decrypting values that were encrypted before the rotation.
+//
+// Each feature should use its own keys, derived from the master key:
+//
+//	keys, err := secrets.Load()
+//	sessionKeys := keys.Derive("session")
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultKeyFile is read when MASTER_KEY and MASTER_KEY_FILE aren't set. Keep
+// this file out of version control.
+const DefaultKeyFile = "config/master.key"
+
+// ErrInvalid is returned when a value can't be decrypted or verified
+var ErrInvalid = errors.New("secrets: invalid value")
+
+// ErrMissing is returned when there's no master key
+var ErrMissing = errors.New("secrets: missing the MASTER_KEY environment variable or " + DefaultKeyFile)
+
+// Load the master keys from the environment
+func Load() (*Keys, error) {
+	return LoadEnv(os.Getenv)
+}
+
+// LoadEnv loads the master keys using getenv. The keyfile holds one key per
+// line. The first key is the current key and the rest are previous keys.
+func LoadEnv(getenv func(key string) string) (*Keys, error) {
+	secrets := []string{getenv("MASTER_KEY")}
+	if secrets[0] == "" {
+		path := getenv("MASTER_KEY_FILE")
+		if path == "" {
+			path = DefaultKeyFile
+		}
+		lines, err := readKeyFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && getenv("MASTER_KEY_FILE") == "" {
+				return nil, ErrMissing
+			}
+			return nil, fmt.Errorf("secrets: unable to read the keyfile. %w", err)
+		}
+		secrets = lines
+	}
+	for _, secret := range strings.Split(getenv("MASTER_PREVIOUS_KEYS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	keys, err := New(secrets...)
+	if err != nil {
+		return nil, fmt.Errorf("secrets: unable to load the master keys. %w", err)
+	}
+	return keys, nil
+}
+
+func readKeyFile(path string) (keys []string, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		keys = append(keys, line)
+	}
+	return keys, scanner.Err()
+}
+
+// GenerateKey returns a random key for MASTER_KEY or the keyfile
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("secrets: unable to generate a key. %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// New keys from secrets. The first secret encrypts and signs, while the rest
+// only decrypt and verify. Empty secrets are skipped.
+func New(secrets ...string) (*Keys, error) {
+	var raw [][]byte
+	for _, secret := range secrets {
+		if secret == "" {
+			continue
+		}
+		key := sha256.Sum256([]byte(secret))
+		raw = append(raw, key[:])
+	}
+	return newKeys(raw)
+}
+
+func newKeys(raw [][]byte) (*Keys, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("secrets: missing a key")
+	}
+	keys := &Keys{raw: raw}
+	for _, key := range raw {
+		keys.signers = append(keys.signers, expand(key, "bud:sign"))
+		block, err := aes.NewCipher(expand(key, "bud:encrypt"))
+		if err != nil {
+			return nil, fmt.Errorf("secrets: unable to create cipher. %w", err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("secrets: unable to create cipher. %w", err)
+		}
+		keys.aeads = append(keys.aeads, aead)
+	}
+	return keys, nil
+}
+
+// Keys encrypt and sign values with AES-GCM and HMAC-SHA256
+type Keys struct {
+	raw     [][]byte
+	aeads   []cipher.AEAD
+	signers [][]byte
+}
+
+// expand derives a 32-byte sub-key from the key with HKDF-SHA256 (RFC 5869),
+// so encryption and signing never share a key
+func expand(key []byte, info string) []byte {
+	extract := hmac.New(sha256.New, make([]byte, sha256.Size))
+	extract.Write(key)
+	mac := hmac.New(sha256.New, extract.Sum(nil))
+	mac.Write([]byte(info))
+	mac.Write([]byte{1})
+	return mac.Sum(nil)
+}
+
+// Derive keys for a purpose like "session" or "csrf". Values encrypted for one
+// purpose can't be decrypted for another, and each derived key rotates along
+// with the key it was derived from.
+func (k *Keys) Derive(purpose string) *Keys {
+	raw := make([][]byte, len(k.raw))
+	for i, key := range k.raw {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte("bud:" + purpose))
+		raw[i] = mac.Sum(nil)
+	}
+	// Derived keys are always 32 bytes, so this can't fail
+	keys, _ := newKeys(raw)
+	return keys
+}
+
+// Encrypt the value. The additional data is authenticated but not encrypted,
+// so the value can only be decrypted with the same data.
+func (k *Keys) Encrypt(value, data []byte) ([]byte, error) {
+	aead := k.aeads[0]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("secrets: unable to generate a nonce. %w", err)
+	}
+	return aead.Seal(nonce, nonce, value, data), nil
+}
+
+// Decrypt the value, trying each key in turn
+func (k *Keys) Decrypt(sealed, data []byte) ([]byte, error) {
+	for _, aead := range k.aeads {
+		size := aead.NonceSize()
+		if len(sealed) < size {
+			return nil, ErrInvalid
+		}
+		plain, err := aead.Open(nil, sealed[:size], sealed[size:], data)
+		if err != nil {
+			continue
+		}
+		return plain, nil
+	}
+	return nil, ErrInvalid
+}
+
+// EncryptString encrypts the value into a URL-safe string
+func (k *Keys) EncryptString(value string) (string, error) {
+	sealed, err := k.Encrypt([]byte(value), nil)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptString decrypts a value from EncryptString
+func (k *Keys) DecryptString(value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	plain, err := k.Decrypt(sealed, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// EncodeCookie encrypts a cookie's value. The cookie's name is authenticated
+// too, so values can't be moved between cookies.
+func (k *Keys) EncodeCookie(name string, value []byte) (string, error) {
+	sealed, err := k.Encrypt(value, []byte(name))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecodeCookie decrypts a cookie's value from EncodeCookie
+func (k *Keys) DecodeCookie(name, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalid
+	}
+	return k.Decrypt(sealed, []byte(name))
+}
+
+// Sign the value, returning a URL-safe signature. Use signatures for values
+// that may be read but not changed, like signed links.
+func (k *Keys) Sign(value []byte) string {
+	return base64.RawURLEncoding.EncodeToString(sign(k.signers[0], value))
+}
+
+// Verify the value's signature with any of the keys
+func (k *Keys) Verify(value []byte, signature string) bool {
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	for _, key := range k.signers {
+		if hmac.Equal(mac, sign(key, value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func sign(key, value []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(value)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,155 @@
+package secrets_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/secrets"
+)
+
+func TestEncrypt(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("secret")
+	is.NoErr(err)
+	sealed, err := keys.Encrypt([]byte("hello"), []byte("greeting"))
+	is.NoErr(err)
+	plain, err := keys.Decrypt(sealed, []byte("greeting"))
+	is.NoErr(err)
+	is.Equal(string(plain), "hello")
+	// The additional data must match
+	_, err = keys.Decrypt(sealed, []byte("farewell"))
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	// Strings round-trip too
+	value, err := keys.EncryptString("hello")
+	is.NoErr(err)
+	plainText, err := keys.DecryptString(value)
+	is.NoErr(err)
+	is.Equal(plainText, "hello")
+	_, err = keys.DecryptString(value[:len(value)-2] + "AA")
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	_, err = secrets.New("")
+	is.Equal(err.Error(), "secrets: missing a key")
+}
+
+func TestCookie(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("secret")
+	is.NoErr(err)
+	value, err := keys.EncodeCookie("session", []byte(`{"id":1}`))
+	is.NoErr(err)
+	plain, err := keys.DecodeCookie("session", value)
+	is.NoErr(err)
+	is.Equal(string(plain), `{"id":1}`)
+	// Values can't be moved between cookies
+	_, err = keys.DecodeCookie("flash", value)
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	_, err = keys.DecodeCookie("session", "not base64!")
+	is.True(errors.Is(err, secrets.ErrInvalid))
+}
+
+func TestRotate(t *testing.T) {
+	is := is.New(t)
+	old, err := secrets.New("old")
+	is.NoErr(err)
+	sealed, err := old.EncryptString("hello")
+	is.NoErr(err)
+	signature := old.Sign([]byte("token"))
+	keys, err := secrets.New("new", "old")
+	is.NoErr(err)
+	plain, err := keys.DecryptString(sealed)
+	is.NoErr(err)
+	is.Equal(plain, "hello")
+	is.True(keys.Verify([]byte("token"), signature))
+	// New values use the new key
+	sealed, err = keys.EncryptString("hello")
+	is.NoErr(err)
+	_, err = old.DecryptString(sealed)
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	is.True(!old.Verify([]byte("token"), keys.Sign([]byte("token"))))
+}
+
+func TestDerive(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("master")
+	is.NoErr(err)
+	sealed, err := keys.Derive("session").EncryptString("hello")
+	is.NoErr(err)
+	plain, err := keys.Derive("session").DecryptString(sealed)
+	is.NoErr(err)
+	is.Equal(plain, "hello")
+	// Each purpose has its own keys
+	_, err = keys.Derive("csrf").DecryptString(sealed)
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	_, err = keys.DecryptString(sealed)
+	is.True(errors.Is(err, secrets.ErrInvalid))
+	// Derived keys rotate with the master key
+	rotated, err := secrets.New("new-master", "master")
+	is.NoErr(err)
+	plain, err = rotated.Derive("session").DecryptString(sealed)
+	is.NoErr(err)
+	is.Equal(plain, "hello")
+}
+
+func TestSign(t *testing.T) {
+	is := is.New(t)
+	keys, err := secrets.New("secret")
+	is.NoErr(err)
+	signature := keys.Sign([]byte("token"))
+	is.True(keys.Verify([]byte("token"), signature))
+	is.True(!keys.Verify([]byte("other"), signature))
+	is.True(!keys.Verify([]byte("token"), "not base64!"))
+	// Signing doesn't reuse the encryption key
+	key := sha256.Sum256([]byte("secret"))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte("token"))
+	is.True(!keys.Verify([]byte("token"), base64.RawURLEncoding.EncodeToString(mac.Sum(nil))))
+}
+
+func TestLoadEnv(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	env := map[string]string{"MASTER_KEY_FILE": filepath.Join(dir, "master.key")}
+	getenv := func(key string) string { return env[key] }
+	_, err := secrets.LoadEnv(getenv)
+	is.True(err != nil)
+	is.In(err.Error(), "secrets: unable to read the keyfile")
+	// Read the keys from the keyfile
+	key, err := secrets.GenerateKey()
+	is.NoErr(err)
+	is.Equal(len(key), 64)
+	is.NoErr(os.WriteFile(env["MASTER_KEY_FILE"], []byte("# master keys\n"+key+"\n\nold\n"), 0600))
+	keys, err := secrets.LoadEnv(getenv)
+	is.NoErr(err)
+	old, err := secrets.New("old")
+	is.NoErr(err)
+	is.True(keys.Verify([]byte("token"), old.Sign([]byte("token"))))
+	current, err := secrets.New(key)
+	is.NoErr(err)
+	is.True(current.Verify([]byte("token"), keys.Sign([]byte("token"))))
+	// MASTER_KEY takes precedence
+	env["MASTER_KEY"] = "master"
+	env["MASTER_PREVIOUS_KEYS"] = "previous, older"
+	keys, err = secrets.LoadEnv(getenv)
+	is.NoErr(err)
+	older, err := secrets.New("older")
+	is.NoErr(err)
+	is.True(keys.Verify([]byte("token"), older.Sign([]byte("token"))))
+	is.True(!keys.Verify([]byte("token"), old.Sign([]byte("token"))))
+	// Missing keys
+	_, err = secrets.LoadEnv(func(string) string { return "" })
+	is.True(errors.Is(err, secrets.ErrMissing))
+	// A keyfile without keys isn't reported as missing
+	is.NoErr(os.WriteFile(env["MASTER_KEY_FILE"], []byte("# master keys\n"), 0600))
+	delete(env, "MASTER_KEY")
+	delete(env, "MASTER_PREVIOUS_KEYS")
+	_, err = secrets.LoadEnv(getenv)
+	is.True(err != nil)
+	is.True(!errors.Is(err, secrets.ErrMissing))
+	is.In(err.Error(), "secrets: unable to load the master keys")
+}
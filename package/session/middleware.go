@@ -0,0 +1,378 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/redis"
+	"github.com/livebud/bud/package/secrets"
+)
+
+// maxCookieSize is the largest cookie that browsers reliably accept
+const maxCookieSize = 4096
+
+// unsafe methods change state, so they must carry the session's CSRF token
+var unsafe = map[string]struct{}{
+	http.MethodPost:   {},
+	http.MethodPut:    {},
+	http.MethodPatch:  {},
+	http.MethodDelete: {},
+}
+
+// touchInterval is how often the session's last seen time is updated. Writing
+// the session on every request would be wasteful.
+const touchInterval = time.Minute
+
+// Load the session middleware from the environment
+func Load(clock clock.Clock, ids idgen.IDGenerator) (*Middleware, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	config.Clock = clock
+	config.IDs = ids
+	return New(config)
+}
+
+// Config for the session middleware
+type Config struct {
+	// Name of the cookie. Defaults to "session".
+	Name string
+	// Secret encrypts the cookie
+	Secret string
+	// PreviousSecrets decrypt cookies that were encrypted before the secret was
+	// rotated. They can be removed once Lifetime has passed.
+	PreviousSecrets []string
+	// Keys encrypt the cookie instead of Secret and PreviousSecrets
+	Keys *secrets.Keys
+	// Store keeps sessions on the server. When nil, sessions are stored within
+	// the cookie itself.
+	Store Store
+	// Lifetime is how long a session lasts, no matter how active it is.
+	// Defaults to 30 days.
+	Lifetime time.Duration
+	// IdleTimeout expires sessions that haven't been used within the timeout.
+	// Defaults to 7 days.
+	IdleTimeout time.Duration
+	// Domain of the cookie. Defaults to the request's host.
+	Domain string
+	// Clock tells the time. Defaults to the system clock.
+	Clock clock.Clock
+	// IDs generates session IDs. Defaults to random IDs.
+	IDs idgen.IDGenerator
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	SESSION_SECRET=change-me
+//	SESSION_PREVIOUS_SECRETS=old-secret,older-secret
+//	SESSION_STORE=cookie
+//	SESSION_LIFETIME=720h
+//	SESSION_IDLE_TIMEOUT=168h
+//	SESSION_COOKIE=session
+//	SESSION_DOMAIN=example.com
+//
+// Without SESSION_SECRET, the cookie is encrypted with keys derived from the
+// app's master key. See the secrets package for details.
+//
+// SESSION_STORE may be cookie, redis or sql. The Redis store connects using
+// REDIS_URL and the SQL store connects using DATABASE_URL.
+func LoadConfig(getenv func(key string) string) (config *Config, err error) {
+	config = &Config{
+		Name:   getenv("SESSION_COOKIE"),
+		Secret: getenv("SESSION_SECRET"),
+		Domain: getenv("SESSION_DOMAIN"),
+	}
+	if config.Secret == "" {
+		keys, err := secrets.LoadEnv(getenv)
+		if err != nil {
+			if errors.Is(err, secrets.ErrMissing) {
+				return nil, fmt.Errorf("session: missing the SESSION_SECRET or MASTER_KEY environment variable")
+			}
+			return nil, fmt.Errorf("session: unable to load the master key. %w", err)
+		}
+		config.Keys = keys.Derive("session")
+	}
+	for _, secret := range strings.Split(getenv("SESSION_PREVIOUS_SECRETS"), ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			config.PreviousSecrets = append(config.PreviousSecrets, secret)
+		}
+	}
+	if config.Lifetime, err = envDuration(getenv, "SESSION_LIFETIME"); err != nil {
+		return nil, err
+	}
+	if config.IdleTimeout, err = envDuration(getenv, "SESSION_IDLE_TIMEOUT"); err != nil {
+		return nil, err
+	}
+	switch store := getenv("SESSION_STORE"); store {
+	case "", "cookie":
+	case "redis":
+		redisConfig, err := redis.LoadConfig(getenv)
+		if err != nil {
+			return nil, err
+		}
+		client, err := redis.Dial(redisConfig)
+		if err != nil {
+			return nil, err
+		}
+		config.Store = NewRedisStore(client)
+	case "sql":
+		databaseURL := getenv("DATABASE_URL")
+		if databaseURL == "" {
+			return nil, fmt.Errorf("session: the sql store requires the DATABASE_URL environment variable")
+		}
+		db, err := dbrt.Open(databaseURL)
+		if err != nil {
+			return nil, err
+		}
+		config.Store = NewSQLStore(db)
+	default:
+		return nil, fmt.Errorf("session: expected SESSION_STORE to be cookie, redis or sql, got %q", store)
+	}
+	return config, nil
+}
+
+func envDuration(getenv func(string) string, key string) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("session: expected %s to be a duration like 24h, got %q", key, value)
+	}
+	return d, nil
+}
+
+// New session middleware
+func New(config *Config) (*Middleware, error) {
+	keys := config.Keys
+	if keys == nil {
+		var err error
+		keys, err = secrets.New(append([]string{config.Secret}, config.PreviousSecrets...)...)
+		if err != nil {
+			return nil, fmt.Errorf("session: unable to load keys. %w", err)
+		}
+	}
+	m := &Middleware{
+		name:        config.Name,
+		store:       config.Store,
+		keys:        keys,
+		lifetime:    config.Lifetime,
+		idleTimeout: config.IdleTimeout,
+		domain:      config.Domain,
+		ids:         config.IDs,
+		Clock:       config.Clock,
+	}
+	if m.Clock == nil {
+		m.Clock = clock.Load()
+	}
+	if m.ids == nil {
+		m.ids = idgen.Load()
+	}
+	if m.name == "" {
+		m.name = "session"
+	}
+	if m.lifetime == 0 {
+		m.lifetime = 30 * 24 * time.Hour
+	}
+	if m.idleTimeout == 0 {
+		m.idleTimeout = 7 * 24 * time.Hour
+	}
+	return m, nil
+}
+
+// Middleware loads the session before each request and saves it before the
+// response is written
+type Middleware struct {
+	name        string
+	store       Store
+	keys        *secrets.Keys
+	lifetime    time.Duration
+	idleTimeout time.Duration
+	domain      string
+	ids         idgen.IDGenerator
+
+	// Clock tells the time that sessions expire by
+	Clock clock.Clock
+}
+
+// Middleware adds the session to the request's context
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := m.load(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !m.verify(r, session) {
+			http.Error(w, "session: invalid csrf token", http.StatusForbidden)
+			return
+		}
+		// Save the session right before the headers are written
+		sw := middleware.RecordStatus(w)
+		sw.BeforeHeader(func() error {
+			return m.save(r.Context(), w, r, session)
+		})
+		next.ServeHTTP(sw, r.WithContext(With(r.Context(), session)))
+		// Save sessions when the handler didn't write a response
+		if !sw.Written() {
+			if err := m.save(r.Context(), w, r, session); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+		}
+	})
+}
+
+// load the session from the request's cookie. Missing, invalid and expired
+// sessions start over with a new session.
+func (m *Middleware) load(r *http.Request) (*Session, error) {
+	now := m.Clock.Now()
+	cookie, err := r.Cookie(m.name)
+	if err != nil {
+		return newSession(now, m.ids), nil
+	}
+	value, err := m.keys.DecodeCookie(m.name, cookie.Value)
+	if err != nil {
+		return newSession(now, m.ids), nil
+	}
+	data := value
+	if m.store != nil {
+		data, err = m.store.Load(r.Context(), string(value))
+		if err != nil {
+			return nil, err
+		} else if data == nil {
+			return newSession(now, m.ids), nil
+		}
+	}
+	session, err := decodeSession(data, m.ids)
+	if err != nil {
+		return newSession(now, m.ids), nil
+	}
+	if m.expired(session, now) {
+		if m.store != nil {
+			if err := m.store.Delete(r.Context(), session.id); err != nil {
+				return nil, err
+			}
+		}
+		return newSession(now, m.ids), nil
+	}
+	// Keep active sessions from idling out
+	if now.Sub(session.seenAt) >= touchInterval {
+		session.seenAt = now
+		session.changed = true
+	}
+	return session, nil
+}
+
+// verify the CSRF token of unsafe requests that carry the session cookie.
+// Requests without the cookie have no session to act on, so they pass.
+func (m *Middleware) verify(r *http.Request, session *Session) bool {
+	if _, ok := unsafe[r.Method]; !ok {
+		return true
+	}
+	if _, err := r.Cookie(m.name); err != nil {
+		return true
+	}
+	token := r.Header.Get("X-CSRF-Token")
+	if token == "" && r.Header.Get("Content-Type") == "application/x-www-form-urlencoded" {
+		if err := r.ParseForm(); err != nil {
+			return false
+		}
+		token = r.PostForm.Get("_csrf")
+	}
+	return session.checkCSRF(token)
+}
+
+func (m *Middleware) expired(session *Session, now time.Time) bool {
+	return now.Sub(session.createdAt) >= m.lifetime || now.Sub(session.seenAt) >= m.idleTimeout
+}
+
+// expires returns when the session expires, whichever comes first
+func (m *Middleware) expires(session *Session) time.Time {
+	absolute := session.createdAt.Add(m.lifetime)
+	idle := session.seenAt.Add(m.idleTimeout)
+	if idle.Before(absolute) {
+		return idle
+	}
+	return absolute
+}
+
+// save the session and set the cookie
+func (m *Middleware) save(ctx context.Context, w http.ResponseWriter, r *http.Request, session *Session) error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !session.changed {
+		return nil
+	}
+	if session.destroyed {
+		if m.store != nil {
+			for _, id := range []string{session.previous, session.id} {
+				if id == "" {
+					continue
+				}
+				if err := m.store.Delete(ctx, id); err != nil {
+					return err
+				}
+			}
+		}
+		// Only clear cookies that were sent
+		if _, err := r.Cookie(m.name); err == nil {
+			http.SetCookie(w, m.cookie(r, "", time.Unix(0, 0)))
+		}
+		return nil
+	}
+	data, err := session.encode()
+	if err != nil {
+		return fmt.Errorf("session: unable to encode session. %w", err)
+	}
+	expires := m.expires(session)
+	value := data
+	if m.store != nil {
+		if session.previous != "" {
+			if err := m.store.Delete(ctx, session.previous); err != nil {
+				return err
+			}
+		}
+		if err := m.store.Save(ctx, session.id, data, expires); err != nil {
+			return err
+		}
+		value = []byte(session.id)
+	}
+	encoded, err := m.keys.EncodeCookie(m.name, value)
+	if err != nil {
+		return err
+	}
+	cookie := m.cookie(r, encoded, expires)
+	if size := len(cookie.String()); size > maxCookieSize {
+		return fmt.Errorf("session: cookie is %d bytes, which is over the %d byte limit. Use the redis or sql store for larger sessions", size, maxCookieSize)
+	}
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+func (m *Middleware) cookie(r *http.Request, value string, expires time.Time) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     m.name,
+		Value:    value,
+		Path:     "/",
+		Domain:   m.domain,
+		Expires:  expires,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	return cookie
+}
@@ -0,0 +1,260 @@
+// Package session stores data between requests. By default, sessions are
+// encrypted and stored in a cookie. For larger sessions or sessions that can be
+// revoked, store them in Redis or SQL and keep only the encrypted session ID in
+// the cookie.
+//
+// Controllers access the session through the request's context:
+//
+//	func (c *Controller) Create(ctx context.Context, email, password string) error {
+//		session := session.From(ctx)
+//		session.Renew()
+//		session.Set("user_id", user.ID)
+//		return nil
+//	}
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/idgen"
+)
+
+type contextKey struct{}
+
+// From returns the session within the context. It returns nil if the session
+// middleware isn't in use.
+func From(ctx context.Context) *Session {
+	session, _ := ctx.Value(contextKey{}).(*Session)
+	return session
+}
+
+// With returns a context that carries the session
+func With(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, session)
+}
+
+// Session data. It's safe for concurrent use.
+type Session struct {
+	mu        sync.Mutex
+	id        string
+	values    map[string]interface{}
+	createdAt time.Time
+	seenAt    time.Time
+	changed   bool
+	destroyed bool
+	csrf      string
+	loaded    bool   // loaded from the request's cookie
+	previous  string // ID before the session was renewed
+	ids       idgen.IDGenerator
+}
+
+// payload is the stored form of a session
+type payload struct {
+	ID        string                 `json:"id"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	SeenAt    time.Time              `json:"seen_at"`
+	CSRF      string                 `json:"csrf,omitempty"`
+}
+
+// newSession starts an empty session. It isn't saved until it changes, so
+// visitors don't get a cookie until there's something to remember.
+func newSession(now time.Time, ids idgen.IDGenerator) *Session {
+	return &Session{
+		id:        ids.NewID(),
+		values:    map[string]interface{}{},
+		createdAt: now,
+		seenAt:    now,
+		ids:       ids,
+	}
+}
+
+func decodeSession(data []byte, ids idgen.IDGenerator) (*Session, error) {
+	var p payload
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.Values == nil {
+		p.Values = map[string]interface{}{}
+	}
+	return &Session{
+		id:        p.ID,
+		values:    p.Values,
+		createdAt: p.CreatedAt,
+		seenAt:    p.SeenAt,
+		csrf:      p.CSRF,
+		loaded:    true,
+		ids:       ids,
+	}, nil
+}
+
+func (s *Session) encode() ([]byte, error) {
+	return json.Marshal(&payload{
+		ID:        s.id,
+		Values:    s.values,
+		CreatedAt: s.createdAt,
+		SeenAt:    s.seenAt,
+		CSRF:      s.csrf,
+	})
+}
+
+// ID of the session. The ID changes when the session is renewed.
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// Get a value from the session. Values are stored as JSON, so numbers are
+// returned as float64. Use Int for integers.
+func (s *Session) Get(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.values[key]
+}
+
+// String returns the value as a string or "" if it isn't one
+func (s *Session) String(key string) string {
+	value, _ := s.Get(key).(string)
+	return value
+}
+
+// Int returns the value as an int or 0 if it isn't a number
+func (s *Session) Int(key string) int {
+	switch value := s.Get(key).(type) {
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		return int(value)
+	default:
+		return 0
+	}
+}
+
+// Bool returns the value as a bool or false if it isn't one
+func (s *Session) Bool(key string) bool {
+	value, _ := s.Get(key).(bool)
+	return value
+}
+
+// Set a value in the session. The value must be encodable as JSON.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.changed = true
+}
+
+// Pop gets a value and removes it from the session. It's useful for values
+// that are only shown once, like flash messages.
+func (s *Session) Pop(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+	return value
+}
+
+// Delete a value from the session
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.changed = true
+	}
+}
+
+// Renew the session's ID while keeping its values. Renew after logging in or
+// out to prevent session fixation.
+func (s *Session) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.previous == "" {
+		s.previous = s.id
+	}
+	s.id = s.ids.NewID()
+	s.csrf = ""
+	s.changed = true
+}
+
+// Destroy the session and its values. The response removes the cookie.
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = map[string]interface{}{}
+	s.csrf = ""
+	s.destroyed = true
+	s.changed = true
+}
+
+// CSRFToken returns the token that forms and scripts send back with POST, PUT,
+// PATCH and DELETE requests, either in the "_csrf" form field or the
+// X-CSRF-Token header. The token is created on first use and changes when the
+// session is renewed.
+func (s *Session) CSRFToken() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.csrf != "" {
+		return s.csrf, nil
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("session: unable to generate a csrf token. %w", err)
+	}
+	s.csrf = base64.RawURLEncoding.EncodeToString(token)
+	s.changed = true
+	return s.csrf, nil
+}
+
+// ViewKey is the key that Svelte views read the CSRF token with, like
+// getContext("csrf")
+const ViewKey = "csrf"
+
+// ViewToken returns the CSRF token for the views. The token is only created
+// when a view renders it and the session will have a cookie, since requests
+// without the cookie aren't checked.
+func ViewToken(ctx context.Context) interface{} {
+	if s := From(ctx); s != nil {
+		return &viewToken{s}
+	}
+	return ""
+}
+
+type viewToken struct {
+	session *Session
+}
+
+func (t *viewToken) MarshalJSON() ([]byte, error) {
+	s := t.session
+	s.mu.Lock()
+	saved := s.loaded || s.changed
+	s.mu.Unlock()
+	if !saved {
+		return []byte(`""`), nil
+	}
+	token, err := s.CSRFToken()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(token)
+}
+
+// checkCSRF compares the token against the session's token in constant time
+func (s *Session) checkCSRF(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.csrf != "" && subtle.ConstantTimeCompare([]byte(s.csrf), []byte(token)) == 1
+}
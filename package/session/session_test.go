@@ -0,0 +1,353 @@
+package session_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/idgen"
+	"github.com/livebud/bud/package/session"
+)
+
+// client sends requests to the handler, keeping cookies between requests
+type client struct {
+	handler http.Handler
+	cookies map[string]*http.Cookie
+}
+
+func newClient(m *session.Middleware, h http.HandlerFunc) *client {
+	return &client{m.Middleware(h), map[string]*http.Cookie{}}
+}
+
+func (c *client) Get(path string) *http.Response {
+	return c.Do(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func (c *client) Do(req *http.Request) *http.Response {
+	for _, cookie := range c.cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	res := rec.Result()
+	for _, cookie := range res.Cookies() {
+		if cookie.MaxAge < 0 {
+			delete(c.cookies, cookie.Name)
+			continue
+		}
+		c.cookies[cookie.Name] = cookie
+	}
+	return res
+}
+
+func body(res *http.Response) string {
+	b, _ := io.ReadAll(res.Body)
+	return string(b)
+}
+
+func counter(w http.ResponseWriter, r *http.Request) {
+	s := session.From(r.Context())
+	switch r.URL.Path {
+	case "/":
+	case "/increment":
+		s.Set("count", s.Int("count")+1)
+	case "/renew":
+		s.Renew()
+	case "/logout":
+		s.Destroy()
+	}
+	fmt.Fprintf(w, "%d", s.Int("count"))
+}
+
+func load(t testing.TB, store session.Store) *session.Middleware {
+	t.Helper()
+	m, err := session.New(&session.Config{
+		Secret:      "secret",
+		Store:       store,
+		Lifetime:    time.Hour,
+		IdleTimeout: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCookieStore(t *testing.T) {
+	is := is.New(t)
+	c := newClient(load(t, nil), counter)
+	// No cookie until the session changes
+	res := c.Get("/")
+	is.Equal(body(res), "0")
+	is.Equal(len(res.Cookies()), 0)
+	is.Equal(body(c.Get("/increment")), "1")
+	is.Equal(body(c.Get("/increment")), "2")
+	is.Equal(body(c.Get("/")), "2")
+	cookie := c.cookies["session"]
+	is.True(cookie != nil)
+	is.True(cookie.HttpOnly)
+	is.Equal(cookie.SameSite, http.SameSiteLaxMode)
+	is.Equal(cookie.Path, "/")
+	// The cookie is encrypted
+	is.True(!strings.Contains(cookie.Value, "count"))
+	// Destroying the session clears the cookie
+	is.Equal(body(c.Get("/logout")), "0")
+	is.Equal(c.cookies["session"], nil)
+	is.Equal(body(c.Get("/")), "0")
+}
+
+func TestTampered(t *testing.T) {
+	is := is.New(t)
+	c := newClient(load(t, nil), counter)
+	is.Equal(body(c.Get("/increment")), "1")
+	cookie := c.cookies["session"]
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	is.NoErr(err)
+	// Flip a byte of the authentication tag at the end
+	sealed[len(sealed)-1] ^= 0xff
+	cookie.Value = base64.RawURLEncoding.EncodeToString(sealed)
+	// Tampered sessions start over
+	is.Equal(body(c.Get("/increment")), "1")
+}
+
+func TestRotateSecret(t *testing.T) {
+	is := is.New(t)
+	old := load(t, nil)
+	c := newClient(old, counter)
+	is.Equal(body(c.Get("/increment")), "1")
+	rotated, err := session.New(&session.Config{
+		Secret:          "new-secret",
+		PreviousSecrets: []string{"secret"},
+	})
+	is.NoErr(err)
+	c.handler = rotated.Middleware(http.HandlerFunc(counter))
+	is.Equal(body(c.Get("/increment")), "2")
+	// Once the previous secret is removed, old cookies are ignored
+	c2 := newClient(old, counter)
+	is.Equal(body(c2.Get("/increment")), "1")
+	rotated, err = session.New(&session.Config{Secret: "new-secret"})
+	is.NoErr(err)
+	c2.handler = rotated.Middleware(http.HandlerFunc(counter))
+	is.Equal(body(c2.Get("/")), "0")
+}
+
+func TestServerStore(t *testing.T) {
+	is := is.New(t)
+	store := session.NewMemoryStore()
+	c := newClient(load(t, store), counter)
+	is.Equal(body(c.Get("/")), "0")
+	is.Equal(store.Len(), 0)
+	is.Equal(body(c.Get("/increment")), "1")
+	is.Equal(body(c.Get("/increment")), "2")
+	is.Equal(store.Len(), 1)
+	// Renewing replaces the session in the store
+	before := c.cookies["session"].Value
+	is.Equal(body(c.Get("/renew")), "2")
+	is.True(c.cookies["session"].Value != before)
+	is.Equal(store.Len(), 1)
+	is.Equal(body(c.Get("/increment")), "3")
+	// Destroying the session removes it from the store
+	is.Equal(body(c.Get("/logout")), "0")
+	is.Equal(store.Len(), 0)
+}
+
+func TestExpiry(t *testing.T) {
+	is := is.New(t)
+	m := load(t, nil)
+	frozen := clock.Freeze(time.Now())
+	m.Clock = frozen
+	c := newClient(m, counter)
+	is.Equal(body(c.Get("/increment")), "1")
+	// Active sessions stay alive past the idle timeout
+	for i := 0; i < 3; i++ {
+		frozen.Advance(5 * time.Minute)
+		is.Equal(body(c.Get("/")), "1")
+	}
+	// Idle sessions expire
+	frozen.Advance(11 * time.Minute)
+	is.Equal(body(c.Get("/")), "0")
+	// Sessions expire after their lifetime, even when active
+	is.Equal(body(c.Get("/increment")), "1")
+	for i := 0; i < 12; i++ {
+		frozen.Advance(5 * time.Minute)
+		c.Get("/")
+	}
+	is.Equal(body(c.Get("/")), "0")
+}
+
+func TestClockAndIDs(t *testing.T) {
+	is := is.New(t)
+	frozen := clock.Freeze(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC))
+	m, err := session.New(&session.Config{
+		Secret:      "secret",
+		Lifetime:    time.Hour,
+		IdleTimeout: 10 * time.Minute,
+		Clock:       frozen,
+		IDs:         idgen.Sequential("s"),
+	})
+	is.NoErr(err)
+	c := newClient(m, func(w http.ResponseWriter, r *http.Request) {
+		s := session.From(r.Context())
+		if r.URL.Path == "/renew" {
+			s.Renew()
+		}
+		s.Set("count", s.Int("count")+1)
+		fmt.Fprintf(w, "%s %d", s.ID(), s.Int("count"))
+	})
+	is.Equal(body(c.Get("/")), "s1 1")
+	is.Equal(body(c.Get("/")), "s1 2")
+	is.Equal(body(c.Get("/renew")), "s2 3")
+	// Idle sessions expire when the clock moves forward
+	frozen.Advance(11 * time.Minute)
+	is.Equal(body(c.Get("/")), "s3 1")
+}
+
+func TestWriteHeader(t *testing.T) {
+	is := is.New(t)
+	c := newClient(load(t, nil), func(w http.ResponseWriter, r *http.Request) {
+		session.From(r.Context()).Set("flash", "created")
+		http.Redirect(w, r, "/", http.StatusFound)
+		// Changes after the response is written are too late to save
+		session.From(r.Context()).Set("late", true)
+	})
+	res := c.Get("/")
+	is.Equal(res.StatusCode, http.StatusFound)
+	is.Equal(len(res.Cookies()), 1)
+	c.handler = load(t, nil).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := session.From(r.Context())
+		fmt.Fprintf(w, "%v %v", s.Pop("flash"), s.Get("late"))
+	}))
+	is.Equal(body(c.Get("/")), "created <nil>")
+	is.Equal(body(c.Get("/")), "<nil> <nil>")
+}
+
+func TestTooLarge(t *testing.T) {
+	is := is.New(t)
+	c := newClient(load(t, nil), func(w http.ResponseWriter, r *http.Request) {
+		session.From(r.Context()).Set("data", strings.Repeat("a", 5000))
+		w.Write([]byte("ok"))
+	})
+	res := c.Get("/")
+	is.Equal(res.StatusCode, http.StatusInternalServerError)
+	is.True(strings.Contains(body(res), "over the 4096 byte limit"))
+}
+
+func TestCSRF(t *testing.T) {
+	is := is.New(t)
+	c := newClient(load(t, nil), func(w http.ResponseWriter, r *http.Request) {
+		s := session.From(r.Context())
+		switch r.URL.Path {
+		case "/form":
+			token, err := s.CSRFToken()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(token))
+		case "/renew":
+			s.Renew()
+		default:
+			s.Set("count", s.Int("count")+1)
+			fmt.Fprintf(w, "%d", s.Int("count"))
+		}
+	})
+	post := func(form url.Values, header string) *http.Response {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			req.Header.Set("X-CSRF-Token", header)
+		}
+		return c.Do(req)
+	}
+	// Requests without the session cookie have no session to act on
+	is.Equal(body(post(nil, "")), "1")
+	// Requests with the cookie need the session's token
+	res := post(nil, "")
+	is.Equal(res.StatusCode, http.StatusForbidden)
+	is.In(body(res), "session: invalid csrf token")
+	token := body(c.Get("/form"))
+	is.True(token != "")
+	is.Equal(body(c.Get("/form")), token)
+	is.Equal(post(url.Values{"_csrf": {"wrong"}}, "").StatusCode, http.StatusForbidden)
+	is.Equal(body(post(url.Values{"_csrf": {token}}, "")), "2")
+	is.Equal(body(post(nil, token)), "3")
+	// Safe methods aren't checked
+	is.Equal(body(c.Get("/")), "4")
+	// Renewing the session changes the token
+	c.Get("/renew")
+	is.Equal(post(nil, token).StatusCode, http.StatusForbidden)
+	is.True(body(c.Get("/form")) != token)
+}
+
+func TestViewToken(t *testing.T) {
+	is := is.New(t)
+	var rendered string
+	c := newClient(load(t, nil), func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/increment" {
+			session.From(r.Context()).Set("count", 1)
+		}
+		data, err := json.Marshal(session.ViewToken(r.Context()))
+		is.NoErr(err)
+		rendered = string(data)
+	})
+	// Visitors without a session don't get a token or a cookie
+	c.Get("/")
+	is.Equal(rendered, `""`)
+	is.Equal(c.cookies["session"], nil)
+	// Sessions that are saved get a token
+	c.Get("/increment")
+	is.True(rendered != `""`)
+	token := rendered
+	c.Get("/")
+	is.Equal(rendered, token)
+	// Without the session middleware, the token is empty
+	data, err := json.Marshal(session.ViewToken(context.Background()))
+	is.NoErr(err)
+	is.Equal(string(data), `""`)
+}
+
+func TestFromWithout(t *testing.T) {
+	is := is.New(t)
+	is.Equal(session.From(context.Background()), nil)
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	env := map[string]string{}
+	getenv := func(key string) string { return env[key] }
+	_, err := session.LoadConfig(getenv)
+	is.True(err != nil)
+	is.Equal(err.Error(), "session: missing the SESSION_SECRET or MASTER_KEY environment variable")
+	// Fallback to the master key
+	env["MASTER_KEY"] = "master"
+	config, err := session.LoadConfig(getenv)
+	is.NoErr(err)
+	is.True(config.Keys != nil)
+	delete(env, "MASTER_KEY")
+	env["SESSION_SECRET"] = "secret"
+	env["SESSION_PREVIOUS_SECRETS"] = "old, older"
+	env["SESSION_LIFETIME"] = "24h"
+	config, err = session.LoadConfig(getenv)
+	is.NoErr(err)
+	is.Equal(config.PreviousSecrets, []string{"old", "older"})
+	is.Equal(config.Lifetime, 24*time.Hour)
+	is.Equal(config.Store, nil)
+	env["SESSION_STORE"] = "memcached"
+	_, err = session.LoadConfig(getenv)
+	is.True(err != nil)
+	is.Equal(err.Error(), `session: expected SESSION_STORE to be cookie, redis or sql, got "memcached"`)
+	env["SESSION_STORE"] = "sql"
+	_, err = session.LoadConfig(getenv)
+	is.True(err != nil)
+	is.Equal(err.Error(), "session: the sql store requires the DATABASE_URL environment variable")
+}
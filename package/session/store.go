@@ -0,0 +1,185 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/package/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Store keeps sessions on the server. The cookie only holds the encrypted
+// session ID, so sessions can be larger than a cookie and revoked by deleting
+// them from the store.
+type Store interface {
+	// Load the session data. Load returns nil data when the session doesn't
+	// exist or has expired.
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save the session data until it expires
+	Save(ctx context.Context, id string, data []byte, expires time.Time) error
+	// Delete the session
+	Delete(ctx context.Context, id string) error
+}
+
+// NewRedisStore stores sessions in Redis. Sessions expire using Redis' TTLs.
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client, "session:"}
+}
+
+// RedisStore stores sessions in Redis
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+var _ Store = (*RedisStore)(nil)
+
+func (s *RedisStore) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.prefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, goredis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("session: unable to load session. %w", err)
+	}
+	return data, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, id string, data []byte, expires time.Time) error {
+	ttl := time.Until(expires)
+	if ttl <= 0 {
+		return s.Delete(ctx, id)
+	}
+	if err := s.client.Set(ctx, s.prefix+id, data, ttl).Err(); err != nil {
+		return fmt.Errorf("session: unable to save session. %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.prefix+id).Err(); err != nil {
+		return fmt.Errorf("session: unable to delete session. %w", err)
+	}
+	return nil
+}
+
+// NewSQLStore stores sessions in the bud_sessions table, which needs to be
+// created with a migration:
+//
+//	create table bud_sessions (
+//		id text primary key,
+//		data text not null,
+//		expires_at timestamp not null
+//	);
+//	create index bud_sessions_expires_at on bud_sessions (expires_at);
+//
+// Expired sessions are ignored when loaded. Remove them with Prune.
+func NewSQLStore(db *dbrt.DB) *SQLStore {
+	return &SQLStore{db}
+}
+
+// SQLStore stores sessions in a Postgres or SQLite database
+type SQLStore struct {
+	db *dbrt.DB
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func (s *SQLStore) Load(ctx context.Context, id string) ([]byte, error) {
+	var data string
+	query := s.db.Dialect().Rebind(`select "data" from "bud_sessions" where "id" = ? and "expires_at" > ?`)
+	if err := s.db.QueryRowContext(ctx, query, id, dbrt.Now()).Scan(&data); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("session: unable to load session. %w", err)
+	}
+	return []byte(data), nil
+}
+
+func (s *SQLStore) Save(ctx context.Context, id string, data []byte, expires time.Time) error {
+	query := s.db.Dialect().Rebind(`insert into "bud_sessions" ("id", "data", "expires_at") values (?, ?, ?) ` +
+		`on conflict ("id") do update set "data" = excluded."data", "expires_at" = excluded."expires_at"`)
+	if _, err := s.db.ExecContext(ctx, query, id, string(data), expires.UTC()); err != nil {
+		return fmt.Errorf("session: unable to save session. %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	query := s.db.Dialect().Rebind(`delete from "bud_sessions" where "id" = ?`)
+	if _, err := s.db.ExecContext(ctx, query, id); err != nil {
+		return fmt.Errorf("session: unable to delete session. %w", err)
+	}
+	return nil
+}
+
+// Prune deletes expired sessions
+func (s *SQLStore) Prune(ctx context.Context) (int64, error) {
+	query := s.db.Dialect().Rebind(`delete from "bud_sessions" where "expires_at" <= ?`)
+	result, err := s.db.ExecContext(ctx, query, dbrt.Now())
+	if err != nil {
+		return 0, fmt.Errorf("session: unable to prune sessions. %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// NewMemoryStore stores sessions in memory. Sessions are lost on restart and
+// aren't shared between processes, so it's only suitable for development and
+// testing.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]*memorySession{}}
+}
+
+// MemoryStore stores sessions in memory
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*memorySession
+}
+
+type memorySession struct {
+	data    []byte
+	expires time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Load(ctx context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	if !time.Now().Before(session.expires) {
+		delete(s.sessions, id)
+		return nil, nil
+	}
+	return session.data, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, id string, data []byte, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = &memorySession{data, expires}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Len returns the number of sessions in the store
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
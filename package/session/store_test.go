@@ -0,0 +1,45 @@
+package session_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/session"
+)
+
+func TestSQLStore(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	is.NoErr(err)
+	defer db.Close()
+	_, err = db.ExecContext(ctx, `create table bud_sessions (id text primary key, data text not null, expires_at timestamp not null)`)
+	is.NoErr(err)
+	store := session.NewSQLStore(db)
+	c := newClient(load(t, store), counter)
+	is.Equal(body(c.Get("/increment")), "1")
+	is.Equal(body(c.Get("/increment")), "2")
+	is.Equal(body(c.Get("/renew")), "2")
+	var count int
+	is.NoErr(db.QueryRowContext(ctx, `select count(*) from bud_sessions`).Scan(&count))
+	is.Equal(count, 1)
+	is.Equal(body(c.Get("/logout")), "0")
+	is.NoErr(db.QueryRowContext(ctx, `select count(*) from bud_sessions`).Scan(&count))
+	is.Equal(count, 0)
+	// Expired sessions aren't loaded and can be pruned
+	is.NoErr(store.Save(ctx, "a", []byte(`{}`), time.Now().Add(-time.Minute)))
+	is.NoErr(store.Save(ctx, "b", []byte(`{}`), time.Now().Add(time.Minute)))
+	data, err := store.Load(ctx, "a")
+	is.NoErr(err)
+	is.Equal(data, nil)
+	pruned, err := store.Prune(ctx)
+	is.NoErr(err)
+	is.Equal(pruned, int64(1))
+	data, err = store.Load(ctx, "b")
+	is.NoErr(err)
+	is.Equal(string(data), `{}`)
+}
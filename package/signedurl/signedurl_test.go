@@ -0,0 +1,120 @@
+package signedurl_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/secrets"
+	"github.com/livebud/bud/package/signedurl"
+)
+
+func newSigner(t testing.TB, secret string) *signedurl.Signer {
+	keys, err := secrets.New(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signedurl.New(keys)
+}
+
+func parse(t testing.TB, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestSign(t *testing.T) {
+	is := is.New(t)
+	signer := newSigner(t, "secret")
+	link, err := signer.Sign("/downloads/42?as=report.pdf", time.Hour)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(link, "/downloads/42?as=report.pdf&expires="))
+	is.True(strings.Contains(link, "&signature="))
+	is.NoErr(signer.Verify(parse(t, link)))
+	// Reordering the query doesn't matter
+	u := parse(t, link)
+	query := u.Query()
+	u.RawQuery = "signature=" + url.QueryEscape(query.Get("signature")) + "&expires=" + query.Get("expires") + "&as=report.pdf"
+	is.NoErr(signer.Verify(u))
+	// Changing the link does
+	for _, tampered := range []string{
+		strings.Replace(link, "/42", "/43", 1),
+		strings.Replace(link, "report.pdf", "other.pdf", 1),
+		strings.Replace(link, "expires=", "expires=9", 1),
+		link + "&admin=true",
+	} {
+		is.True(errors.Is(signer.Verify(parse(t, tampered)), signedurl.ErrInvalid))
+	}
+	// Other keys don't verify the link
+	is.True(errors.Is(newSigner(t, "other").Verify(parse(t, link)), signedurl.ErrInvalid))
+	is.True(errors.Is(signer.Verify(parse(t, "/downloads/42")), signedurl.ErrUnsigned))
+	// Absolute URLs only sign the path and query
+	link, err = signer.Sign("https://example.com/downloads/42", time.Hour)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(link, "https://example.com/downloads/42?expires="))
+	is.NoErr(signer.Verify(parse(t, strings.Replace(link, "https://example.com", "http://localhost:3000", 1))))
+	_, err = signer.Sign("/downloads/42", 0)
+	is.True(err != nil)
+}
+
+func TestExpired(t *testing.T) {
+	is := is.New(t)
+	signer := newSigner(t, "secret")
+	frozen := clock.Freeze(time.Unix(1700000000, 0))
+	signer.Clock = frozen
+	link, err := signer.Sign("/confirm", time.Minute)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(link, "/confirm?expires=1700000060&signature="))
+	is.NoErr(signer.Verify(parse(t, link)))
+	frozen.Advance(time.Minute)
+	is.True(errors.Is(signer.Verify(parse(t, link)), signedurl.ErrExpired))
+}
+
+func TestRoute(t *testing.T) {
+	is := is.New(t)
+	signer := newSigner(t, "secret")
+	link, err := signer.Route("/users/:user_id/files/:id", map[string]string{"user_id": "1", "id": "2"}, time.Hour)
+	is.NoErr(err)
+	is.True(strings.HasPrefix(link, "/users/1/files/2?expires="))
+	is.NoErr(signer.Verify(parse(t, link)))
+	_, err = signer.Route("/files/:id", nil, time.Hour)
+	is.True(err != nil)
+}
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	signer := newSigner(t, "secret")
+	h := signer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := signedurl.Require(r.Context()); err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+		w.Write([]byte("signed"))
+	}))
+	serve := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	link, err := signer.Sign("/downloads/42", time.Hour)
+	is.NoErr(err)
+	rec := serve(link)
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "signed")
+	// Unsigned requests pass through
+	rec = serve("/downloads/42")
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "signedurl: request wasn't signed")
+	// Tampered links are forbidden
+	rec = serve(strings.Replace(link, "/42", "/43", 1))
+	is.Equal(rec.Code, http.StatusForbidden)
+	is.Equal(strings.TrimSpace(rec.Body.String()), "signedurl: invalid signature")
+}
@@ -89,7 +89,7 @@ func ListenUp(path string, attempts int) (Listener, error) {
 			if err != nil {
 				return nil, err
 			}
-			return ListenUp(newPath, attempts-1)
+			return ListenUp(newPath, attempts)
 		}
 		return nil, err
 	}
@@ -141,6 +141,11 @@ func Transport(path string) (http.RoundTripper, error) {
 			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
 				return dialer.DialContext(ctx, "unix", path)
 			},
+			// Every request goes to the same host, so keep plenty of connections
+			// around for reuse
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 100,
+			IdleConnTimeout:     90 * time.Second,
 		}, nil
 	}
 	return httpTransport(url.Host), nil
@@ -159,6 +164,7 @@ func httpTransport(host string) http.RoundTripper {
 		},
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   100,
 		IdleConnTimeout:       90 * time.Second,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
@@ -147,6 +147,33 @@ func TestListenUp(t *testing.T) {
 	is.Equal(port, priorPort+1)
 }
 
+func TestListenUpAttempts(t *testing.T) {
+	is := is.New(t)
+	ln0, err := socket.Listen(":0")
+	is.NoErr(err)
+	defer ln0.Close()
+	// Take the next two ports too
+	ln1, err := socket.ListenUp(ln0.Addr().String(), 1)
+	is.NoErr(err)
+	defer ln1.Close()
+	ln2, err := socket.ListenUp(ln1.Addr().String(), 1)
+	is.NoErr(err)
+	defer ln2.Close()
+	// Each attempt tries the next port
+	ln3, err := socket.ListenUp(ln0.Addr().String(), 3)
+	is.NoErr(err)
+	defer ln3.Close()
+	priorURL, err := urlx.Parse(ln0.Addr().String())
+	is.NoErr(err)
+	priorPort, err := strconv.Atoi(priorURL.Port())
+	is.NoErr(err)
+	url, err := urlx.Parse(ln3.Addr().String())
+	is.NoErr(err)
+	port, err := strconv.Atoi(url.Port())
+	is.NoErr(err)
+	is.Equal(port, priorPort+3)
+}
+
 func TestListenMaxAttemptsReached(t *testing.T) {
 	is := is.New(t)
 	ln0, err := socket.Listen(":0")
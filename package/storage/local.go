@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/package/signedurl"
+)
+
+// Prefix of the links to locally stored files
+const Prefix = "/bud/storage/"
+
+// NewLocal stores files in dir. Links are signed by signer.
+func NewLocal(dir string, signer *signedurl.Signer) *Local {
+	return &Local{dir, signer}
+}
+
+// Local stores files on disk
+type Local struct {
+	dir    string
+	signer *signedurl.Signer
+}
+
+var _ Storage = (*Local)(nil)
+
+// Save the file. The file is written to a temporary file first, so readers
+// never see a partial file.
+func (l *Local) Save(ctx context.Context, key string, body io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	filePath := filepath.Join(l.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	return nil
+}
+
+// Open the file
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	return l.open(key)
+}
+
+func (l *Local) open(key string) (*os.File, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filepath.Join(l.dir, filepath.FromSlash(key)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("storage: unable to open %q. %w", key, ErrNotFound)
+		}
+		return nil, fmt.Errorf("storage: unable to open %q. %w", key, err)
+	}
+	return file, nil
+}
+
+// URL returns a signed link to the file, served by Middleware
+func (l *Local) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	u := &url.URL{Path: Prefix + key}
+	return l.signer.Sign(u.String(), ttl)
+}
+
+// Delete the file
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(l.dir, filepath.FromSlash(key))); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("storage: unable to delete %q. %w", key, err)
+	}
+	return nil
+}
+
+// Middleware serves the files at their signed links. Links that were tampered
+// with or have expired get a 403 Forbidden.
+func (l *Local) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, Prefix) || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err := l.signer.Verify(r.URL); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.Path, Prefix)
+		file, err := l.open(key)
+		if err != nil {
+			if errors.Is(err, ErrNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		stat, err := file.Stat()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if stat.IsDir() {
+			http.Error(w, fmt.Sprintf("storage: %q is a directory", key), http.StatusNotFound)
+			return
+		}
+		// Links are private to whoever they were given to
+		w.Header().Set("Cache-Control", "private")
+		http.ServeContent(w, r, path.Base(key), stat.ModTime(), file)
+	})
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/livebud/bud/internal/s3"
+)
+
+// newBucket stores files in the client's bucket, under prefix
+func newBucket(client *s3.Client, prefix string) *bucket {
+	return &bucket{client, prefix}
+}
+
+// bucket stores files in S3-compatible storage
+type bucket struct {
+	client *s3.Client
+	prefix string
+}
+
+var _ Storage = (*bucket)(nil)
+
+// Save the file. Requests are signed over their payload, so the file is read
+// into memory first.
+func (s *bucket) Save(ctx context.Context, key string, body io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("storage: unable to save %q. %w", key, err)
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return s.client.Put(ctx, &s3.Object{
+		Key:     path.Join(s.prefix, key),
+		Data:    data,
+		Headers: http.Header{"Content-Type": {contentType}},
+	})
+}
+
+// Open the file
+func (s *bucket) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validKey(key); err != nil {
+		return nil, err
+	}
+	body, err := s.client.Get(ctx, path.Join(s.prefix, key))
+	if err != nil {
+		if errors.Is(err, s3.ErrNotFound) {
+			return nil, fmt.Errorf("storage: unable to open %q. %w", key, ErrNotFound)
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+// URL returns a presigned link to the file, which lasts up to 7 days
+func (s *bucket) URL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	if err := validKey(key); err != nil {
+		return "", err
+	}
+	return s.client.Presign(path.Join(s.prefix, key), ttl)
+}
+
+// Delete the file
+func (s *bucket) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	return s.client.Delete(ctx, path.Join(s.prefix, key))
+}
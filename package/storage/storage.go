@@ -0,0 +1,125 @@
+// Package storage saves the files that users upload, like avatars and
+// attachments. Files are stored on local disk or in S3-compatible storage,
+// depending on the environment:
+//
+//	STORAGE=s3
+//	STORAGE_BUCKET=s3://bucket/uploads
+//
+// Add Storage to a controller to use it:
+//
+//	type Controller struct {
+//		Storage storage.Storage
+//	}
+//
+//	func (c *Controller) Create(ctx context.Context, r *http.Request) (string, error) {
+//		file, header, err := r.FormFile("avatar")
+//		// ...
+//		key := "avatars/" + header.Filename
+//		if err := c.Storage.Save(ctx, key, file); err != nil {
+//			return "", err
+//		}
+//		return c.Storage.URL(ctx, key, time.Hour)
+//	}
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/livebud/bud/internal/s3"
+	"github.com/livebud/bud/package/clock"
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/secrets"
+	"github.com/livebud/bud/package/signedurl"
+)
+
+// ErrNotFound is returned when a file doesn't exist
+var ErrNotFound = errors.New("storage: file not found")
+
+// Storage saves files by key, like "avatars/42.png". Keys are slash-separated
+// paths without "." or ".." elements.
+type Storage interface {
+	// Save the file at key, replacing any file that's already there
+	Save(ctx context.Context, key string, body io.Reader) error
+	// Open the file at key to stream it. The caller closes the file.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// URL returns a link to the file that expires after ttl
+	URL(ctx context.Context, key string, ttl time.Duration) (string, error)
+	// Delete the file at key. Deleting a missing file isn't an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Load storage from the environment
+func Load(clock clock.Clock) (Storage, error) {
+	return LoadEnv(os.Getenv, clock)
+}
+
+// LoadEnv loads storage using getenv:
+//
+//	STORAGE=local
+//	STORAGE_DIR=storage
+//
+// STORAGE may be local or s3. Local storage keeps the files in STORAGE_DIR,
+// which defaults to storage, and serves their links at /bud/storage with the
+// app's master key. S3 storage keeps the files in STORAGE_BUCKET, like
+// s3://bucket/prefix or gs://bucket/prefix. Set STORAGE_ENDPOINT for other
+// S3-compatible services, like Cloudflare R2 or MinIO. The credentials come
+// from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.
+func LoadEnv(getenv func(key string) string, clock clock.Clock) (Storage, error) {
+	switch backend := getenv("STORAGE"); backend {
+	case "", "local":
+		keys, err := secrets.LoadEnv(getenv)
+		if err != nil {
+			return nil, err
+		}
+		signer := signedurl.New(keys)
+		signer.Clock = clock
+		dir := getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "storage"
+		}
+		return NewLocal(dir, signer), nil
+	case "s3":
+		bucket := getenv("STORAGE_BUCKET")
+		if bucket == "" {
+			return nil, fmt.Errorf("storage: the s3 backend requires the STORAGE_BUCKET environment variable")
+		}
+		client, prefix, err := s3.Load(getenv, bucket, getenv("STORAGE_ENDPOINT"), "")
+		if err != nil {
+			return nil, err
+		}
+		if err := client.Verify(); err != nil {
+			return nil, err
+		}
+		client.Now = clock.Now
+		return newBucket(client, prefix), nil
+	default:
+		return nil, fmt.Errorf("storage: unknown backend %q. Expected local or s3", backend)
+	}
+}
+
+// Middleware serves the links to locally stored files. Other backends serve
+// their own links, so requests pass through.
+func Middleware(storage Storage) middleware.Middleware {
+	if mw, ok := storage.(middleware.Middleware); ok {
+		return mw
+	}
+	return middleware.Function(func(next http.Handler) http.Handler {
+		return next
+	})
+}
+
+// validKey checks that the key is a clean, relative path
+func validKey(key string) error {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, `\`) {
+		return fmt.Errorf("storage: invalid key %q", key)
+	}
+	return nil
+}
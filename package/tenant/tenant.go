@@ -0,0 +1,215 @@
+// Package tenant scopes requests to a tenant, like a customer's account in a
+// multi-tenant app. The middleware resolves the tenant from the subdomain, a
+// header or the first segment of the path and adds it to the context:
+//
+//	acme.example.com/posts        # TENANT_FROM=subdomain
+//	X-Tenant: acme                # TENANT_FROM=header
+//	example.com/acme/posts        # TENANT_FROM=path
+//
+// Models with a TenantID field are scoped to the request's tenant by the
+// generated database code.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/livebud/bud/package/middleware"
+)
+
+// ErrMissing is returned when the request doesn't have a tenant
+var ErrMissing = errors.New("tenant: request doesn't have a tenant")
+
+// Load the middleware from the environment
+func Load() (*Middleware, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	return New(config), nil
+}
+
+// Config for the middleware
+type Config struct {
+	// From is where the tenant comes from: "subdomain", "header" or "path".
+	// Defaults to "subdomain".
+	From string
+	// Domain is the root domain of the subdomains, like "example.com".
+	// Requests to the root domain and www don't have a tenant.
+	Domain string
+	// Header that holds the tenant. Defaults to "X-Tenant".
+	Header string
+}
+
+// LoadConfig reads the configuration from the environment:
+//
+//	TENANT_FROM=subdomain
+//	TENANT_DOMAIN=example.com
+//	TENANT_HEADER=X-Tenant
+//
+// TENANT_DOMAIN is required for subdomains.
+func LoadConfig(getenv func(key string) string) (*Config, error) {
+	config := &Config{
+		From:   getenv("TENANT_FROM"),
+		Domain: getenv("TENANT_DOMAIN"),
+		Header: getenv("TENANT_HEADER"),
+	}
+	switch config.From {
+	case "", "subdomain":
+		if config.Domain == "" {
+			return nil, errors.New("tenant: missing the TENANT_DOMAIN environment variable")
+		}
+	case "header", "path":
+	default:
+		return nil, errors.New(`tenant: expected TENANT_FROM to be "subdomain", "header" or "path"`)
+	}
+	return config, nil
+}
+
+// New tenant middleware
+func New(config *Config) *Middleware {
+	m := &Middleware{
+		from:   config.From,
+		domain: strings.ToLower(strings.TrimPrefix(config.Domain, ".")),
+		header: config.Header,
+	}
+	if m.from == "" {
+		m.from = "subdomain"
+	}
+	if m.header == "" {
+		m.header = "X-Tenant"
+	}
+	return m
+}
+
+// Middleware adds the request's tenant to the context
+type Middleware struct {
+	from   string
+	domain string
+	header string
+}
+
+// Resolve the tenant from the request. It returns false when the request
+// doesn't have a tenant.
+func (m *Middleware) Resolve(r *http.Request) (string, bool) {
+	switch m.from {
+	case "header":
+		id := strings.TrimSpace(r.Header.Get(m.header))
+		return id, id != ""
+	case "path":
+		// Bud's own routes, like /bud/live.js, don't belong to a tenant
+		if r.URL.Path == "/bud" || strings.HasPrefix(r.URL.Path, "/bud/") {
+			return "", false
+		}
+		id := strings.TrimPrefix(r.URL.Path, "/")
+		if i := strings.IndexByte(id, '/'); i >= 0 {
+			id = id[:i]
+		}
+		// Files like /favicon.ico aren't tenants either
+		return id, Valid(id)
+	default:
+		host := strings.ToLower(r.Host)
+		if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.Contains(host[i:], "]") {
+			host = host[:i]
+		}
+		id := strings.TrimSuffix(host, "."+m.domain)
+		if id == host || id == "www" {
+			return "", false
+		}
+		return id, true
+	}
+}
+
+// Middleware resolves the tenant before calling next. Requests without a
+// tenant pass through, so pages like the home page can be served without
+// one. In path mode, the tenant is removed from the path before routing.
+func (m *Middleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, ok := m.Resolve(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !Valid(id) {
+			http.Error(w, "tenant: invalid tenant "+id, http.StatusBadRequest)
+			return
+		}
+		r = r.WithContext(With(r.Context(), id))
+		if m.from == "path" {
+			prefix := "/" + id
+			u := *r.URL
+			u.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, prefix), "/")
+			u.RawPath = ""
+			r.URL = &u
+			sw := middleware.RecordStatus(w)
+			sw.BeforeHeader(func() error {
+				prefixLocation(w.Header(), prefix)
+				return nil
+			})
+			w = sw
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Valid checks that the tenant is safe to use in hostnames, paths and keys.
+// Tenants are lowercase letters, digits and hyphens, like "acme-inc".
+func Valid(id string) bool {
+	if id == "" || len(id) > 63 || id[0] == '-' || id[len(id)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+type contextKey struct{}
+
+// With adds the tenant to the context
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the request's tenant. It returns false when the request doesn't
+// have a tenant.
+func From(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	return id, ok && id != ""
+}
+
+// Require returns the request's tenant or ErrMissing
+func Require(ctx context.Context) (string, error) {
+	id, ok := From(ctx)
+	if !ok {
+		return "", ErrMissing
+	}
+	return id, nil
+}
+
+// Key prefixes a cache key with the request's tenant, so tenants don't share
+// cached values. Keys are unchanged for requests without a tenant.
+func Key(ctx context.Context, key string) string {
+	id, ok := From(ctx)
+	if !ok {
+		return key
+	}
+	return "tenant:" + id + ":" + key
+}
+
+// prefixLocation adds the tenant back onto redirects to paths, so redirecting
+// to /posts after stripping /acme goes to /acme/posts
+func prefixLocation(header http.Header, prefix string) {
+	location := header.Get("Location")
+	if location == "/" {
+		header.Set("Location", prefix)
+	} else if strings.HasPrefix(location, "/") && !strings.HasPrefix(location, "//") {
+		header.Set("Location", prefix+location)
+	}
+}
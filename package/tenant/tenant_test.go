@@ -0,0 +1,122 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/tenant"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	_, err := tenant.LoadConfig(env(nil))
+	is.True(err != nil)
+	is.Equal(err.Error(), "tenant: missing the TENANT_DOMAIN environment variable")
+	_, err = tenant.LoadConfig(env(map[string]string{"TENANT_FROM": "cookie"}))
+	is.True(err != nil)
+	is.Equal(err.Error(), `tenant: expected TENANT_FROM to be "subdomain", "header" or "path"`)
+	config, err := tenant.LoadConfig(env(map[string]string{"TENANT_FROM": "header", "TENANT_HEADER": "X-Org"}))
+	is.NoErr(err)
+	is.Equal(config.From, "header")
+	is.Equal(config.Header, "X-Org")
+}
+
+// serve a request through the middleware, responding with the tenant and path
+func serve(m *tenant.Middleware, req *http.Request) *httptest.ResponseRecorder {
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/posts", http.StatusFound)
+			return
+		}
+		id, _ := tenant.From(r.Context())
+		w.Write([]byte(id + " " + r.URL.Path))
+	}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSubdomain(t *testing.T) {
+	is := is.New(t)
+	m := tenant.New(&tenant.Config{Domain: "example.com"})
+	rec := serve(m, httptest.NewRequest(http.MethodGet, "http://acme.example.com:3000/posts", nil))
+	is.Equal(rec.Body.String(), "acme /posts")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "http://Acme.Example.com/posts", nil))
+	is.Equal(rec.Body.String(), "acme /posts")
+	// The root domain and www don't have a tenant
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "http://example.com/", nil))
+	is.Equal(rec.Body.String(), " /")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil))
+	is.Equal(rec.Body.String(), " /")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "http://other.com/", nil))
+	is.Equal(rec.Body.String(), " /")
+	// Nested subdomains aren't valid tenants
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "http://a.b.example.com/", nil))
+	is.Equal(rec.Code, http.StatusBadRequest)
+}
+
+func TestHeader(t *testing.T) {
+	is := is.New(t)
+	m := tenant.New(&tenant.Config{From: "header"})
+	req := httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-Tenant", "acme")
+	rec := serve(m, req)
+	is.Equal(rec.Body.String(), "acme /posts")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "/posts", nil))
+	is.Equal(rec.Body.String(), " /posts")
+	req = httptest.NewRequest(http.MethodGet, "/posts", nil)
+	req.Header.Set("X-Tenant", "../acme")
+	rec = serve(m, req)
+	is.Equal(rec.Code, http.StatusBadRequest)
+}
+
+func TestPath(t *testing.T) {
+	is := is.New(t)
+	m := tenant.New(&tenant.Config{From: "path"})
+	rec := serve(m, httptest.NewRequest(http.MethodGet, "/acme/posts/1", nil))
+	is.Equal(rec.Body.String(), "acme /posts/1")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "/acme", nil))
+	is.Equal(rec.Body.String(), "acme /")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "/", nil))
+	is.Equal(rec.Body.String(), " /")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "/favicon.ico", nil))
+	is.Equal(rec.Body.String(), " /favicon.ico")
+	rec = serve(m, httptest.NewRequest(http.MethodGet, "/bud/live.js", nil))
+	is.Equal(rec.Body.String(), " /bud/live.js")
+	// Redirects stay within the tenant
+	rec = serve(m, httptest.NewRequest(http.MethodPost, "/acme/redirect", nil))
+	is.Equal(rec.Code, http.StatusFound)
+	is.Equal(rec.Header().Get("Location"), "/acme/posts")
+}
+
+func TestContext(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	_, err := tenant.Require(ctx)
+	is.True(errors.Is(err, tenant.ErrMissing))
+	is.Equal(tenant.Key(ctx, "posts"), "posts")
+	ctx = tenant.With(ctx, "acme")
+	id, err := tenant.Require(ctx)
+	is.NoErr(err)
+	is.Equal(id, "acme")
+	is.Equal(tenant.Key(ctx, "posts"), "tenant:acme:posts")
+}
+
+func TestValid(t *testing.T) {
+	is := is.New(t)
+	is.True(tenant.Valid("acme"))
+	is.True(tenant.Valid("acme-inc-2"))
+	is.True(!tenant.Valid(""))
+	is.True(!tenant.Valid("-acme"))
+	is.True(!tenant.Valid("Acme"))
+	is.True(!tenant.Valid("acme.com"))
+	is.True(!tenant.Valid("acme:posts"))
+}
@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/livebud/bud/package/middleware"
+	"github.com/livebud/bud/package/router"
+)
+
+// Middleware starts a server span for each request. The span continues the
+// trace in the request's traceparent header and is named after the matched
+// route, like "GET /posts/:id".
+func (t *Tracer) Middleware(next http.Handler) http.Handler {
+	if !t.Enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parent, _ := Extract(r.Header)
+		ctx, route := router.Track(r.Context())
+		ctx, span := t.start(ctx, r.Method, Server, parent)
+		defer span.End()
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		span.SetAttribute("url.scheme", scheme(r))
+		span.SetAttribute("server.address", r.Host)
+		span.SetAttribute("network.protocol.version", strconv.Itoa(r.ProtoMajor)+"."+strconv.Itoa(r.ProtoMinor))
+		if userAgent := r.UserAgent(); userAgent != "" {
+			span.SetAttribute("user_agent.original", userAgent)
+		}
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			span.SetAttribute("client.address", host)
+		}
+		sw := middleware.RecordStatus(w)
+		defer func() {
+			// Name the span once the router has matched the route
+			if route := route(); route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttribute("http.route", route)
+			}
+			status := sw.Status()
+			span.SetAttribute("http.response.status_code", status)
+			// Panics are reported as 500s further down the stack
+			if e := recover(); e != nil {
+				span.SetStatus(Error, "panic")
+				span.SetAttribute("http.response.status_code", http.StatusInternalServerError)
+				panic(e)
+			}
+			if status >= 500 {
+				span.SetStatus(Error, http.StatusText(status))
+			}
+		}()
+		next.ServeHTTP(sw, r.WithContext(ctx))
+	})
+}
+
+func scheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Transport starts a client span for each request sent within a traced
+// request and passes the trace along in the traceparent header
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if FromContext(r.Context()) == nil {
+		return t.base.RoundTrip(r)
+	}
+	ctx, span := StartClient(r.Context(), r.Method)
+	defer span.End()
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("url.full", redact(r))
+	span.SetAttribute("server.address", r.URL.Hostname())
+	// Round trippers mustn't modify the original request
+	r = r.Clone(ctx)
+	Inject(ctx, r.Header)
+	res, err := t.base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.response.status_code", res.StatusCode)
+	if res.StatusCode >= 400 {
+		span.SetStatus(Error, http.StatusText(res.StatusCode))
+	}
+	return res, nil
+}
+
+// redact the credentials and query from the URL, since they can hold secrets
+func redact(r *http.Request) string {
+	u := *r.URL
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
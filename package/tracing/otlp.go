@@ -0,0 +1,179 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLP exports spans to an OpenTelemetry collector with OTLP over HTTP, encoded
+// as JSON
+type OTLP struct {
+	Endpoint string // Like http://localhost:4318/v1/traces
+	Headers  map[string]string
+	Timeout  time.Duration
+	Client   *http.Client
+}
+
+var _ Exporter = (*OTLP)(nil)
+
+// scope names the instrumentation in the exported spans
+const scope = "github.com/livebud/bud/package/tracing"
+
+// Export the spans
+func (o *OTLP) Export(ctx context.Context, resource map[string]string, spans []*SpanData) error {
+	body, err := json.Marshal(encodeRequest(resource, spans))
+	if err != nil {
+		return err
+	}
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range o.Headers {
+		req.Header.Set(key, value)
+	}
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("%s responded with %d. %s", o.Endpoint, res.StatusCode, bytes.TrimSpace(message))
+	}
+	io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+// The request follows the JSON encoding of OTLP's ExportTraceServiceRequest.
+// IDs are hex and 64-bit integers are strings.
+type (
+	exportRequest struct {
+		ResourceSpans []resourceSpans `json:"resourceSpans"`
+	}
+	resourceSpans struct {
+		Resource   resourceJSON `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	resourceJSON struct {
+		Attributes []keyValue `json:"attributes"`
+	}
+	scopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []spanJSON `json:"spans"`
+	}
+	spanJSON struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		TraceState        string      `json:"traceState,omitempty"`
+		ParentSpanID      string      `json:"parentSpanId,omitempty"`
+		Name              string      `json:"name"`
+		Kind              Kind        `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []keyValue  `json:"attributes,omitempty"`
+		Events            []eventJSON `json:"events,omitempty"`
+		Status            statusJSON  `json:"status"`
+	}
+	eventJSON struct {
+		TimeUnixNano string     `json:"timeUnixNano"`
+		Name         string     `json:"name"`
+		Attributes   []keyValue `json:"attributes,omitempty"`
+	}
+	statusJSON struct {
+		Code    Status `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	keyValue struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	}
+)
+
+func encodeRequest(resource map[string]string, spans []*SpanData) *exportRequest {
+	keys := make([]string, 0, len(resource))
+	for key := range resource {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var rs resourceSpans
+	for _, key := range keys {
+		rs.Resource.Attributes = append(rs.Resource.Attributes, encodeAttribute(Attribute{key, resource[key]}))
+	}
+	var ss scopeSpans
+	ss.Scope.Name = scope
+	for _, span := range spans {
+		ss.Spans = append(ss.Spans, encodeSpan(span))
+	}
+	rs.ScopeSpans = []scopeSpans{ss}
+	return &exportRequest{ResourceSpans: []resourceSpans{rs}}
+}
+
+func encodeSpan(span *SpanData) spanJSON {
+	out := spanJSON{
+		TraceID:           span.TraceID.String(),
+		SpanID:            span.SpanID.String(),
+		TraceState:        span.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: unixNano(span.Start),
+		EndTimeUnixNano:   unixNano(span.End),
+		Status:            statusJSON{span.Status, span.StatusMessage},
+	}
+	if !span.Parent.IsZero() {
+		out.ParentSpanID = span.Parent.String()
+	}
+	for _, attr := range span.Attributes {
+		out.Attributes = append(out.Attributes, encodeAttribute(attr))
+	}
+	for _, event := range span.Events {
+		e := eventJSON{TimeUnixNano: unixNano(event.Time), Name: event.Name}
+		for _, attr := range event.Attributes {
+			e.Attributes = append(e.Attributes, encodeAttribute(attr))
+		}
+		out.Events = append(out.Events, e)
+	}
+	return out
+}
+
+func encodeAttribute(attr Attribute) keyValue {
+	var value map[string]interface{}
+	switch v := attr.Value.(type) {
+	case string:
+		value = map[string]interface{}{"stringValue": v}
+	case bool:
+		value = map[string]interface{}{"boolValue": v}
+	case int:
+		value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+	case int64:
+		value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		value = map[string]interface{}{"doubleValue": v}
+	default:
+		value = map[string]interface{}{"stringValue": fmt.Sprint(v)}
+	}
+	return keyValue{attr.Key, value}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
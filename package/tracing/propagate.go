@@ -0,0 +1,201 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// IsZero is true for the invalid all-zero ID
+func (id TraceID) IsZero() bool {
+	return id == TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// IsZero is true for the invalid all-zero ID
+func (id SpanID) IsZero() bool {
+	return id == SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// SpanContext is the part of a span that's passed between services in the
+// W3C traceparent and tracestate headers
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string // Passed through as-is
+	Remote     bool   // True if the span came from another service
+}
+
+// IsValid is true if the trace and span IDs are set
+func (sc SpanContext) IsValid() bool {
+	return !sc.TraceID.IsZero() && !sc.SpanID.IsZero()
+}
+
+// TraceParent formats the span context as a traceparent header, like
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func (sc SpanContext) TraceParent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceParent parses a traceparent header
+func ParseTraceParent(header string) (sc SpanContext, err error) {
+	header = strings.TrimSpace(header)
+	parts := strings.Split(header, "-")
+	if len(parts) < 4 {
+		return sc, fmt.Errorf("tracing: invalid traceparent %q", header)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	// Future versions may add fields, but version 00 has exactly four
+	if len(version) != 2 || !isLowerHex(version) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return sc, fmt.Errorf("tracing: invalid traceparent version in %q", header)
+	}
+	if len(traceID) != 32 || !isLowerHex(traceID) {
+		return sc, fmt.Errorf("tracing: invalid trace id in %q", header)
+	}
+	if len(spanID) != 16 || !isLowerHex(spanID) {
+		return sc, fmt.Errorf("tracing: invalid span id in %q", header)
+	}
+	if len(flags) != 2 || !isLowerHex(flags) {
+		return sc, fmt.Errorf("tracing: invalid trace flags in %q", header)
+	}
+	hex.Decode(sc.TraceID[:], []byte(traceID))
+	hex.Decode(sc.SpanID[:], []byte(spanID))
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("tracing: invalid traceparent %q. The ids can't be all zeros", header)
+	}
+	flag, _ := strconv.ParseUint(flags, 16, 8)
+	sc.Sampled = flag&1 == 1
+	sc.Remote = true
+	return sc, nil
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Inject the span in the context into the headers of an outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	span := FromContext(ctx)
+	if span == nil {
+		return
+	}
+	header.Set("traceparent", span.context.TraceParent())
+	if span.context.TraceState != "" {
+		header.Set("tracestate", span.context.TraceState)
+	}
+}
+
+// Extract the remote parent from the headers of an incoming request. Invalid
+// headers are ignored, which starts a new trace.
+func Extract(header http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceParent(header.Get("traceparent"))
+	if err != nil {
+		return SpanContext{}, false
+	}
+	sc.TraceState = strings.Join(header.Values("tracestate"), ",")
+	return sc, true
+}
+
+// Sampler decides whether to record a new trace or a trace that continues
+// from another service
+type Sampler func(parent SpanContext, traceID TraceID) bool
+
+// AlwaysOn records every trace
+func AlwaysOn(SpanContext, TraceID) bool {
+	return true
+}
+
+// AlwaysOff records no traces
+func AlwaysOff(SpanContext, TraceID) bool {
+	return false
+}
+
+// Ratio records a fraction of traces, based on their trace ID so that every
+// service makes the same decision
+func Ratio(fraction float64) Sampler {
+	if fraction >= 1 {
+		return AlwaysOn
+	}
+	if fraction <= 0 {
+		return AlwaysOff
+	}
+	bound := uint64(fraction * (1 << 63))
+	return func(_ SpanContext, traceID TraceID) bool {
+		return binary.BigEndian.Uint64(traceID[8:])>>1 < bound
+	}
+}
+
+// ParentBased follows the parent's decision and uses root for new traces
+func ParentBased(root Sampler) Sampler {
+	return func(parent SpanContext, traceID TraceID) bool {
+		if parent.IsValid() {
+			return parent.Sampled
+		}
+		return root(parent, traceID)
+	}
+}
+
+// loadSampler loads the sampler named by $OTEL_TRACES_SAMPLER
+func loadSampler(name, arg string) (Sampler, error) {
+	ratio := func() (float64, error) {
+		if arg == "" {
+			return 1, nil
+		}
+		fraction, err := strconv.ParseFloat(arg, 64)
+		if err != nil || fraction < 0 || fraction > 1 {
+			return 0, fmt.Errorf("tracing: invalid OTEL_TRACES_SAMPLER_ARG %q. Expected a number from 0 to 1", arg)
+		}
+		return fraction, nil
+	}
+	switch name {
+	case "", "parentbased_always_on":
+		return ParentBased(AlwaysOn), nil
+	case "parentbased_always_off":
+		return ParentBased(AlwaysOff), nil
+	case "always_on":
+		return AlwaysOn, nil
+	case "always_off":
+		return AlwaysOff, nil
+	case "traceidratio":
+		fraction, err := ratio()
+		if err != nil {
+			return nil, err
+		}
+		return Ratio(fraction), nil
+	case "parentbased_traceidratio":
+		fraction, err := ratio()
+		if err != nil {
+			return nil, err
+		}
+		return ParentBased(Ratio(fraction)), nil
+	default:
+		return nil, fmt.Errorf("tracing: unsupported OTEL_TRACES_SAMPLER %q", name)
+	}
+}
@@ -0,0 +1,529 @@
+// Package tracing records distributed traces of requests and exports them to
+// an OpenTelemetry collector over OTLP. Tracing is off until an endpoint is
+// configured:
+//
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318
+//	OTEL_EXPORTER_OTLP_HEADERS=x-honeycomb-team=abc123
+//	OTEL_SERVICE_NAME=blog
+//
+// The middleware starts a server span for each request, continuing the trace
+// in the request's traceparent header. Code that serves the request starts
+// child spans with Start:
+//
+//	ctx, span := tracing.Start(ctx, "charge card")
+//	defer span.End()
+//
+// Spans are exported in batches in the background. Traces are sent as JSON,
+// so OTEL_EXPORTER_OTLP_PROTOCOL must be http/json when it's set.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/package/log"
+)
+
+// Load the tracer from the environment. The tracer is disabled when there's no
+// endpoint to export to.
+func Load(log log.Interface) (*Tracer, error) {
+	config, err := LoadConfig(os.Getenv)
+	if err != nil {
+		return nil, err
+	}
+	if config.Endpoint == "" {
+		return New(nil, config, log), nil
+	}
+	exporter := &OTLP{
+		Endpoint: config.Endpoint,
+		Headers:  config.Headers,
+		Timeout:  config.Timeout,
+	}
+	return New(exporter, config, log), nil
+}
+
+// Config for the tracer
+type Config struct {
+	// Endpoint that receives the traces, like http://localhost:4318/v1/traces.
+	// Empty turns tracing off.
+	Endpoint string
+	// Headers sent with each export, like API keys
+	Headers map[string]string
+	// Timeout of each export
+	Timeout time.Duration
+	// Resource attributes that describe the app, like service.name
+	Resource map[string]string
+	// Sampler decides which traces are recorded. Defaults to recording every
+	// trace that isn't turned off by its parent.
+	Sampler Sampler
+}
+
+// LoadConfig reads the configuration from the standard OpenTelemetry
+// environment variables:
+//
+//	OTEL_SDK_DISABLED=false
+//	OTEL_TRACES_EXPORTER=otlp
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT=http://localhost:4318/v1/traces
+//	OTEL_EXPORTER_OTLP_HEADERS=key=value,key2=value2
+//	OTEL_EXPORTER_OTLP_TIMEOUT=10000
+//	OTEL_EXPORTER_OTLP_PROTOCOL=http/json
+//	OTEL_SERVICE_NAME=blog
+//	OTEL_RESOURCE_ATTRIBUTES=deployment.environment=production
+//	OTEL_TRACES_SAMPLER=parentbased_traceidratio
+//	OTEL_TRACES_SAMPLER_ARG=0.25
+//
+// The TRACES variants of the endpoint, headers, timeout and protocol take
+// precedence over the general ones.
+func LoadConfig(getenv func(key string) string) (*Config, error) {
+	config := &Config{
+		Timeout:  10 * time.Second,
+		Resource: map[string]string{},
+	}
+	if disabled, _ := strconv.ParseBool(getenv("OTEL_SDK_DISABLED")); disabled {
+		return config, nil
+	}
+	switch exporter := getenv("OTEL_TRACES_EXPORTER"); exporter {
+	case "", "otlp":
+	case "none":
+		return config, nil
+	default:
+		return nil, fmt.Errorf("tracing: unsupported OTEL_TRACES_EXPORTER %q. Expected otlp or none", exporter)
+	}
+	if endpoint := getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		config.Endpoint = endpoint
+	} else if endpoint := getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		config.Endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	protocol := firstOf(getenv, "OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "OTEL_EXPORTER_OTLP_PROTOCOL")
+	if protocol != "" && protocol != "http/json" {
+		return nil, fmt.Errorf("tracing: unsupported OTLP protocol %q. Expected http/json", protocol)
+	}
+	headers, err := parseList(firstOf(getenv, "OTEL_EXPORTER_OTLP_TRACES_HEADERS", "OTEL_EXPORTER_OTLP_HEADERS"))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: invalid OTLP headers. %w", err)
+	}
+	config.Headers = headers
+	if timeout := firstOf(getenv, "OTEL_EXPORTER_OTLP_TRACES_TIMEOUT", "OTEL_EXPORTER_OTLP_TIMEOUT"); timeout != "" {
+		ms, err := strconv.Atoi(timeout)
+		if err != nil || ms <= 0 {
+			return nil, fmt.Errorf("tracing: invalid OTLP timeout %q. Expected milliseconds", timeout)
+		}
+		config.Timeout = time.Duration(ms) * time.Millisecond
+	}
+	resource, err := parseList(getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("tracing: invalid OTEL_RESOURCE_ATTRIBUTES. %w", err)
+	}
+	config.Resource = resource
+	if name := getenv("OTEL_SERVICE_NAME"); name != "" {
+		config.Resource["service.name"] = name
+	} else if config.Resource["service.name"] == "" {
+		config.Resource["service.name"] = "unknown_service:" + filepath.Base(os.Args[0])
+	}
+	sampler, err := loadSampler(getenv("OTEL_TRACES_SAMPLER"), getenv("OTEL_TRACES_SAMPLER_ARG"))
+	if err != nil {
+		return nil, err
+	}
+	config.Sampler = sampler
+	return config, nil
+}
+
+func firstOf(getenv func(key string) string, keys ...string) string {
+	for _, key := range keys {
+		if value := getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseList parses a list like key=value,key2=value2 with URL-encoded values
+func parseList(list string) (map[string]string, error) {
+	entries := map[string]string{}
+	for _, entry := range strings.Split(list, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected key=value but got %q", entry)
+		}
+		value, err := url.PathUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		entries[strings.TrimSpace(key)] = value
+	}
+	return entries, nil
+}
+
+// Exporter sends finished spans to a tracing backend
+type Exporter interface {
+	Export(ctx context.Context, resource map[string]string, spans []*SpanData) error
+}
+
+// New tracer that exports to exporter. A nil exporter disables tracing.
+func New(exporter Exporter, config *Config, log log.Interface) *Tracer {
+	sampler := config.Sampler
+	if sampler == nil {
+		sampler = ParentBased(AlwaysOn)
+	}
+	return &Tracer{
+		Sampler:   sampler,
+		BatchSize: 512,
+		MaxQueue:  2048,
+		Interval:  5 * time.Second,
+		exporter:  exporter,
+		resource:  config.Resource,
+		log:       log.Named("tracing"),
+		flush:     make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Tracer starts traces and exports the spans once they end
+type Tracer struct {
+	Sampler   Sampler
+	BatchSize int           // Spans sent in each export
+	MaxQueue  int           // Spans waiting to be exported before new spans are dropped
+	Interval  time.Duration // How often spans are exported
+
+	exporter Exporter
+	resource map[string]string
+	log      log.Interface
+
+	mu      sync.Mutex
+	queue   []*SpanData
+	dropped int
+	flush   chan struct{}
+	stop    chan struct{}
+	stopped sync.Once
+	done    chan struct{}
+}
+
+// Enabled is true if the tracer exports spans
+func (t *Tracer) Enabled() bool {
+	return t.exporter != nil
+}
+
+// Start exporting spans in the background
+func (t *Tracer) Start(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+	t.done = make(chan struct{})
+	go t.run()
+	return nil
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(t.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		case <-t.flush:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), t.Interval)
+		if err := t.Flush(ctx); err != nil {
+			t.log.Error(err.Error())
+		}
+		cancel()
+	}
+}
+
+// Stop exporting in the background and export the remaining spans
+func (t *Tracer) Stop(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+	t.stopped.Do(func() { close(t.stop) })
+	if t.done != nil {
+		<-t.done
+	}
+	return t.Flush(ctx)
+}
+
+// Flush exports the spans that have ended
+func (t *Tracer) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	queue, dropped := t.queue, t.dropped
+	t.queue, t.dropped = nil, 0
+	t.mu.Unlock()
+	if dropped > 0 {
+		t.log.Warn("tracing: dropped spans because the export queue was full", "dropped", dropped)
+	}
+	for len(queue) > 0 {
+		n := len(queue)
+		if n > t.BatchSize {
+			n = t.BatchSize
+		}
+		if err := t.exporter.Export(ctx, t.resource, queue[:n]); err != nil {
+			return fmt.Errorf("tracing: unable to export %d spans. %w", len(queue), err)
+		}
+		queue = queue[n:]
+	}
+	return nil
+}
+
+// enqueue a span to export, flushing early once there's a full batch
+func (t *Tracer) enqueue(data *SpanData) {
+	t.mu.Lock()
+	if len(t.queue) >= t.MaxQueue {
+		t.dropped++
+		t.mu.Unlock()
+		return
+	}
+	t.queue = append(t.queue, data)
+	full := len(t.queue) >= t.BatchSize
+	t.mu.Unlock()
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// start a span. Spans with an in-process parent share its sampling decision,
+// while spans with a remote parent or no parent are sampled by the tracer.
+func (t *Tracer) start(ctx context.Context, name string, kind Kind, parent SpanContext) (context.Context, *Span) {
+	sc := SpanContext{
+		TraceID:    parent.TraceID,
+		TraceState: parent.TraceState,
+		Sampled:    parent.Sampled,
+	}
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		sc.TraceState = ""
+	}
+	if !parent.IsValid() || parent.Remote {
+		sc.Sampled = t.Sampler(parent, sc.TraceID)
+	}
+	sc.SpanID = newSpanID()
+	span := &Span{
+		tracer:  t,
+		context: sc,
+		data: SpanData{
+			Name:       name,
+			Kind:       kind,
+			TraceID:    sc.TraceID,
+			SpanID:     sc.SpanID,
+			TraceState: sc.TraceState,
+			Start:      time.Now(),
+		},
+	}
+	if parent.IsValid() {
+		span.data.Parent = parent.SpanID
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+type spanKey struct{}
+
+// FromContext returns the span in the context or nil if there isn't one
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start a child span of the span in the context. When the context doesn't
+// have a span, like when tracing is off, Start returns a nil span, which is
+// safe to use.
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return startChild(ctx, name, Internal)
+}
+
+// StartClient starts a child span for a call to another service, like a query
+// to the database or an HTTP request
+func StartClient(ctx context.Context, name string) (context.Context, *Span) {
+	return startChild(ctx, name, Client)
+}
+
+func startChild(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.start(ctx, name, kind, parent.context)
+}
+
+// Kind of span. The values match OTLP.
+type Kind int
+
+const (
+	Internal Kind = 1
+	Server   Kind = 2
+	Client   Kind = 3
+)
+
+// Status of a span. The values match OTLP.
+type Status int
+
+const (
+	Unset Status = 0
+	OK    Status = 1
+	Error Status = 2
+)
+
+// Span is an operation within a trace. The methods of a nil span do nothing.
+type Span struct {
+	tracer  *Tracer
+	context SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// SpanData is a span that has ended
+type SpanData struct {
+	Name          string
+	Kind          Kind
+	TraceID       TraceID
+	SpanID        SpanID
+	Parent        SpanID // Zero for the root span
+	TraceState    string
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	Events        []Event
+	Status        Status
+	StatusMessage string
+}
+
+// Attribute of a span or event. Values are strings, bools, ints or floats.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Event that happened during a span, like an error
+type Event struct {
+	Name       string
+	Time       time.Time
+	Attributes []Attribute
+}
+
+// SpanContext returns the IDs that are passed to other services
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetName renames the span, like when the name is only known at the end
+func (s *Span) SetName(name string) {
+	if s == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttribute sets an attribute of the span, like http.route
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, attr := range s.data.Attributes {
+		if attr.Key == key {
+			s.data.Attributes[i].Value = value
+			return
+		}
+	}
+	s.data.Attributes = append(s.data.Attributes, Attribute{key, value})
+}
+
+// SetStatus sets the status of the span
+func (s *Span) SetStatus(status Status, message string) {
+	if s == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = status
+	s.data.StatusMessage = message
+}
+
+// RecordError marks the span as failed and records the error as an exception
+// event. Nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil || !s.context.Sampled {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Status = Error
+	s.data.StatusMessage = err.Error()
+	s.data.Events = append(s.data.Events, Event{
+		Name: "exception",
+		Time: time.Now(),
+		Attributes: []Attribute{
+			{"exception.type", errorType(err)},
+			{"exception.message", err.Error()},
+		},
+	})
+}
+
+// End the span. Sampled spans are queued to be exported.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+	if !s.context.Sampled || !s.tracer.Enabled() {
+		return
+	}
+	s.tracer.enqueue(&data)
+}
+
+// errorType returns the type of the innermost error, like *fs.PathError
+func errorType(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = inner
+	}
+}
+
+func newTraceID() (id TraceID) {
+	for id.IsZero() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	for id.IsZero() {
+		rand.Read(id[:])
+	}
+	return id
+}
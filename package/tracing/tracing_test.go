@@ -0,0 +1,295 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log/testlog"
+	"github.com/livebud/bud/package/router"
+	"github.com/livebud/bud/package/tracing"
+)
+
+// recorder exports spans to memory
+type recorder struct {
+	mu    sync.Mutex
+	spans []*tracing.SpanData
+}
+
+func (r *recorder) Export(ctx context.Context, resource map[string]string, spans []*tracing.SpanData) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func (r *recorder) Find(name string) *tracing.SpanData {
+	for _, span := range r.spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func attribute(span *tracing.SpanData, key string) interface{} {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return nil
+}
+
+func TestParseTraceParent(t *testing.T) {
+	is := is.New(t)
+	sc, err := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	is.NoErr(err)
+	is.Equal(sc.TraceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.Equal(sc.SpanID.String(), "00f067aa0ba902b7")
+	is.True(sc.Sampled)
+	is.True(sc.Remote)
+	is.Equal(sc.TraceParent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	sc, err = tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	is.NoErr(err)
+	is.True(!sc.Sampled)
+	// Future versions may have more fields
+	_, err = tracing.ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	is.NoErr(err)
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, err := tracing.ParseTraceParent(header)
+		is.True(err != nil)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	is := is.New(t)
+	exporter := new(recorder)
+	tracer := tracing.New(exporter, &tracing.Config{}, testlog.New())
+	rt := router.New()
+	rt.Get("/posts/:id", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, span := tracing.Start(r.Context(), "load post")
+		span.SetAttribute("post.id", 10)
+		span.RecordError(errors.New("not found"))
+		span.End()
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/posts/10", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("tracestate", "vendor=1")
+	tracer.Middleware(rt).ServeHTTP(httptest.NewRecorder(), req)
+	is.NoErr(tracer.Stop(context.Background()))
+	is.Equal(len(exporter.spans), 2)
+	server := exporter.Find("GET /posts/:id")
+	is.True(server != nil)
+	is.Equal(server.Kind, tracing.Server)
+	is.Equal(server.TraceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.Equal(server.Parent.String(), "00f067aa0ba902b7")
+	is.Equal(server.TraceState, "vendor=1")
+	is.Equal(server.Status, tracing.Unset)
+	is.Equal(attribute(server, "http.route"), "/posts/:id")
+	is.Equal(attribute(server, "http.response.status_code"), 404)
+	child := exporter.Find("load post")
+	is.True(child != nil)
+	is.Equal(child.Kind, tracing.Internal)
+	is.Equal(child.TraceID, server.TraceID)
+	is.Equal(child.Parent, server.SpanID)
+	is.Equal(child.Status, tracing.Error)
+	is.Equal(child.StatusMessage, "not found")
+	is.Equal(attribute(child, "post.id"), 10)
+	is.Equal(len(child.Events), 1)
+	is.Equal(child.Events[0].Name, "exception")
+}
+
+func TestUnsampled(t *testing.T) {
+	is := is.New(t)
+	exporter := new(recorder)
+	tracer := tracing.New(exporter, &tracing.Config{}, testlog.New())
+	var traceparent string
+	handler := tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "child")
+		defer span.End()
+		header := http.Header{}
+		tracing.Inject(ctx, header)
+		traceparent = header.Get("traceparent")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	is.NoErr(tracer.Stop(context.Background()))
+	// The trace is still passed along, but nothing is recorded
+	is.Equal(len(exporter.spans), 0)
+	sc, err := tracing.ParseTraceParent(traceparent)
+	is.NoErr(err)
+	is.Equal(sc.TraceID.String(), "4bf92f3577b34da6a3ce929d0e0e4736")
+	is.True(!sc.Sampled)
+}
+
+func TestDisabled(t *testing.T) {
+	is := is.New(t)
+	tracer := tracing.New(nil, &tracing.Config{}, testlog.New())
+	is.True(!tracer.Enabled())
+	called := false
+	handler := tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "child")
+		is.True(span == nil)
+		is.True(tracing.FromContext(ctx) == nil)
+		span.SetAttribute("key", "value")
+		span.RecordError(errors.New("oops"))
+		span.End()
+		called = true
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	is.True(called)
+	is.NoErr(tracer.Stop(context.Background()))
+}
+
+func TestTransport(t *testing.T) {
+	is := is.New(t)
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer upstream.Close()
+	exporter := new(recorder)
+	tracer := tracing.New(exporter, &tracing.Config{}, testlog.New())
+	client := &http.Client{Transport: tracing.Transport(nil)}
+	handler := tracer.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL+"/charge?key=secret", nil)
+		is.NoErr(err)
+		res, err := client.Do(req)
+		is.NoErr(err)
+		res.Body.Close()
+		// The request isn't modified
+		is.Equal(req.Header.Get("traceparent"), "")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	is.NoErr(tracer.Stop(context.Background()))
+	is.Equal(len(exporter.spans), 2)
+	server, outgoing := exporter.Find("POST"), exporter.Find("GET")
+	is.Equal(outgoing.Kind, tracing.Client)
+	is.Equal(outgoing.Parent, server.SpanID)
+	is.Equal(outgoing.Status, tracing.Error)
+	is.Equal(attribute(outgoing, "url.full"), upstream.URL+"/charge")
+	is.Equal(attribute(outgoing, "http.response.status_code"), 502)
+	sc, err := tracing.ParseTraceParent(traceparent)
+	is.NoErr(err)
+	is.Equal(sc.TraceID, outgoing.TraceID)
+	is.Equal(sc.SpanID, outgoing.SpanID)
+	// Requests outside of a trace pass through
+	res, err := client.Get(upstream.URL)
+	is.NoErr(err)
+	res.Body.Close()
+	is.Equal(traceparent, "")
+}
+
+func TestOTLP(t *testing.T) {
+	is := is.New(t)
+	var body map[string]interface{}
+	var header http.Header
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Path, "/v1/traces")
+		header = r.Header
+		data, err := io.ReadAll(r.Body)
+		is.NoErr(err)
+		is.NoErr(json.Unmarshal(data, &body))
+	}))
+	defer collector.Close()
+	config, err := tracing.LoadConfig(func(key string) string {
+		return map[string]string{
+			"OTEL_EXPORTER_OTLP_ENDPOINT": collector.URL,
+			"OTEL_EXPORTER_OTLP_HEADERS":  "x-api-key=abc%20123",
+			"OTEL_SERVICE_NAME":           "blog",
+			"OTEL_RESOURCE_ATTRIBUTES":    "deployment.environment=production",
+		}[key]
+	})
+	is.NoErr(err)
+	is.Equal(config.Endpoint, collector.URL+"/v1/traces")
+	exporter := &tracing.OTLP{Endpoint: config.Endpoint, Headers: config.Headers}
+	tracer := tracing.New(exporter, config, testlog.New())
+	is.NoErr(tracer.Start(context.Background()))
+	tracer.Middleware(http.NotFoundHandler()).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	is.NoErr(tracer.Stop(context.Background()))
+	is.Equal(header.Get("Content-Type"), "application/json")
+	is.Equal(header.Get("X-Api-Key"), "abc 123")
+	resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	resource, err := json.Marshal(resourceSpans["resource"])
+	is.NoErr(err)
+	is.Equal(string(resource), `{"attributes":[{"key":"deployment.environment","value":{"stringValue":"production"}},{"key":"service.name","value":{"stringValue":"blog"}}]}`)
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	span := scopeSpans["spans"].([]interface{})[0].(map[string]interface{})
+	is.Equal(span["name"], "GET")
+	is.Equal(span["kind"], 2.0)
+	is.Equal(len(span["traceId"].(string)), 32)
+	is.Equal(len(span["spanId"].(string)), 16)
+	_, hasParent := span["parentSpanId"]
+	is.True(!hasParent)
+}
+
+func TestLoadConfig(t *testing.T) {
+	is := is.New(t)
+	env := func(vars map[string]string) func(string) string {
+		return func(key string) string { return vars[key] }
+	}
+	// Off without an endpoint
+	config, err := tracing.LoadConfig(env(nil))
+	is.NoErr(err)
+	is.Equal(config.Endpoint, "")
+	// The traces endpoint is used as-is
+	config, err = tracing.LoadConfig(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT":        "http://localhost:4318",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://collector:4318/traces",
+		"OTEL_EXPORTER_OTLP_TIMEOUT":         "2500",
+	}))
+	is.NoErr(err)
+	is.Equal(config.Endpoint, "http://collector:4318/traces")
+	is.Equal(config.Timeout.String(), "2.5s")
+	// Disabled
+	config, err = tracing.LoadConfig(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318",
+		"OTEL_SDK_DISABLED":           "true",
+	}))
+	is.NoErr(err)
+	is.Equal(config.Endpoint, "")
+	// Only JSON is supported
+	_, err = tracing.LoadConfig(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318",
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "grpc",
+	}))
+	is.Equal(err.Error(), `tracing: unsupported OTLP protocol "grpc". Expected http/json`)
+	_, err = tracing.LoadConfig(env(map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318",
+		"OTEL_TRACES_SAMPLER":         "traceidratio",
+		"OTEL_TRACES_SAMPLER_ARG":     "2",
+	}))
+	is.Equal(err.Error(), `tracing: invalid OTEL_TRACES_SAMPLER_ARG "2". Expected a number from 0 to 1`)
+}
+
+func TestRatio(t *testing.T) {
+	is := is.New(t)
+	sampler := tracing.Ratio(0.5)
+	low := tracing.TraceID{15: 1}
+	high := tracing.TraceID{8: 0xff}
+	is.True(sampler(tracing.SpanContext{}, low))
+	is.True(!sampler(tracing.SpanContext{}, high))
+	is.True(tracing.Ratio(1)(tracing.SpanContext{}, high))
+	is.True(!tracing.Ratio(0)(tracing.SpanContext{}, low))
+	// Parents decide for their children
+	parent := tracing.SpanContext{TraceID: high, SpanID: tracing.SpanID{7: 1}, Sampled: true}
+	is.True(tracing.ParentBased(sampler)(parent, high))
+}
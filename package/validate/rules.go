@@ -0,0 +1,205 @@
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// builtin checks the value and returns a message when it's invalid. Errors are
+// reserved for invalid parameters.
+type builtin func(value reflect.Value, param string) (message string, err error)
+
+var builtins = map[string]builtin{
+	"required": required,
+	"min":      minimum,
+	"max":      maximum,
+	"len":      length,
+	"email":    email,
+	"url":      isURL,
+	"oneof":    oneOf,
+	"pattern":  pattern,
+}
+
+func indirect(value reflect.Value) reflect.Value {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return value
+		}
+		value = value.Elem()
+	}
+	return value
+}
+
+func isEmpty(value reflect.Value) bool {
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+func required(value reflect.Value, param string) (string, error) {
+	if isEmpty(value) {
+		return "is required", nil
+	}
+	return "", nil
+}
+
+// size returns the value's size and the unit it's measured in
+func size(value reflect.Value) (n float64, unit string, err error) {
+	value = indirect(value)
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters", nil
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), " items", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "", nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "", nil
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "", nil
+	default:
+		return 0, "", fmt.Errorf("unable to measure the size of %s", value.Type())
+	}
+}
+
+// quantity formats the limit with its unit, e.g. "1 character" or "3 items"
+func quantity(param, unit string) string {
+	if param == "1" {
+		unit = strings.TrimSuffix(unit, "s")
+	}
+	return param + unit
+}
+
+func parseLimit(rule, param string) (float64, error) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected %s to have a number, got %q", rule, param)
+	}
+	return n, nil
+}
+
+func minimum(value reflect.Value, param string) (string, error) {
+	limit, err := parseLimit("min", param)
+	if err != nil {
+		return "", err
+	}
+	n, unit, err := size(value)
+	if err != nil {
+		return "", err
+	}
+	if n < limit {
+		return "must be at least " + quantity(param, unit), nil
+	}
+	return "", nil
+}
+
+func maximum(value reflect.Value, param string) (string, error) {
+	limit, err := parseLimit("max", param)
+	if err != nil {
+		return "", err
+	}
+	n, unit, err := size(value)
+	if err != nil {
+		return "", err
+	}
+	if n > limit {
+		return "must be at most " + quantity(param, unit), nil
+	}
+	return "", nil
+}
+
+func length(value reflect.Value, param string) (string, error) {
+	limit, err := parseLimit("len", param)
+	if err != nil {
+		return "", err
+	}
+	n, unit, err := size(value)
+	if err != nil {
+		return "", err
+	}
+	if n != limit {
+		return "must be exactly " + quantity(param, unit), nil
+	}
+	return "", nil
+}
+
+func stringOf(rule string, value reflect.Value) (string, error) {
+	value = indirect(value)
+	if value.Kind() != reflect.String {
+		return "", fmt.Errorf("expected %s to be used on a string, got %s", rule, value.Type())
+	}
+	return value.String(), nil
+}
+
+func email(value reflect.Value, param string) (string, error) {
+	s, err := stringOf("email", value)
+	if err != nil {
+		return "", err
+	}
+	// Reject display names like "Alice <alice@livebud.com>"
+	address, err := mail.ParseAddress(s)
+	if err != nil || address.Address != s {
+		return "must be a valid email address", nil
+	}
+	return "", nil
+}
+
+func isURL(value reflect.Value, param string) (string, error) {
+	s, err := stringOf("url", value)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "must be a valid URL", nil
+	}
+	return "", nil
+}
+
+func oneOf(value reflect.Value, param string) (string, error) {
+	options := strings.Fields(param)
+	if len(options) == 0 {
+		return "", fmt.Errorf("expected oneof to have a list of options")
+	}
+	actual := fmt.Sprint(indirect(value).Interface())
+	for _, option := range options {
+		if actual == option {
+			return "", nil
+		}
+	}
+	return "must be one of " + strings.Join(options, ", "), nil
+}
+
+var patterns sync.Map
+
+func pattern(value reflect.Value, param string) (string, error) {
+	s, err := stringOf("pattern", value)
+	if err != nil {
+		return "", err
+	}
+	re, ok := patterns.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return "", fmt.Errorf("unable to compile pattern %q. %w", param, err)
+		}
+		re, _ = patterns.LoadOrStore(param, compiled)
+	}
+	if !re.(*regexp.Regexp).MatchString(s) {
+		return "is invalid", nil
+	}
+	return "", nil
+}
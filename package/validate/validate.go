@@ -0,0 +1,316 @@
+// Package validate checks structs against the rules in their validate tags:
+//
+//	type User struct {
+//		Name  string `json:"name" validate:"required,max=50"`
+//		Email string `json:"email" validate:"required,email"`
+//		Role  string `json:"role" validate:"oneof=admin member"`
+//	}
+//
+// Rules are separated by commas. The built-in rules are required, min, max,
+// len, email, url, oneof and pattern. Since patterns may contain commas,
+// pattern must be the last rule. Add your own rules with Register.
+//
+// Types can also implement Validator to check rules that span fields.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/matthewmueller/gotext"
+)
+
+// Func checks a field's value against the rule's parameter. The parameter is
+// empty when the rule doesn't have one. The returned error's message is shown
+// to the user, prefixed with the field name.
+type Func func(value interface{}, param string) error
+
+// Validator is implemented by types that validate themselves. Validate may
+// return Errors to attach messages to specific fields.
+type Validator interface {
+	Validate() error
+}
+
+var (
+	mu    sync.RWMutex
+	funcs = map[string]Func{}
+)
+
+// Register a custom rule. Registering a built-in rule overrides it.
+func Register(name string, fn Func) {
+	mu.Lock()
+	defer mu.Unlock()
+	funcs[name] = fn
+}
+
+func lookup(name string) (Func, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	fn, ok := funcs[name]
+	return fn, ok
+}
+
+// Struct validates v, which must be a struct or a pointer to a struct. Nested
+// structs are validated too, with their field names joined by dots (e.g.
+// "address.city"). Validation failures are returned as Errors. Any other error
+// means a tag is invalid.
+func Struct(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("validate: expected a struct, got %s", rv.Type())
+	}
+	var errs Errors
+	if err := validateStruct(&errs, rv, "", true); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateStruct validates the fields of rv. When self is true, rv's Validate
+// method is called too. Embedded structs don't call Validate, because their
+// method is promoted to the parent.
+func validateStruct(errs *Errors, rv reflect.Value, prefix string, self bool) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := rv.Field(i)
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		// Embedded structs share their parent's namespace
+		if !field.Anonymous {
+			name = prefix + name
+		} else {
+			name = strings.TrimSuffix(prefix, ".")
+		}
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			rules, err := parseTag(tag)
+			if err != nil {
+				return fmt.Errorf("validate: invalid tag on %s.%s. %w", rt.Name(), field.Name, err)
+			}
+			failed, err := checkRules(rules, value)
+			if err != nil {
+				return fmt.Errorf("validate: unable to validate %s.%s. %w", rt.Name(), field.Name, err)
+			}
+			if failed != nil {
+				failed.Field = name
+				*errs = append(*errs, failed)
+				// Skip nested validation when the field itself is invalid
+				continue
+			}
+		}
+		if err := validateNested(errs, value, name, !field.Anonymous); err != nil {
+			return err
+		}
+	}
+	if !self {
+		return nil
+	}
+	return validateSelf(errs, rv, strings.TrimSuffix(prefix, "."))
+}
+
+func validateNested(errs *Errors, value reflect.Value, name string, self bool) error {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	prefix := ""
+	if name != "" {
+		prefix = name + "."
+	}
+	return validateStruct(errs, value, prefix, self)
+}
+
+// validateSelf calls Validate on types that implement Validator
+func validateSelf(errs *Errors, rv reflect.Value, name string) error {
+	var validator Validator
+	if rv.CanAddr() {
+		validator, _ = rv.Addr().Interface().(Validator)
+	}
+	if validator == nil {
+		validator, _ = rv.Interface().(Validator)
+	}
+	if validator == nil {
+		return nil
+	}
+	err := validator.Validate()
+	if err == nil {
+		return nil
+	}
+	var fieldErrs Errors
+	if errors.As(err, &fieldErrs) {
+		for _, fieldErr := range fieldErrs {
+			if name != "" {
+				fieldErr.Field = joinField(name, fieldErr.Field)
+			}
+			*errs = append(*errs, fieldErr)
+		}
+		return nil
+	}
+	*errs = append(*errs, &FieldError{Field: name, Message: err.Error()})
+	return nil
+}
+
+func joinField(prefix, field string) string {
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
+
+// fieldName returns the name used in error messages, preferring the JSON name
+func fieldName(field reflect.StructField) string {
+	if tag, ok := field.Tag.Lookup("json"); ok {
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return gotext.Lower(gotext.Snake(field.Name))
+}
+
+type rule struct {
+	Name  string
+	Param string
+}
+
+func parseTag(tag string) (rules []*rule, err error) {
+	for tag != "" {
+		var part string
+		// Patterns may contain commas, so they take the rest of the tag
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else if i := strings.IndexByte(tag, ','); i >= 0 {
+			part, tag = tag[:i], tag[i+1:]
+		} else {
+			part, tag = tag, ""
+		}
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r := new(rule)
+		if i := strings.IndexByte(part, '='); i >= 0 {
+			r.Name, r.Param = part[:i], part[i+1:]
+		} else {
+			r.Name = part
+		}
+		if _, ok := lookup(r.Name); !ok {
+			if _, ok := builtins[r.Name]; !ok {
+				return nil, fmt.Errorf("unknown rule %q", r.Name)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// checkRules returns the first rule that fails
+func checkRules(rules []*rule, value reflect.Value) (*FieldError, error) {
+	for _, r := range rules {
+		// Empty fields are only checked by required, so other rules are optional
+		if r.Name != "required" && isEmpty(value) {
+			return nil, nil
+		}
+		// Custom rules take precedence over built-in rules
+		if fn, ok := lookup(r.Name); ok {
+			if err := fn(value.Interface(), r.Param); err != nil {
+				return &FieldError{Rule: r.Name, Message: err.Error()}, nil
+			}
+			continue
+		}
+		message, err := builtins[r.Name](value, r.Param)
+		if err != nil {
+			return nil, err
+		}
+		if message != "" {
+			return &FieldError{Rule: r.Name, Message: message}, nil
+		}
+	}
+	return nil, nil
+}
+
+// FieldError is a validation failure on a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + " " + e.Message
+}
+
+// Errors are the validation failures for a struct
+type Errors []*FieldError
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Fields maps each field to its first error message. Errors that aren't
+// attached to a field are keyed by an empty string. This is what views receive
+// to render messages next to their inputs.
+func (errs Errors) Fields() map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, err := range errs {
+		if _, ok := fields[err.Field]; ok {
+			continue
+		}
+		fields[err.Field] = err.Message
+	}
+	return fields
+}
+
+// Has returns true if the field has an error
+func (errs Errors) Has(field string) bool {
+	for _, err := range errs {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns an error for a field. It's useful within Validate methods:
+//
+//	func (u *User) Validate() error {
+//		if u.Password != u.Confirm {
+//			return validate.Field("confirm", "must match the password")
+//		}
+//		return nil
+//	}
+func Field(name, message string) Errors {
+	return Errors{{Field: name, Message: message}}
+}
@@ -0,0 +1,138 @@
+package validate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/validate"
+)
+
+type User struct {
+	Name    string   `json:"name" validate:"required,min=2,max=10"`
+	Email   string   `json:"email" validate:"required,email"`
+	Age     int      `json:"age" validate:"min=18"`
+	Role    string   `json:"role" validate:"oneof=admin member"`
+	Website string   `json:"website" validate:"url"`
+	Zip     string   `validate:"pattern=^[0-9]{5}$"`
+	Tags    []string `json:"tags" validate:"max=2"`
+}
+
+func TestValid(t *testing.T) {
+	is := is.New(t)
+	user := &User{Name: "Alice", Email: "alice@livebud.com", Age: 30, Role: "admin"}
+	is.NoErr(validate.Struct(user))
+}
+
+func TestInvalid(t *testing.T) {
+	is := is.New(t)
+	user := &User{
+		Name:    "A",
+		Email:   "Alice <alice@livebud.com>",
+		Age:     12,
+		Role:    "owner",
+		Website: "livebud.com",
+		Zip:     "1234",
+		Tags:    []string{"a", "b", "c"},
+	}
+	err := validate.Struct(user)
+	is.True(err != nil)
+	var errs validate.Errors
+	is.True(errors.As(err, &errs))
+	is.Equal(errs.Fields(), map[string]string{
+		"name":    "must be at least 2 characters",
+		"email":   "must be a valid email address",
+		"age":     "must be at least 18",
+		"role":    "must be one of admin, member",
+		"website": "must be a valid URL",
+		"zip":     "is invalid",
+		"tags":    "must be at most 2 items",
+	})
+}
+
+func TestRequired(t *testing.T) {
+	is := is.New(t)
+	err := validate.Struct(&User{})
+	is.True(err != nil)
+	// Empty fields are only checked by required
+	is.Equal(err.Error(), "name is required, email is required")
+}
+
+func TestNested(t *testing.T) {
+	is := is.New(t)
+	type Address struct {
+		City string `json:"city" validate:"required"`
+	}
+	type Input struct {
+		Name    string   `json:"name" validate:"required"`
+		Address *Address `json:"address"`
+	}
+	err := validate.Struct(&Input{Name: "Alice", Address: &Address{}})
+	is.True(err != nil)
+	is.Equal(err.Error(), "address.city is required")
+	// Nil pointers are skipped
+	is.NoErr(validate.Struct(&Input{Name: "Alice"}))
+}
+
+type Signup struct {
+	Password string `json:"password" validate:"required"`
+	Confirm  string `json:"confirm"`
+}
+
+func (s *Signup) Validate() error {
+	if s.Password != s.Confirm {
+		return validate.Field("confirm", "must match the password")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	is := is.New(t)
+	err := validate.Struct(&Signup{Password: "secret", Confirm: "secert"})
+	is.True(err != nil)
+	is.Equal(err.Error(), "confirm must match the password")
+	is.NoErr(validate.Struct(&Signup{Password: "secret", Confirm: "secret"}))
+	// Nested validators are prefixed with the field name
+	type Input struct {
+		Signup *Signup `json:"signup"`
+	}
+	err = validate.Struct(&Input{&Signup{Password: "secret"}})
+	is.True(err != nil)
+	is.Equal(err.Error(), "signup.confirm must match the password")
+}
+
+func TestRegister(t *testing.T) {
+	is := is.New(t)
+	validate.Register("lowercase", func(value interface{}, param string) error {
+		s, _ := value.(string)
+		if s != strings.ToLower(s) {
+			return errors.New("must be lowercase")
+		}
+		return nil
+	})
+	type Input struct {
+		Slug string `json:"slug" validate:"required,lowercase"`
+	}
+	err := validate.Struct(&Input{Slug: "Hello"})
+	is.True(err != nil)
+	var errs validate.Errors
+	is.True(errors.As(err, &errs))
+	is.Equal(len(errs), 1)
+	is.Equal(errs[0].Field, "slug")
+	is.Equal(errs[0].Rule, "lowercase")
+	is.Equal(errs[0].Message, "must be lowercase")
+	is.NoErr(validate.Struct(&Input{Slug: "hello"}))
+}
+
+func TestInvalidTag(t *testing.T) {
+	is := is.New(t)
+	type Input struct {
+		Name string `validate:"requird"`
+	}
+	err := validate.Struct(&Input{})
+	is.True(err != nil)
+	is.Equal(err.Error(), `validate: invalid tag on Input.Name. unknown rule "requird"`)
+	var errs validate.Errors
+	is.True(!errors.As(err, &errs))
+}
@@ -0,0 +1,337 @@
+// Package vcr records the HTTP requests your app makes to other services and
+// replays them in tests, so tests of third-party integrations don't depend on
+// the network.
+//
+// The first time a test runs, requests are sent and their responses are
+// recorded to a cassette in testdata/cassettes. After that the responses are
+// replayed from the cassette. Run the tests with -update to record them again.
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/livebud/bud/package/golden"
+)
+
+// Redacted replaces secrets in cassettes
+const Redacted = "[REDACTED]"
+
+// ErrNoResponse is returned when replaying a request that wasn't recorded
+var ErrNoResponse = errors.New("vcr: no recorded response")
+
+// Headers that are redacted by default
+var defaultHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// Option configures the recorder
+type Option func(r *Recorder)
+
+// RedactHeaders redacts the values of these headers, along with
+// Authorization, Cookie, Proxy-Authorization, Set-Cookie and X-Api-Key
+func RedactHeaders(headers ...string) Option {
+	return func(r *Recorder) {
+		r.headers = append(r.headers, headers...)
+	}
+}
+
+// RedactQuery redacts the values of these query parameters, like
+// "api_key"
+func RedactQuery(params ...string) Option {
+	return func(r *Recorder) {
+		r.params = append(r.params, params...)
+	}
+}
+
+// RedactSecrets redacts the secrets wherever they appear in a request or
+// response, like an API key loaded from the environment. Empty secrets are
+// ignored.
+func RedactSecrets(secrets ...string) Option {
+	return func(r *Recorder) {
+		for _, secret := range secrets {
+			if secret != "" {
+				r.secrets = append(r.secrets, secret)
+			}
+		}
+	}
+}
+
+// Transport sends the requests that are recorded. Defaults to
+// http.DefaultTransport.
+func Transport(transport http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.transport = transport
+	}
+}
+
+// New recorder for the cassette in testdata/cassettes/<name>.json. It records
+// when the cassette doesn't exist or the tests are run with -update,
+// otherwise it replays. Recordings are saved when the test finishes.
+func New(t testing.TB, name string, options ...Option) *Recorder {
+	t.Helper()
+	r := &Recorder{
+		path:      filepath.Join("testdata", "cassettes", name+".json"),
+		transport: http.DefaultTransport,
+		headers:   defaultHeaders,
+		used:      map[int]bool{},
+	}
+	for _, option := range options {
+		option(r)
+	}
+	data, err := os.ReadFile(r.path)
+	switch {
+	case err == nil && !golden.Update():
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			t.Fatalf("vcr: unable to read the cassette %s. %s", r.path, err)
+		}
+	case err == nil || errors.Is(err, os.ErrNotExist):
+		r.recording = true
+		t.Cleanup(func() {
+			if err := r.save(); err != nil {
+				t.Errorf("vcr: unable to save the cassette %s. %s", r.path, err)
+			}
+		})
+	default:
+		t.Fatalf("vcr: unable to read the cassette %s. %s", r.path, err)
+	}
+	return r
+}
+
+// Recorder records and replays HTTP requests. It's safe for concurrent use.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+	headers   []string
+	params    []string
+	secrets   []string
+	recording bool
+
+	mu       sync.Mutex
+	cassette cassette
+	used     map[int]bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+type cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a request and its response
+type Interaction struct {
+	Request  *Request  `json:"request"`
+	Response *Response `json:"response"`
+}
+
+// Request that was recorded
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response that was recorded
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Recording reports whether requests are being recorded rather than replayed
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+// Client that records and replays its requests
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Install the recorder as http.DefaultTransport until the test finishes, so
+// requests made with http.DefaultClient by controllers and jobs running in
+// the test's process are recorded. Tests using Install can't run in
+// parallel.
+func (r *Recorder) Install(t testing.TB) {
+	original := http.DefaultTransport
+	http.DefaultTransport = r
+	t.Cleanup(func() { http.DefaultTransport = original })
+}
+
+// Interactions returns what's been recorded or replayed so far
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recording {
+		return append([]*Interaction{}, r.cassette.Interactions...)
+	}
+	var interactions []*Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			interactions = append(interactions, interaction)
+		}
+	}
+	return interactions
+}
+
+// RoundTrip records or replays the request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := &Request{
+		Method: req.Method,
+		URL:    r.redactURL(req.URL),
+		Header: r.redactHeader(req.Header),
+		Body:   r.redact(string(body)),
+	}
+	if !r.recording {
+		return r.replay(req, recorded)
+	}
+	res, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: recorded,
+		Response: &Response{
+			Status: res.StatusCode,
+			Header: r.redactHeader(res.Header),
+			Body:   r.redact(string(resBody)),
+		},
+	})
+	r.mu.Unlock()
+	return res, nil
+}
+
+// replay the first unused interaction that matches the request
+func (r *Recorder) replay(req *http.Request, recorded *Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !matches(interaction.Request, recorded) {
+			continue
+		}
+		r.used[i] = true
+		res := interaction.Response
+		header := res.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", res.Status, http.StatusText(res.Status)),
+			StatusCode:    res.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(res.Body)),
+			ContentLength: int64(len(res.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s in %s. Run the tests with -update to record it", ErrNoResponse, recorded.Method, recorded.URL, r.path)
+}
+
+// matches requests by their method, URL and body
+func matches(a, b *Request) bool {
+	return a.Method == b.Method && a.URL == b.URL && a.Body == b.Body
+}
+
+func (r *Recorder) save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// readBody reads the request's body and puts it back to be sent
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func (r *Recorder) redact(s string) string {
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	return s
+}
+
+func (r *Recorder) redactURL(u *url.URL) string {
+	if len(r.params) > 0 && u.RawQuery != "" {
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			key, _, _ := strings.Cut(pair, "=")
+			if name, err := url.QueryUnescape(key); err == nil && r.redactsParam(name) {
+				pairs[i] = key + "=" + Redacted
+			}
+		}
+		copy := *u
+		copy.RawQuery = strings.Join(pairs, "&")
+		u = &copy
+	}
+	return r.redact(u.String())
+}
+
+func (r *Recorder) redactsParam(name string) bool {
+	for _, param := range r.params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	redacted := http.Header{}
+	for key, values := range header {
+		redacted[key] = make([]string, len(values))
+		for i, value := range values {
+			redacted[key][i] = r.redact(value)
+		}
+	}
+	for _, key := range r.headers {
+		if redacted.Get(key) != "" {
+			redacted.Set(key, Redacted)
+		}
+	}
+	return redacted
+}
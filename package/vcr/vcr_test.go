@@ -0,0 +1,124 @@
+package vcr_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/golden"
+	"github.com/livebud/bud/package/vcr"
+)
+
+func get(client *http.Client, req *http.Request) (int, string, error) {
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, "", err
+	}
+	return res.StatusCode, string(body), nil
+}
+
+func TestReplay(t *testing.T) {
+	if golden.Update() {
+		t.Skip("the cassette isn't recorded from a real service")
+	}
+	is := is.New(t)
+	recorder := vcr.New(t, "replay", vcr.RedactQuery("api_key"))
+	is.True(!recorder.Recording())
+	client := recorder.Client()
+	req, _ := http.NewRequest("GET", "https://api.example.com/charges/1?api_key=sk_live_123", nil)
+	req.Header.Set("Authorization", "Bearer sk_live_123")
+	status, body, err := get(client, req)
+	is.NoErr(err)
+	is.Equal(status, 200)
+	is.Equal(body, `{"id":1,"amount":100}`)
+	// Identical requests are replayed in order
+	req, _ = http.NewRequest("POST", "https://api.example.com/charges", strings.NewReader("amount=200"))
+	_, body, err = get(client, req)
+	is.NoErr(err)
+	is.Equal(body, `{"id":2,"amount":200}`)
+	req, _ = http.NewRequest("POST", "https://api.example.com/charges", strings.NewReader("amount=200"))
+	status, body, err = get(client, req)
+	is.NoErr(err)
+	is.Equal(status, 201)
+	is.Equal(body, `{"id":3,"amount":200}`)
+	// Until they run out
+	req, _ = http.NewRequest("POST", "https://api.example.com/charges", strings.NewReader("amount=200"))
+	_, _, err = get(client, req)
+	is.True(errors.Is(err, vcr.ErrNoResponse))
+	is.Equal(len(recorder.Interactions()), 3)
+}
+
+func TestRecord(t *testing.T) {
+	is := is.New(t)
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc"})
+		fmt.Fprintf(w, "%s %s token=sk_test_456 %s", r.Method, r.URL.Path, body)
+	}))
+	defer server.Close()
+	name := "record-" + strings.ReplaceAll(t.Name(), "/", "-")
+	path := filepath.Join("testdata", "cassettes", name+".json")
+	os.Remove(path)
+	defer os.Remove(path)
+	t.Run("record", func(t *testing.T) {
+		is := is.New(t)
+		recorder := vcr.New(t, name, vcr.RedactSecrets("sk_test_456", ""), vcr.RedactHeaders("X-Secret"))
+		is.True(recorder.Recording())
+		recorder.Install(t)
+		req, _ := http.NewRequest("POST", server.URL+"/charges", strings.NewReader("key=sk_test_456"))
+		req.Header.Set("X-Secret", "shh")
+		status, body, err := get(http.DefaultClient, req)
+		is.NoErr(err)
+		is.Equal(status, 200)
+		// The app gets the real response
+		is.Equal(body, "POST /charges token=sk_test_456 key=sk_test_456")
+	})
+	is.Equal(calls, 1)
+	data, err := os.ReadFile(path)
+	is.NoErr(err)
+	is.True(!strings.Contains(string(data), "sk_test_456"))
+	is.True(!strings.Contains(string(data), "shh"))
+	is.True(!strings.Contains(string(data), "session=abc"))
+	var cassette struct {
+		Interactions []*vcr.Interaction
+	}
+	is.NoErr(json.Unmarshal(data, &cassette))
+	is.Equal(len(cassette.Interactions), 1)
+	is.Equal(cassette.Interactions[0].Request.Body, "key=[REDACTED]")
+	is.Equal(cassette.Interactions[0].Request.Header.Get("X-Secret"), "[REDACTED]")
+	is.Equal(cassette.Interactions[0].Response.Header.Get("Set-Cookie"), "[REDACTED]")
+	is.Equal(cassette.Interactions[0].Response.Body, "POST /charges token=[REDACTED] key=[REDACTED]")
+	if golden.Update() {
+		return
+	}
+	t.Run("replay", func(t *testing.T) {
+		is := is.New(t)
+		recorder := vcr.New(t, name, vcr.RedactSecrets("sk_test_456"))
+		is.True(!recorder.Recording())
+		req, _ := http.NewRequest("POST", server.URL+"/charges", strings.NewReader("key=sk_test_456"))
+		status, body, err := get(recorder.Client(), req)
+		is.NoErr(err)
+		is.Equal(status, 200)
+		is.Equal(body, "POST /charges token=[REDACTED] key=[REDACTED]")
+	})
+	is.Equal(calls, 1)
+	// Install restores the default transport
+	is.True(http.DefaultTransport != nil)
+	_, isRecorder := http.DefaultTransport.(*vcr.Recorder)
+	is.True(!isRecorder)
+}
@@ -0,0 +1,212 @@
+package vfs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Layer is a named filesystem within a MergeFS. The name is used to report
+// where a file came from, like the module path of a plugin.
+type Layer struct {
+	Name string
+	FS   fs.FS
+}
+
+// Merge the layers into one filesystem. Earlier layers take precedence, so a
+// file in the first layer shadows the same file in the layers after it.
+// Directories are merged, listing the entries of every layer.
+func Merge(layers ...*Layer) *MergeFS {
+	return &MergeFS{layers}
+}
+
+// MergeFS is a filesystem made up of layers
+type MergeFS struct {
+	layers []*Layer
+}
+
+var _ fs.ReadDirFS = (*MergeFS)(nil)
+var _ fs.StatFS = (*MergeFS)(nil)
+
+// Open the file from the first layer that has it
+func (m *MergeFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	var dir fs.FileInfo
+	for _, layer := range m.layers {
+		file, err := layer.FS.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if !info.IsDir() {
+			return file, nil
+		}
+		file.Close()
+		dir = info
+		break
+	}
+	if dir == nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	entries, err := m.ReadDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &mergeDir{info: dir, entries: entries}, nil
+}
+
+// Stat the file from the first layer that has it
+func (m *MergeFS) Stat(name string) (fs.FileInfo, error) {
+	for _, layer := range m.layers {
+		info, err := fs.Stat(layer.FS, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		return info, nil
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir lists the entries of the directory in every layer. When layers
+// share an entry, the earlier layer's entry is used.
+func (m *MergeFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := map[string]bool{}
+	var entries []fs.DirEntry
+	found := false
+	for _, layer := range m.layers {
+		des, err := fs.ReadDir(layer.FS, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			// The path is a file in this layer, so it has no entries to add
+			if _, err := fs.Stat(layer.FS, name); err == nil {
+				if !found {
+					return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+				}
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, de := range des {
+			if seen[de.Name()] {
+				continue
+			}
+			seen[de.Name()] = true
+			entries = append(entries, de)
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+	return entries, nil
+}
+
+// Which returns the name of the layer that provides the path
+func (m *MergeFS) Which(name string) (string, error) {
+	for _, layer := range m.layers {
+		if _, err := fs.Stat(layer.FS, name); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return "", err
+		}
+		return layer.Name, nil
+	}
+	return "", &fs.PathError{Op: "which", Path: name, Err: fs.ErrNotExist}
+}
+
+// Conflict is a file that more than one layer provides
+type Conflict struct {
+	Path   string
+	Layers []string // In order of precedence. The first layer's file is used.
+}
+
+func (c *Conflict) String() string {
+	return fmt.Sprintf("%s is provided by %s. Using the file from %s", c.Path, strings.Join(c.Layers, " and "), c.Layers[0])
+}
+
+// Conflicts returns the files within dir that more than one layer provides
+func (m *MergeFS) Conflicts(dir string) (conflicts []*Conflict, err error) {
+	err = fs.WalkDir(m, dir, func(fpath string, de fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && fpath == dir {
+				return fs.SkipDir
+			}
+			return err
+		}
+		if de.IsDir() {
+			return nil
+		}
+		var layers []string
+		for _, layer := range m.layers {
+			info, err := fs.Stat(layer.FS, fpath)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			layers = append(layers, layer.Name)
+		}
+		if len(layers) > 1 {
+			conflicts = append(conflicts, &Conflict{fpath, layers})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// mergeDir is a directory that lists the entries from every layer
+type mergeDir struct {
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *mergeDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *mergeDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+func (d *mergeDir) Close() error {
+	return nil
+}
+
+func (d *mergeDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	entries := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return entries, nil
+	}
+	if len(entries) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(entries) {
+		n = len(entries)
+	}
+	d.offset += n
+	return entries[:n], nil
+}
@@ -0,0 +1,102 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/vfs"
+)
+
+func TestMerge(t *testing.T) {
+	is := is.New(t)
+	app := vfs.Memory{
+		"public/favicon.ico": &vfs.File{Data: []byte("app favicon")},
+		"view/index.svelte":  &vfs.File{Data: []byte("<h1>index</h1>")},
+	}
+	plugin := vfs.Memory{
+		"public/favicon.ico":     &vfs.File{Data: []byte("plugin favicon")},
+		"public/normalize.css":   &vfs.File{Data: []byte("html {}")},
+		"public/fonts/inter.ttf": &vfs.File{Data: []byte("font")},
+	}
+	fsys := vfs.Merge(
+		&vfs.Layer{Name: "app.com", FS: app},
+		&vfs.Layer{Name: "github.com/a/bud-normalize", FS: plugin},
+	)
+	// Earlier layers take precedence
+	code, err := fs.ReadFile(fsys, "public/favicon.ico")
+	is.NoErr(err)
+	is.Equal(string(code), "app favicon")
+	code, err = fs.ReadFile(fsys, "public/normalize.css")
+	is.NoErr(err)
+	is.Equal(string(code), "html {}")
+	// Directories are merged
+	des, err := fs.ReadDir(fsys, "public")
+	is.NoErr(err)
+	is.Equal(len(des), 3)
+	is.Equal(des[0].Name(), "favicon.ico")
+	is.Equal(des[1].Name(), "fonts")
+	is.Equal(des[2].Name(), "normalize.css")
+	des, err = fs.ReadDir(fsys, ".")
+	is.NoErr(err)
+	is.Equal(len(des), 2)
+	// Missing files
+	_, err = fs.ReadFile(fsys, "public/missing.css")
+	is.True(errors.Is(err, fs.ErrNotExist))
+	// Which layer provides the file
+	layer, err := fsys.Which("public/favicon.ico")
+	is.NoErr(err)
+	is.Equal(layer, "app.com")
+	layer, err = fsys.Which("public/fonts/inter.ttf")
+	is.NoErr(err)
+	is.Equal(layer, "github.com/a/bud-normalize")
+	is.NoErr(fstest.TestFS(fsys, "public/favicon.ico", "public/normalize.css", "public/fonts/inter.ttf", "view/index.svelte"))
+}
+
+func TestMergeConflicts(t *testing.T) {
+	is := is.New(t)
+	fsys := vfs.Merge(
+		&vfs.Layer{Name: "app.com", FS: vfs.Memory{
+			"public/favicon.ico": &vfs.File{Data: []byte("app")},
+		}},
+		&vfs.Layer{Name: "github.com/a/bud-a", FS: vfs.Memory{
+			"public/favicon.ico": &vfs.File{Data: []byte("a")},
+			"public/a.css":       &vfs.File{Data: []byte("a")},
+			"public/shared.css":  &vfs.File{Data: []byte("a")},
+		}},
+		&vfs.Layer{Name: "github.com/b/bud-b", FS: vfs.Memory{
+			"public/shared.css": &vfs.File{Data: []byte("b")},
+		}},
+	)
+	conflicts, err := fsys.Conflicts("public")
+	is.NoErr(err)
+	is.Equal(len(conflicts), 2)
+	is.Equal(conflicts[0].Path, "public/favicon.ico")
+	is.Equal(conflicts[0].Layers, []string{"app.com", "github.com/a/bud-a"})
+	is.Equal(conflicts[1].Path, "public/shared.css")
+	is.Equal(conflicts[1].Layers, []string{"github.com/a/bud-a", "github.com/b/bud-b"})
+	is.Equal(conflicts[1].String(), "public/shared.css is provided by github.com/a/bud-a and github.com/b/bud-b. Using the file from github.com/a/bud-a")
+	// Missing directories have no conflicts
+	conflicts, err = fsys.Conflicts("view")
+	is.NoErr(err)
+	is.Equal(len(conflicts), 0)
+}
+
+func TestMergeFileShadowsDir(t *testing.T) {
+	is := is.New(t)
+	fsys := vfs.Merge(
+		&vfs.Layer{Name: "a", FS: vfs.Memory{
+			"public": &vfs.File{Data: []byte("a file")},
+		}},
+		&vfs.Layer{Name: "b", FS: vfs.Memory{
+			"public/a.css": &vfs.File{Data: []byte("a")},
+		}},
+	)
+	code, err := fs.ReadFile(fsys, "public")
+	is.NoErr(err)
+	is.Equal(string(code), "a file")
+	_, err = fs.ReadDir(fsys, "public")
+	is.True(err != nil)
+}
@@ -0,0 +1,186 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Reading directories is mostly waiting on the disk, so walk more directories
+// at once than there are CPUs.
+var walkers = runtime.NumCPU() * 4
+
+// ConcurrentFS is implemented by filesystems that can be read from multiple
+// goroutines at once.
+type ConcurrentFS interface {
+	fs.FS
+	Concurrent() bool
+}
+
+// concurrent returns true if fsys is safe to read from multiple goroutines
+func concurrent(fsys fs.FS) bool {
+	switch fsys := fsys.(type) {
+	case OS, Memory, Map:
+		return true
+	case ConcurrentFS:
+		return fsys.Concurrent()
+	}
+	return reflect.TypeOf(fsys) == reflect.TypeOf(os.DirFS(""))
+}
+
+// WalkParallel walks the file tree like WalkDir, but reads several directories
+// at once. It's used for large trees where most of the time is spent waiting
+// on the disk.
+//
+// Unlike WalkDir, fn may be called from multiple goroutines at once and in any
+// order. A directory is still passed to fn before it's read, so returning
+// fs.SkipDir prunes it early. Returning fs.SkipDir for a file is ignored. Use
+// SortWalk to put the walked paths back in the order WalkDir visits them.
+//
+// Filesystems that aren't safe to read concurrently are walked one directory
+// at a time.
+func WalkParallel(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	if !concurrent(fsys) || walkers <= 1 {
+		return WalkDir(fsys, root, func(path string, de fs.DirEntry, err error) error {
+			if err := fn(path, de, err); err != nil {
+				if err == fs.SkipDir && de != nil && !de.IsDir() {
+					return nil
+				}
+				return err
+			}
+			return nil
+		})
+	}
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		if err = fn(root, nil, err); err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+	w := &parallelWalk{
+		fsys: fsys,
+		fn:   fn,
+		sem:  make(chan struct{}, walkers-1),
+	}
+	w.walk(root, fs.FileInfoToDirEntry(info), nil, 0)
+	w.wg.Wait()
+	return w.err
+}
+
+type parallelWalk struct {
+	fsys fs.FS
+	fn   fs.WalkDirFunc
+	sem  chan struct{} // Limits the extra goroutines
+	wg   sync.WaitGroup
+
+	mu  sync.Mutex
+	err error // First error stops the walk
+}
+
+func (w *parallelWalk) fail(err error) {
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+}
+
+func (w *parallelWalk) failed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err != nil
+}
+
+// call fn, returning false if the walk shouldn't go any further
+func (w *parallelWalk) call(name string, de fs.DirEntry, err error) bool {
+	if err := w.fn(name, de, err); err != nil {
+		if err != fs.SkipDir {
+			w.fail(err)
+		}
+		return false
+	}
+	return true
+}
+
+func (w *parallelWalk) walk(name string, de fs.DirEntry, parents []fs.FileInfo, links int) {
+	if w.failed() || !w.call(name, de, nil) || !de.IsDir() {
+		return
+	}
+	if info, err := de.Info(); err == nil {
+		// Copy the parents, since siblings may be walked at the same time
+		parents = append(parents[:len(parents):len(parents)], info)
+	}
+	des, err := fs.ReadDir(w.fsys, name)
+	if err != nil && !w.call(name, de, err) {
+		return
+	}
+	for _, child := range des {
+		childName := path.Join(name, child.Name())
+		childLinks := links
+		if child.Type()&fs.ModeSymlink != 0 {
+			if target, ok := follow(w.fsys, childName, parents, links); ok {
+				child = target
+				childLinks++
+			}
+		}
+		if !child.IsDir() {
+			if w.failed() {
+				return
+			}
+			w.call(childName, child, nil)
+			continue
+		}
+		// Walk the directory in another goroutine when there's room, otherwise
+		// walk it in this one
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+			go func(name string, de fs.DirEntry, links int) {
+				defer w.wg.Done()
+				defer func() { <-w.sem }()
+				w.walk(name, de, parents, links)
+			}(childName, child, childLinks)
+		default:
+			w.walk(childName, child, parents, childLinks)
+		}
+	}
+}
+
+// SortWalk sorts the paths in the order that WalkDir visits them, where a
+// directory comes right before its entries.
+func SortWalk(paths []string) {
+	sort.Slice(paths, func(i, j int) bool {
+		return walkLess(paths[i], paths[j])
+	})
+}
+
+func walkLess(a, b string) bool {
+	if a == "." || b == "." {
+		return a == "." && b != "."
+	}
+	for {
+		ai := strings.IndexByte(a, '/')
+		bi := strings.IndexByte(b, '/')
+		aseg, bseg := a, b
+		if ai >= 0 {
+			aseg = a[:ai]
+		}
+		if bi >= 0 {
+			bseg = b[:bi]
+		}
+		if aseg != bseg {
+			return aseg < bseg
+		}
+		// A parent comes before its entries
+		if ai < 0 || bi < 0 {
+			return ai < 0 && bi >= 0
+		}
+		a, b = a[ai+1:], b[bi+1:]
+	}
+}
@@ -0,0 +1,141 @@
+package vfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/internal/testdir"
+	"github.com/livebud/bud/package/vfs"
+)
+
+func walkParallel(fsys fs.FS, root string) (paths []string, err error) {
+	var mu sync.Mutex
+	err = vfs.WalkParallel(fsys, root, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		paths = append(paths, path)
+		mu.Unlock()
+		return nil
+	})
+	vfs.SortWalk(paths)
+	return paths, err
+}
+
+func TestWalkParallel(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(testdir.WriteTree(dir, 3, 4, 5))
+	expect, err := walk(vfs.OS(dir), ".")
+	is.NoErr(err)
+	paths, err := walkParallel(vfs.OS(dir), ".")
+	is.NoErr(err)
+	is.Equal(len(paths), len(expect))
+	for i, path := range expect {
+		is.Equal(paths[i], strings.TrimSuffix(path, "/"))
+	}
+}
+
+func TestWalkParallelSymlinkCycle(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "a", "b"), 0755))
+	is.NoErr(os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "a", "b", "a")))
+	paths, err := walkParallel(os.DirFS(dir), ".")
+	is.NoErr(err)
+	is.Equal(strings.Join(paths, " "), ". a a/b a/b/a")
+}
+
+func TestWalkParallelSkip(t *testing.T) {
+	is := is.New(t)
+	fsys := vfs.Memory{
+		"a/a.txt":   &vfs.File{Data: []byte("a")},
+		"b/b.txt":   &vfs.File{Data: []byte("b")},
+		"b/c/c.txt": &vfs.File{Data: []byte("c")},
+		"root.txt":  &vfs.File{Data: []byte("root")},
+		"skip.txt":  &vfs.File{Data: []byte("skip")},
+	}
+	var mu sync.Mutex
+	var paths []string
+	err := vfs.WalkParallel(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skipping a file doesn't skip its siblings
+		if path == "b" || path == "skip.txt" {
+			return fs.SkipDir
+		}
+		mu.Lock()
+		paths = append(paths, path)
+		mu.Unlock()
+		return nil
+	})
+	is.NoErr(err)
+	vfs.SortWalk(paths)
+	is.Equal(strings.Join(paths, " "), ". a a/a.txt root.txt")
+}
+
+func TestWalkParallelError(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(testdir.WriteTree(dir, 3, 4, 5))
+	errStop := errors.New("stop")
+	err := vfs.WalkParallel(vfs.OS(dir), ".", func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "d1/d2/3.go" {
+			return errStop
+		}
+		return nil
+	})
+	is.True(errors.Is(err, errStop))
+}
+
+func TestSortWalk(t *testing.T) {
+	is := is.New(t)
+	paths := []string{"b", "a/b", "a-b", "a", ".", "a/a/a", "a/a", "a.txt"}
+	vfs.SortWalk(paths)
+	is.Equal(strings.Join(paths, " "), ". a a/a a/a/a a/b a-b a.txt b")
+}
+
+// A tree with 55,986 files in 9,331 directories
+func benchTree(b *testing.B) string {
+	b.Helper()
+	dir := b.TempDir()
+	if err := testdir.WriteTree(dir, 5, 6, 6); err != nil {
+		b.Fatal(err)
+	}
+	return dir
+}
+
+func BenchmarkWalkDir(b *testing.B) {
+	fsys := vfs.OS(benchTree(b))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vfs.WalkDir(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+			return err
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWalkParallel(b *testing.B) {
+	fsys := vfs.OS(benchTree(b))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vfs.WalkParallel(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+			return err
+		}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
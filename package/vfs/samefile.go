@@ -0,0 +1,27 @@
+//go:build !windows
+
+package vfs
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// sameFile checks if two file infos describe the same file. Unlike
+// os.SameFile, it also works for infos that wrap the stat of a file, like the
+// directories of a merged filesystem.
+func sameFile(a, b fs.FileInfo) bool {
+	if os.SameFile(a, b) {
+		return true
+	}
+	sa, ok := a.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	sb, ok := b.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return sa.Dev == sb.Dev && sa.Ino == sb.Ino
+}
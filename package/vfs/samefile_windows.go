@@ -0,0 +1,12 @@
+package vfs
+
+import (
+	"io/fs"
+	"os"
+)
+
+// sameFile checks if two file infos describe the same file. Windows doesn't
+// expose file IDs through Sys, so only infos from the os package compare.
+func sameFile(a, b fs.FileInfo) bool {
+	return os.SameFile(a, b)
+}
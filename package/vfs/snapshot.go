@@ -0,0 +1,167 @@
+package vfs
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Snapshot copies the directories of the filesystem into memory. Missing
+// directories are skipped.
+func Snapshot(fsys fs.FS, dirs ...string) (Memory, error) {
+	memory := Memory{}
+	for _, dir := range dirs {
+		err := fs.WalkDir(fsys, dir, func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := de.Info()
+			if err != nil {
+				return err
+			}
+			file := &File{Mode: info.Mode(), ModTime: info.ModTime()}
+			if !de.IsDir() {
+				data, err := fs.ReadFile(fsys, path)
+				if err != nil {
+					return err
+				}
+				file.Data = data
+			}
+			memory[path] = file
+			return nil
+		})
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+	}
+	return memory, nil
+}
+
+// Snapshot copies the memory filesystem, so later writes to either copy
+// don't affect the other
+func (m Memory) Snapshot() Memory {
+	snapshot := make(Memory, len(m))
+	for path, file := range m {
+		clone := *file
+		clone.Data = append([]byte(nil), file.Data...)
+		snapshot[path] = &clone
+	}
+	return snapshot
+}
+
+// Op is the kind of change to a file
+type Op uint8
+
+const (
+	OpCreate Op = iota + 1
+	OpUpdate
+	OpDelete
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpCreate:
+		return "create"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	default:
+		return ""
+	}
+}
+
+// Change to a file between two filesystems. Data is empty for deletes.
+type Change struct {
+	Op   Op
+	Path string
+	Data []byte
+}
+
+func (c *Change) String() string {
+	return c.Op.String() + " " + c.Path
+}
+
+// Diff the files in two filesystems, returning the changes that turn before
+// into after sorted by path. Files are compared by their contents, so files
+// that were rewritten with the same data aren't changes. Directories are only
+// changed through their files.
+func Diff(before, after fs.FS) (changes []*Change, err error) {
+	from, err := readFiles(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := readFiles(after)
+	if err != nil {
+		return nil, err
+	}
+	for path, data := range to {
+		prev, ok := from[path]
+		if !ok {
+			changes = append(changes, &Change{OpCreate, path, data})
+			continue
+		}
+		if !bytes.Equal(prev, data) {
+			changes = append(changes, &Change{OpUpdate, path, data})
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			changes = append(changes, &Change{OpDelete, path, nil})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// Apply the changes to the filesystem
+func Apply(fsys Writable, changes []*Change) error {
+	for _, change := range changes {
+		switch change.Op {
+		case OpCreate, OpUpdate:
+			if i := strings.LastIndex(change.Path, "/"); i > 0 {
+				if err := fsys.MkdirAll(change.Path[:i], 0755); err != nil {
+					return err
+				}
+			}
+			if err := fsys.WriteFile(change.Path, change.Data, 0644); err != nil {
+				return err
+			}
+		case OpDelete:
+			if err := fsys.RemoveAll(change.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readFiles reads every file in the filesystem
+func readFiles(fsys fs.FS) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	err := fs.WalkDir(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		files[path] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
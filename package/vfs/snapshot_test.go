@@ -0,0 +1,58 @@
+package vfs_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/vfs"
+)
+
+func TestSnapshotDiff(t *testing.T) {
+	is := is.New(t)
+	before := vfs.Memory{
+		"bud/internal/app/main.go": &vfs.File{Data: []byte("package main")},
+		"bud/internal/web/web.go":  &vfs.File{Data: []byte("package web")},
+		"bud/internal/old/old.go":  &vfs.File{Data: []byte("package old")},
+	}
+	after := before.Snapshot()
+	// Writes to the snapshot don't change the original
+	is.NoErr(after.WriteFile("bud/internal/web/web.go", []byte("package web // changed"), 0644))
+	is.NoErr(after.WriteFile("bud/internal/app/main.go", []byte("package main"), 0644))
+	is.NoErr(after.MkdirAll("bud/internal/new", 0755))
+	is.NoErr(after.WriteFile("bud/internal/new/new.go", []byte("package new"), 0644))
+	is.NoErr(after.RemoveAll("bud/internal/old"))
+	code, err := fs.ReadFile(before, "bud/internal/web/web.go")
+	is.NoErr(err)
+	is.Equal(string(code), "package web")
+	changes, err := vfs.Diff(before, after)
+	is.NoErr(err)
+	is.Equal(len(changes), 3)
+	is.Equal(changes[0].String(), "create bud/internal/new/new.go")
+	is.Equal(changes[1].String(), "delete bud/internal/old/old.go")
+	is.Equal(changes[2].String(), "update bud/internal/web/web.go")
+	is.Equal(string(changes[2].Data), "package web // changed")
+	// Applying the changes turns before into after
+	is.NoErr(vfs.Apply(before, changes))
+	changes, err = vfs.Diff(before, after)
+	is.NoErr(err)
+	is.Equal(len(changes), 0)
+}
+
+func TestSnapshotOS(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "bud", "internal"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "bud", "internal", "a.go"), []byte("package a"), 0644))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0644))
+	snapshot, err := vfs.Snapshot(vfs.OS(dir), "bud/internal", "bud/missing")
+	is.NoErr(err)
+	code, err := fs.ReadFile(snapshot, "bud/internal/a.go")
+	is.NoErr(err)
+	is.Equal(string(code), "package a")
+	// Only the directories are copied
+	_, err = fs.Stat(snapshot, "main.go")
+	is.True(err != nil)
+}
@@ -0,0 +1,87 @@
+package vfs
+
+import (
+	"io/fs"
+	"path"
+)
+
+// maxLinks is the most symlinks WalkDir follows within a single path. It stops
+// cycles in filesystems that can't tell when two directories are the same.
+// Linux has the same limit.
+const maxLinks = 40
+
+// WalkDir walks the file tree like fs.WalkDir, but also walks directories that
+// are symlinked, like pnpm's node_modules or a shared view directory. Symlinks
+// that point back to one of their parent directories are passed to fn without
+// being followed, so cycles don't walk forever.
+func WalkDir(fsys fs.FS, root string, fn fs.WalkDirFunc) error {
+	info, err := fs.Stat(fsys, root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkDir(fsys, root, fs.FileInfoToDirEntry(info), fn, nil, 0)
+	}
+	if err == fs.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walkDir(fsys fs.FS, name string, de fs.DirEntry, fn fs.WalkDirFunc, parents []fs.FileInfo, links int) error {
+	if err := fn(name, de, nil); err != nil || !de.IsDir() {
+		if err == fs.SkipDir && de.IsDir() {
+			// Successfully skipped directory
+			err = nil
+		}
+		return err
+	}
+	if info, err := de.Info(); err == nil {
+		parents = append(parents, info)
+	}
+	des, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		// Second call, to report ReadDir error
+		err = fn(name, de, err)
+		if err != nil {
+			if err == fs.SkipDir && de.IsDir() {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, child := range des {
+		childName := path.Join(name, child.Name())
+		childLinks := links
+		if child.Type()&fs.ModeSymlink != 0 {
+			if target, ok := follow(fsys, childName, parents, links); ok {
+				child = target
+				childLinks++
+			}
+		}
+		if err := walkDir(fsys, childName, child, fn, parents, childLinks); err != nil {
+			if err == fs.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// follow the symlink when it points to a directory that isn't one of its
+// parents. Broken links and links to files aren't followed.
+func follow(fsys fs.FS, name string, parents []fs.FileInfo, links int) (fs.DirEntry, bool) {
+	if links >= maxLinks {
+		return nil, false
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil || !info.IsDir() {
+		return nil, false
+	}
+	for _, parent := range parents {
+		if sameFile(parent, info) {
+			return nil, false
+		}
+	}
+	return fs.FileInfoToDirEntry(info), true
+}
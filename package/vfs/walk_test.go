@@ -0,0 +1,77 @@
+package vfs_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/vfs"
+)
+
+func walk(fsys fs.FS, root string) (paths []string, err error) {
+	err = vfs.WalkDir(fsys, root, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if de.IsDir() {
+			path += "/"
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	return paths, err
+}
+
+func TestWalkDirSymlinks(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "shared"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "shared", "header.svelte"), []byte("<h1>header</h1>"), 0644))
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "view"), 0755))
+	is.NoErr(os.WriteFile(filepath.Join(dir, "view", "index.svelte"), []byte("<h1>index</h1>"), 0644))
+	// A symlinked directory is walked
+	is.NoErr(os.Symlink(filepath.Join(dir, "shared"), filepath.Join(dir, "view", "shared")))
+	// A symlink back to a parent isn't
+	is.NoErr(os.Symlink(filepath.Join(dir, "view"), filepath.Join(dir, "view", "self")))
+	// Nor is a broken symlink
+	is.NoErr(os.Symlink(filepath.Join(dir, "missing"), filepath.Join(dir, "view", "missing")))
+	paths, err := walk(vfs.OS(dir), "view")
+	is.NoErr(err)
+	is.Equal(strings.Join(paths, " "), "view/ view/index.svelte view/missing view/self view/shared/ view/shared/header.svelte")
+}
+
+func TestWalkDirSymlinkCycle(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	is.NoErr(os.MkdirAll(filepath.Join(dir, "a", "b"), 0755))
+	is.NoErr(os.Symlink(filepath.Join(dir, "a"), filepath.Join(dir, "a", "b", "a")))
+	paths, err := walk(vfs.OS(dir), ".")
+	is.NoErr(err)
+	is.Equal(strings.Join(paths, " "), "./ a/ a/b/ a/b/a")
+}
+
+func TestWalkDirSkip(t *testing.T) {
+	is := is.New(t)
+	fsys := vfs.Memory{
+		"a/a.txt":  &vfs.File{Data: []byte("a")},
+		"b/b.txt":  &vfs.File{Data: []byte("b")},
+		"c/c.txt":  &vfs.File{Data: []byte("c")},
+		"root.txt": &vfs.File{Data: []byte("root")},
+	}
+	var paths []string
+	err := vfs.WalkDir(fsys, ".", func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == "b" {
+			return fs.SkipDir
+		}
+		paths = append(paths, path)
+		return nil
+	})
+	is.NoErr(err)
+	is.Equal(strings.Join(paths, " "), ". a a/a.txt c c/c.txt root.txt")
+}
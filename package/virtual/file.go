@@ -1,9 +1,12 @@
 package virtual
 
 import (
+	"bytes"
+	"compress/gzip"
 	"io"
 	"io/fs"
 	"path"
+	"strings"
 	"time"
 )
 
@@ -13,6 +16,10 @@ type File struct {
 	Data    []byte
 	Mode    fs.FileMode
 	ModTime time.Time
+	// Stream opens the contents of large files instead of holding them in
+	// Data. Streamed files also need their Size.
+	Stream func() (io.ReadSeekCloser, error) `json:"-"`
+	Size   int64                             `json:",omitempty"`
 }
 
 var _ fs.DirEntry = (*File)(nil)
@@ -39,38 +46,104 @@ func (f *File) Info() (fs.FileInfo, error) {
 		path:    f.Path,
 		mode:    f.Mode &^ fs.ModeDir,
 		modTime: f.ModTime,
-		size:    int64(len(f.Data)),
+		size:    f.size(),
 	}, nil
 }
 
+func (f *File) size() int64 {
+	if f.Stream != nil {
+		return f.Size
+	}
+	return int64(len(f.Data))
+}
+
 func (f *File) open() fs.File {
-	return &entryFile{f, 0}
+	return &entryFile{File: f}
+}
+
+// StreamString streams the file from a string. Generated code can embed large
+// files as string constants, which aren't copied into memory the way a
+// []byte is.
+func StreamString(data string) func() (io.ReadSeekCloser, error) {
+	return func() (io.ReadSeekCloser, error) {
+		return nopCloser{strings.NewReader(data)}, nil
+	}
 }
 
+// GunzipString streams the file from gzipped data. The data is decompressed
+// into memory when the file is first read and released when it's closed, so
+// files that are usually served compressed don't have to be embedded twice.
+func GunzipString(data string) func() (io.ReadSeekCloser, error) {
+	return func() (io.ReadSeekCloser, error) {
+		reader, err := gzip.NewReader(strings.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		decompressed, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return nopCloser{bytes.NewReader(decompressed)}, nil
+	}
+}
+
+type nopCloser struct {
+	io.ReadSeeker
+}
+
+func (nopCloser) Close() error { return nil }
+
 type entryFile struct {
 	*File
 	offset int64
+	stream io.ReadSeekCloser // Opened on the first read of a streamed file
 }
 
 var _ io.ReadSeeker = (*entryFile)(nil)
 var _ fs.File = (*entryFile)(nil)
 
 func (f *entryFile) Close() error {
-	return nil
+	if f.stream == nil {
+		return nil
+	}
+	err := f.stream.Close()
+	f.stream = nil
+	return err
 }
 
 func (f *entryFile) Read(b []byte) (int, error) {
-	if f.offset >= int64(len(f.Data)) {
+	if f.offset >= f.size() {
 		return 0, io.EOF
 	}
 	if f.offset < 0 {
 		return 0, &fs.PathError{Op: "read", Path: f.Path, Err: fs.ErrInvalid}
 	}
+	if f.Stream != nil {
+		return f.readStream(b)
+	}
 	n := copy(b, f.Data[f.offset:])
 	f.offset += int64(n)
 	return n, nil
 }
 
+func (f *entryFile) readStream(b []byte) (int, error) {
+	if f.stream == nil {
+		stream, err := f.Stream()
+		if err != nil {
+			return 0, &fs.PathError{Op: "read", Path: f.Path, Err: err}
+		}
+		if _, err := stream.Seek(f.offset, io.SeekStart); err != nil {
+			stream.Close()
+			return 0, &fs.PathError{Op: "read", Path: f.Path, Err: err}
+		}
+		f.stream = stream
+	}
+	n, err := f.stream.Read(b)
+	f.offset += int64(n)
+	return n, err
+}
+
 func (f *entryFile) Stat() (fs.FileInfo, error) {
 	return f.Info()
 }
@@ -82,11 +155,16 @@ func (f *entryFile) Seek(offset int64, whence int) (int64, error) {
 	case 1:
 		offset += f.offset
 	case 2:
-		offset += int64(len(f.Data))
+		offset += f.size()
 	}
-	if offset < 0 || offset > int64(len(f.Data)) {
+	if offset < 0 || offset > f.size() {
 		return 0, &fs.PathError{Op: "seek", Path: f.Path, Err: fs.ErrInvalid}
 	}
+	if f.stream != nil && offset != f.offset {
+		if _, err := f.stream.Seek(offset, io.SeekStart); err != nil {
+			return 0, &fs.PathError{Op: "seek", Path: f.Path, Err: err}
+		}
+	}
 	f.offset = offset
 	return offset, nil
 }
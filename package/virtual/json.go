@@ -36,12 +36,12 @@ func (f *jsonEntry) Open() fs.File {
 			Entries: entries,
 		}, 0}
 	}
-	return &entryFile{&File{
+	return &entryFile{File: &File{
 		Path:    f.Path,
 		Data:    f.Data,
 		Mode:    f.Mode,
 		ModTime: f.ModTime,
-	}, 0}
+	}}
 }
 
 func UnmarshalJSON(file []byte) (fs.File, error) {
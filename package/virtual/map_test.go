@@ -1,9 +1,13 @@
 package virtual_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"io"
 	"io/fs"
 	"testing"
+	"time"
 
 	"github.com/livebud/bud/internal/is"
 	"github.com/livebud/bud/package/virtual"
@@ -73,3 +77,80 @@ func TestMapRoot(t *testing.T) {
 	is.NoErr(err)
 	is.Equal(len(des), 0)
 }
+
+func TestMapStream(t *testing.T) {
+	is := is.New(t)
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := virtual.Map{
+		"public/video.mp4": &virtual.File{
+			Mode:    0755,
+			ModTime: modTime,
+			Size:    10,
+			Stream:  virtual.StreamString("0123456789"),
+		},
+	}
+	stat, err := fs.Stat(fsys, "public/video.mp4")
+	is.NoErr(err)
+	is.Equal(stat.Size(), int64(10))
+	is.Equal(stat.Mode(), fs.FileMode(0755))
+	is.True(stat.ModTime().Equal(modTime))
+	data, err := fs.ReadFile(fsys, "public/video.mp4")
+	is.NoErr(err)
+	is.Equal(string(data), "0123456789")
+	// Seek to read a range
+	file, err := fsys.Open("public/video.mp4")
+	is.NoErr(err)
+	defer file.Close()
+	seeker, ok := file.(io.ReadSeeker)
+	is.True(ok)
+	_, err = seeker.Seek(6, io.SeekStart)
+	is.NoErr(err)
+	buf := make([]byte, 2)
+	_, err = io.ReadFull(seeker, buf)
+	is.NoErr(err)
+	is.Equal(string(buf), "67")
+	_, err = seeker.Seek(-2, io.SeekEnd)
+	is.NoErr(err)
+	rest, err := io.ReadAll(seeker)
+	is.NoErr(err)
+	is.Equal(string(rest), "89")
+}
+
+func TestMapGunzip(t *testing.T) {
+	is := is.New(t)
+	buf := new(bytes.Buffer)
+	writer := gzip.NewWriter(buf)
+	_, err := writer.Write([]byte("0123456789"))
+	is.NoErr(err)
+	is.NoErr(writer.Close())
+	fsys := virtual.Map{
+		"public/app.js": &virtual.File{
+			Size:   10,
+			Stream: virtual.GunzipString(buf.String()),
+		},
+		"public/broken.js": &virtual.File{
+			Size:   10,
+			Stream: virtual.GunzipString("not gzip"),
+		},
+	}
+	stat, err := fs.Stat(fsys, "public/app.js")
+	is.NoErr(err)
+	is.Equal(stat.Size(), int64(10))
+	data, err := fs.ReadFile(fsys, "public/app.js")
+	is.NoErr(err)
+	is.Equal(string(data), "0123456789")
+	// Seek to read a range
+	file, err := fsys.Open("public/app.js")
+	is.NoErr(err)
+	defer file.Close()
+	seeker, ok := file.(io.ReadSeeker)
+	is.True(ok)
+	_, err = seeker.Seek(-3, io.SeekEnd)
+	is.NoErr(err)
+	rest, err := io.ReadAll(seeker)
+	is.NoErr(err)
+	is.Equal(string(rest), "789")
+	// Corrupt data fails when it's read
+	_, err = fs.ReadFile(fsys, "public/broken.js")
+	is.True(err != nil)
+}
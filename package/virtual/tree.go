@@ -23,7 +23,7 @@ func (fsys Tree) Open(path string) (fs.File, error) {
 		if file.IsDir() {
 			return &entryDir{&Dir{file.Path, file.Mode, file.ModTime, nil}, 0}, nil
 		}
-		return &entryFile{file, 0}, nil
+		return &entryFile{File: file}, nil
 	}
 
 	// The following logic is based on "testing/fstest".MapFS.Open
@@ -82,14 +82,14 @@ func (fsys Tree) Open(path string) (fs.File, error) {
 
 // Mkdir create a directory.
 func (t Tree) MkdirAll(path string, perm fs.FileMode) error {
-	t[path] = &File{path, nil, perm | fs.ModeDir, time.Time{}}
+	t[path] = &File{Path: path, Mode: perm | fs.ModeDir}
 	return nil
 }
 
 // WriteFile writes a file
 // TODO: WriteFile should fail if path.Dir(name) doesn't exist
 func (t Tree) WriteFile(path string, data []byte, perm fs.FileMode) error {
-	t[path] = &File{path, data, perm, time.Time{}}
+	t[path] = &File{Path: path, Data: data, Mode: perm}
 	return nil
 }
 
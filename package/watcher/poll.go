@@ -0,0 +1,138 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/livebud/bud/internal/gitignore"
+	"github.com/livebud/bud/package/vfs"
+)
+
+// Polling starts out fast and slows down while nothing changes. Large trees
+// that take a while to scan are polled less often, so polling never spends
+// more than a tenth of its time scanning.
+var (
+	pollMin = 100 * time.Millisecond
+	pollMax = 500 * time.Millisecond
+)
+
+// Poll the directory and its subdirectories for changes, calling fn with the
+// changes since the last call. Polling is slower than watching, but works on
+// filesystems that don't report changes, like network mounts.
+func Poll(ctx context.Context, dir string, fn func(events []Event) error) error {
+	p := &poller{dir, gitignore.From(dir)}
+	stamps, err := p.scan()
+	if err != nil {
+		return err
+	}
+	interval := pollMin
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+		start := time.Now()
+		next, err := p.scan()
+		if err != nil {
+			return err
+		}
+		cost := time.Since(start)
+		events := diffStamps(stamps, next)
+		stamps = next
+		if len(events) > 0 {
+			if err := fn(events); err != nil {
+				if errors.Is(err, Stop) {
+					return nil
+				}
+				return err
+			}
+			interval = pollMin
+		} else if interval *= 2; interval > pollMax {
+			interval = pollMax
+		}
+		if interval < cost*10 {
+			timer.Reset(cost * 10)
+			continue
+		}
+		timer.Reset(interval)
+	}
+}
+
+type poller struct {
+	dir       string
+	gitIgnore func(path string) bool
+}
+
+// scan stamps every file and directory that isn't ignored
+func (p *poller) scan() (map[string]string, error) {
+	stamps := map[string]string{}
+	err := vfs.WalkDir(os.DirFS(p.dir), ".", func(rel string, de fs.DirEntry, err error) error {
+		if err != nil {
+			// Removed while we were scanning
+			if errors.Is(err, fs.ErrNotExist) && rel != "." {
+				return nil
+			}
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		path := filepath.FromSlash(rel)
+		if p.gitIgnore(path) {
+			if de.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		// Directories change when their entries do, which are reported on
+		// their own
+		if de.IsDir() {
+			stamps[path] = "dir"
+			return nil
+		}
+		stat, err := followStat(filepath.Join(p.dir, path))
+		if err != nil {
+			return nil
+		}
+		stamps[path] = computeStamp(path, stat)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// diffStamps returns the events between two scans. Like Watch, deleting a
+// directory is a single event.
+func diffStamps(prev, next map[string]string) (events []Event) {
+	for path, stamp := range next {
+		prevStamp, ok := prev[path]
+		if !ok {
+			events = append(events, Event{OpCreate, path})
+		} else if prevStamp != stamp {
+			events = append(events, Event{OpUpdate, path})
+		}
+	}
+	for path := range prev {
+		if _, ok := next[path]; ok {
+			continue
+		}
+		if parent := filepath.Dir(path); parent != "." && prev[parent] != "" && next[parent] == "" {
+			continue
+		}
+		events = append(events, Event{OpDelete, path})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].String() < events[j].String()
+	})
+	return events
+}
@@ -0,0 +1,86 @@
+package watcher_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/vfs"
+	"github.com/livebud/bud/package/watcher"
+	"golang.org/x/sync/errgroup"
+)
+
+func TestPoll(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"a.txt":             []byte(`a`),
+		"view/index.svelte": []byte(`<h1>index</h1>`),
+		"node_modules/x.js": []byte(`x`),
+	})
+	is.NoErr(err)
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Poll(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	// Update
+	err = os.WriteFile(filepath.Join(dir, "a.txt"), []byte("bb"), 0644)
+	is.NoErr(err)
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "a.txt")
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	// Create
+	err = os.MkdirAll(filepath.Join(dir, "view", "posts"), 0755)
+	is.NoErr(err)
+	err = os.WriteFile(filepath.Join(dir, "view", "posts", "index.svelte"), []byte("<h1>posts</h1>"), 0644)
+	is.NoErr(err)
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 2)
+	is.Equal(events[0].String(), "C:"+filepath.Join("view", "posts"))
+	is.Equal(events[1].String(), "C:"+filepath.Join("view", "posts", "index.svelte"))
+	// Ignored files aren't polled
+	err = os.WriteFile(filepath.Join(dir, "node_modules", "x.js"), []byte("xx"), 0644)
+	is.NoErr(err)
+	// Deleting a directory is a single event
+	err = os.RemoveAll(filepath.Join(dir, "view"))
+	is.NoErr(err)
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].String(), "D:view")
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestPollStop(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Poll(ctx, dir, func(events []watcher.Event) error {
+			return watcher.Stop
+		})
+	})
+	time.Sleep(waitForEvents)
+	is.NoErr(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	is.NoErr(eg.Wait())
+	is.NoErr(ctx.Err())
+}
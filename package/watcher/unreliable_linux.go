@@ -0,0 +1,24 @@
+package watcher
+
+import "syscall"
+
+// Magic numbers of filesystems that don't report changes to inotify. See
+// statfs(2).
+var unreliable = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517b:     true, // SMB
+	0xff534d42: true, // CIFS
+	0xfe534d42: true, // SMB2
+	0x01021997: true, // 9P, like WSL's mounts of Windows drives
+	0x65735546: true, // FUSE, like Docker Desktop's mounts of the host
+}
+
+// Unreliable returns true if the directory is on a filesystem that doesn't
+// report its changes, so it needs to be polled
+func Unreliable(dir string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return false
+	}
+	return unreliable[uint32(stat.Type)]
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package watcher
+
+// Unreliable returns true if the directory is on a filesystem that doesn't
+// report its changes, so it needs to be polled. Only Linux is checked.
+func Unreliable(dir string) bool {
+	return false
+}
@@ -1,21 +1,37 @@
+// Package watcher watches a directory tree for changes. It uses the native
+// backend of each platform through fsnotify: inotify on Linux,
+// ReadDirectoryChangesW on Windows and kqueue on macOS and BSD.
+//
+// Events are debounced and coalesced by path, so the burst of events that an
+// editor's atomic save causes (rename the original aside, write a new file,
+// remove the backup) is reported as a single update.
+//
+// Symlinked directories are followed, so a view directory that links
+// elsewhere is watched like any other. Each directory is only watched once,
+// which also keeps symlink cycles from being walked forever.
+//
+// Some filesystems don't report their changes, like network mounts, the
+// Docker Desktop and WSL mounts of the host's files. Watch polls those
+// instead, as well as any directory that the native backend can't watch.
 package watcher
 
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
-	"sync"
+	"strings"
 	"time"
 
-	"golang.org/x/sync/errgroup"
-
-	"github.com/bep/debounce"
+	"github.com/cespare/xxhash"
 	"github.com/fsnotify/fsnotify"
 	"github.com/livebud/bud/internal/gitignore"
+	"github.com/livebud/bud/package/vfs"
 )
 
 // Stop informs the watcher to stop.
@@ -49,265 +65,404 @@ func (e Event) String() string {
 
 func newEventSet() *eventSet {
 	return &eventSet{
-		events: map[string]Event{},
+		events: map[string]Op{},
 	}
 }
 
-// eventset is used to collect events that have changed and flush them all at once
-// when the watch function is triggered.
+// eventSet collects the events that happened since the last flush, keeping
+// one event per path.
 type eventSet struct {
-	mu     sync.RWMutex
-	events map[string]Event
+	events map[string]Op
 }
 
-// Add a event to the set
+// Add an event to the set, coalescing it with the earlier event for the same
+// path
 func (p *eventSet) Add(event Event) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	p.events[event.String()] = event
+	prev, ok := p.events[event.Path]
+	if !ok {
+		p.events[event.Path] = event.Op
+		return
+	}
+	switch {
+	// A file that was created and deleted within the same flush never existed
+	// as far as the callback is concerned
+	case prev == OpCreate && event.Op == OpDelete:
+		delete(p.events, event.Path)
+	// Created files stay created, even when they're written to right after
+	case prev == OpCreate:
+	// A file that was deleted and then created again was replaced. This is how
+	// many editors save files atomically.
+	case prev == OpDelete && event.Op != OpDelete:
+		p.events[event.Path] = OpUpdate
+	default:
+		p.events[event.Path] = event.Op
+	}
+}
+
+// Len returns the number of events waiting to be flushed
+func (p *eventSet) Len() int {
+	return len(p.events)
 }
 
 // Flush the stored events and clear the event set.
 func (p *eventSet) Flush() (events []Event) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-	for _, event := range p.events {
-		events = append(events, event)
+	for path, op := range p.events {
+		events = append(events, Event{op, path})
 	}
 	sort.Slice(events, func(i, j int) bool {
 		return events[i].String() < events[j].String()
 	})
-	p.events = map[string]Event{}
+	p.events = map[string]Op{}
 	return events
 }
 
-// Watch function
+// errUnavailable is returned when the native backend can't watch a directory,
+// like when there are more directories than the operating system's limit
+var errUnavailable = errors.New("watcher: native watching is unavailable")
+
+// Watch the directory and its subdirectories, calling fn with the events
+// that happened since the last call. Calls never overlap. Events that happen
+// while fn is running are passed to the next call.
+//
+// Directories on filesystems that don't report their changes, or that the
+// native backend is unable to watch, are polled instead.
 func Watch(ctx context.Context, dir string, fn func(events []Event) error) error {
-	watcher, err := fsnotify.NewWatcher()
-	if err != nil {
+	if Unreliable(dir) {
+		return Poll(ctx, dir, fn)
+	}
+	if err := Native(ctx, dir, fn); err != nil {
+		if errors.Is(err, errUnavailable) {
+			return Poll(ctx, dir, fn)
+		}
 		return err
 	}
-	defer watcher.Close()
-	// Don't watch files in .gitignore
-	gitIgnore := gitignore.From(dir)
-	// Trigger is debounced to group events together
-	errorCh := make(chan error)
-	eventSet := newEventSet()
-	debounce := debounce.New(debounceDelay)
-	trigger := func(event Event) {
-		relPath, err := filepath.Rel(dir, event.Path)
+	return nil
+}
+
+// Native watches the directory with the operating system's backend, without
+// falling back to polling.
+func Native(ctx context.Context, dir string, fn func(events []Event) error) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("%w. %s", errUnavailable, err)
+	}
+	defer fsw.Close()
+	w := &watch{
+		dir:       dir,
+		fsw:       fsw,
+		gitIgnore: gitignore.From(dir),
+		dirs:      map[string]string{},
+		reals:     map[string]string{},
+		stamps:    map[string]string{},
+		events:    newEventSet(),
+	}
+	// Walk the files, watching directories that aren't ignored and stamping
+	// the files within them
+	if err := vfs.WalkDir(os.DirFS(dir), ".", func(rel string, de fs.DirEntry, err error) error {
 		if err != nil {
-			errorCh <- err
-			return
+			return err
 		}
-		event.Path = relPath
-		eventSet.Add(event)
-		debounce(func() {
-			if err := fn(eventSet.Flush()); err != nil {
-				errorCh <- err
-				return
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if rel != "." && w.ignored(path) {
+			// Skip directories
+			if de.IsDir() {
+				return filepath.SkipDir
 			}
-		})
-	}
-	// Avoid duplicate events by checking the stamp of the file. This allows us
-	// to bring down the debounce delay to trigger events faster.
-	// TODO: bound this map
-	duplicates := map[string]struct{}{}
-	isDuplicate := func(path string, stat fs.FileInfo) bool {
-		stamp, err := computeStamp(path, stat)
-		if err != nil {
-			return false
-		}
-		// Duplicate check
-		if _, ok := duplicates[stamp]; ok {
-			return true
-		}
-		duplicates[stamp] = struct{}{}
-		return false
-	}
-	// For some reason renames are often emitted instead of
-	// Remove. Check it and correct.
-	rename := func(path string) error {
-		_, err := os.Stat(path)
-		if nil == err {
+			// Ignore files
 			return nil
 		}
-		// If it's a different error, ignore
-		if !errors.Is(err, fs.ErrNotExist) {
+		if de.IsDir() {
+			added, err := w.add(path)
+			if err != nil {
+				return fmt.Errorf("%w. %s", errUnavailable, err)
+			} else if !added {
+				return filepath.SkipDir
+			}
 			return nil
 		}
-		// Remove the path and emit an update
-		watcher.Remove(path)
-		// Trigger an update
-		trigger(Event{OpDelete, path})
-		return nil
-	}
-	// Remove the file or directory from the watcher.
-	// We intentionally ignore errors for this case.
-	remove := func(path string) error {
-		watcher.Remove(path)
-		// Trigger an update
-		trigger(Event{OpDelete, path})
-		return nil
-	}
-	// Watching a file or directory as long as it's not inside .gitignore.
-	// Ignore most errors since missing a file isn't the end of the world.
-	// If a new directory is created, add and trigger all the files within
-	// that directory.
-	var create func(path string) error
-	create = func(path string) error {
-		// Stat the file
 		stat, err := os.Stat(path)
 		if err != nil {
 			return nil
 		}
-		relPath, err := filepath.Rel(dir, path)
-		if err != nil {
+		w.isDuplicate(path, stat)
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := w.run(ctx, fn); err != nil {
+		if !errors.Is(err, Stop) {
 			return err
 		}
-		if gitIgnore(relPath) {
-			return nil
-		}
-		if isDuplicate(path, stat) {
+		return nil
+	}
+	return nil
+}
+
+type watch struct {
+	dir       string
+	fsw       *fsnotify.Watcher
+	gitIgnore func(path string) bool
+	dirs      map[string]string // Watched directories to their real paths
+	reals     map[string]string // Real paths to their watched directories
+	stamps    map[string]string // Last seen stamp of each file and directory
+	events    *eventSet
+}
+
+// run handles file events until the context is canceled. The callback runs
+// in its own goroutine so the backend doesn't fall behind during a rebuild.
+func (w *watch) run(ctx context.Context, fn func(events []Event) error) error {
+	timer := time.NewTimer(debounceDelay)
+	stopTimer(timer)
+	defer timer.Stop()
+	done := make(chan error, 1)
+	running := false
+	for {
+		select {
+		case <-ctx.Done():
 			return nil
-		}
-		err = watcher.Add(path)
-		if err != nil {
+		case err := <-w.fsw.Errors:
 			return err
-		}
-		// If it's a directory, walk the dir and trigger creates
-		// because those create events won't happen on their own
-		if stat.IsDir() {
-			trigger(Event{OpCreate, path})
-			des, err := os.ReadDir(path)
+		case evt := <-w.fsw.Events:
+			if err := w.handle(evt); err != nil {
+				return err
+			}
+			// Wait until the events stop coming in
+			if w.events.Len() > 0 {
+				stopTimer(timer)
+				timer.Reset(debounceDelay)
+			}
+		case <-timer.C:
+			// Events are flushed once the running callback finishes
+			if running || w.events.Len() == 0 {
+				continue
+			}
+			running = true
+			events := w.events.Flush()
+			go func() { done <- fn(events) }()
+		case err := <-done:
+			running = false
 			if err != nil {
 				return err
 			}
-			for _, de := range des {
-				if err := create(filepath.Join(path, de.Name())); err != nil {
-					return err
-				}
+			if w.events.Len() > 0 {
+				stopTimer(timer)
+				timer.Reset(debounceDelay)
 			}
-			return nil
 		}
-		// Otherwise, trigger the create
-		trigger(Event{OpCreate, path})
-		return nil
 	}
-	// A file or directory has been updated. Notify our matchers.
-	write := func(path string) error {
-		relPath, err := filepath.Rel(dir, path)
-		if err != nil {
-			return err
-		}
-		if gitIgnore(relPath) {
-			return nil
-		}
-		// Stat the file
-		stat, err := os.Stat(path)
-		if err != nil {
-			return nil
-		}
-		if isDuplicate(path, stat) {
-			return nil
+}
+
+// stopTimer stops the timer and drains its channel, so it can be reset
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
 		}
-		// Trigger an update
-		trigger(Event{OpUpdate, path})
+	}
+}
+
+func (w *watch) handle(evt fsnotify.Event) error {
+	// Sometimes the event name can be empty on Linux during deletes. Ignore
+	// those events.
+	if evt.Name == "" || w.ignored(evt.Name) {
 		return nil
 	}
+	switch op := evt.Op; {
+	case op&fsnotify.Rename != 0, op&fsnotify.Remove != 0:
+		return w.remove(evt.Name)
+	case op&fsnotify.Create != 0:
+		return w.create(evt.Name)
+	case op&fsnotify.Write != 0:
+		return w.write(evt.Name)
+	}
+	return nil
+}
 
-	// Walk the files, adding files that aren't ignored
-	if err := filepath.WalkDir(dir, func(path string, de fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		relPath, err := filepath.Rel(dir, path)
-		if err != nil {
-			return err
+// ignored returns true for paths inside .gitignore
+func (w *watch) ignored(path string) bool {
+	relPath, err := filepath.Rel(w.dir, path)
+	if err != nil {
+		return false
+	}
+	return w.gitIgnore(relPath)
+}
+
+func (w *watch) trigger(op Op, path string) error {
+	relPath, err := filepath.Rel(w.dir, path)
+	if err != nil {
+		return err
+	}
+	w.events.Add(Event{op, relPath})
+	return nil
+}
+
+// add a directory to the backend. Files are watched through their directory.
+// Directories that are reachable through more than one path because of
+// symlinks are only added the first time, in which case added is false.
+func (w *watch) add(dir string) (added bool, err error) {
+	real, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		// Already gone again
+		return false, nil
+	}
+	if _, ok := w.reals[real]; ok {
+		return false, nil
+	}
+	if err := w.fsw.Add(dir); err != nil {
+		return false, err
+	}
+	w.dirs[dir] = real
+	w.reals[real] = dir
+	return true, nil
+}
+
+// forget the path and everything inside it. Errors are ignored because the
+// backend may have already dropped the watch.
+func (w *watch) forget(path string) {
+	prefix := path + string(filepath.Separator)
+	for dir, real := range w.dirs {
+		if dir == path || strings.HasPrefix(dir, prefix) {
+			w.fsw.Remove(dir)
+			delete(w.dirs, dir)
+			delete(w.reals, real)
 		}
-		// Support .gitignore
-		if gitIgnore(relPath) {
-			// Skip directories
-			if de.IsDir() {
-				return filepath.SkipDir
-			}
-			// Ignore files
-			return nil
+	}
+	for stamped := range w.stamps {
+		if stamped == path || strings.HasPrefix(stamped, prefix) {
+			delete(w.stamps, stamped)
 		}
-		// Add the path to the watcher
-		if err := watcher.Add(path); err != nil {
+	}
+}
+
+// The path was renamed or removed. Events are handled after the fact, so by
+// now a new file may have taken its place, like during an atomic save. In
+// that case the path is created again and the events coalesce into an update.
+//
+// Deletes are only triggered for paths we've seen. Temporary files that are
+// removed before we can stat them never existed as far as the callback is
+// concerned.
+func (w *watch) remove(path string) error {
+	_, seen := w.stamps[path]
+	if _, ok := w.dirs[path]; ok {
+		seen = true
+	}
+	w.forget(path)
+	if seen {
+		if err := w.trigger(OpDelete, path); err != nil {
 			return err
 		}
+	}
+	if _, err := os.Lstat(path); err != nil {
 		return nil
-	}); err != nil {
-		return err
 	}
+	return w.create(path)
+}
 
-	// Watch for file events!
-	// Note: The FAQ currently says it needs to be in a separate Go routine
-	// https://github.com/fsnotify/fsnotify#faq, so we'll do that.
-	eg, ctx := errgroup.WithContext(ctx)
-	eg.Go(func() error {
-		for {
-			select {
-			case <-ctx.Done():
-				return nil
-			case err := <-errorCh:
-				return err
-			case err := <-watcher.Errors:
-				return err
-			case evt := <-watcher.Events:
-				// Sometimes the event name can be empty on Linux during deletes. Ignore
-				// those events.
-				if evt.Name == "" {
-					continue
-				}
-				// Switch over the operations
-				switch op := evt.Op; {
-
-				// Handle rename events
-				case op&fsnotify.Rename != 0:
-					if err := rename(evt.Name); err != nil {
-						return err
-					}
-
-				// Handle remove events
-				case op&fsnotify.Remove != 0:
-					if err := remove(evt.Name); err != nil {
-						return err
-					}
-
-				// Handle create events
-				case op&fsnotify.Create != 0:
-					if err := create(evt.Name); err != nil {
-						return err
-					}
-
-				// Handle write events
-				case op&fsnotify.Write != 0:
-					if err := write(evt.Name); err != nil {
-						return err
-					}
-				}
-			}
+// A file or directory has been created. New directories are watched and the
+// files within them are triggered, because those create events won't happen
+// on their own.
+func (w *watch) create(path string) error {
+	stat, err := followStat(path)
+	if err != nil {
+		// Already gone again
+		return nil
+	}
+	if w.isDuplicate(path, stat) {
+		return nil
+	}
+	if !stat.IsDir() {
+		return w.trigger(OpCreate, path)
+	}
+	if err := w.trigger(OpCreate, path); err != nil {
+		return err
+	}
+	// Directories we're already watching through another path have no new
+	// files to trigger
+	if added, err := w.add(path); err != nil || !added {
+		return nil
+	}
+	des, err := os.ReadDir(path)
+	if err != nil {
+		return nil
+	}
+	for _, de := range des {
+		subpath := filepath.Join(path, de.Name())
+		if w.ignored(subpath) {
+			continue
 		}
-	})
-	// Wait for the watcher to complete
-	if err := eg.Wait(); err != nil {
-		if !errors.Is(err, Stop) {
+		if err := w.create(subpath); err != nil {
 			return err
 		}
-		return nil
 	}
 	return nil
 }
 
+// A file has been written to
+func (w *watch) write(path string) error {
+	stat, err := followStat(path)
+	if err != nil {
+		return nil
+	}
+	// Directories are written to when their entries change. Those changes
+	// have events of their own.
+	if stat.IsDir() {
+		return nil
+	}
+	// Writes are compared by their contents, so touching a file isn't a change
+	// and a change isn't missed when the modtime has a coarse resolution
+	stamp, err := hashStamp(path, stat)
+	if err != nil {
+		return nil
+	}
+	if w.stamps[path] == stamp {
+		return nil
+	}
+	w.stamps[path] = stamp
+	return w.trigger(OpUpdate, path)
+}
+
+// followStat follows symlinks, falling back to the link itself when it's broken
+func followStat(path string) (fs.FileInfo, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return os.Lstat(path)
+	}
+	return stat, nil
+}
+
+// isDuplicate avoids duplicate events by checking the stamp of the file. This
+// allows us to bring down the debounce delay to trigger events faster.
+func (w *watch) isDuplicate(path string, stat fs.FileInfo) bool {
+	stamp := computeStamp(path, stat)
+	if w.stamps[path] == stamp {
+		return true
+	}
+	w.stamps[path] = stamp
+	return false
+}
+
 // computeStamp uses path, size, mode and modtime to try and ensure this is a
 // unique event.
-func computeStamp(path string, stat fs.FileInfo) (stamp string, err error) {
+func computeStamp(path string, stat fs.FileInfo) (stamp string) {
 	mtime := stat.ModTime().UnixNano()
 	mode := stat.Mode()
 	size := stat.Size()
-	stamp = path + ":" + strconv.Itoa(int(size)) + ":" + mode.String() + ":" + strconv.Itoa(int(mtime))
-	return stamp, nil
+	return path + ":" + strconv.Itoa(int(size)) + ":" + mode.String() + ":" + strconv.Itoa(int(mtime))
+}
+
+// hashStamp uses path, size, mode and the hash of the file's contents. Stamps
+// from the initial walk don't read files, so the first write is always
+// reported.
+func hashStamp(path string, stat fs.FileInfo) (stamp string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := xxhash.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return path + ":" + strconv.Itoa(int(stat.Size())) + ":" + stat.Mode().String() + ":#" + strconv.FormatUint(h.Sum64(), 16), nil
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -285,3 +286,275 @@ func TestRename(t *testing.T) {
 	cancel()
 	is.NoErr(eg.Wait())
 }
+
+func TestAtomicSave(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"a.txt": []byte(`a`),
+	})
+	is.NoErr(err)
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	// Save like vim does: check the directory is writable, move the original
+	// aside, write the new file and remove the backup
+	path := filepath.Join(dir, "a.txt")
+	is.NoErr(os.WriteFile(filepath.Join(dir, "4913"), nil, 0644))
+	is.NoErr(os.Remove(filepath.Join(dir, "4913")))
+	is.NoErr(os.Rename(path, path+"~"))
+	is.NoErr(os.WriteFile(path, []byte("b"), 0644))
+	is.NoErr(os.Remove(path + "~"))
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "a.txt")
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	select {
+	case events := <-eventCh:
+		t.Fatalf("unexpected extra events %v", events)
+	case <-time.After(waitForEvents):
+	}
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestMoveDirOut(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	outside := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"view/index.svelte": []byte(`<h1>index</h1>`),
+	})
+	is.NoErr(err)
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	err = os.Rename(filepath.Join(dir, "view"), filepath.Join(outside, "view"))
+	is.NoErr(err)
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "view")
+	is.Equal(events[0].Op, watcher.OpDelete)
+	// Changes outside of the directory aren't reported
+	err = os.WriteFile(filepath.Join(outside, "view", "index.svelte"), []byte(`<h1>moved</h1>`), 0644)
+	is.NoErr(err)
+	select {
+	case events := <-eventCh:
+		t.Fatalf("unexpected events %v", events)
+	case <-time.After(waitForEvents):
+	}
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestNoOverlap(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	var running int32
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+				return errors.New("overlapping calls")
+			}
+			defer atomic.StoreInt32(&running, 0)
+			// Simulate a slow rebuild
+			time.Sleep(100 * time.Millisecond)
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	is.NoErr(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0644))
+	time.Sleep(50 * time.Millisecond)
+	// Written while the first call is running
+	is.NoErr(os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b"), 0644))
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "a.txt")
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "b.txt")
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestBudIgnore(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := writeFiles(dir, map[string]string{
+		".budignore":          "/tmp\n",
+		"tmp/scratch.txt":     "a",
+		"node_modules/a/a.js": "a",
+		"view/index.svelte":   "<h1>index</h1>",
+	})
+	is.NoErr(err)
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	err = writeFiles(dir, map[string]string{
+		"tmp/scratch.txt":        "b",
+		"tmp/new/scratch.txt":    "b",
+		"node_modules/a/a.js":    "b",
+		"view/.index.svelte.swp": "b",
+		"view/index.svelte":      "<h1>updated</h1>",
+	})
+	is.NoErr(err)
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "view/index.svelte")
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestSymlinkDir(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	shared := t.TempDir()
+	err := vfs.Write(shared, vfs.Map{
+		"index.svelte": []byte(`<h1>index</h1>`),
+	})
+	is.NoErr(err)
+	is.NoErr(os.Symlink(shared, filepath.Join(dir, "view")))
+	// Cycles are only watched once
+	is.NoErr(os.Symlink(dir, filepath.Join(shared, "app")))
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	// Changes to the link's target are reported through the link
+	err = os.WriteFile(filepath.Join(shared, "index.svelte"), []byte(`<h1>hi</h1>`), 0644)
+	is.NoErr(err)
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "view/index.svelte")
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	// Linking another directory in watches it too
+	other := t.TempDir()
+	is.NoErr(os.Symlink(other, filepath.Join(dir, "public")))
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "public")
+	is.Equal(events[0].Op, watcher.OpCreate)
+	err = os.WriteFile(filepath.Join(other, "favicon.ico"), []byte(`ico`), 0644)
+	is.NoErr(err)
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "public/favicon.ico")
+	is.Equal(events[0].Op, watcher.OpCreate)
+	cancel()
+	is.NoErr(eg.Wait())
+}
+
+func TestSameModTime(t *testing.T) {
+	is := is.New(t)
+	dir := t.TempDir()
+	err := vfs.Write(dir, vfs.Map{
+		"a.txt": []byte(`a`),
+	})
+	is.NoErr(err)
+	path := filepath.Join(dir, "a.txt")
+	modTime := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	eventCh := make(chan []watcher.Event)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	eg := new(errgroup.Group)
+	eg.Go(func() error {
+		return watcher.Watch(ctx, dir, func(events []watcher.Event) error {
+			select {
+			case eventCh <- events:
+			case <-ctx.Done():
+			}
+			return nil
+		})
+	})
+	time.Sleep(waitForEvents)
+	is.NoErr(os.WriteFile(path, []byte("b"), 0644))
+	is.NoErr(os.Chtimes(path, modTime, modTime))
+	events, err := getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	// Same size and modtime, but different contents, like on a filesystem with
+	// a coarse clock
+	is.NoErr(os.WriteFile(path, []byte("c"), 0644))
+	is.NoErr(os.Chtimes(path, modTime, modTime))
+	events, err = getEvent(eventCh)
+	is.NoErr(err)
+	is.Equal(len(events), 1)
+	is.Equal(events[0].Path, "a.txt")
+	is.Equal(events[0].Op, watcher.OpUpdate)
+	// Rewriting the same contents isn't a change
+	is.NoErr(os.WriteFile(path, []byte("c"), 0644))
+	select {
+	case events := <-eventCh:
+		t.Fatalf("unexpected events %v", events)
+	case <-time.After(waitForEvents):
+	}
+	cancel()
+	is.NoErr(eg.Wait())
+}
@@ -0,0 +1,152 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Prefix is where webhooks are received. Controllers in controller/webhook
+// are routed here.
+const Prefix = "/webhook"
+
+// ErrUnverified is returned by Require when the request isn't a verified
+// webhook
+var ErrUnverified = errors.New("webhook: request isn't a verified webhook")
+
+// LoadReceiver loads the receiver from the environment:
+//
+//	STRIPE_WEBHOOK_SECRET=whsec_...
+//	GITHUB_WEBHOOK_SECRET=...
+//	SLACK_SIGNING_SECRET=...
+//	WEBHOOK_SECRET=...
+//
+// WEBHOOK_SECRET verifies webhooks sent from another Bud app. Providers
+// without a secret are turned off.
+func LoadReceiver() *Receiver {
+	return loadReceiver(os.Getenv)
+}
+
+func loadReceiver(getenv func(key string) string) *Receiver {
+	receiver := NewReceiver()
+	if secret := getenv("STRIPE_WEBHOOK_SECRET"); secret != "" {
+		receiver.Add("stripe", "Stripe-Signature", Stripe(secret))
+	}
+	if secret := getenv("GITHUB_WEBHOOK_SECRET"); secret != "" {
+		receiver.Add("github", "X-Hub-Signature-256", GitHub(secret))
+	}
+	if secret := getenv("SLACK_SIGNING_SECRET"); secret != "" {
+		receiver.Add("slack", "X-Slack-Signature", Slack(secret))
+	}
+	if secret := getenv("WEBHOOK_SECRET"); secret != "" {
+		receiver.Add("bud", SignatureHeader, Bud(secret))
+	}
+	return receiver
+}
+
+// NewReceiver verifies the webhooks sent to Prefix. Add providers to accept
+// their webhooks.
+func NewReceiver() *Receiver {
+	return &Receiver{
+		Prefix:  Prefix,
+		MaxBody: 5 << 20,
+	}
+}
+
+// Receiver verifies webhooks before they reach their controllers
+type Receiver struct {
+	// Prefix is the path that webhooks are sent to
+	Prefix string
+	// MaxBody is the largest body in bytes that's accepted
+	MaxBody   int64
+	providers []*provider
+}
+
+type provider struct {
+	name     string
+	header   string
+	verifier Verifier
+}
+
+// Add a provider. Requests with the header are verified by the verifier.
+func (r *Receiver) Add(name, header string, verifier Verifier) {
+	r.providers = append(r.providers, &provider{name, header, verifier})
+}
+
+type contextKey struct{}
+
+type received struct {
+	provider string
+	body     []byte
+}
+
+// Middleware rejects requests to the prefix without a valid signature from
+// one of the providers. The body is read to check the signature and put back,
+// so controllers can still decode it. The raw body is also available from
+// RawBody, since decoding it again doesn't always give back the same bytes.
+func (r *Receiver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != r.Prefix && !strings.HasPrefix(req.URL.Path, r.Prefix+"/") {
+			next.ServeHTTP(w, req)
+			return
+		}
+		provider := r.find(req.Header)
+		if provider == nil {
+			http.Error(w, "webhook: missing a signature", http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.MaxBody))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := provider.verifier.Verify(req.Header, body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		ctx := context.WithValue(req.Context(), contextKey{}, &received{provider.name, body})
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// find the provider by its signature header
+func (r *Receiver) find(header http.Header) *provider {
+	for _, provider := range r.providers {
+		if header.Get(provider.header) != "" {
+			return provider
+		}
+	}
+	return nil
+}
+
+// RawBody returns the verified webhook's body as it was sent. It returns nil
+// for other requests.
+func RawBody(ctx context.Context) []byte {
+	if received, ok := ctx.Value(contextKey{}).(*received); ok {
+		return received.body
+	}
+	return nil
+}
+
+// Provider returns the name of the provider that sent the verified webhook,
+// like "stripe". It returns an empty string for other requests.
+func Provider(ctx context.Context) string {
+	if received, ok := ctx.Value(contextKey{}).(*received); ok {
+		return received.provider
+	}
+	return ""
+}
+
+// Require returns ErrUnverified unless the request is a webhook from the
+// provider. Use it in controllers that only accept one provider's webhooks.
+func Require(ctx context.Context, provider string) error {
+	if name := Provider(ctx); name == "" || name != provider {
+		return ErrUnverified
+	}
+	return nil
+}
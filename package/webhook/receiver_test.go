@@ -0,0 +1,142 @@
+package webhook_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/webhook"
+)
+
+func sign(secret, content string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(content))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// echo responds with the provider and the body
+func echo(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	fmt.Fprintf(w, "%s %s %s", webhook.Provider(r.Context()), body, webhook.RawBody(r.Context()))
+}
+
+func receive(h http.Handler, path, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func receiver() http.Handler {
+	receiver := webhook.NewReceiver()
+	receiver.Add("stripe", "Stripe-Signature", webhook.Stripe("whsec_stripe"))
+	receiver.Add("github", "X-Hub-Signature-256", webhook.GitHub("github"))
+	receiver.Add("slack", "X-Slack-Signature", webhook.Slack("slack"))
+	receiver.Add("bud", webhook.SignatureHeader, webhook.Bud("bud"))
+	return receiver.Middleware(http.HandlerFunc(echo))
+}
+
+func TestReceiveStripe(t *testing.T) {
+	is := is.New(t)
+	h := receiver()
+	body := `{"type":"charge.succeeded"}`
+	rec := receive(h, "/webhook", body, map[string]string{
+		"Stripe-Signature": webhook.Sign("whsec_stripe", time.Now(), []byte(body)),
+	})
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "stripe "+body+" "+body)
+	rec = receive(h, "/webhook", body, map[string]string{
+		"Stripe-Signature": webhook.Sign("whsec_other", time.Now(), []byte(body)),
+	})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+}
+
+func TestReceiveGitHub(t *testing.T) {
+	is := is.New(t)
+	h := receiver()
+	body := `{"action":"opened"}`
+	rec := receive(h, "/webhook/github", body, map[string]string{
+		"X-Hub-Signature-256": "sha256=" + sign("github", body),
+	})
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "github "+body+" "+body)
+	rec = receive(h, "/webhook/github", body+" ", map[string]string{
+		"X-Hub-Signature-256": "sha256=" + sign("github", body),
+	})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(strings.TrimSpace(rec.Body.String()), webhook.ErrInvalidSignature.Error())
+}
+
+func TestReceiveSlack(t *testing.T) {
+	is := is.New(t)
+	h := receiver()
+	body := `token=abc&command=%2Fdeploy`
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	rec := receive(h, "/webhook/slack", body, map[string]string{
+		"Content-Type":              "application/x-www-form-urlencoded",
+		"X-Slack-Request-Timestamp": now,
+		"X-Slack-Signature":         "v0=" + sign("slack", "v0:"+now+":"+body),
+	})
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), "slack "+body+" "+body)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	rec = receive(h, "/webhook/slack", body, map[string]string{
+		"X-Slack-Request-Timestamp": old,
+		"X-Slack-Signature":         "v0=" + sign("slack", "v0:"+old+":"+body),
+	})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	is.Equal(strings.TrimSpace(rec.Body.String()), webhook.ErrExpiredSignature.Error())
+}
+
+func TestReceiveUnsigned(t *testing.T) {
+	is := is.New(t)
+	h := receiver()
+	rec := receive(h, "/webhook", `{}`, nil)
+	is.Equal(rec.Code, http.StatusUnauthorized)
+	// Other routes pass through
+	rec = receive(h, "/webhooks", `{}`, nil)
+	is.Equal(rec.Code, http.StatusOK)
+	is.Equal(rec.Body.String(), " {} ")
+	// Receivers without providers reject every webhook
+	rec = receive(webhook.NewReceiver().Middleware(http.HandlerFunc(echo)), "/webhook", `{}`, map[string]string{
+		webhook.SignatureHeader: webhook.Sign("", time.Now(), []byte(`{}`)),
+	})
+	is.Equal(rec.Code, http.StatusUnauthorized)
+}
+
+func TestHMAC(t *testing.T) {
+	is := is.New(t)
+	verifier := &webhook.HMAC{
+		Secret:          "secret",
+		Header:          "X-Signature",
+		TimestampHeader: "X-Timestamp",
+	}
+	body := []byte(`{}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	header := http.Header{}
+	header.Set("X-Timestamp", now)
+	header.Set("X-Signature", sign("secret", now+"."+string(body)))
+	is.NoErr(verifier.Verify(header, body))
+	header.Set("X-Timestamp", strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10))
+	is.Equal(verifier.Verify(header, body), webhook.ErrExpiredSignature)
+}
+
+func TestRequire(t *testing.T) {
+	is := is.New(t)
+	is.Equal(webhook.Require(context.Background(), ""), webhook.ErrUnverified)
+	is.Equal(webhook.Require(context.Background(), "stripe"), webhook.ErrUnverified)
+	is.Equal(webhook.RawBody(context.Background()), nil)
+}
@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SignatureHeader holds the signature, like "t=1700000000,v1=5257a8..."
+	SignatureHeader = "Webhook-Signature"
+	// IDHeader holds the delivery's ID. Retries have the same ID, so receivers
+	// can ignore deliveries they've already handled.
+	IDHeader = "Webhook-Id"
+	// EventHeader holds the event, like "order.created"
+	EventHeader = "Webhook-Event"
+)
+
+// ErrInvalidSignature is returned when a webhook's signature doesn't match
+var ErrInvalidSignature = errors.New("webhook: invalid signature")
+
+// ErrExpiredSignature is returned when a webhook was signed too long ago
+var ErrExpiredSignature = errors.New("webhook: signature has expired")
+
+// Sign the body at the given time. The signature is an HMAC-SHA256 of the
+// unix timestamp and the body, joined by a dot.
+func Sign(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac(secret, timestamp, body))
+}
+
+// Verify the signature header. Signatures that are older than the tolerance
+// are rejected, so captured webhooks can't be replayed later.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 || secret == "" {
+		return ErrInvalidSignature
+	}
+	expected := mac(secret, timestamp, body)
+	for _, signature := range signatures {
+		if !hmac.Equal(signature, expected) {
+			continue
+		}
+		if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+			return ErrExpiredSignature
+		}
+		return nil
+	}
+	return ErrInvalidSignature
+}
+
+// VerifyRequest reads and verifies the request's body, allowing signatures up
+// to 5 minutes old. Use it to receive webhooks from another Bud app.
+func VerifyRequest(secret string, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := Verify(secret, r.Header.Get(SignatureHeader), body, defaultTolerance); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
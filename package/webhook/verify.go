@@ -0,0 +1,139 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Verifier checks the signature of a webhook that was sent to your app
+type Verifier interface {
+	Verify(header http.Header, body []byte) error
+}
+
+// VerifierFunc is a function that verifies webhooks
+type VerifierFunc func(header http.Header, body []byte) error
+
+// Verify calls the function
+func (fn VerifierFunc) Verify(header http.Header, body []byte) error {
+	return fn(header, body)
+}
+
+// defaultTolerance is how old a signature's timestamp can be
+const defaultTolerance = 5 * time.Minute
+
+// Bud verifies webhooks sent from another Bud app with the Webhook-Signature
+// header
+func Bud(secret string) Verifier {
+	return VerifierFunc(func(header http.Header, body []byte) error {
+		return Verify(secret, header.Get(SignatureHeader), body, defaultTolerance)
+	})
+}
+
+// Stripe verifies webhooks from Stripe with the Stripe-Signature header. The
+// secret is the endpoint's signing secret, which starts with "whsec_".
+func Stripe(secret string) Verifier {
+	return VerifierFunc(func(header http.Header, body []byte) error {
+		// Stripe uses the same scheme as Bud
+		return Verify(secret, header.Get("Stripe-Signature"), body, defaultTolerance)
+	})
+}
+
+// GitHub verifies webhooks from GitHub with the X-Hub-Signature-256 header
+func GitHub(secret string) Verifier {
+	return &HMAC{
+		Secret: secret,
+		Header: "X-Hub-Signature-256",
+		Prefix: "sha256=",
+	}
+}
+
+// Slack verifies requests from Slack with the X-Slack-Signature and
+// X-Slack-Request-Timestamp headers
+func Slack(secret string) Verifier {
+	return VerifierFunc(func(header http.Header, body []byte) error {
+		timestamp := header.Get("X-Slack-Request-Timestamp")
+		if err := checkTimestamp(timestamp, defaultTolerance); err != nil {
+			return err
+		}
+		return checkHMAC(secret, "v0:"+timestamp+":", body, header.Get("X-Slack-Signature"), "v0=", false)
+	})
+}
+
+// HMAC verifies webhooks with an HMAC-SHA256 signature of the body in a
+// header. When there's a timestamp header, the signature is of the timestamp
+// and the body joined by a dot, and old timestamps are rejected.
+type HMAC struct {
+	Secret string
+	// Header holds the signature
+	Header string
+	// Prefix comes before the signature, like "sha256="
+	Prefix string
+	// Base64 signatures instead of hex
+	Base64 bool
+	// TimestampHeader holds the unix timestamp. It's optional.
+	TimestampHeader string
+	// Tolerance is how old the timestamp can be. It defaults to 5 minutes.
+	Tolerance time.Duration
+}
+
+var _ Verifier = (*HMAC)(nil)
+
+// Verify the signature
+func (h *HMAC) Verify(header http.Header, body []byte) error {
+	prefix := ""
+	if h.TimestampHeader != "" {
+		timestamp := header.Get(h.TimestampHeader)
+		tolerance := h.Tolerance
+		if tolerance == 0 {
+			tolerance = defaultTolerance
+		}
+		if err := checkTimestamp(timestamp, tolerance); err != nil {
+			return err
+		}
+		prefix = timestamp + "."
+	}
+	return checkHMAC(h.Secret, prefix, body, header.Get(h.Header), h.Prefix, h.Base64)
+}
+
+// checkTimestamp checks that the unix timestamp is within the tolerance
+func checkTimestamp(timestamp string, tolerance time.Duration) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredSignature
+	}
+	return nil
+}
+
+// checkHMAC checks that the signature is an HMAC-SHA256 of the prefix and body
+func checkHMAC(secret, prefix string, body []byte, signature, signaturePrefix string, isBase64 bool) error {
+	if secret == "" || !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	signature = strings.TrimPrefix(signature, signaturePrefix)
+	var actual []byte
+	var err error
+	if isBase64 {
+		actual, err = base64.StdEncoding.DecodeString(signature)
+	} else {
+		actual, err = hex.DecodeString(signature)
+	}
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(prefix))
+	h.Write(body)
+	if !hmac.Equal(actual, h.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
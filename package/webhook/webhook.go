@@ -0,0 +1,446 @@
+// Package webhook delivers webhooks to other apps. Deliveries are signed, so
+// receivers can check that they came from your app, retried with exponential
+// backoff until they succeed and logged in the database:
+//
+//	type Controller struct {
+//		Webhooks *webhook.Webhooks
+//	}
+//
+//	func (c *Controller) Create(ctx context.Context, order *Order) error {
+//		// ...save the order...
+//		endpoint := &webhook.Endpoint{URL: shop.WebhookURL, Secret: shop.WebhookSecret}
+//		return c.Webhooks.Enqueue(ctx, endpoint, "order.created", order)
+//	}
+//
+// Jobs can enqueue webhooks the same way. The package also verifies webhooks
+// that other services, like Stripe and GitHub, send to controller/webhook.
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/framework/job/jobrt"
+	"github.com/livebud/bud/package/log"
+)
+
+// ErrNotFound is returned when a delivery doesn't exist
+var ErrNotFound = errors.New("webhook: delivery not found")
+
+// Load the webhooks from the environment:
+//
+//	WEBHOOK_MAX_ATTEMPTS=10
+//	WEBHOOK_TIMEOUT=10s
+//
+// Deliveries are kept in the database using DATABASE_URL.
+func Load(log log.Interface) (*Webhooks, error) {
+	return load(log, os.Getenv)
+}
+
+func load(log log.Interface, getenv func(key string) string) (*Webhooks, error) {
+	databaseURL := getenv("DATABASE_URL")
+	if databaseURL == "" {
+		return nil, fmt.Errorf("webhook: missing the DATABASE_URL environment variable")
+	}
+	webhooks := New(log, nil)
+	if value := getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("webhook: expected WEBHOOK_MAX_ATTEMPTS to be a positive number, got %q", value)
+		}
+		webhooks.MaxAttempts = n
+	}
+	if value := getenv("WEBHOOK_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("webhook: expected WEBHOOK_TIMEOUT to be a duration like 10s, got %q", value)
+		}
+		webhooks.Timeout = timeout
+	}
+	db, err := dbrt.Open(databaseURL)
+	if err != nil {
+		return nil, err
+	}
+	webhooks.db = db
+	return webhooks, nil
+}
+
+// New webhooks that keep their deliveries in the bud_webhook_deliveries and
+// bud_webhook_attempts tables. The tables need to be created with a
+// migration. For Postgres:
+//
+//	create table bud_webhook_deliveries (
+//		id bigserial primary key,
+//		url text not null,
+//		secret text not null,
+//		event text not null,
+//		payload text not null,
+//		state text not null,
+//		attempts integer not null default 0,
+//		run_at timestamp not null,
+//		locked_until timestamp,
+//		created_at timestamp not null
+//	);
+//	create index bud_webhook_deliveries_run_at on bud_webhook_deliveries (run_at);
+//	create table bud_webhook_attempts (
+//		id bigserial primary key,
+//		delivery_id bigint not null references bud_webhook_deliveries (id) on delete cascade,
+//		status_code integer not null,
+//		response text not null,
+//		error text not null,
+//		duration_ms bigint not null,
+//		created_at timestamp not null
+//	);
+//	create index bud_webhook_attempts_delivery_id on bud_webhook_attempts (delivery_id);
+//
+// Use "id integer primary key" for SQLite.
+func New(log log.Interface, db *dbrt.DB) *Webhooks {
+	return &Webhooks{
+		MaxAttempts:  10,
+		Timeout:      10 * time.Second,
+		PollInterval: time.Second,
+		BatchSize:    10,
+		Backoff:      jobrt.Backoff,
+		Client:       http.DefaultClient,
+		log:          log.Named("webhook"),
+		db:           db,
+	}
+}
+
+// Webhooks enqueues and delivers webhooks
+type Webhooks struct {
+	// MaxAttempts is the number of times a delivery is tried before it fails
+	MaxAttempts int
+	// Timeout is how long receivers have to respond
+	Timeout time.Duration
+	// PollInterval is how often to check for deliveries
+	PollInterval time.Duration
+	// BatchSize is the number of webhooks delivered at once
+	BatchSize int
+	// Backoff returns how long to wait before retrying a delivery
+	Backoff func(attempts int) time.Duration
+	// Client sends the webhooks
+	Client *http.Client
+	log    log.Interface
+	db     *dbrt.DB
+}
+
+// Endpoint receives webhooks
+type Endpoint struct {
+	URL string
+	// Secret signs the webhooks. Share it with the receiver, so it can verify
+	// the Webhook-Signature header.
+	Secret string
+}
+
+// State of a delivery
+type State string
+
+const (
+	Pending   State = "pending"
+	Delivered State = "delivered"
+	Failed    State = "failed"
+)
+
+// Delivery of a webhook to an endpoint
+type Delivery struct {
+	ID        int64
+	URL       string
+	Event     string
+	Payload   []byte
+	State     State
+	Attempts  int
+	RunAt     time.Time
+	CreatedAt time.Time
+	// History of attempts, oldest first
+	History []*Attempt
+}
+
+// Attempt to deliver a webhook
+type Attempt struct {
+	StatusCode int // 0 when the request failed
+	Response   string
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// maxResponse is the number of bytes of the response that are logged
+const maxResponse = 1024
+
+// Enqueue the webhook for delivery to the endpoint. The payload is encoded as
+// JSON. Webhooks that are enqueued within a transaction, like during a request
+// that changes the database, are only delivered if the transaction commits.
+func (w *Webhooks) Enqueue(ctx context.Context, endpoint *Endpoint, event string, payload interface{}) error {
+	if endpoint.URL == "" || endpoint.Secret == "" {
+		return fmt.Errorf("webhook: endpoint for %s needs a URL and a secret", event)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("webhook: unable to encode the %s payload. %w", event, err)
+	}
+	query := w.db.Dialect().Rebind(`insert into "bud_webhook_deliveries" ` +
+		`("url", "secret", "event", "payload", "state", "attempts", "run_at", "created_at") ` +
+		`values (?, ?, ?, ?, ?, 0, ?, ?)`)
+	now := dbrt.Now()
+	if _, err := w.db.ExecContext(ctx, query, endpoint.URL, endpoint.Secret, event, string(data), Pending, now, now); err != nil {
+		return fmt.Errorf("webhook: unable to enqueue %s. %w", event, err)
+	}
+	return nil
+}
+
+// Deliveries returns the latest deliveries without their history
+func (w *Webhooks) Deliveries(ctx context.Context, limit int) ([]*Delivery, error) {
+	query := w.db.Dialect().Rebind(`select "id", "url", "event", "payload", "state", "attempts", "run_at", "created_at" ` +
+		`from "bud_webhook_deliveries" order by "id" desc limit ?`)
+	rows, err := w.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: unable to list deliveries. %w", err)
+	}
+	defer rows.Close()
+	var deliveries []*Delivery
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func scanDelivery(row dbrt.Scanner) (*Delivery, error) {
+	delivery := new(Delivery)
+	var payload string
+	if err := row.Scan(&delivery.ID, &delivery.URL, &delivery.Event, &payload, &delivery.State, &delivery.Attempts, &delivery.RunAt, &delivery.CreatedAt); err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	return delivery, nil
+}
+
+// Delivery returns the delivery with its history of attempts
+func (w *Webhooks) Delivery(ctx context.Context, id int64) (*Delivery, error) {
+	query := w.db.Dialect().Rebind(`select "id", "url", "event", "payload", "state", "attempts", "run_at", "created_at" ` +
+		`from "bud_webhook_deliveries" where "id" = ?`)
+	delivery, err := scanDelivery(w.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("webhook: unable to load delivery %d. %w", id, err)
+	}
+	query = w.db.Dialect().Rebind(`select "status_code", "response", "error", "duration_ms", "created_at" ` +
+		`from "bud_webhook_attempts" where "delivery_id" = ? order by "id"`)
+	rows, err := w.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: unable to load the attempts for delivery %d. %w", id, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		attempt := new(Attempt)
+		var ms int64
+		if err := rows.Scan(&attempt.StatusCode, &attempt.Response, &attempt.Error, &ms, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempt.Duration = time.Duration(ms) * time.Millisecond
+		delivery.History = append(delivery.History, attempt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Redeliver a delivery right away, like after it failed
+func (w *Webhooks) Redeliver(ctx context.Context, id int64) error {
+	query := w.db.Dialect().Rebind(`update "bud_webhook_deliveries" set "state" = ?, "attempts" = 0, "run_at" = ?, "locked_until" = null where "id" = ?`)
+	result, err := w.db.ExecContext(ctx, query, Pending, dbrt.Now(), id)
+	if err != nil {
+		return fmt.Errorf("webhook: unable to redeliver %d. %w", id, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Run delivers webhooks until the context is canceled. Webhooks that are being
+// delivered finish first.
+func (w *Webhooks) Run(ctx context.Context) error {
+	for {
+		delivered, err := w.Deliver(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			w.log.Error(err.Error())
+		}
+		// Keep going while there are webhooks to deliver
+		if err == nil && delivered == w.BatchSize {
+			continue
+		}
+		timer := time.NewTimer(w.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// Deliver the next batch of webhooks that are due. It returns the number of
+// webhooks that were attempted.
+func (w *Webhooks) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := w.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+	errs := make([]error, len(deliveries))
+	var wg sync.WaitGroup
+	wg.Add(len(deliveries))
+	for i, delivery := range deliveries {
+		go func(i int, delivery *claimed) {
+			defer wg.Done()
+			// Finish the delivery even if the context is canceled
+			errs[i] = w.deliver(context.Background(), delivery)
+		}(i, delivery)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return len(deliveries), err
+		}
+	}
+	return len(deliveries), nil
+}
+
+// claimed delivery
+type claimed struct {
+	ID       int64
+	URL      string
+	Secret   string
+	Event    string
+	Payload  []byte
+	Attempts int
+}
+
+// claim the next batch of deliveries. Claimed deliveries aren't claimed again
+// until the lease runs out, in case the process crashed.
+func (w *Webhooks) claim(ctx context.Context) ([]*claimed, error) {
+	lock := ""
+	if w.db.Dialect() == dbrt.Postgres {
+		lock = " for update skip locked"
+	}
+	query := w.db.Dialect().Rebind(`update "bud_webhook_deliveries" set "locked_until" = ? ` +
+		`where "id" in (select "id" from "bud_webhook_deliveries" where "state" = ? and "run_at" <= ? ` +
+		`and ("locked_until" is null or "locked_until" <= ?) order by "run_at", "id" limit ?` + lock + `) ` +
+		`returning "id", "url", "secret", "event", "payload", "attempts"`)
+	now := dbrt.Now()
+	rows, err := w.db.QueryContext(ctx, query, now.Add(w.Timeout+time.Minute), Pending, now, now, w.BatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: unable to claim deliveries. %w", err)
+	}
+	defer rows.Close()
+	var deliveries []*claimed
+	for rows.Next() {
+		delivery := new(claimed)
+		var payload string
+		if err := rows.Scan(&delivery.ID, &delivery.URL, &delivery.Secret, &delivery.Event, &payload, &delivery.Attempts); err != nil {
+			return nil, err
+		}
+		delivery.Payload = []byte(payload)
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Returning doesn't keep the order
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+// deliver the webhook and log the attempt
+func (w *Webhooks) deliver(ctx context.Context, delivery *claimed) error {
+	attempt := w.send(ctx, delivery)
+	attempts := delivery.Attempts + 1
+	state, runAt := Delivered, dbrt.Now()
+	if attempt.Error != "" {
+		if attempts >= w.MaxAttempts {
+			state = Failed
+			w.log.Error("webhook: giving up on delivery", "event", delivery.Event, "id", delivery.ID, "url", delivery.URL, "attempts", attempts, "error", attempt.Error)
+		} else {
+			state, runAt = Pending, runAt.Add(w.Backoff(attempts))
+			w.log.Warn("webhook: retrying delivery", "event", delivery.Event, "id", delivery.ID, "url", delivery.URL, "at", runAt.Format(time.RFC3339), "error", attempt.Error)
+		}
+	}
+	return w.db.Transact(ctx, func(ctx context.Context) error {
+		query := w.db.Dialect().Rebind(`insert into "bud_webhook_attempts" ` +
+			`("delivery_id", "status_code", "response", "error", "duration_ms", "created_at") values (?, ?, ?, ?, ?, ?)`)
+		if _, err := w.db.ExecContext(ctx, query, delivery.ID, attempt.StatusCode, attempt.Response, attempt.Error, attempt.Duration.Milliseconds(), attempt.CreatedAt); err != nil {
+			return fmt.Errorf("webhook: unable to log the attempt for delivery %d. %w", delivery.ID, err)
+		}
+		query = w.db.Dialect().Rebind(`update "bud_webhook_deliveries" set "state" = ?, "attempts" = ?, "run_at" = ?, "locked_until" = null where "id" = ?`)
+		if _, err := w.db.ExecContext(ctx, query, state, attempts, runAt, delivery.ID); err != nil {
+			return fmt.Errorf("webhook: unable to update delivery %d. %w", delivery.ID, err)
+		}
+		return nil
+	})
+}
+
+// send the webhook. Responses outside of 2xx are errors.
+func (w *Webhooks) send(ctx context.Context, delivery *claimed) *Attempt {
+	attempt := &Attempt{CreatedAt: dbrt.Now()}
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, strings.NewReader(string(delivery.Payload)))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bud-webhook")
+	req.Header.Set(IDHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), delivery.Payload))
+	start := time.Now()
+	res, err := w.Client.Do(req)
+	if err != nil {
+		attempt.Duration = time.Since(start)
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+	attempt.Duration = time.Since(start)
+	attempt.StatusCode = res.StatusCode
+	attempt.Response = strings.ToValidUTF8(string(body), "")
+	if err != nil {
+		attempt.Error = err.Error()
+	} else if res.StatusCode < 200 || res.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("webhook: %s responded with %d", delivery.URL, res.StatusCode)
+	}
+	return attempt
+}
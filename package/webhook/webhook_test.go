@@ -0,0 +1,192 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/livebud/bud/framework/db/dbrt"
+	"github.com/livebud/bud/internal/is"
+	"github.com/livebud/bud/package/log"
+	"github.com/livebud/bud/package/webhook"
+)
+
+const schema = `
+create table bud_webhook_deliveries (
+	id integer primary key,
+	url text not null,
+	secret text not null,
+	event text not null,
+	payload text not null,
+	state text not null,
+	attempts integer not null default 0,
+	run_at timestamp not null,
+	locked_until timestamp,
+	created_at timestamp not null
+);
+create table bud_webhook_attempts (
+	id integer primary key,
+	delivery_id bigint not null references bud_webhook_deliveries (id) on delete cascade,
+	status_code integer not null,
+	response text not null,
+	error text not null,
+	duration_ms bigint not null,
+	created_at timestamp not null
+);
+`
+
+func load(t testing.TB) (*webhook.Webhooks, *dbrt.DB) {
+	t.Helper()
+	db, err := dbrt.Open("sqlite://" + filepath.Join(t.TempDir(), "app.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.ExecContext(context.Background(), schema); err != nil {
+		t.Fatal(err)
+	}
+	webhooks := webhook.New(log.Discard, db)
+	webhooks.Backoff = func(attempts int) time.Duration { return 0 }
+	return webhooks, db
+}
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func TestDeliver(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	webhooks, _ := load(t)
+	received := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := webhook.VerifyRequest("secret", r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		is.Equal(r.Header.Get(webhook.EventHeader), "order.created")
+		is.Equal(r.Header.Get(webhook.IDHeader), "1")
+		is.Equal(r.Header.Get("Content-Type"), "application/json")
+		received <- string(body)
+		w.Write([]byte("thanks"))
+	}))
+	defer server.Close()
+	endpoint := &webhook.Endpoint{URL: server.URL, Secret: "secret"}
+	is.NoErr(webhooks.Enqueue(ctx, endpoint, "order.created", &order{ID: 1}))
+	n, err := webhooks.Deliver(ctx)
+	is.NoErr(err)
+	is.Equal(n, 1)
+	is.Equal(<-received, `{"id":1}`)
+	// Delivered webhooks aren't sent again
+	n, err = webhooks.Deliver(ctx)
+	is.NoErr(err)
+	is.Equal(n, 0)
+	deliveries, err := webhooks.Deliveries(ctx, 10)
+	is.NoErr(err)
+	is.Equal(len(deliveries), 1)
+	is.Equal(deliveries[0].State, webhook.Delivered)
+	delivery, err := webhooks.Delivery(ctx, deliveries[0].ID)
+	is.NoErr(err)
+	is.Equal(delivery.Attempts, 1)
+	is.Equal(len(delivery.History), 1)
+	is.Equal(delivery.History[0].StatusCode, 200)
+	is.Equal(delivery.History[0].Response, "thanks")
+	is.Equal(delivery.History[0].Error, "")
+}
+
+func TestRetry(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	webhooks, _ := load(t)
+	webhooks.MaxAttempts = 3
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 5 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+	}))
+	defer server.Close()
+	is.NoErr(webhooks.Enqueue(ctx, &webhook.Endpoint{URL: server.URL, Secret: "secret"}, "order.created", &order{ID: 1}))
+	for i := 0; i < 4; i++ {
+		_, err := webhooks.Deliver(ctx)
+		is.NoErr(err)
+	}
+	// Gives up after the max attempts
+	is.Equal(atomic.LoadInt32(&calls), int32(3))
+	delivery, err := webhooks.Delivery(ctx, 1)
+	is.NoErr(err)
+	is.Equal(delivery.State, webhook.Failed)
+	is.Equal(len(delivery.History), 3)
+	is.Equal(delivery.History[0].StatusCode, http.StatusServiceUnavailable)
+	is.Equal(delivery.History[0].Response, "unavailable\n")
+	is.Equal(delivery.History[0].Error, "webhook: "+server.URL+" responded with 503")
+	// Redeliver failed webhooks
+	is.NoErr(webhooks.Redeliver(ctx, 1))
+	_, err = webhooks.Deliver(ctx)
+	is.NoErr(err)
+	_, err = webhooks.Deliver(ctx)
+	is.NoErr(err)
+	delivery, err = webhooks.Delivery(ctx, 1)
+	is.NoErr(err)
+	is.Equal(delivery.State, webhook.Delivered)
+	is.Equal(delivery.Attempts, 2)
+	is.Equal(len(delivery.History), 5)
+	is.Equal(webhooks.Redeliver(ctx, 2), webhook.ErrNotFound)
+	_, err = webhooks.Delivery(ctx, 2)
+	is.Equal(err, webhook.ErrNotFound)
+}
+
+func TestEnqueueRolledBack(t *testing.T) {
+	is := is.New(t)
+	ctx := context.Background()
+	webhooks, db := load(t)
+	rollback := errors.New("rollback")
+	err := db.Transact(ctx, func(ctx context.Context) error {
+		is.NoErr(webhooks.Enqueue(ctx, &webhook.Endpoint{URL: "http://localhost", Secret: "secret"}, "order.created", &order{ID: 1}))
+		return rollback
+	})
+	is.Equal(err, rollback)
+	deliveries, err := webhooks.Deliveries(ctx, 10)
+	is.NoErr(err)
+	is.Equal(len(deliveries), 0)
+}
+
+func TestEnqueueInvalid(t *testing.T) {
+	is := is.New(t)
+	webhooks, _ := load(t)
+	err := webhooks.Enqueue(context.Background(), &webhook.Endpoint{URL: "http://localhost"}, "order.created", &order{ID: 1})
+	is.True(err != nil)
+	is.Equal(err.Error(), "webhook: endpoint for order.created needs a URL and a secret")
+}
+
+func TestVerify(t *testing.T) {
+	is := is.New(t)
+	body := []byte(`{"id":1}`)
+	signature := webhook.Sign("secret", time.Now(), body)
+	is.NoErr(webhook.Verify("secret", signature, body, time.Minute))
+	is.Equal(webhook.Verify("other", signature, body, time.Minute), webhook.ErrInvalidSignature)
+	is.Equal(webhook.Verify("secret", signature, []byte(`{"id":2}`), time.Minute), webhook.ErrInvalidSignature)
+	is.Equal(webhook.Verify("secret", "", body, time.Minute), webhook.ErrInvalidSignature)
+	old := webhook.Sign("secret", time.Now().Add(-time.Hour), body)
+	is.Equal(webhook.Verify("secret", old, body, time.Minute), webhook.ErrExpiredSignature)
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+	t.Setenv("DATABASE_URL", "")
+	_, err := webhook.Load(log.Discard)
+	is.True(err != nil)
+	is.Equal(err.Error(), "webhook: missing the DATABASE_URL environment variable")
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "app.db"))
+	t.Setenv("WEBHOOK_TIMEOUT", "soon")
+	_, err = webhook.Load(log.Discard)
+	is.True(err != nil)
+	is.Equal(err.Error(), `webhook: expected WEBHOOK_TIMEOUT to be a duration like 10s, got "soon"`)
+}